@@ -0,0 +1,187 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAPI_Assets tests hardware asset data, the expiring warranty report and the CSV export
+func TestAPI_Assets(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	sendJSON := func(t *testing.T, method, path string, payload interface{}) (*http.Response, map[string]interface{}) {
+		t.Helper()
+		data, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, ts.URL()+path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &result)
+		return resp, result
+	}
+
+	getJSON := func(t *testing.T, path string, v interface{}) {
+		t.Helper()
+		resp, err := http.Get(ts.URL() + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d", path, resp.StatusCode)
+		}
+		json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	date := func(days int) string {
+		return time.Now().UTC().AddDate(0, 0, days).Format("2006-01-02")
+	}
+
+	var deviceID string
+
+	t.Run("CreateWithAsset", func(t *testing.T) {
+		resp, device := sendJSON(t, http.MethodPost, "/api/devices", map[string]interface{}{
+			"name": "srv-1",
+			"asset": map[string]interface{}{
+				"serial_number":    "SN-001",
+				"asset_tag":        "AT-001",
+				"vendor":           "Dell",
+				"purchase_date":    "2024-01-15T00:00:00Z",
+				"po_number":        "PO-42",
+				"cost":             4999.5,
+				"warranty_expires": date(30),
+				"support_contract": "ProSupport 123",
+			},
+		})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+		deviceID = device["id"].(string)
+		asset, _ := device["asset"].(map[string]interface{})
+		if asset["serial_number"] != "SN-001" || asset["purchase_date"] != "2024-01-15" {
+			t.Errorf("Unexpected asset in response: %v", asset)
+		}
+
+		for _, d := range []struct {
+			name    string
+			serial  string
+			expires string
+		}{
+			{"srv-2", "SN-002", date(200)},
+			{"srv-3", "SN-003", date(-10)},
+		} {
+			resp, _ := sendJSON(t, http.MethodPost, "/api/devices", map[string]interface{}{
+				"name":  d.name,
+				"asset": map[string]interface{}{"serial_number": d.serial, "warranty_expires": d.expires},
+			})
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("RejectInvalidAsset", func(t *testing.T) {
+		resp, _ := sendJSON(t, http.MethodPost, "/api/devices", map[string]interface{}{
+			"name":  "dup",
+			"asset": map[string]interface{}{"serial_number": "sn-001"},
+		})
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("Expected status 409 for duplicate serial, got %d", resp.StatusCode)
+		}
+
+		resp, _ = sendJSON(t, http.MethodPost, "/api/devices", map[string]interface{}{
+			"name":  "bad-date",
+			"asset": map[string]interface{}{"warranty_expires": "next year"},
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for invalid date, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("UpdateKeepsAsset", func(t *testing.T) {
+		resp, device := sendJSON(t, http.MethodPut, "/api/devices/"+deviceID, map[string]interface{}{"name": "srv-1"})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if asset, _ := device["asset"].(map[string]interface{}); asset["asset_tag"] != "AT-001" {
+			t.Errorf("Expected update without asset to keep it, got %v", device["asset"])
+		}
+
+		var found []map[string]interface{}
+		getJSON(t, "/api/devices/search?q=at-001", &found)
+		if len(found) != 1 || found[0]["name"] != "srv-1" {
+			t.Errorf("Expected search by asset tag to find srv-1, got %v", found)
+		}
+	})
+
+	t.Run("ExpiringWarranties", func(t *testing.T) {
+		var assets []map[string]interface{}
+		getJSON(t, "/api/assets/expiring?days=90", &assets)
+		if len(assets) != 1 || assets[0]["device_name"] != "srv-1" {
+			t.Fatalf("Expected only srv-1 to expire within 90 days, got %v", assets)
+		}
+		if assets[0]["warranty_days_left"] != float64(30) {
+			t.Errorf("Expected 30 days left, got %v", assets[0]["warranty_days_left"])
+		}
+
+		assets = nil
+		getJSON(t, "/api/assets/expiring?days=365&include_expired=true", &assets)
+		if len(assets) != 3 || assets[0]["device_name"] != "srv-3" {
+			t.Errorf("Expected all 3 assets with expired srv-3 first, got %v", assets)
+		}
+
+		resp, err := http.Get(ts.URL() + "/api/assets/expiring?days=-1")
+		if err != nil {
+			t.Fatalf("Failed to list assets: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for negative days, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("CSVExport", func(t *testing.T) {
+		resp, err := http.Get(ts.URL() + "/api/assets?format=csv")
+		if err != nil {
+			t.Fatalf("Failed to export assets: %v", err)
+		}
+		defer resp.Body.Close()
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+			t.Errorf("Expected CSV content type, got %s", resp.Header.Get("Content-Type"))
+		}
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to parse CSV: %v", err)
+		}
+		if len(records) != 4 || records[0][0] != "device_id" {
+			t.Fatalf("Expected header and 3 rows, got %v", records)
+		}
+		if records[2][1] != "srv-1" || records[2][7] != "PO-42" || records[2][8] != "4999.50" {
+			t.Errorf("Unexpected srv-1 row: %v", records[2])
+		}
+	})
+
+	t.Run("RemoveAsset", func(t *testing.T) {
+		resp, device := sendJSON(t, http.MethodPut, "/api/devices/"+deviceID, map[string]interface{}{"name": "srv-1", "asset": map[string]interface{}{}})
+		if resp.StatusCode != http.StatusOK || device["asset"] != nil {
+			t.Errorf("Expected empty asset to remove asset data, got %d %v", resp.StatusCode, device["asset"])
+		}
+		var assets []map[string]interface{}
+		getJSON(t, "/api/assets", &assets)
+		if len(assets) != 2 {
+			t.Errorf("Expected 2 remaining assets, got %d", len(assets))
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/martinsuchenak/rackd/internal/api"
+	"github.com/martinsuchenak/rackd/internal/backup"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// TestAPI_Backups tests taking, listing and downloading backups
+func TestAPI_Backups(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := storage.NewSQLiteStorage(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+
+	manager := backup.NewManager(store, backup.Options{Dir: filepath.Join(dataDir, "backups"), Compress: true, KeepDaily: 7})
+	mux := http.NewServeMux()
+	api.NewHandler(store).RegisterRoutes(mux)
+	api.NewBackupHandler(manager).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/devices", "application/json", bytes.NewReader(DeviceJSON("web-1", nil)))
+	if err != nil {
+		t.Fatalf("Failed to create device: %v", err)
+	}
+	resp.Body.Close()
+
+	var created map[string]interface{}
+
+	t.Run("Create", func(t *testing.T) {
+		resp, err := http.Post(server.URL+"/api/admin/backups", "", nil)
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+		json.NewDecoder(resp.Body).Decode(&created)
+		if created["compressed"] != true || created["size"].(float64) <= 0 {
+			t.Errorf("Unexpected backup %v", created)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/admin/backups")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var backups []map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&backups)
+		if len(backups) != 1 || backups[0]["name"] != created["name"] {
+			t.Errorf("Expected the created backup to be listed, got %v", backups)
+		}
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/admin/backups/" + created["name"].(string))
+		if err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if len(data) != int(created["size"].(float64)) || !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+			t.Errorf("Expected the gzipped backup, got %d bytes", len(data))
+		}
+
+		for _, name := range []string{"devices.db", "rackd-20250101-000000.db"} {
+			resp, err := http.Get(server.URL + "/api/admin/backups/" + name)
+			if err != nil {
+				t.Fatalf("Download failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("Expected status 404 for %s, got %d", name, resp.StatusCode)
+			}
+		}
+	})
+}
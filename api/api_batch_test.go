@@ -0,0 +1,114 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestAPI_Batch tests running several operations in one transaction
+func TestAPI_Batch(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	runBatch := func(t *testing.T, body string) (int, map[string]interface{}) {
+		t.Helper()
+		resp, err := http.Post(ts.URL()+"/api/batch", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Batch failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	searchDevices := func(t *testing.T, query string) []map[string]interface{} {
+		t.Helper()
+		resp, err := http.Get(ts.URL() + "/api/devices/search?q=" + query)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var devices []map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&devices)
+		return devices
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		status, result := runBatch(t, `{"operations": [
+			{"method": "POST", "path": "/api/networks", "body": {"name": "Batch", "subnet": "10.50.0.0/24"}},
+			{"method": "POST", "path": "/api/networks/${0.id}/pools", "body": {"name": "Hosts", "start_ip": "10.50.0.10", "end_ip": "10.50.0.20"}},
+			{"method": "GET", "path": "/api/pools/${1.id}/next-ip"},
+			{"method": "POST", "path": "/api/devices", "body": {"name": "batch-host", "addresses": [{"ip": "${2.ip}", "type": "ipv4", "network_id": "${0.id}", "pool_id": "${1.id}"}]}},
+			{"method": "POST", "path": "/api/devices", "body": {"name": "batch-rack"}},
+			{"method": "POST", "path": "/api/devices/${4.id}/relationships", "body": {"child_id": "${3.id}", "relationship_type": "contains"}}
+		]}`)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %v", status, result)
+		}
+		results, _ := result["results"].([]interface{})
+		if len(results) != 6 {
+			t.Fatalf("Expected 6 results, got %v", result)
+		}
+		nextIP := results[2].(map[string]interface{})["body"].(map[string]interface{})["ip"]
+		if nextIP != "10.50.0.10" {
+			t.Errorf("Expected next IP 10.50.0.10, got %v", nextIP)
+		}
+		if status := results[3].(map[string]interface{})["status"]; status != float64(http.StatusCreated) {
+			t.Errorf("Expected device creation status 201, got %v", status)
+		}
+
+		devices := searchDevices(t, "batch-host")
+		if len(devices) != 1 {
+			t.Fatalf("Expected batch-host to be committed, got %v", devices)
+		}
+		addresses, _ := devices[0]["addresses"].([]interface{})
+		if len(addresses) != 1 || addresses[0].(map[string]interface{})["ip"] != "10.50.0.10" {
+			t.Errorf("Expected allocated address, got %v", addresses)
+		}
+	})
+
+	t.Run("RollbackOnFailure", func(t *testing.T) {
+		status, result := runBatch(t, `{"operations": [
+			{"method": "POST", "path": "/api/devices", "body": {"name": "batch-orphan"}},
+			{"method": "POST", "path": "/api/devices/${0.id}/relationships", "body": {"child_id": "missing", "relationship_type": "contains"}}
+		]}`)
+		if status != http.StatusNotFound {
+			t.Fatalf("Expected status of failed operation 404, got %d: %v", status, result)
+		}
+		if result["failed_index"] != float64(1) || result["error"] == nil {
+			t.Errorf("Expected failure at operation 1, got %v", result)
+		}
+		if devices := searchDevices(t, "batch-orphan"); len(devices) != 0 {
+			t.Errorf("Expected batch-orphan to be rolled back, got %v", devices)
+		}
+	})
+
+	t.Run("InvalidReference", func(t *testing.T) {
+		status, result := runBatch(t, `{"operations": [
+			{"method": "POST", "path": "/api/devices", "body": {"name": "batch-ref"}},
+			{"method": "GET", "path": "/api/devices/${5.id}"}
+		]}`)
+		if status != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d: %v", status, result)
+		}
+		if devices := searchDevices(t, "batch-ref"); len(devices) != 0 {
+			t.Errorf("Expected batch-ref to be rolled back, got %v", devices)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for name, body := range map[string]string{
+			"Empty":      `{"operations": []}`,
+			"NoMethod":   `{"operations": [{"path": "/api/devices"}]}`,
+			"OutsideAPI": `{"operations": [{"method": "GET", "path": "/mcp"}]}`,
+			"Nested":     `{"operations": [{"method": "POST", "path": "/api/batch", "body": {"operations": []}}]}`,
+		} {
+			if status, result := runBatch(t, body); status != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d: %v", name, status, result)
+			}
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// TestAPI_CustomFields tests custom field definitions and values on devices
+func TestAPI_CustomFields(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	postJSON := func(t *testing.T, path string, payload interface{}) (*http.Response, map[string]interface{}) {
+		t.Helper()
+		data, _ := json.Marshal(payload)
+		resp, err := http.Post(ts.URL()+path, "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("POST %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &result)
+		return resp, result
+	}
+
+	var ownerID string
+
+	t.Run("CreateDefinitions", func(t *testing.T) {
+		resp, field := postJSON(t, "/api/custom-fields", map[string]interface{}{
+			"entity_type": "device",
+			"name":        "owner",
+			"type":        "string",
+			"required":    true,
+		})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+		ownerID, _ = field["id"].(string)
+		if ownerID == "" {
+			t.Fatal("Expected custom field ID to be set")
+		}
+
+		resp, _ = postJSON(t, "/api/custom-fields", map[string]interface{}{
+			"entity_type": "device",
+			"name":        "tier",
+			"type":        "enum",
+			"options":     []string{"gold", "silver"},
+		})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("RejectInvalidDefinition", func(t *testing.T) {
+		resp, _ := postJSON(t, "/api/custom-fields", map[string]interface{}{
+			"entity_type": "device",
+			"name":        "Bad Name",
+			"type":        "string",
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for invalid name, got %d", resp.StatusCode)
+		}
+
+		resp, _ = postJSON(t, "/api/custom-fields", map[string]interface{}{
+			"entity_type": "device",
+			"name":        "owner",
+			"type":        "string",
+		})
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("Expected status 409 for duplicate name, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ValidateValues", func(t *testing.T) {
+		resp, _ := postJSON(t, "/api/devices", map[string]interface{}{"name": "no-owner"})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for missing required field, got %d", resp.StatusCode)
+		}
+
+		resp, _ = postJSON(t, "/api/devices", map[string]interface{}{
+			"name":          "bad-tier",
+			"custom_fields": map[string]string{"owner": "ops", "tier": "bronze"},
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for invalid enum value, got %d", resp.StatusCode)
+		}
+
+		resp, _ = postJSON(t, "/api/devices", map[string]interface{}{
+			"name":          "unknown-field",
+			"custom_fields": map[string]string{"owner": "ops", "color": "red"},
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for unknown field, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("FilterAndSearch", func(t *testing.T) {
+		for _, d := range []struct{ name, owner, tier string }{
+			{"web-1", "ops", "gold"},
+			{"web-2", "ops", "silver"},
+			{"db-1", "dba", "gold"},
+		} {
+			resp, device := postJSON(t, "/api/devices", map[string]interface{}{
+				"name":          d.name,
+				"custom_fields": map[string]string{"owner": d.owner, "tier": d.tier},
+			})
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+			}
+			fields, _ := device["custom_fields"].(map[string]interface{})
+			if fields["owner"] != d.owner {
+				t.Errorf("Expected owner %s in response, got %v", d.owner, fields["owner"])
+			}
+		}
+
+		resp, err := http.Get(ts.URL() + "/api/devices?field.owner=ops&field.tier=gold")
+		if err != nil {
+			t.Fatalf("Failed to list devices: %v", err)
+		}
+		var devices []map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&devices)
+		resp.Body.Close()
+		if len(devices) != 1 || devices[0]["name"] != "web-1" {
+			t.Errorf("Expected only web-1 to match, got %v", devices)
+		}
+
+		resp, err = http.Get(ts.URL() + "/api/devices/search?q=dba")
+		if err != nil {
+			t.Fatalf("Failed to search devices: %v", err)
+		}
+		devices = nil
+		json.NewDecoder(resp.Body).Decode(&devices)
+		resp.Body.Close()
+		if len(devices) != 1 || devices[0]["name"] != "db-1" {
+			t.Errorf("Expected search to find db-1, got %v", devices)
+		}
+	})
+
+	t.Run("DeleteDefinition", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodDelete, ts.URL()+"/api/custom-fields/"+ownerID, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to delete custom field: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+		}
+
+		resp, err = http.Get(ts.URL() + "/api/devices?field.owner=ops")
+		if err != nil {
+			t.Fatalf("Failed to list devices: %v", err)
+		}
+		var devices []map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&devices)
+		resp.Body.Close()
+		if len(devices) != 0 {
+			t.Errorf("Expected no devices after deleting the field, got %d", len(devices))
+		}
+	})
+}
@@ -0,0 +1,528 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/api"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/scanner"
+	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/martinsuchenak/rackd/internal/worker"
+	"github.com/martinsuchenak/rackd/pkg/discovery"
+)
+
+// blockingScanner runs until its scan is cancelled
+type blockingScanner struct {
+	started chan string
+}
+
+func (s *blockingScanner) ScanNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, updateFunc func(*model.DiscoveryScan)) error {
+	now := time.Now()
+	updateFunc(&model.DiscoveryScan{NetworkID: networkID, Status: "running", ScanType: rule.ScanType, StartedAt: &now})
+	s.started <- networkID
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// resumingScanner scans a fixed list of hosts, recording the ones it skips
+type resumingScanner struct {
+	hosts   []string
+	skipped []string
+}
+
+func (s *resumingScanner) ScanNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, updateFunc func(*model.DiscoveryScan)) error {
+	return s.ResumeNetwork(ctx, networkID, rule, nil, updateFunc)
+}
+
+func (s *resumingScanner) ResumeNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, progress discovery.Progress, updateFunc func(*model.DiscoveryScan)) error {
+	scan := &model.DiscoveryScan{NetworkID: networkID, Status: "running", TotalHosts: len(s.hosts)}
+	for _, ip := range s.hosts {
+		if progress.Done(ip) {
+			s.skipped = append(s.skipped, ip)
+		} else {
+			progress.MarkDone(ip, &model.DiscoveredDevice{IP: ip})
+			scan.FoundHosts++
+		}
+		scan.ScannedHosts++
+	}
+	now := time.Now()
+	scan.Status = "completed"
+	scan.CompletedAt = &now
+	updateFunc(scan)
+	return nil
+}
+
+// steppedScanner waits for release, then stores a device for each of its hosts
+type steppedScanner struct {
+	store   *storage.SQLiteStorage
+	hosts   []string
+	release chan struct{}
+}
+
+func (s *steppedScanner) ScanNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, updateFunc func(*model.DiscoveryScan)) error {
+	return s.ResumeNetwork(ctx, networkID, rule, nil, updateFunc)
+}
+
+func (s *steppedScanner) ResumeNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, progress discovery.Progress, updateFunc func(*model.DiscoveryScan)) error {
+	now := time.Now()
+	scan := &model.DiscoveryScan{NetworkID: networkID, Status: "running", TotalHosts: len(s.hosts), StartedAt: &now}
+	updateFunc(scan)
+	<-s.release
+
+	for _, ip := range s.hosts {
+		device := &model.DiscoveredDevice{IP: ip, NetworkID: networkID, Status: "online", LastSeen: time.Now()}
+		if err := s.store.CreateOrUpdateDiscoveredDevice(device); err != nil {
+			return err
+		}
+		progress.MarkDone(ip, device)
+	}
+	scan.Status = "completed"
+	scan.ScannedHosts = len(s.hosts)
+	scan.FoundHosts = len(s.hosts)
+	updateFunc(scan)
+	return nil
+}
+
+// newScanTestStore returns a store with one network for discovery scans
+func newScanTestStore(t *testing.T) (*storage.SQLiteStorage, *model.Network) {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	network := &model.Network{ID: "scan-net", Name: "scans", Subnet: "10.62.0.0/24", DatacenterID: "default"}
+	if err := store.CreateNetwork(network); err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+	return store, network
+}
+
+// waitForScan waits until the scan has the given status and no stored progress
+func waitForScan(t *testing.T, store *storage.SQLiteStorage, id, status string) *model.DiscoveryScan {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		scan, err := store.GetDiscoveryScan(id)
+		if err != nil {
+			t.Fatalf("Failed to get scan: %v", err)
+		}
+		hosts, _ := store.GetDiscoveryScanHosts(id)
+		if scan.Status == status && len(hosts) == 0 {
+			return scan
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Scan did not reach status %s, got %+v with %d hosts", status, scan, len(hosts))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestAPI_DiscoveryScanCancel tests queueing and cancelling discovery scans
+func TestAPI_DiscoveryScanCancel(t *testing.T) {
+	store, network := newScanTestStore(t)
+	scanner := &blockingScanner{started: make(chan string, 2)}
+	scans := worker.NewScanManager(store, scanner, 1)
+	scans.Start()
+	defer scans.Stop()
+
+	mux := http.NewServeMux()
+	api.NewDiscoveryHandler(store, scans).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	start := func(t *testing.T) string {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/api/discovery/scans", "application/json",
+			strings.NewReader(`{"network_id": "`+network.ID+`", "scan_type": "quick"}`))
+		if err != nil {
+			t.Fatalf("Start scan failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var scan map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&scan)
+		if resp.StatusCode != http.StatusCreated || scan["status"] != "pending" {
+			t.Fatalf("Expected pending scan with status 201, got %d: %v", resp.StatusCode, scan)
+		}
+		return scan["id"].(string)
+	}
+	cancel := func(t *testing.T, id string) (int, map[string]interface{}) {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/api/discovery/scans/"+id+"/cancel", "", nil)
+		if err != nil {
+			t.Fatalf("Cancel scan failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var scan map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&scan)
+		return resp.StatusCode, scan
+	}
+
+	running := start(t)
+	select {
+	case <-scanner.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Scan did not start")
+	}
+	queued := start(t)
+
+	t.Run("Queued", func(t *testing.T) {
+		got, err := store.GetDiscoveryScan(queued)
+		if err != nil || got.Status != "pending" {
+			t.Fatalf("Expected second scan to wait for the limit, got %+v, %v", got, err)
+		}
+		if status, scan := cancel(t, queued); status != http.StatusOK || scan["status"] != "cancelled" {
+			t.Errorf("Expected cancelled scan, got %d: %v", status, scan)
+		}
+	})
+
+	t.Run("Running", func(t *testing.T) {
+		status, scan := cancel(t, running)
+		if status != http.StatusOK || scan["status"] != "cancelled" || scan["completed_at"] == nil {
+			t.Errorf("Expected cancelled scan, got %d: %v", status, scan)
+		}
+	})
+
+	t.Run("NotActive", func(t *testing.T) {
+		if status, scan := cancel(t, running); status != http.StatusConflict {
+			t.Errorf("Expected status 409, got %d: %v", status, scan)
+		}
+		if status, scan := cancel(t, "missing"); status != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d: %v", status, scan)
+		}
+	})
+}
+
+// TestAPI_DiscoveryScanRestart tests picking up scans left running by a restart
+func TestAPI_DiscoveryScanRestart(t *testing.T) {
+	t.Run("Resume", func(t *testing.T) {
+		store, network := newScanTestStore(t)
+		scan := &model.DiscoveryScan{ID: "resume-1", NetworkID: network.ID, Status: "running", ScanType: "quick"}
+		if err := store.CreateDiscoveryScan(scan); err != nil {
+			t.Fatalf("Failed to create scan: %v", err)
+		}
+		if err := store.MarkDiscoveryScanHost(scan.ID, "10.62.0.1", true); err != nil {
+			t.Fatalf("Failed to mark host: %v", err)
+		}
+
+		scanner := &resumingScanner{hosts: []string{"10.62.0.1", "10.62.0.2", "10.62.0.3"}}
+		scans := worker.NewScanManager(store, scanner, 1)
+		scans.Start()
+		defer scans.Stop()
+
+		got := waitForScan(t, store, scan.ID, "completed")
+		if len(scanner.skipped) != 1 || scanner.skipped[0] != "10.62.0.1" {
+			t.Errorf("Expected the finished host to be skipped, got %v", scanner.skipped)
+		}
+		if got.FoundHosts != 3 {
+			t.Errorf("Expected found hosts of both runs, got %d", got.FoundHosts)
+		}
+	})
+
+	t.Run("Interrupted", func(t *testing.T) {
+		store, network := newScanTestStore(t)
+		scan := &model.DiscoveryScan{ID: "interrupted-1", NetworkID: network.ID, Status: "running", ScanType: "quick"}
+		if err := store.CreateDiscoveryScan(scan); err != nil {
+			t.Fatalf("Failed to create scan: %v", err)
+		}
+
+		scans := worker.NewScanManager(store, &blockingScanner{started: make(chan string, 1)}, 1)
+		scans.Start()
+		defer scans.Stop()
+
+		got, err := store.GetDiscoveryScan(scan.ID)
+		if err != nil || got.Status != "interrupted" || got.CompletedAt == nil {
+			t.Errorf("Expected interrupted scan, got %+v, %v", got, err)
+		}
+	})
+}
+
+// TestAPI_DiscoveryScanEvents tests streaming scan progress as Server-Sent Events
+func TestAPI_DiscoveryScanEvents(t *testing.T) {
+	store, network := newScanTestStore(t)
+	known := &model.DiscoveredDevice{IP: "10.62.0.1", NetworkID: network.ID, Status: "offline", LastSeen: time.Now()}
+	if err := store.CreateOrUpdateDiscoveredDevice(known); err != nil {
+		t.Fatalf("Failed to create discovered device: %v", err)
+	}
+
+	scanner := &steppedScanner{store: store, hosts: []string{"10.62.0.1", "10.62.0.2"}, release: make(chan struct{})}
+	scans := worker.NewScanManager(store, scanner, 1)
+	scans.Start()
+	defer scans.Stop()
+
+	mux := http.NewServeMux()
+	api.NewDiscoveryHandler(store, scans).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/discovery/scans", "application/json",
+		strings.NewReader(`{"network_id": "`+network.ID+`", "scan_type": "quick"}`))
+	if err != nil {
+		t.Fatalf("Start scan failed: %v", err)
+	}
+	var scan map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&scan)
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/api/discovery/scans/" + scan["id"].(string) + "/events")
+	if err != nil {
+		t.Fatalf("Events request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("Expected event stream, got %d %q", resp.StatusCode, ct)
+	}
+
+	// Collect the event names and the data of the last event until the stream ends
+	var names []string
+	var last map[string]interface{}
+	reader := bufio.NewScanner(resp.Body)
+	for reader.Scan() {
+		line := reader.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+			if len(names) == 1 {
+				close(scanner.release)
+			}
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			last = nil
+			json.Unmarshal([]byte(data), &last)
+		}
+	}
+
+	count := func(name string) int {
+		n := 0
+		for _, got := range names {
+			if got == name {
+				n++
+			}
+		}
+		return n
+	}
+	if names[0] != "progress" || names[len(names)-1] != "complete" {
+		t.Errorf("Expected stream from progress to complete, got %v", names)
+	}
+	if count("found") != 1 || count("updated") != 1 {
+		t.Errorf("Expected one found and one updated device, got %v", names)
+	}
+	if last["status"] != "completed" || last["found_hosts"] != float64(2) {
+		t.Errorf("Expected completed scan as last event, got %v", last)
+	}
+
+	t.Run("Ended", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/discovery/scans/" + scan["id"].(string) + "/events")
+		if err != nil {
+			t.Fatalf("Events request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if !strings.HasPrefix(string(body), "event: complete\n") {
+			t.Errorf("Expected a single complete event for an ended scan, got %q", body)
+		}
+	})
+}
+
+// sequenceScanner finds the next list of devices on each scan
+type sequenceScanner struct {
+	runs [][]model.DiscoveredDevice
+}
+
+func (s *sequenceScanner) ScanNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, updateFunc func(*model.DiscoveryScan)) error {
+	return s.ResumeNetwork(ctx, networkID, rule, nil, updateFunc)
+}
+
+func (s *sequenceScanner) ResumeNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, progress discovery.Progress, updateFunc func(*model.DiscoveryScan)) error {
+	devices := s.runs[0]
+	s.runs = s.runs[1:]
+
+	now := time.Now()
+	scan := &model.DiscoveryScan{NetworkID: networkID, Status: "running", TotalHosts: len(devices), StartedAt: &now}
+	for i := range devices {
+		progress.MarkDone(devices[i].IP, &devices[i])
+		scan.ScannedHosts++
+		scan.FoundHosts++
+	}
+	scan.Status = "completed"
+	scan.CompletedAt = &now
+	updateFunc(scan)
+	return nil
+}
+
+// TestAPI_DiscoveryScanDiff tests comparing a scan with the previous scan of its network
+func TestAPI_DiscoveryScanDiff(t *testing.T) {
+	store, network := newScanTestStore(t)
+	scanner := &sequenceScanner{runs: [][]model.DiscoveredDevice{
+		{
+			{IP: "10.62.0.1", Status: "online", Hostname: "db1", MACAddress: "00:00:00:00:00:01", OpenPorts: []int{22, 5432}},
+			{IP: "10.62.0.2", Status: "online", OpenPorts: []int{80}},
+		},
+		{
+			{IP: "10.62.0.1", Status: "online", Hostname: "db1", MACAddress: "00:00:00:00:00:99", OpenPorts: []int{22, 5432, 6379}},
+			{IP: "10.62.0.3", Status: "online", OpenPorts: []int{23}},
+		},
+	}}
+	scans := worker.NewScanManager(store, scanner, 1)
+	scans.Start()
+	defer scans.Stop()
+
+	mux := http.NewServeMux()
+	api.NewDiscoveryHandler(store, scans).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	getDiff := func(t *testing.T, id string) (int, *model.DiscoveryScanDiff) {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/api/discovery/scans/" + id + "/diff")
+		if err != nil {
+			t.Fatalf("Diff request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var diff model.DiscoveryScanDiff
+		json.NewDecoder(resp.Body).Decode(&diff)
+		return resp.StatusCode, &diff
+	}
+
+	first := &model.DiscoveryScan{ID: "diff-1", NetworkID: network.ID, ScanType: "quick"}
+	if err := scans.Submit(first, nil); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if got := waitForScan(t, store, first.ID, "completed"); got.Changes == nil || got.Changes.PreviousScanID != "" {
+		t.Errorf("Expected the first scan to be the baseline, got %+v", got.Changes)
+	}
+
+	second := &model.DiscoveryScan{ID: "diff-2", NetworkID: network.ID, ScanType: "quick"}
+	if err := scans.Submit(second, nil); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	got := waitForScan(t, store, second.ID, "completed")
+	want := model.DiscoveryChangeSummary{PreviousScanID: first.ID, NewHosts: 1, DisappearedHosts: 1, ChangedHosts: 1, OpenedPorts: 1}
+	if got.Changes == nil || *got.Changes != want {
+		t.Errorf("Expected change summary %+v on completion, got %+v", want, got.Changes)
+	}
+
+	status, diff := getDiff(t, second.ID)
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if len(diff.NewHosts) != 1 || diff.NewHosts[0].IP != "10.62.0.3" {
+		t.Errorf("Expected 10.62.0.3 to be new, got %+v", diff.NewHosts)
+	}
+	if len(diff.DisappearedHosts) != 1 || diff.DisappearedHosts[0].IP != "10.62.0.2" {
+		t.Errorf("Expected 10.62.0.2 to have disappeared, got %+v", diff.DisappearedHosts)
+	}
+	if len(diff.ChangedHosts) != 1 || len(diff.ChangedHosts[0].OpenedPorts) != 1 || diff.ChangedHosts[0].OpenedPorts[0] != 6379 ||
+		len(diff.ChangedHosts[0].Fields) != 1 || diff.ChangedHosts[0].Fields[0].Field != "mac_address" {
+		t.Errorf("Expected a new port and MAC on 10.62.0.1, got %+v", diff.ChangedHosts)
+	}
+
+	t.Run("NotCompleted", func(t *testing.T) {
+		running := &model.DiscoveryScan{ID: "diff-running", NetworkID: network.ID, Status: "running", ScanType: "quick"}
+		if err := store.CreateDiscoveryScan(running); err != nil {
+			t.Fatalf("Failed to create scan: %v", err)
+		}
+		if status, _ := getDiff(t, running.ID); status != http.StatusConflict {
+			t.Errorf("Expected status 409, got %d", status)
+		}
+		if status, _ := getDiff(t, "missing"); status != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", status)
+		}
+	})
+}
+
+// storingScanner stores the devices it finds without reporting hosts one by one
+type storingScanner struct {
+	store *storage.SQLiteStorage
+	hosts []string
+}
+
+func (s *storingScanner) ScanNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, updateFunc func(*model.DiscoveryScan)) error {
+	for _, ip := range s.hosts {
+		device := &model.DiscoveredDevice{IP: ip, NetworkID: networkID, Status: "online", OpenPorts: []int{22}, LastSeen: time.Now()}
+		if err := s.store.CreateOrUpdateDiscoveredDevice(device); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	updateFunc(&model.DiscoveryScan{NetworkID: networkID, Status: "completed", TotalHosts: len(s.hosts), ScannedHosts: len(s.hosts), FoundHosts: len(s.hosts), CompletedAt: &now})
+	return nil
+}
+
+// TestAPI_DiscoveryScanObservations tests that scanners without per-host progress still record observations
+func TestAPI_DiscoveryScanObservations(t *testing.T) {
+	store, network := newScanTestStore(t)
+	stale := &model.DiscoveredDevice{IP: "10.62.0.99", NetworkID: network.ID, Status: "online", LastSeen: time.Now().Add(-time.Hour)}
+	if err := store.CreateOrUpdateDiscoveredDevice(stale); err != nil {
+		t.Fatalf("Failed to create discovered device: %v", err)
+	}
+
+	scans := worker.NewScanManager(store, &storingScanner{store: store, hosts: []string{"10.62.0.1", "10.62.0.2"}}, 1)
+	scans.Start()
+	defer scans.Stop()
+
+	scan := &model.DiscoveryScan{ID: "observed-1", NetworkID: network.ID, ScanType: "full"}
+	if err := scans.Submit(scan, nil); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	waitForScan(t, store, scan.ID, "completed")
+
+	observations, err := store.ListDiscoveryObservations(scan.ID)
+	if err != nil {
+		t.Fatalf("Failed to list observations: %v", err)
+	}
+	if len(observations) != 2 || observations[0].IP != "10.62.0.1" || observations[1].IP != "10.62.0.2" || len(observations[0].OpenPorts) != 1 {
+		t.Errorf("Expected observations of the two scanned hosts only, got %+v", observations)
+	}
+}
+
+// TestAPI_DiscoveryRuleProbes tests that rules only turn on or off probes the scanner runs
+func TestAPI_DiscoveryRuleProbes(t *testing.T) {
+	store, network := newScanTestStore(t)
+	scans := worker.NewScanManager(store, scanner.NewDiscoveryScanner(store), 1)
+
+	mux := http.NewServeMux()
+	api.NewDiscoveryHandler(store, scans).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	send := func(method, path, body string) (int, map[string]interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	status, result := send("POST", "/api/discovery/rules", `{"network_id": "`+network.ID+`", "probes": {"snmp": true, "ports": false}}`)
+	if status != http.StatusBadRequest || !strings.Contains(result["error"].(string), "snmp") {
+		t.Fatalf("Expected 400 naming the unknown probe, got %d: %v", status, result)
+	}
+
+	status, created := send("POST", "/api/discovery/rules", `{"network_id": "`+network.ID+`", "probes": {"ports": false}}`)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %v", status, created)
+	}
+	path := "/api/discovery/rules/" + created["id"].(string)
+
+	if status, result := send("PATCH", path, `{"probes": {"os": false}}`); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a probe of another scanner, got %d: %v", status, result)
+	}
+	if status, result := send("PATCH", path, `{"probes": {"hostname": false}}`); status != http.StatusOK {
+		t.Errorf("Expected 200, got %d: %v", status, result)
+	}
+}
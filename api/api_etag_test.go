@@ -0,0 +1,145 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestAPI_ETags tests optimistic concurrency control with ETag and If-Match
+func TestAPI_ETags(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	do := func(t *testing.T, method, path, ifMatch, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL()+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp, err := http.Post(ts.URL()+"/api/devices", "application/json", bytes.NewReader(DeviceJSON("etag-1", nil)))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	var device map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&device)
+	resp.Body.Close()
+	if etag := resp.Header.Get("ETag"); etag != `"1"` {
+		t.Errorf("Expected ETag \"1\" on create, got %q", etag)
+	}
+	path := "/api/devices/" + device["id"].(string)
+
+	t.Run("Get", func(t *testing.T) {
+		resp := do(t, http.MethodGet, path, "", "")
+		if etag := resp.Header.Get("ETag"); etag != `"1"` {
+			t.Errorf("Expected ETag \"1\", got %q", etag)
+		}
+	})
+
+	t.Run("UpdateMatching", func(t *testing.T) {
+		resp := do(t, http.MethodPut, path, `"1"`, `{"name": "etag-1", "description": "first"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if etag := resp.Header.Get("ETag"); etag != `"2"` {
+			t.Errorf("Expected ETag \"2\" after update, got %q", etag)
+		}
+	})
+
+	t.Run("UpdateStale", func(t *testing.T) {
+		resp := do(t, http.MethodPut, path, `"1"`, `{"name": "etag-1", "description": "second"}`)
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			t.Fatalf("Expected status 412, got %d", resp.StatusCode)
+		}
+		resp = do(t, http.MethodPut, path, "", `{"name": "etag-1", "description": "second", "version": 1}`)
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			t.Errorf("Expected status 412 for stale version in body, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("InvalidIfMatch", func(t *testing.T) {
+		resp := do(t, http.MethodPut, path, `"1", "2"`, `{"name": "etag-1"}`)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("WeakIfMatch", func(t *testing.T) {
+		// If-Match compares strongly, so a weak ETag of the current version fails
+		if resp := do(t, http.MethodPut, path, `W/"2"`, `{"name": "etag-1"}`); resp.StatusCode != http.StatusPreconditionFailed {
+			t.Errorf("Expected status 412 for a weak ETag, got %d", resp.StatusCode)
+		}
+		if resp := do(t, http.MethodDelete, path, `W/"2"`, ""); resp.StatusCode != http.StatusPreconditionFailed {
+			t.Errorf("Expected status 412 for a weak ETag on delete, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("StatusChange", func(t *testing.T) {
+		resp, err := http.Post(ts.URL()+"/api/devices", "application/json", bytes.NewReader(DeviceJSON("etag-2", nil)))
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		var created map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&created)
+		resp.Body.Close()
+		devicePath := "/api/devices/" + created["id"].(string)
+
+		// The ETag returned by an update changing the status is that of the
+		// stored device, so the next update can use it
+		resp = do(t, http.MethodPut, devicePath, `"1"`, `{"name": "etag-2", "status": "maintenance"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		etag := resp.Header.Get("ETag")
+		resp = do(t, http.MethodPut, devicePath, etag, `{"name": "etag-2", "description": "after status"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200 updating with ETag %s, got %d", etag, resp.StatusCode)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		resp := do(t, http.MethodDelete, path, `"1"`, "")
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			t.Fatalf("Expected status 412 for stale delete, got %d", resp.StatusCode)
+		}
+		resp = do(t, http.MethodDelete, path, `"2"`, "")
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Datacenter", func(t *testing.T) {
+		resp, err := http.Post(ts.URL()+"/api/datacenters", "application/json", strings.NewReader(`{"name": "ETag DC"}`))
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		var dc map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&dc)
+		resp.Body.Close()
+		dcPath := "/api/datacenters/" + dc["id"].(string)
+
+		if resp := do(t, http.MethodPut, dcPath, `"1"`, `{"name": "ETag DC", "location": "Berlin"}`); resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if resp := do(t, http.MethodPut, dcPath, `"1"`, `{"name": "ETag DC", "location": "Paris"}`); resp.StatusCode != http.StatusPreconditionFailed {
+			t.Errorf("Expected status 412, got %d", resp.StatusCode)
+		}
+		if resp := do(t, http.MethodDelete, dcPath, `"1"`, ""); resp.StatusCode != http.StatusPreconditionFailed {
+			t.Errorf("Expected status 412 for stale delete, got %d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestAPI_ExportRestore tests that an export restores into a fresh server unchanged
+func TestAPI_ExportRestore(t *testing.T) {
+	source := NewTestServer(t)
+	defer source.Close()
+	target := NewTestServer(t)
+	defer target.Close()
+
+	send := func(t *testing.T, ts *TestServer, method, path string, body []byte) (int, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL()+path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, data
+	}
+
+	status, _ := send(t, source, http.MethodPost, "/api/custom-fields",
+		[]byte(`{"entity_type": "device", "name": "owner", "type": "string", "required": true}`))
+	if status != http.StatusCreated {
+		t.Fatalf("Expected custom field to be created, got %d", status)
+	}
+	status, data := send(t, source, http.MethodPost, "/api/import", []byte(`{
+		"datacenters": [{"name": "DC-East"}],
+		"networks": [{"name": "Prod", "subnet": "10.9.0.0/24", "datacenter": "DC-East", "labels": {"env": "prod"}}],
+		"pools": [{"name": "Servers", "network": "Prod", "start_ip": "10.9.0.10", "end_ip": "10.9.0.50"}],
+		"devices": [
+			{"name": "web-1", "datacenter": "DC-East", "status": "maintenance", "tags": ["web"],
+			 "custom_fields": {"owner": "ops"}, "asset": {"serial_number": "SN1"},
+			 "addresses": [{"ip": "10.9.0.10", "type": "ipv4", "network": "Prod", "pool": "Servers"}]},
+			{"name": "db-1", "datacenter": "DC-East", "custom_fields": {"owner": "dba"}}
+		],
+		"relationships": [{"parent": "web-1", "child": "db-1", "type": "depends_on"}]
+	}`))
+	if status != http.StatusOK {
+		t.Fatalf("Expected import to succeed, got %d: %s", status, data)
+	}
+	status, data = send(t, source, http.MethodPut, "/api/devices/web-1/software",
+		[]byte(`{"os": {"family": "linux"}, "packages": [{"name": "openssl", "version": "3.0.2"}]}`))
+	if status != http.StatusOK {
+		t.Fatalf("Expected software to be stored, got %d: %s", status, data)
+	}
+
+	var export map[string]interface{}
+
+	t.Run("Export", func(t *testing.T) {
+		resp, err := http.Get(source.URL() + "/api/export")
+		if err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") {
+			t.Errorf("Expected attachment, got %q", resp.Header.Get("Content-Disposition"))
+		}
+		json.NewDecoder(resp.Body).Decode(&export)
+
+		if export["version"] != float64(1) {
+			t.Errorf("Expected version 1, got %v", export["version"])
+		}
+		for section, want := range map[string]int{"custom_fields": 1, "datacenters": 2, "networks": 1, "pools": 1, "devices": 2, "relationships": 1, "software": 1} {
+			if got := len(export[section].([]interface{})); got != want {
+				t.Errorf("Expected %d %s, got %d", want, section, got)
+			}
+		}
+	})
+
+	body, _ := json.Marshal(export)
+
+	t.Run("Restore", func(t *testing.T) {
+		status, data := send(t, target, http.MethodPost, "/api/restore", body)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", status, data)
+		}
+		var result map[string]interface{}
+		json.Unmarshal(data, &result)
+		if result["devices"] != float64(2) || result["relationships"] != float64(1) {
+			t.Errorf("Unexpected restore result %v", result)
+		}
+
+		// Exporting the restored inventory yields the same data
+		_, data = send(t, target, http.MethodGet, "/api/export", nil)
+		var restored map[string]interface{}
+		json.Unmarshal(data, &restored)
+		delete(export, "exported_at")
+		delete(restored, "exported_at")
+		// Required custom fields are re-marked at the end of a restore, which bumps their update time
+		for _, doc := range []map[string]interface{}{export, restored} {
+			for _, field := range doc["custom_fields"].([]interface{}) {
+				delete(field.(map[string]interface{}), "updated_at")
+			}
+		}
+		if !reflect.DeepEqual(export, restored) {
+			for section := range export {
+				if !reflect.DeepEqual(export[section], restored[section]) {
+					t.Errorf("Section %s differs after restore:\n got %v\nwant %v", section, restored[section], export[section])
+				}
+			}
+		}
+	})
+
+	t.Run("RequiresReplace", func(t *testing.T) {
+		status, _ := send(t, target, http.MethodPost, "/api/restore", body)
+		if status != http.StatusConflict {
+			t.Errorf("Expected status 409 without replace, got %d", status)
+		}
+		status, data := send(t, target, http.MethodPost, "/api/restore?replace=true", body)
+		if status != http.StatusOK {
+			t.Errorf("Expected status 200 with replace, got %d: %s", status, data)
+		}
+	})
+
+	t.Run("InvalidExport", func(t *testing.T) {
+		status, data := send(t, target, http.MethodPost, "/api/restore?replace=true", []byte(`{"version": 99}`))
+		if status != http.StatusBadRequest || !strings.Contains(string(data), "unsupported version") {
+			t.Errorf("Expected unsupported version error, got %d: %s", status, data)
+		}
+
+		status, data = send(t, target, http.MethodPost, "/api/restore?replace=true", []byte(`{
+			"version": 1,
+			"networks": [{"id": "n1", "name": "Lab", "subnet": "10.8.0.0/24", "datacenter_id": "missing"}],
+			"pools": [{"id": "p1", "name": "Pool", "network_id": "n2", "start_ip": "10.8.0.1", "end_ip": "10.8.0.9"}]
+		}`))
+		if status != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d", status)
+		}
+		for _, want := range []string{`unknown datacenter \"missing\"`, `unknown network \"n2\"`} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("Expected %q in error, got %s", want, data)
+			}
+		}
+
+		// A rejected restore leaves the inventory untouched
+		status, _ = send(t, target, http.MethodGet, "/api/devices/web-1", nil)
+		if status != http.StatusOK {
+			t.Errorf("Expected web-1 to survive a rejected restore, got %d", status)
+		}
+	})
+}
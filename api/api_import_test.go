@@ -0,0 +1,169 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestAPI_Import tests bulk imports with upserts, name references and dry runs
+func TestAPI_Import(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	importData := func(t *testing.T, query, contentType, body string) (int, map[string]interface{}) {
+		t.Helper()
+		resp, err := http.Post(ts.URL()+"/api/import?"+query, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	getJSON := func(t *testing.T, path string, v interface{}) {
+		t.Helper()
+		resp, err := http.Get(ts.URL() + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d", path, resp.StatusCode)
+		}
+		json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	document := `{
+		"datacenters": [{"name": "DC-East", "location": "New York"}],
+		"networks": [{"name": "Prod", "subnet": "10.9.0.0/24", "datacenter": "DC-East"}],
+		"pools": [{"name": "Servers", "network": "Prod", "start_ip": "10.9.0.10", "end_ip": "10.9.0.50"}],
+		"devices": [
+			{"name": "web-1", "datacenter": "DC-East", "labels": {"role": "web"},
+			 "addresses": [{"ip": "10.9.0.10", "type": "ipv4", "network": "Prod", "pool": "Servers"}]},
+			{"name": "db-1", "datacenter": "DC-East"}
+		],
+		"relationships": [{"parent": "web-1", "child": "db-1", "type": "depends_on"}]
+	}`
+
+	t.Run("DryRun", func(t *testing.T) {
+		status, result := importData(t, "dry_run=true", "application/json", document)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %v", status, result)
+		}
+		if result["created"] != float64(6) || result["committed"] != false {
+			t.Errorf("Expected 6 creates without commit, got %v", result)
+		}
+
+		var datacenters []map[string]interface{}
+		getJSON(t, "/api/datacenters?name=DC-East", &datacenters)
+		if len(datacenters) != 0 {
+			t.Errorf("Expected dry run not to create DC-East, got %v", datacenters)
+		}
+	})
+
+	var deviceID string
+
+	t.Run("Commit", func(t *testing.T) {
+		status, result := importData(t, "", "application/json", document)
+		if status != http.StatusOK || result["committed"] != true {
+			t.Fatalf("Expected committed import, got %d: %v", status, result)
+		}
+
+		var devices []map[string]interface{}
+		getJSON(t, "/api/devices?selector=role=web", &devices)
+		if len(devices) != 1 {
+			t.Fatalf("Expected web-1 to be imported, got %v", devices)
+		}
+		deviceID = devices[0]["id"].(string)
+		addresses := devices[0]["addresses"].([]interface{})
+		addr := addresses[0].(map[string]interface{})
+		if addr["network_id"] == nil || addr["pool_id"] == nil || devices[0]["datacenter_id"] == nil {
+			t.Errorf("Expected references to be resolved by name, got %v", devices[0])
+		}
+
+		var related []map[string]interface{}
+		getJSON(t, "/api/devices/"+deviceID+"/related", &related)
+		if len(related) != 1 {
+			t.Errorf("Expected one related device, got %d", len(related))
+		}
+
+		// Importing the same document again changes nothing
+		status, result = importData(t, "", "application/json", document)
+		if status != http.StatusOK || result["unchanged"] != float64(6) {
+			t.Errorf("Expected re-import to leave 6 rows unchanged, got %d: %v", status, result)
+		}
+	})
+
+	t.Run("CSVUpsertWithMapping", func(t *testing.T) {
+		csv := "hostname,description,ip,pool,tags,labels.env,asset.cost\n" +
+			"web-1,Front end,10.9.0.10,Servers,a;b,prod,\n" +
+			"web-2,,10.9.0.11,Servers,,prod,1200.50\n"
+		status, result := importData(t, "entity=devices&mapping=hostname=name", "text/csv", csv)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %v", status, result)
+		}
+		rows := result["rows"].([]interface{})
+		if rows[0].(map[string]interface{})["action"] != "update" || rows[1].(map[string]interface{})["action"] != "create" {
+			t.Errorf("Expected update then create, got %v", rows)
+		}
+
+		var device map[string]interface{}
+		getJSON(t, "/api/devices/"+deviceID, &device)
+		if device["description"] != "Front end" || len(device["tags"].([]interface{})) != 2 {
+			t.Errorf("Expected web-1 to be updated, got %v", device)
+		}
+		if labels := device["labels"].(map[string]interface{}); labels["env"] != "prod" {
+			t.Errorf("Expected labels to be replaced, got %v", labels)
+		}
+	})
+
+	t.Run("FailedRowRollsBack", func(t *testing.T) {
+		yaml := "- name: app-1\n- name: app-2\n  addresses:\n    - ip: not-an-ip\n"
+		status, result := importData(t, "format=yaml&entity=device", "", yaml)
+		if status != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status 422, got %d", status)
+		}
+		if result["failed"] != float64(1) || result["committed"] != false {
+			t.Errorf("Expected one failed row and no commit, got %v", result)
+		}
+		row := result["rows"].([]interface{})[1].(map[string]interface{})
+		if row["row"] != float64(2) || !strings.Contains(row["error"].(string), "invalid IP address") {
+			t.Errorf("Expected row 2 to report the invalid IP, got %v", row)
+		}
+
+		resp, err := http.Get(ts.URL() + "/api/devices/app-1")
+		if err != nil {
+			t.Fatalf("Failed to get device: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected app-1 not to be created, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("UnknownReference", func(t *testing.T) {
+		status, result := importData(t, "entity=network", "application/json", `[{"name": "Lab", "subnet": "10.8.0.0/24", "datacenter": "DC-West"}]`)
+		if status != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status 422, got %d", status)
+		}
+		row := result["rows"].([]interface{})[0].(map[string]interface{})
+		if !strings.Contains(row["error"].(string), `datacenter "DC-West" not found`) {
+			t.Errorf("Expected unknown datacenter error, got %v", row["error"])
+		}
+	})
+
+	t.Run("InvalidInput", func(t *testing.T) {
+		status, _ := importData(t, "format=xml", "", "<devices/>")
+		if status != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for unknown format, got %d", status)
+		}
+		status, _ = importData(t, "", "text/csv", "name\nx\n")
+		if status != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for CSV without entity, got %d", status)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/api"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/martinsuchenak/rackd/internal/worker"
+)
+
+// waitForJob waits until the job has the given status
+func waitForJob(t *testing.T, store storage.JobStorage, id, status string) *model.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := store.GetJob(id)
+		if err != nil {
+			t.Fatalf("Failed to get job: %v", err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job did not reach status %s, got %+v", status, job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// countLogs returns the number of log lines of a job starting with prefix
+func countLogs(job *model.Job, prefix string) int {
+	n := 0
+	for _, entry := range job.Logs {
+		if strings.HasPrefix(entry.Message, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// TestAPI_Jobs tests queueing, running, retrying and listing background jobs
+func TestAPI_Jobs(t *testing.T) {
+	store, network := newScanTestStore(t)
+	scans := worker.NewScanManager(store, &resumingScanner{hosts: []string{"10.62.0.5", "10.62.0.6"}}, 1)
+	scans.Start()
+	defer scans.Stop()
+
+	var flakyCalls atomic.Int32
+	jobs := worker.NewJobManager(store, 2, 10*time.Millisecond)
+	jobs.Register(model.JobTypeDiscovery, worker.DiscoveryJob(scans), 3)
+	jobs.Register(model.JobTypeCleanup, worker.CleanupJob(store, 30), 3)
+	jobs.Register(model.JobTypeImport, worker.ImportJob(store), 1)
+	jobs.Register("flaky", func(ctx context.Context, run *worker.JobRun) (interface{}, error) {
+		if flakyCalls.Add(1) < 3 {
+			return nil, errors.New("not yet")
+		}
+		return map[string]string{"outcome": "done"}, nil
+	}, 3)
+	jobs.Register("broken", func(ctx context.Context, run *worker.JobRun) (interface{}, error) {
+		return nil, errors.New("always broken")
+	}, 2)
+	jobs.Start()
+	defer jobs.Stop()
+
+	mux := http.NewServeMux()
+	api.NewJobHandler(store, jobs).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	send := func(method, path string, body interface{}) (int, *model.Job) {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var job model.Job
+		json.NewDecoder(resp.Body).Decode(&job)
+		return resp.StatusCode, &job
+	}
+	enqueue := func(jobType string, params interface{}) *model.Job {
+		t.Helper()
+		status, job := send("POST", "/api/jobs", map[string]interface{}{"type": jobType, "params": params})
+		if status != http.StatusAccepted || job.ID == "" || job.Status != model.JobStatusPending {
+			t.Fatalf("Expected %s job to be queued, got %d: %+v", jobType, status, job)
+		}
+		return job
+	}
+
+	t.Run("UnknownType", func(t *testing.T) {
+		if status, _ := send("POST", "/api/jobs", map[string]interface{}{"type": "teleport"}); status != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown job type, got %d", status)
+		}
+		if status, _ := send("GET", "/api/jobs/missing", nil); status != http.StatusNotFound {
+			t.Errorf("Expected 404 for a missing job, got %d", status)
+		}
+	})
+
+	t.Run("Discovery", func(t *testing.T) {
+		job := enqueue(model.JobTypeDiscovery, map[string]string{"network_id": network.ID, "scan_type": "quick"})
+		job = waitForJob(t, store, job.ID, model.JobStatusCompleted)
+
+		var scan model.DiscoveryScan
+		if err := json.Unmarshal(job.Result, &scan); err != nil {
+			t.Fatalf("Failed to decode job result: %v", err)
+		}
+		if scan.Status != "completed" || scan.FoundHosts != 2 || scan.NetworkID != network.ID {
+			t.Errorf("Expected the completed scan as result, got %+v", scan)
+		}
+		if countLogs(job, "Started quick discovery scan") != 1 {
+			t.Errorf("Expected the scan to be logged, got %+v", job.Logs)
+		}
+	})
+
+	t.Run("Cleanup", func(t *testing.T) {
+		old := time.Now().AddDate(0, 0, -60)
+		for _, ip := range []string{"10.62.0.50", "10.62.0.51"} {
+			device := &model.DiscoveredDevice{IP: ip, NetworkID: network.ID, Status: "offline", FirstSeen: old, LastSeen: old}
+			if err := store.CreateOrUpdateDiscoveredDevice(device); err != nil {
+				t.Fatalf("Failed to create discovered device: %v", err)
+			}
+		}
+
+		job := enqueue(model.JobTypeCleanup, nil)
+		job = waitForJob(t, store, job.ID, model.JobStatusCompleted)
+		var result worker.CleanupResult
+		json.Unmarshal(job.Result, &result)
+		if result.Removed != 2 || result.OlderThanDays != 30 {
+			t.Errorf("Expected 2 old devices removed, got %+v", result)
+		}
+
+		// Invalid parameters fail the job without retries
+		job = enqueue(model.JobTypeCleanup, map[string]int{"older_than_days": -1})
+		job = waitForJob(t, store, job.ID, model.JobStatusFailed)
+		if job.Attempts != 1 || !strings.Contains(job.Error, "older_than_days") {
+			t.Errorf("Expected a single failed attempt, got %+v", job)
+		}
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		job := enqueue(model.JobTypeImport, map[string]string{
+			"format": "csv", "entity": "devices", "data": "name,description\nimported-1,from a job\n",
+		})
+		waitForJob(t, store, job.ID, model.JobStatusCompleted)
+		if _, err := store.GetDevice("imported-1"); err != nil {
+			t.Errorf("Expected the device to be imported: %v", err)
+		}
+	})
+
+	t.Run("RetryWithBackoff", func(t *testing.T) {
+		job := enqueue("flaky", nil)
+		job = waitForJob(t, store, job.ID, model.JobStatusCompleted)
+		if job.Attempts != 3 || countLogs(job, "Attempt failed: not yet") != 2 || string(job.Result) != `{"outcome":"done"}` {
+			t.Errorf("Expected success on the third attempt, got %+v", job)
+		}
+		if job.Error != "" {
+			t.Errorf("Expected no error on a completed job, got %q", job.Error)
+		}
+	})
+
+	t.Run("ManualRetry", func(t *testing.T) {
+		job := enqueue("broken", nil)
+		job = waitForJob(t, store, job.ID, model.JobStatusFailed)
+		if job.Attempts != 2 || job.Error != "always broken" || job.FinishedAt == nil {
+			t.Fatalf("Expected the job to fail after 2 attempts, got %+v", job)
+		}
+
+		status, retried := send("POST", "/api/jobs/"+job.ID+"/retry", nil)
+		if status != http.StatusAccepted || retried.Status != model.JobStatusPending || retried.Attempts != 0 {
+			t.Fatalf("Expected the job to be queued again, got %d: %+v", status, retried)
+		}
+		job = waitForJob(t, store, job.ID, model.JobStatusFailed)
+		if job.Attempts != 2 || countLogs(job, "Retry requested") != 1 || countLogs(job, "Failed: always broken") != 2 {
+			t.Errorf("Expected a second round of attempts, got %+v", job.Logs)
+		}
+
+		// Only failed jobs can be retried
+		completed, _ := store.ListJobs(&model.JobFilter{Status: model.JobStatusCompleted, Limit: 1})
+		if status, _ := send("POST", "/api/jobs/"+completed[0].ID+"/retry", nil); status != http.StatusConflict {
+			t.Errorf("Expected 409 retrying a completed job, got %d", status)
+		}
+		if status, _ := send("POST", "/api/jobs/missing/retry", nil); status != http.StatusNotFound {
+			t.Errorf("Expected 404 retrying a missing job, got %d", status)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/jobs?type=cleanup")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var listed []model.Job
+		json.NewDecoder(resp.Body).Decode(&listed)
+		if len(listed) != 2 || listed[0].Status != model.JobStatusFailed || listed[1].Status != model.JobStatusCompleted {
+			t.Errorf("Expected the cleanup jobs newest first, got %+v", listed)
+		}
+
+		resp, err = http.Get(server.URL + "/api/jobs?status=failed&limit=1")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		defer resp.Body.Close()
+		listed = nil
+		json.NewDecoder(resp.Body).Decode(&listed)
+		if len(listed) != 1 || listed[0].Status != model.JobStatusFailed {
+			t.Errorf("Expected one failed job, got %+v", listed)
+		}
+	})
+}
+
+// TestAPI_JobsResume tests that jobs cut short by a restart run again
+func TestAPI_JobsResume(t *testing.T) {
+	store, _ := newScanTestStore(t)
+	started := time.Now().Add(-time.Minute)
+	job := &model.Job{Type: model.JobTypeCleanup, Status: model.JobStatusRunning, Attempts: 1, MaxAttempts: 3, StartedAt: &started}
+	if err := store.CreateJob(job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	jobs := worker.NewJobManager(store, 1, time.Millisecond)
+	jobs.Register(model.JobTypeCleanup, worker.CleanupJob(store, 30), 3)
+	jobs.Start()
+	defer jobs.Stop()
+
+	job = waitForJob(t, store, job.ID, model.JobStatusCompleted)
+	if job.Attempts != 2 || countLogs(job, "Interrupted by a server restart") != 1 {
+		t.Errorf("Expected the interrupted job to run again, got %+v", job)
+	}
+}
+
+// TestAPI_JobsLeased tests that jobs another instance holds the lease of are left to it
+func TestAPI_JobsLeased(t *testing.T) {
+	store, _ := newScanTestStore(t)
+	started := time.Now().Add(-time.Minute)
+	running := &model.Job{Type: model.JobTypeCleanup, Status: model.JobStatusRunning, Attempts: 1, MaxAttempts: 3, StartedAt: &started}
+	pending := &model.Job{Type: model.JobTypeCleanup, Status: model.JobStatusPending, MaxAttempts: 3}
+	for _, job := range []*model.Job{running, pending} {
+		if err := store.CreateJob(job); err != nil {
+			t.Fatalf("Failed to create job: %v", err)
+		}
+		if ok, err := store.AcquireLease("job:"+job.ID, "other-instance", time.Minute); err != nil || !ok {
+			t.Fatalf("Failed to acquire lease: %v, %v", ok, err)
+		}
+	}
+
+	jobs := worker.NewJobManager(store, 1, time.Millisecond)
+	jobs.Register(model.JobTypeCleanup, worker.CleanupJob(store, 30), 3)
+	jobs.Start()
+	defer jobs.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	for _, want := range []*model.Job{running, pending} {
+		got, err := store.GetJob(want.ID)
+		if err != nil {
+			t.Fatalf("Failed to get job: %v", err)
+		}
+		if got.Status != want.Status || got.Attempts != want.Attempts {
+			t.Errorf("Expected the leased job to be left alone, got %+v", got)
+		}
+	}
+
+	// Once the other instance lets go of it, the pending job runs here
+	if err := store.ReleaseLease("job:"+pending.ID, "other-instance"); err != nil {
+		t.Fatalf("Failed to release lease: %v", err)
+	}
+	if _, err := jobs.Enqueue(model.JobTypeCleanup, nil); err != nil {
+		t.Fatalf("Failed to enqueue job: %v", err)
+	}
+	waitForJob(t, store, pending.ID, model.JobStatusCompleted)
+}
+
+// TestAPI_JobsShutdown tests that a job stopped by a shutdown is left pending
+func TestAPI_JobsShutdown(t *testing.T) {
+	store, _ := newScanTestStore(t)
+	running := make(chan struct{})
+	jobs := worker.NewJobManager(store, 1, time.Millisecond)
+	jobs.Register("slow", func(ctx context.Context, run *worker.JobRun) (interface{}, error) {
+		close(running)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, 1)
+	jobs.Start()
+
+	job, err := jobs.Enqueue("slow", nil)
+	if err != nil {
+		t.Fatalf("Failed to enqueue job: %v", err)
+	}
+	select {
+	case <-running:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Job did not start")
+	}
+	jobs.Stop()
+
+	stored, err := store.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if stored.Status != model.JobStatusPending || stored.Attempts != 0 || countLogs(stored, "Interrupted by shutdown") != 1 {
+		t.Errorf("Expected the job to be pending again without using an attempt, got %+v", stored)
+	}
+}
@@ -0,0 +1,165 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// TestAPI_Labels tests labels, label selectors and the tag migration on devices
+func TestAPI_Labels(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	postJSON := func(t *testing.T, path string, payload interface{}) (*http.Response, map[string]interface{}) {
+		t.Helper()
+		data, _ := json.Marshal(payload)
+		resp, err := http.Post(ts.URL()+path, "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("POST %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &result)
+		return resp, result
+	}
+
+	listNames := func(t *testing.T, selector string) []string {
+		t.Helper()
+		resp, err := http.Get(ts.URL() + "/api/devices?" + url.Values{"selector": {selector}}.Encode())
+		if err != nil {
+			t.Fatalf("Failed to list devices: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200 for selector %q, got %d", selector, resp.StatusCode)
+		}
+		var devices []map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&devices)
+		names := make([]string, 0, len(devices))
+		for _, d := range devices {
+			names = append(names, d["name"].(string))
+		}
+		return names
+	}
+
+	t.Run("CreateWithLabels", func(t *testing.T) {
+		for _, d := range []struct {
+			name   string
+			labels map[string]string
+		}{
+			{"db-1", map[string]string{"env": "prod", "role": "db"}},
+			{"cache-1", map[string]string{"env": "prod", "role": "cache", "decom": ""}},
+			{"web-1", map[string]string{"env": "prod", "role": "web"}},
+			{"db-2", map[string]string{"env": "dev", "role": "db"}},
+		} {
+			resp, device := postJSON(t, "/api/devices", map[string]interface{}{
+				"name":   d.name,
+				"labels": d.labels,
+			})
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+			}
+			labels, _ := device["labels"].(map[string]interface{})
+			if labels["role"] != d.labels["role"] {
+				t.Errorf("Expected role %s in response, got %v", d.labels["role"], labels["role"])
+			}
+		}
+	})
+
+	t.Run("RejectInvalidLabel", func(t *testing.T) {
+		resp, _ := postJSON(t, "/api/devices", map[string]interface{}{
+			"name":   "bad-label",
+			"labels": map[string]string{"bad key": "x"},
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for invalid label, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("FilterBySelector", func(t *testing.T) {
+		tests := []struct {
+			selector string
+			want     int
+		}{
+			{"env=prod", 3},
+			{"env=prod,role in (db,cache),!decom", 1},
+			{"role notin (db)", 2},
+			{"decom", 1},
+			{"env!=prod", 1},
+		}
+		for _, tt := range tests {
+			if names := listNames(t, tt.selector); len(names) != tt.want {
+				t.Errorf("Selector %q matched %v, want %d devices", tt.selector, names, tt.want)
+			}
+		}
+
+		if names := listNames(t, "env=prod,role in (db,cache),!decom"); len(names) != 1 || names[0] != "db-1" {
+			t.Errorf("Expected only db-1 to match, got %v", names)
+		}
+	})
+
+	t.Run("RejectInvalidSelector", func(t *testing.T) {
+		resp, err := http.Get(ts.URL() + "/api/devices?" + url.Values{"selector": {"role in (db"}}.Encode())
+		if err != nil {
+			t.Fatalf("Failed to list devices: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for invalid selector, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("MigrateTags", func(t *testing.T) {
+		resp, _ := postJSON(t, "/api/devices", map[string]interface{}{
+			"name":   "tagged",
+			"tags":   []string{"env=staging", "team=core", "web"},
+			"labels": map[string]string{"team": "ops"},
+		})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+
+		resp, result := postJSON(t, "/api/labels/migrate-tags?dry_run=true", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		devices, _ := result["devices"].([]interface{})
+		if len(devices) != 1 {
+			t.Fatalf("Expected one device to migrate, got %v", result["devices"])
+		}
+		change := devices[0].(map[string]interface{})
+		if labels, _ := change["labels"].(map[string]interface{}); labels["env"] != "staging" {
+			t.Errorf("Expected env=staging to be migrated, got %v", change["labels"])
+		}
+		if skipped, _ := change["skipped"].([]interface{}); len(skipped) != 1 || skipped[0] != "team=core" {
+			t.Errorf("Expected team=core to be skipped, got %v", change["skipped"])
+		}
+		if names := listNames(t, "env=staging"); len(names) != 0 {
+			t.Errorf("Expected dry run to leave labels unchanged, got %v", names)
+		}
+
+		resp, _ = postJSON(t, "/api/labels/migrate-tags", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if names := listNames(t, "env=staging,team=ops"); len(names) != 1 || names[0] != "tagged" {
+			t.Errorf("Expected tagged device to match migrated labels, got %v", names)
+		}
+
+		resp, err := http.Get(ts.URL() + "/api/devices?tag=env=staging")
+		if err != nil {
+			t.Fatalf("Failed to list devices: %v", err)
+		}
+		var tagged []map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&tagged)
+		resp.Body.Close()
+		if len(tagged) != 0 {
+			t.Errorf("Expected migrated tag to be removed, got %d devices", len(tagged))
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// TestAPI_DeviceLifecycle tests device lifecycle states, transitions and IP release
+func TestAPI_DeviceLifecycle(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	sendJSON := func(t *testing.T, method, path string, payload interface{}) (*http.Response, map[string]interface{}) {
+		t.Helper()
+		data, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, ts.URL()+path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &result)
+		return resp, result
+	}
+
+	getJSON := func(t *testing.T, path string, v interface{}) {
+		t.Helper()
+		resp, err := http.Get(ts.URL() + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d", path, resp.StatusCode)
+		}
+		json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	// Setup: network with a pool
+	_, network := sendJSON(t, http.MethodPost, "/api/networks", map[string]interface{}{
+		"name":   "Lifecycle Network",
+		"subnet": "10.1.0.0/24",
+	})
+	netID := network["id"].(string)
+	_, pool := sendJSON(t, http.MethodPost, "/api/networks/"+netID+"/pools", map[string]interface{}{
+		"name":     "Lifecycle Pool",
+		"start_ip": "10.1.0.10",
+		"end_ip":   "10.1.0.20",
+	})
+	poolID := pool["id"].(string)
+
+	var deviceID string
+
+	t.Run("DefaultStatus", func(t *testing.T) {
+		resp, device := sendJSON(t, http.MethodPost, "/api/devices", map[string]interface{}{
+			"name": "srv-1",
+			"addresses": []map[string]interface{}{
+				{"ip": "10.1.0.10", "type": "ipv4", "network_id": netID, "pool_id": poolID},
+				{"ip": "192.168.0.5", "type": "ipv4"},
+			},
+		})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+		deviceID = device["id"].(string)
+		if device["status"] != "active" {
+			t.Errorf("Expected default status active, got %v", device["status"])
+		}
+		if device["status_changed_at"] == nil {
+			t.Error("Expected status_changed_at to be set")
+		}
+
+		resp, _ = sendJSON(t, http.MethodPost, "/api/devices", map[string]interface{}{"name": "srv-2", "status": "planned"})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+
+		resp, _ = sendJSON(t, http.MethodPost, "/api/devices", map[string]interface{}{"name": "srv-3", "status": "broken"})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for unknown status, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("FilterByStatus", func(t *testing.T) {
+		var devices []map[string]interface{}
+		getJSON(t, "/api/devices?status=planned", &devices)
+		if len(devices) != 1 || devices[0]["name"] != "srv-2" {
+			t.Errorf("Expected only srv-2 to be planned, got %v", devices)
+		}
+
+		devices = nil
+		getJSON(t, "/api/devices?status=planned,active", &devices)
+		if len(devices) != 2 {
+			t.Errorf("Expected 2 devices, got %d", len(devices))
+		}
+
+		resp, err := http.Get(ts.URL() + "/api/devices?status=broken")
+		if err != nil {
+			t.Fatalf("Failed to list devices: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for unknown status filter, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Transitions", func(t *testing.T) {
+		resp, _ := sendJSON(t, http.MethodPost, "/api/devices/"+deviceID+"/status", map[string]string{"status": "ordered"})
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("Expected status 409 for active -> ordered, got %d", resp.StatusCode)
+		}
+
+		resp, change := sendJSON(t, http.MethodPost, "/api/devices/"+deviceID+"/status", map[string]string{"status": "maintenance", "note": "disk swap"})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if change["from_status"] != "active" || change["to_status"] != "maintenance" {
+			t.Errorf("Unexpected change: %v", change)
+		}
+
+		// Status changes through PUT follow the same rules; omitting status keeps it
+		resp, device := sendJSON(t, http.MethodPut, "/api/devices/"+deviceID, map[string]interface{}{
+			"name":      "srv-1",
+			"addresses": []map[string]interface{}{{"ip": "10.1.0.10", "type": "ipv4", "network_id": netID, "pool_id": poolID}, {"ip": "192.168.0.5", "type": "ipv4"}},
+		})
+		if resp.StatusCode != http.StatusOK || device["status"] != "maintenance" {
+			t.Errorf("Expected update without status to keep maintenance, got %d %v", resp.StatusCode, device["status"])
+		}
+		resp, _ = sendJSON(t, http.MethodPut, "/api/devices/"+deviceID, map[string]interface{}{"name": "srv-1", "status": "planned"})
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("Expected status 409 for maintenance -> planned, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("DecommissionReleasesIPs", func(t *testing.T) {
+		var next map[string]interface{}
+		getJSON(t, "/api/pools/"+poolID+"/next-ip", &next)
+		if next["ip"] != "10.1.0.11" {
+			t.Fatalf("Expected 10.1.0.10 to be in use, next IP is %v", next["ip"])
+		}
+
+		resp, change := sendJSON(t, http.MethodPost, "/api/devices/"+deviceID+"/status", map[string]string{"status": "decommissioned"})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		released, _ := change["released_addresses"].([]interface{})
+		if len(released) != 1 || released[0].(map[string]interface{})["ip"] != "10.1.0.10" {
+			t.Errorf("Expected 10.1.0.10 to be released, got %v", change["released_addresses"])
+		}
+
+		getJSON(t, "/api/pools/"+poolID+"/next-ip", &next)
+		if next["ip"] != "10.1.0.10" {
+			t.Errorf("Expected released IP 10.1.0.10 to be available, got %v", next["ip"])
+		}
+
+		var device map[string]interface{}
+		getJSON(t, "/api/devices/"+deviceID, &device)
+		addresses, _ := device["addresses"].([]interface{})
+		if len(addresses) != 1 {
+			t.Errorf("Expected only the non-pool address to remain, got %v", addresses)
+		}
+	})
+
+	t.Run("History", func(t *testing.T) {
+		var history []map[string]interface{}
+		getJSON(t, "/api/devices/"+deviceID+"/status-history", &history)
+
+		want := []string{"active", "maintenance", "decommissioned"}
+		if len(history) != len(want) {
+			t.Fatalf("Expected %d history entries, got %d", len(want), len(history))
+		}
+		for i, status := range want {
+			if history[i]["to_status"] != status {
+				t.Errorf("History entry %d: expected %s, got %v", i, status, history[i]["to_status"])
+			}
+		}
+		if history[1]["note"] != "disk swap" {
+			t.Errorf("Expected note to be recorded, got %v", history[1]["note"])
+		}
+
+		resp, err := http.Get(ts.URL() + "/api/devices/missing/status-history")
+		if err != nil {
+			t.Fatalf("Failed to get history: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+}
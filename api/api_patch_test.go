@@ -0,0 +1,171 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/martinsuchenak/rackd/internal/api"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// TestAPI_Patch tests partial updates with JSON Merge Patch and JSON Patch
+func TestAPI_Patch(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	send := func(t *testing.T, method, path, contentType, body string) (int, map[string]interface{}) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL()+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	status, device := send(t, http.MethodPost, "/api/devices", "application/json", `{
+		"name": "patch-1", "description": "original", "tags": ["web"],
+		"labels": {"env": "prod", "role": "web"},
+		"addresses": [{"ip": "10.0.0.1", "type": "ipv4"}]
+	}`)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %v", status, device)
+	}
+	path := "/api/devices/" + device["id"].(string)
+
+	t.Run("MergePatch", func(t *testing.T) {
+		status, result := send(t, http.MethodPatch, path, "application/merge-patch+json",
+			`{"description": "patched", "labels": {"role": null, "tier": "gold"}}`)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %v", status, result)
+		}
+		if result["description"] != "patched" || result["name"] != "patch-1" {
+			t.Errorf("Expected only description to change, got %v", result)
+		}
+		labels, _ := result["labels"].(map[string]interface{})
+		if len(labels) != 2 || labels["env"] != "prod" || labels["tier"] != "gold" {
+			t.Errorf("Expected merged labels, got %v", labels)
+		}
+		addresses, _ := result["addresses"].([]interface{})
+		if len(addresses) != 1 {
+			t.Errorf("Expected addresses to be kept, got %v", result["addresses"])
+		}
+		if result["version"] != float64(2) {
+			t.Errorf("Expected version 2, got %v", result["version"])
+		}
+	})
+
+	t.Run("JSONPatch", func(t *testing.T) {
+		status, result := send(t, http.MethodPatch, path, "application/json-patch+json",
+			`[{"op": "add", "path": "/tags/-", "value": "frontend"}, {"op": "remove", "path": "/labels"}]`)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %v", status, result)
+		}
+		tags, _ := result["tags"].([]interface{})
+		if len(tags) != 2 || tags[1] != "frontend" {
+			t.Errorf("Expected tag to be appended, got %v", result["tags"])
+		}
+		if labels, _ := result["labels"].(map[string]interface{}); len(labels) != 0 {
+			t.Errorf("Expected labels to be removed, got %v", labels)
+		}
+
+		_, stored := send(t, http.MethodGet, path, "", "")
+		if labels, _ := stored["labels"].(map[string]interface{}); len(labels) != 0 {
+			t.Errorf("Expected removed labels to be stored, got %v", labels)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			path        string
+			contentType string
+			body        string
+			want        int
+		}{
+			{"NotFound", "/api/devices/missing", "application/merge-patch+json", `{"name": "x"}`, http.StatusNotFound},
+			{"UnsupportedType", path, "text/plain", `name=x`, http.StatusUnsupportedMediaType},
+			{"InvalidPatch", path, "application/json-patch+json", `{"op": "add"}`, http.StatusBadRequest},
+			{"TestFailed", path, "application/json-patch+json", `[{"op": "test", "path": "/name", "value": "other"}]`, http.StatusConflict},
+			{"MissingPath", path, "application/json-patch+json", `[{"op": "remove", "path": "/asset/vendor"}]`, http.StatusUnprocessableEntity},
+			{"Validation", path, "application/merge-patch+json", `{"addresses": [{"ip": "not-an-ip"}]}`, http.StatusBadRequest},
+			{"StaleVersion", path, "application/merge-patch+json", `{"version": 1, "description": "stale"}`, http.StatusPreconditionFailed},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if status, result := send(t, http.MethodPatch, tt.path, tt.contentType, tt.body); status != tt.want {
+					t.Errorf("Expected status %d, got %d: %v", tt.want, status, result)
+				}
+			})
+		}
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		status, network := send(t, http.MethodPost, "/api/networks", "application/json", `{"name": "patch-net", "subnet": "10.60.0.0/24"}`)
+		if status != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %v", status, network)
+		}
+		status, result := send(t, http.MethodPatch, "/api/networks/"+network["id"].(string), "application/merge-patch+json", `{"description": "patched"}`)
+		if status != http.StatusOK || result["description"] != "patched" || result["subnet"] != "10.60.0.0/24" {
+			t.Errorf("Expected patched network, got %d: %v", status, result)
+		}
+	})
+}
+
+// TestAPI_PatchDiscoveryRule tests partial updates of discovery rules
+func TestAPI_PatchDiscoveryRule(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	mux := http.NewServeMux()
+	api.NewHandler(store).RegisterRoutes(mux)
+	api.NewDiscoveryHandler(store, nil).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/networks", "application/json", strings.NewReader(`{"name": "rules", "subnet": "10.61.0.0/24"}`))
+	if err != nil {
+		t.Fatalf("Create network failed: %v", err)
+	}
+	var network map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&network)
+	resp.Body.Close()
+
+	resp, err = http.Post(server.URL+"/api/discovery/rules", "application/json",
+		strings.NewReader(`{"network_id": "`+network["id"].(string)+`", "enabled": true, "scan_type": "quick", "scan_interval_hours": 24}`))
+	if err != nil {
+		t.Fatalf("Create rule failed: %v", err)
+	}
+	var rule map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&rule)
+	resp.Body.Close()
+
+	req, _ := http.NewRequest(http.MethodPatch, server.URL+"/api/discovery/rules/"+rule["id"].(string), strings.NewReader(`{"enabled": false}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Patch rule failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var patched map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&patched)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %v", resp.StatusCode, patched)
+	}
+	if patched["enabled"] != false || patched["scan_type"] != "quick" || patched["scan_interval_hours"] != float64(24) {
+		t.Errorf("Expected only enabled to change, got %v", patched)
+	}
+}
@@ -0,0 +1,194 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/api"
+	"github.com/martinsuchenak/rackd/internal/autopromote"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/worker"
+)
+
+// TestAPI_PromotionPolicies tests managing promotion policies and running them
+func TestAPI_PromotionPolicies(t *testing.T) {
+	store, network := newScanTestStore(t)
+
+	mux := http.NewServeMux()
+	api.NewDiscoveryHandler(store, nil).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	send := func(t *testing.T, method, path string, body any, out any) int {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, server.URL+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		if out != nil {
+			json.NewDecoder(resp.Body).Decode(out)
+		}
+		return resp.StatusCode
+	}
+
+	for _, d := range []model.DiscoveredDevice{
+		{IP: "10.62.0.10", NetworkID: network.ID, Status: "online", Hostname: "web1.lan", OpenPorts: []int{80, 443}, LastSeen: time.Now()},
+		{IP: "10.62.0.11", NetworkID: network.ID, Status: "online", Hostname: "printer", OpenPorts: []int{9100}, LastSeen: time.Now()},
+	} {
+		if err := store.CreateOrUpdateDiscoveredDevice(&d); err != nil {
+			t.Fatalf("Failed to create discovered device: %v", err)
+		}
+	}
+
+	t.Run("InvalidPolicy", func(t *testing.T) {
+		status := send(t, http.MethodPost, "/api/discovery/promotion-policies", model.PromotionPolicy{Name: "empty"}, nil)
+		if status != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", status)
+		}
+	})
+
+	var policy model.PromotionPolicy
+	t.Run("Create", func(t *testing.T) {
+		status := send(t, http.MethodPost, "/api/discovery/promotion-policies", model.PromotionPolicy{
+			Name:     "web servers",
+			Enabled:  true,
+			Match:    model.PromotionMatch{NetworkID: network.ID, OpenPorts: []int{80, 443}},
+			Template: model.PromotionTemplate{Tags: []string{"web"}, DatacenterID: "default"},
+		}, &policy)
+		if status != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", status)
+		}
+		if policy.ID == "" || policy.CreatedAt.IsZero() {
+			t.Errorf("Expected an ID and timestamps, got %+v", policy)
+		}
+
+		status = send(t, http.MethodPost, "/api/discovery/promotion-policies", model.PromotionPolicy{
+			Name:  "web servers",
+			Match: model.PromotionMatch{OpenPorts: []int{80}},
+		}, nil)
+		if status != http.StatusConflict {
+			t.Errorf("Expected status 409 for a duplicate name, got %d", status)
+		}
+	})
+
+	t.Run("Patch", func(t *testing.T) {
+		var patched model.PromotionPolicy
+		status := send(t, http.MethodPatch, "/api/discovery/promotion-policies/"+policy.ID, map[string]any{"priority": 5}, &patched)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+		if patched.Priority != 5 || !patched.Enabled || len(patched.Match.OpenPorts) != 2 {
+			t.Errorf("Expected only the priority to change, got %+v", patched)
+		}
+
+		status = send(t, http.MethodGet, "/api/discovery/promotion-policies/missing", nil, nil)
+		if status != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", status)
+		}
+	})
+
+	t.Run("Preview", func(t *testing.T) {
+		var run model.PromotionRun
+		status := send(t, http.MethodGet, "/api/discovery/promotion-policies/preview?network_id="+network.ID, nil, &run)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+		if !run.DryRun || run.Promoted != 1 || len(run.Candidates) != 1 || run.Candidates[0].Device.Name != "web1" {
+			t.Errorf("Unexpected preview %+v", run)
+		}
+		if devices, _ := store.ListDevices(nil); len(devices) != 0 {
+			t.Errorf("Expected a preview not to create devices, got %d", len(devices))
+		}
+
+		status = send(t, http.MethodGet, "/api/discovery/promotion-policies/preview?network_id=missing", nil, nil)
+		if status != http.StatusNotFound {
+			t.Errorf("Expected status 404 for an unknown network, got %d", status)
+		}
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		var run model.PromotionRun
+		status := send(t, http.MethodPost, "/api/discovery/promotion-policies/run", nil, &run)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+		if run.DryRun || run.Promoted != 1 || run.Candidates[0].DeviceID == "" {
+			t.Fatalf("Unexpected run %+v", run)
+		}
+		device, err := store.GetDevice(run.Candidates[0].DeviceID)
+		if err != nil {
+			t.Fatalf("Expected the promoted device to exist: %v", err)
+		}
+		if device.Name != "web1" || len(device.Tags) != 1 || device.Tags[0] != "web" {
+			t.Errorf("Expected the template to be applied, got %+v", device)
+		}
+
+		// Running again does not promote the device twice
+		status = send(t, http.MethodPost, "/api/discovery/promotion-policies/run", nil, &run)
+		if status != http.StatusOK || run.Promoted != 0 {
+			t.Errorf("Expected nothing to promote, got %d %+v", status, run)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		status := send(t, http.MethodDelete, "/api/discovery/promotion-policies/"+policy.ID, nil, nil)
+		if status != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", status)
+		}
+		var policies []model.PromotionPolicy
+		send(t, http.MethodGet, "/api/discovery/promotion-policies", nil, &policies)
+		if len(policies) != 0 {
+			t.Errorf("Expected no policies, got %+v", policies)
+		}
+	})
+}
+
+// TestAPI_PromotionAfterScan tests that policies run when a scan completes
+func TestAPI_PromotionAfterScan(t *testing.T) {
+	store, network := newScanTestStore(t)
+	if err := store.CreatePromotionPolicy(&model.PromotionPolicy{
+		Name:     "scanned hosts",
+		Enabled:  true,
+		Match:    model.PromotionMatch{NetworkID: network.ID},
+		Template: model.PromotionTemplate{NamePattern: "host-{ip_dashed}"},
+	}); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	scanner := &steppedScanner{store: store, hosts: []string{"10.62.0.1"}, release: make(chan struct{})}
+	close(scanner.release)
+	scans := worker.NewScanManager(store, scanner, 1)
+	promoted := make(chan *model.PromotionRun, 1)
+	scans.OnCompleted(func(networkID string) {
+		run, err := autopromote.Run(store, networkID)
+		if err != nil {
+			t.Errorf("Run failed: %v", err)
+		}
+		promoted <- run
+	})
+	scans.Start()
+	defer scans.Stop()
+
+	if err := scans.Submit(&model.DiscoveryScan{ID: "promote-1", NetworkID: network.ID, ScanType: "quick"}, nil); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	select {
+	case run := <-promoted:
+		if run.Promoted != 1 || run.Candidates[0].Device.Name != "host-10-62-0-1" {
+			t.Errorf("Expected host-10-62-0-1 to be promoted, got %+v", run)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for promotion")
+	}
+}
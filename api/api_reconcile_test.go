@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// TestAPI_Reconcile tests the reconciliation report of discovered hosts against inventory
+func TestAPI_Reconcile(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	network := &model.Network{Name: "lan", Subnet: "10.0.0.0/24", DatacenterID: "default"}
+	if err := ts.storage.(storage.NetworkStorage).CreateNetwork(network); err != nil {
+		t.Fatalf("CreateNetwork failed: %v", err)
+	}
+	device := &model.Device{
+		Name:      "db1",
+		Addresses: []model.Address{{IP: "10.0.0.5", Type: "ipv4", MACAddress: "aa:bb:cc:dd:ee:01"}},
+	}
+	if err := ts.storage.CreateDevice(device); err != nil {
+		t.Fatalf("CreateDevice failed: %v", err)
+	}
+
+	// 10.0.0.60 was discovered before but is not online in the latest scan
+	discovery := ts.storage.(storage.DiscoveryStorage)
+	scan := &model.DiscoveryScan{ID: "reconcile-scan", NetworkID: network.ID, Status: "completed", ScanType: "full", CreatedAt: time.Now()}
+	if err := discovery.CreateDiscoveryScan(scan); err != nil {
+		t.Fatalf("CreateDiscoveryScan failed: %v", err)
+	}
+	for _, d := range []*model.DiscoveredDevice{
+		{IP: "10.0.0.5", MACAddress: "AA:BB:CC:DD:EE:02", Hostname: "db1"},
+		{IP: "10.0.0.50", Hostname: "printer"},
+		{IP: "10.0.0.60", Hostname: "laptop"},
+	} {
+		d.NetworkID = network.ID
+		d.Status = "online"
+		d.LastSeen = time.Now()
+		if err := discovery.CreateOrUpdateDiscoveredDevice(d); err != nil {
+			t.Fatalf("CreateOrUpdateDiscoveredDevice failed: %v", err)
+		}
+		if d.IP == "10.0.0.60" {
+			continue
+		}
+		obs := &model.DiscoveryObservation{ScanID: scan.ID, IP: d.IP, Status: d.Status, MACAddress: d.MACAddress, Hostname: d.Hostname}
+		if err := discovery.RecordDiscoveryObservation(obs); err != nil {
+			t.Fatalf("RecordDiscoveryObservation failed: %v", err)
+		}
+	}
+
+	get := func(t *testing.T, path string) *http.Response {
+		t.Helper()
+		resp, err := http.Get(ts.URL() + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		return resp
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		resp := get(t, "/api/reconcile?network_id="+network.ID)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var report model.ReconcileReport
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			t.Fatalf("Failed to decode report: %v", err)
+		}
+		if report.Matched != 1 || report.MissedScans != 3 {
+			t.Errorf("Unexpected report %+v", report)
+		}
+		if len(report.Findings) != 2 {
+			t.Fatalf("Expected 2 findings, got %+v", report.Findings)
+		}
+		mac := report.Findings[0]
+		if mac.Type != model.DriftMACMismatch || mac.DeviceID != device.ID || mac.Expected != "aa:bb:cc:dd:ee:01" {
+			t.Errorf("Unexpected MAC finding %+v", mac)
+		}
+		if report.Findings[1].Type != model.DriftUnrecorded || report.Findings[1].IP != "10.0.0.50" {
+			t.Errorf("Unexpected unrecorded finding %+v", report.Findings[1])
+		}
+		if report.Summary[model.DriftNotSeen] != 0 || report.Summary[model.DriftUnrecorded] != 1 {
+			t.Errorf("Unexpected summary %v", report.Summary)
+		}
+	})
+
+	t.Run("InvalidMissedScans", func(t *testing.T) {
+		resp := get(t, "/api/reconcile?missed_scans=0")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("UnknownNetwork", func(t *testing.T) {
+		resp := get(t, "/api/reconcile?network_id=missing")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+}
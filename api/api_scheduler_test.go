@@ -0,0 +1,394 @@
+package api_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/api"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/worker"
+)
+
+// TestAPI_SchedulerTasks tests that discovery rule changes reach the scheduler
+func TestAPI_SchedulerTasks(t *testing.T) {
+	store, network := newScanTestStore(t)
+	scheduler := worker.NewScheduler(store, nil)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	mux := http.NewServeMux()
+	discovery := api.NewDiscoveryHandler(store, nil)
+	discovery.SetScheduler(scheduler)
+	discovery.RegisterRoutes(mux)
+	api.NewSchedulerHandler(scheduler).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	listTasks := func() []worker.TaskInfo {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/api/scheduler/tasks")
+		if err != nil {
+			t.Fatalf("Failed to list tasks: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var tasks []worker.TaskInfo
+		if err := json.NewDecoder(resp.Body).Decode(&tasks); err != nil {
+			t.Fatalf("Failed to decode tasks: %v", err)
+		}
+		return tasks
+	}
+	send := func(method, path string, body interface{}) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: unexpected status %d", method, path, resp.StatusCode)
+		}
+	}
+
+	if tasks := listTasks(); len(tasks) != 0 {
+		t.Fatalf("Expected no tasks, got %+v", tasks)
+	}
+
+	// A new rule is scheduled one interval out, and the due time is stored with it
+	before := time.Now()
+	send("POST", "/api/discovery/rules", model.DiscoveryRule{NetworkID: network.ID, Enabled: true, ScanIntervalHours: 6, ScanType: "quick"})
+	rules, _ := store.ListDiscoveryRules(network.ID)
+	if len(rules) != 1 {
+		t.Fatalf("Expected 1 rule, got %d", len(rules))
+	}
+	rule := rules[0]
+
+	tasks := listTasks()
+	if len(tasks) != 1 || tasks[0].ID != "discovery-"+rule.ID || tasks[0].IntervalSeconds != 6*3600 {
+		t.Fatalf("Expected a task for the rule, got %+v", tasks)
+	}
+	nextRun := tasks[0].NextRun
+	if nextRun.Before(before.Add(6*time.Hour)) || nextRun.After(time.Now().Add(6*time.Hour)) {
+		t.Errorf("Expected next run in 6 hours, got %v", nextRun)
+	}
+	stored, _ := store.GetDiscoveryRule(rule.ID)
+	if stored.NextRunAt == nil || !stored.NextRunAt.Equal(nextRun) {
+		t.Errorf("Expected next run %v to be stored, got %v", nextRun, stored.NextRunAt)
+	}
+
+	// Changing the interval moves the next run by the difference
+	rule.ScanIntervalHours = 2
+	send("PUT", "/api/discovery/rules/"+rule.ID, rule)
+	tasks = listTasks()
+	if len(tasks) != 1 || tasks[0].IntervalSeconds != 2*3600 || !tasks[0].NextRun.Equal(nextRun.Add(-4*time.Hour)) {
+		t.Fatalf("Expected the task to be rescheduled, got %+v", tasks)
+	}
+	stored, _ = store.GetDiscoveryRule(rule.ID)
+	if stored.NextRunAt == nil || !stored.NextRunAt.Equal(tasks[0].NextRun) {
+		t.Errorf("Expected rescheduled run to be stored, got %v", stored.NextRunAt)
+	}
+
+	// Disabling the rule removes its task
+	send("PATCH", "/api/discovery/rules/"+rule.ID, map[string]interface{}{"enabled": false})
+	if tasks := listTasks(); len(tasks) != 0 {
+		t.Fatalf("Expected no tasks for a disabled rule, got %+v", tasks)
+	}
+	stored, _ = store.GetDiscoveryRule(rule.ID)
+	if stored.NextRunAt != nil {
+		t.Errorf("Expected no next run for a disabled rule, got %v", stored.NextRunAt)
+	}
+
+	// Enabling it again schedules it, and deleting it removes it
+	send("PATCH", "/api/discovery/rules/"+rule.ID, map[string]interface{}{"enabled": true})
+	if tasks := listTasks(); len(tasks) != 1 {
+		t.Fatalf("Expected the task to be scheduled again, got %+v", tasks)
+	}
+	send("DELETE", "/api/discovery/rules/"+rule.ID, nil)
+	if tasks := listTasks(); len(tasks) != 0 {
+		t.Fatalf("Expected no tasks for a deleted rule, got %+v", tasks)
+	}
+}
+
+// TestAPI_SchedulerResume tests that a restarted scheduler keeps the stored schedule
+func TestAPI_SchedulerResume(t *testing.T) {
+	store, network := newScanTestStore(t)
+	rule := &model.DiscoveryRule{ID: "resume-rule", NetworkID: network.ID, Enabled: true, ScanIntervalHours: 24, ScanType: "quick"}
+	if err := store.CreateDiscoveryRule(rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	lastRun := time.Now().Add(-20 * time.Hour).Truncate(time.Second)
+	nextRun := lastRun.Add(24 * time.Hour)
+	if err := store.SetDiscoveryRuleSchedule(rule.ID, &lastRun, &nextRun); err != nil {
+		t.Fatalf("Failed to set schedule: %v", err)
+	}
+
+	scheduler := worker.NewScheduler(store, nil)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	tasks := scheduler.Tasks()
+	if len(tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %+v", tasks)
+	}
+	if !tasks[0].NextRun.Equal(nextRun) {
+		t.Errorf("Expected next run %v, got %v", nextRun, tasks[0].NextRun)
+	}
+	if tasks[0].LastRun == nil || !tasks[0].LastRun.Equal(lastRun) {
+		t.Errorf("Expected last run %v, got %v", lastRun, tasks[0].LastRun)
+	}
+
+	// Without a stored next run, the rule is due one interval after its last run
+	if err := store.SetDiscoveryRuleSchedule(rule.ID, &lastRun, nil); err != nil {
+		t.Fatalf("Failed to set schedule: %v", err)
+	}
+	restarted := worker.NewScheduler(store, nil)
+	restarted.Start()
+	defer restarted.Stop()
+	if tasks := restarted.Tasks(); len(tasks) != 1 || !tasks[0].NextRun.Equal(nextRun) {
+		t.Errorf("Expected next run %v from the last run, got %+v", nextRun, tasks)
+	}
+}
+
+// TestAPI_SchedulerCronRules tests cron schedules, blackouts and their validation
+func TestAPI_SchedulerCronRules(t *testing.T) {
+	store, network := newScanTestStore(t)
+	scheduler := worker.NewScheduler(store, nil)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	mux := http.NewServeMux()
+	discovery := api.NewDiscoveryHandler(store, nil)
+	discovery.SetScheduler(scheduler)
+	discovery.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	send := func(method, path string, body interface{}) (int, map[string]interface{}) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	for _, rule := range []map[string]interface{}{
+		{"network_id": network.ID, "schedule": "0 25 * * *"},
+		{"network_id": network.ID, "schedule": "0 2 * * *", "timezone": "Nowhere/Special"},
+		{"network_id": network.ID, "scan_interval_hours": 6, "blackouts": []map[string]interface{}{{"schedule": "0 0 * * *"}}},
+	} {
+		if status, result := send("POST", "/api/discovery/rules", rule); status != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %v, got %d: %v", rule, status, result)
+		}
+	}
+
+	status, created := send("POST", "/api/discovery/rules", map[string]interface{}{
+		"network_id": network.ID, "enabled": true, "schedule": "0 2 * * *", "timezone": "UTC", "jitter_minutes": 0,
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %v", status, created)
+	}
+	ruleID := created["id"].(string)
+
+	now := time.Now().UTC()
+	nightly := time.Date(now.Year(), now.Month(), now.Day(), 2, 0, 0, 0, time.UTC)
+	if !nightly.After(now) {
+		nightly = nightly.AddDate(0, 0, 1)
+	}
+	tasks := scheduler.Tasks()
+	if len(tasks) != 1 || tasks[0].Schedule != "0 2 * * *" || !tasks[0].NextRun.Equal(nightly) {
+		t.Fatalf("Expected a nightly task at %v, got %+v", nightly, tasks)
+	}
+
+	// A change freeze over the next night skips to the one after
+	freezeStart, freezeEnd := nightly.Add(-time.Hour), nightly.Add(time.Hour)
+	status, patched := send("PATCH", "/api/discovery/rules/"+ruleID, map[string]interface{}{
+		"blackouts": []model.DiscoveryBlackout{{Reason: "change freeze", Start: &freezeStart, End: &freezeEnd}},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", status, patched)
+	}
+	if tasks := scheduler.Tasks(); len(tasks) != 1 || !tasks[0].NextRun.Equal(nightly.AddDate(0, 0, 1)) {
+		t.Errorf("Expected the run after the freeze, got %+v", tasks)
+	}
+	stored, _ := store.GetDiscoveryRule(ruleID)
+	if len(stored.Blackouts) != 1 || stored.Blackouts[0].Reason != "change freeze" {
+		t.Errorf("Expected the blackout to be stored, got %+v", stored.Blackouts)
+	}
+}
+
+// TestAPI_SchedulerRunsScans tests that a rule falling due runs a scan through the scan manager
+func TestAPI_SchedulerRunsScans(t *testing.T) {
+	store, network := newScanTestStore(t)
+	rule := &model.DiscoveryRule{ID: "scheduled-rule", NetworkID: network.ID, Enabled: true, ScanIntervalHours: 24, ScanType: "quick"}
+	if err := store.CreateDiscoveryRule(rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	lastRun := time.Now().Add(-25 * time.Hour)
+	nextRun := lastRun.Add(24 * time.Hour)
+	if err := store.SetDiscoveryRuleSchedule(rule.ID, &lastRun, &nextRun); err != nil {
+		t.Fatalf("Failed to set schedule: %v", err)
+	}
+
+	release := make(chan struct{})
+	close(release)
+	scans := worker.NewScanManager(store, &steppedScanner{store: store, hosts: []string{"10.62.0.5"}, release: release}, 1)
+	completed := make(chan string, 1)
+	scans.OnCompleted(func(networkID string) { completed <- networkID })
+	scans.Start()
+	defer scans.Stop()
+
+	// The overdue rule runs when the scheduler starts
+	scheduler := worker.NewScheduler(store, scans)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	var scan model.DiscoveryScan
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		list, err := store.ListDiscoveryScans(network.ID)
+		if err != nil {
+			t.Fatalf("Failed to list scans: %v", err)
+		}
+		if len(list) == 1 {
+			scan = list[0]
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the scheduled run to create a scan, got %+v", list)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if scan.RuleID != rule.ID || scan.ScanType != "quick" {
+		t.Errorf("Expected a quick scan for the rule, got %+v", scan)
+	}
+
+	done := waitForScan(t, store, scan.ID, "completed")
+	if done.FoundHosts != 1 {
+		t.Errorf("Expected 1 host found, got %+v", done)
+	}
+	if observations, _ := store.ListDiscoveryObservations(scan.ID); len(observations) != 1 || observations[0].IP != "10.62.0.5" {
+		t.Errorf("Expected the scheduled scan to record its observation, got %+v", observations)
+	}
+	select {
+	case got := <-completed:
+		if got != network.ID {
+			t.Errorf("Expected completion for network %s, got %s", network.ID, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the completion hook to run for the scheduled scan")
+	}
+
+	// The run is recorded with the rule and the next one planned a day later
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		tasks := scheduler.Tasks()
+		if len(tasks) == 1 && tasks[0].Status == "completed" {
+			stored, _ := store.GetDiscoveryRule(rule.ID)
+			if stored.LastRunAt == nil || !stored.LastRunAt.After(lastRun) || stored.NextRunAt == nil || !stored.NextRunAt.After(time.Now().Add(23*time.Hour)) {
+				t.Errorf("Expected the run to be recorded with the rule, got %+v", stored)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the task to complete, got %+v", tasks)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestAPI_SchedulerScanEvents tests that a scheduled scan streams its progress like any other scan
+func TestAPI_SchedulerScanEvents(t *testing.T) {
+	store, network := newScanTestStore(t)
+	rule := &model.DiscoveryRule{ID: "streamed-rule", NetworkID: network.ID, Enabled: true, ScanIntervalHours: 1, ScanType: "quick"}
+	if err := store.CreateDiscoveryRule(rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	due := time.Now().Add(-time.Minute)
+	if err := store.SetDiscoveryRuleSchedule(rule.ID, nil, &due); err != nil {
+		t.Fatalf("Failed to set schedule: %v", err)
+	}
+
+	scanner := &steppedScanner{store: store, hosts: []string{"10.62.0.7", "10.62.0.8"}, release: make(chan struct{})}
+	scans := worker.NewScanManager(store, scanner, 1)
+	scans.Start()
+	defer scans.Stop()
+
+	mux := http.NewServeMux()
+	api.NewDiscoveryHandler(store, scans).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	scheduler := worker.NewScheduler(store, scans)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	// The scan waits for release, so it can be subscribed to while it runs
+	var scanID string
+	deadline := time.Now().Add(5 * time.Second)
+	for scanID == "" {
+		if list, _ := store.ListDiscoveryScans(network.ID); len(list) == 1 {
+			scanID = list[0].ID
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Expected the scheduled run to create a scan, got %+v", list)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := http.Get(server.URL + "/api/discovery/scans/" + scanID + "/events")
+	if err != nil {
+		t.Fatalf("Events request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected event stream, got %d", resp.StatusCode)
+	}
+
+	var names []string
+	var last map[string]interface{}
+	reader := bufio.NewScanner(resp.Body)
+	for reader.Scan() {
+		line := reader.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+			if len(names) == 1 {
+				close(scanner.release)
+			}
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			last = nil
+			json.Unmarshal([]byte(data), &last)
+		}
+	}
+
+	found := 0
+	for _, name := range names {
+		if name == "found" {
+			found++
+		}
+	}
+	if len(names) == 0 || names[0] != "progress" || names[len(names)-1] != "complete" || found != 2 {
+		t.Errorf("Expected progress, two found devices and complete, got %v", names)
+	}
+	if last["status"] != "completed" || last["rule_id"] != rule.ID {
+		t.Errorf("Expected the completed scheduled scan as last event, got %v", last)
+	}
+}
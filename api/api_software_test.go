@@ -0,0 +1,158 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// TestAPI_Software tests software inventory ingest and fleet-wide package queries
+func TestAPI_Software(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	sendJSON := func(t *testing.T, method, path string, payload interface{}) (*http.Response, map[string]interface{}) {
+		t.Helper()
+		data, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, ts.URL()+path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &result)
+		return resp, result
+	}
+
+	findDevices := func(t *testing.T, name, version string) []string {
+		t.Helper()
+		resp, err := http.Get(ts.URL() + "/api/software/packages?" + url.Values{"name": {name}, "version": {version}}.Encode())
+		if err != nil {
+			t.Fatalf("Failed to find packages: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s %s, got %d", name, version, resp.StatusCode)
+		}
+		var installs []map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&installs)
+		names := make([]string, 0, len(installs))
+		for _, i := range installs {
+			names = append(names, i["device_name"].(string))
+		}
+		return names
+	}
+
+	inventories := map[string]string{
+		"web-1": "3.0.2-0ubuntu1.10",
+		"web-2": "3.0.13-1",
+		"db-1":  "1.1.1w",
+	}
+	for name := range inventories {
+		resp, _ := sendJSON(t, http.MethodPost, "/api/devices", map[string]interface{}{"name": name})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+	}
+
+	t.Run("Push", func(t *testing.T) {
+		for name, version := range inventories {
+			resp, inventory := sendJSON(t, http.MethodPut, "/api/devices/"+name+"/software", map[string]interface{}{
+				"os": map[string]string{"family": "linux", "distribution": "ubuntu", "version": "22.04", "kernel": "5.15.0-91-generic"},
+				"packages": []map[string]string{
+					{"name": "openssl", "version": version},
+					{"name": "curl", "version": "7.81.0"},
+					{"name": "curl", "version": "7.81.0"},
+				},
+			})
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+			if packages, _ := inventory["packages"].([]interface{}); len(packages) != 2 {
+				t.Errorf("Expected duplicate packages to be merged, got %v", inventory["packages"])
+			}
+		}
+
+		resp, _ := sendJSON(t, http.MethodPut, "/api/devices/web-1/software", map[string]interface{}{
+			"packages": []map[string]string{{"name": " ", "version": "1"}},
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for missing package name, got %d", resp.StatusCode)
+		}
+
+		resp, _ = sendJSON(t, http.MethodPut, "/api/devices/missing/software", map[string]interface{}{})
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("DeviceOS", func(t *testing.T) {
+		resp, device := sendJSON(t, http.MethodGet, "/api/devices/web-1", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if device["os"] != "ubuntu 22.04" {
+			t.Errorf("Expected empty OS to be filled from inventory, got %v", device["os"])
+		}
+		if osInfo, _ := device["os_info"].(map[string]interface{}); osInfo["kernel"] != "5.15.0-91-generic" {
+			t.Errorf("Expected structured OS on device, got %v", device["os_info"])
+		}
+
+		resp, inventory := sendJSON(t, http.MethodGet, "/api/devices/web-1/software", nil)
+		if resp.StatusCode != http.StatusOK || inventory["collected_at"] == nil {
+			t.Errorf("Expected stored inventory, got %d %v", resp.StatusCode, inventory)
+		}
+	})
+
+	t.Run("FindByVersion", func(t *testing.T) {
+		tests := []struct {
+			version string
+			want    int
+		}{
+			{"", 3},
+			{"<3.0.8", 2},
+			{">=3.0.0,<3.0.8", 1},
+			{">=3.0.8", 1},
+			{"=1.1.1w", 1},
+		}
+		for _, tt := range tests {
+			if names := findDevices(t, "OpenSSL", tt.version); len(names) != tt.want {
+				t.Errorf("openssl %q matched %v, want %d devices", tt.version, names, tt.want)
+			}
+		}
+
+		if names := findDevices(t, "openssl", ">=3.0.0,<3.0.8"); len(names) != 1 || names[0] != "web-1" {
+			t.Errorf("Expected only web-1 to match, got %v", names)
+		}
+
+		resp, err := http.Get(ts.URL() + "/api/software/packages?" + url.Values{"name": {"openssl"}, "version": {"<<3"}}.Encode())
+		if err != nil {
+			t.Fatalf("Failed to find packages: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for invalid constraint, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("PushReplaces", func(t *testing.T) {
+		resp, _ := sendJSON(t, http.MethodPut, "/api/devices/db-1/software", map[string]interface{}{
+			"packages": []map[string]string{{"name": "openssl", "version": "3.0.13"}},
+		})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if names := findDevices(t, "openssl", "<3.0.8"); len(names) != 1 {
+			t.Errorf("Expected db-1 to no longer match after upgrade, got %v", names)
+		}
+		if names := findDevices(t, "curl", ""); len(names) != 2 {
+			t.Errorf("Expected curl to be removed from db-1, got %v", names)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/config"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func Commands() []*cli.Command {
+	return []*cli.Command{
+		ListCommand(),
+		ExpiringCommand(),
+	}
+}
+
+func getDefaultServerURL() string {
+	cfg := config.Load()
+	return "http://localhost" + cfg.ListenAddr
+}
+
+func makeRequest(method, url, token string) (*http.Response, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req)
+}
+
+// fetchAssets requests an asset report and either prints it as a table or,
+// for the csv format, copies the CSV export to stdout or the output file
+func fetchAssets(endpoint, token, format, output string) error {
+	resp, err := makeRequest("GET", endpoint, token)
+	if err != nil {
+		log.Error("Failed to connect to server for asset report", "error", err)
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Error("Server returned error for asset report", "status", resp.StatusCode, "body", string(body))
+		return fmt.Errorf("server error: %s", string(body))
+	}
+
+	out := io.Writer(os.Stdout)
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if format == "csv" {
+		if _, err := io.Copy(out, resp.Body); err != nil {
+			return fmt.Errorf("writing assets: %w", err)
+		}
+		return nil
+	}
+
+	var assets []model.DeviceAsset
+	if err := json.NewDecoder(resp.Body).Decode(&assets); err != nil {
+		log.Error("Failed to decode asset report", "error", err)
+		return err
+	}
+	log.Info("Retrieved assets", "count", len(assets))
+
+	if len(assets) == 0 {
+		fmt.Fprintln(out, "No assets found")
+		return nil
+	}
+	printAssets(out, assets)
+	return nil
+}
+
+func printAssets(out io.Writer, assets []model.DeviceAsset) {
+	for _, a := range assets {
+		daysLeft := "-"
+		if a.WarrantyDaysLeft != nil {
+			daysLeft = fmt.Sprintf("%dd", *a.WarrantyDaysLeft)
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			a.DeviceName, a.SerialNumber, a.AssetTag, a.Vendor, a.WarrantyExpires, daysLeft, a.SupportContract)
+	}
+}
@@ -0,0 +1,42 @@
+package asset
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/paularlott/cli"
+)
+
+func ExpiringCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "expiring",
+		Usage:       "List warranties expiring soon",
+		Description: "List devices whose warranty expires within the given number of days",
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "days", Usage: "Number of days to look ahead", DefaultValue: 90},
+			&cli.BoolFlag{Name: "include-expired", Usage: "Also list warranties that have already expired"},
+			&cli.StringFlag{Name: "format", Usage: "Output format (table, csv)", DefaultValue: "table"},
+			&cli.StringFlag{Name: "output", Usage: "Write to file instead of stdout"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			format := cmd.GetString("format")
+			params := url.Values{}
+			params.Set("days", strconv.Itoa(cmd.GetInt("days")))
+			if cmd.GetBool("include-expired") {
+				params.Set("include_expired", "true")
+			}
+			if format == "csv" {
+				params.Set("format", "csv")
+			}
+
+			log.Debug("Listing expiring warranties", "params", params.Encode(), "server", cmd.GetString("server"))
+
+			endpoint := cmd.GetString("server") + "/api/assets/expiring?" + params.Encode()
+			return fetchAssets(endpoint, cmd.GetString("api-token"), format, cmd.GetString("output"))
+		},
+	}
+}
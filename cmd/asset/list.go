@@ -0,0 +1,32 @@
+package asset
+
+import (
+	"context"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/paularlott/cli"
+)
+
+func ListCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "list",
+		Usage:       "List hardware assets",
+		Description: "List the serial numbers, warranty and procurement data of all devices with asset data",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Usage: "Output format (table, csv)", DefaultValue: "table"},
+			&cli.StringFlag{Name: "output", Usage: "Write to file instead of stdout"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			format := cmd.GetString("format")
+			log.Debug("Listing assets", "format", format, "server", cmd.GetString("server"))
+
+			endpoint := cmd.GetString("server") + "/api/assets"
+			if format == "csv" {
+				endpoint += "?format=csv"
+			}
+			return fetchAssets(endpoint, cmd.GetString("api-token"), format, cmd.GetString("output"))
+		},
+	}
+}
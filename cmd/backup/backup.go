@@ -0,0 +1,56 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/config"
+	"github.com/paularlott/cli"
+)
+
+func Commands() []*cli.Command {
+	return []*cli.Command{
+		NowCommand(),
+		ListCommand(),
+		DownloadCommand(),
+		RestoreCommand(),
+	}
+}
+
+func getDefaultServerURL() string {
+	cfg := config.Load()
+	return "http://localhost" + cfg.ListenAddr
+}
+
+func makeRequest(method, url, token string) (*http.Response, error) {
+	client := &http.Client{Timeout: 30 * time.Minute}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req)
+}
+
+// formatSize formats a byte count for display
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func serverError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("server error: %s", string(body))
+}
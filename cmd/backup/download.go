@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/paularlott/cli"
+)
+
+func DownloadCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "download",
+		Usage:       "Download a backup",
+		Description: "Download a backup from the server, e.g. to restore it on another machine",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "name", Usage: "Backup name as shown by backup list", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "output", Usage: "Output file, defaults to the backup name"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			name := cmd.GetStringArg("name")
+			output := cmd.GetString("output")
+			if output == "" {
+				output = name
+			}
+			log.Debug("Downloading backup", "name", name, "output", output)
+
+			endpoint := cmd.GetString("server") + "/api/admin/backups/" + url.PathEscape(name)
+			resp, err := makeRequest("GET", endpoint, cmd.GetString("api-token"))
+			if err != nil {
+				log.Error("Failed to connect to server for backup download", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				log.Error("Server returned error for backup download", "status", resp.StatusCode)
+				return serverError(resp)
+			}
+
+			f, err := os.OpenFile(output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				return fmt.Errorf("creating output file: %w", err)
+			}
+			defer f.Close()
+
+			n, err := io.Copy(f, resp.Body)
+			if err != nil {
+				return fmt.Errorf("writing backup: %w", err)
+			}
+
+			fmt.Printf("Downloaded %s (%s)\n", output, formatSize(n))
+			return nil
+		},
+	}
+}
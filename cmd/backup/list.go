@@ -0,0 +1,60 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func ListCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "list",
+		Usage:       "List backups",
+		Description: "List the backups kept on the server, newest first",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			log.Debug("Listing backups", "server", cmd.GetString("server"))
+
+			resp, err := makeRequest("GET", cmd.GetString("server")+"/api/admin/backups", cmd.GetString("api-token"))
+			if err != nil {
+				log.Error("Failed to connect to server for backup list", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				log.Error("Server returned error for backup list", "status", resp.StatusCode)
+				return serverError(resp)
+			}
+
+			var backups []model.Backup
+			if err := json.NewDecoder(resp.Body).Decode(&backups); err != nil {
+				log.Error("Failed to decode backup list", "error", err)
+				return err
+			}
+
+			if len(backups) == 0 {
+				fmt.Println("No backups found")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tCREATED\tSIZE")
+			for _, b := range backups {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", b.Name, b.CreatedAt.Local().Format(time.DateTime), formatSize(b.Size))
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func NowCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "now",
+		Usage:       "Take a backup now",
+		Description: "Take a verified online backup on the server and apply the retention policy",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			log.Debug("Requesting backup", "server", cmd.GetString("server"))
+
+			resp, err := makeRequest("POST", cmd.GetString("server")+"/api/admin/backups", cmd.GetString("api-token"))
+			if err != nil {
+				log.Error("Failed to connect to server for backup", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusCreated {
+				log.Error("Server returned error for backup", "status", resp.StatusCode)
+				return serverError(resp)
+			}
+
+			var backup model.Backup
+			if err := json.NewDecoder(resp.Body).Decode(&backup); err != nil {
+				log.Error("Failed to decode backup response", "error", err)
+				return err
+			}
+
+			fmt.Printf("Backup created: %s (%s)\n", backup.Name, formatSize(backup.Size))
+			return nil
+		},
+	}
+}
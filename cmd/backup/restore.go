@@ -0,0 +1,59 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/martinsuchenak/rackd/internal/backup"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/paularlott/cli"
+)
+
+func RestoreCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "restore",
+		Usage: "Restore the database from a backup",
+		Description: "Verify a backup and install it as the database of the data directory. The server must be stopped; " +
+			"the current database is kept with a .pre-restore suffix.",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "backup", Usage: "Backup file, or the name of a backup in the backup directory", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "data-dir", Usage: "Data directory path", EnvVars: []string{"RACKD_DATA_DIR"}, DefaultValue: filepath.Join(".", "data")},
+			&cli.StringFlag{Name: "backup-dir", Usage: "Backup directory, defaults to backups in the data directory", EnvVars: []string{"RACKD_BACKUP_DIR"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			src := cmd.GetStringArg("backup")
+			dataDir := cmd.GetString("data-dir")
+
+			if _, err := os.Stat(src); err != nil {
+				backupDir := cmd.GetString("backup-dir")
+				if backupDir == "" {
+					backupDir = filepath.Join(dataDir, "backups")
+				}
+				m := backup.NewManager(nil, backup.Options{Dir: backupDir})
+				if src, err = m.Path(src); err != nil {
+					return fmt.Errorf("backup %s not found", cmd.GetStringArg("backup"))
+				}
+			}
+
+			log.Debug("Restoring backup", "backup", src, "data_dir", dataDir)
+
+			dbPath := filepath.Join(dataDir, storage.DatabaseFile)
+			_, statErr := os.Stat(dbPath)
+
+			if err := backup.Restore(src, dataDir); err != nil {
+				return err
+			}
+
+			fmt.Printf("Restored %s into %s\n", src, dbPath)
+			if statErr == nil {
+				fmt.Printf("The previous database was kept as %s.pre-restore\n", dbPath)
+			}
+			return nil
+		},
+	}
+}
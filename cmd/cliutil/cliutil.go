@@ -0,0 +1,24 @@
+// Package cliutil holds flag parsing helpers shared by the CLI command packages.
+package cliutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseFields converts name=value pairs from --field flags into a custom field map
+func ParseFields(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	fields := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid custom field %q, expected name=value", pair)
+		}
+		fields[name] = strings.TrimSpace(value)
+	}
+	return fields, nil
+}
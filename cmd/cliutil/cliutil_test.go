@@ -0,0 +1,28 @@
+package cliutil
+
+import "testing"
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields([]string{"owner = ops", "cost=", "note=a=b"})
+	if err != nil {
+		t.Fatalf("ParseFields: %v", err)
+	}
+	want := map[string]string{"owner": "ops", "cost": "", "note": "a=b"}
+	if len(fields) != len(want) {
+		t.Fatalf("got %v, want %v", fields, want)
+	}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("field %q = %q, want %q", name, fields[name], value)
+		}
+	}
+
+	if fields, err := ParseFields(nil); err != nil || fields != nil {
+		t.Errorf("ParseFields(nil) = %v, %v; want nil, nil", fields, err)
+	}
+	for _, pair := range []string{"owner", "=ops", " =ops"} {
+		if _, err := ParseFields([]string{pair}); err == nil {
+			t.Errorf("ParseFields(%q) succeeded, want error", pair)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package customfield
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func AddCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "add",
+		Usage:       "Add a custom field definition",
+		Description: "Define a new custom field for devices, networks or datacenters",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "entity-type", Usage: "Entity type (device, network, datacenter)", Required: true},
+			&cli.StringFlag{Name: "name", Usage: "Field name (lowercase letters, digits and underscores)", Required: true},
+			&cli.StringFlag{Name: "type", Usage: "Value type (string, int, bool, date, enum, url)", DefaultValue: "string"},
+			&cli.StringFlag{Name: "label", Usage: "Display label"},
+			&cli.BoolFlag{Name: "required", Usage: "Require a value on every entity"},
+			&cli.StringFlag{Name: "pattern", Usage: "Regular expression values must match"},
+			&cli.StringFlag{Name: "options", Usage: "Comma-separated allowed values (enum only)"},
+			&cli.StringFlag{Name: "description", Usage: "Field description"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			field := &model.CustomFieldDefinition{
+				EntityType:  cmd.GetString("entity-type"),
+				Name:        cmd.GetString("name"),
+				Type:        cmd.GetString("type"),
+				Label:       cmd.GetString("label"),
+				Required:    cmd.GetBool("required"),
+				Pattern:     cmd.GetString("pattern"),
+				Options:     parseList(cmd.GetString("options")),
+				Description: cmd.GetString("description"),
+			}
+
+			log.Debug("Adding custom field", "entity_type", field.EntityType, "name", field.Name, "server", cmd.GetString("server"))
+
+			data, err := json.Marshal(field)
+			if err != nil {
+				log.Error("Failed to marshal custom field data", "error", err, "name", field.Name)
+				return err
+			}
+
+			resp, err := makeRequest("POST", cmd.GetString("server")+"/api/custom-fields", cmd.GetString("api-token"), strings.NewReader(string(data)))
+			if err != nil {
+				log.Error("Failed to connect to server", "error", err, "name", field.Name)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusCreated {
+				body, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error", "status", resp.StatusCode, "body", string(body), "name", field.Name)
+				return fmt.Errorf("server error: %s", string(body))
+			}
+
+			if err := json.NewDecoder(resp.Body).Decode(field); err != nil {
+				log.Error("Failed to decode response", "error", err, "name", field.Name)
+				return err
+			}
+
+			log.Info("Custom field created", "name", field.Name, "id", field.ID)
+			fmt.Printf("Custom field created: %s (ID: %s)\n", field.Name, field.ID)
+			return nil
+		},
+	}
+}
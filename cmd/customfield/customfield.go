@@ -0,0 +1,75 @@
+package customfield
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/config"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func Commands() []*cli.Command {
+	return []*cli.Command{
+		ListCommand(),
+		AddCommand(),
+		UpdateCommand(),
+		DeleteCommand(),
+	}
+}
+
+func parseList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func getDefaultServerURL() string {
+	cfg := config.Load()
+	return "http://localhost" + cfg.ListenAddr
+}
+
+func makeRequest(method, url, token string, body *strings.Reader) (*http.Response, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequest(method, url, body)
+	} else {
+		req, err = http.NewRequest(method, url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req)
+}
+
+func printCustomFields(fields []model.CustomFieldDefinition) {
+	if len(fields) == 0 {
+		fmt.Println("No custom fields found")
+		return
+	}
+	for _, f := range fields {
+		required := ""
+		if f.Required {
+			required = "required"
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\n", f.ID, f.EntityType, f.Name, f.Type, required)
+	}
+}
@@ -0,0 +1,49 @@
+package customfield
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/paularlott/cli"
+)
+
+func DeleteCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "delete",
+		Usage:       "Delete a custom field definition",
+		Description: "Delete a custom field definition and all of its values",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "id", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			id := cmd.GetStringArg("id")
+			log.Debug("Deleting custom field", "id", id, "server", cmd.GetString("server"))
+
+			resp, err := makeRequest("DELETE", cmd.GetString("server")+"/api/custom-fields/"+id, cmd.GetString("api-token"), nil)
+			if err != nil {
+				log.Error("Failed to connect to server for custom field delete", "error", err, "id", id)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode == http.StatusNotFound {
+				log.Warn("Custom field not found for deletion", "id", id)
+				return fmt.Errorf("custom field not found")
+			}
+			if resp.StatusCode != http.StatusNoContent {
+				log.Error("Server returned error for custom field delete", "status", resp.Status, "id", id)
+				return fmt.Errorf("server error: %s", resp.Status)
+			}
+
+			log.Info("Custom field deleted successfully", "id", id)
+			fmt.Println("Custom field deleted")
+			return nil
+		},
+	}
+}
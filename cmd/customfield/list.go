@@ -0,0 +1,57 @@
+package customfield
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func ListCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "list",
+		Usage:       "List custom field definitions",
+		Description: "List custom field definitions, optionally for a single entity type",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "entity-type", Usage: "Entity type (device, network, datacenter)"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			entityType := cmd.GetString("entity-type")
+			log.Debug("Listing custom fields", "entity_type", entityType, "server", cmd.GetString("server"))
+
+			endpoint := cmd.GetString("server") + "/api/custom-fields"
+			if entityType != "" {
+				endpoint += "?entity_type=" + url.QueryEscape(entityType)
+			}
+
+			resp, err := makeRequest("GET", endpoint, cmd.GetString("api-token"), nil)
+			if err != nil {
+				log.Error("Failed to connect to server for custom field list", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				log.Error("Server returned error for custom field list", "status", resp.Status)
+				return fmt.Errorf("server error: %s", resp.Status)
+			}
+
+			var fields []model.CustomFieldDefinition
+			if err := json.NewDecoder(resp.Body).Decode(&fields); err != nil {
+				log.Error("Failed to decode custom field list response", "error", err)
+				return err
+			}
+
+			log.Info("Listed custom fields successfully", "count", len(fields))
+			printCustomFields(fields)
+			return nil
+		},
+	}
+}
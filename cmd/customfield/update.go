@@ -0,0 +1,114 @@
+package customfield
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func UpdateCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "update",
+		Usage:       "Update a custom field definition",
+		Description: "Update an existing custom field definition. Unset flags keep their current value.",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "id", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "name", Usage: "Field name"},
+			&cli.StringFlag{Name: "type", Usage: "Value type (string, int, bool, date, enum, url)"},
+			&cli.StringFlag{Name: "label", Usage: "Display label"},
+			&cli.BoolFlag{Name: "required", Usage: "Require a value on every entity"},
+			&cli.BoolFlag{Name: "optional", Usage: "Make the field optional"},
+			&cli.StringFlag{Name: "pattern", Usage: "Regular expression values must match"},
+			&cli.StringFlag{Name: "options", Usage: "Comma-separated allowed values (enum only)"},
+			&cli.StringFlag{Name: "description", Usage: "Field description"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			id := cmd.GetStringArg("id")
+			server := cmd.GetString("server")
+			token := cmd.GetString("api-token")
+			log.Debug("Updating custom field", "id", id, "server", server)
+
+			// Fetch the current definition so unset flags keep their values
+			resp, err := makeRequest("GET", server+"/api/custom-fields/"+id, token, nil)
+			if err != nil {
+				log.Error("Failed to connect to server for custom field update", "error", err, "id", id)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode == http.StatusNotFound {
+				log.Warn("Custom field not found for update", "id", id)
+				return fmt.Errorf("custom field not found")
+			}
+			if resp.StatusCode != http.StatusOK {
+				log.Error("Server returned error for custom field get", "status", resp.Status, "id", id)
+				return fmt.Errorf("server error: %s", resp.Status)
+			}
+
+			var field model.CustomFieldDefinition
+			if err := json.NewDecoder(resp.Body).Decode(&field); err != nil {
+				log.Error("Failed to decode custom field response", "error", err, "id", id)
+				return err
+			}
+
+			if cmd.HasFlag("name") {
+				field.Name = cmd.GetString("name")
+			}
+			if cmd.HasFlag("type") {
+				field.Type = cmd.GetString("type")
+			}
+			if cmd.HasFlag("label") {
+				field.Label = cmd.GetString("label")
+			}
+			if cmd.GetBool("required") {
+				field.Required = true
+			}
+			if cmd.GetBool("optional") {
+				field.Required = false
+			}
+			if cmd.HasFlag("pattern") {
+				field.Pattern = cmd.GetString("pattern")
+			}
+			if cmd.HasFlag("options") {
+				field.Options = parseList(cmd.GetString("options"))
+			}
+			if cmd.HasFlag("description") {
+				field.Description = cmd.GetString("description")
+			}
+
+			data, err := json.Marshal(field)
+			if err != nil {
+				log.Error("Failed to marshal custom field update data", "error", err, "id", id)
+				return err
+			}
+
+			updateResp, err := makeRequest("PUT", server+"/api/custom-fields/"+id, token, strings.NewReader(string(data)))
+			if err != nil {
+				log.Error("Failed to connect to server for custom field update", "error", err, "id", id)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer updateResp.Body.Close()
+
+			if updateResp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(updateResp.Body)
+				log.Error("Server returned error for custom field update", "status", updateResp.StatusCode, "body", string(body), "id", id)
+				return fmt.Errorf("server error: %s", string(body))
+			}
+
+			log.Info("Custom field updated successfully", "id", id)
+			fmt.Println("Custom field updated")
+			return nil
+		},
+	}
+}
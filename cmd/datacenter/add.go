@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/martinsuchenak/rackd/cmd/cliutil"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/paularlott/cli"
@@ -23,6 +24,7 @@ func AddCommand() *cli.Command {
 			&cli.StringFlag{Name: "name", Usage: "Datacenter name", Required: true},
 			&cli.StringFlag{Name: "location", Usage: "Datacenter location"},
 			&cli.StringFlag{Name: "description", Usage: "Datacenter description"},
+			&cli.StringSliceFlag{Name: "field", Usage: "Custom field value as name=value (repeatable)"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
 		},
@@ -30,10 +32,16 @@ func AddCommand() *cli.Command {
 			datacenterName := cmd.GetString("name")
 			log.Debug("Adding datacenter", "name", datacenterName, "server", cmd.GetString("server"))
 			
+			customFields, err := cliutil.ParseFields(cmd.GetStringSlice("field"))
+			if err != nil {
+				return err
+			}
+
 			datacenter := &model.Datacenter{
-				Name:        datacenterName,
-				Location:    cmd.GetString("location"),
-				Description: cmd.GetString("description"),
+				Name:         datacenterName,
+				Location:     cmd.GetString("location"),
+				Description:  cmd.GetString("description"),
+				CustomFields: customFields,
 			}
 
 			data, err := json.Marshal(datacenter)
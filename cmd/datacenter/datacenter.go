@@ -2,6 +2,7 @@ package datacenter
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/martinsuchenak/rackd/internal/config"
@@ -20,6 +21,21 @@ func Commands() []*cli.Command {
 	}
 }
 
+func printCustomFields(fields map[string]string) {
+	if len(fields) == 0 {
+		return
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("Custom Fields:")
+	for _, name := range names {
+		fmt.Printf("  - %s: %s\n", name, fields[name])
+	}
+}
+
 func getDefaultServerURL() string {
 	cfg := config.Load()
 	return "http://localhost" + cfg.ListenAddr
@@ -42,6 +58,7 @@ func printDatacenter(datacenter *model.Datacenter) {
 	fmt.Printf("Description:  %s\n", datacenter.Description)
 	fmt.Printf("Created:      %s\n", datacenter.CreatedAt.Format(time.RFC3339))
 	fmt.Printf("Updated:      %s\n", datacenter.UpdatedAt.Format(time.RFC3339))
+	printCustomFields(datacenter.CustomFields)
 }
 
 func printDevices(devices []model.Device) {
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/martinsuchenak/rackd/cmd/cliutil"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/paularlott/cli"
@@ -25,8 +26,19 @@ func AddCommand() *cli.Command {
 			&cli.StringFlag{Name: "os", Usage: "Operating system"},
 			&cli.StringFlag{Name: "datacenter-id", Usage: "Datacenter ID"},
 			&cli.StringFlag{Name: "location", Usage: "Device location"},
+			&cli.StringFlag{Name: "status", Usage: "Lifecycle status (default active)"},
 			&cli.StringFlag{Name: "tags", Usage: "Comma-separated tags"},
 			&cli.StringFlag{Name: "domains", Usage: "Comma-separated domains"},
+			&cli.StringSliceFlag{Name: "field", Usage: "Custom field value as name=value (repeatable)"},
+			&cli.StringSliceFlag{Name: "label", Usage: "Label as key=value (repeatable)"},
+			&cli.StringFlag{Name: "serial", Usage: "Serial number"},
+			&cli.StringFlag{Name: "asset-tag", Usage: "Asset tag"},
+			&cli.StringFlag{Name: "vendor", Usage: "Vendor the device was purchased from"},
+			&cli.StringFlag{Name: "purchase-date", Usage: "Purchase date (YYYY-MM-DD)"},
+			&cli.StringFlag{Name: "po-number", Usage: "Purchase order number"},
+			&cli.Float64Flag{Name: "cost", Usage: "Purchase cost"},
+			&cli.StringFlag{Name: "warranty-expires", Usage: "Warranty expiry date (YYYY-MM-DD)"},
+			&cli.StringFlag{Name: "support-contract", Usage: "Support contract reference"},
 			&cli.StringFlag{Name: "addresses-json", Usage: "JSON array of addresses (overrides single IP flags)"},
 			&cli.StringFlag{Name: "ip", Usage: "IP address"},
 			&cli.IntFlag{Name: "port", Usage: "Port number"},
@@ -35,6 +47,7 @@ func AddCommand() *cli.Command {
 			&cli.StringFlag{Name: "network-id", Usage: "Network ID for IP address"},
 			&cli.StringFlag{Name: "pool-id", Usage: "Pool ID for IP address"},
 			&cli.StringFlag{Name: "switch-port", Usage: "Switch port"},
+			&cli.StringFlag{Name: "mac", Usage: "MAC address of the interface"},
 			&cli.StringFlag{Name: "addresses-json", Usage: "JSON array of addresses (overrides single IP flags)"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
@@ -43,6 +56,15 @@ func AddCommand() *cli.Command {
 			deviceName := cmd.GetString("name")
 			log.Debug("Adding device", "name", deviceName, "server", cmd.GetString("server"))
 			
+			customFields, err := cliutil.ParseFields(cmd.GetStringSlice("field"))
+			if err != nil {
+				return err
+			}
+			labels, err := parseLabels(cmd.GetStringSlice("label"))
+			if err != nil {
+				return err
+			}
+
 			device := &model.Device{
 				Name:         deviceName,
 				Description:  cmd.GetString("description"),
@@ -50,8 +72,12 @@ func AddCommand() *cli.Command {
 				OS:           cmd.GetString("os"),
 				DatacenterID: cmd.GetString("datacenter-id"),
 				Location:     cmd.GetString("location"),
+				Status:       model.DeviceStatus(cmd.GetString("status")),
 				Tags:         parseList(cmd.GetString("tags")),
 				Domains:      parseList(cmd.GetString("domains")),
+				Labels:       labels,
+				Asset:        parseAsset(cmd),
+				CustomFields: customFields,
 			}
 
 			// Add addresses
@@ -70,6 +96,7 @@ func AddCommand() *cli.Command {
 					NetworkID:  cmd.GetString("network-id"),
 					PoolID:     cmd.GetString("pool-id"),
 					SwitchPort: cmd.GetString("switch-port"),
+					MACAddress: cmd.GetString("mac"),
 				}}
 			}
 
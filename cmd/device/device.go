@@ -3,6 +3,7 @@ package device
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -20,6 +21,8 @@ func Commands() []*cli.Command {
 		DeleteCommand(),
 		SearchCommand(),
 		RelationshipsCommand(),
+		StatusCommand(),
+		HistoryCommand(),
 	}
 }
 
@@ -37,6 +40,53 @@ func parseList(s string) []string {
 	return result
 }
 
+// parseLabels converts key=value pairs from --label flags into a label map
+func parseLabels(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+func printLabels(labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Println("Labels:")
+	for _, key := range keys {
+		fmt.Printf("  - %s=%s\n", key, labels[key])
+	}
+}
+
+func printCustomFields(fields map[string]string) {
+	if len(fields) == 0 {
+		return
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("Custom Fields:")
+	for _, name := range names {
+		fmt.Printf("  - %s: %s\n", name, fields[name])
+	}
+}
+
 func getDefaultServerURL() string {
 	cfg := config.Load()
 	return "http://localhost" + cfg.ListenAddr
@@ -54,7 +104,14 @@ func addAuthHeader(req *http.Request, token string) {
 
 func makeRequest(method, url, token string, body *strings.Reader) (*http.Response, error) {
 	client := createHTTPClient()
-	req, err := http.NewRequest(method, url, body)
+	// Don't pass a typed nil *strings.Reader to http.NewRequest, it would be dereferenced
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequest(method, url, body)
+	} else {
+		req, err = http.NewRequest(method, url, nil)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -65,13 +122,36 @@ func makeRequest(method, url, token string, body *strings.Reader) (*http.Respons
 	return client.Do(req)
 }
 
+// parseAsset builds the hardware asset from the asset flags, or returns nil if none were given
+func parseAsset(cmd *cli.Command) *model.HardwareAsset {
+	given := false
+	for _, name := range []string{"serial", "asset-tag", "vendor", "purchase-date", "po-number", "cost", "warranty-expires", "support-contract"} {
+		if cmd.HasFlag(name) {
+			given = true
+		}
+	}
+	if !given {
+		return nil
+	}
+	return &model.HardwareAsset{
+		SerialNumber:    cmd.GetString("serial"),
+		AssetTag:        cmd.GetString("asset-tag"),
+		Vendor:          cmd.GetString("vendor"),
+		PurchaseDate:    cmd.GetString("purchase-date"),
+		PONumber:        cmd.GetString("po-number"),
+		Cost:            cmd.GetFloat64("cost"),
+		WarrantyExpires: cmd.GetString("warranty-expires"),
+		SupportContract: cmd.GetString("support-contract"),
+	}
+}
+
 func printDevices(devices []model.Device) {
 	if len(devices) == 0 {
 		fmt.Println("No devices found")
 		return
 	}
 	for _, d := range devices {
-		fmt.Printf("%s\t%s\t%s\n", d.ID, d.Name, d.DatacenterID)
+		fmt.Printf("%s\t%s\t%s\t%s\n", d.ID, d.Name, d.Status, d.DatacenterID)
 	}
 }
 
@@ -81,6 +161,10 @@ func printDevice(device *model.Device) {
 	fmt.Printf("Description:  %s\n", device.Description)
 	fmt.Printf("Make/Model:   %s\n", device.MakeModel)
 	fmt.Printf("OS:           %s\n", device.OS)
+	if os := device.OSInfo; os != nil {
+		fmt.Printf("OS details:   %s %s %s (kernel %s)\n", os.Family, os.Distribution, os.Version, os.Kernel)
+	}
+	fmt.Printf("Status:       %s\n", device.Status)
 	fmt.Printf("Datacenter:   %s\n", device.DatacenterID)
 	fmt.Printf("Location:     %s\n", device.Location)
 	fmt.Printf("Tags:         %s\n", strings.Join(device.Tags, ", "))
@@ -89,7 +173,20 @@ func printDevice(device *model.Device) {
 	fmt.Printf("Updated:      %s\n", device.UpdatedAt.Format(time.RFC3339))
 	fmt.Println("Addresses:")
 	for _, a := range device.Addresses {
-		fmt.Printf("  - %s:%d (%s) [%s] network:%s port:%s\n", 
-			a.IP, a.Port, a.Label, a.Type, a.NetworkID, a.SwitchPort)
+		fmt.Printf("  - %s:%d (%s) [%s] network:%s port:%s mac:%s\n", 
+			a.IP, a.Port, a.Label, a.Type, a.NetworkID, a.SwitchPort, a.MACAddress)
+	}
+	if a := device.Asset; a != nil {
+		fmt.Println("Asset:")
+		fmt.Printf("  Serial:       %s\n", a.SerialNumber)
+		fmt.Printf("  Asset tag:    %s\n", a.AssetTag)
+		fmt.Printf("  Vendor:       %s\n", a.Vendor)
+		fmt.Printf("  Purchased:    %s\n", a.PurchaseDate)
+		fmt.Printf("  PO number:    %s\n", a.PONumber)
+		fmt.Printf("  Cost:         %.2f\n", a.Cost)
+		fmt.Printf("  Warranty:     %s\n", a.WarrantyExpires)
+		fmt.Printf("  Support:      %s\n", a.SupportContract)
 	}
+	printLabels(device.Labels)
+	printCustomFields(device.CustomFields)
 }
\ No newline at end of file
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 
+	"github.com/martinsuchenak/rackd/cmd/cliutil"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/paularlott/cli"
@@ -18,6 +20,9 @@ func ListCommand() *cli.Command {
 		Description: "List all devices in the inventory",
 		Flags: []cli.Flag{
 			&cli.StringFlag{Name: "filter", Usage: "Filter by tags (comma-separated)"},
+			&cli.StringSliceFlag{Name: "field", Usage: "Filter by custom field value as name=value (repeatable)"},
+			&cli.StringFlag{Name: "status", Usage: "Filter by lifecycle status (comma-separated)"},
+			&cli.StringFlag{Name: "selector", Aliases: []string{"l"}, Usage: "Filter by label selector (e.g. env=prod,role in (db,cache))"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
 		},
@@ -25,21 +30,31 @@ func ListCommand() *cli.Command {
 			filterTags := parseList(cmd.GetString("filter"))
 			log.Debug("Listing devices", "filter_tags", filterTags, "server", cmd.GetString("server"))
 			
-			url := cmd.GetString("server") + "/api/devices"
-			if len(filterTags) > 0 {
-				first := true
-				for _, tag := range filterTags {
-					if first {
-						url += "?"
-						first = false
-					} else {
-						url += "&"
-					}
-					url += "tag=" + tag
-				}
+			customFields, err := cliutil.ParseFields(cmd.GetStringSlice("field"))
+			if err != nil {
+				return err
+			}
+
+			params := url.Values{}
+			for _, tag := range filterTags {
+				params.Add("tag", tag)
+			}
+			for name, value := range customFields {
+				params.Set("field."+name, value)
+			}
+			for _, status := range parseList(cmd.GetString("status")) {
+				params.Add("status", status)
+			}
+			if selector := cmd.GetString("selector"); selector != "" {
+				params.Set("selector", selector)
+			}
+
+			endpoint := cmd.GetString("server") + "/api/devices"
+			if len(params) > 0 {
+				endpoint += "?" + params.Encode()
 			}
 
-			resp, err := makeRequest("GET", url, cmd.GetString("api-token"), nil)
+			resp, err := makeRequest("GET", endpoint, cmd.GetString("api-token"), nil)
 			if err != nil {
 				log.Error("Failed to connect to server for list", "error", err)
 				return fmt.Errorf("failed to connect to server: %w", err)
@@ -57,7 +72,7 @@ func ListCommand() *cli.Command {
 				return err
 			}
 
-			log.Info("Listed devices successfully", "count", len(devices), "filtered", len(filterTags) > 0)
+			log.Info("Listed devices successfully", "count", len(devices), "filtered", len(params) > 0)
 			printDevices(devices)
 			return nil
 		},
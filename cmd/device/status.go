@@ -0,0 +1,126 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func StatusCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "status",
+		Usage:       "Change the lifecycle status of a device",
+		Description: "Move a device to a new lifecycle status (planned, ordered, received, staged, active, maintenance, decommissioned, disposed). Decommissioning releases the device's pool IP addresses",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "id", Required: true},
+			&cli.StringArg{Name: "status", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "note", Usage: "Reason for the change"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			id := cmd.GetStringArg("id")
+			status := cmd.GetStringArg("status")
+			log.Debug("Changing device status", "id", id, "status", status, "server", cmd.GetString("server"))
+
+			data, err := json.Marshal(map[string]string{"status": status, "note": cmd.GetString("note")})
+			if err != nil {
+				return err
+			}
+
+			endpoint := cmd.GetString("server") + "/api/devices/" + url.PathEscape(id) + "/status"
+			resp, err := makeRequest("POST", endpoint, cmd.GetString("api-token"), strings.NewReader(string(data)))
+			if err != nil {
+				log.Error("Failed to connect to server for status change", "error", err, "id", id)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode == http.StatusNotFound {
+				log.Warn("Device not found for status change", "id", id)
+				return fmt.Errorf("device not found")
+			}
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for status change", "status", resp.StatusCode, "body", string(body), "id", id)
+				return fmt.Errorf("server error: %s", string(body))
+			}
+
+			var change model.DeviceStatusChange
+			if err := json.NewDecoder(resp.Body).Decode(&change); err != nil {
+				log.Error("Failed to decode status change response", "error", err, "id", id)
+				return err
+			}
+
+			log.Info("Device status changed", "id", change.DeviceID, "from", change.FromStatus, "to", change.ToStatus)
+			fmt.Printf("Device %s: %s -> %s\n", change.DeviceID, change.FromStatus, change.ToStatus)
+			for _, a := range change.ReleasedAddresses {
+				fmt.Printf("Released %s from pool %s\n", a.IP, a.PoolID)
+			}
+			return nil
+		},
+	}
+}
+
+func HistoryCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "history",
+		Usage:       "Show the lifecycle history of a device",
+		Description: "List the lifecycle status changes of a device, oldest first",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "id", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			id := cmd.GetStringArg("id")
+			log.Debug("Getting device status history", "id", id, "server", cmd.GetString("server"))
+
+			endpoint := cmd.GetString("server") + "/api/devices/" + url.PathEscape(id) + "/status-history"
+			resp, err := makeRequest("GET", endpoint, cmd.GetString("api-token"), nil)
+			if err != nil {
+				log.Error("Failed to connect to server for status history", "error", err, "id", id)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode == http.StatusNotFound {
+				log.Warn("Device not found for status history", "id", id)
+				return fmt.Errorf("device not found")
+			}
+			if resp.StatusCode != http.StatusOK {
+				log.Error("Server returned error for status history", "status", resp.Status, "id", id)
+				return fmt.Errorf("server error: %s", resp.Status)
+			}
+
+			var history []model.DeviceStatusChange
+			if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+				log.Error("Failed to decode status history response", "error", err, "id", id)
+				return err
+			}
+
+			log.Info("Retrieved device status history", "id", id, "count", len(history))
+			for _, c := range history {
+				from := string(c.FromStatus)
+				if from == "" {
+					from = "-"
+				}
+				fmt.Printf("%s\t%s\t%s\t%s\n", c.ChangedAt.Format(time.RFC3339), from, c.ToStatus, c.Note)
+			}
+			return nil
+		},
+	}
+}
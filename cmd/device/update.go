@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/martinsuchenak/rackd/cmd/cliutil"
+	"github.com/martinsuchenak/rackd/internal/jsonpatch"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/paularlott/cli"
@@ -29,8 +31,19 @@ func UpdateCommand() *cli.Command {
 			&cli.StringFlag{Name: "os", Usage: "Operating system"},
 			&cli.StringFlag{Name: "datacenter-id", Usage: "Datacenter ID"},
 			&cli.StringFlag{Name: "location", Usage: "Device location"},
+			&cli.StringFlag{Name: "status", Usage: "Lifecycle status (must be an allowed transition)"},
 			&cli.StringFlag{Name: "tags", Usage: "Comma-separated tags"},
 			&cli.StringFlag{Name: "domains", Usage: "Comma-separated domains"},
+			&cli.StringSliceFlag{Name: "field", Usage: "Custom field value as name=value (repeatable, replaces all custom field values)"},
+			&cli.StringSliceFlag{Name: "label", Usage: "Label as key=value (repeatable, replaces all labels)"},
+			&cli.StringFlag{Name: "serial", Usage: "Serial number (asset flags replace all asset data)"},
+			&cli.StringFlag{Name: "asset-tag", Usage: "Asset tag"},
+			&cli.StringFlag{Name: "vendor", Usage: "Vendor the device was purchased from"},
+			&cli.StringFlag{Name: "purchase-date", Usage: "Purchase date (YYYY-MM-DD)"},
+			&cli.StringFlag{Name: "po-number", Usage: "Purchase order number"},
+			&cli.Float64Flag{Name: "cost", Usage: "Purchase cost"},
+			&cli.StringFlag{Name: "warranty-expires", Usage: "Warranty expiry date (YYYY-MM-DD)"},
+			&cli.StringFlag{Name: "support-contract", Usage: "Support contract reference"},
 			&cli.StringFlag{Name: "addresses-json", Usage: "JSON array of addresses"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 		},
@@ -38,15 +51,44 @@ func UpdateCommand() *cli.Command {
 			id := cmd.GetStringArg("id")
 			log.Debug("Updating device", "id", id, "server", cmd.GetString("server"))
 			
-			updates := &model.Device{
-				Name:         cmd.GetString("name"),
-				Description:  cmd.GetString("description"),
-				MakeModel:    cmd.GetString("make-model"),
-				OS:           cmd.GetString("os"),
-				DatacenterID: cmd.GetString("datacenter-id"),
-				Location:     cmd.GetString("location"),
-				Tags:         parseList(cmd.GetString("tags")),
-				Domains:      parseList(cmd.GetString("domains")),
+			customFields, err := cliutil.ParseFields(cmd.GetStringSlice("field"))
+			if err != nil {
+				return err
+			}
+			labels, err := parseLabels(cmd.GetStringSlice("label"))
+			if err != nil {
+				return err
+			}
+
+			// Send only the given flags, so other fields and concurrent changes are kept
+			var ops []jsonpatch.Operation
+			for _, f := range []struct{ flag, path string }{
+				{"name", "/name"},
+				{"description", "/description"},
+				{"make-model", "/make_model"},
+				{"os", "/os"},
+				{"datacenter-id", "/datacenter_id"},
+				{"location", "/location"},
+				{"status", "/status"},
+			} {
+				if cmd.HasFlag(f.flag) {
+					ops = append(ops, jsonpatch.Add(f.path, cmd.GetString(f.flag)))
+				}
+			}
+			if cmd.HasFlag("tags") {
+				ops = append(ops, jsonpatch.Add("/tags", parseList(cmd.GetString("tags"))))
+			}
+			if cmd.HasFlag("domains") {
+				ops = append(ops, jsonpatch.Add("/domains", parseList(cmd.GetString("domains"))))
+			}
+			if cmd.HasFlag("field") {
+				ops = append(ops, jsonpatch.Add("/custom_fields", customFields))
+			}
+			if cmd.HasFlag("label") {
+				ops = append(ops, jsonpatch.Add("/labels", labels))
+			}
+			if asset := parseAsset(cmd); asset != nil {
+				ops = append(ops, jsonpatch.Add("/asset", asset))
 			}
 
 			// Add addresses if provided
@@ -56,22 +98,26 @@ func UpdateCommand() *cli.Command {
 					log.Error("Invalid addresses JSON", "error", err, "id", id)
 					return fmt.Errorf("invalid addresses JSON: %w", err)
 				}
-				updates.Addresses = addresses
+				ops = append(ops, jsonpatch.Add("/addresses", addresses))
+			}
+
+			if len(ops) == 0 {
+				return fmt.Errorf("nothing to update")
 			}
 
-			data, err := json.Marshal(updates)
+			data, err := json.Marshal(ops)
 			if err != nil {
 				log.Error("Failed to marshal update data", "error", err, "id", id)
 				return err
 			}
 
 			client := &http.Client{Timeout: 30 * time.Second}
-			req, err := http.NewRequest("PUT", cmd.GetString("server")+"/api/devices/"+id, strings.NewReader(string(data)))
+			req, err := http.NewRequest("PATCH", cmd.GetString("server")+"/api/devices/"+id, strings.NewReader(string(data)))
 			if err != nil {
 				log.Error("Failed to create update request", "error", err, "id", id)
 				return err
 			}
-			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Type", jsonpatch.JSONPatchType)
 
 			resp, err := client.Do(req)
 			if err != nil {
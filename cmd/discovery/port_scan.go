@@ -186,5 +186,7 @@ func Commands() []*cli.Command {
 	return []*cli.Command{
 		TestScanCommand(),
 		TestPortScanCommand(),
+		WatchCommand(),
+		ReconcileCommand(),
 	}
 }
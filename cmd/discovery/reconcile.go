@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+// ReconcileCommand reports drift between discovered hosts and inventory devices
+func ReconcileCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "reconcile",
+		Usage:       "Compare discovered hosts with inventory devices",
+		Description: "Match discovered hosts to devices by IP, MAC address and hostname, and report MAC and hostname mismatches, moved and unrecorded addresses, and devices not seen in recent scans",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "network", Usage: "Only reconcile this network ID"},
+			&cli.IntFlag{Name: "missed-scans", Usage: "Scans a device must be missing from to be reported as not seen (default 3)"},
+			&cli.StringFlag{Name: "format", Usage: "Output format: table or json", DefaultValue: "table"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			format := cmd.GetString("format")
+			if format != "table" && format != "json" {
+				return fmt.Errorf("format must be table or json")
+			}
+
+			query := url.Values{}
+			if network := cmd.GetString("network"); network != "" {
+				query.Set("network_id", network)
+			}
+			if missed := cmd.GetInt("missed-scans"); missed != 0 {
+				query.Set("missed_scans", strconv.Itoa(missed))
+			}
+			endpoint := cmd.GetString("server") + "/api/reconcile"
+			if len(query) > 0 {
+				endpoint += "?" + query.Encode()
+			}
+
+			req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+			if err != nil {
+				return err
+			}
+			if token := cmd.GetString("api-token"); token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			client := &http.Client{Timeout: 60 * time.Second}
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Error("Failed to connect to server for reconciliation", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for reconciliation", "status", resp.StatusCode, "body", string(body))
+				return fmt.Errorf("server error: %s", strings.TrimSpace(string(body)))
+			}
+
+			var report model.ReconcileReport
+			if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+				log.Error("Failed to decode reconciliation report", "error", err)
+				return err
+			}
+			log.Info("Retrieved reconciliation report", "matched", report.Matched, "findings", len(report.Findings))
+
+			if format == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(report)
+			}
+			printReport(os.Stdout, &report)
+			return nil
+		},
+	}
+}
+
+// printReport writes the summary and findings of a reconciliation report as a table
+func printReport(out io.Writer, report *model.ReconcileReport) {
+	var counts []string
+	for _, t := range model.DriftTypes {
+		counts = append(counts, fmt.Sprintf("%s %d", t, report.Summary[t]))
+	}
+	fmt.Fprintf(out, "Matched %d live hosts; %s\n", report.Matched, strings.Join(counts, ", "))
+
+	if len(report.Findings) == 0 {
+		fmt.Fprintln(out, "No drift found")
+		return
+	}
+
+	fmt.Fprintln(out)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tIP\tDEVICE\tEXPECTED\tOBSERVED")
+	for _, f := range report.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.Type, dash(f.IP), dash(f.DeviceName), dash(f.Expected), dash(f.Observed))
+	}
+	tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
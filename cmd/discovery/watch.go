@@ -0,0 +1,158 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/config"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+// progressBarWidth is the number of characters of the progress bar
+const progressBarWidth = 30
+
+// WatchCommand follows the progress of a discovery scan
+func WatchCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "watch",
+		Usage:       "Watch the progress of a discovery scan",
+		Description: "Show a live progress bar of a running discovery scan and the devices it finds, until the scan ends",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "scan-id", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			scanID := cmd.GetStringArg("scan-id")
+			log.Debug("Watching discovery scan", "scan_id", scanID)
+
+			req, err := http.NewRequestWithContext(ctx, "GET", cmd.GetString("server")+"/api/discovery/scans/"+scanID+"/events", nil)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Accept", "text/event-stream")
+			if token := cmd.GetString("api-token"); token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			// No client timeout, the stream stays open for as long as the scan runs
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				log.Error("Failed to connect to server for scan events", "error", err, "scan_id", scanID)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode == http.StatusNotFound {
+				return fmt.Errorf("scan not found")
+			}
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for scan events", "status", resp.Status, "scan_id", scanID)
+				return fmt.Errorf("server error: %s", strings.TrimSpace(string(body)))
+			}
+
+			var final *model.DiscoveryScan
+			err = readEvents(resp.Body, func(event string, data []byte) error {
+				switch event {
+				case "progress", "complete":
+					var scan model.DiscoveryScan
+					if err := json.Unmarshal(data, &scan); err != nil {
+						return fmt.Errorf("decoding %s event: %w", event, err)
+					}
+					fmt.Print(progressLine(&scan))
+					if event == "complete" {
+						final = &scan
+					}
+				case "found":
+					var device model.DiscoveredDevice
+					if err := json.Unmarshal(data, &device); err != nil {
+						return fmt.Errorf("decoding found event: %w", err)
+					}
+					// Clear the progress bar, print the device above it; the next update redraws the bar
+					fmt.Printf("\r\033[K+ %s", device.IP)
+					if device.Hostname != "" {
+						fmt.Printf(" (%s)", device.Hostname)
+					}
+					fmt.Printf(" %s\n", device.Status)
+				}
+				return nil
+			})
+			fmt.Println()
+			if err != nil {
+				return err
+			}
+
+			if final == nil {
+				return fmt.Errorf("event stream ended before the scan finished")
+			}
+			fmt.Printf("Scan %s: %d of %d hosts scanned, %d found", final.Status, final.ScannedHosts, final.TotalHosts, final.FoundHosts)
+			if final.DurationSeconds > 0 {
+				fmt.Printf(" in %ds", final.DurationSeconds)
+			}
+			fmt.Println()
+			if c := final.Changes; c != nil && c.PreviousScanID != "" {
+				fmt.Printf("Changes since scan %s: %d new, %d disappeared, %d changed hosts, %d opened, %d closed ports\n",
+					c.PreviousScanID, c.NewHosts, c.DisappearedHosts, c.ChangedHosts, c.OpenedPorts, c.ClosedPorts)
+			}
+			if final.Status == "failed" {
+				return fmt.Errorf("scan failed: %s", final.ErrorMessage)
+			}
+			return nil
+		},
+	}
+}
+
+// readEvents reads a Server-Sent Events stream, calling fn with the name and
+// data of each event until the stream ends
+func readEvents(r io.Reader, fn func(event string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	event := "message"
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if err := fn(event, []byte(strings.Join(data, "\n"))); err != nil {
+					return err
+				}
+			}
+			event, data = "message", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment, sent to keep the connection open
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return scanner.Err()
+}
+
+// progressLine renders the progress bar of a scan, redrawing the current line
+func progressLine(scan *model.DiscoveryScan) string {
+	filled := int(scan.ProgressPercent / 100 * progressBarWidth)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+	return fmt.Sprintf("\r\033[K[%s] %5.1f%%  %d/%d hosts  %d found  %s",
+		bar, scan.ProgressPercent, scan.ScannedHosts, scan.TotalHosts, scan.FoundHosts, scan.Status)
+}
+
+func getDefaultServerURL() string {
+	cfg := config.Load()
+	return "http://localhost" + cfg.ListenAddr
+}
@@ -0,0 +1,146 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/config"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+// Command returns the rackd export command
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Export the complete inventory to a versioned JSON file",
+		Description: "Write datacenters, networks, pools, devices, relationships, software inventory, custom fields, " +
+			"discovery rules and discovered devices to a single file that rackd restore can read back.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output file, defaults to stdout"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			log.Debug("Exporting inventory", "server", cmd.GetString("server"))
+
+			resp, err := makeRequest("GET", cmd.GetString("server")+"/api/export", cmd.GetString("api-token"), nil)
+			if err != nil {
+				log.Error("Failed to connect to server for export", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				data, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for export", "status", resp.StatusCode, "body", string(data))
+				return fmt.Errorf("server error: %s", string(data))
+			}
+
+			var out io.Writer = os.Stdout
+			if path := cmd.GetString("output"); path != "" {
+				f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+				if err != nil {
+					return fmt.Errorf("creating export file: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			if _, err := io.Copy(out, resp.Body); err != nil {
+				return fmt.Errorf("writing export: %w", err)
+			}
+
+			log.Info("Export finished", "output", cmd.GetString("output"))
+			return nil
+		},
+	}
+}
+
+// RestoreCommand returns the rackd restore command
+func RestoreCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "restore",
+		Usage: "Restore the inventory from a file written by rackd export",
+		Description: "Validate the export and load it in a single transaction, keeping IDs and timestamps. " +
+			"The inventory must be empty unless --replace is given, which removes all existing data first.",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "file", Usage: "Export file to restore, - for stdin", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "replace", Usage: "Replace all existing data"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			path := cmd.GetStringArg("file")
+			log.Debug("Restoring inventory", "file", path, "server", cmd.GetString("server"))
+
+			var body io.Reader
+			if path == "-" {
+				body = os.Stdin
+			} else {
+				f, err := os.Open(path)
+				if err != nil {
+					return fmt.Errorf("opening export file: %w", err)
+				}
+				defer f.Close()
+				body = f
+			}
+
+			endpoint := cmd.GetString("server") + "/api/restore"
+			if cmd.GetBool("replace") {
+				endpoint += "?replace=true"
+			}
+			resp, err := makeRequest("POST", endpoint, cmd.GetString("api-token"), body)
+			if err != nil {
+				log.Error("Failed to connect to server for restore", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				data, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for restore", "status", resp.StatusCode, "body", string(data))
+				return fmt.Errorf("server error: %s", string(data))
+			}
+
+			var result model.RestoreResult
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				log.Error("Failed to decode restore response", "error", err)
+				return err
+			}
+
+			fmt.Printf("Restored export version %d:\n", result.Version)
+			fmt.Printf("  %d datacenters, %d networks, %d pools\n", result.Datacenters, result.Networks, result.Pools)
+			fmt.Printf("  %d devices, %d relationships, %d software inventories\n", result.Devices, result.Relationships, result.Software)
+			fmt.Printf("  %d custom fields, %d discovery rules, %d discovered devices\n", result.CustomFields, result.DiscoveryRules, result.DiscoveredDevices)
+
+			log.Info("Restore finished", "devices", result.Devices)
+			return nil
+		},
+	}
+}
+
+func getDefaultServerURL() string {
+	cfg := config.Load()
+	return "http://localhost" + cfg.ListenAddr
+}
+
+func makeRequest(method, url, token string, body io.Reader) (*http.Response, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req)
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/config"
+	dataimport "github.com/martinsuchenak/rackd/internal/importer"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "Bulk import datacenters, networks, pools, devices and relationships",
+		Description: "Import a CSV, JSON or YAML file in a single transaction. Rows are matched to existing entities by ID, then by name, " +
+			"and updated in place; other rows are created. Nothing is changed if any row fails.",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "file", Usage: "File to import, - for stdin", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Usage: "File format (csv, json, yaml), defaults to the file extension"},
+			&cli.StringFlag{Name: "entity", Usage: "Entity type of the rows (datacenter, network, pool, device, relationship), required for CSV"},
+			&cli.StringSliceFlag{Name: "map", Usage: "Map a source column to a field (source=field, empty field drops the column)"},
+			&cli.BoolFlag{Name: "dry-run", Usage: "Report what would change without changing anything"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			path := cmd.GetStringArg("file")
+			log.Debug("Importing file", "file", path, "server", cmd.GetString("server"))
+
+			format := dataimport.FormatFromFilename(path)
+			if f := cmd.GetString("format"); f != "" {
+				var err error
+				if format, err = dataimport.ParseFormat(f); err != nil {
+					return err
+				}
+			}
+			if format == "" {
+				return fmt.Errorf("cannot determine the format of %s, use --format", path)
+			}
+
+			var body io.Reader
+			if path == "-" {
+				body = os.Stdin
+			} else {
+				f, err := os.Open(path)
+				if err != nil {
+					return fmt.Errorf("opening import file: %w", err)
+				}
+				defer f.Close()
+				body = f
+			}
+
+			params := url.Values{}
+			params.Set("format", string(format))
+			if entity := cmd.GetString("entity"); entity != "" {
+				params.Set("entity", entity)
+			}
+			for _, m := range cmd.GetStringSlice("map") {
+				params.Add("mapping", m)
+			}
+			if cmd.GetBool("dry-run") {
+				params.Set("dry_run", "true")
+			}
+
+			endpoint := cmd.GetString("server") + "/api/import?" + params.Encode()
+			resp, err := makeRequest("POST", endpoint, cmd.GetString("api-token"), body)
+			if err != nil {
+				log.Error("Failed to connect to server for import", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnprocessableEntity {
+				data, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for import", "status", resp.StatusCode, "body", string(data))
+				return fmt.Errorf("server error: %s", string(data))
+			}
+
+			var result model.ImportResult
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				log.Error("Failed to decode import response", "error", err)
+				return err
+			}
+
+			printResult(os.Stdout, &result)
+
+			if result.Failed > 0 {
+				return fmt.Errorf("%d rows failed, nothing was imported", result.Failed)
+			}
+			log.Info("Import finished", "created", result.Created, "updated", result.Updated, "dry_run", result.DryRun)
+			return nil
+		},
+	}
+}
+
+func getDefaultServerURL() string {
+	cfg := config.Load()
+	return "http://localhost" + cfg.ListenAddr
+}
+
+func makeRequest(method, url, token string, body io.Reader) (*http.Response, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req)
+}
+
+func printResult(out io.Writer, result *model.ImportResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENTITY\tROW\tACTION\tNAME\tID\tERROR")
+	for _, row := range result.Rows {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", row.Entity, row.Row, row.Action, row.Name, row.ID, row.Error)
+	}
+	w.Flush()
+
+	status := "committed"
+	switch {
+	case result.DryRun:
+		status = "dry run, nothing changed"
+	case !result.Committed:
+		status = "rolled back"
+	}
+	fmt.Fprintf(out, "\n%d created, %d updated, %d unchanged, %d failed (%s)\n",
+		result.Created, result.Updated, result.Unchanged, result.Failed, status)
+}
@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func GetCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "get",
+		Usage:       "Show a background job",
+		Description: "Show a background job with its parameters, result and log",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "id", Usage: "Job ID", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			id := cmd.GetStringArg("id")
+			log.Debug("Getting job", "id", id)
+
+			resp, err := makeRequest("GET", cmd.GetString("server")+"/api/jobs/"+url.PathEscape(id), cmd.GetString("api-token"))
+			if err != nil {
+				log.Error("Failed to connect to server for job", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				log.Error("Server returned error for job", "status", resp.StatusCode)
+				return serverError(resp)
+			}
+
+			var job model.Job
+			if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+				log.Error("Failed to decode job", "error", err)
+				return err
+			}
+
+			printJob(&job)
+			return nil
+		},
+	}
+}
+
+// printJob prints the details of a job
+func printJob(job *model.Job) {
+	fmt.Printf("ID:        %s\n", job.ID)
+	fmt.Printf("Type:      %s\n", job.Type)
+	fmt.Printf("Status:    %s\n", job.Status)
+	fmt.Printf("Attempts:  %d/%d\n", job.Attempts, job.MaxAttempts)
+	fmt.Printf("Created:   %s\n", job.CreatedAt.Local().Format(time.DateTime))
+	if job.Status == model.JobStatusPending {
+		fmt.Printf("Run at:    %s\n", job.RunAt.Local().Format(time.DateTime))
+	}
+	fmt.Printf("Started:   %s\n", formatTime(job.StartedAt))
+	fmt.Printf("Finished:  %s\n", formatTime(job.FinishedAt))
+	if job.Error != "" {
+		fmt.Printf("Error:     %s\n", job.Error)
+	}
+	if len(job.Params) > 0 {
+		fmt.Printf("Params:    %s\n", job.Params)
+	}
+	if len(job.Result) > 0 {
+		var out bytes.Buffer
+		if json.Indent(&out, job.Result, "", "  ") == nil {
+			fmt.Printf("Result:\n%s\n", out.String())
+		}
+	}
+	if len(job.Logs) > 0 {
+		fmt.Println("Log:")
+		for _, entry := range job.Logs {
+			fmt.Printf("  %s  %s\n", entry.Time.Local().Format(time.DateTime), entry.Message)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/config"
+	"github.com/paularlott/cli"
+)
+
+func Commands() []*cli.Command {
+	return []*cli.Command{
+		ListCommand(),
+		GetCommand(),
+		RetryCommand(),
+	}
+}
+
+func getDefaultServerURL() string {
+	cfg := config.Load()
+	return "http://localhost" + cfg.ListenAddr
+}
+
+func makeRequest(method, url, token string) (*http.Response, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req)
+}
+
+// formatTime formats an optional time for display
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+func serverError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("server error: %s", string(body))
+}
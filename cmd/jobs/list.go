@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func ListCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "list",
+		Usage:       "List background jobs",
+		Description: "List background jobs, newest first",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "type", Usage: "Only jobs of this type: discovery, cleanup, backup, report or import"},
+			&cli.StringFlag{Name: "status", Usage: "Only jobs with this status: pending, running, completed or failed"},
+			&cli.IntFlag{Name: "limit", Usage: "Maximum number of jobs to list", DefaultValue: 50},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			log.Debug("Listing jobs", "server", cmd.GetString("server"))
+
+			query := url.Values{}
+			if t := cmd.GetString("type"); t != "" {
+				query.Set("type", t)
+			}
+			if s := cmd.GetString("status"); s != "" {
+				query.Set("status", s)
+			}
+			query.Set("limit", strconv.Itoa(cmd.GetInt("limit")))
+
+			resp, err := makeRequest("GET", cmd.GetString("server")+"/api/jobs?"+query.Encode(), cmd.GetString("api-token"))
+			if err != nil {
+				log.Error("Failed to connect to server for job list", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				log.Error("Server returned error for job list", "status", resp.StatusCode)
+				return serverError(resp)
+			}
+
+			var jobs []model.Job
+			if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+				log.Error("Failed to decode job list", "error", err)
+				return err
+			}
+
+			if len(jobs) == 0 {
+				fmt.Println("No jobs found")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tATTEMPTS\tCREATED\tFINISHED\tERROR")
+			for _, j := range jobs {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%s\t%s\t%s\n", j.ID, j.Type, j.Status, j.Attempts, j.MaxAttempts,
+					j.CreatedAt.Local().Format(time.DateTime), formatTime(j.FinishedAt), j.Error)
+			}
+			return w.Flush()
+		},
+	}
+}
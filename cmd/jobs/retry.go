@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func RetryCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "retry",
+		Usage:       "Retry a failed background job",
+		Description: "Queue a failed background job again with a fresh set of attempts",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "id", Usage: "Job ID", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			id := cmd.GetStringArg("id")
+			log.Debug("Retrying job", "id", id)
+
+			resp, err := makeRequest("POST", cmd.GetString("server")+"/api/jobs/"+url.PathEscape(id)+"/retry", cmd.GetString("api-token"))
+			if err != nil {
+				log.Error("Failed to connect to server for job retry", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusAccepted {
+				log.Error("Server returned error for job retry", "status", resp.StatusCode)
+				return serverError(resp)
+			}
+
+			var job model.Job
+			if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+				log.Error("Failed to decode job", "error", err)
+				return err
+			}
+
+			fmt.Printf("Job %s queued again\n", job.ID)
+			return nil
+		},
+	}
+}
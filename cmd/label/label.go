@@ -0,0 +1,58 @@
+package label
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/config"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func Commands() []*cli.Command {
+	return []*cli.Command{
+		MigrateTagsCommand(),
+	}
+}
+
+func getDefaultServerURL() string {
+	cfg := config.Load()
+	return "http://localhost" + cfg.ListenAddr
+}
+
+func makeRequest(method, url, token string) (*http.Response, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req)
+}
+
+func printMigration(kind string, changes []model.LabelMigrationChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", kind)
+	for _, c := range changes {
+		keys := make([]string, 0, len(c.Labels))
+		for key := range c.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = key + "=" + c.Labels[key]
+		}
+		fmt.Printf("  %s\t%s\t%s\n", c.ID, c.Name, strings.Join(pairs, ","))
+		for _, tag := range c.Skipped {
+			fmt.Printf("    skipped %q (label already set to a different value)\n", tag)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package label
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func MigrateTagsCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "migrate-tags",
+		Usage:       "Convert key=value tags into labels",
+		Description: "Convert key=value tags of devices and pools into labels, removing the migrated tags",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "dry-run", Usage: "Show the changes without applying them"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			dryRun := cmd.GetBool("dry-run")
+			log.Debug("Migrating tags to labels", "dry_run", dryRun, "server", cmd.GetString("server"))
+
+			endpoint := cmd.GetString("server") + "/api/labels/migrate-tags"
+			if dryRun {
+				endpoint += "?dry_run=true"
+			}
+
+			resp, err := makeRequest("POST", endpoint, cmd.GetString("api-token"))
+			if err != nil {
+				log.Error("Failed to connect to server for tag migration", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for tag migration", "status", resp.StatusCode, "body", string(body))
+				return fmt.Errorf("server error: %s", string(body))
+			}
+
+			var result model.LabelMigration
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				log.Error("Failed to decode tag migration response", "error", err)
+				return err
+			}
+
+			log.Info("Migrated tags to labels", "devices", len(result.Devices), "pools", len(result.Pools), "dry_run", dryRun)
+			if len(result.Devices) == 0 && len(result.Pools) == 0 {
+				fmt.Println("No key=value tags to migrate")
+				return nil
+			}
+			printMigration("Devices", result.Devices)
+			printMigration("Pools", result.Pools)
+			if dryRun {
+				fmt.Println("Dry run, no changes were made")
+			}
+			return nil
+		},
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/martinsuchenak/rackd/cmd/cliutil"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/paularlott/cli"
@@ -24,17 +25,30 @@ func AddCommand() *cli.Command {
 			&cli.StringFlag{Name: "subnet", Usage: "Network subnet (CIDR notation)", Required: true},
 			&cli.StringFlag{Name: "datacenter-id", Usage: "Datacenter ID", Required: true},
 			&cli.StringFlag{Name: "description", Usage: "Network description"},
+			&cli.StringSliceFlag{Name: "field", Usage: "Custom field value as name=value (repeatable)"},
+			&cli.StringSliceFlag{Name: "label", Usage: "Label as key=value (repeatable)"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 		},
 		Run: func(ctx context.Context, cmd *cli.Command) error {
 			networkName := cmd.GetString("name")
 			log.Debug("Adding network", "name", networkName, "subnet", cmd.GetString("subnet"), "server", cmd.GetString("server"))
 			
+			customFields, err := cliutil.ParseFields(cmd.GetStringSlice("field"))
+			if err != nil {
+				return err
+			}
+			labels, err := parseLabels(cmd.GetStringSlice("label"))
+			if err != nil {
+				return err
+			}
+
 			network := &model.Network{
 				Name:         networkName,
 				Subnet:       cmd.GetString("subnet"),
 				DatacenterID: cmd.GetString("datacenter-id"),
 				Description:  cmd.GetString("description"),
+				Labels:       labels,
+				CustomFields: customFields,
 			}
 
 			data, err := json.Marshal(network)
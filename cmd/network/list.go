@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/martinsuchenak/rackd/internal/log"
@@ -19,19 +20,28 @@ func ListCommand() *cli.Command {
 		Description: "List all networks in the inventory",
 		Flags: []cli.Flag{
 			&cli.StringFlag{Name: "datacenter-id", Usage: "Filter by datacenter ID"},
+			&cli.StringFlag{Name: "selector", Aliases: []string{"l"}, Usage: "Filter by label selector (e.g. env=prod,role in (db,cache))"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 		},
 		Run: func(ctx context.Context, cmd *cli.Command) error {
 			dcID := cmd.GetString("datacenter-id")
 			log.Debug("Listing networks", "datacenter_id", dcID, "server", cmd.GetString("server"))
 			
-			url := cmd.GetString("server") + "/api/networks"
+			params := url.Values{}
 			if dcID != "" {
-				url += "?datacenter_id=" + dcID
+				params.Set("datacenter_id", dcID)
+			}
+			if selector := cmd.GetString("selector"); selector != "" {
+				params.Set("selector", selector)
+			}
+
+			endpoint := cmd.GetString("server") + "/api/networks"
+			if len(params) > 0 {
+				endpoint += "?" + params.Encode()
 			}
 
 			client := &http.Client{Timeout: 30 * time.Second}
-			resp, err := client.Get(url)
+			resp, err := client.Get(endpoint)
 			if err != nil {
 				log.Error("Failed to connect to server for network list", "error", err)
 				return fmt.Errorf("failed to connect to server: %w", err)
@@ -49,7 +59,7 @@ func ListCommand() *cli.Command {
 				return err
 			}
 
-			log.Info("Listed networks successfully", "count", len(networks), "filtered", dcID != "")
+			log.Info("Listed networks successfully", "count", len(networks), "filtered", len(params) > 0)
 			printNetworks(networks)
 			return nil
 		},
@@ -2,6 +2,8 @@ package network
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/martinsuchenak/rackd/internal/config"
@@ -21,6 +23,53 @@ func Commands() []*cli.Command {
 	}
 }
 
+// parseLabels converts key=value pairs from --label flags into a label map
+func parseLabels(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+func printLabels(labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Println("Labels:")
+	for _, key := range keys {
+		fmt.Printf("  - %s=%s\n", key, labels[key])
+	}
+}
+
+func printCustomFields(fields map[string]string) {
+	if len(fields) == 0 {
+		return
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("Custom Fields:")
+	for _, name := range names {
+		fmt.Printf("  - %s: %s\n", name, fields[name])
+	}
+}
+
 func getDefaultServerURL() string {
 	cfg := config.Load()
 	return "http://localhost" + cfg.ListenAddr
@@ -44,6 +93,8 @@ func printNetwork(network *model.Network) {
 	fmt.Printf("Description:  %s\n", network.Description)
 	fmt.Printf("Created:      %s\n", network.CreatedAt.Format(time.RFC3339))
 	fmt.Printf("Updated:      %s\n", network.UpdatedAt.Format(time.RFC3339))
+	printLabels(network.Labels)
+	printCustomFields(network.CustomFields)
 }
 
 func printDevices(devices []model.Device) {
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/martinsuchenak/rackd/internal/jsonpatch"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/paularlott/cli"
@@ -39,14 +41,20 @@ func PoolListCommand() *cli.Command {
 			&cli.StringArg{Name: "network-id", Required: true},
 		},
 		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "selector", Aliases: []string{"l"}, Usage: "Filter by label selector (e.g. env=prod,role in (db,cache))"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 		},
 		Run: func(ctx context.Context, cmd *cli.Command) error {
 			networkID := cmd.GetStringArg("network-id")
 			log.Debug("Listing network pools", "network_id", networkID)
 			
+			endpoint := cmd.GetString("server") + "/api/networks/" + networkID + "/pools"
+			if selector := cmd.GetString("selector"); selector != "" {
+				endpoint += "?" + url.Values{"selector": {selector}}.Encode()
+			}
+
 			client := &http.Client{Timeout: 30 * time.Second}
-			resp, err := client.Get(cmd.GetString("server") + "/api/networks/" + networkID + "/pools")
+			resp, err := client.Get(endpoint)
 			if err != nil {
 				log.Error("Failed to connect to server for pools list", "error", err, "network_id", networkID)
 				return fmt.Errorf("failed to connect to server: %w", err)
@@ -88,6 +96,7 @@ func PoolAddCommand() *cli.Command {
 			&cli.StringFlag{Name: "start-ip", Usage: "Start IP address", Required: true},
 			&cli.StringFlag{Name: "end-ip", Usage: "End IP address", Required: true},
 			&cli.StringFlag{Name: "description", Usage: "Pool description"},
+			&cli.StringSliceFlag{Name: "label", Usage: "Label as key=value (repeatable)"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 		},
 		Run: func(ctx context.Context, cmd *cli.Command) error {
@@ -95,12 +104,18 @@ func PoolAddCommand() *cli.Command {
 			poolName := cmd.GetString("name")
 			log.Debug("Adding network pool", "network_id", networkID, "name", poolName)
 			
+			labels, err := parseLabels(cmd.GetStringSlice("label"))
+			if err != nil {
+				return err
+			}
+
 			pool := &model.NetworkPool{
 				NetworkID:   networkID,
 				Name:        poolName,
 				StartIP:     cmd.GetString("start-ip"),
 				EndIP:       cmd.GetString("end-ip"),
 				Description: cmd.GetString("description"),
+				Labels:      labels,
 			}
 
 			data, err := json.Marshal(pool)
@@ -186,28 +201,47 @@ func PoolUpdateCommand() *cli.Command {
 			&cli.StringFlag{Name: "start-ip", Usage: "Start IP address"},
 			&cli.StringFlag{Name: "end-ip", Usage: "End IP address"},
 			&cli.StringFlag{Name: "description", Usage: "Pool description"},
+			&cli.StringSliceFlag{Name: "label", Usage: "Label as key=value (repeatable, replaces all labels)"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 		},
 		Run: func(ctx context.Context, cmd *cli.Command) error {
 			poolID := cmd.GetStringArg("pool-id")
-			updates := &model.NetworkPool{
-				Name:        cmd.GetString("name"),
-				StartIP:     cmd.GetString("start-ip"),
-				EndIP:       cmd.GetString("end-ip"),
-				Description: cmd.GetString("description"),
+			labels, err := parseLabels(cmd.GetStringSlice("label"))
+			if err != nil {
+				return err
+			}
+
+			// Send only the given flags, so other fields and concurrent changes are kept
+			var ops []jsonpatch.Operation
+			for _, f := range []struct{ flag, path string }{
+				{"name", "/name"},
+				{"start-ip", "/start_ip"},
+				{"end-ip", "/end_ip"},
+				{"description", "/description"},
+			} {
+				if cmd.HasFlag(f.flag) {
+					ops = append(ops, jsonpatch.Add(f.path, cmd.GetString(f.flag)))
+				}
+			}
+			if cmd.HasFlag("label") {
+				ops = append(ops, jsonpatch.Add("/labels", labels))
+			}
+
+			if len(ops) == 0 {
+				return fmt.Errorf("nothing to update")
 			}
 
-			data, err := json.Marshal(updates)
+			data, err := json.Marshal(ops)
 			if err != nil {
 				return err
 			}
 
 			client := &http.Client{Timeout: 30 * time.Second}
-			req, err := http.NewRequest("PUT", cmd.GetString("server")+"/api/pools/"+poolID, strings.NewReader(string(data)))
+			req, err := http.NewRequest("PATCH", cmd.GetString("server")+"/api/pools/"+poolID, strings.NewReader(string(data)))
 			if err != nil {
 				return err
 			}
-			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Type", jsonpatch.JSONPatchType)
 
 			resp, err := client.Do(req)
 			if err != nil {
@@ -325,4 +359,5 @@ func printPool(pool *model.NetworkPool) {
 	fmt.Printf("Start IP:    %s\n", pool.StartIP)
 	fmt.Printf("End IP:      %s\n", pool.EndIP)
 	fmt.Printf("Description: %s\n", pool.Description)
+	printLabels(pool.Labels)
 }
\ No newline at end of file
@@ -9,8 +9,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/martinsuchenak/rackd/cmd/cliutil"
+	"github.com/martinsuchenak/rackd/internal/jsonpatch"
 	"github.com/martinsuchenak/rackd/internal/log"
-	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/paularlott/cli"
 )
 
@@ -27,32 +28,59 @@ func UpdateCommand() *cli.Command {
 			&cli.StringFlag{Name: "subnet", Usage: "Network subnet (CIDR notation)"},
 			&cli.StringFlag{Name: "datacenter-id", Usage: "Datacenter ID"},
 			&cli.StringFlag{Name: "description", Usage: "Network description"},
+			&cli.StringSliceFlag{Name: "field", Usage: "Custom field value as name=value (repeatable, replaces all custom field values)"},
+			&cli.StringSliceFlag{Name: "label", Usage: "Label as key=value (repeatable, replaces all labels)"},
 			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
 		},
 		Run: func(ctx context.Context, cmd *cli.Command) error {
 			id := cmd.GetStringArg("id")
 			log.Debug("Updating network", "id", id, "server", cmd.GetString("server"))
 			
-			updates := &model.Network{
-				Name:         cmd.GetString("name"),
-				Subnet:       cmd.GetString("subnet"),
-				DatacenterID: cmd.GetString("datacenter-id"),
-				Description:  cmd.GetString("description"),
+			customFields, err := cliutil.ParseFields(cmd.GetStringSlice("field"))
+			if err != nil {
+				return err
+			}
+			labels, err := parseLabels(cmd.GetStringSlice("label"))
+			if err != nil {
+				return err
+			}
+
+			// Send only the given flags, so other fields and concurrent changes are kept
+			var ops []jsonpatch.Operation
+			for _, f := range []struct{ flag, path string }{
+				{"name", "/name"},
+				{"subnet", "/subnet"},
+				{"datacenter-id", "/datacenter_id"},
+				{"description", "/description"},
+			} {
+				if cmd.HasFlag(f.flag) {
+					ops = append(ops, jsonpatch.Add(f.path, cmd.GetString(f.flag)))
+				}
+			}
+			if cmd.HasFlag("field") {
+				ops = append(ops, jsonpatch.Add("/custom_fields", customFields))
+			}
+			if cmd.HasFlag("label") {
+				ops = append(ops, jsonpatch.Add("/labels", labels))
+			}
+
+			if len(ops) == 0 {
+				return fmt.Errorf("nothing to update")
 			}
 
-			data, err := json.Marshal(updates)
+			data, err := json.Marshal(ops)
 			if err != nil {
 				log.Error("Failed to marshal network update data", "error", err, "id", id)
 				return err
 			}
 
 			client := &http.Client{Timeout: 30 * time.Second}
-			req, err := http.NewRequest("PUT", cmd.GetString("server")+"/api/networks/"+id, strings.NewReader(string(data)))
+			req, err := http.NewRequest("PATCH", cmd.GetString("server")+"/api/networks/"+id, strings.NewReader(string(data)))
 			if err != nil {
 				log.Error("Failed to create network update request", "error", err, "id", id)
 				return err
 			}
-			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Type", jsonpatch.JSONPatchType)
 
 			resp, err := client.Do(req)
 			if err != nil {
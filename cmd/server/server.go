@@ -7,12 +7,16 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/martinsuchenak/rackd/internal/api"
+	"github.com/martinsuchenak/rackd/internal/autopromote"
+	"github.com/martinsuchenak/rackd/internal/backup"
 	"github.com/martinsuchenak/rackd/internal/config"
 	"github.com/martinsuchenak/rackd/pkg/discovery"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/mcp"
+	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/martinsuchenak/rackd/pkg/registry"
 	"github.com/martinsuchenak/rackd/internal/scanner"
 	"github.com/martinsuchenak/rackd/internal/storage"
@@ -23,11 +27,11 @@ import (
 
 // initializeDiscoveryFromRegistry attempts to initialize discovery features from the premium registry.
 // Falls back to built-in implementations if premium features are not available.
-// Returns (scanner, scheduler, handler, usePremium)
+// Returns (scanner, scan manager, scheduler, handler, usePremium)
 func initializeDiscoveryFromRegistry(
 	cfg *config.Config,
 	discoveryStore storage.DiscoveryStorage,
-) (discovery.Scanner, *worker.Scheduler, *api.DiscoveryHandler, bool) {
+) (discovery.Scanner, *worker.ScanManager, *worker.Scheduler, *api.DiscoveryHandler, bool) {
 	reg := registry.GetRegistry()
 
 	var discoveryScanner discovery.Scanner
@@ -60,8 +64,21 @@ func initializeDiscoveryFromRegistry(
 		log.Info("Using built-in discovery scanner")
 	}
 
+	// Start the scan manager, which resumes scans interrupted by the last shutdown
+	scanManager := worker.NewScanManager(discoveryStore, discoveryScanner, cfg.DiscoveryMaxConcurrent)
+	scanManager.Start()
+
+	// Auto-promotion policies run after every completed scan, scheduled or not
+	if promoteStore, ok := discoveryStore.(autopromote.Store); ok {
+		scanManager.OnCompleted(func(networkID string) {
+			if _, err := autopromote.Run(promoteStore, networkID); err != nil {
+				log.Error("Failed to run promotion policies", "network_id", networkID, "error", err)
+			}
+		})
+	}
+
 	// Create discovery handler
-	discoveryHandler := api.NewDiscoveryHandler(discoveryStore, discoveryScanner)
+	discoveryHandler := api.NewDiscoveryHandler(discoveryStore, scanManager)
 
 	// Initialize scheduler if discovery is enabled
 	if cfg.DiscoveryEnabled {
@@ -75,6 +92,7 @@ func initializeDiscoveryFromRegistry(
 			schedulerInterface, err := schedulerFactory(map[string]interface{}{
 				"storage": discoveryStore,
 				"scanner": discoveryScanner,
+				"scans":   scanManager,
 				"config":  cfg,
 			})
 			if err != nil {
@@ -93,10 +111,13 @@ func initializeDiscoveryFromRegistry(
 
 		// Fall back to built-in scheduler if premium wasn't loaded
 		if discoveryScheduler == nil {
-			discoveryScheduler = worker.NewScheduler(discoveryStore, discoveryScanner)
+			discoveryScheduler = worker.NewScheduler(discoveryStore, scanManager)
 			log.Info("Using built-in discovery scheduler")
 		}
 
+		// Rule changes made through the API apply to the scheduler straight away
+		discoveryHandler.SetScheduler(discoveryScheduler)
+
 		// Start scheduler in background
 		discoveryScheduler.Start()
 		log.Info("Discovery scheduler started")
@@ -104,7 +125,7 @@ func initializeDiscoveryFromRegistry(
 		log.Info("Discovery disabled (scheduler not running). Manual scans via UI/API are still available.")
 	}
 
-	return discoveryScanner, discoveryScheduler, discoveryHandler, usePremium
+	return discoveryScanner, scanManager, discoveryScheduler, discoveryHandler, usePremium
 }
 
 // initializeEnterpriseRoutes registers enterprise-specific routes from the registry
@@ -167,6 +188,9 @@ type ServerConfig struct {
 	DiscoveryHandler   *api.DiscoveryHandler
 	DiscoveryScanner   discovery.Scanner
 	DiscoveryScheduler *worker.Scheduler
+	BackupHandler      *api.BackupHandler
+	SchedulerHandler   *api.SchedulerHandler
+	JobHandler         *api.JobHandler
 	MCPServer          *mcp.Server
 	APIHandler         *api.Handler
 	CustomUIHandler    http.HandlerFunc // Optional: override default UI handler
@@ -185,6 +209,21 @@ func RunServer(cfg *ServerConfig) error {
 		cfg.DiscoveryHandler.RegisterRoutes(mux)
 	}
 
+	// Backup admin routes
+	if cfg.BackupHandler != nil {
+		cfg.BackupHandler.RegisterRoutes(mux)
+	}
+
+	// Scheduler routes
+	if cfg.SchedulerHandler != nil {
+		cfg.SchedulerHandler.RegisterRoutes(mux)
+	}
+
+	// Job routes
+	if cfg.JobHandler != nil {
+		cfg.JobHandler.RegisterRoutes(mux)
+	}
+
 	// Enterprise routes (if registered)
 	initializeEnterpriseRoutes(mux, registry.GetRegistry(), cfg.Store)
 
@@ -261,13 +300,17 @@ func Command() *cli.Command {
 
 			log.Info("Configuration loaded", "data_dir", cfg.DataDir, "listen_addr", cfg.ListenAddr)
 
-			// Initialize storage (SQLite only)
-			store, err := storage.NewStorage(cfg.DataDir, "", "")
+			// Initialize storage
+			store, err := storage.NewStorage(cfg.DataDir, cfg.StorageType, cfg.PostgresDSN)
 			if err != nil {
 				log.Error("Failed to initialize storage", "error", err)
 				return err
 			}
-			log.Info("Storage initialized", "backend", "SQLite", "path", cfg.DataDir)
+			if cfg.StorageType == storage.TypePostgres {
+				log.Info("Storage initialized", "backend", "PostgreSQL")
+			} else {
+				log.Info("Storage initialized", "backend", "SQLite", "path", cfg.DataDir)
+			}
 
 			// Create API handler
 			apiHandler := api.NewHandler(store)
@@ -277,12 +320,19 @@ func Command() *cli.Command {
 			var discoveryHandler *api.DiscoveryHandler
 			var discoveryScheduler *worker.Scheduler
 			var discoveryScanner discovery.Scanner
+			var scanManager *worker.ScanManager
 
 			if ok {
 				log.Info("Discovery storage initialized")
 
 				// Initialize discovery features from registry (with fallback to built-in)
-				discoveryScanner, discoveryScheduler, discoveryHandler, _ = initializeDiscoveryFromRegistry(cfg, discoveryStore)
+				discoveryScanner, scanManager, discoveryScheduler, discoveryHandler, _ = initializeDiscoveryFromRegistry(cfg, discoveryStore)
+
+				// Running scans are stopped on shutdown and resumed on the next start
+				defer func() {
+					log.Info("Stopping discovery scans...")
+					scanManager.Stop()
+				}()
 
 				// Defer stopping the scheduler if it was created
 				if discoveryScheduler != nil {
@@ -296,6 +346,74 @@ func Command() *cli.Command {
 				log.Warn("Storage does not support discovery, discovery features will be unavailable")
 			}
 
+			// Initialize backups
+			var backupHandler *api.BackupHandler
+			var backupManager *backup.Manager
+			if backupStore, ok := store.(storage.BackupStorage); ok {
+				backupManager = backup.NewManager(backupStore, backup.Options{
+					Dir:        cfg.BackupDir,
+					Compress:   cfg.BackupCompress,
+					KeepDaily:  cfg.BackupKeepDaily,
+					KeepWeekly: cfg.BackupKeepWeekly,
+				})
+				backupHandler = api.NewBackupHandler(backupManager)
+			} else {
+				log.Warn("Storage does not support backups, backup features will be unavailable")
+			}
+
+			// Initialize background jobs; scheduled jobs share the discovery scheduler when it runs
+			var jobHandler *api.JobHandler
+			scheduler := discoveryScheduler
+			if jobStore, ok := store.(storage.JobStorage); ok {
+				jobManager := worker.NewJobManager(jobStore, cfg.JobWorkers, cfg.JobRetryBackoff)
+				if discoveryStore != nil {
+					jobManager.Register(model.JobTypeCleanup, worker.CleanupJob(discoveryStore, cfg.DiscoveryCleanupDays), 3)
+					jobManager.Register(model.JobTypeDiscovery, worker.DiscoveryJob(scanManager), 3)
+				}
+				if backupManager != nil {
+					jobManager.Register(model.JobTypeBackup, worker.BackupJob(backupManager), 3)
+				}
+				if assetStore, ok := store.(storage.AssetStorage); ok {
+					jobManager.Register(model.JobTypeReport, worker.ReportJob(assetStore), 1)
+				}
+				if importStore, ok := store.(storage.ImportStorage); ok {
+					jobManager.Register(model.JobTypeImport, worker.ImportJob(importStore), 1)
+				}
+
+				// Jobs cut short by the shutdown run again on the next start
+				jobManager.Start()
+				defer func() {
+					log.Info("Stopping background jobs...")
+					jobManager.Stop()
+				}()
+				jobHandler = api.NewJobHandler(jobStore, jobManager)
+
+				if scheduler == nil {
+					scheduler = worker.NewScheduler(nil, nil)
+					scheduler.Start()
+					defer scheduler.Stop()
+				}
+
+				if discoveryStore != nil && cfg.DiscoveryCleanupDays > 0 {
+					if err := scheduler.RegisterTask(jobManager.Task("cleanup", "Discovered device cleanup", model.JobTypeCleanup, 24*time.Hour, nil)); err != nil {
+						log.Error("Failed to schedule discovered device cleanup", "error", err)
+						return err
+					}
+					log.Info("Scheduled discovered device cleanup", "older_than_days", cfg.DiscoveryCleanupDays)
+				}
+
+				if backupManager != nil && cfg.BackupEnabled {
+					if err := scheduler.RegisterTask(jobManager.Task("backup", "Database backup", model.JobTypeBackup, cfg.BackupInterval, nil)); err != nil {
+						log.Error("Failed to schedule backups", "error", err)
+						return err
+					}
+					log.Info("Scheduled backups enabled", "interval", cfg.BackupInterval, "dir", cfg.BackupDir,
+						"keep_daily", cfg.BackupKeepDaily, "keep_weekly", cfg.BackupKeepWeekly)
+				}
+			} else {
+				log.Warn("Storage does not support jobs, scheduled backups and cleanup will be unavailable")
+			}
+
 			// Create MCP server
 			mcpServer := mcp.NewServer(store, cfg.MCPAuthToken)
 
@@ -316,6 +434,9 @@ func Command() *cli.Command {
 				DiscoveryHandler:   discoveryHandler,
 				DiscoveryScanner:   discoveryScanner,
 				DiscoveryScheduler: discoveryScheduler,
+				BackupHandler:      backupHandler,
+				SchedulerHandler:   api.NewSchedulerHandler(scheduler),
+				JobHandler:         jobHandler,
 				MCPServer:          mcpServer,
 				APIHandler:         apiHandler,
 				CustomUIHandler:    customUIHandler,
@@ -0,0 +1,68 @@
+package software
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func FindCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "find",
+		Usage:       "Find devices with a package installed",
+		Description: "List the devices that have a package installed, optionally limited to versions matching a constraint such as \"<3.0.8\" or \">=3.0.0,<3.0.8\"",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "package", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "version", Usage: "Version constraint (e.g. \"<3.0.8\")"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			params := url.Values{}
+			params.Set("name", cmd.GetStringArg("package"))
+			if version := cmd.GetString("version"); version != "" {
+				params.Set("version", version)
+			}
+
+			log.Debug("Finding package installs", "params", params.Encode(), "server", cmd.GetString("server"))
+
+			resp, err := makeRequest("GET", cmd.GetString("server")+"/api/software/packages?"+params.Encode(), cmd.GetString("api-token"), nil)
+			if err != nil {
+				log.Error("Failed to connect to server for package search", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for package search", "status", resp.StatusCode, "body", string(body))
+				return fmt.Errorf("server error: %s", string(body))
+			}
+
+			var installs []model.PackageInstall
+			if err := json.NewDecoder(resp.Body).Decode(&installs); err != nil {
+				log.Error("Failed to decode package search response", "error", err)
+				return err
+			}
+
+			log.Info("Found package installs", "count", len(installs))
+			if len(installs) == 0 {
+				fmt.Println("No matching packages found")
+				return nil
+			}
+			for _, i := range installs {
+				fmt.Printf("%s\t%s\t%s\t%s\n", i.DeviceID, i.DeviceName, i.Name, i.Version)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package software
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func PushCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "push",
+		Usage:       "Push the software inventory of a device",
+		Description: "Replace the OS and package inventory of a device with a JSON document ({\"os\": {...}, \"packages\": [{\"name\": ..., \"version\": ...}]})",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "id", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "file", Usage: "JSON inventory file, - for stdin", Required: true},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			id := cmd.GetStringArg("id")
+			path := cmd.GetString("file")
+			log.Debug("Pushing software inventory", "id", id, "file", path, "server", cmd.GetString("server"))
+
+			var data []byte
+			var err error
+			if path == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(path)
+			}
+			if err != nil {
+				return fmt.Errorf("reading inventory: %w", err)
+			}
+
+			endpoint := cmd.GetString("server") + "/api/devices/" + url.PathEscape(id) + "/software"
+			resp, err := makeRequest("PUT", endpoint, cmd.GetString("api-token"), bytes.NewReader(data))
+			if err != nil {
+				log.Error("Failed to connect to server for software push", "error", err, "id", id)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode == http.StatusNotFound {
+				log.Warn("Device not found for software push", "id", id)
+				return fmt.Errorf("device not found")
+			}
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for software push", "status", resp.StatusCode, "body", string(body), "id", id)
+				return fmt.Errorf("server error: %s", string(body))
+			}
+
+			var inventory model.SoftwareInventory
+			if err := json.NewDecoder(resp.Body).Decode(&inventory); err != nil {
+				log.Error("Failed to decode software push response", "error", err, "id", id)
+				return err
+			}
+
+			log.Info("Software inventory pushed", "id", inventory.DeviceID, "packages", len(inventory.Packages))
+			fmt.Printf("Software inventory stored for %s (%d packages)\n", inventory.DeviceID, len(inventory.Packages))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package software
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func ShowCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "show",
+		Usage:       "Show the software inventory of a device",
+		Description: "Show the structured OS and installed packages of a device",
+		Arguments: []cli.Argument{
+			&cli.StringArg{Name: "id", Required: true},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			id := cmd.GetStringArg("id")
+			log.Debug("Getting software inventory", "id", id, "server", cmd.GetString("server"))
+
+			endpoint := cmd.GetString("server") + "/api/devices/" + url.PathEscape(id) + "/software"
+			resp, err := makeRequest("GET", endpoint, cmd.GetString("api-token"), nil)
+			if err != nil {
+				log.Error("Failed to connect to server for software inventory", "error", err, "id", id)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode == http.StatusNotFound {
+				log.Warn("Device not found for software inventory", "id", id)
+				return fmt.Errorf("device not found")
+			}
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for software inventory", "status", resp.StatusCode, "body", string(body), "id", id)
+				return fmt.Errorf("server error: %s", string(body))
+			}
+
+			var inventory model.SoftwareInventory
+			if err := json.NewDecoder(resp.Body).Decode(&inventory); err != nil {
+				log.Error("Failed to decode software inventory", "error", err, "id", id)
+				return err
+			}
+
+			log.Info("Retrieved software inventory", "id", inventory.DeviceID, "packages", len(inventory.Packages))
+			printInventory(&inventory)
+			return nil
+		},
+	}
+}
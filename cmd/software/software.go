@@ -0,0 +1,59 @@
+package software
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/config"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/paularlott/cli"
+)
+
+func Commands() []*cli.Command {
+	return []*cli.Command{
+		ShowCommand(),
+		PushCommand(),
+		FindCommand(),
+	}
+}
+
+func getDefaultServerURL() string {
+	cfg := config.Load()
+	return "http://localhost" + cfg.ListenAddr
+}
+
+func makeRequest(method, url, token string, body io.Reader) (*http.Response, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req)
+}
+
+func printInventory(inventory *model.SoftwareInventory) {
+	fmt.Printf("Device:       %s\n", inventory.DeviceID)
+	if inventory.CollectedAt == nil {
+		fmt.Println("No software inventory reported")
+		return
+	}
+	fmt.Printf("Collected:    %s\n", inventory.CollectedAt.Format(time.RFC3339))
+	if os := inventory.OS; os != nil {
+		fmt.Printf("OS family:    %s\n", os.Family)
+		fmt.Printf("Distribution: %s\n", os.Distribution)
+		fmt.Printf("Version:      %s\n", os.Version)
+		fmt.Printf("Kernel:       %s\n", os.Kernel)
+	}
+	fmt.Printf("Packages (%d):\n", len(inventory.Packages))
+	for _, pkg := range inventory.Packages {
+		fmt.Printf("  %s\t%s\n", pkg.Name, pkg.Version)
+	}
+}
@@ -0,0 +1,79 @@
+package topology
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/paularlott/cli"
+)
+
+func ExportCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "export",
+		Usage:       "Export the inventory topology graph",
+		Description: "Export devices, datacenters, networks and relationships as a graph in DOT, GraphML or JSON format",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Usage: "Output format (dot, graphml, json)", DefaultValue: "dot"},
+			&cli.StringFlag{Name: "datacenter-id", Usage: "Only include this datacenter (ID or name)"},
+			&cli.StringFlag{Name: "tags", Usage: "Only include devices with all of these tags (comma-separated)"},
+			&cli.StringFlag{Name: "types", Usage: "Only include these relationship types (comma-separated)"},
+			&cli.StringFlag{Name: "output", Usage: "Write to file instead of stdout"},
+			&cli.StringFlag{Name: "server", Usage: "Server URL", DefaultValue: getDefaultServerURL()},
+			&cli.StringFlag{Name: "api-token", Usage: "API authentication token", EnvVars: []string{"RACKD_API_TOKEN"}},
+		},
+		Run: func(ctx context.Context, cmd *cli.Command) error {
+			params := url.Values{}
+			params.Set("format", cmd.GetString("format"))
+			if dc := cmd.GetString("datacenter-id"); dc != "" {
+				params.Set("datacenter_id", dc)
+			}
+			for _, tag := range parseList(cmd.GetString("tags")) {
+				params.Add("tag", tag)
+			}
+			for _, relType := range parseList(cmd.GetString("types")) {
+				params.Add("type", relType)
+			}
+
+			log.Debug("Exporting topology", "params", params.Encode(), "server", cmd.GetString("server"))
+
+			resp, err := makeRequest("GET", cmd.GetString("server")+"/api/topology?"+params.Encode(), cmd.GetString("api-token"))
+			if err != nil {
+				log.Error("Failed to connect to server for topology export", "error", err)
+				return fmt.Errorf("failed to connect to server: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode == http.StatusNotFound {
+				log.Warn("Datacenter not found for topology export", "datacenter_id", cmd.GetString("datacenter-id"))
+				return fmt.Errorf("datacenter not found")
+			}
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				log.Error("Server returned error for topology export", "status", resp.Status, "body", string(body))
+				return fmt.Errorf("server error: %s", resp.Status)
+			}
+
+			out := io.Writer(os.Stdout)
+			if path := cmd.GetString("output"); path != "" {
+				f, err := os.Create(path)
+				if err != nil {
+					return fmt.Errorf("creating output file: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			if _, err := io.Copy(out, resp.Body); err != nil {
+				return fmt.Errorf("writing topology: %w", err)
+			}
+
+			log.Info("Exported topology", "format", cmd.GetString("format"), "output", cmd.GetString("output"))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// defaultWarrantyDays is the look-ahead window of the expiring warranty report
+const defaultWarrantyDays = 90
+
+// listAssets handles GET /api/assets
+func (h *Handler) listAssets(w http.ResponseWriter, r *http.Request) {
+	log.Debug("Listing hardware assets")
+	h.writeAssets(w, r, &model.AssetFilter{}, "assets.csv")
+}
+
+// listExpiringAssets handles GET /api/assets/expiring
+func (h *Handler) listExpiringAssets(w http.ResponseWriter, r *http.Request) {
+	days := defaultWarrantyDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			log.Warn("Invalid warranty window", "days", value)
+			h.writeError(w, http.StatusBadRequest, "days must be a non-negative integer")
+			return
+		}
+		days = n
+	}
+	includeExpired, _ := strconv.ParseBool(r.URL.Query().Get("include_expired"))
+
+	log.Debug("Listing assets with expiring warranties", "days", days, "include_expired", includeExpired)
+
+	today := time.Now().UTC()
+	filter := &model.AssetFilter{
+		WarrantyExpiresBefore: today.AddDate(0, 0, days).Format("2006-01-02"),
+	}
+	if !includeExpired {
+		filter.WarrantyExpiresAfter = today.Format("2006-01-02")
+	}
+	h.writeAssets(w, r, filter, "expiring-warranties.csv")
+}
+
+// writeAssets lists assets matching the filter and writes them as JSON, or as CSV when format=csv
+func (h *Handler) writeAssets(w http.ResponseWriter, r *http.Request, filter *model.AssetFilter, filename string) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		h.writeError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	assetStorage, ok := h.storage.(storage.AssetStorage)
+	if !ok {
+		log.Warn("Hardware assets not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "hardware assets are not supported by this storage backend")
+		return
+	}
+
+	assets, err := assetStorage.ListAssets(filter)
+	if err != nil {
+		log.Error("Failed to list assets", "error", err)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Listed hardware assets", "count", len(assets), "format", format)
+	if format != "csv" {
+		h.writeJSON(w, http.StatusOK, assets)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	if err := writeAssetCSV(csv.NewWriter(w), assets); err != nil {
+		log.Error("Failed to write asset CSV", "error", err)
+	}
+}
+
+// assetCSVHeader is the column layout of the procurement CSV export
+var assetCSVHeader = []string{
+	"device_id", "device_name", "status", "serial_number", "asset_tag", "vendor", "purchase_date",
+	"po_number", "cost", "warranty_expires", "warranty_days_left", "support_contract",
+}
+
+func writeAssetCSV(cw *csv.Writer, assets []model.DeviceAsset) error {
+	if err := cw.Write(assetCSVHeader); err != nil {
+		return err
+	}
+	for _, a := range assets {
+		cost := ""
+		if a.Cost != 0 {
+			cost = strconv.FormatFloat(a.Cost, 'f', 2, 64)
+		}
+		daysLeft := ""
+		if a.WarrantyDaysLeft != nil {
+			daysLeft = strconv.Itoa(*a.WarrantyDaysLeft)
+		}
+		record := []string{
+			a.DeviceID, a.DeviceName, string(a.Status), a.SerialNumber, a.AssetTag, a.Vendor, a.PurchaseDate,
+			a.PONumber, cost, a.WarrantyExpires, daysLeft, a.SupportContract,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/martinsuchenak/rackd/internal/backup"
+	"github.com/martinsuchenak/rackd/internal/log"
+)
+
+// BackupHandler handles the database backup admin endpoints
+type BackupHandler struct {
+	manager *backup.Manager
+}
+
+// NewBackupHandler creates a new backup API handler
+func NewBackupHandler(m *backup.Manager) *BackupHandler {
+	return &BackupHandler{manager: m}
+}
+
+// RegisterRoutes registers the backup API routes
+func (h *BackupHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/backups", h.listBackups)
+	mux.HandleFunc("POST /api/admin/backups", h.createBackup)
+	mux.HandleFunc("GET /api/admin/backups/{name}", h.downloadBackup)
+}
+
+// listBackups handles GET /api/admin/backups
+func (h *BackupHandler) listBackups(w http.ResponseWriter, r *http.Request) {
+	backups, err := h.manager.List()
+	if err != nil {
+		log.Error("Failed to list backups", "error", err)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Debug("Listed backups", "count", len(backups))
+	h.writeJSON(w, http.StatusOK, backups)
+}
+
+// createBackup handles POST /api/admin/backups
+func (h *BackupHandler) createBackup(w http.ResponseWriter, r *http.Request) {
+	log.Debug("Creating backup on request")
+
+	b, err := h.manager.Create()
+	if err != nil {
+		log.Error("Failed to create backup", "error", err)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Created backup", "name", b.Name, "size", b.Size)
+	h.writeJSON(w, http.StatusCreated, b)
+}
+
+// downloadBackup handles GET /api/admin/backups/{name}
+func (h *BackupHandler) downloadBackup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	path, err := h.manager.Path(name)
+	if err != nil {
+		if errors.Is(err, backup.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "backup not found")
+			return
+		}
+		h.internalError(w, err)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Downloading backup", "name", name, "size", info.Size())
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+func (h *BackupHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *BackupHandler) writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func (h *BackupHandler) internalError(w http.ResponseWriter, err error) {
+	log.Error("Internal server error", "error", err)
+	h.writeError(w, http.StatusInternalServerError, "internal server error")
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// maxBatchOperations limits the size of a batch, which holds the storage lock while it runs
+const maxBatchOperations = 100
+
+// batchRefPattern matches references to earlier results, e.g. ${0.id}
+var batchRefPattern = regexp.MustCompile(`\$\{(\d+)\.([A-Za-z0-9_]+)\}`)
+
+// batchFailure aborts a batch at the operation that failed
+type batchFailure struct {
+	index   int
+	status  int
+	message string
+}
+
+func (f *batchFailure) Error() string {
+	return fmt.Sprintf("operation %d failed: %s", f.index, f.message)
+}
+
+// batch handles POST /api/batch. The operations run in order against the API on
+// one transaction; if one fails, all of them are rolled back.
+func (h *Handler) batch(w http.ResponseWriter, r *http.Request) {
+	var req model.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("Invalid batch request body", "error", err)
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if len(req.Operations) == 0 {
+		h.writeError(w, http.StatusBadRequest, "operations are required")
+		return
+	}
+	if len(req.Operations) > maxBatchOperations {
+		h.writeError(w, http.StatusBadRequest, fmt.Sprintf("a batch holds at most %d operations", maxBatchOperations))
+		return
+	}
+	for i, op := range req.Operations {
+		if op.Method == "" {
+			h.writeError(w, http.StatusBadRequest, fmt.Sprintf("operation %d: method is required", i))
+			return
+		}
+		if !strings.HasPrefix(op.Path, "/api/") {
+			h.writeError(w, http.StatusBadRequest, fmt.Sprintf("operation %d: path must start with /api/", i))
+			return
+		}
+		if strings.HasPrefix(op.Path, "/api/batch") {
+			h.writeError(w, http.StatusBadRequest, fmt.Sprintf("operation %d: batches cannot be nested", i))
+			return
+		}
+	}
+
+	txStorage, ok := h.storage.(storage.TxStorage)
+	if !ok {
+		log.Warn("Transactions not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "batch operations are not supported by this storage backend")
+		return
+	}
+
+	log.Debug("Running batch", "operations", len(req.Operations))
+
+	results := make([]model.BatchResult, 0, len(req.Operations))
+	err := txStorage.WithTx(func(tx storage.Storage) error {
+		mux := http.NewServeMux()
+		NewHandler(tx).RegisterRoutes(mux)
+
+		for i, op := range req.Operations {
+			path, err := resolveBatchRefs(op.Path, results, url.PathEscape)
+			if err != nil {
+				return &batchFailure{index: i, status: http.StatusBadRequest, message: err.Error()}
+			}
+			body, err := resolveBatchRefs(string(op.Body), results, jsonEscape)
+			if err != nil {
+				return &batchFailure{index: i, status: http.StatusBadRequest, message: err.Error()}
+			}
+
+			opReq, err := http.NewRequestWithContext(r.Context(), strings.ToUpper(op.Method), path, strings.NewReader(body))
+			if err != nil {
+				return &batchFailure{index: i, status: http.StatusBadRequest, message: err.Error()}
+			}
+			opReq.Header.Set("Content-Type", "application/json")
+
+			rec := newBatchRecorder()
+			mux.ServeHTTP(rec, opReq)
+			result := rec.result()
+			results = append(results, result)
+
+			if result.Status >= http.StatusBadRequest {
+				return &batchFailure{index: i, status: result.Status, message: batchErrorMessage(result)}
+			}
+		}
+		return nil
+	})
+
+	if failure, ok := err.(*batchFailure); ok {
+		log.Warn("Batch rolled back", "operation", failure.index, "status", failure.status, "error", failure.message)
+		h.writeJSON(w, failure.status, model.BatchResponse{
+			Results:     results,
+			Error:       failure.Error(),
+			FailedIndex: &failure.index,
+		})
+		return
+	}
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Batch completed", "operations", len(results))
+	h.writeJSON(w, http.StatusOK, model.BatchResponse{Results: results})
+}
+
+// resolveBatchRefs replaces ${N.field} references in s with the field of result N,
+// escaping string values for where they are used
+func resolveBatchRefs(s string, results []model.BatchResult, escape func(string) string) (string, error) {
+	var refErr error
+	resolved := batchRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := batchRefPattern.FindStringSubmatch(ref)
+		index, _ := strconv.Atoi(m[1])
+		if index >= len(results) {
+			refErr = fmt.Errorf("%s refers to an operation that has not run", ref)
+			return ref
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(results[index].Body, &fields); err != nil {
+			refErr = fmt.Errorf("%s: result of operation %d is not an object", ref, index)
+			return ref
+		}
+		raw, ok := fields[m[2]]
+		if !ok {
+			refErr = fmt.Errorf("%s: result of operation %d has no field %q", ref, index, m[2])
+			return ref
+		}
+
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			return escape(str)
+		}
+		return string(raw)
+	})
+	return resolved, refErr
+}
+
+// jsonEscape escapes s for use inside a JSON string
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+// batchErrorMessage returns the error of a failed operation
+func batchErrorMessage(result model.BatchResult) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(result.Body, &body) == nil && body.Error != "" {
+		return body.Error
+	}
+	return http.StatusText(result.Status)
+}
+
+// batchRecorder captures the response to one operation of a batch
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: make(http.Header)}
+}
+
+func (rec *batchRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *batchRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *batchRecorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(b)
+}
+
+func (rec *batchRecorder) result() model.BatchResult {
+	result := model.BatchResult{Status: rec.status}
+	if result.Status == 0 {
+		result.Status = http.StatusOK
+	}
+
+	body := bytes.TrimSpace(rec.body.Bytes())
+	switch {
+	case len(body) == 0:
+	case json.Valid(body):
+		result.Body = json.RawMessage(body)
+	default:
+		// Non-JSON responses, e.g. CSV exports, are returned as a string
+		result.Body, _ = json.Marshal(string(body))
+	}
+	return result
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// customFieldFilter extracts field.<name>=<value> query parameters used to filter list endpoints
+func customFieldFilter(r *http.Request) map[string]string {
+	var fields map[string]string
+	for key, values := range r.URL.Query() {
+		name, ok := strings.CutPrefix(key, "field.")
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[name] = values[0]
+	}
+	return fields
+}
+
+// listCustomFields handles GET /api/custom-fields
+func (h *Handler) listCustomFields(w http.ResponseWriter, r *http.Request) {
+	entityType := r.URL.Query().Get("entity_type")
+
+	log.Debug("Listing custom fields", "entity_type", entityType)
+
+	cfStorage, ok := h.storage.(storage.CustomFieldStorage)
+	if !ok {
+		log.Warn("Custom fields not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "custom fields are not supported by this storage backend")
+		return
+	}
+
+	fields, err := cfStorage.ListCustomFields(&model.CustomFieldFilter{EntityType: entityType})
+	if err != nil {
+		log.Error("Failed to list custom fields", "error", err, "entity_type", entityType)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Listed custom fields", "count", len(fields), "entity_type", entityType)
+	h.writeJSON(w, http.StatusOK, fields)
+}
+
+// getCustomField handles GET /api/custom-fields/{id}
+func (h *Handler) getCustomField(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	log.Debug("Getting custom field", "id", id)
+
+	cfStorage, ok := h.storage.(storage.CustomFieldStorage)
+	if !ok {
+		log.Warn("Custom fields not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "custom fields are not supported by this storage backend")
+		return
+	}
+
+	field, err := cfStorage.GetCustomField(id)
+	if err != nil {
+		if errors.Is(err, storage.ErrCustomFieldNotFound) {
+			log.Warn("Custom field not found", "id", id)
+			h.writeError(w, http.StatusNotFound, "custom field not found")
+			return
+		}
+		log.Error("Failed to get custom field", "error", err, "id", id)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Retrieved custom field", "id", id, "name", field.Name)
+	h.writeJSON(w, http.StatusOK, field)
+}
+
+// createCustomField handles POST /api/custom-fields
+func (h *Handler) createCustomField(w http.ResponseWriter, r *http.Request) {
+	var field model.CustomFieldDefinition
+	if err := json.NewDecoder(r.Body).Decode(&field); err != nil {
+		log.Warn("Invalid custom field creation request body", "error", err)
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	log.Debug("Creating custom field", "entity_type", field.EntityType, "name", field.Name)
+
+	if field.ID == "" {
+		field.ID = generateID(field.Name)
+	}
+
+	cfStorage, ok := h.storage.(storage.CustomFieldStorage)
+	if !ok {
+		log.Warn("Custom fields not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "custom fields are not supported by this storage backend")
+		return
+	}
+
+	if err := cfStorage.CreateCustomField(&field); err != nil {
+		if errors.Is(err, storage.ErrInvalidCustomField) {
+			log.Warn("Custom field creation failed - invalid definition", "error", err, "name", field.Name)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			log.Warn("Custom field creation failed - already exists", "entity_type", field.EntityType, "name", field.Name)
+			h.writeError(w, http.StatusConflict, "custom field with this name already exists")
+			return
+		}
+		log.Error("Failed to create custom field", "error", err, "name", field.Name)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Custom field created successfully", "id", field.ID, "entity_type", field.EntityType, "name", field.Name)
+	h.writeJSON(w, http.StatusCreated, field)
+}
+
+// updateCustomField handles PUT /api/custom-fields/{id}
+func (h *Handler) updateCustomField(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var field model.CustomFieldDefinition
+	if err := json.NewDecoder(r.Body).Decode(&field); err != nil {
+		log.Warn("Invalid custom field update request body", "error", err, "id", id)
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	log.Debug("Updating custom field", "id", id, "name", field.Name)
+
+	// Ensure ID matches URL
+	field.ID = id
+
+	cfStorage, ok := h.storage.(storage.CustomFieldStorage)
+	if !ok {
+		log.Warn("Custom fields not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "custom fields are not supported by this storage backend")
+		return
+	}
+
+	if err := cfStorage.UpdateCustomField(&field); err != nil {
+		if errors.Is(err, storage.ErrCustomFieldNotFound) {
+			log.Warn("Custom field update failed - not found", "id", id)
+			h.writeError(w, http.StatusNotFound, "custom field not found")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidCustomField) {
+			log.Warn("Custom field update failed - invalid definition", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			log.Warn("Custom field update failed - name already exists", "id", id, "name", field.Name)
+			h.writeError(w, http.StatusConflict, "custom field with this name already exists")
+			return
+		}
+		log.Error("Failed to update custom field", "error", err, "id", id)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Custom field updated successfully", "id", id, "name", field.Name)
+	h.writeJSON(w, http.StatusOK, field)
+}
+
+// deleteCustomField handles DELETE /api/custom-fields/{id}
+func (h *Handler) deleteCustomField(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	log.Debug("Deleting custom field", "id", id)
+
+	cfStorage, ok := h.storage.(storage.CustomFieldStorage)
+	if !ok {
+		log.Warn("Custom fields not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "custom fields are not supported by this storage backend")
+		return
+	}
+
+	if err := cfStorage.DeleteCustomField(id); err != nil {
+		if errors.Is(err, storage.ErrCustomFieldNotFound) {
+			log.Warn("Custom field deletion failed - not found", "id", id)
+			h.writeError(w, http.StatusNotFound, "custom field not found")
+			return
+		}
+		log.Error("Failed to delete custom field", "error", err, "id", id)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Custom field deleted successfully", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
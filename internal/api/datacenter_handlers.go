@@ -15,7 +15,7 @@ import (
 // listDatacenters handles GET /api/datacenters
 func (h *Handler) listDatacenters(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
-	filter := &model.DatacenterFilter{Name: name}
+	filter := &model.DatacenterFilter{Name: name, CustomFields: customFieldFilter(r)}
 
 	log.Debug("Listing datacenters", "name", name)
 
@@ -69,6 +69,7 @@ func (h *Handler) getDatacenter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Retrieved datacenter", "id", id, "name", datacenter.Name)
+	setETag(w, datacenter.Version)
 	h.writeJSON(w, http.StatusOK, datacenter)
 }
 
@@ -103,6 +104,11 @@ func (h *Handler) createDatacenter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := dcStorage.CreateDatacenter(&datacenter); err != nil {
+		if errors.Is(err, storage.ErrInvalidCustomField) {
+			log.Warn("Datacenter creation failed - invalid custom field", "error", err, "name", datacenter.Name)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			log.Warn("Datacenter creation failed - already exists", "name", datacenter.Name)
 			h.writeError(w, http.StatusConflict, "datacenter with this name already exists")
@@ -114,6 +120,7 @@ func (h *Handler) createDatacenter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Datacenter created successfully", "id", datacenter.ID, "name", datacenter.Name)
+	setETag(w, datacenter.Version)
 	h.writeJSON(w, http.StatusCreated, datacenter)
 }
 
@@ -138,6 +145,17 @@ func (h *Handler) updateDatacenter(w http.ResponseWriter, r *http.Request) {
 	// Ensure ID matches URL
 	datacenter.ID = id
 
+	// An If-Match header takes precedence over the version in the body
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Warn("If-Match header not accepted", "error", err, "id", id)
+		h.preconditionError(w, err)
+		return
+	}
+	if version != 0 {
+		datacenter.Version = version
+	}
+
 	dcStorage, ok := h.storage.(storage.DatacenterStorage)
 	if !ok {
 		log.Warn("Datacenters not supported by storage backend")
@@ -146,6 +164,16 @@ func (h *Handler) updateDatacenter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := dcStorage.UpdateDatacenter(&datacenter); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			log.Warn("Datacenter update failed - version conflict", "id", id, "version", datacenter.Version)
+			h.preconditionError(w, err)
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidCustomField) {
+			log.Warn("Datacenter update failed - invalid custom field", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if errors.Is(err, storage.ErrDatacenterNotFound) {
 			log.Warn("Datacenter update failed - not found", "id", id)
 			h.writeError(w, http.StatusNotFound, "datacenter not found")
@@ -162,9 +190,23 @@ func (h *Handler) updateDatacenter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Datacenter updated successfully", "id", id, "name", datacenter.Name)
+	setETag(w, datacenter.Version)
 	h.writeJSON(w, http.StatusOK, datacenter)
 }
 
+// patchDatacenter handles PATCH /api/datacenters/{id}
+func (h *Handler) patchDatacenter(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.storage.(storage.DatacenterStorage); !ok {
+		log.Warn("Datacenters not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "datacenters are not supported by this storage backend")
+		return
+	}
+
+	h.patch(w, r, func(st storage.Storage, id string) (interface{}, error) {
+		return st.(storage.DatacenterStorage).GetDatacenter(id)
+	}, (*Handler).updateDatacenter)
+}
+
 // deleteDatacenter handles DELETE /api/datacenters/{id}
 func (h *Handler) deleteDatacenter(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -176,14 +218,30 @@ func (h *Handler) deleteDatacenter(w http.ResponseWriter, r *http.Request) {
 
 	log.Debug("Deleting datacenter", "id", id)
 
-	dcStorage, ok := h.storage.(storage.DatacenterStorage)
-	if !ok {
+	if _, ok := h.storage.(storage.DatacenterStorage); !ok {
 		log.Warn("Datacenters not supported by storage backend")
 		h.writeError(w, http.StatusNotImplemented, "datacenters are not supported by this storage backend")
 		return
 	}
 
-	if err := dcStorage.DeleteDatacenter(id); err != nil {
+	err := h.withTx(func(st storage.Storage) error {
+		dcStorage := st.(storage.DatacenterStorage)
+		if err := checkIfMatch(r, func() (int, error) {
+			current, err := dcStorage.GetDatacenter(id)
+			if err != nil {
+				return 0, err
+			}
+			return current.Version, nil
+		}); err != nil {
+			return err
+		}
+		return dcStorage.DeleteDatacenter(id)
+	})
+	if err != nil {
+		if h.preconditionError(w, err) {
+			log.Warn("Datacenter deletion failed - precondition", "error", err, "id", id)
+			return
+		}
 		if errors.Is(err, storage.ErrDatacenterNotFound) {
 			log.Warn("Datacenter deletion failed - not found", "id", id)
 			h.writeError(w, http.StatusNotFound, "datacenter not found")
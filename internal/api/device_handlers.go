@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/martinsuchenak/rackd/internal/labels"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/martinsuchenak/rackd/internal/storage"
@@ -17,7 +18,19 @@ import (
 // listDevices handles GET /api/devices
 func (h *Handler) listDevices(w http.ResponseWriter, r *http.Request) {
 	tags := r.URL.Query()["tag"]
-	filter := &model.DeviceFilter{Tags: tags}
+	selector, err := labelSelector(r)
+	if err != nil {
+		log.Warn("Invalid label selector", "error", err)
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	statuses, err := deviceStatusFilter(r)
+	if err != nil {
+		log.Warn("Invalid device status filter", "error", err)
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := &model.DeviceFilter{Tags: tags, Statuses: statuses, LabelSelector: selector, CustomFields: customFieldFilter(r)}
 
 	log.Debug("Listing devices", "tags", tags)
 	devices, err := h.storage.ListDevices(filter)
@@ -54,6 +67,7 @@ func (h *Handler) getDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Retrieved device", "id", id, "name", device.Name)
+	setETag(w, device.Version)
 	h.writeJSON(w, http.StatusOK, device)
 }
 
@@ -116,6 +130,31 @@ func (h *Handler) createDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.storage.CreateDevice(&device); err != nil {
+		if errors.Is(err, storage.ErrInvalidCustomField) {
+			log.Warn("Device creation failed - invalid custom field", "error", err, "name", device.Name)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, labels.ErrInvalidLabel) {
+			log.Warn("Device creation failed - invalid label", "error", err, "name", device.Name)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidStatus) {
+			log.Warn("Device creation failed - invalid status", "error", err, "name", device.Name)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidAsset) {
+			log.Warn("Device creation failed - invalid asset", "error", err, "name", device.Name)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrDuplicateAsset) {
+			log.Warn("Device creation failed - duplicate asset", "error", err, "name", device.Name)
+			h.writeError(w, http.StatusConflict, err.Error())
+			return
+		}
 		if err == storage.ErrInvalidID {
 			log.Warn("Device creation failed - invalid ID", "id", device.ID, "name", device.Name)
 			h.writeError(w, http.StatusBadRequest, "invalid device ID")
@@ -132,6 +171,7 @@ func (h *Handler) createDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Device created successfully", "id", device.ID, "name", device.Name, "datacenter_id", device.DatacenterID)
+	setETag(w, device.Version)
 	h.writeJSON(w, http.StatusCreated, device)
 }
 
@@ -155,6 +195,17 @@ func (h *Handler) updateDevice(w http.ResponseWriter, r *http.Request) {
 
 	// Ensure ID matches URL
 	device.ID = id
+
+	// An If-Match header takes precedence over the version in the body
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Warn("If-Match header not accepted", "error", err, "id", id)
+		h.preconditionError(w, err)
+		return
+	}
+	if version != 0 {
+		device.Version = version
+	}
 	device.UpdatedAt = time.Now()
 
 	// Validate IP addresses and Pools
@@ -181,6 +232,41 @@ func (h *Handler) updateDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.storage.UpdateDevice(&device); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			log.Warn("Device update failed - version conflict", "id", id, "version", device.Version)
+			h.preconditionError(w, err)
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidCustomField) {
+			log.Warn("Device update failed - invalid custom field", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, labels.ErrInvalidLabel) {
+			log.Warn("Device update failed - invalid label", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidStatus) {
+			log.Warn("Device update failed - invalid status", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidAsset) {
+			log.Warn("Device update failed - invalid asset", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrDuplicateAsset) {
+			log.Warn("Device update failed - duplicate asset", "error", err, "id", id)
+			h.writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidTransition) {
+			log.Warn("Device update failed - status transition not allowed", "error", err, "id", id)
+			h.writeError(w, http.StatusConflict, err.Error())
+			return
+		}
 		if errors.Is(err, storage.ErrDeviceNotFound) {
 			log.Warn("Device update failed - not found", "id", id)
 			h.writeError(w, http.StatusNotFound, "device not found")
@@ -192,9 +278,17 @@ func (h *Handler) updateDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Device updated successfully", "id", id, "name", device.Name)
+	setETag(w, device.Version)
 	h.writeJSON(w, http.StatusOK, device)
 }
 
+// patchDevice handles PATCH /api/devices/{id}
+func (h *Handler) patchDevice(w http.ResponseWriter, r *http.Request) {
+	h.patch(w, r, func(st storage.Storage, id string) (interface{}, error) {
+		return st.GetDevice(id)
+	}, (*Handler).updateDevice)
+}
+
 // deleteDevice handles DELETE /api/devices/{id}
 func (h *Handler) deleteDevice(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -205,7 +299,23 @@ func (h *Handler) deleteDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Debug("Deleting device", "id", id)
-	if err := h.storage.DeleteDevice(id); err != nil {
+	err := h.withTx(func(st storage.Storage) error {
+		if err := checkIfMatch(r, func() (int, error) {
+			current, err := st.GetDevice(id)
+			if err != nil {
+				return 0, err
+			}
+			return current.Version, nil
+		}); err != nil {
+			return err
+		}
+		return st.DeleteDevice(id)
+	})
+	if err != nil {
+		if h.preconditionError(w, err) {
+			log.Warn("Device deletion failed - precondition", "error", err, "id", id)
+			return
+		}
 		if errors.Is(err, storage.ErrDeviceNotFound) {
 			log.Warn("Device deletion failed - not found", "id", id)
 			h.writeError(w, http.StatusNotFound, "device not found")
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/martinsuchenak/rackd/internal/worker"
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment, so proxies keep it open
+const sseKeepAlive = 15 * time.Second
+
+// streamDiscoveryScan handles GET /api/discovery/scans/{id}/events, streaming
+// the progress of a scan as Server-Sent Events until it ends
+func (h *DiscoveryHandler) streamDiscoveryScan(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if h.scans == nil {
+		h.writeError(w, http.StatusNotImplemented, "discovery scans are not available")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	// Subscribe before reading the scan, so no event is missed in between
+	events, unsubscribe := h.scans.Subscribe(id)
+	defer unsubscribe()
+
+	scan, err := h.storage.GetDiscoveryScan(id)
+	if err != nil {
+		if errors.Is(err, storage.ErrDiscoveryScanNotFound) {
+			h.writeError(w, http.StatusNotFound, "scan not found")
+			return
+		}
+		h.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	log.Debug("Streaming discovery scan events", "scan_id", id)
+	if scanEnded(scan) {
+		writeEvent(w, worker.EventComplete, scan)
+		flusher.Flush()
+		return
+	}
+	writeEvent(w, worker.EventProgress, scan)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				// The scan ended without a complete event reaching this stream, e.g. on shutdown
+				if scan, err := h.storage.GetDiscoveryScan(id); err == nil && scanEnded(scan) {
+					writeEvent(w, worker.EventComplete, scan)
+					flusher.Flush()
+				}
+				return
+			}
+			writeEvent(w, event.Type, event.Data)
+			flusher.Flush()
+			if event.Type == worker.EventComplete {
+				return
+			}
+		}
+	}
+}
+
+// scanEnded reports whether a scan has reached a final status
+func scanEnded(scan *model.DiscoveryScan) bool {
+	return scan.Status != "pending" && scan.Status != "running"
+}
+
+// writeEvent writes one Server-Sent Event with data encoded as JSON
+func writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Error("Failed to encode event", "event", event, "error", err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
@@ -1,28 +1,39 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/martinsuchenak/rackd/internal/discovery"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/scandiff"
+	"github.com/martinsuchenak/rackd/internal/schedule"
 	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/martinsuchenak/rackd/internal/worker"
 )
 
 // DiscoveryHandler handles discovery-related HTTP requests
 type DiscoveryHandler struct {
-	storage storage.DiscoveryStorage
-	scanner discovery.Scanner
+	storage   storage.DiscoveryStorage
+	scans     *worker.ScanManager
+	scheduler *worker.Scheduler
 }
 
-// NewDiscoveryHandler creates a new discovery API handler
-func NewDiscoveryHandler(s storage.DiscoveryStorage, sc discovery.Scanner) *DiscoveryHandler {
-	return &DiscoveryHandler{storage: s, scanner: sc}
+// NewDiscoveryHandler creates a new discovery API handler. Scans are run by
+// scans; without a scan manager starting and cancelling scans is not available.
+func NewDiscoveryHandler(s storage.DiscoveryStorage, scans *worker.ScanManager) *DiscoveryHandler {
+	return &DiscoveryHandler{storage: s, scans: scans}
+}
+
+// SetScheduler sets the scheduler running the discovery rules, which is
+// reloaded after every change to a rule so the change applies straight away
+func (h *DiscoveryHandler) SetScheduler(s *worker.Scheduler) {
+	h.scheduler = s
 }
 
 // RegisterDiscoveryRoutes registers all discovery API routes
@@ -38,6 +49,9 @@ func (h *DiscoveryHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/discovery/scans", h.listDiscoveryScans)
 	mux.HandleFunc("POST /api/discovery/scans", h.startDiscoveryScan)
 	mux.HandleFunc("GET /api/discovery/scans/{id}", h.getDiscoveryScan)
+	mux.HandleFunc("GET /api/discovery/scans/{id}/events", h.streamDiscoveryScan)
+	mux.HandleFunc("GET /api/discovery/scans/{id}/diff", h.getDiscoveryScanDiff)
+	mux.HandleFunc("POST /api/discovery/scans/{id}/cancel", h.cancelDiscoveryScan)
 	mux.HandleFunc("DELETE /api/discovery/scans/{id}", h.deleteDiscoveryScan)
 
 	// Discovery Rules
@@ -45,7 +59,18 @@ func (h *DiscoveryHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/discovery/rules", h.createDiscoveryRule)
 	mux.HandleFunc("GET /api/discovery/rules/{id}", h.getDiscoveryRule)
 	mux.HandleFunc("PUT /api/discovery/rules/{id}", h.updateDiscoveryRule)
+	mux.HandleFunc("PATCH /api/discovery/rules/{id}", h.patchDiscoveryRule)
 	mux.HandleFunc("DELETE /api/discovery/rules/{id}", h.deleteDiscoveryRule)
+
+	// Auto-promotion policies
+	mux.HandleFunc("GET /api/discovery/promotion-policies", h.listPromotionPolicies)
+	mux.HandleFunc("POST /api/discovery/promotion-policies", h.createPromotionPolicy)
+	mux.HandleFunc("GET /api/discovery/promotion-policies/preview", h.previewPromotionPolicies)
+	mux.HandleFunc("POST /api/discovery/promotion-policies/run", h.runPromotionPolicies)
+	mux.HandleFunc("GET /api/discovery/promotion-policies/{id}", h.getPromotionPolicy)
+	mux.HandleFunc("PUT /api/discovery/promotion-policies/{id}", h.updatePromotionPolicy)
+	mux.HandleFunc("PATCH /api/discovery/promotion-policies/{id}", h.patchPromotionPolicy)
+	mux.HandleFunc("DELETE /api/discovery/promotion-policies/{id}", h.deletePromotionPolicy)
 }
 
 // listDiscoveredDevices handles GET /api/discovered
@@ -182,6 +207,11 @@ func (h *DiscoveryHandler) listDiscoveryScans(w http.ResponseWriter, r *http.Req
 
 // startDiscoveryScan handles POST /api/discovery/scans
 func (h *DiscoveryHandler) startDiscoveryScan(w http.ResponseWriter, r *http.Request) {
+	if h.scans == nil {
+		h.writeError(w, http.StatusNotImplemented, "discovery scans are not available")
+		return
+	}
+
 	var req struct {
 		NetworkID string `json:"network_id"`
 		ScanType  string `json:"scan_type"` // quick, full, deep
@@ -201,47 +231,18 @@ func (h *DiscoveryHandler) startDiscoveryScan(w http.ResponseWriter, r *http.Req
 		req.ScanType = "full"
 	}
 
-	// Create scan record
 	scan := &model.DiscoveryScan{
 		ID:        generateID("discovery_scan"),
 		NetworkID: req.NetworkID,
-		Status:    "pending",
 		ScanType:  req.ScanType,
 	}
 
-	if err := h.storage.CreateDiscoveryScan(scan); err != nil {
+	if err := h.scans.Submit(scan, nil); err != nil {
 		h.internalError(w, err)
 		return
 	}
 
-	// Execute scan in background
-	go func() {
-		ctx := context.Background()
-		// Create a discovery rule for this one-time scan
-		rule := &model.DiscoveryRule{
-			NetworkID:         req.NetworkID,
-			ScanType:          req.ScanType,
-			TimeoutSeconds:    5,
-			ScanPorts:         req.ScanType != "quick",
-			ServiceDetection:  req.ScanType != "quick",
-			OSDetection:       req.ScanType == "deep",
-		}
-
-		// Run the scan
-		if err := h.scanner.ScanNetwork(ctx, req.NetworkID, rule, func(update *model.DiscoveryScan) {
-			update.ID = scan.ID
-			h.storage.UpdateDiscoveryScan(update)
-		}); err != nil {
-			log.Error("Discovery scan failed", "scan_id", scan.ID, "error", err)
-			scan.Status = "failed"
-			scan.ErrorMessage = err.Error()
-			now := time.Now()
-			scan.CompletedAt = &now
-			h.storage.UpdateDiscoveryScan(scan)
-		}
-	}()
-
-	log.Info("Discovery scan started", "scan_id", scan.ID, "network_id", req.NetworkID)
+	log.Info("Discovery scan queued", "scan_id", scan.ID, "network_id", req.NetworkID)
 	h.writeJSON(w, http.StatusCreated, scan)
 }
 
@@ -262,10 +263,79 @@ func (h *DiscoveryHandler) getDiscoveryScan(w http.ResponseWriter, r *http.Reque
 	h.writeJSON(w, http.StatusOK, scan)
 }
 
+// getDiscoveryScanDiff handles GET /api/discovery/scans/{id}/diff
+func (h *DiscoveryHandler) getDiscoveryScanDiff(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	scan, err := h.storage.GetDiscoveryScan(id)
+	if err != nil {
+		if errors.Is(err, storage.ErrDiscoveryScanNotFound) {
+			h.writeError(w, http.StatusNotFound, "scan not found")
+			return
+		}
+		h.internalError(w, err)
+		return
+	}
+
+	// Hosts a scan has not reached yet would show up as disappeared
+	if scan.Status != "completed" {
+		h.writeError(w, http.StatusConflict, "scan is not completed")
+		return
+	}
+
+	diff, err := scandiff.ForScan(h.storage, scan)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, diff)
+}
+
+// cancelDiscoveryScan handles POST /api/discovery/scans/{id}/cancel
+func (h *DiscoveryHandler) cancelDiscoveryScan(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if h.scans == nil {
+		h.writeError(w, http.StatusNotImplemented, "discovery scans are not available")
+		return
+	}
+
+	if err := h.scans.Cancel(id); err != nil {
+		if !errors.Is(err, worker.ErrScanNotActive) {
+			h.internalError(w, err)
+			return
+		}
+		if _, err := h.storage.GetDiscoveryScan(id); errors.Is(err, storage.ErrDiscoveryScanNotFound) {
+			h.writeError(w, http.StatusNotFound, "scan not found")
+			return
+		}
+		h.writeError(w, http.StatusConflict, "scan is not pending or running")
+		return
+	}
+
+	scan, err := h.storage.GetDiscoveryScan(id)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Discovery scan cancelled", "id", id)
+	h.writeJSON(w, http.StatusOK, scan)
+}
+
 // deleteDiscoveryScan handles DELETE /api/discovery/scans/{id}
 func (h *DiscoveryHandler) deleteDiscoveryScan(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
+	// A running scan is stopped first, so it does not write to the deleted record
+	if h.scans != nil {
+		if err := h.scans.Cancel(id); err != nil && !errors.Is(err, worker.ErrScanNotActive) {
+			h.internalError(w, err)
+			return
+		}
+	}
+
 	if err := h.storage.DeleteDiscoveryScan(id); err != nil {
 		if errors.Is(err, storage.ErrDiscoveryScanNotFound) {
 			h.writeError(w, http.StatusNotFound, "scan not found")
@@ -304,8 +374,17 @@ func (h *DiscoveryHandler) createDiscoveryRule(w http.ResponseWriter, r *http.Re
 		h.writeError(w, http.StatusBadRequest, "network_id is required")
 		return
 	}
+	if _, err := schedule.ForRule(&rule); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.validateProbes(&rule); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	rule.ID = generateID("discovery_rule")
+	rule.LastRunAt, rule.NextRunAt = nil, nil
 	now := time.Now()
 	rule.CreatedAt = now
 	rule.UpdatedAt = now
@@ -316,9 +395,38 @@ func (h *DiscoveryHandler) createDiscoveryRule(w http.ResponseWriter, r *http.Re
 	}
 
 	log.Info("Discovery rule created", "id", rule.ID, "network_id", rule.NetworkID)
+	h.reloadScheduler()
 	h.writeJSON(w, http.StatusCreated, rule)
 }
 
+// validateProbes checks that the scanner runs every probe the rule turns on or
+// off. Without a scanner built from probes there is nothing to check against.
+func (h *DiscoveryHandler) validateProbes(rule *model.DiscoveryRule) error {
+	if len(rule.Probes) == 0 || h.scans == nil {
+		return nil
+	}
+	names, ok := h.scans.ProbeNames()
+	if !ok {
+		return nil
+	}
+
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+	var unknown []string
+	for name := range rule.Probes {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown probes: %s (available: %s)", strings.Join(unknown, ", "), strings.Join(names, ", "))
+}
+
 // getDiscoveryRule handles GET /api/discovery/rules/{id}
 func (h *DiscoveryHandler) getDiscoveryRule(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -346,7 +454,17 @@ func (h *DiscoveryHandler) updateDiscoveryRule(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if _, err := schedule.ForRule(&rule); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.validateProbes(&rule); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	rule.ID = id
+	rule.LastRunAt, rule.NextRunAt = nil, nil
 	rule.UpdatedAt = time.Now()
 
 	if err := h.storage.UpdateDiscoveryRule(&rule); err != nil {
@@ -359,9 +477,52 @@ func (h *DiscoveryHandler) updateDiscoveryRule(w http.ResponseWriter, r *http.Re
 	}
 
 	log.Info("Discovery rule updated", "id", id)
+	h.reloadScheduler()
 	h.writeJSON(w, http.StatusOK, rule)
 }
 
+// patchDiscoveryRule handles PATCH /api/discovery/rules/{id}
+func (h *DiscoveryHandler) patchDiscoveryRule(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	apply := func(st storage.DiscoveryStorage) error {
+		rule, err := st.GetDiscoveryRule(id)
+		if err != nil {
+			return err
+		}
+		req, err := patchedRequest(r, rule)
+		if err != nil {
+			return err
+		}
+		// Without a scheduler, which is reloaded once the transaction commits
+		NewDiscoveryHandler(st, h.scans).updateDiscoveryRule(w, req)
+		return nil
+	}
+
+	var err error
+	if txStorage, ok := h.storage.(storage.TxStorage); ok {
+		err = txStorage.WithTx(func(tx storage.Storage) error {
+			return apply(tx.(storage.DiscoveryStorage))
+		})
+	} else {
+		err = apply(h.storage)
+	}
+	if err != nil {
+		status := patchErrorStatus(err)
+		if status == 0 {
+			h.internalError(w, err)
+			return
+		}
+		if status == http.StatusNotFound {
+			h.writeError(w, status, "rule not found")
+			return
+		}
+		h.writeError(w, status, err.Error())
+		return
+	}
+	h.reloadScheduler()
+}
+
 // deleteDiscoveryRule handles DELETE /api/discovery/rules/{id}
 func (h *DiscoveryHandler) deleteDiscoveryRule(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -376,11 +537,20 @@ func (h *DiscoveryHandler) deleteDiscoveryRule(w http.ResponseWriter, r *http.Re
 	}
 
 	log.Info("Discovery rule deleted", "id", id)
+	h.reloadScheduler()
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // Helper methods
 
+// reloadScheduler brings the tasks of the scheduler in line with the stored
+// discovery rules, when a scheduler is set
+func (h *DiscoveryHandler) reloadScheduler() {
+	if h.scheduler != nil {
+		h.scheduler.Reload()
+	}
+}
+
 func (h *DiscoveryHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
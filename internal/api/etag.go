@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// errInvalidIfMatch is returned for an If-Match header that is not a single version ETag
+var errInvalidIfMatch = errors.New("If-Match must be a single ETag or *")
+
+// setETag sets the ETag header to the version of a resource
+func setETag(w http.ResponseWriter, version int) {
+	if version > 0 {
+		w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+	}
+}
+
+// ifMatchVersion returns the version required by the If-Match header of r.
+// It returns 0 when the header is absent or *, which match any version. If-Match
+// compares ETags strongly (RFC 9110), so a weak ETag matches no version and
+// fails with storage.ErrVersionConflict.
+func ifMatchVersion(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	if strings.HasPrefix(value, "W/") {
+		return 0, storage.ErrVersionConflict
+	}
+	unquoted, err := strconv.Unquote(value)
+	if err != nil || !strings.HasPrefix(value, `"`) {
+		return 0, errInvalidIfMatch
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, errInvalidIfMatch
+	}
+	return version, nil
+}
+
+// checkIfMatch compares the If-Match header of r with the current version of a
+// resource, returning storage.ErrVersionConflict when they differ
+func checkIfMatch(r *http.Request, current func() (int, error)) error {
+	expected, err := ifMatchVersion(r)
+	if err != nil || expected == 0 {
+		return err
+	}
+	version, err := current()
+	if err != nil {
+		return err
+	}
+	if version != expected {
+		return storage.ErrVersionConflict
+	}
+	return nil
+}
+
+// withTx runs fn in a transaction when the storage backend supports one
+func (h *Handler) withTx(fn func(st storage.Storage) error) error {
+	if txStorage, ok := h.storage.(storage.TxStorage); ok {
+		return txStorage.WithTx(fn)
+	}
+	return fn(h.storage)
+}
+
+// preconditionError writes the response for a failed If-Match precondition,
+// reporting whether err was one
+func (h *Handler) preconditionError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, errInvalidIfMatch):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, storage.ErrVersionConflict):
+		h.writeError(w, http.StatusPreconditionFailed, "resource has been modified, fetch it and retry")
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// exportInventory handles GET /api/export
+func (h *Handler) exportInventory(w http.ResponseWriter, r *http.Request) {
+	exportStorage, ok := h.storage.(storage.ExportStorage)
+	if !ok {
+		log.Warn("Export not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "export is not supported by this storage backend")
+		return
+	}
+
+	log.Debug("Exporting inventory")
+
+	export, err := exportStorage.Export()
+	if err != nil {
+		log.Error("Failed to export inventory", "error", err)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Exported inventory", "version", export.Version, "devices", len(export.Devices))
+	filename := "rackd-export-" + export.ExportedAt.Format("20060102-150405") + ".json"
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	h.writeJSON(w, http.StatusOK, export)
+}
+
+// restoreInventory handles POST /api/restore
+func (h *Handler) restoreInventory(w http.ResponseWriter, r *http.Request) {
+	replace, _ := strconv.ParseBool(r.URL.Query().Get("replace"))
+
+	var export model.Export
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		log.Warn("Invalid restore request body", "error", err)
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	exportStorage, ok := h.storage.(storage.ExportStorage)
+	if !ok {
+		log.Warn("Restore not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "restore is not supported by this storage backend")
+		return
+	}
+
+	log.Debug("Restoring inventory", "version", export.Version, "replace", replace)
+
+	result, err := exportStorage.Restore(&export, replace)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvalidExport):
+			log.Warn("Invalid export", "error", err)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, storage.ErrInventoryNotEmpty):
+			h.writeError(w, http.StatusConflict, "inventory is not empty; restore with replace=true to overwrite it")
+		default:
+			log.Error("Failed to restore inventory", "error", err)
+			h.internalError(w, err)
+		}
+		return
+	}
+
+	log.Info("Restored inventory", "version", result.Version, "datacenters", result.Datacenters, "networks", result.Networks, "devices", result.Devices, "replace", replace)
+	h.writeJSON(w, http.StatusOK, result)
+}
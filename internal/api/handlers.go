@@ -29,6 +29,7 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/datacenters", h.createDatacenter)
 	mux.HandleFunc("GET /api/datacenters/{id}", h.getDatacenter)
 	mux.HandleFunc("PUT /api/datacenters/{id}", h.updateDatacenter)
+	mux.HandleFunc("PATCH /api/datacenters/{id}", h.patchDatacenter)
 	mux.HandleFunc("DELETE /api/datacenters/{id}", h.deleteDatacenter)
 	mux.HandleFunc("GET /api/datacenters/{id}/devices", h.getDatacenterDevices)
 
@@ -37,6 +38,7 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/networks", h.createNetwork)
 	mux.HandleFunc("GET /api/networks/{id}", h.getNetwork)
 	mux.HandleFunc("PUT /api/networks/{id}", h.updateNetwork)
+	mux.HandleFunc("PATCH /api/networks/{id}", h.patchNetwork)
 	mux.HandleFunc("DELETE /api/networks/{id}", h.deleteNetwork)
 	mux.HandleFunc("GET /api/networks/{id}/devices", h.getNetworkDevices)
 
@@ -45,9 +47,33 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/devices", h.createDevice)
 	mux.HandleFunc("GET /api/devices/{id}", h.getDevice)
 	mux.HandleFunc("PUT /api/devices/{id}", h.updateDevice)
+	mux.HandleFunc("PATCH /api/devices/{id}", h.patchDevice)
 	mux.HandleFunc("DELETE /api/devices/{id}", h.deleteDevice)
 	mux.HandleFunc("GET /api/devices/search", h.searchDevices)
 
+	// Device lifecycle
+	mux.HandleFunc("POST /api/devices/{id}/status", h.transitionDevice)
+	mux.HandleFunc("GET /api/devices/{id}/status-history", h.getDeviceStatusHistory)
+
+	// Software inventory
+	mux.HandleFunc("PUT /api/devices/{id}/software", h.setDeviceSoftware)
+	mux.HandleFunc("GET /api/devices/{id}/software", h.getDeviceSoftware)
+	mux.HandleFunc("GET /api/software/packages", h.findPackageInstalls)
+
+	// Hardware assets
+	mux.HandleFunc("GET /api/assets", h.listAssets)
+	mux.HandleFunc("GET /api/assets/expiring", h.listExpiringAssets)
+
+	// Bulk import
+	mux.HandleFunc("POST /api/import", h.importData)
+
+	// Export and restore
+	mux.HandleFunc("GET /api/export", h.exportInventory)
+	mux.HandleFunc("POST /api/restore", h.restoreInventory)
+
+	// Batch operations
+	mux.HandleFunc("POST /api/batch", h.batch)
+
 	// Relationships
 	mux.HandleFunc("POST /api/devices/{id}/relationships", h.addRelationship)
 	mux.HandleFunc("GET /api/devices/{id}/relationships", h.getRelationships)
@@ -59,8 +85,26 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/networks/{id}/pools", h.createNetworkPool)
 	mux.HandleFunc("GET /api/pools/{id}", h.getNetworkPool)
 	mux.HandleFunc("PUT /api/pools/{id}", h.updateNetworkPool)
+	mux.HandleFunc("PATCH /api/pools/{id}", h.patchNetworkPool)
 	mux.HandleFunc("DELETE /api/pools/{id}", h.deleteNetworkPool)
 	mux.HandleFunc("GET /api/pools/{id}/next-ip", h.getNextIP)
+
+	// Custom fields
+	mux.HandleFunc("GET /api/custom-fields", h.listCustomFields)
+	mux.HandleFunc("POST /api/custom-fields", h.createCustomField)
+	mux.HandleFunc("GET /api/custom-fields/{id}", h.getCustomField)
+	mux.HandleFunc("PUT /api/custom-fields/{id}", h.updateCustomField)
+	mux.HandleFunc("DELETE /api/custom-fields/{id}", h.deleteCustomField)
+
+	// Labels
+	mux.HandleFunc("POST /api/labels/migrate-tags", h.migrateTagsToLabels)
+
+	// Topology
+	mux.HandleFunc("GET /api/topology", h.getTopology)
+	mux.HandleFunc("GET /api/topology/layout", h.getTopologyLayout)
+
+	// Reconciliation of discovery results with inventory
+	mux.HandleFunc("GET /api/reconcile", h.getReconcileReport)
 }
 
 // writeJSON writes a JSON response
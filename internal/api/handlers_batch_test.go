@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+func TestHandler_Batch_NotSupported(t *testing.T) {
+	handler := setupTestHandler()
+
+	body := `{"operations": [{"method": "GET", "path": "/api/devices"}]}`
+	req := httptest.NewRequest("POST", "/api/batch", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.batch(w, req)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without transaction support, got %d", w.Code)
+	}
+}
+
+func TestResolveBatchRefs(t *testing.T) {
+	results := []model.BatchResult{
+		{Status: 201, Body: []byte(`{"id": "a/b", "port": 22, "name": "say \"hi\""}`)},
+	}
+
+	tests := []struct {
+		in      string
+		escape  func(string) string
+		want    string
+		wantErr bool
+	}{
+		{"/api/devices/${0.id}", url.PathEscape, "/api/devices/a%2Fb", false},
+		{`{"port": ${0.port}}`, jsonEscape, `{"port": 22}`, false},
+		{`{"name": "${0.name}"}`, jsonEscape, `{"name": "say \"hi\""}`, false},
+		{"/api/devices/${0.missing}", url.PathEscape, "", true},
+		{"/api/devices/${1.id}", url.PathEscape, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveBatchRefs(tt.in, results, tt.escape)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveBatchRefs(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("resolveBatchRefs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"*", 0, false},
+		{`"3"`, 3, false},
+		{`W/"3"`, 0, true},
+		{"3", 0, true},
+		{`"0"`, 0, true},
+		{`"abc"`, 0, true},
+		{`"1", "2"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("PUT", "/api/devices/1", nil)
+		if tt.header != "" {
+			req.Header.Set("If-Match", tt.header)
+		}
+		got, err := ifMatchVersion(req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ifMatchVersion(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ifMatchVersion(%q) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+func TestHandler_Topology(t *testing.T) {
+	handler := setupTestHandler()
+	store := handler.storage.(*mockStorage)
+
+	store.CreateDatacenter(&model.Datacenter{ID: "dc1", Name: "DC One"})
+	store.CreateDevice(&model.Device{ID: "d1", Name: "Parent", DatacenterID: "dc1", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	store.CreateDevice(&model.Device{ID: "d2", Name: "Child", DatacenterID: "dc1", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	store.AddRelationship("d1", "d2", "contains")
+	store.AddRelationship("d1", "d2", "depends_on")
+
+	t.Run("JSON", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topology?type=contains", nil)
+		w := httptest.NewRecorder()
+
+		handler.getTopology(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var graph model.Topology
+		if err := json.NewDecoder(resp.Body).Decode(&graph); err != nil {
+			t.Fatalf("Failed to decode topology: %v", err)
+		}
+		if len(graph.Nodes) != 3 {
+			t.Errorf("Expected 3 nodes, got %d", len(graph.Nodes))
+		}
+		// Two located_in edges plus the single contains relationship
+		if len(graph.Edges) != 3 {
+			t.Errorf("Expected 3 edges, got %d", len(graph.Edges))
+		}
+		for _, e := range graph.Edges {
+			if e.Type == "depends_on" {
+				t.Errorf("Expected depends_on edges to be filtered out")
+			}
+		}
+	})
+
+	t.Run("DOT", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topology?format=dot", nil)
+		w := httptest.NewRecorder()
+
+		handler.getTopology(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/vnd.graphviz") {
+			t.Errorf("Expected graphviz content type, got %s", ct)
+		}
+		if !strings.HasPrefix(w.Body.String(), "digraph rackd {") {
+			t.Errorf("Expected DOT output, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("InvalidFormat", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topology?format=png", nil)
+		w := httptest.NewRecorder()
+
+		handler.getTopology(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("UnknownDatacenter", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topology?datacenter_id=missing", nil)
+		w := httptest.NewRecorder()
+
+		handler.getTopology(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandler_TopologyLayout(t *testing.T) {
+	handler := setupTestHandler()
+	store := handler.storage.(*mockStorage)
+
+	store.CreateDatacenter(&model.Datacenter{ID: "dc1", Name: "DC One"})
+	store.CreateDevice(&model.Device{ID: "d1", Name: "Parent", DatacenterID: "dc1", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	store.CreateDevice(&model.Device{ID: "d2", Name: "Child", DatacenterID: "dc1", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	store.AddRelationship("d1", "d2", "contains")
+	store.AddRelationship("d2", "d1", "depends_on")
+
+	t.Run("Datacenter", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topology/layout", nil)
+		w := httptest.NewRecorder()
+
+		handler.getTopologyLayout(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		var layout model.TopologyLayout
+		if err := json.NewDecoder(w.Body).Decode(&layout); err != nil {
+			t.Fatalf("Failed to decode layout: %v", err)
+		}
+		if len(layout.Groups) != 1 || layout.Groups[0].ID != "dc1" {
+			t.Errorf("Expected a single dc1 group, got %+v", layout.Groups)
+		}
+		if len(layout.Edges) != 1 || layout.Edges[0].Count != 2 {
+			t.Errorf("Expected one aggregated edge with count 2, got %+v", layout.Edges)
+		}
+	})
+
+	t.Run("InvalidGroupBy", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topology/layout?group_by=rack", nil)
+		w := httptest.NewRecorder()
+
+		handler.getTopologyLayout(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+}
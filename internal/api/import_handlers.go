@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/martinsuchenak/rackd/internal/importer"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// importData handles POST /api/import
+func (h *Handler) importData(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+
+	format := importer.FormatFromContentType(r.Header.Get("Content-Type"))
+	if f := query.Get("format"); f != "" {
+		var err error
+		if format, err = importer.ParseFormat(f); err != nil {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if format == "" {
+		format = importer.FormatJSON
+	}
+
+	mapping, err := importer.ParseMapping(query["mapping"])
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	batch, err := importer.Parse(r.Body, importer.Options{
+		Format:  format,
+		Entity:  query.Get("entity"),
+		Mapping: mapping,
+	})
+	if err != nil {
+		log.Warn("Invalid import data", "error", err, "format", format)
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Debug("Importing data", "format", format, "rows", batch.Len(), "dry_run", dryRun)
+
+	importStorage, ok := h.storage.(storage.ImportStorage)
+	if !ok {
+		log.Warn("Import not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "import is not supported by this storage backend")
+		return
+	}
+
+	result, err := importStorage.Import(batch, dryRun)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidImport) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Error("Failed to import data", "error", err, "format", format)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Imported data", "created", result.Created, "updated", result.Updated, "unchanged", result.Unchanged, "failed", result.Failed, "dry_run", dryRun, "committed", result.Committed)
+
+	status := http.StatusOK
+	if result.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	h.writeJSON(w, status, result)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/martinsuchenak/rackd/internal/worker"
+)
+
+// defaultJobLimit is the number of jobs listed when no limit is given
+const defaultJobLimit = 100
+
+// JobHandler handles the endpoints of background jobs
+type JobHandler struct {
+	storage storage.JobStorage
+	jobs    *worker.JobManager
+}
+
+// NewJobHandler creates a new job API handler
+func NewJobHandler(s storage.JobStorage, jobs *worker.JobManager) *JobHandler {
+	return &JobHandler{storage: s, jobs: jobs}
+}
+
+// RegisterRoutes registers the job API routes
+func (h *JobHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/jobs", h.listJobs)
+	mux.HandleFunc("POST /api/jobs", h.createJob)
+	mux.HandleFunc("GET /api/jobs/{id}", h.getJob)
+	mux.HandleFunc("POST /api/jobs/{id}/retry", h.retryJob)
+}
+
+// listJobs handles GET /api/jobs
+func (h *JobHandler) listJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := &model.JobFilter{
+		Type:   query.Get("type"),
+		Status: query.Get("status"),
+		Limit:  defaultJobLimit,
+	}
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			h.writeError(w, http.StatusBadRequest, "limit must be a non-negative integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	jobs, err := h.storage.ListJobs(filter)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	log.Debug("Listed jobs", "count", len(jobs), "type", filter.Type, "status", filter.Status)
+	h.writeJSON(w, http.StatusOK, jobs)
+}
+
+// createJob handles POST /api/jobs
+func (h *JobHandler) createJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type   string          `json:"type"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Type == "" {
+		h.writeError(w, http.StatusBadRequest, "type is required")
+		return
+	}
+
+	var params interface{}
+	if len(req.Params) > 0 {
+		params = req.Params
+	}
+	job, err := h.jobs.Enqueue(req.Type, params)
+	if err != nil {
+		if errors.Is(err, worker.ErrUnknownJobType) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.internalError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusAccepted, job)
+}
+
+// getJob handles GET /api/jobs/{id}
+func (h *JobHandler) getJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.storage.GetJob(r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrJobNotFound) {
+			h.writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		h.internalError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, job)
+}
+
+// retryJob handles POST /api/jobs/{id}/retry
+func (h *JobHandler) retryJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Retry(r.PathValue("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrJobNotFound):
+			h.writeError(w, http.StatusNotFound, "job not found")
+		case errors.Is(err, worker.ErrJobNotRetryable):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			h.internalError(w, err)
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusAccepted, job)
+}
+
+func (h *JobHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *JobHandler) writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func (h *JobHandler) internalError(w http.ResponseWriter, err error) {
+	log.Error("Internal server error", "error", err)
+	h.writeError(w, http.StatusInternalServerError, "internal server error")
+}
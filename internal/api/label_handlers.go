@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/martinsuchenak/rackd/internal/labels"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// labelSelector reads the selector query parameter used to filter list endpoints
+// and checks that it is a valid label selector
+func labelSelector(r *http.Request) (string, error) {
+	selector := r.URL.Query().Get("selector")
+	if _, err := labels.Parse(selector); err != nil {
+		return "", err
+	}
+	return selector, nil
+}
+
+// migrateTagsToLabels handles POST /api/labels/migrate-tags
+func (h *Handler) migrateTagsToLabels(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	log.Debug("Migrating tags to labels", "dry_run", dryRun)
+
+	labelStorage, ok := h.storage.(storage.LabelStorage)
+	if !ok {
+		log.Warn("Labels not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "labels are not supported by this storage backend")
+		return
+	}
+
+	result, err := labelStorage.MigrateTagsToLabels(dryRun)
+	if err != nil {
+		log.Error("Failed to migrate tags to labels", "error", err, "dry_run", dryRun)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Migrated tags to labels", "devices", len(result.Devices), "pools", len(result.Pools), "dry_run", dryRun)
+	h.writeJSON(w, http.StatusOK, result)
+}
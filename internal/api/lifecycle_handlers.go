@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// deviceStatusFilter reads the status query parameters used to filter device lists.
+// Both repeated parameters and comma-separated values are accepted.
+func deviceStatusFilter(r *http.Request) ([]model.DeviceStatus, error) {
+	var statuses []model.DeviceStatus
+	for _, param := range r.URL.Query()["status"] {
+		for _, value := range strings.Split(param, ",") {
+			status := model.DeviceStatus(strings.TrimSpace(value))
+			if status == "" {
+				continue
+			}
+			if !status.Valid() {
+				return nil, fmt.Errorf("%w: %q", storage.ErrInvalidStatus, status)
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// transitionDevice handles POST /api/devices/{id}/status
+func (h *Handler) transitionDevice(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req struct {
+		Status model.DeviceStatus `json:"status"`
+		Note   string             `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("Invalid device status request body", "error", err, "id", id)
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Status == "" {
+		h.writeError(w, http.StatusBadRequest, "status is required")
+		return
+	}
+
+	log.Debug("Changing device status", "id", id, "status", req.Status)
+
+	lifecycleStorage, ok := h.storage.(storage.LifecycleStorage)
+	if !ok {
+		log.Warn("Device lifecycle not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "device lifecycle is not supported by this storage backend")
+		return
+	}
+
+	change, err := lifecycleStorage.TransitionDevice(id, req.Status, req.Note)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceNotFound) {
+			log.Warn("Device not found for status change", "id", id)
+			h.writeError(w, http.StatusNotFound, "device not found")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidStatus) {
+			log.Warn("Device status change failed - invalid status", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidTransition) {
+			log.Warn("Device status change failed - transition not allowed", "error", err, "id", id)
+			h.writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		log.Error("Failed to change device status", "error", err, "id", id)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Device status changed", "id", change.DeviceID, "from", change.FromStatus, "to", change.ToStatus,
+		"released_addresses", len(change.ReleasedAddresses))
+	h.writeJSON(w, http.StatusOK, change)
+}
+
+// getDeviceStatusHistory handles GET /api/devices/{id}/status-history
+func (h *Handler) getDeviceStatusHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	log.Debug("Getting device status history", "id", id)
+
+	lifecycleStorage, ok := h.storage.(storage.LifecycleStorage)
+	if !ok {
+		log.Warn("Device lifecycle not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "device lifecycle is not supported by this storage backend")
+		return
+	}
+
+	history, err := lifecycleStorage.GetDeviceStatusHistory(id)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceNotFound) {
+			log.Warn("Device not found for status history", "id", id)
+			h.writeError(w, http.StatusNotFound, "device not found")
+			return
+		}
+		log.Error("Failed to get device status history", "error", err, "id", id)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Retrieved device status history", "id", id, "count", len(history))
+	h.writeJSON(w, http.StatusOK, history)
+}
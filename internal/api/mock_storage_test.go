@@ -223,6 +223,18 @@ func (m *mockStorage) GetRelatedDevices(deviceID string, relType string) ([]mode
 	return result, nil
 }
 
+func (m *mockStorage) ListRelationships(relType string) ([]model.DeviceRelationship, error) {
+	result := make([]model.DeviceRelationship, 0)
+	for _, rels := range m.relationships {
+		for _, r := range rels {
+			if relType == "" || r.Type == relType {
+				result = append(result, r)
+			}
+		}
+	}
+	return result, nil
+}
+
 func (m *mockStorage) GetReverseRelationships(deviceID string) ([]model.DeviceRelationship, error) {
 	return nil, nil // Not needed for current tests
 }
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/martinsuchenak/rackd/internal/labels"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/martinsuchenak/rackd/internal/storage"
@@ -17,7 +18,13 @@ import (
 func (h *Handler) listNetworks(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 	datacenterID := r.URL.Query().Get("datacenter_id")
-	filter := &model.NetworkFilter{Name: name, DatacenterID: datacenterID}
+	selector, err := labelSelector(r)
+	if err != nil {
+		log.Warn("Invalid label selector", "error", err)
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := &model.NetworkFilter{Name: name, DatacenterID: datacenterID, LabelSelector: selector, CustomFields: customFieldFilter(r)}
 
 	log.Debug("Listing networks", "name", name, "datacenter_id", datacenterID)
 
@@ -71,6 +78,7 @@ func (h *Handler) getNetwork(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Retrieved network", "id", id, "name", network.Name)
+	setETag(w, network.Version)
 	h.writeJSON(w, http.StatusOK, network)
 }
 
@@ -124,6 +132,16 @@ func (h *Handler) createNetwork(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := netStorage.CreateNetwork(&network); err != nil {
+		if errors.Is(err, storage.ErrInvalidCustomField) {
+			log.Warn("Network creation failed - invalid custom field", "error", err, "name", network.Name)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, labels.ErrInvalidLabel) {
+			log.Warn("Network creation failed - invalid label", "error", err, "name", network.Name)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			log.Warn("Network creation failed - already exists", "name", network.Name)
 			h.writeError(w, http.StatusConflict, "network with this name already exists")
@@ -140,6 +158,7 @@ func (h *Handler) createNetwork(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Network created successfully", "id", network.ID, "name", network.Name, "subnet", network.Subnet)
+	setETag(w, network.Version)
 	h.writeJSON(w, http.StatusCreated, network)
 }
 
@@ -164,6 +183,17 @@ func (h *Handler) updateNetwork(w http.ResponseWriter, r *http.Request) {
 	// Ensure ID matches URL
 	network.ID = id
 
+	// An If-Match header takes precedence over the version in the body
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Warn("If-Match header not accepted", "error", err, "id", id)
+		h.preconditionError(w, err)
+		return
+	}
+	if version != 0 {
+		network.Version = version
+	}
+
 	// Validate subnet if provided (though it's required in model, JSON decode might leave it empty or partially filled)
 	if network.Subnet != "" {
 		if _, _, err := net.ParseCIDR(network.Subnet); err != nil {
@@ -181,6 +211,21 @@ func (h *Handler) updateNetwork(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := netStorage.UpdateNetwork(&network); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			log.Warn("Network update failed - version conflict", "id", id, "version", network.Version)
+			h.preconditionError(w, err)
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidCustomField) {
+			log.Warn("Network update failed - invalid custom field", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, labels.ErrInvalidLabel) {
+			log.Warn("Network update failed - invalid label", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if errors.Is(err, storage.ErrNetworkNotFound) {
 			log.Warn("Network update failed - not found", "id", id)
 			h.writeError(w, http.StatusNotFound, "network not found")
@@ -202,9 +247,23 @@ func (h *Handler) updateNetwork(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Network updated successfully", "id", id, "name", network.Name)
+	setETag(w, network.Version)
 	h.writeJSON(w, http.StatusOK, network)
 }
 
+// patchNetwork handles PATCH /api/networks/{id}
+func (h *Handler) patchNetwork(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.storage.(storage.NetworkStorage); !ok {
+		log.Warn("Networks not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "networks are not supported by this storage backend")
+		return
+	}
+
+	h.patch(w, r, func(st storage.Storage, id string) (interface{}, error) {
+		return st.(storage.NetworkStorage).GetNetwork(id)
+	}, (*Handler).updateNetwork)
+}
+
 // deleteNetwork handles DELETE /api/networks/{id}
 func (h *Handler) deleteNetwork(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -216,14 +275,30 @@ func (h *Handler) deleteNetwork(w http.ResponseWriter, r *http.Request) {
 
 	log.Debug("Deleting network", "id", id)
 
-	netStorage, ok := h.storage.(storage.NetworkStorage)
-	if !ok {
+	if _, ok := h.storage.(storage.NetworkStorage); !ok {
 		log.Warn("Networks not supported by storage backend")
 		h.writeError(w, http.StatusNotImplemented, "networks are not supported by this storage backend")
 		return
 	}
 
-	if err := netStorage.DeleteNetwork(id); err != nil {
+	err := h.withTx(func(st storage.Storage) error {
+		netStorage := st.(storage.NetworkStorage)
+		if err := checkIfMatch(r, func() (int, error) {
+			current, err := netStorage.GetNetwork(id)
+			if err != nil {
+				return 0, err
+			}
+			return current.Version, nil
+		}); err != nil {
+			return err
+		}
+		return netStorage.DeleteNetwork(id)
+	})
+	if err != nil {
+		if h.preconditionError(w, err) {
+			log.Warn("Network deletion failed - precondition", "error", err, "id", id)
+			return
+		}
 		if errors.Is(err, storage.ErrNetworkNotFound) {
 			log.Warn("Network deletion failed - not found", "id", id)
 			h.writeError(w, http.StatusNotFound, "network not found")
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/martinsuchenak/rackd/internal/jsonpatch"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// errUnsupportedPatch is returned for a PATCH body that is neither a merge patch nor a JSON Patch
+var errUnsupportedPatch = errors.New("PATCH requires Content-Type " + jsonpatch.MergePatchType + " or " + jsonpatch.JSONPatchType)
+
+// patchedRequest applies the PATCH request r to current and returns the
+// equivalent PUT request with the full, patched resource as its body
+func patchedRequest(r *http.Request, current interface{}) (*http.Request, error) {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", jsonpatch.ErrInvalidPatch, err)
+	}
+
+	var patched []byte
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "", "application/json", jsonpatch.MergePatchType:
+		patched, err = jsonpatch.Merge(doc, body)
+	case jsonpatch.JSONPatchType:
+		patched, err = jsonpatch.Apply(doc, body)
+	default:
+		return nil, errUnsupportedPatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	if patched, err = clearRemovedFields(doc, patched); err != nil {
+		return nil, err
+	}
+
+	put := r.Clone(r.Context())
+	put.Method = http.MethodPut
+	put.Body = io.NopCloser(bytes.NewReader(patched))
+	put.ContentLength = int64(len(patched))
+	put.Header.Set("Content-Type", "application/json")
+	return put, nil
+}
+
+// clearRemovedFields sets fields that the patch removed or set to null to their
+// empty value. Updates keep the stored value of a missing field, such as the
+// labels of a device, so removing it from the patched document would do nothing.
+func clearRemovedFields(doc, patched []byte) ([]byte, error) {
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return nil, fmt.Errorf("%w: the patched document must be an object", jsonpatch.ErrInvalidPatch)
+	}
+
+	for key, value := range before {
+		if current, ok := after[key]; ok && string(current) != "null" {
+			continue
+		}
+		switch value[0] {
+		case '{':
+			after[key] = json.RawMessage(`{}`)
+		case '[':
+			after[key] = json.RawMessage(`[]`)
+		case '"':
+			after[key] = json.RawMessage(`""`)
+		case 't', 'f':
+			after[key] = json.RawMessage(`false`)
+		case 'n':
+		default:
+			after[key] = json.RawMessage(`0`)
+		}
+	}
+	return json.Marshal(after)
+}
+
+// patch handles PATCH on a resource: the patch is applied to the current
+// resource from get, and the result passed to the resource's PUT handler. Both
+// run in one transaction, so no concurrent change is lost in between.
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, get func(st storage.Storage, id string) (interface{}, error), put func(h *Handler, w http.ResponseWriter, r *http.Request)) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, http.StatusBadRequest, "ID required")
+		return
+	}
+
+	log.Debug("Patching resource", "path", r.URL.Path)
+	err := h.withTx(func(st storage.Storage) error {
+		current, err := get(st, id)
+		if err != nil {
+			return err
+		}
+		req, err := patchedRequest(r, current)
+		if err != nil {
+			return err
+		}
+		put(NewHandler(st), w, req)
+		return nil
+	})
+	if err != nil {
+		status := patchErrorStatus(err)
+		if status == 0 {
+			h.internalError(w, err)
+			return
+		}
+		log.Warn("Patch failed", "path", r.URL.Path, "error", err)
+		h.writeError(w, status, err.Error())
+	}
+}
+
+// patchErrorStatus returns the response status for a PATCH that could not be
+// applied, or 0 for an internal error
+func patchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrDeviceNotFound), errors.Is(err, storage.ErrDatacenterNotFound),
+		errors.Is(err, storage.ErrNetworkNotFound), errors.Is(err, storage.ErrPoolNotFound),
+		errors.Is(err, storage.ErrDiscoveryRuleNotFound), errors.Is(err, storage.ErrPromotionPolicyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errUnsupportedPatch):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, jsonpatch.ErrInvalidPatch):
+		return http.StatusBadRequest
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		return http.StatusConflict
+	case errors.Is(err, jsonpatch.ErrPathNotFound):
+		return http.StatusUnprocessableEntity
+	}
+	return 0
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net"
 	"net/http"
 	"strings"
 
+	"github.com/martinsuchenak/rackd/internal/labels"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
 	"github.com/martinsuchenak/rackd/internal/storage"
@@ -29,7 +31,14 @@ func (h *Handler) listNetworkPools(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pools, err := poolStorage.ListNetworkPools(&model.NetworkPoolFilter{NetworkID: networkID})
+	selector, err := labelSelector(r)
+	if err != nil {
+		log.Warn("Invalid label selector", "error", err, "network_id", networkID)
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pools, err := poolStorage.ListNetworkPools(&model.NetworkPoolFilter{NetworkID: networkID, LabelSelector: selector})
 	if err != nil {
 		log.Error("Failed to list network pools", "error", err, "network_id", networkID)
 		h.internalError(w, err)
@@ -60,7 +69,7 @@ func (h *Handler) getNetworkPool(w http.ResponseWriter, r *http.Request) {
 
 	pool, err := poolStorage.GetNetworkPool(id)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, storage.ErrPoolNotFound) {
 			log.Warn("Network pool not found", "id", id)
 			h.writeError(w, http.StatusNotFound, "network pool not found")
 			return
@@ -71,6 +80,7 @@ func (h *Handler) getNetworkPool(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Retrieved network pool", "id", id, "name", pool.Name)
+	setETag(w, pool.Version)
 	h.writeJSON(w, http.StatusOK, pool)
 }
 
@@ -121,6 +131,11 @@ func (h *Handler) createNetworkPool(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := poolStorage.CreateNetworkPool(&pool); err != nil {
+		if errors.Is(err, labels.ErrInvalidLabel) {
+			log.Warn("Network pool creation failed - invalid label", "error", err, "name", pool.Name, "network_id", networkID)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if strings.Contains(err.Error(), "already exists") { // Assuming unique name/network constraint
 			log.Warn("Network pool creation failed - already exists", "name", pool.Name, "network_id", networkID)
 			h.writeError(w, http.StatusConflict, "network pool already exists")
@@ -132,6 +147,7 @@ func (h *Handler) createNetworkPool(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Network pool created successfully", "id", pool.ID, "name", pool.Name, "network_id", networkID)
+	setETag(w, pool.Version)
 	h.writeJSON(w, http.StatusCreated, pool)
 }
 
@@ -155,6 +171,17 @@ func (h *Handler) updateNetworkPool(w http.ResponseWriter, r *http.Request) {
 
 	pool.ID = id
 
+	// An If-Match header takes precedence over the version in the body
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Warn("If-Match header not accepted", "error", err, "id", id)
+		h.preconditionError(w, err)
+		return
+	}
+	if version != 0 {
+		pool.Version = version
+	}
+
 	poolStorage, ok := h.storage.(storage.NetworkPoolStorage)
 	if !ok {
 		log.Warn("Network pools not supported by storage backend")
@@ -163,7 +190,17 @@ func (h *Handler) updateNetworkPool(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := poolStorage.UpdateNetworkPool(&pool); err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			log.Warn("Network pool update failed - version conflict", "id", id, "version", pool.Version)
+			h.preconditionError(w, err)
+			return
+		}
+		if errors.Is(err, labels.ErrInvalidLabel) {
+			log.Warn("Network pool update failed - invalid label", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrPoolNotFound) {
 			log.Warn("Network pool update failed - not found", "id", id)
 			h.writeError(w, http.StatusNotFound, "network pool not found")
 			return
@@ -174,9 +211,23 @@ func (h *Handler) updateNetworkPool(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Network pool updated successfully", "id", id, "name", pool.Name)
+	setETag(w, pool.Version)
 	h.writeJSON(w, http.StatusOK, pool)
 }
 
+// patchNetworkPool handles PATCH /api/pools/{id}
+func (h *Handler) patchNetworkPool(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.storage.(storage.NetworkPoolStorage); !ok {
+		log.Warn("Network pools not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "network pools not supported by storage backend")
+		return
+	}
+
+	h.patch(w, r, func(st storage.Storage, id string) (interface{}, error) {
+		return st.(storage.NetworkPoolStorage).GetNetworkPool(id)
+	}, (*Handler).updateNetworkPool)
+}
+
 // deleteNetworkPool handles DELETE /api/pools/{id}
 func (h *Handler) deleteNetworkPool(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -188,15 +239,31 @@ func (h *Handler) deleteNetworkPool(w http.ResponseWriter, r *http.Request) {
 
 	log.Debug("Deleting network pool", "id", id)
 
-	poolStorage, ok := h.storage.(storage.NetworkPoolStorage)
-	if !ok {
+	if _, ok := h.storage.(storage.NetworkPoolStorage); !ok {
 		log.Warn("Network pools not supported by storage backend")
 		h.writeError(w, http.StatusNotImplemented, "network pools not supported by storage backend")
 		return
 	}
 
-	if err := poolStorage.DeleteNetworkPool(id); err != nil {
-		if strings.Contains(err.Error(), "not found") {
+	err := h.withTx(func(st storage.Storage) error {
+		poolStorage := st.(storage.NetworkPoolStorage)
+		if err := checkIfMatch(r, func() (int, error) {
+			current, err := poolStorage.GetNetworkPool(id)
+			if err != nil {
+				return 0, err
+			}
+			return current.Version, nil
+		}); err != nil {
+			return err
+		}
+		return poolStorage.DeleteNetworkPool(id)
+	})
+	if err != nil {
+		if h.preconditionError(w, err) {
+			log.Warn("Network pool deletion failed - precondition", "error", err, "id", id)
+			return
+		}
+		if errors.Is(err, storage.ErrPoolNotFound) {
 			log.Warn("Network pool deletion failed - not found", "id", id)
 			h.writeError(w, http.StatusNotFound, "network pool not found")
 			return
@@ -230,7 +297,12 @@ func (h *Handler) getNextIP(w http.ResponseWriter, r *http.Request) {
 
 	ip, err := poolStorage.GetNextAvailableIP(id)
 	if err != nil {
-		if strings.Contains(err.Error(), "no available IPs") {
+		if errors.Is(err, storage.ErrPoolNotFound) {
+			log.Warn("Network pool not found", "pool_id", id)
+			h.writeError(w, http.StatusNotFound, "network pool not found")
+			return
+		}
+		if errors.Is(err, storage.ErrPoolExhausted) {
 			log.Warn("No available IPs in pool", "pool_id", id)
 			h.writeError(w, http.StatusConflict, "no available IPs in pool")
 			return
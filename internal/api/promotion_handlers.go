@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/martinsuchenak/rackd/internal/autopromote"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// listPromotionPolicies handles GET /api/discovery/promotion-policies
+func (h *DiscoveryHandler) listPromotionPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.storage.ListPromotionPolicies()
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, policies)
+}
+
+// createPromotionPolicy handles POST /api/discovery/promotion-policies
+func (h *DiscoveryHandler) createPromotionPolicy(w http.ResponseWriter, r *http.Request) {
+	var policy model.PromotionPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := autopromote.Validate(&policy); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	policy.ID = generateID("promotion_policy")
+	if err := h.storage.CreatePromotionPolicy(&policy); err != nil {
+		h.promotionPolicyError(w, err)
+		return
+	}
+
+	log.Info("Promotion policy created", "id", policy.ID, "name", policy.Name)
+	h.writeJSON(w, http.StatusCreated, policy)
+}
+
+// getPromotionPolicy handles GET /api/discovery/promotion-policies/{id}
+func (h *DiscoveryHandler) getPromotionPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.storage.GetPromotionPolicy(r.PathValue("id"))
+	if err != nil {
+		h.promotionPolicyError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, policy)
+}
+
+// updatePromotionPolicy handles PUT /api/discovery/promotion-policies/{id}
+func (h *DiscoveryHandler) updatePromotionPolicy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var policy model.PromotionPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := autopromote.Validate(&policy); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	policy.ID = id
+	if err := h.storage.UpdatePromotionPolicy(&policy); err != nil {
+		h.promotionPolicyError(w, err)
+		return
+	}
+
+	log.Info("Promotion policy updated", "id", id)
+	h.writeJSON(w, http.StatusOK, policy)
+}
+
+// patchPromotionPolicy handles PATCH /api/discovery/promotion-policies/{id}
+func (h *DiscoveryHandler) patchPromotionPolicy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	apply := func(st storage.DiscoveryStorage) error {
+		policy, err := st.GetPromotionPolicy(id)
+		if err != nil {
+			return err
+		}
+		req, err := patchedRequest(r, policy)
+		if err != nil {
+			return err
+		}
+		NewDiscoveryHandler(st, h.scans).updatePromotionPolicy(w, req)
+		return nil
+	}
+
+	var err error
+	if txStorage, ok := h.storage.(storage.TxStorage); ok {
+		err = txStorage.WithTx(func(tx storage.Storage) error {
+			return apply(tx.(storage.DiscoveryStorage))
+		})
+	} else {
+		err = apply(h.storage)
+	}
+	if err != nil {
+		status := patchErrorStatus(err)
+		if status == 0 {
+			h.internalError(w, err)
+			return
+		}
+		if status == http.StatusNotFound {
+			h.writeError(w, status, "promotion policy not found")
+			return
+		}
+		h.writeError(w, status, err.Error())
+	}
+}
+
+// deletePromotionPolicy handles DELETE /api/discovery/promotion-policies/{id}
+func (h *DiscoveryHandler) deletePromotionPolicy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := h.storage.DeletePromotionPolicy(id); err != nil {
+		h.promotionPolicyError(w, err)
+		return
+	}
+
+	log.Info("Promotion policy deleted", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// previewPromotionPolicies handles GET /api/discovery/promotion-policies/preview
+func (h *DiscoveryHandler) previewPromotionPolicies(w http.ResponseWriter, r *http.Request) {
+	h.evaluatePromotionPolicies(w, r, autopromote.Preview)
+}
+
+// runPromotionPolicies handles POST /api/discovery/promotion-policies/run
+func (h *DiscoveryHandler) runPromotionPolicies(w http.ResponseWriter, r *http.Request) {
+	h.evaluatePromotionPolicies(w, r, autopromote.Run)
+}
+
+// evaluatePromotionPolicies previews or runs the enabled policies on the
+// discovered devices of the network_id query parameter, or of all networks
+func (h *DiscoveryHandler) evaluatePromotionPolicies(w http.ResponseWriter, r *http.Request, evaluate func(autopromote.Store, string) (*model.PromotionRun, error)) {
+	store, ok := h.storage.(autopromote.Store)
+	if !ok {
+		h.writeError(w, http.StatusNotImplemented, "auto-promotion is not supported by this storage backend")
+		return
+	}
+
+	networkID := r.URL.Query().Get("network_id")
+	if networkID != "" {
+		if _, err := h.storage.GetNetwork(networkID); err != nil {
+			if errors.Is(err, storage.ErrNetworkNotFound) {
+				h.writeError(w, http.StatusNotFound, "network not found")
+				return
+			}
+			h.internalError(w, err)
+			return
+		}
+	}
+
+	run, err := evaluate(store, networkID)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, run)
+}
+
+// promotionPolicyError writes the response for a promotion policy storage error
+func (h *DiscoveryHandler) promotionPolicyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrPromotionPolicyNotFound):
+		h.writeError(w, http.StatusNotFound, "promotion policy not found")
+	case errors.Is(err, storage.ErrDuplicatePromotionPolicy):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.internalError(w, err)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/reconcile"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// getReconcileReport handles GET /api/reconcile
+func (h *Handler) getReconcileReport(w http.ResponseWriter, r *http.Request) {
+	opts := model.ReconcileOptions{NetworkID: r.URL.Query().Get("network_id")}
+	if value := r.URL.Query().Get("missed_scans"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			log.Warn("Invalid missed scan count", "missed_scans", value)
+			h.writeError(w, http.StatusBadRequest, "missed_scans must be a positive integer")
+			return
+		}
+		opts.MissedScans = n
+	}
+
+	store, ok := h.storage.(reconcile.Store)
+	if !ok {
+		log.Warn("Reconciliation not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "reconciliation is not supported by this storage backend")
+		return
+	}
+
+	if netStorage, ok := h.storage.(storage.NetworkStorage); ok && opts.NetworkID != "" {
+		if _, err := netStorage.GetNetwork(opts.NetworkID); err != nil {
+			if errors.Is(err, storage.ErrNetworkNotFound) {
+				log.Warn("Network not found", "id", opts.NetworkID)
+				h.writeError(w, http.StatusNotFound, "network not found")
+				return
+			}
+			h.internalError(w, err)
+			return
+		}
+	}
+
+	log.Debug("Reconciling discovered hosts with inventory", "network_id", opts.NetworkID, "missed_scans", opts.MissedScans)
+	report, err := reconcile.Run(store, opts)
+	if err != nil {
+		log.Error("Failed to reconcile inventory", "error", err)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Reconciled inventory", "network_id", opts.NetworkID, "matched", report.Matched, "findings", len(report.Findings))
+	h.writeJSON(w, http.StatusOK, report)
+}
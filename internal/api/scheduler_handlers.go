@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/worker"
+)
+
+// SchedulerHandler handles the endpoints of the background task scheduler
+type SchedulerHandler struct {
+	scheduler *worker.Scheduler
+}
+
+// NewSchedulerHandler creates a new scheduler API handler. The scheduler may
+// be nil when nothing is scheduled.
+func NewSchedulerHandler(s *worker.Scheduler) *SchedulerHandler {
+	return &SchedulerHandler{scheduler: s}
+}
+
+// RegisterRoutes registers the scheduler API routes
+func (h *SchedulerHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/scheduler/tasks", h.listTasks)
+}
+
+// listTasks handles GET /api/scheduler/tasks
+func (h *SchedulerHandler) listTasks(w http.ResponseWriter, r *http.Request) {
+	tasks := []worker.TaskInfo{}
+	if h.scheduler != nil {
+		tasks = h.scheduler.Tasks()
+	}
+
+	log.Debug("Listed scheduler tasks", "count", len(tasks))
+	h.writeJSON(w, http.StatusOK, tasks)
+}
+
+func (h *SchedulerHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/software"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// setDeviceSoftware handles PUT /api/devices/{id}/software
+func (h *Handler) setDeviceSoftware(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var inventory model.SoftwareInventory
+	if err := json.NewDecoder(r.Body).Decode(&inventory); err != nil {
+		log.Warn("Invalid software inventory request body", "error", err, "id", id)
+		h.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	log.Debug("Storing software inventory", "id", id, "packages", len(inventory.Packages))
+
+	softwareStorage, ok := h.storage.(storage.SoftwareStorage)
+	if !ok {
+		log.Warn("Software inventory not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "software inventory is not supported by this storage backend")
+		return
+	}
+
+	if err := softwareStorage.SetDeviceSoftware(id, &inventory); err != nil {
+		if errors.Is(err, storage.ErrDeviceNotFound) {
+			log.Warn("Device not found for software inventory", "id", id)
+			h.writeError(w, http.StatusNotFound, "device not found")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidSoftware) {
+			log.Warn("Software inventory rejected", "error", err, "id", id)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Error("Failed to store software inventory", "error", err, "id", id)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Software inventory stored", "device_id", inventory.DeviceID, "packages", len(inventory.Packages))
+	h.writeJSON(w, http.StatusOK, inventory)
+}
+
+// getDeviceSoftware handles GET /api/devices/{id}/software
+func (h *Handler) getDeviceSoftware(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	log.Debug("Getting software inventory", "id", id)
+
+	softwareStorage, ok := h.storage.(storage.SoftwareStorage)
+	if !ok {
+		log.Warn("Software inventory not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "software inventory is not supported by this storage backend")
+		return
+	}
+
+	inventory, err := softwareStorage.GetDeviceSoftware(id)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceNotFound) {
+			log.Warn("Device not found for software inventory", "id", id)
+			h.writeError(w, http.StatusNotFound, "device not found")
+			return
+		}
+		log.Error("Failed to get software inventory", "error", err, "id", id)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Retrieved software inventory", "device_id", inventory.DeviceID, "packages", len(inventory.Packages))
+	h.writeJSON(w, http.StatusOK, inventory)
+}
+
+// findPackageInstalls handles GET /api/software/packages?name=&version=
+func (h *Handler) findPackageInstalls(w http.ResponseWriter, r *http.Request) {
+	filter := &model.PackageFilter{
+		Name:    r.URL.Query().Get("name"),
+		Version: r.URL.Query().Get("version"),
+	}
+	if filter.Name == "" {
+		h.writeError(w, http.StatusBadRequest, "package name required")
+		return
+	}
+
+	log.Debug("Finding package installs", "name", filter.Name, "version", filter.Version)
+
+	softwareStorage, ok := h.storage.(storage.SoftwareStorage)
+	if !ok {
+		log.Warn("Software inventory not supported by storage backend")
+		h.writeError(w, http.StatusNotImplemented, "software inventory is not supported by this storage backend")
+		return
+	}
+
+	installs, err := softwareStorage.FindPackageInstalls(filter)
+	if err != nil {
+		if errors.Is(err, software.ErrInvalidConstraint) {
+			log.Warn("Invalid version constraint", "error", err, "version", filter.Version)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Error("Failed to find package installs", "error", err, "name", filter.Name)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Found package installs", "name", filter.Name, "version", filter.Version, "count", len(installs))
+	h.writeJSON(w, http.StatusOK, installs)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/martinsuchenak/rackd/internal/topology"
+)
+
+// getTopology handles GET /api/topology
+func (h *Handler) getTopology(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = topology.FormatJSON
+	}
+
+	switch format {
+	case topology.FormatDOT, topology.FormatGraphML, topology.FormatJSON:
+	default:
+		log.Warn("Unsupported topology format requested", "format", format)
+		h.writeError(w, http.StatusBadRequest, "format must be one of: dot, graphml, json")
+		return
+	}
+
+	filter := topologyFilter(r)
+
+	log.Debug("Building topology", "format", format, "datacenter_id", filter.DatacenterID, "tags", filter.Tags, "types", filter.RelationshipTypes)
+
+	graph, err := topology.Build(h.storage, filter)
+	if err != nil {
+		if errors.Is(err, storage.ErrDatacenterNotFound) {
+			log.Warn("Topology datacenter not found", "datacenter_id", filter.DatacenterID)
+			h.writeError(w, http.StatusNotFound, "datacenter not found")
+			return
+		}
+		log.Error("Failed to build topology", "error", err)
+		h.internalError(w, err)
+		return
+	}
+
+	// Encode into a buffer so encoding failures still produce a clean error response
+	var buf bytes.Buffer
+	if err := topology.Encode(&buf, graph, format); err != nil {
+		log.Error("Failed to encode topology", "error", err, "format", format)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Exported topology", "format", format, "nodes", len(graph.Nodes), "edges", len(graph.Edges))
+	w.Header().Set("Content-Type", topology.ContentType(format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// getTopologyLayout handles GET /api/topology/layout
+func (h *Handler) getTopologyLayout(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = model.TopologyGroupByDatacenter
+	}
+	if groupBy != model.TopologyGroupByDatacenter && groupBy != model.TopologyGroupByNetwork {
+		log.Warn("Unsupported topology grouping requested", "group_by", groupBy)
+		h.writeError(w, http.StatusBadRequest, "group_by must be one of: datacenter, network")
+		return
+	}
+
+	filter := topologyFilter(r)
+
+	log.Debug("Building topology layout", "group_by", groupBy, "datacenter_id", filter.DatacenterID, "tags", filter.Tags, "types", filter.RelationshipTypes)
+
+	graph, err := topology.Build(h.storage, filter)
+	if err != nil {
+		if errors.Is(err, storage.ErrDatacenterNotFound) {
+			log.Warn("Topology datacenter not found", "datacenter_id", filter.DatacenterID)
+			h.writeError(w, http.StatusNotFound, "datacenter not found")
+			return
+		}
+		log.Error("Failed to build topology", "error", err)
+		h.internalError(w, err)
+		return
+	}
+
+	layout, err := topology.Layout(graph, groupBy)
+	if err != nil {
+		log.Error("Failed to lay out topology", "error", err, "group_by", groupBy)
+		h.internalError(w, err)
+		return
+	}
+
+	log.Info("Built topology layout", "group_by", groupBy, "groups", len(layout.Groups), "nodes", len(layout.Nodes), "edges", len(layout.Edges))
+	h.writeJSON(w, http.StatusOK, layout)
+}
+
+// topologyFilter reads the shared topology filter query parameters
+func topologyFilter(r *http.Request) *model.TopologyFilter {
+	query := r.URL.Query()
+	return &model.TopologyFilter{
+		DatacenterID:      query.Get("datacenter_id"),
+		Tags:              query["tag"],
+		RelationshipTypes: query["type"],
+	}
+}
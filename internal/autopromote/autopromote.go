@@ -0,0 +1,337 @@
+// Package autopromote promotes discovered devices to inventory devices by
+// declarative policies instead of one at a time.
+//
+// Policies run in priority order and a discovered device is promoted by the first
+// enabled policy whose conditions it meets. Only online devices that were not
+// promoted before are considered. The device is named from the template's name
+// pattern, which may use the placeholders {hostname}, {short_hostname}, {ip},
+// {ip_dashed} and {mac}; a device is skipped when a placeholder has no value or
+// the name is already taken, so reruns never create duplicates.
+package autopromote
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// DefaultNamePattern names promoted devices when a template has no name pattern
+const DefaultNamePattern = "{short_hostname}"
+
+// ErrInvalidPolicy is returned for a policy that cannot be run
+var ErrInvalidPolicy = errors.New("invalid promotion policy")
+
+// Store is the storage policies are read from and devices promoted in
+type Store interface {
+	ListPromotionPolicies() ([]model.PromotionPolicy, error)
+	ListDiscoveredDevices(filter *model.DiscoveredDeviceFilter) ([]model.DiscoveredDevice, error)
+	ListDevices(filter *model.DeviceFilter) ([]model.Device, error)
+	PromoteDevice(id string, req *model.PromoteDeviceRequest) (*model.Device, error)
+	AddRelationship(parentID, childID, relationshipType string) error
+}
+
+// placeholder matches a placeholder of a name pattern
+var placeholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// placeholders lists the known placeholders of name patterns
+var placeholders = map[string]bool{
+	"hostname":       true,
+	"short_hostname": true,
+	"ip":             true,
+	"ip_dashed":      true,
+	"mac":            true,
+}
+
+// runMu serializes runs, so concurrent scans do not promote a device twice
+var runMu sync.Mutex
+
+// Validate checks that a policy has a name, at least one condition, a valid
+// hostname pattern and a usable template
+func Validate(policy *model.PromotionPolicy) error {
+	if strings.TrimSpace(policy.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPolicy)
+	}
+
+	m := policy.Match
+	if m.NetworkID == "" && m.MinConfidence == 0 && len(m.OpenPorts) == 0 && m.HostnamePattern == "" && m.OSFamily == "" {
+		return fmt.Errorf("%w: at least one match condition is required", ErrInvalidPolicy)
+	}
+	if m.MinConfidence < 0 || m.MinConfidence > 100 {
+		return fmt.Errorf("%w: min_confidence must be between 0 and 100", ErrInvalidPolicy)
+	}
+	for _, port := range m.OpenPorts {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("%w: invalid port %d", ErrInvalidPolicy, port)
+		}
+	}
+	if m.HostnamePattern != "" {
+		if _, err := regexp.Compile(m.HostnamePattern); err != nil {
+			return fmt.Errorf("%w: invalid hostname_pattern: %v", ErrInvalidPolicy, err)
+		}
+	}
+
+	for _, match := range placeholder.FindAllStringSubmatch(policy.Template.NamePattern, -1) {
+		if !placeholders[match[1]] {
+			return fmt.Errorf("%w: unknown placeholder {%s} in name_pattern", ErrInvalidPolicy, match[1])
+		}
+	}
+	for _, r := range policy.Template.Relationships {
+		if r.DeviceID == "" || r.Type == "" {
+			return fmt.Errorf("%w: relationships need a device_id and a type", ErrInvalidPolicy)
+		}
+	}
+	return nil
+}
+
+// Preview returns what the enabled policies would promote in a network, or in
+// all networks when networkID is empty, without changing anything
+func Preview(store Store, networkID string) (*model.PromotionRun, error) {
+	return evaluate(store, networkID, true)
+}
+
+// Run promotes the devices the enabled policies match in a network, or in all
+// networks when networkID is empty
+func Run(store Store, networkID string) (*model.PromotionRun, error) {
+	runMu.Lock()
+	defer runMu.Unlock()
+	return evaluate(store, networkID, false)
+}
+
+// compiled is a policy with its hostname pattern compiled
+type compiled struct {
+	policy   *model.PromotionPolicy
+	hostname *regexp.Regexp
+}
+
+func evaluate(store Store, networkID string, dryRun bool) (*model.PromotionRun, error) {
+	run := &model.PromotionRun{DryRun: dryRun, NetworkID: networkID, Candidates: []model.PromotionCandidate{}}
+
+	all, err := store.ListPromotionPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("listing promotion policies: %w", err)
+	}
+	var policies []compiled
+	for i := range all {
+		p := &all[i]
+		if !p.Enabled {
+			continue
+		}
+		if err := Validate(p); err != nil {
+			log.Warn("Skipping invalid promotion policy", "policy_id", p.ID, "error", err)
+			continue
+		}
+		c := compiled{policy: p}
+		if p.Match.HostnamePattern != "" {
+			c.hostname = regexp.MustCompile(p.Match.HostnamePattern)
+		}
+		policies = append(policies, c)
+	}
+	if len(policies) == 0 {
+		return run, nil
+	}
+
+	promoted := false
+	discovered, err := store.ListDiscoveredDevices(&model.DiscoveredDeviceFilter{
+		NetworkID: networkID,
+		Status:    "online",
+		Promoted:  &promoted,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing discovered devices: %w", err)
+	}
+	sortByIP(discovered)
+
+	devices, err := store.ListDevices(nil)
+	if err != nil {
+		return nil, fmt.Errorf("listing devices: %w", err)
+	}
+	// Names are compared ignoring case, as two devices differing only in case are confusing
+	names := make(map[string]bool, len(devices))
+	ids := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		names[strings.ToLower(d.Name)] = true
+		ids[d.ID] = true
+	}
+
+	for i := range discovered {
+		d := &discovered[i]
+		var policy *model.PromotionPolicy
+		for _, c := range policies {
+			if matches(c, d) {
+				policy = c.policy
+				break
+			}
+		}
+		if policy == nil {
+			continue
+		}
+
+		candidate := plan(policy, d)
+		switch {
+		case candidate.Skipped != "":
+		case names[strings.ToLower(candidate.Device.Name)]:
+			candidate.Skipped = fmt.Sprintf("a device named %q already exists", candidate.Device.Name)
+		default:
+			for _, r := range candidate.Relationships {
+				if !ids[r.DeviceID] {
+					candidate.Skipped = fmt.Sprintf("related device %s does not exist", r.DeviceID)
+					break
+				}
+			}
+		}
+
+		switch {
+		case candidate.Skipped != "":
+			run.Skipped++
+		case dryRun:
+			names[strings.ToLower(candidate.Device.Name)] = true
+			run.Promoted++
+		default:
+			names[strings.ToLower(candidate.Device.Name)] = true
+			if promote(store, &candidate) {
+				run.Promoted++
+			} else {
+				run.Failed++
+			}
+		}
+		run.Candidates = append(run.Candidates, candidate)
+	}
+
+	if !dryRun && (run.Promoted > 0 || run.Failed > 0) {
+		log.Info("Promotion policies applied", "network_id", networkID, "promoted", run.Promoted, "skipped", run.Skipped, "failed", run.Failed)
+	}
+	return run, nil
+}
+
+// matches reports whether a discovered device meets every condition of a policy
+func matches(c compiled, d *model.DiscoveredDevice) bool {
+	m := c.policy.Match
+	if m.NetworkID != "" && m.NetworkID != d.NetworkID {
+		return false
+	}
+	if d.Confidence < m.MinConfidence {
+		return false
+	}
+	if m.OSFamily != "" && !strings.EqualFold(m.OSFamily, d.OSFamily) {
+		return false
+	}
+	if c.hostname != nil && (d.Hostname == "" || !c.hostname.MatchString(d.Hostname)) {
+		return false
+	}
+	open := make(map[int]bool, len(d.OpenPorts))
+	for _, port := range d.OpenPorts {
+		open[port] = true
+	}
+	for _, port := range m.OpenPorts {
+		if !open[port] {
+			return false
+		}
+	}
+	return true
+}
+
+// plan returns the device a policy creates for a discovered device
+func plan(policy *model.PromotionPolicy, d *model.DiscoveredDevice) model.PromotionCandidate {
+	t := policy.Template
+	candidate := model.PromotionCandidate{
+		DiscoveredID:  d.ID,
+		IP:            d.IP,
+		Hostname:      d.Hostname,
+		NetworkID:     d.NetworkID,
+		PolicyID:      policy.ID,
+		PolicyName:    policy.Name,
+		Relationships: t.Relationships,
+		Device: model.PromoteDeviceRequest{
+			Description:  t.Description,
+			MakeModel:    t.MakeModel,
+			DatacenterID: t.DatacenterID,
+			Location:     t.Location,
+			Tags:         append([]string(nil), t.Tags...),
+			Domains:      append([]string(nil), t.Domains...),
+		},
+	}
+
+	name, missing := expand(t.NamePattern, d)
+	if missing != "" {
+		candidate.Skipped = fmt.Sprintf("name pattern needs {%s}, which is unknown", missing)
+		return candidate
+	}
+	candidate.Device.Name = name
+	return candidate
+}
+
+// expand fills in the placeholders of a name pattern, returning the first
+// placeholder without a value when the name cannot be made
+func expand(pattern string, d *model.DiscoveredDevice) (string, string) {
+	if pattern == "" {
+		pattern = DefaultNamePattern
+	}
+	hostname := strings.TrimSuffix(d.Hostname, ".")
+	short, _, _ := strings.Cut(hostname, ".")
+	values := map[string]string{
+		"hostname":       hostname,
+		"short_hostname": short,
+		"ip":             d.IP,
+		"ip_dashed":      strings.NewReplacer(".", "-", ":", "-").Replace(d.IP),
+		"mac":            d.MACAddress,
+	}
+
+	missing := ""
+	name := placeholder.ReplaceAllStringFunc(pattern, func(p string) string {
+		key := p[1 : len(p)-1]
+		if values[key] == "" && missing == "" {
+			missing = key
+		}
+		return values[key]
+	})
+	if missing != "" {
+		return "", missing
+	}
+	return strings.TrimSpace(name), ""
+}
+
+// promote promotes a candidate and adds its relationships, recording the
+// created device or the error on the candidate. It reports whether the device
+// was created; a failed relationship does not undo the promotion.
+func promote(store Store, candidate *model.PromotionCandidate) bool {
+	req := candidate.Device
+	device, err := store.PromoteDevice(candidate.DiscoveredID, &req)
+	if err != nil {
+		log.Error("Failed to auto-promote discovered device", "discovered_id", candidate.DiscoveredID, "policy_id", candidate.PolicyID, "error", err)
+		candidate.Error = err.Error()
+		return false
+	}
+	candidate.DeviceID = device.ID
+	log.Info("Discovered device auto-promoted", "discovered_id", candidate.DiscoveredID, "device_id", device.ID, "name", device.Name, "policy_id", candidate.PolicyID)
+
+	for _, r := range candidate.Relationships {
+		parent, child := r.DeviceID, device.ID
+		if r.AsParent {
+			parent, child = device.ID, r.DeviceID
+		}
+		if err := store.AddRelationship(parent, child, r.Type); err != nil {
+			log.Warn("Failed to add relationship to auto-promoted device", "device_id", device.ID, "related_id", r.DeviceID, "error", err)
+			candidate.Error = fmt.Sprintf("adding %s relationship with %s: %v", r.Type, r.DeviceID, err)
+		}
+	}
+	return true
+}
+
+// sortByIP orders discovered devices by address, so runs are reproducible
+func sortByIP(devices []model.DiscoveredDevice) {
+	sort.SliceStable(devices, func(i, j int) bool {
+		a, errA := netip.ParseAddr(devices[i].IP)
+		b, errB := netip.ParseAddr(devices[j].IP)
+		if errA != nil || errB != nil {
+			return devices[i].IP < devices[j].IP
+		}
+		return a.Less(b)
+	})
+}
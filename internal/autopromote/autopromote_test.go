@@ -0,0 +1,181 @@
+package autopromote
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// memStore holds policies, discovered devices and promoted devices in memory
+type memStore struct {
+	policies      []model.PromotionPolicy
+	discovered    []model.DiscoveredDevice
+	devices       []model.Device
+	relationships []model.DeviceRelationship
+}
+
+func (s *memStore) ListPromotionPolicies() ([]model.PromotionPolicy, error) {
+	return s.policies, nil
+}
+
+func (s *memStore) ListDiscoveredDevices(filter *model.DiscoveredDeviceFilter) ([]model.DiscoveredDevice, error) {
+	var devices []model.DiscoveredDevice
+	for _, d := range s.discovered {
+		if filter.NetworkID != "" && d.NetworkID != filter.NetworkID {
+			continue
+		}
+		if filter.Status != "" && d.Status != filter.Status {
+			continue
+		}
+		if filter.Promoted != nil && (d.PromotedToDeviceID != "") != *filter.Promoted {
+			continue
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+func (s *memStore) ListDevices(filter *model.DeviceFilter) ([]model.Device, error) {
+	return s.devices, nil
+}
+
+func (s *memStore) PromoteDevice(id string, req *model.PromoteDeviceRequest) (*model.Device, error) {
+	for i := range s.discovered {
+		if s.discovered[i].ID == id {
+			device := model.Device{ID: "dev-" + id, Name: req.Name, Tags: req.Tags}
+			s.devices = append(s.devices, device)
+			s.discovered[i].PromotedToDeviceID = device.ID
+			return &device, nil
+		}
+	}
+	return nil, errors.New("discovered device not found")
+}
+
+func (s *memStore) AddRelationship(parentID, childID, relationshipType string) error {
+	s.relationships = append(s.relationships, model.DeviceRelationship{ParentID: parentID, ChildID: childID, Type: relationshipType})
+	return nil
+}
+
+func newStore() *memStore {
+	return &memStore{
+		policies: []model.PromotionPolicy{
+			{ID: "web", Name: "web", Enabled: true, Priority: 10,
+				Match: model.PromotionMatch{NetworkID: "net-1", MinConfidence: 50, OpenPorts: []int{80, 443}, HostnamePattern: `^web\d+`},
+				Template: model.PromotionTemplate{
+					Tags:          []string{"web"},
+					Relationships: []model.PromotionRelationship{{DeviceID: "sw-1", Type: "connected_to"}},
+				}},
+			{ID: "linux", Name: "linux", Enabled: true, Priority: 20,
+				Match:    model.PromotionMatch{OSFamily: "linux"},
+				Template: model.PromotionTemplate{NamePattern: "linux-{ip_dashed}"}},
+			{ID: "off", Name: "off", Enabled: false, Priority: 0,
+				Match: model.PromotionMatch{MinConfidence: 1}},
+		},
+		// WEB3 takes the name of d4, as names are compared ignoring case
+		devices: []model.Device{{ID: "sw-1", Name: "sw-1"}, {ID: "web3", Name: "WEB3"}},
+		discovered: []model.DiscoveredDevice{
+			{ID: "d2", IP: "10.0.0.2", NetworkID: "net-1", Status: "online", Confidence: 90, Hostname: "web1.example.com", OpenPorts: []int{22, 80, 443}, OSFamily: "Linux"},
+			{ID: "d3", IP: "10.0.0.3", NetworkID: "net-1", Status: "online", Confidence: 90, Hostname: "web2", OpenPorts: []int{80}, OSFamily: "Linux"},
+			{ID: "d4", IP: "10.0.0.4", NetworkID: "net-1", Status: "online", Confidence: 90, Hostname: "web3", OpenPorts: []int{80, 443}},
+			{ID: "d5", IP: "10.0.0.5", NetworkID: "net-1", Status: "offline", Confidence: 90, OSFamily: "Linux"},
+			{ID: "d6", IP: "10.0.0.6", NetworkID: "net-1", Status: "online", Confidence: 10, Hostname: "printer"},
+			{ID: "d7", IP: "10.0.0.7", NetworkID: "net-1", Status: "online", OSFamily: "Linux", PromotedToDeviceID: "dev-x"},
+		},
+	}
+}
+
+func TestPreview(t *testing.T) {
+	store := newStore()
+	run, err := Preview(store, "net-1")
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	if !run.DryRun || run.Promoted != 2 || run.Skipped != 1 || run.Failed != 0 {
+		t.Errorf("unexpected counts %+v", run)
+	}
+	want := []struct{ id, policy, name, skipped string }{
+		{"d2", "web", "web1", ""},
+		{"d3", "linux", "linux-10-0-0-3", ""},
+		{"d4", "web", "web3", `a device named "web3" already exists`},
+	}
+	if len(run.Candidates) != len(want) {
+		t.Fatalf("candidates = %+v", run.Candidates)
+	}
+	for i, w := range want {
+		c := run.Candidates[i]
+		if c.DiscoveredID != w.id || c.PolicyID != w.policy || c.Device.Name != w.name || c.Skipped != w.skipped || c.DeviceID != "" {
+			t.Errorf("candidate %d = %+v, want %+v", i, c, w)
+		}
+	}
+
+	if len(store.devices) != 2 || len(store.relationships) != 0 {
+		t.Error("expected a dry run not to change anything")
+	}
+}
+
+func TestRun(t *testing.T) {
+	store := newStore()
+	run, err := Run(store, "")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if run.DryRun || run.Promoted != 2 || run.Skipped != 1 {
+		t.Errorf("unexpected counts %+v", run)
+	}
+	if run.Candidates[0].DeviceID != "dev-d2" || len(store.devices) != 4 {
+		t.Errorf("expected devices to be promoted, got %+v", run.Candidates)
+	}
+	if len(store.relationships) != 1 || store.relationships[0] != (model.DeviceRelationship{ParentID: "sw-1", ChildID: "dev-d2", Type: "connected_to"}) {
+		t.Errorf("unexpected relationships %+v", store.relationships)
+	}
+
+	// Promoted devices are not considered again
+	again, err := Run(store, "")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if again.Promoted != 0 || len(again.Candidates) != 1 {
+		t.Errorf("expected only the skipped device again, got %+v", again.Candidates)
+	}
+}
+
+func TestNamePattern(t *testing.T) {
+	d := &model.DiscoveredDevice{IP: "10.0.0.9", Hostname: "db1.example.com.", MACAddress: "aa:bb:cc:dd:ee:ff"}
+	for pattern, want := range map[string]string{
+		"":                       "db1",
+		"{hostname}":             "db1.example.com",
+		"srv-{ip_dashed}":        "srv-10-0-0-9",
+		"{short_hostname}-{mac}": "db1-aa:bb:cc:dd:ee:ff",
+	} {
+		if got, missing := expand(pattern, d); got != want || missing != "" {
+			t.Errorf("expand(%q) = %q, %q, want %q", pattern, got, missing, want)
+		}
+	}
+
+	if _, missing := expand("{hostname}", &model.DiscoveredDevice{IP: "10.0.0.9"}); missing != "hostname" {
+		t.Errorf("expected hostname to be missing, got %q", missing)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := model.PromotionPolicy{Name: "p", Match: model.PromotionMatch{OpenPorts: []int{22}}}
+	if err := Validate(&valid); err != nil {
+		t.Errorf("expected valid policy, got %v", err)
+	}
+
+	for name, policy := range map[string]model.PromotionPolicy{
+		"no name":        {Match: valid.Match},
+		"no conditions":  {Name: "p"},
+		"bad regexp":     {Name: "p", Match: model.PromotionMatch{HostnamePattern: "("}},
+		"bad port":       {Name: "p", Match: model.PromotionMatch{OpenPorts: []int{70000}}},
+		"bad confidence": {Name: "p", Match: model.PromotionMatch{MinConfidence: 101}},
+		"bad pattern":    {Name: "p", Match: valid.Match, Template: model.PromotionTemplate{NamePattern: "{serial}"}},
+		"bad relation":   {Name: "p", Match: valid.Match, Template: model.PromotionTemplate{Relationships: []model.PromotionRelationship{{Type: "contains"}}}},
+	} {
+		if err := Validate(&policy); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("%s: expected ErrInvalidPolicy, got %v", name, err)
+		}
+	}
+}
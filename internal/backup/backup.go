@@ -0,0 +1,351 @@
+// Package backup takes verified online backups of the database, applies
+// retention to them and restores them into a data directory.
+package backup
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+var (
+	// ErrNotFound is returned for unknown backup names
+	ErrNotFound = errors.New("backup not found")
+	// ErrInvalidBackup is returned when a backup fails verification
+	ErrInvalidBackup = errors.New("invalid backup")
+)
+
+const (
+	namePrefix = "rackd-"
+	nameLayout = "20060102-150405"
+	dbExt      = ".db"
+	gzipExt    = ".gz"
+)
+
+// Options configures a Manager
+type Options struct {
+	// Dir is the directory backups are written to
+	Dir string
+	// Compress gzips backups
+	Compress bool
+	// KeepDaily keeps the newest backup of each of the last N days that have one
+	KeepDaily int
+	// KeepWeekly keeps the newest backup of each of the last N weeks that have one
+	KeepWeekly int
+}
+
+// Manager creates, lists and prunes backups
+type Manager struct {
+	mu      sync.Mutex
+	storage storage.BackupStorage
+	opts    Options
+	now     func() time.Time
+}
+
+// NewManager creates a backup manager writing to opts.Dir
+func NewManager(s storage.BackupStorage, opts Options) *Manager {
+	return &Manager{storage: s, opts: opts, now: time.Now}
+}
+
+// Create takes a backup, verifies it and applies the retention policy
+func (m *Manager) Create() (*model.Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.opts.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating backup directory: %w", err)
+	}
+
+	created := m.now().UTC().Truncate(time.Second)
+	name := namePrefix + created.Format(nameLayout) + dbExt
+	if m.opts.Compress {
+		name += gzipExt
+	}
+	path := filepath.Join(m.opts.Dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("backup %s already exists", name)
+	}
+
+	log.Debug("Creating backup", "name", name)
+
+	tmp := filepath.Join(m.opts.Dir, "."+namePrefix+created.Format(nameLayout)+dbExt+".tmp")
+	os.Remove(tmp)
+	defer os.Remove(tmp)
+
+	if err := m.storage.Backup(tmp); err != nil {
+		return nil, err
+	}
+	if err := storage.VerifyDatabase(tmp); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+
+	if m.opts.Compress {
+		if err := compressFile(tmp, path); err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+	} else if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("saving backup: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	backup := &model.Backup{Name: name, Size: info.Size(), CreatedAt: created, Compressed: m.opts.Compress}
+	log.Info("Backup created", "name", name, "size", backup.Size)
+
+	if _, err := m.prune(); err != nil {
+		log.Warn("Failed to apply backup retention", "error", err)
+	}
+	return backup, nil
+}
+
+// List returns the backups in the backup directory, newest first
+func (m *Manager) List() ([]model.Backup, error) {
+	entries, err := os.ReadDir(m.opts.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []model.Backup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading backup directory: %w", err)
+	}
+
+	backups := []model.Backup{}
+	for _, entry := range entries {
+		backup, ok := parseName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backup.Size = info.Size()
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Path returns the file of a backup
+func (m *Manager) Path(name string) (string, error) {
+	if _, ok := parseName(name); !ok || filepath.Base(name) != name {
+		return "", ErrNotFound
+	}
+	path := filepath.Join(m.opts.Dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrNotFound
+	}
+	return path, nil
+}
+
+// Prune deletes the backups not kept by the retention policy and returns their names
+func (m *Manager) Prune() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.prune()
+}
+
+func (m *Manager) prune() ([]string, error) {
+	if m.opts.KeepDaily <= 0 && m.opts.KeepWeekly <= 0 {
+		return nil, nil
+	}
+
+	backups, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	keep := retain(backups, m.opts.KeepDaily, m.opts.KeepWeekly)
+	for _, b := range backups {
+		if keep[b.Name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.opts.Dir, b.Name)); err != nil {
+			return removed, fmt.Errorf("removing backup %s: %w", b.Name, err)
+		}
+		log.Info("Backup removed by retention policy", "name", b.Name)
+		removed = append(removed, b.Name)
+	}
+	return removed, nil
+}
+
+// retain selects the backups to keep: the newest one overall, plus the newest of each
+// of the last keepDaily days and keepWeekly ISO weeks. backups must be newest first.
+func retain(backups []model.Backup, keepDaily, keepWeekly int) map[string]bool {
+	keep := make(map[string]bool)
+	if len(backups) > 0 {
+		keep[backups[0].Name] = true
+	}
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for _, b := range backups {
+		day := b.CreatedAt.Format("2006-01-02")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[b.Name] = true
+		}
+		year, week := b.CreatedAt.ISOWeek()
+		key := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[key] && len(weeks) < keepWeekly {
+			weeks[key] = true
+			keep[b.Name] = true
+		}
+	}
+	return keep
+}
+
+// parseName parses a backup file name of the form rackd-20060102-150405.db[.gz]
+func parseName(name string) (model.Backup, bool) {
+	backup := model.Backup{Name: name}
+	rest, ok := strings.CutPrefix(name, namePrefix)
+	if !ok {
+		return backup, false
+	}
+	if r, ok := strings.CutSuffix(rest, gzipExt); ok {
+		rest = r
+		backup.Compressed = true
+	}
+	rest, ok = strings.CutSuffix(rest, dbExt)
+	if !ok {
+		return backup, false
+	}
+	created, err := time.Parse(nameLayout, rest)
+	if err != nil {
+		return backup, false
+	}
+	backup.CreatedAt = created
+	return backup, true
+}
+
+// Restore verifies a backup file and installs it as the database of dataDir. The
+// current database, if any, is kept next to it with a .pre-restore suffix,
+// replacing the copy kept by any earlier restore. The
+// server must not be running while the database is replaced.
+func Restore(src, dataDir string) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(dataDir, storage.DatabaseFile)
+	tmp := dbPath + ".restore.tmp"
+	os.Remove(tmp)
+	defer os.Remove(tmp)
+
+	if strings.HasSuffix(src, gzipExt) {
+		if err := decompressFile(src, tmp); err != nil {
+			return err
+		}
+	} else if err := copyFile(src, tmp); err != nil {
+		return err
+	}
+	if err := storage.VerifyDatabase(tmp); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+
+	// The WAL and shared memory files belong to the current database. Files left
+	// by an earlier restore are removed first so the set kept aside stays consistent.
+	suffix := ".pre-restore"
+	exts := []string{"", "-wal", "-shm"}
+	if _, err := os.Stat(dbPath); err == nil {
+		for _, ext := range exts {
+			if err := os.Remove(dbPath + ext + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("removing previous pre-restore copy: %w", err)
+			}
+		}
+	}
+	for _, ext := range exts {
+		if err := os.Rename(dbPath+ext, dbPath+ext+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("moving current database aside: %w", err)
+		}
+	}
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return fmt.Errorf("installing backup: %w", err)
+	}
+
+	log.Info("Database restored from backup", "backup", src, "path", dbPath)
+	return nil
+}
+
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("creating backup: %w", err)
+	}
+	defer out.Close()
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		return fmt.Errorf("compressing backup: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compressing backup: %w", err)
+	}
+	return out.Sync()
+}
+
+func decompressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer zr.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, zr); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	return out.Sync()
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Sync()
+}
@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+func TestRetain(t *testing.T) {
+	// One backup every 12 hours for three weeks, newest first
+	start := time.Date(2025, 3, 23, 12, 0, 0, 0, time.UTC) // a Sunday
+	var backups []model.Backup
+	for i := 0; i < 42; i++ {
+		created := start.Add(-time.Duration(i) * 12 * time.Hour)
+		backups = append(backups, model.Backup{Name: created.Format(nameLayout), CreatedAt: created})
+	}
+
+	keep := retain(backups, 3, 2)
+	var names []string
+	for name := range keep {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	want := []string{
+		"20250316-120000", // newest of the previous week
+		"20250321-120000",
+		"20250322-120000",
+		"20250323-120000", // newest overall, of its day and of its week
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("retain() = %v, want %v", names, want)
+	}
+
+	if keep := retain(backups[:1], 0, 0); !keep[backups[0].Name] {
+		t.Error("Expected the newest backup to always be kept")
+	}
+}
+
+func TestParseName(t *testing.T) {
+	b, ok := parseName("rackd-20250323-120000.db.gz")
+	if !ok || !b.Compressed || !b.CreatedAt.Equal(time.Date(2025, 3, 23, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseName() = %+v, %v", b, ok)
+	}
+	for _, name := range []string{"devices.db", "rackd-2025.db", ".rackd-20250323-120000.db.tmp", "rackd-20250323-120000.db.bak"} {
+		if _, ok := parseName(name); ok {
+			t.Errorf("Expected %q not to be a backup", name)
+		}
+	}
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := storage.NewSQLiteStorage(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	if err := store.CreateDevice(&model.Device{ID: "web-1", Name: "web-1"}); err != nil {
+		t.Fatalf("Failed to create device: %v", err)
+	}
+
+	m := NewManager(store, Options{Dir: filepath.Join(dataDir, "backups"), Compress: true, KeepDaily: 1})
+	clock := time.Date(2025, 3, 23, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return clock }
+
+	first, err := m.Create()
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if first.Name != "rackd-20250323-120000.db.gz" || !first.Compressed || first.Size == 0 {
+		t.Errorf("Unexpected backup %+v", first)
+	}
+
+	// A second backup on the same day replaces the first under KeepDaily=1
+	clock = clock.Add(time.Hour)
+	if _, err := m.Create(); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	backups, err := m.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(backups) != 1 || backups[0].Name != "rackd-20250323-130000.db.gz" {
+		t.Fatalf("Expected only the newest backup to be kept, got %+v", backups)
+	}
+
+	if _, err := m.Path("../devices.db"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a path outside the backup directory, got %v", err)
+	}
+	path, err := m.Path(backups[0].Name)
+	if err != nil {
+		t.Fatalf("Path() error = %v", err)
+	}
+
+	restoreDir := t.TempDir()
+	os.WriteFile(filepath.Join(restoreDir, storage.DatabaseFile), []byte("old"), 0600)
+	if err := Restore(path, restoreDir); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(restoreDir, storage.DatabaseFile+".pre-restore")); string(data) != "old" {
+		t.Errorf("Expected the previous database to be kept aside, got %q", data)
+	}
+
+	restored, err := storage.NewSQLiteStorage(restoreDir)
+	if err != nil {
+		t.Fatalf("Failed to open restored database: %v", err)
+	}
+	if _, err := restored.GetDevice("web-1"); err != nil {
+		t.Errorf("Expected device in restored database: %v", err)
+	}
+
+	// A second restore replaces the kept copy, including WAL files of the first
+	secondDir := t.TempDir()
+	dbPath := filepath.Join(secondDir, storage.DatabaseFile)
+	os.WriteFile(dbPath+".pre-restore", []byte("older"), 0600)
+	os.WriteFile(dbPath+"-wal.pre-restore", []byte("stale"), 0600)
+	os.WriteFile(dbPath, []byte("old"), 0600)
+	if err := Restore(path, secondDir); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if data, _ := os.ReadFile(dbPath + ".pre-restore"); string(data) != "old" {
+		t.Errorf("Expected the previous database to replace the older copy, got %q", data)
+	}
+	if _, err := os.Stat(dbPath + "-wal.pre-restore"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the stale WAL copy to be removed, got %v", err)
+	}
+
+	corrupt := filepath.Join(t.TempDir(), "rackd-20250101-000000.db")
+	os.WriteFile(corrupt, []byte("not a database"), 0600)
+	if err := Restore(corrupt, restoreDir); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("Expected ErrInvalidBackup, got %v", err)
+	}
+}
@@ -14,6 +14,10 @@ type Config struct {
 	MCPAuthToken string
 	APIAuthToken string
 
+	// Storage settings
+	StorageType string
+	PostgresDSN string
+
 	// Discovery settings
 	DiscoveryEnabled          bool
 	DiscoveryInterval         time.Duration
@@ -21,6 +25,18 @@ type Config struct {
 	DiscoveryTimeout          time.Duration
 	DiscoveryDefaultScanType  string
 	DiscoveryCleanupDays      int
+
+	// Backup settings
+	BackupEnabled    bool
+	BackupInterval   time.Duration
+	BackupDir        string
+	BackupCompress   bool
+	BackupKeepDaily  int
+	BackupKeepWeekly int
+
+	// Job settings
+	JobWorkers      int
+	JobRetryBackoff time.Duration
 }
 
 var (
@@ -29,6 +45,10 @@ var (
 	mcpAuthToken string
 	apiAuthToken string
 
+	// Storage flag variables
+	storageType string
+	postgresDSN string
+
 	// Discovery flag variables
 	discoveryEnabled          bool
 	discoveryInterval         string
@@ -36,6 +56,18 @@ var (
 	discoveryTimeout          string
 	discoveryDefaultScanType  string
 	discoveryCleanupDays      string
+
+	// Backup flag variables
+	backupEnabled    bool
+	backupInterval   string
+	backupDir        string
+	backupCompress   bool
+	backupKeepDaily  string
+	backupKeepWeekly string
+
+	// Job flag variables
+	jobWorkers      string
+	jobRetryBackoff string
 )
 
 func GetFlags() []cli.Flag {
@@ -66,6 +98,20 @@ func GetFlags() []cli.Flag {
 			EnvVars:  []string{"RACKD_API_TOKEN"},
 			AssignTo: &apiAuthToken,
 		},
+		// Storage flags
+		&cli.StringFlag{
+			Name:         "storage",
+			Usage:        "Storage backend: sqlite or postgres",
+			EnvVars:      []string{"RACKD_STORAGE"},
+			DefaultValue: "sqlite",
+			AssignTo:     &storageType,
+		},
+		&cli.StringFlag{
+			Name:     "postgres-dsn",
+			Usage:    "PostgreSQL connection string, used with --storage postgres",
+			EnvVars:  []string{"RACKD_POSTGRES_DSN"},
+			AssignTo: &postgresDSN,
+		},
 		// Discovery flags
 		&cli.BoolFlag{
 			Name:         "discovery-enabled",
@@ -109,6 +155,63 @@ func GetFlags() []cli.Flag {
 			DefaultValue: "30",
 			AssignTo:     &discoveryCleanupDays,
 		},
+		// Backup flags
+		&cli.BoolFlag{
+			Name:         "backup-enabled",
+			Usage:        "Enable scheduled database backups",
+			EnvVars:      []string{"RACKD_BACKUP_ENABLED"},
+			DefaultValue: false,
+			AssignTo:     &backupEnabled,
+		},
+		&cli.StringFlag{
+			Name:         "backup-interval",
+			Usage:        "Backup interval (e.g., 24h, 6h)",
+			EnvVars:      []string{"RACKD_BACKUP_INTERVAL"},
+			DefaultValue: "24h",
+			AssignTo:     &backupInterval,
+		},
+		&cli.StringFlag{
+			Name:     "backup-dir",
+			Usage:    "Backup directory, defaults to backups in the data directory",
+			EnvVars:  []string{"RACKD_BACKUP_DIR"},
+			AssignTo: &backupDir,
+		},
+		&cli.BoolFlag{
+			Name:         "backup-compress",
+			Usage:        "Compress backups with gzip",
+			EnvVars:      []string{"RACKD_BACKUP_COMPRESS"},
+			DefaultValue: true,
+			AssignTo:     &backupCompress,
+		},
+		&cli.StringFlag{
+			Name:         "backup-keep-daily",
+			Usage:        "Number of daily backups to keep",
+			EnvVars:      []string{"RACKD_BACKUP_KEEP_DAILY"},
+			DefaultValue: "7",
+			AssignTo:     &backupKeepDaily,
+		},
+		&cli.StringFlag{
+			Name:         "backup-keep-weekly",
+			Usage:        "Number of weekly backups to keep",
+			EnvVars:      []string{"RACKD_BACKUP_KEEP_WEEKLY"},
+			DefaultValue: "4",
+			AssignTo:     &backupKeepWeekly,
+		},
+		// Job flags
+		&cli.StringFlag{
+			Name:         "job-workers",
+			Usage:        "Maximum background jobs running at once",
+			EnvVars:      []string{"RACKD_JOB_WORKERS"},
+			DefaultValue: "2",
+			AssignTo:     &jobWorkers,
+		},
+		&cli.StringFlag{
+			Name:         "job-retry-backoff",
+			Usage:        "Delay before retrying a failed job, doubling with every attempt (e.g., 30s, 1m)",
+			EnvVars:      []string{"RACKD_JOB_RETRY_BACKOFF"},
+			DefaultValue: "30s",
+			AssignTo:     &jobRetryBackoff,
+		},
 	}
 }
 
@@ -146,12 +249,47 @@ func Load() *Config {
 		scanType = "full"
 	}
 
+	// Parse backup interval
+	backupIntervalDur, _ := time.ParseDuration(backupInterval)
+	if backupIntervalDur <= 0 {
+		backupIntervalDur = 24 * time.Hour
+	}
+
+	// Parse backup retention; 0 disables that tier, both 0 keeps every backup
+	backupKeepDailyInt, err := strconv.Atoi(backupKeepDaily)
+	if err != nil || backupKeepDailyInt < 0 {
+		backupKeepDailyInt = 7
+	}
+	backupKeepWeeklyInt, err := strconv.Atoi(backupKeepWeekly)
+	if err != nil || backupKeepWeeklyInt < 0 {
+		backupKeepWeeklyInt = 4
+	}
+
+	// Parse job settings
+	jobWorkersInt, err := strconv.Atoi(jobWorkers)
+	if err != nil || jobWorkersInt <= 0 {
+		jobWorkersInt = 2
+	}
+	jobRetryBackoffDur, _ := time.ParseDuration(jobRetryBackoff)
+	if jobRetryBackoffDur <= 0 {
+		jobRetryBackoffDur = 30 * time.Second
+	}
+
+	backupDirPath := backupDir
+	if backupDirPath == "" {
+		backupDirPath = filepath.Join(dataDir, "backups")
+	}
+
 	return &Config{
 		DataDir:      dataDir,
 		ListenAddr:   listenAddr,
 		MCPAuthToken: mcpAuthToken,
 		APIAuthToken: apiAuthToken,
 
+		// Storage settings
+		StorageType: storageType,
+		PostgresDSN: postgresDSN,
+
 		// Discovery settings
 		DiscoveryEnabled:          discoveryEnabled,
 		DiscoveryInterval:         discoveryIntervalDur,
@@ -159,6 +297,18 @@ func Load() *Config {
 		DiscoveryTimeout:          discoveryTimeoutDur,
 		DiscoveryDefaultScanType:  scanType,
 		DiscoveryCleanupDays:      discoveryCleanupDaysInt,
+
+		// Backup settings
+		BackupEnabled:    backupEnabled,
+		BackupInterval:   backupIntervalDur,
+		BackupDir:        backupDirPath,
+		BackupCompress:   backupCompress,
+		BackupKeepDaily:  backupKeepDailyInt,
+		BackupKeepWeekly: backupKeepWeeklyInt,
+
+		// Job settings
+		JobWorkers:      jobWorkersInt,
+		JobRetryBackoff: jobRetryBackoffDur,
 	}
 }
 
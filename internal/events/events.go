@@ -0,0 +1,76 @@
+// Package events is an in-process publish/subscribe bus for progress updates
+// of background work, such as discovery scans.
+package events
+
+import "sync"
+
+// subscriberBuffer is the number of events a subscriber can fall behind by
+const subscriberBuffer = 64
+
+// Event is a message published on a topic
+type Event struct {
+	Type string      // e.g. progress, found, complete
+	Data interface{} // JSON-encodable payload
+}
+
+// Bus delivers events to the subscribers of a topic
+type Bus struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Event]struct{}
+}
+
+// NewBus creates an event bus
+func NewBus() *Bus {
+	return &Bus{subs: make(map[string]map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving the events published on topic and a
+// function ending the subscription. The channel is closed when the topic is
+// closed or the subscription ends.
+func (b *Bus) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[chan Event]struct{})
+	}
+	b.subs[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[topic][ch]; ok {
+			delete(b.subs[topic], ch)
+			if len(b.subs[topic]) == 0 {
+				delete(b.subs, topic)
+			}
+			close(ch)
+		}
+	}
+}
+
+// Publish sends e to the subscribers of topic. A subscriber that has fallen
+// too far behind misses the event rather than blocking the publisher.
+func (b *Bus) Publish(topic string, e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Close ends all subscriptions of topic, closing their channels
+func (b *Bus) Close(topic string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs[topic] {
+		close(ch)
+	}
+	delete(b.subs, topic)
+}
@@ -0,0 +1,44 @@
+package events
+
+import "testing"
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	a, unsubscribeA := bus.Subscribe("scan-1")
+	b, _ := bus.Subscribe("scan-1")
+	other, unsubscribeOther := bus.Subscribe("scan-2")
+	defer unsubscribeOther()
+
+	bus.Publish("scan-1", Event{Type: "progress", Data: 1})
+	for name, ch := range map[string]<-chan Event{"a": a, "b": b} {
+		if e := <-ch; e.Type != "progress" || e.Data != 1 {
+			t.Errorf("subscriber %s: unexpected event %+v", name, e)
+		}
+	}
+	if len(other) != 0 {
+		t.Errorf("expected no event on another topic, got %d", len(other))
+	}
+
+	unsubscribeA()
+	if _, ok := <-a; ok {
+		t.Error("expected channel to be closed after unsubscribe")
+	}
+	unsubscribeA()
+
+	// A subscriber that does not keep up misses events instead of blocking
+	for i := 0; i < subscriberBuffer+10; i++ {
+		bus.Publish("scan-1", Event{Type: "progress", Data: i})
+	}
+	if len(b) != subscriberBuffer {
+		t.Errorf("expected %d buffered events, got %d", subscriberBuffer, len(b))
+	}
+
+	bus.Close("scan-1")
+	n := 0
+	for range b {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("expected buffered events before close, got %d", n)
+	}
+}
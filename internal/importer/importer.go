@@ -0,0 +1,419 @@
+// Package importer parses bulk import files into import batches.
+//
+// JSON and YAML files are either a document with one list per entity type:
+//
+//	datacenters: [...]
+//	networks: [...]
+//	pools: [...]
+//	devices: [...]
+//	relationships: [...]
+//
+// or a plain list of rows of a single entity type given in Options.Entity.
+// Rows use the JSON field names of the entity and may refer to other entities
+// by name ("datacenter", "network", "pool", "parent", "child").
+//
+// CSV files always hold rows of a single entity type, with a header row naming
+// the fields. Empty cells are skipped, list fields (tags, domains) are split on
+// ',' or ';', dotted columns such as labels.env, custom_fields.rack or
+// asset.serial_number fill nested fields, and a labels column may hold
+// key=value pairs. Device rows can describe a single address with the ip,
+// ip_type, ip_label, port, network, pool, switch_port and mac_address columns.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// ErrInvalidInput is returned when import data cannot be parsed
+var ErrInvalidInput = errors.New("invalid import data")
+
+// Format is the encoding of an import file
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Options control how import data is parsed
+type Options struct {
+	Format Format
+	// Entity is the entity type of the rows. It is required for CSV and for
+	// JSON or YAML lists, and may be singular or plural ("device", "devices").
+	Entity string
+	// Mapping renames source columns or keys to field names. A mapping to an
+	// empty name drops the column.
+	Mapping map[string]string
+}
+
+// ParseFormat parses a format name
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "csv":
+		return FormatCSV, nil
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("%w: unknown format %q (expected csv, json or yaml)", ErrInvalidInput, s)
+}
+
+// FormatFromFilename guesses the format from a file extension, or returns "" if unknown
+func FormatFromFilename(name string) Format {
+	format, _ := ParseFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+	return format
+}
+
+// FormatFromContentType guesses the format from a MIME type, or returns "" if unknown
+func FormatFromContentType(contentType string) Format {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV
+	case "application/json":
+		return FormatJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML
+	}
+	return ""
+}
+
+// ParseEntity normalizes an entity type name
+func ParseEntity(s string) (string, error) {
+	entity := strings.ToLower(strings.TrimSpace(s))
+	switch entity {
+	case "datacenters", "networks", "pools", "devices", "relationships":
+		entity = strings.TrimSuffix(entity, "s")
+	}
+	switch entity {
+	case model.ImportEntityDatacenter, model.ImportEntityNetwork, model.ImportEntityPool,
+		model.ImportEntityDevice, model.ImportEntityRelationship:
+		return entity, nil
+	}
+	return "", fmt.Errorf("%w: unknown entity %q", ErrInvalidInput, s)
+}
+
+// ParseMapping parses column mappings of the form "source=field". Each spec may
+// hold several comma-separated mappings.
+func ParseMapping(specs []string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, spec := range specs {
+		for _, part := range strings.Split(spec, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			source, field, ok := strings.Cut(part, "=")
+			source = strings.TrimSpace(source)
+			if !ok || source == "" {
+				return nil, fmt.Errorf("%w: invalid mapping %q (expected source=field)", ErrInvalidInput, part)
+			}
+			mapping[source] = strings.TrimSpace(field)
+		}
+	}
+	return mapping, nil
+}
+
+// Parse reads import data into a batch
+func Parse(r io.Reader, opts Options) (*model.ImportBatch, error) {
+	entity := ""
+	if opts.Entity != "" {
+		var err error
+		if entity, err = ParseEntity(opts.Entity); err != nil {
+			return nil, err
+		}
+	}
+
+	switch opts.Format {
+	case FormatCSV:
+		if entity == "" {
+			return nil, fmt.Errorf("%w: entity is required for CSV imports", ErrInvalidInput)
+		}
+		records, err := parseCSV(r, entity, opts.Mapping)
+		if err != nil {
+			return nil, err
+		}
+		batch := &model.ImportBatch{}
+		addRecords(batch, entity, records)
+		return batch, nil
+	case FormatJSON:
+		var doc interface{}
+		if err := json.NewDecoder(r).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		return parseDocument(doc, entity, opts.Mapping)
+	case FormatYAML:
+		var doc interface{}
+		if err := yaml.NewDecoder(r).Decode(&doc); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		return parseDocument(normalizeYAML(doc), entity, opts.Mapping)
+	}
+	return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidInput, opts.Format)
+}
+
+// parseDocument converts a decoded JSON or YAML document into a batch
+func parseDocument(doc interface{}, entity string, mapping map[string]string) (*model.ImportBatch, error) {
+	batch := &model.ImportBatch{}
+
+	switch t := doc.(type) {
+	case []interface{}:
+		if entity == "" {
+			return nil, fmt.Errorf("%w: entity is required for a list of rows", ErrInvalidInput)
+		}
+		records, err := toRecords(entity, t, mapping)
+		if err != nil {
+			return nil, err
+		}
+		addRecords(batch, entity, records)
+	case map[string]interface{}:
+		for key, value := range t {
+			sectionEntity, err := ParseEntity(key)
+			if err != nil || !strings.HasSuffix(key, "s") {
+				return nil, fmt.Errorf("%w: unknown section %q", ErrInvalidInput, key)
+			}
+			if entity != "" && sectionEntity != entity {
+				continue
+			}
+			rows, ok := value.([]interface{})
+			if !ok && value != nil {
+				return nil, fmt.Errorf("%w: section %q must be a list", ErrInvalidInput, key)
+			}
+			records, err := toRecords(sectionEntity, rows, mapping)
+			if err != nil {
+				return nil, err
+			}
+			addRecords(batch, sectionEntity, records)
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("%w: expected a list of rows or a document with entity sections", ErrInvalidInput)
+	}
+
+	return batch, nil
+}
+
+// toRecords converts decoded rows into records, applying the column mapping
+func toRecords(entity string, rows []interface{}, mapping map[string]string) ([]model.ImportRecord, error) {
+	records := make([]model.ImportRecord, 0, len(rows))
+	for i, row := range rows {
+		fields, ok := row.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: %s row %d is not an object", ErrInvalidInput, entity, i+1)
+		}
+		rec := make(model.ImportRecord, len(fields))
+		for key, value := range fields {
+			if field, mapped := mapping[key]; mapped {
+				if field == "" {
+					continue
+				}
+				key = field
+			}
+			rec[key] = value
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func addRecords(batch *model.ImportBatch, entity string, records []model.ImportRecord) {
+	switch entity {
+	case model.ImportEntityDatacenter:
+		batch.Datacenters = append(batch.Datacenters, records...)
+	case model.ImportEntityNetwork:
+		batch.Networks = append(batch.Networks, records...)
+	case model.ImportEntityPool:
+		batch.Pools = append(batch.Pools, records...)
+	case model.ImportEntityDevice:
+		batch.Devices = append(batch.Devices, records...)
+	case model.ImportEntityRelationship:
+		batch.Relationships = append(batch.Relationships, records...)
+	}
+}
+
+// normalizeYAML converts YAML maps with non-string keys and other YAML-specific
+// values into their JSON equivalents
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			t[k] = normalizeYAML(val)
+		}
+		return t
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(val)
+		}
+		return m
+	case []interface{}:
+		for i := range t {
+			t[i] = normalizeYAML(t[i])
+		}
+		return t
+	}
+
+	// Round-trip scalars such as timestamps through JSON
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fmt.Sprint(v)
+	}
+	return out
+}
+
+// addressColumns maps the CSV address shorthand columns of device rows to address fields
+var addressColumns = map[string]string{
+	"ip":          "ip",
+	"ip_type":     "type",
+	"ip_label":    "label",
+	"port":        "port",
+	"network":     "network",
+	"pool":        "pool",
+	"switch_port": "switch_port",
+	"mac_address": "mac_address",
+}
+
+// listColumns are the CSV columns holding lists, per entity type
+var listColumns = map[string]map[string]bool{
+	model.ImportEntityDevice: {"tags": true, "domains": true},
+	model.ImportEntityPool:   {"tags": true},
+}
+
+// parseCSV reads CSV rows of a single entity type
+func parseCSV(r io.Reader, entity string, mapping map[string]string) ([]model.ImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []model.ImportRecord{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if field, mapped := mapping[name]; mapped {
+			name = field
+		}
+		// Field names are case-insensitive, nested keys such as label keys are not
+		if prefix, rest, nested := strings.Cut(name, "."); nested {
+			name = strings.ToLower(prefix) + "." + rest
+		} else {
+			name = strings.ToLower(name)
+		}
+		columns[i] = name
+	}
+
+	records := []model.ImportRecord{}
+	for {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+
+		rec := make(model.ImportRecord)
+		var address map[string]interface{}
+		for i, cell := range cells {
+			column := columns[i]
+			value := strings.TrimSpace(cell)
+			if column == "" || value == "" {
+				continue
+			}
+
+			if field, ok := addressColumns[column]; ok && entity == model.ImportEntityDevice {
+				if address == nil {
+					address = make(map[string]interface{})
+				}
+				address[field] = csvValue(field, value)
+				continue
+			}
+
+			if prefix, key, nested := strings.Cut(column, "."); nested {
+				section, _ := rec[prefix].(map[string]interface{})
+				if section == nil {
+					section = make(map[string]interface{})
+					rec[prefix] = section
+				}
+				section[key] = csvValue(column, value)
+				continue
+			}
+
+			switch {
+			case listColumns[entity][column]:
+				rec[column] = splitList(value)
+			case column == "labels":
+				labels, _ := rec["labels"].(map[string]interface{})
+				if labels == nil {
+					labels = make(map[string]interface{})
+					rec["labels"] = labels
+				}
+				for _, pair := range splitList(value) {
+					key, val, _ := strings.Cut(pair.(string), "=")
+					labels[strings.TrimSpace(key)] = strings.TrimSpace(val)
+				}
+			default:
+				rec[column] = value
+			}
+		}
+		if address != nil {
+			rec["addresses"] = []interface{}{address}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// csvValue converts numeric CSV fields. Values that do not parse are kept as
+// strings so the row is rejected with a field error on import.
+func csvValue(field, value string) interface{} {
+	switch field {
+	case "port":
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	case "asset.cost":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// splitList splits a list cell on commas or semicolons
+func splitList(value string) []interface{} {
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' })
+	list := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+func TestParseCSV(t *testing.T) {
+	data := "Host,Serial,ip,port,tags,labels,labels.env,asset.cost,notes\n" +
+		"web-1,SN1,10.0.0.5,22,a; b,role=web,prod,99.5,ignored\n" +
+		"web-2,,,,,,,,\n"
+
+	batch, err := Parse(strings.NewReader(data), Options{
+		Format:  FormatCSV,
+		Entity:  "devices",
+		Mapping: map[string]string{"Host": "name", "Serial": "asset.serial_number", "notes": ""},
+	})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(batch.Devices) != 2 {
+		t.Fatalf("Expected 2 device rows, got %d", len(batch.Devices))
+	}
+
+	want := model.ImportRecord{
+		"name":      "web-1",
+		"asset":     map[string]interface{}{"serial_number": "SN1", "cost": 99.5},
+		"addresses": []interface{}{map[string]interface{}{"ip": "10.0.0.5", "port": 22}},
+		"tags":      []interface{}{"a", "b"},
+		"labels":    map[string]interface{}{"role": "web", "env": "prod"},
+	}
+	if !reflect.DeepEqual(batch.Devices[0], want) {
+		t.Errorf("Row 1 = %v, want %v", batch.Devices[0], want)
+	}
+	if want := (model.ImportRecord{"name": "web-2"}); !reflect.DeepEqual(batch.Devices[1], want) {
+		t.Errorf("Row 2 = %v, want %v", batch.Devices[1], want)
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	yaml := `
+datacenters:
+  - name: DC1
+networks:
+  - name: Prod
+    subnet: 10.0.0.0/24
+    datacenter: DC1
+`
+	batch, err := Parse(strings.NewReader(yaml), Options{Format: FormatYAML})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(batch.Datacenters) != 1 || len(batch.Networks) != 1 || batch.Networks[0]["datacenter"] != "DC1" {
+		t.Errorf("Unexpected batch %+v", batch)
+	}
+
+	// An entity restricts a document to one section
+	batch, err = Parse(strings.NewReader(yaml), Options{Format: FormatYAML, Entity: "network"})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(batch.Datacenters) != 0 || len(batch.Networks) != 1 {
+		t.Errorf("Expected only networks, got %+v", batch)
+	}
+
+	batch, err = Parse(strings.NewReader(`[{"id": "p1", "start": "10.0.0.1"}]`), Options{
+		Format:  FormatJSON,
+		Entity:  "pool",
+		Mapping: map[string]string{"start": "start_ip"},
+	})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := (model.ImportRecord{"id": "p1", "start_ip": "10.0.0.1"}); !reflect.DeepEqual(batch.Pools[0], want) {
+		t.Errorf("Pool row = %v, want %v", batch.Pools[0], want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts Options
+	}{
+		{"csv without entity", "name\nx\n", Options{Format: FormatCSV}},
+		{"list without entity", `[{"name": "x"}]`, Options{Format: FormatJSON}},
+		{"unknown section", `{"racks": []}`, Options{Format: FormatJSON}},
+		{"unknown entity", `[]`, Options{Format: FormatJSON, Entity: "rack"}},
+		{"row not an object", `["x"]`, Options{Format: FormatJSON, Entity: "device"}},
+		{"invalid json", `{`, Options{Format: FormatJSON}},
+		{"unknown format", ``, Options{Format: "xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.data), tt.opts); !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("Parse() error = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := ParseMapping([]string{"Host=name,Notes=", "SN = asset.serial_number"})
+	if err != nil {
+		t.Fatalf("ParseMapping() error = %v", err)
+	}
+	want := map[string]string{"Host": "name", "Notes": "", "SN": "asset.serial_number"}
+	if !reflect.DeepEqual(mapping, want) {
+		t.Errorf("ParseMapping() = %v, want %v", mapping, want)
+	}
+
+	if _, err := ParseMapping([]string{"name"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected error for mapping without '=', got %v", err)
+	}
+}
+
+func TestFormatDetection(t *testing.T) {
+	if f := FormatFromFilename("devices.YML"); f != FormatYAML {
+		t.Errorf("FormatFromFilename() = %q, want yaml", f)
+	}
+	if f := FormatFromContentType("text/csv; charset=utf-8"); f != FormatCSV {
+		t.Errorf("FormatFromContentType() = %q, want csv", f)
+	}
+	if f := FormatFromFilename("devices.txt"); f != "" {
+		t.Errorf("FormatFromFilename() = %q, want empty", f)
+	}
+}
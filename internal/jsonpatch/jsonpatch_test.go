@@ -0,0 +1,113 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid result %s: %v", got, err)
+	}
+	json.Unmarshal([]byte(want), &w)
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		// Examples from RFC 7386, appendix A
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("Merge(%s, %s) failed: %v", tt.doc, tt.patch, err)
+			continue
+		}
+		assertJSON(t, got, tt.want)
+	}
+
+	if _, err := Merge([]byte(`{}`), []byte(`{`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("expected ErrInvalidPatch, got %v", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	doc := `{"name":"web-1","tags":["a","b"],"labels":{"env":"prod","a/b":"x"},"port":22}`
+
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+		err   error
+	}{
+		{"AddMember", `[{"op":"add","path":"/os","value":"linux"}]`, `{"name":"web-1","os":"linux","tags":["a","b"],"labels":{"env":"prod","a/b":"x"},"port":22}`, nil},
+		{"AppendToArray", `[{"op":"add","path":"/tags/-","value":"c"}]`, `{"name":"web-1","tags":["a","b","c"],"labels":{"env":"prod","a/b":"x"},"port":22}`, nil},
+		{"InsertIntoArray", `[{"op":"add","path":"/tags/0","value":"z"}]`, `{"name":"web-1","tags":["z","a","b"],"labels":{"env":"prod","a/b":"x"},"port":22}`, nil},
+		{"RemoveArrayItem", `[{"op":"remove","path":"/tags/0"}]`, `{"name":"web-1","tags":["b"],"labels":{"env":"prod","a/b":"x"},"port":22}`, nil},
+		{"RemoveEscaped", `[{"op":"remove","path":"/labels/a~1b"}]`, `{"name":"web-1","tags":["a","b"],"labels":{"env":"prod"},"port":22}`, nil},
+		{"Replace", `[{"op":"replace","path":"/labels/env","value":"dev"}]`, `{"name":"web-1","tags":["a","b"],"labels":{"env":"dev","a/b":"x"},"port":22}`, nil},
+		{"Move", `[{"op":"move","from":"/labels/env","path":"/env"}]`, `{"name":"web-1","env":"prod","tags":["a","b"],"labels":{"a/b":"x"},"port":22}`, nil},
+		{"Copy", `[{"op":"copy","from":"/tags/1","path":"/tags/0"}]`, `{"name":"web-1","tags":["b","a","b"],"labels":{"env":"prod","a/b":"x"},"port":22}`, nil},
+		{"TestThenReplace", `[{"op":"test","path":"/port","value":22.0},{"op":"replace","path":"/port","value":2222}]`, `{"name":"web-1","tags":["a","b"],"labels":{"env":"prod","a/b":"x"},"port":2222}`, nil},
+		{"ReplaceRoot", `[{"op":"replace","path":"","value":{"a":1}}]`, `{"a":1}`, nil},
+		{"TestFails", `[{"op":"test","path":"/name","value":"web-2"}]`, "", ErrTestFailed},
+		{"RemoveMissing", `[{"op":"remove","path":"/os"}]`, "", ErrPathNotFound},
+		{"ReplaceMissing", `[{"op":"replace","path":"/os","value":"x"}]`, "", ErrPathNotFound},
+		{"AddBeyondArray", `[{"op":"add","path":"/tags/5","value":"x"}]`, "", ErrPathNotFound},
+		{"AddToMissingParent", `[{"op":"add","path":"/asset/serial","value":"x"}]`, "", ErrPathNotFound},
+		{"LeadingZeroIndex", `[{"op":"remove","path":"/tags/01"}]`, "", ErrInvalidPatch},
+		{"MoveIntoItself", `[{"op":"move","from":"/labels","path":"/labels/env"}]`, "", ErrInvalidPatch},
+		{"UnknownOp", `[{"op":"merge","path":"/name","value":"x"}]`, "", ErrInvalidPatch},
+		{"MissingValue", `[{"op":"add","path":"/name"}]`, "", ErrInvalidPatch},
+		{"BadPointer", `[{"op":"remove","path":"name"}]`, "", ErrInvalidPatch},
+		{"NotAList", `{"op":"remove","path":"/name"}`, "", ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(doc), []byte(tt.patch))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v (%s)", tt.err, err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	doc := []byte(`{"tags":["a"]}`)
+	if _, err := Apply(doc, []byte(`[{"op":"add","path":"/tags/-","value":"b"},{"op":"remove","path":"/missing"}]`)); err == nil {
+		t.Fatal("expected error")
+	}
+	if string(doc) != `{"tags":["a"]}` {
+		t.Errorf("document was modified: %s", doc)
+	}
+}
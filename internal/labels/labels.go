@@ -0,0 +1,283 @@
+// Package labels implements key/value labels and Kubernetes-style label selectors.
+//
+// A selector is a comma-separated list of requirements that must all match:
+//
+//	env=prod          key equals value (also env==prod)
+//	env!=prod         key does not equal value (or is not set)
+//	role in (db,web)  key equals one of the values
+//	role notin (db)   key equals none of the values (or is not set)
+//	decom             key is set
+//	!decom            key is not set
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrInvalidSelector is returned when a label selector cannot be parsed
+	ErrInvalidSelector = errors.New("invalid label selector")
+	// ErrInvalidLabel is returned when a label key or value is not valid
+	ErrInvalidLabel = errors.New("invalid label")
+)
+
+// Operator is the comparison used by a selector requirement
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is a single term of a selector
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Selector is a set of requirements that must all match (AND logic)
+type Selector []Requirement
+
+const maxLength = 63
+
+var (
+	keyPattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.\-/]*[A-Za-z0-9])?$`)
+	valuePattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9_.\-]*[A-Za-z0-9])?)?$`)
+	setPattern   = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// ValidateKey checks that a label key is well-formed
+func ValidateKey(key string) error {
+	if len(key) > maxLength || !keyPattern.MatchString(key) {
+		return fmt.Errorf("%w: key %q must be at most %d characters of letters, digits, '-', '_', '.' or '/', starting and ending with a letter or digit", ErrInvalidLabel, key, maxLength)
+	}
+	return nil
+}
+
+// ValidateValue checks that a label value is well-formed. Empty values are allowed.
+func ValidateValue(value string) error {
+	if len(value) > maxLength || !valuePattern.MatchString(value) {
+		return fmt.Errorf("%w: value %q must be at most %d characters of letters, digits, '-', '_' or '.', starting and ending with a letter or digit", ErrInvalidLabel, value, maxLength)
+	}
+	return nil
+}
+
+// Validate checks all keys and values of a label set
+func Validate(labels map[string]string) error {
+	for _, key := range sortedKeys(labels) {
+		if err := ValidateKey(key); err != nil {
+			return err
+		}
+		if err := ValidateValue(labels[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse parses a selector string. An empty string yields an empty selector that matches everything.
+func Parse(s string) (Selector, error) {
+	terms, err := splitTerms(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var sel Selector
+	for _, term := range terms {
+		req, err := parseRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// splitTerms splits a selector on commas that are not inside parentheses
+func splitTerms(s string) ([]string, error) {
+	var terms []string
+	depth := 0
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("%w: nested parentheses", ErrInvalidSelector)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("%w: unbalanced parentheses", ErrInvalidSelector)
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: unbalanced parentheses", ErrInvalidSelector)
+	}
+	terms = append(terms, s[start:])
+
+	if len(terms) == 1 && strings.TrimSpace(terms[0]) == "" {
+		return nil, nil
+	}
+	for i := range terms {
+		terms[i] = strings.TrimSpace(terms[i])
+		if terms[i] == "" {
+			return nil, fmt.Errorf("%w: empty requirement", ErrInvalidSelector)
+		}
+	}
+	return terms, nil
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	var req Requirement
+
+	switch {
+	case setPattern.MatchString(term):
+		m := setPattern.FindStringSubmatch(term)
+		req.Key = m[1]
+		req.Operator = Operator(m[2])
+		for _, v := range strings.Split(m[3], ",") {
+			req.Values = append(req.Values, strings.TrimSpace(v))
+		}
+	case strings.HasPrefix(term, "!") && !strings.Contains(term, "="):
+		req.Key = strings.TrimSpace(term[1:])
+		req.Operator = DoesNotExist
+	case strings.Contains(term, "!="):
+		key, value, _ := strings.Cut(term, "!=")
+		req.Key = strings.TrimSpace(key)
+		req.Operator = NotEquals
+		req.Values = []string{strings.TrimSpace(value)}
+	case strings.Contains(term, "="):
+		key, value, _ := strings.Cut(term, "=")
+		req.Key = strings.TrimSpace(key)
+		req.Operator = Equals
+		req.Values = []string{strings.TrimSpace(strings.TrimPrefix(value, "="))}
+	default:
+		req.Key = term
+		req.Operator = Exists
+	}
+
+	if err := ValidateKey(req.Key); err != nil {
+		return req, fmt.Errorf("%w: %q: %v", ErrInvalidSelector, term, err)
+	}
+	for _, v := range req.Values {
+		if err := ValidateValue(v); err != nil {
+			return req, fmt.Errorf("%w: %q: %v", ErrInvalidSelector, term, err)
+		}
+	}
+	return req, nil
+}
+
+// Matches reports whether the requirement is satisfied by the labels
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	case Equals, In:
+		return ok && contains(r.Values, value)
+	case NotEquals, NotIn:
+		return !ok || !contains(r.Values, value)
+	}
+	return false
+}
+
+// String returns the requirement in selector syntax
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	}
+	return r.Key + string(r.Operator) + strings.Join(r.Values, ",")
+}
+
+// Matches reports whether all requirements are satisfied by the labels
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the selector in its canonical string form
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseTag splits a key=value tag into a label. ok is false if the tag is not
+// in key=value form or is not a valid label.
+func ParseTag(tag string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(tag, "=")
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if !ok || ValidateKey(key) != nil || ValidateValue(value) != nil {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+// FromTags splits key=value tags into labels. Tags that are not valid labels or
+// repeat a key that was already taken are returned unchanged in remaining.
+func FromTags(tags []string) (labels map[string]string, remaining []string) {
+	for _, tag := range tags {
+		key, value, ok := ParseTag(tag)
+		if !ok {
+			remaining = append(remaining, tag)
+			continue
+		}
+		if _, taken := labels[key]; taken {
+			remaining = append(remaining, tag)
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[key] = value
+	}
+	return labels, remaining
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package labels
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Selector
+	}{
+		{"", nil},
+		{"env=prod", Selector{{Key: "env", Operator: Equals, Values: []string{"prod"}}}},
+		{"env==prod", Selector{{Key: "env", Operator: Equals, Values: []string{"prod"}}}},
+		{"env != prod", Selector{{Key: "env", Operator: NotEquals, Values: []string{"prod"}}}},
+		{"role in (db, cache)", Selector{{Key: "role", Operator: In, Values: []string{"db", "cache"}}}},
+		{"role notin (db)", Selector{{Key: "role", Operator: NotIn, Values: []string{"db"}}}},
+		{"decom", Selector{{Key: "decom", Operator: Exists}}},
+		{"!decom", Selector{{Key: "decom", Operator: DoesNotExist}}},
+		{"env=prod,role in (db,cache),!decom", Selector{
+			{Key: "env", Operator: Equals, Values: []string{"prod"}},
+			{Key: "role", Operator: In, Values: []string{"db", "cache"}},
+			{Key: "decom", Operator: DoesNotExist},
+		}},
+		{"example.com/team=core", Selector{{Key: "example.com/team", Operator: Equals, Values: []string{"core"}}}},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{
+		"env=prod,",
+		"role in (db",
+		"role in ((db))",
+		"!env=prod",
+		"env=bad value",
+		"-env",
+	} {
+		if _, err := Parse(in); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidSelector", in, err)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"env": "prod", "role": "db"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"team!=core", true},
+		{"role in (db,cache)", true},
+		{"role notin (db,cache)", false},
+		{"env", true},
+		{"decom", false},
+		{"!decom", true},
+		{"env=prod,role in (db,cache),!decom", true},
+		{"env=prod,decom", false},
+	}
+
+	for _, tt := range tests {
+		sel, err := Parse(tt.selector)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tt.selector, err)
+		}
+		if got := sel.Matches(labels); got != tt.want {
+			t.Errorf("%q.Matches(%v) = %v, want %v", tt.selector, labels, got, tt.want)
+		}
+	}
+}
+
+func TestSelectorString(t *testing.T) {
+	sel, err := Parse("env == prod, role in (db, cache), !decom, owner")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if got, want := sel.String(), "env=prod,role in (db,cache),!decom,owner"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(map[string]string{"env": "prod", "example.com/team": "", "tier": "gold-1"}); err != nil {
+		t.Errorf("Validate returned error for valid labels: %v", err)
+	}
+	for _, labels := range []map[string]string{
+		{"": "prod"},
+		{"env": "has space"},
+		{"-env": "prod"},
+		{"env": "prod-"},
+	} {
+		if err := Validate(labels); !errors.Is(err, ErrInvalidLabel) {
+			t.Errorf("Validate(%v) error = %v, want ErrInvalidLabel", labels, err)
+		}
+	}
+}
+
+func TestFromTags(t *testing.T) {
+	labels, remaining := FromTags([]string{"env=prod", "web", "role=db", "env=dev", "bad key=x", "decom="})
+
+	wantLabels := map[string]string{"env": "prod", "role": "db", "decom": ""}
+	if !reflect.DeepEqual(labels, wantLabels) {
+		t.Errorf("labels = %v, want %v", labels, wantLabels)
+	}
+	wantRemaining := []string{"web", "env=dev", "bad key=x"}
+	if !reflect.DeepEqual(remaining, wantRemaining) {
+		t.Errorf("remaining = %v, want %v", remaining, wantRemaining)
+	}
+}
@@ -2,13 +2,20 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/martinsuchenak/rackd/internal/labels"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/reconcile"
+	"github.com/martinsuchenak/rackd/internal/software"
 	"github.com/martinsuchenak/rackd/internal/storage"
 	"github.com/paularlott/mcp"
 )
@@ -46,6 +53,7 @@ func (s *Server) registerTools() {
 			mcp.String("datacenter_id", "Datacenter ID"),
 			mcp.String("username", "Username for SSH/login access"),
 			mcp.String("location", "Device location (e.g., rack, office)"),
+			mcp.String("status", "Lifecycle status (planned, ordered, received, staged, active, maintenance, decommissioned, disposed). Defaults to active for new devices; on update only allowed transitions are accepted"),
 			mcp.StringArray("tags", "Tags for categorization"),
 			mcp.StringArray("domains", "Domain names associated with device"),
 			mcp.ObjectArray("addresses", "Network addresses",
@@ -55,7 +63,21 @@ func (s *Server) registerTools() {
 				mcp.String("label", "Label for the address (e.g., management, data)"),
 				mcp.String("network_id", "Network ID"),
 				mcp.String("switch_port", "Switch port (e.g., eth0, Gi1/0/1)"),
+				mcp.String("mac_address", "MAC address of the interface, checked against discovery scans"),
 			),
+			mcp.Object("labels", "Labels as key/value pairs (e.g. {\"env\": \"prod\"}). On update, replaces all labels"),
+			mcp.Object("asset", "Hardware asset data. On update, replaces all asset data; an empty object removes it",
+				mcp.String("serial_number", "Serial number (unique)"),
+				mcp.String("asset_tag", "Asset tag (unique)"),
+				mcp.String("vendor", "Vendor the device was purchased from"),
+				mcp.String("purchase_date", "Purchase date (YYYY-MM-DD)"),
+				mcp.String("po_number", "Purchase order number"),
+				mcp.Number("cost", "Purchase cost"),
+				mcp.String("warranty_expires", "Warranty expiry date (YYYY-MM-DD)"),
+				mcp.String("support_contract", "Support contract reference"),
+			),
+			mcp.Object("custom_fields", "Custom field values keyed by field name (see custom_field_list). On update, given fields are merged and empty values clear a field"),
+			mcp.Number("version", "Expected version when updating (see device_get). The update fails if the device has changed since"),
 		),
 		s.handleDeviceSave,
 	)
@@ -73,6 +95,9 @@ func (s *Server) registerTools() {
 		mcp.NewTool("device_list", "List all devices, optionally filtered by search query or tags",
 			mcp.String("query", "Search query (searches name, IP, tags, domains, datacenter)"),
 			mcp.StringArray("tags", "Filter by tags (returns devices matching any tag)"),
+			mcp.String("selector", "Label selector (e.g. env=prod,role in (db,cache),!decom)"),
+			mcp.StringArray("status", "Filter by lifecycle status (returns devices in any of the given states)"),
+			mcp.Object("custom_fields", "Filter by custom field values keyed by field name (all must match)"),
 		),
 		s.handleDeviceList,
 	)
@@ -85,6 +110,54 @@ func (s *Server) registerTools() {
 		s.handleDeviceDelete,
 	)
 
+	// device_set_status - Move a device to a new lifecycle status
+	s.mcpServer.RegisterTool(
+		mcp.NewTool("device_set_status", "Move a device to a new lifecycle status. Allowed transitions: planned->ordered|disposed, ordered->received|planned|disposed, received->staged|decommissioned, staged->active|decommissioned, active->maintenance|decommissioned, maintenance->active|decommissioned, decommissioned->staged|disposed. Decommissioning releases the device's pool IP addresses",
+			mcp.String("id", "Device ID or name", mcp.Required()),
+			mcp.String("status", "New lifecycle status", mcp.Required()),
+			mcp.String("note", "Reason for the change"),
+		),
+		s.handleDeviceSetStatus,
+	)
+
+	// device_status_history - List the lifecycle transitions of a device
+	s.mcpServer.RegisterTool(
+		mcp.NewTool("device_status_history", "List the lifecycle status changes of a device, oldest first",
+			mcp.String("id", "Device ID or name", mcp.Required()),
+		),
+		s.handleDeviceStatusHistory,
+	)
+
+	// Hardware asset tools (SQLite only)
+
+	// asset_expiring_warranties - List devices whose warranty expires soon
+	s.mcpServer.RegisterTool(
+		mcp.NewTool("asset_expiring_warranties", "List devices whose hardware warranty expires within the given number of days, soonest first",
+			mcp.Number("days", "Number of days to look ahead (default 90)"),
+			mcp.Boolean("include_expired", "Also list warranties that have already expired"),
+		),
+		s.handleAssetExpiringWarranties,
+	)
+
+	// Software inventory tools (SQLite only)
+
+	// device_software - Get the OS and package inventory of a device
+	s.mcpServer.RegisterTool(
+		mcp.NewTool("device_software", "Get the structured OS and installed software packages of a device",
+			mcp.String("id", "Device ID or name", mcp.Required()),
+		),
+		s.handleDeviceSoftware,
+	)
+
+	// software_find - Find devices with a package installed
+	s.mcpServer.RegisterTool(
+		mcp.NewTool("software_find", "Find the devices that have a software package installed, optionally in versions matching a constraint",
+			mcp.String("name", "Package name (e.g. openssl)", mcp.Required()),
+			mcp.String("version", "Version constraint, e.g. \"<3.0.8\" or \">=3.0.0,<3.0.8\" (operators =, !=, <, <=, >, >=)"),
+		),
+		s.handleSoftwareFind,
+	)
+
 	// Relationship tools (SQLite only)
 
 	// device_add_relationship - Add a relationship between two devices
@@ -149,6 +222,8 @@ func (s *Server) registerTools() {
 			mcp.String("name", "Datacenter name", mcp.Required()),
 			mcp.String("location", "Physical location or address"),
 			mcp.String("description", "Datacenter description"),
+			mcp.Object("custom_fields", "Custom field values keyed by field name (see custom_field_list)"),
+			mcp.Number("version", "Expected version when updating (see datacenter_get). The update fails if the datacenter has changed since"),
 		),
 		s.handleDatacenterSave,
 	)
@@ -176,6 +251,7 @@ func (s *Server) registerTools() {
 		mcp.NewTool("network_list", "List all networks, optionally filtered by name or datacenter",
 			mcp.String("name", "Filter by network name"),
 			mcp.String("datacenter_id", "Filter by datacenter ID"),
+			mcp.String("selector", "Label selector (e.g. env=prod,zone in (a,b))"),
 		),
 		s.handleNetworkList,
 	)
@@ -196,6 +272,9 @@ func (s *Server) registerTools() {
 			mcp.String("subnet", "IP subnet in CIDR notation (e.g., 192.168.1.0/24)", mcp.Required()),
 			mcp.String("datacenter_id", "Datacenter ID", mcp.Required()),
 			mcp.String("description", "Network description"),
+			mcp.Object("labels", "Labels as key/value pairs. On update, replaces all labels"),
+			mcp.Object("custom_fields", "Custom field values keyed by field name (see custom_field_list)"),
+			mcp.Number("version", "Expected version when updating (see network_get). The update fails if the network has changed since"),
 		),
 		s.handleNetworkSave,
 	)
@@ -220,6 +299,7 @@ func (s *Server) registerTools() {
 	s.mcpServer.RegisterTool(
 		mcp.NewTool("network_get_pools", "Get all pools associated with a specific network",
 			mcp.String("id", "Network ID or name", mcp.Required()),
+			mcp.String("selector", "Label selector to filter pools (e.g. purpose=dhcp)"),
 		),
 		s.handleNetworkGetPools,
 	)
@@ -233,6 +313,27 @@ func (s *Server) registerTools() {
 		),
 		s.handleGetNextPoolIP,
 	)
+
+	// Discovery tools (SQLite only)
+
+	// discovery_reconcile - Report drift between discovered hosts and inventory
+	s.mcpServer.RegisterTool(
+		mcp.NewTool("discovery_reconcile", "Compare discovered hosts with inventory devices and report drift: MAC or hostname mismatches on recorded IPs, devices seen on unrecorded IPs, live IPs not recorded on any device, and active devices not seen in recent scans",
+			mcp.String("network_id", "Only reconcile this network"),
+			mcp.Number("missed_scans", "Consecutive scans a device must be missing from to be reported as not seen (default 3)"),
+		),
+		s.handleDiscoveryReconcile,
+	)
+
+	// Custom field tools (SQLite only)
+
+	// custom_field_list - List custom field definitions
+	s.mcpServer.RegisterTool(
+		mcp.NewTool("custom_field_list", "List custom field definitions that can be set on devices, networks and datacenters",
+			mcp.String("entity_type", "Filter by entity type (device, network, datacenter)"),
+		),
+		s.handleCustomFieldList,
+	)
 }
 
 // HandleRequest handles MCP HTTP requests with optional bearer token authentication
@@ -278,29 +379,15 @@ func (s *Server) handleDeviceSave(ctx context.Context, req *mcp.ToolRequest) (*m
 
 	log.Debug("MCP device save request", "name", name)
 
-	// Check if this is an update (id provided) or create
 	id, _ := req.String("id")
-	var device *model.Device
-	isUpdate := false
-
-	if id != "" {
-		log.Debug("Checking for existing device", "id", id)
-		// Try to get existing device
-		existingDevice, err := s.storage.GetDevice(id)
-		if err == nil {
-			// Device exists, update it
-			device = existingDevice
-			isUpdate = true
-			log.Debug("Found existing device for update", "id", id, "name", existingDevice.Name)
-		}
-	}
-
 	description := req.StringOr("description", "")
 	makeModel := req.StringOr("make_model", "")
 	os := req.StringOr("os", "")
 	datacenterID := req.StringOr("datacenter_id", "")
 	username := req.StringOr("username", "")
 	location := req.StringOr("location", "")
+	status := model.DeviceStatus(req.StringOr("status", ""))
+	version := req.IntOr("version", 0)
 
 	tags, _ := req.StringSlice("tags")
 	domains, _ := req.StringSlice("domains")
@@ -310,73 +397,145 @@ func (s *Server) handleDeviceSave(ctx context.Context, req *mcp.ToolRequest) (*m
 		return nil, mcp.NewToolErrorInvalidParams("invalid addresses: " + err.Error())
 	}
 
-	if isUpdate {
-		// Update existing device
-		device.Name = name
-		if description != "" {
-			device.Description = description
-		}
-		if makeModel != "" {
-			device.MakeModel = makeModel
-		}
-		if os != "" {
-			device.OS = os
-		}
-		if datacenterID != "" {
-			device.DatacenterID = datacenterID
-		}
-		if username != "" {
-			device.Username = username
-		}
-		if location != "" {
-			device.Location = location
-		}
-		if tags != nil {
-			device.Tags = tags
+	deviceLabels := s.parseStringMap(req, "labels")
+	customFields := s.parseStringMap(req, "custom_fields")
+	asset, err := s.parseAsset(req)
+	if err != nil {
+		return nil, mcp.NewToolErrorInvalidParams("invalid asset: " + err.Error())
+	}
+
+	// Look up and save the device in one transaction, so concurrent saves don't
+	// overwrite each other's changes
+	var device *model.Device
+	isUpdate := false
+	err = s.withTx(func(st storage.Storage) error {
+		if id != "" {
+			log.Debug("Checking for existing device", "id", id)
+			// Try to get existing device
+			existingDevice, err := st.GetDevice(id)
+			if err == nil {
+				// Device exists, update it
+				device = existingDevice
+				isUpdate = true
+				log.Debug("Found existing device for update", "id", id, "name", existingDevice.Name)
+			}
 		}
-		if domains != nil {
-			device.Domains = domains
+
+		if isUpdate {
+			// Update existing device
+			device.Name = name
+			if description != "" {
+				device.Description = description
+			}
+			if makeModel != "" {
+				device.MakeModel = makeModel
+			}
+			if os != "" {
+				device.OS = os
+			}
+			if datacenterID != "" {
+				device.DatacenterID = datacenterID
+			}
+			if username != "" {
+				device.Username = username
+			}
+			if location != "" {
+				device.Location = location
+			}
+			if status != "" {
+				device.Status = status
+			}
+			if tags != nil {
+				device.Tags = tags
+			}
+			if domains != nil {
+				device.Domains = domains
+			}
+			if addresses != nil {
+				device.Addresses = addresses
+			}
+			if deviceLabels != nil {
+				device.Labels = deviceLabels
+			}
+			if asset != nil {
+				device.Asset = asset
+			}
+			if customFields != nil {
+				device.CustomFields = mergeCustomFields(device.CustomFields, customFields)
+			}
+			if version != 0 {
+				device.Version = version
+			}
+
+			if err := st.UpdateDevice(device); err != nil {
+				log.Error("MCP device update failed", "error", err, "id", device.ID, "name", device.Name)
+				if errors.Is(err, storage.ErrVersionConflict) {
+					return mcp.NewToolErrorInvalidParams(fmt.Sprintf("device has changed since version %d, get it again and retry", version))
+				}
+				if errors.Is(err, storage.ErrInvalidCustomField) || errors.Is(err, labels.ErrInvalidLabel) ||
+					errors.Is(err, storage.ErrInvalidStatus) || errors.Is(err, storage.ErrInvalidTransition) ||
+					errors.Is(err, storage.ErrInvalidAsset) || errors.Is(err, storage.ErrDuplicateAsset) {
+					return mcp.NewToolErrorInvalidParams(err.Error())
+				}
+				return mcp.NewToolErrorInternal("failed to update device: " + err.Error())
+			}
+
+			log.Info("MCP device updated successfully", "id", device.ID, "name", device.Name)
+			return nil
 		}
-		if addresses != nil {
-			device.Addresses = addresses
+
+		// Create new device
+		device = &model.Device{
+			ID:           id, // Will be generated if empty by API layer, but we can set it here too
+			Name:         name,
+			Description:  description,
+			MakeModel:    makeModel,
+			OS:           os,
+			DatacenterID: datacenterID,
+			Username:     username,
+			Location:     location,
+			Status:       status,
+			Tags:         tags,
+			Domains:      domains,
+			Addresses:    addresses,
+			Labels:       deviceLabels,
+			Asset:        asset,
+			CustomFields: customFields,
 		}
 
-		if err := s.storage.UpdateDevice(device); err != nil {
-			log.Error("MCP device update failed", "error", err, "id", device.ID, "name", device.Name)
-			return nil, mcp.NewToolErrorInternal("failed to update device: " + err.Error())
+		// Generate ID if not provided
+		if device.ID == "" {
+			device.ID = s.generateID(name)
 		}
 
-		log.Info("MCP device updated successfully", "id", device.ID, "name", device.Name)
-		return mcp.NewToolResponseText(fmt.Sprintf("Device updated: %s (ID: %s)", device.Name, device.ID)), nil
-	}
+		if err := st.CreateDevice(device); err != nil {
+			log.Error("MCP device creation failed", "error", err, "name", device.Name)
+			if errors.Is(err, storage.ErrInvalidCustomField) || errors.Is(err, labels.ErrInvalidLabel) ||
+				errors.Is(err, storage.ErrInvalidStatus) || errors.Is(err, storage.ErrInvalidAsset) || errors.Is(err, storage.ErrDuplicateAsset) {
+				return mcp.NewToolErrorInvalidParams(err.Error())
+			}
+			return mcp.NewToolErrorInternal("failed to create device: " + err.Error())
+		}
 
-	// Create new device
-	device = &model.Device{
-		ID:           id, // Will be generated if empty by API layer, but we can set it here too
-		Name:         name,
-		Description:  description,
-		MakeModel:    makeModel,
-		OS:           os,
-		DatacenterID: datacenterID,
-		Username:     username,
-		Location:     location,
-		Tags:         tags,
-		Domains:      domains,
-		Addresses:    addresses,
+		log.Info("MCP device created successfully", "id", device.ID, "name", device.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate ID if not provided
-	if device.ID == "" {
-		device.ID = s.generateID(name)
+	if isUpdate {
+		return mcp.NewToolResponseText(fmt.Sprintf("Device updated: %s (ID: %s)", device.Name, device.ID)), nil
 	}
+	return mcp.NewToolResponseText(fmt.Sprintf("Device created: %s (ID: %s)", device.Name, device.ID)), nil
+}
 
-	if err := s.storage.CreateDevice(device); err != nil {
-		log.Error("MCP device creation failed", "error", err, "name", device.Name)
-		return nil, mcp.NewToolErrorInternal("failed to create device: " + err.Error())
+// withTx runs fn in a transaction when the storage backend supports one
+func (s *Server) withTx(fn func(st storage.Storage) error) error {
+	if txStorage, ok := s.storage.(storage.TxStorage); ok {
+		return txStorage.WithTx(fn)
 	}
-
-	log.Info("MCP device created successfully", "id", device.ID, "name", device.Name)
-	return mcp.NewToolResponseText(fmt.Sprintf("Device created: %s (ID: %s)", device.Name, device.ID)), nil
+	return fn(s.storage)
 }
 
 func (s *Server) handleDeviceGet(ctx context.Context, req *mcp.ToolRequest) (*mcp.ToolResponse, error) {
@@ -404,8 +563,20 @@ func (s *Server) handleDeviceList(ctx context.Context, req *mcp.ToolRequest) (*m
 
 	query, _ := req.String("query")
 	tags, _ := req.StringSlice("tags")
+	selector, _ := req.String("selector")
+	statusValues, _ := req.StringSlice("status")
+	customFields := s.parseStringMap(req, "custom_fields")
+
+	var statuses []model.DeviceStatus
+	for _, value := range statusValues {
+		status := model.DeviceStatus(value)
+		if !status.Valid() {
+			return nil, mcp.NewToolErrorInvalidParams(fmt.Sprintf("%v: %q", storage.ErrInvalidStatus, value))
+		}
+		statuses = append(statuses, status)
+	}
 
-	log.Debug("MCP device list request", "query", query, "tags", tags)
+	log.Debug("MCP device list request", "query", query, "tags", tags, "selector", selector, "status", statusValues)
 
 	// Prioritize search query over tag filter
 	if query != "" {
@@ -416,13 +587,22 @@ func (s *Server) handleDeviceList(ctx context.Context, req *mcp.ToolRequest) (*m
 		}
 		searchDescription = fmt.Sprintf("matching '%s'", query)
 	} else {
-		devices, err = s.storage.ListDevices(&model.DeviceFilter{Tags: tags})
+		devices, err = s.storage.ListDevices(&model.DeviceFilter{Tags: tags, Statuses: statuses, LabelSelector: selector, CustomFields: customFields})
 		if err != nil {
-			log.Error("MCP device list failed", "error", err, "tags", tags)
+			log.Error("MCP device list failed", "error", err, "tags", tags, "selector", selector)
+			if errors.Is(err, labels.ErrInvalidSelector) {
+				return nil, mcp.NewToolErrorInvalidParams(err.Error())
+			}
 			return nil, mcp.NewToolErrorInternal("failed to list devices: " + err.Error())
 		}
 		if len(tags) > 0 {
 			searchDescription = fmt.Sprintf("with tags: %s", strings.Join(tags, ", "))
+		} else if selector != "" {
+			searchDescription = fmt.Sprintf("matching selector: %s", selector)
+		} else if len(statuses) > 0 {
+			searchDescription = fmt.Sprintf("with status: %s", strings.Join(statusValues, ", "))
+		} else if len(customFields) > 0 {
+			searchDescription = "matching custom fields"
 		} else {
 			searchDescription = "in inventory"
 		}
@@ -467,6 +647,234 @@ func (s *Server) handleDeviceDelete(ctx context.Context, req *mcp.ToolRequest) (
 	return mcp.NewToolResponseText("Device deleted successfully"), nil
 }
 
+func (s *Server) handleDeviceSetStatus(ctx context.Context, req *mcp.ToolRequest) (*mcp.ToolResponse, error) {
+	id, err := req.String("id")
+	if err != nil {
+		log.Warn("MCP device set status - missing ID", "error", err)
+		return nil, mcp.NewToolErrorInvalidParams("id is required: " + err.Error())
+	}
+	status, err := req.String("status")
+	if err != nil {
+		log.Warn("MCP device set status - missing status", "error", err)
+		return nil, mcp.NewToolErrorInvalidParams("status is required: " + err.Error())
+	}
+	note := req.StringOr("note", "")
+
+	log.Debug("MCP device set status request", "id", id, "status", status)
+
+	lifecycleStorage, ok := s.storage.(storage.LifecycleStorage)
+	if !ok {
+		return mcp.NewToolResponseText("Device lifecycle is not supported by the current storage backend. Use SQLite storage to enable it."), nil
+	}
+
+	change, err := lifecycleStorage.TransitionDevice(id, model.DeviceStatus(status), note)
+	if err != nil {
+		log.Error("MCP device set status failed", "error", err, "id", id, "status", status)
+		if errors.Is(err, storage.ErrInvalidStatus) || errors.Is(err, storage.ErrInvalidTransition) {
+			return nil, mcp.NewToolErrorInvalidParams(err.Error())
+		}
+		if errors.Is(err, storage.ErrDeviceNotFound) {
+			return nil, mcp.NewToolErrorInvalidParams("device not found: " + id)
+		}
+		return nil, mcp.NewToolErrorInternal("failed to change device status: " + err.Error())
+	}
+
+	log.Info("MCP device status changed", "id", change.DeviceID, "from", change.FromStatus, "to", change.ToStatus)
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Device %s moved from %s to %s\n", change.DeviceID, change.FromStatus, change.ToStatus))
+	if len(change.ReleasedAddresses) > 0 {
+		result.WriteString("Released addresses:\n")
+		for _, addr := range change.ReleasedAddresses {
+			result.WriteString(fmt.Sprintf("  - %s (pool: %s)\n", addr.IP, addr.PoolID))
+		}
+	}
+	return mcp.NewToolResponseText(result.String()), nil
+}
+
+func (s *Server) handleAssetExpiringWarranties(ctx context.Context, req *mcp.ToolRequest) (*mcp.ToolResponse, error) {
+	days := req.IntOr("days", 90)
+	if days < 0 {
+		return nil, mcp.NewToolErrorInvalidParams("days must not be negative")
+	}
+	includeExpired := req.BoolOr("include_expired", false)
+
+	log.Debug("MCP expiring warranties request", "days", days, "include_expired", includeExpired)
+
+	assetStorage, ok := s.storage.(storage.AssetStorage)
+	if !ok {
+		return mcp.NewToolResponseText("Hardware assets are not supported by the current storage backend. Use SQLite storage to enable them."), nil
+	}
+
+	today := time.Now().UTC()
+	filter := &model.AssetFilter{WarrantyExpiresBefore: today.AddDate(0, 0, days).Format("2006-01-02")}
+	if !includeExpired {
+		filter.WarrantyExpiresAfter = today.Format("2006-01-02")
+	}
+	assets, err := assetStorage.ListAssets(filter)
+	if err != nil {
+		log.Error("MCP expiring warranties failed", "error", err)
+		return nil, mcp.NewToolErrorInternal("failed to list assets: " + err.Error())
+	}
+
+	if len(assets) == 0 {
+		return mcp.NewToolResponseText(fmt.Sprintf("No warranties expire in the next %d days", days)), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Warranties expiring in the next %d days (%d devices):\n", days, len(assets)))
+	for _, a := range assets {
+		result.WriteString(fmt.Sprintf("  - %s: expires %s", a.DeviceName, a.WarrantyExpires))
+		if a.WarrantyDaysLeft != nil {
+			if *a.WarrantyDaysLeft < 0 {
+				result.WriteString(fmt.Sprintf(" (expired %d days ago)", -*a.WarrantyDaysLeft))
+			} else {
+				result.WriteString(fmt.Sprintf(" (%d days left)", *a.WarrantyDaysLeft))
+			}
+		}
+		if a.SerialNumber != "" {
+			result.WriteString(fmt.Sprintf(", serial %s", a.SerialNumber))
+		}
+		if a.SupportContract != "" {
+			result.WriteString(fmt.Sprintf(", contract %s", a.SupportContract))
+		}
+		result.WriteString("\n")
+	}
+	return mcp.NewToolResponseText(result.String()), nil
+}
+
+func (s *Server) handleDeviceSoftware(ctx context.Context, req *mcp.ToolRequest) (*mcp.ToolResponse, error) {
+	id, err := req.String("id")
+	if err != nil {
+		log.Warn("MCP device software - missing ID", "error", err)
+		return nil, mcp.NewToolErrorInvalidParams("id is required: " + err.Error())
+	}
+
+	log.Debug("MCP device software request", "id", id)
+
+	softwareStorage, ok := s.storage.(storage.SoftwareStorage)
+	if !ok {
+		return mcp.NewToolResponseText("Software inventory is not supported by the current storage backend. Use SQLite storage to enable it."), nil
+	}
+
+	inventory, err := softwareStorage.GetDeviceSoftware(id)
+	if err != nil {
+		log.Error("MCP device software failed", "error", err, "id", id)
+		if errors.Is(err, storage.ErrDeviceNotFound) {
+			return nil, mcp.NewToolErrorInvalidParams("device not found: " + id)
+		}
+		return nil, mcp.NewToolErrorInternal("failed to get software inventory: " + err.Error())
+	}
+
+	if inventory.CollectedAt == nil {
+		return mcp.NewToolResponseText("No software inventory has been reported for this device"), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Software inventory of %s (collected %s)\n", inventory.DeviceID, inventory.CollectedAt.Format(time.RFC3339)))
+	if os := inventory.OS; os != nil {
+		result.WriteString(fmt.Sprintf("OS: %s\n", formatOSInfo(os)))
+	}
+	result.WriteString(fmt.Sprintf("Packages (%d):\n", len(inventory.Packages)))
+	for _, pkg := range inventory.Packages {
+		result.WriteString(fmt.Sprintf("  - %s %s\n", pkg.Name, pkg.Version))
+	}
+	return mcp.NewToolResponseText(result.String()), nil
+}
+
+func (s *Server) handleSoftwareFind(ctx context.Context, req *mcp.ToolRequest) (*mcp.ToolResponse, error) {
+	name, err := req.String("name")
+	if err != nil {
+		log.Warn("MCP software find - missing name", "error", err)
+		return nil, mcp.NewToolErrorInvalidParams("name is required: " + err.Error())
+	}
+	filter := &model.PackageFilter{Name: name, Version: req.StringOr("version", "")}
+
+	log.Debug("MCP software find request", "name", filter.Name, "version", filter.Version)
+
+	softwareStorage, ok := s.storage.(storage.SoftwareStorage)
+	if !ok {
+		return mcp.NewToolResponseText("Software inventory is not supported by the current storage backend. Use SQLite storage to enable it."), nil
+	}
+
+	installs, err := softwareStorage.FindPackageInstalls(filter)
+	if err != nil {
+		log.Error("MCP software find failed", "error", err, "name", filter.Name)
+		if errors.Is(err, software.ErrInvalidConstraint) {
+			return nil, mcp.NewToolErrorInvalidParams(err.Error())
+		}
+		return nil, mcp.NewToolErrorInternal("failed to find packages: " + err.Error())
+	}
+
+	if len(installs) == 0 {
+		return mcp.NewToolResponseText(fmt.Sprintf("No devices have %s %s installed", filter.Name, filter.Version)), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Found %s on %d device(s):\n", filter.Name, len(installs)))
+	for _, i := range installs {
+		result.WriteString(fmt.Sprintf("  - %s (ID: %s): %s\n", i.DeviceName, i.DeviceID, i.Version))
+	}
+	return mcp.NewToolResponseText(result.String()), nil
+}
+
+// formatOSInfo formats structured OS data on one line, skipping empty fields
+func formatOSInfo(os *model.OSInfo) string {
+	var parts []string
+	for _, part := range []string{os.Family, os.Distribution, os.Version} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if os.Kernel != "" {
+		parts = append(parts, "(kernel "+os.Kernel+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *Server) handleDeviceStatusHistory(ctx context.Context, req *mcp.ToolRequest) (*mcp.ToolResponse, error) {
+	id, err := req.String("id")
+	if err != nil {
+		log.Warn("MCP device status history - missing ID", "error", err)
+		return nil, mcp.NewToolErrorInvalidParams("id is required: " + err.Error())
+	}
+
+	log.Debug("MCP device status history request", "id", id)
+
+	lifecycleStorage, ok := s.storage.(storage.LifecycleStorage)
+	if !ok {
+		return mcp.NewToolResponseText("Device lifecycle is not supported by the current storage backend. Use SQLite storage to enable it."), nil
+	}
+
+	history, err := lifecycleStorage.GetDeviceStatusHistory(id)
+	if err != nil {
+		log.Error("MCP device status history failed", "error", err, "id", id)
+		if errors.Is(err, storage.ErrDeviceNotFound) {
+			return nil, mcp.NewToolErrorInvalidParams("device not found: " + id)
+		}
+		return nil, mcp.NewToolErrorInternal("failed to get device status history: " + err.Error())
+	}
+
+	if len(history) == 0 {
+		return mcp.NewToolResponseText("No status changes recorded"), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Status history (%d changes):\n", len(history)))
+	for _, c := range history {
+		from := string(c.FromStatus)
+		if from == "" {
+			from = "(created)"
+		}
+		result.WriteString(fmt.Sprintf("  - %s: %s -> %s", c.ChangedAt.Format(time.RFC3339), from, c.ToStatus))
+		if c.Note != "" {
+			result.WriteString(fmt.Sprintf(" (%s)", c.Note))
+		}
+		result.WriteString("\n")
+	}
+	return mcp.NewToolResponseText(result.String()), nil
+}
+
 // Datacenter tool handlers
 
 func (s *Server) handleDatacenterList(ctx context.Context, req *mcp.ToolRequest) (*mcp.ToolResponse, error) {
@@ -561,6 +969,8 @@ func (s *Server) handleDatacenterSave(ctx context.Context, req *mcp.ToolRequest)
 
 	location := req.StringOr("location", "")
 	description := req.StringOr("description", "")
+	customFields := s.parseStringMap(req, "custom_fields")
+	version := req.IntOr("version", 0)
 
 	if isUpdate {
 		// Update existing datacenter
@@ -571,9 +981,21 @@ func (s *Server) handleDatacenterSave(ctx context.Context, req *mcp.ToolRequest)
 		if description != "" {
 			datacenter.Description = description
 		}
+		if customFields != nil {
+			datacenter.CustomFields = mergeCustomFields(datacenter.CustomFields, customFields)
+		}
+		if version != 0 {
+			datacenter.Version = version
+		}
 
 		if err := dcStorage.UpdateDatacenter(datacenter); err != nil {
 			log.Error("MCP datacenter update failed", "error", err, "id", datacenter.ID, "name", datacenter.Name)
+			if errors.Is(err, storage.ErrVersionConflict) {
+				return nil, mcp.NewToolErrorInvalidParams(fmt.Sprintf("datacenter has changed since version %d, get it again and retry", version))
+			}
+			if errors.Is(err, storage.ErrInvalidCustomField) {
+				return nil, mcp.NewToolErrorInvalidParams(err.Error())
+			}
 			return nil, mcp.NewToolErrorInternal("failed to update datacenter: " + err.Error())
 		}
 
@@ -583,13 +1005,17 @@ func (s *Server) handleDatacenterSave(ctx context.Context, req *mcp.ToolRequest)
 
 	// Create new datacenter
 	datacenter = &model.Datacenter{
-		Name:        name,
-		Location:    location,
-		Description: description,
+		Name:         name,
+		Location:     location,
+		Description:  description,
+		CustomFields: customFields,
 	}
 
 	if err := dcStorage.CreateDatacenter(datacenter); err != nil {
 		log.Error("MCP datacenter creation failed", "error", err, "name", datacenter.Name)
+		if errors.Is(err, storage.ErrInvalidCustomField) {
+			return nil, mcp.NewToolErrorInvalidParams(err.Error())
+		}
 		return nil, mcp.NewToolErrorInternal("failed to create datacenter: " + err.Error())
 	}
 
@@ -663,13 +1089,17 @@ func (s *Server) handleNetworkList(ctx context.Context, req *mcp.ToolRequest) (*
 
 	name, _ := req.String("name")
 	datacenterID, _ := req.String("datacenter_id")
-	log.Debug("MCP network list request", "name", name, "datacenter_id", datacenterID)
+	selector, _ := req.String("selector")
+	log.Debug("MCP network list request", "name", name, "datacenter_id", datacenterID, "selector", selector)
 
-	filter := &model.NetworkFilter{Name: name, DatacenterID: datacenterID}
+	filter := &model.NetworkFilter{Name: name, DatacenterID: datacenterID, LabelSelector: selector}
 
 	networks, err := netStorage.ListNetworks(filter)
 	if err != nil {
 		log.Error("MCP network list failed", "error", err, "name", name, "datacenter_id", datacenterID)
+		if errors.Is(err, labels.ErrInvalidSelector) {
+			return nil, mcp.NewToolErrorInvalidParams(err.Error())
+		}
 		return nil, mcp.NewToolErrorInternal("failed to list networks: " + err.Error())
 	}
 
@@ -750,6 +1180,9 @@ func (s *Server) handleNetworkSave(ctx context.Context, req *mcp.ToolRequest) (*
 	}
 
 	description := req.StringOr("description", "")
+	networkLabels := s.parseStringMap(req, "labels")
+	customFields := s.parseStringMap(req, "custom_fields")
+	version := req.IntOr("version", 0)
 
 	if isUpdate {
 		// Update existing network
@@ -759,8 +1192,23 @@ func (s *Server) handleNetworkSave(ctx context.Context, req *mcp.ToolRequest) (*
 		if description != "" {
 			network.Description = description
 		}
+		if networkLabels != nil {
+			network.Labels = networkLabels
+		}
+		if customFields != nil {
+			network.CustomFields = mergeCustomFields(network.CustomFields, customFields)
+		}
+		if version != 0 {
+			network.Version = version
+		}
 
 		if err := netStorage.UpdateNetwork(network); err != nil {
+			if errors.Is(err, storage.ErrVersionConflict) {
+				return nil, mcp.NewToolErrorInvalidParams(fmt.Sprintf("network has changed since version %d, get it again and retry", version))
+			}
+			if errors.Is(err, storage.ErrInvalidCustomField) || errors.Is(err, labels.ErrInvalidLabel) {
+				return nil, mcp.NewToolErrorInvalidParams(err.Error())
+			}
 			return nil, mcp.NewToolErrorInternal("failed to update network: " + err.Error())
 		}
 
@@ -773,9 +1221,14 @@ func (s *Server) handleNetworkSave(ctx context.Context, req *mcp.ToolRequest) (*
 		Subnet:       subnet,
 		DatacenterID: datacenterID,
 		Description:  description,
+		Labels:       networkLabels,
+		CustomFields: customFields,
 	}
 
 	if err := netStorage.CreateNetwork(network); err != nil {
+		if errors.Is(err, storage.ErrInvalidCustomField) || errors.Is(err, labels.ErrInvalidLabel) {
+			return nil, mcp.NewToolErrorInvalidParams(err.Error())
+		}
 		return nil, mcp.NewToolErrorInternal("failed to create network: " + err.Error())
 	}
 
@@ -859,11 +1312,17 @@ func (s *Server) handleNetworkGetPools(ctx context.Context, req *mcp.ToolRequest
 		return nil, mcp.NewToolErrorInternal("network not found: " + err.Error())
 	}
 
+	selector, _ := req.String("selector")
+
 	// List pools filtering by network ID
 	pools, err := poolStorage.ListNetworkPools(&model.NetworkPoolFilter{
-		NetworkID: network.ID,
+		NetworkID:     network.ID,
+		LabelSelector: selector,
 	})
 	if err != nil {
+		if errors.Is(err, labels.ErrInvalidSelector) {
+			return nil, mcp.NewToolErrorInvalidParams(err.Error())
+		}
 		return nil, mcp.NewToolErrorInternal("failed to get network pools: " + err.Error())
 	}
 
@@ -879,6 +1338,9 @@ func (s *Server) handleNetworkGetPools(ctx context.Context, req *mcp.ToolRequest
 		if len(pool.Tags) > 0 {
 			result.WriteString(fmt.Sprintf("  Tags: %s\n", strings.Join(pool.Tags, ", ")))
 		}
+		if len(pool.Labels) > 0 {
+			result.WriteString(fmt.Sprintf("  Labels: %s\n", formatLabels(pool.Labels)))
+		}
 		if pool.Description != "" {
 			result.WriteString(fmt.Sprintf("  Description: %s\n", pool.Description))
 		}
@@ -1152,6 +1614,10 @@ func (s *Server) parseAddresses(req *mcp.ToolRequest) ([]model.Address, error) {
 			addr.SwitchPort = switchPort
 		}
 
+		if macAddress, ok := addrObj["mac_address"].(string); ok {
+			addr.MACAddress = macAddress
+		}
+
 		addresses = append(addresses, addr)
 	}
 
@@ -1162,12 +1628,19 @@ func (s *Server) formatDeviceSummary(device *model.Device) string {
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("Name: %s\n", device.Name))
 	result.WriteString(fmt.Sprintf("ID: %s\n", device.ID))
+	result.WriteString(fmt.Sprintf("Version: %d\n", device.Version))
 	if device.MakeModel != "" {
 		result.WriteString(fmt.Sprintf("Make/Model: %s\n", device.MakeModel))
 	}
 	if device.OS != "" {
 		result.WriteString(fmt.Sprintf("OS: %s\n", device.OS))
 	}
+	if device.OSInfo != nil {
+		result.WriteString(fmt.Sprintf("OS Details: %s\n", formatOSInfo(device.OSInfo)))
+	}
+	if device.Status != "" {
+		result.WriteString(fmt.Sprintf("Status: %s\n", device.Status))
+	}
 	if device.DatacenterID != "" {
 		// Try to get datacenter name
 		if dcStorage, ok := s.storage.(storage.DatacenterStorage); ok {
@@ -1185,6 +1658,26 @@ func (s *Server) formatDeviceSummary(device *model.Device) string {
 	if len(device.Tags) > 0 {
 		result.WriteString(fmt.Sprintf("Tags: %s\n", strings.Join(device.Tags, ", ")))
 	}
+	if len(device.Labels) > 0 {
+		result.WriteString(fmt.Sprintf("Labels: %s\n", formatLabels(device.Labels)))
+	}
+	if a := device.Asset; a != nil {
+		if a.SerialNumber != "" {
+			result.WriteString(fmt.Sprintf("Serial: %s\n", a.SerialNumber))
+		}
+		if a.AssetTag != "" {
+			result.WriteString(fmt.Sprintf("Asset Tag: %s\n", a.AssetTag))
+		}
+		if a.Vendor != "" {
+			result.WriteString(fmt.Sprintf("Vendor: %s\n", a.Vendor))
+		}
+		if a.WarrantyExpires != "" {
+			result.WriteString(fmt.Sprintf("Warranty Expires: %s\n", a.WarrantyExpires))
+		}
+		if a.SupportContract != "" {
+			result.WriteString(fmt.Sprintf("Support Contract: %s\n", a.SupportContract))
+		}
+	}
 	if len(device.Addresses) > 0 {
 		result.WriteString("Addresses:\n")
 		for _, addr := range device.Addresses {
@@ -1200,12 +1693,17 @@ func (s *Server) formatDeviceSummary(device *model.Device) string {
 			if addr.SwitchPort != "" {
 				switchPort = fmt.Sprintf(" (switch: %s)", addr.SwitchPort)
 			}
-			result.WriteString(fmt.Sprintf("  - %s%s%s%s%s\n", addr.IP, port, label, switchPort, addr.Type))
+			mac := ""
+			if addr.MACAddress != "" {
+				mac = fmt.Sprintf(" (MAC: %s)", addr.MACAddress)
+			}
+			result.WriteString(fmt.Sprintf("  - %s%s%s%s%s%s\n", addr.IP, port, label, switchPort, mac, addr.Type))
 		}
 	}
 	if len(device.Domains) > 0 {
 		result.WriteString(fmt.Sprintf("Domains: %s\n", strings.Join(device.Domains, ", ")))
 	}
+	writeCustomFields(&result, device.CustomFields)
 	return result.String()
 }
 
@@ -1213,12 +1711,14 @@ func (s *Server) formatDatacenterSummary(datacenter *model.Datacenter) string {
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("Name: %s\n", datacenter.Name))
 	result.WriteString(fmt.Sprintf("ID: %s\n", datacenter.ID))
+	result.WriteString(fmt.Sprintf("Version: %d\n", datacenter.Version))
 	if datacenter.Location != "" {
 		result.WriteString(fmt.Sprintf("Location: %s\n", datacenter.Location))
 	}
 	if datacenter.Description != "" {
 		result.WriteString(fmt.Sprintf("Description: %s\n", datacenter.Description))
 	}
+	writeCustomFields(&result, datacenter.CustomFields)
 	return result.String()
 }
 
@@ -1226,6 +1726,7 @@ func (s *Server) formatNetworkSummary(network *model.Network) string {
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("Name: %s\n", network.Name))
 	result.WriteString(fmt.Sprintf("ID: %s\n", network.ID))
+	result.WriteString(fmt.Sprintf("Version: %d\n", network.Version))
 	result.WriteString(fmt.Sprintf("Subnet: %s\n", network.Subnet))
 	result.WriteString(fmt.Sprintf("Datacenter ID: %s\n", network.DatacenterID))
 	// Try to get datacenter name
@@ -1237,9 +1738,96 @@ func (s *Server) formatNetworkSummary(network *model.Network) string {
 	if network.Description != "" {
 		result.WriteString(fmt.Sprintf("Description: %s\n", network.Description))
 	}
+	if len(network.Labels) > 0 {
+		result.WriteString(fmt.Sprintf("Labels: %s\n", formatLabels(network.Labels)))
+	}
+	writeCustomFields(&result, network.CustomFields)
 	return result.String()
 }
 
+// parseAsset reads the asset object parameter, returning nil if it was not given
+func (s *Server) parseAsset(req *mcp.ToolRequest) (*model.HardwareAsset, error) {
+	obj, err := req.Object("asset")
+	if err != nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var asset model.HardwareAsset
+	if err := json.Unmarshal(data, &asset); err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// parseStringMap reads an object parameter such as custom_fields or labels, converting values to strings
+func (s *Server) parseStringMap(req *mcp.ToolRequest, name string) map[string]string {
+	obj, err := req.Object(name)
+	if err != nil {
+		return nil
+	}
+	values := make(map[string]string, len(obj))
+	for key, value := range obj {
+		switch v := value.(type) {
+		case nil:
+			values[key] = ""
+		case float64:
+			// JSON numbers arrive as float64; avoid exponent notation for integers
+			values[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return values
+}
+
+// mergeCustomFields overlays updates onto existing values; empty values clear a field
+func mergeCustomFields(existing, updates map[string]string) map[string]string {
+	merged := make(map[string]string, len(existing)+len(updates))
+	for name, value := range existing {
+		merged[name] = value
+	}
+	for name, value := range updates {
+		if value == "" {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = value
+	}
+	return merged
+}
+
+// formatLabels renders labels as a sorted key=value list
+func formatLabels(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + values[key]
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeCustomFields(result *strings.Builder, fields map[string]string) {
+	if len(fields) == 0 {
+		return
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result.WriteString("Custom Fields:\n")
+	for _, name := range names {
+		result.WriteString(fmt.Sprintf("  - %s: %s\n", name, fields[name]))
+	}
+}
+
 func (s *Server) deviceToResponse(device *model.Device) *mcp.ToolResponse {
 	return mcp.NewToolResponseText(s.formatDeviceSummary(device))
 }
@@ -1263,3 +1851,111 @@ func (s *Server) LogStartup() {
 		log.Debug("MCP tool registered", "name", tool.Name, "description", tool.Description)
 	}
 }
+
+// Custom field tool handlers
+
+func (s *Server) handleCustomFieldList(ctx context.Context, req *mcp.ToolRequest) (*mcp.ToolResponse, error) {
+	cfStorage, ok := s.storage.(storage.CustomFieldStorage)
+	if !ok {
+		log.Debug("MCP custom field list - storage not supported")
+		return mcp.NewToolResponseText("Custom fields are not supported by the current storage backend. Use SQLite storage to enable custom fields."), nil
+	}
+
+	entityType, _ := req.String("entity_type")
+	log.Debug("MCP custom field list request", "entity_type", entityType)
+
+	fields, err := cfStorage.ListCustomFields(&model.CustomFieldFilter{EntityType: entityType})
+	if err != nil {
+		log.Error("MCP custom field list failed", "error", err, "entity_type", entityType)
+		return nil, mcp.NewToolErrorInternal("failed to list custom fields: " + err.Error())
+	}
+
+	log.Info("MCP custom field list completed", "count", len(fields), "entity_type", entityType)
+
+	if len(fields) == 0 {
+		return mcp.NewToolResponseText("No custom fields defined"), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Found %d custom fields:\n\n", len(fields)))
+	for _, field := range fields {
+		result.WriteString(fmt.Sprintf("Name: %s\n", field.Name))
+		result.WriteString(fmt.Sprintf("Entity: %s\n", field.EntityType))
+		result.WriteString(fmt.Sprintf("Type: %s\n", field.Type))
+		if field.Label != "" {
+			result.WriteString(fmt.Sprintf("Label: %s\n", field.Label))
+		}
+		if field.Required {
+			result.WriteString("Required: yes\n")
+		}
+		if field.Pattern != "" {
+			result.WriteString(fmt.Sprintf("Pattern: %s\n", field.Pattern))
+		}
+		if len(field.Options) > 0 {
+			result.WriteString(fmt.Sprintf("Options: %s\n", strings.Join(field.Options, ", ")))
+		}
+		if field.Description != "" {
+			result.WriteString(fmt.Sprintf("Description: %s\n", field.Description))
+		}
+		result.WriteString("\n")
+	}
+
+	return mcp.NewToolResponseText(result.String()), nil
+}
+
+func (s *Server) handleDiscoveryReconcile(ctx context.Context, req *mcp.ToolRequest) (*mcp.ToolResponse, error) {
+	opts := model.ReconcileOptions{
+		NetworkID:   req.StringOr("network_id", ""),
+		MissedScans: req.IntOr("missed_scans", reconcile.DefaultMissedScans),
+	}
+	if opts.MissedScans < 1 {
+		return nil, mcp.NewToolErrorInvalidParams("missed_scans must be at least 1")
+	}
+
+	log.Debug("MCP reconcile request", "network_id", opts.NetworkID, "missed_scans", opts.MissedScans)
+
+	store, ok := s.storage.(reconcile.Store)
+	if !ok {
+		return mcp.NewToolResponseText("Reconciliation is not supported by the current storage backend. Use SQLite storage to enable discovery."), nil
+	}
+	if netStorage, ok := s.storage.(storage.NetworkStorage); ok && opts.NetworkID != "" {
+		if _, err := netStorage.GetNetwork(opts.NetworkID); err != nil {
+			return nil, mcp.NewToolErrorInvalidParams("network not found: " + err.Error())
+		}
+	}
+
+	report, err := reconcile.Run(store, opts)
+	if err != nil {
+		log.Error("MCP reconcile failed", "error", err)
+		return nil, mcp.NewToolErrorInternal("failed to reconcile inventory: " + err.Error())
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Matched %d live hosts to inventory devices.\n", report.Matched))
+	if len(report.Findings) == 0 {
+		result.WriteString("No drift found.\n")
+		return mcp.NewToolResponseText(result.String()), nil
+	}
+
+	result.WriteString(fmt.Sprintf("Drift (%d findings):\n", len(report.Findings)))
+	for _, t := range model.DriftTypes {
+		if report.Summary[t] == 0 {
+			continue
+		}
+		result.WriteString(fmt.Sprintf("\n%s (%d):\n", t, report.Summary[t]))
+		for _, f := range report.Findings {
+			if f.Type != t {
+				continue
+			}
+			result.WriteString("  - " + f.Message)
+			if f.DeviceID != "" {
+				result.WriteString(fmt.Sprintf(" [device %s]", f.DeviceID))
+			}
+			if f.DiscoveredID != "" {
+				result.WriteString(fmt.Sprintf(" [discovered %s]", f.DiscoveredID))
+			}
+			result.WriteString("\n")
+		}
+	}
+	return mcp.NewToolResponseText(result.String()), nil
+}
@@ -0,0 +1,34 @@
+package model
+
+// HardwareAsset holds the procurement and support data of a physical device
+type HardwareAsset struct {
+	SerialNumber    string  `json:"serial_number,omitempty"` // Unique across devices
+	AssetTag        string  `json:"asset_tag,omitempty"`     // Unique across devices
+	Vendor          string  `json:"vendor,omitempty"`
+	PurchaseDate    string  `json:"purchase_date,omitempty"` // YYYY-MM-DD
+	PONumber        string  `json:"po_number,omitempty"`
+	Cost            float64 `json:"cost,omitempty"`
+	WarrantyExpires string  `json:"warranty_expires,omitempty"` // YYYY-MM-DD
+	SupportContract string  `json:"support_contract,omitempty"`
+}
+
+// IsZero reports whether no asset data is set
+func (a HardwareAsset) IsZero() bool {
+	return a == HardwareAsset{}
+}
+
+// DeviceAsset is a hardware asset together with the device it belongs to, as used in reports and exports
+type DeviceAsset struct {
+	DeviceID     string       `json:"device_id"`
+	DeviceName   string       `json:"device_name"`
+	DatacenterID string       `json:"datacenter_id,omitempty"`
+	Status       DeviceStatus `json:"status"`
+	HardwareAsset
+	WarrantyDaysLeft *int `json:"warranty_days_left,omitempty"` // Negative once the warranty has expired
+}
+
+// AssetFilter holds filter criteria for listing hardware assets
+type AssetFilter struct {
+	WarrantyExpiresAfter  string // Only assets whose warranty expires on or after this date (YYYY-MM-DD)
+	WarrantyExpiresBefore string // Only assets whose warranty expires on or before this date (YYYY-MM-DD)
+}
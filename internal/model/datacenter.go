@@ -4,15 +4,18 @@ import "time"
 
 // Datacenter represents a data center location
 type Datacenter struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Location    string    `json:"location,omitempty"`
-	Description string    `json:"description,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Location     string            `json:"location,omitempty"`
+	Description  string            `json:"description,omitempty"`
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	Version      int               `json:"version"` // Revision, see Device.Version
 }
 
 // DatacenterFilter holds filter criteria for listing datacenters
 type DatacenterFilter struct {
-	Name string // Filter by name (partial match)
+	Name         string            // Filter by name (partial match)
+	CustomFields map[string]string // Filter by custom field values (AND logic, exact match)
 }
@@ -52,7 +52,8 @@ type ServiceInfo struct {
 type DiscoveryScan struct {
 	ID              string    `json:"id"`
 	NetworkID       string    `json:"network_id"`
-	Status          string    `json:"status"` // pending, running, completed, failed, cancelled
+	RuleID          string    `json:"rule_id,omitempty"` // the rule a scheduled scan ran for
+	Status          string    `json:"status"` // pending, running, completed, failed, cancelled, interrupted
 
 	// Scan configuration
 	ScanType        string    `json:"scan_type"` // quick, full, deep
@@ -71,19 +72,75 @@ type DiscoveryScan struct {
 
 	// Results
 	ErrorMessage    string    `json:"error_message,omitempty"`
+	Changes         *DiscoveryChangeSummary `json:"changes,omitempty"` // set when the scan completes
 
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
 
+// DiscoveryObservation is what a scan saw on one host. Unlike the discovered
+// device, which holds the latest state of an IP, observations are kept per scan.
+type DiscoveryObservation struct {
+	ScanID          string    `json:"scan_id"`
+	IP              string    `json:"ip"`
+	Status          string    `json:"status"`
+	MACAddress      string    `json:"mac_address,omitempty"`
+	Hostname        string    `json:"hostname,omitempty"`
+	OSGuess         string    `json:"os_guess,omitempty"`
+	OpenPorts       []int     `json:"open_ports,omitempty"`
+	ObservedAt      time.Time `json:"observed_at"`
+}
+
+// DiscoveryChangeSummary counts the changes between a scan and the previous
+// completed scan of its network
+type DiscoveryChangeSummary struct {
+	PreviousScanID   string `json:"previous_scan_id,omitempty"` // empty for the first scan
+	NewHosts         int    `json:"new_hosts"`
+	DisappearedHosts int    `json:"disappeared_hosts"`
+	ChangedHosts     int    `json:"changed_hosts"`
+	OpenedPorts      int    `json:"opened_ports"`
+	ClosedPorts      int    `json:"closed_ports"`
+}
+
+// DiscoveryScanDiff lists the changes between a scan and the previous
+// completed scan of its network
+type DiscoveryScanDiff struct {
+	ScanID           string                 `json:"scan_id"`
+	NetworkID        string                 `json:"network_id"`
+	Summary          DiscoveryChangeSummary `json:"summary"`
+	NewHosts         []DiscoveryObservation `json:"new_hosts"`
+	DisappearedHosts []DiscoveryObservation `json:"disappeared_hosts"`
+	ChangedHosts     []DiscoveryHostChange  `json:"changed_hosts"`
+}
+
+// DiscoveryHostChange describes how a host seen by both scans changed
+type DiscoveryHostChange struct {
+	IP          string                 `json:"ip"`
+	Hostname    string                 `json:"hostname,omitempty"`
+	OpenedPorts []int                  `json:"opened_ports,omitempty"`
+	ClosedPorts []int                  `json:"closed_ports,omitempty"`
+	Fields      []DiscoveryFieldChange `json:"fields,omitempty"`
+}
+
+// DiscoveryFieldChange is a host attribute with a different value than in the previous scan
+type DiscoveryFieldChange struct {
+	Field    string `json:"field"` // mac_address, hostname, os_guess
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
 // DiscoveryRule represents scan configuration for a network
 type DiscoveryRule struct {
 	ID                  string    `json:"id"`
 	NetworkID           string    `json:"network_id"`
 	Enabled             bool      `json:"enabled"`
 
-	// Schedule
+	// Schedule: a cron expression takes precedence over the interval
 	ScanIntervalHours   int       `json:"scan_interval_hours"`
+	Schedule            string    `json:"schedule,omitempty"`
+	Timezone            string    `json:"timezone,omitempty"` // IANA name, server local time if empty
+	JitterMinutes       int       `json:"jitter_minutes,omitempty"`
+	Blackouts           []DiscoveryBlackout `json:"blackouts,omitempty"`
 	ScanType            string    `json:"scan_type"`
 
 	// Limits
@@ -99,14 +156,32 @@ type DiscoveryRule struct {
 	ServiceDetection    bool      `json:"service_detection"`
 	OSDetection         bool      `json:"os_detection"`
 
+	// Probes turns individual scan probes on or off by name; unlisted probes run
+	Probes              map[string]bool `json:"probes,omitempty"`
+
 	// Exclusions
 	ExcludeIPs          []string  `json:"exclude_ips,omitempty"`
 	ExcludeHosts        []string  `json:"exclude_hosts,omitempty"`
 
+	// Maintained by the scheduler, ignored on create and update
+	LastRunAt           *time.Time `json:"last_run_at,omitempty"`
+	NextRunAt           *time.Time `json:"next_run_at,omitempty"`
+
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 }
 
+// DiscoveryBlackout is a window in which a discovery rule does not scan.
+// Recurring windows start at every match of Schedule and last DurationMinutes;
+// one-off windows run from Start to End.
+type DiscoveryBlackout struct {
+	Reason          string     `json:"reason,omitempty"`
+	Schedule        string     `json:"schedule,omitempty"`
+	DurationMinutes int        `json:"duration_minutes,omitempty"`
+	Start           *time.Time `json:"start,omitempty"`
+	End             *time.Time `json:"end,omitempty"`
+}
+
 // DiscoveredDeviceFilter holds filter criteria for listing discovered devices
 type DiscoveredDeviceFilter struct {
 	NetworkID     string
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// ExportVersion is the version of the export format written by this release.
+// Restores accept exports up to this version.
+const ExportVersion = 1
+
+// Export is a complete, self-contained copy of the inventory used for
+// backups and for cloning an environment
+type Export struct {
+	Version           int                     `json:"version"`
+	ExportedAt        time.Time               `json:"exported_at"`
+	CustomFields      []CustomFieldDefinition `json:"custom_fields"`
+	Datacenters       []Datacenter            `json:"datacenters"`
+	Networks          []Network               `json:"networks"`
+	Pools             []NetworkPool           `json:"pools"`
+	Devices           []Device                `json:"devices"`
+	Relationships     []DeviceRelationship    `json:"relationships"`
+	Software          []SoftwareInventory     `json:"software"`
+	DiscoveryRules    []DiscoveryRule         `json:"discovery_rules"`
+	PromotionPolicies []PromotionPolicy       `json:"promotion_policies"`
+	DiscoveredDevices []DiscoveredDevice      `json:"discovered_devices"`
+}
+
+// RestoreResult reports how many entities a restore wrote
+type RestoreResult struct {
+	Version           int `json:"version"`
+	CustomFields      int `json:"custom_fields"`
+	Datacenters       int `json:"datacenters"`
+	Networks          int `json:"networks"`
+	Pools             int `json:"pools"`
+	Devices           int `json:"devices"`
+	Relationships     int `json:"relationships"`
+	Software          int `json:"software"`
+	DiscoveryRules    int `json:"discovery_rules"`
+	PromotionPolicies int `json:"promotion_policies"`
+	DiscoveredDevices int `json:"discovered_devices"`
+}
@@ -0,0 +1,78 @@
+package model
+
+// ImportRecord is one row of an import, keyed by the JSON field names of the
+// target entity. References to other entities may be given by name, e.g.
+// "datacenter" instead of "datacenter_id".
+type ImportRecord map[string]interface{}
+
+// ImportBatch holds the rows of a bulk import, grouped by entity type.
+// Sections are applied in field order so later sections can refer to
+// entities created by earlier ones.
+type ImportBatch struct {
+	Datacenters   []ImportRecord `json:"datacenters,omitempty"`
+	Networks      []ImportRecord `json:"networks,omitempty"`
+	Pools         []ImportRecord `json:"pools,omitempty"`
+	Devices       []ImportRecord `json:"devices,omitempty"`
+	Relationships []ImportRecord `json:"relationships,omitempty"`
+}
+
+// Len returns the total number of rows in the batch
+func (b *ImportBatch) Len() int {
+	return len(b.Datacenters) + len(b.Networks) + len(b.Pools) + len(b.Devices) + len(b.Relationships)
+}
+
+// Import entity types
+const (
+	ImportEntityDatacenter   = "datacenter"
+	ImportEntityNetwork      = "network"
+	ImportEntityPool         = "pool"
+	ImportEntityDevice       = "device"
+	ImportEntityRelationship = "relationship"
+)
+
+// ImportAction describes what an import did (or would do) with a row
+type ImportAction string
+
+const (
+	ImportActionCreate    ImportAction = "create"
+	ImportActionUpdate    ImportAction = "update"
+	ImportActionUnchanged ImportAction = "unchanged"
+	ImportActionError     ImportAction = "error"
+)
+
+// ImportRowResult is the outcome of importing a single row
+type ImportRowResult struct {
+	Entity string       `json:"entity"`
+	Row    int          `json:"row"` // 1-based position within the entity section
+	Name   string       `json:"name,omitempty"`
+	ID     string       `json:"id,omitempty"`
+	Action ImportAction `json:"action"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// ImportResult summarises a bulk import. Changes are only committed when
+// the import is not a dry run and no row failed.
+type ImportResult struct {
+	DryRun    bool              `json:"dry_run"`
+	Committed bool              `json:"committed"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+// Add records a row result and updates the counters
+func (r *ImportResult) Add(row ImportRowResult) {
+	switch row.Action {
+	case ImportActionCreate:
+		r.Created++
+	case ImportActionUpdate:
+		r.Updated++
+	case ImportActionUnchanged:
+		r.Unchanged++
+	case ImportActionError:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, row)
+}
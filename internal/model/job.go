@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job types run by the background job runner
+const (
+	JobTypeDiscovery = "discovery"
+	JobTypeCleanup   = "cleanup"
+	JobTypeBackup    = "backup"
+	JobTypeReport    = "report"
+	JobTypeImport    = "import"
+)
+
+// Job statuses
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// Job is a unit of background work, kept after it finishes as job history
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Status      string          `json:"status"`
+	Params      json.RawMessage `json:"params,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Logs        []JobLogEntry   `json:"logs,omitempty"`
+	RunAt       time.Time       `json:"run_at"` // When a pending job is due, later than its creation while it backs off after a failure
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// JobLogEntry is a line of a job's log
+type JobLogEntry struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// JobFilter selects jobs, newest first
+type JobFilter struct {
+	Type   string
+	Status string
+	Limit  int // 0 for all
+}
@@ -0,0 +1,16 @@
+package model
+
+// LabelMigration reports the result of converting key=value tags into labels
+type LabelMigration struct {
+	DryRun  bool                   `json:"dry_run"`
+	Devices []LabelMigrationChange `json:"devices"`
+	Pools   []LabelMigrationChange `json:"pools"`
+}
+
+// LabelMigrationChange describes the labels created from the tags of one entity
+type LabelMigrationChange struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Labels  map[string]string `json:"labels"`            // Labels added from tags
+	Skipped []string          `json:"skipped,omitempty"` // key=value tags kept because their key is already set
+}
@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// PromotionPolicy promotes the discovered devices matching its conditions to
+// inventory devices, created from its template. Policies run after each scan.
+type PromotionPolicy struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Enabled     bool              `json:"enabled"`
+	Priority    int               `json:"priority"` // Lower runs first; a device is promoted by the first policy it matches
+	Match       PromotionMatch    `json:"match"`
+	Template    PromotionTemplate `json:"template"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// PromotionMatch holds the conditions a discovered device must all meet
+type PromotionMatch struct {
+	NetworkID       string `json:"network_id,omitempty"`
+	MinConfidence   int    `json:"min_confidence,omitempty"`
+	OpenPorts       []int  `json:"open_ports,omitempty"`       // All of these ports must be open
+	HostnamePattern string `json:"hostname_pattern,omitempty"` // Regular expression the hostname must match
+	OSFamily        string `json:"os_family,omitempty"`        // Case-insensitive
+}
+
+// PromotionTemplate describes the device created for a matching discovered device
+type PromotionTemplate struct {
+	NamePattern   string                  `json:"name_pattern,omitempty"` // e.g. "{short_hostname}", the default
+	Description   string                  `json:"description,omitempty"`
+	MakeModel     string                  `json:"make_model,omitempty"`
+	DatacenterID  string                  `json:"datacenter_id,omitempty"`
+	Location      string                  `json:"location,omitempty"`
+	Tags          []string                `json:"tags,omitempty"`
+	Domains       []string                `json:"domains,omitempty"`
+	Relationships []PromotionRelationship `json:"relationships,omitempty"`
+}
+
+// PromotionRelationship relates a promoted device to an existing device
+type PromotionRelationship struct {
+	DeviceID string `json:"device_id"`
+	Type     string `json:"type"`                // e.g. "connected_to", "depends_on"
+	AsParent bool   `json:"as_parent,omitempty"` // The promoted device is the parent; by default it is the child
+}
+
+// PromotionCandidate is a discovered device matched by a policy, with the
+// device it is, or would be, promoted to
+type PromotionCandidate struct {
+	DiscoveredID  string                  `json:"discovered_id"`
+	IP            string                  `json:"ip"`
+	Hostname      string                  `json:"hostname,omitempty"`
+	NetworkID     string                  `json:"network_id"`
+	PolicyID      string                  `json:"policy_id"`
+	PolicyName    string                  `json:"policy_name"`
+	Device        PromoteDeviceRequest    `json:"device"`
+	Relationships []PromotionRelationship `json:"relationships,omitempty"`
+	DeviceID      string                  `json:"device_id,omitempty"` // Created device, not set in a dry run
+	Skipped       string                  `json:"skipped,omitempty"`   // Why the device is not promoted
+	Error         string                  `json:"error,omitempty"`     // Why promoting the device failed
+}
+
+// PromotionRun is the result of running the promotion policies, or of previewing them
+type PromotionRun struct {
+	DryRun     bool                 `json:"dry_run"`
+	NetworkID  string               `json:"network_id,omitempty"`
+	Promoted   int                  `json:"promoted"` // Devices promoted, or that would be in a dry run
+	Skipped    int                  `json:"skipped"`
+	Failed     int                  `json:"failed"`
+	Candidates []PromotionCandidate `json:"candidates"`
+}
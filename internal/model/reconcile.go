@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// Drift types reported by reconciliation
+const (
+	DriftMACMismatch      = "mac_mismatch"      // A recorded IP answered with a different MAC address
+	DriftHostnameMismatch = "hostname_mismatch" // A recorded IP resolved to a name that does not match its device
+	DriftNotSeen          = "not_seen"          // An active device was not online in the last scans of its network
+	DriftUnrecorded       = "unrecorded"        // A live IP is not recorded on any device
+	DriftAddressMoved     = "address_moved"     // A known device was seen on an IP that is not recorded for it
+)
+
+// DriftTypes lists every drift type in report order
+var DriftTypes = []string{
+	DriftMACMismatch,
+	DriftHostnameMismatch,
+	DriftAddressMoved,
+	DriftUnrecorded,
+	DriftNotSeen,
+}
+
+// ReconcileFinding is a single difference between discovery and inventory
+type ReconcileFinding struct {
+	Type         string `json:"type"`
+	IP           string `json:"ip,omitempty"`
+	NetworkID    string `json:"network_id,omitempty"`
+	DeviceID     string `json:"device_id,omitempty"`
+	DeviceName   string `json:"device_name,omitempty"`
+	DiscoveredID string `json:"discovered_id,omitempty"`
+	MatchedBy    string `json:"matched_by,omitempty"` // How a moved address was tied to its device: mac_address, promotion or hostname
+	Expected     string `json:"expected,omitempty"`   // Value recorded in inventory
+	Observed     string `json:"observed,omitempty"`   // Value seen by discovery
+	Message      string `json:"message"`
+}
+
+// ReconcileReport is the result of reconciling discovered hosts with inventory devices
+type ReconcileReport struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	NetworkID   string             `json:"network_id,omitempty"`
+	MissedScans int                `json:"missed_scans"` // Consecutive scans a device must be missing from to be not_seen
+	Matched     int                `json:"matched"`      // Live hosts whose IP is recorded on a device
+	Summary     map[string]int     `json:"summary"`      // Number of findings by drift type
+	Findings    []ReconcileFinding `json:"findings"`
+}
+
+// ReconcileOptions holds the parameters of a reconciliation run
+type ReconcileOptions struct {
+	NetworkID   string // Limit to one network, empty for all
+	MissedScans int    // Scans a device must be missing from to be reported, 0 for the default
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// OSInfo describes the operating system of a device as reported by inventory ingest
+type OSInfo struct {
+	Family       string `json:"family,omitempty"`       // e.g. "linux", "windows"
+	Distribution string `json:"distribution,omitempty"` // e.g. "ubuntu", "rhel"
+	Version      string `json:"version,omitempty"`      // e.g. "22.04"
+	Kernel       string `json:"kernel,omitempty"`       // e.g. "5.15.0-91-generic"
+}
+
+// SoftwarePackage is an installed software package
+type SoftwarePackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// SoftwareInventory is the OS and package inventory of a device.
+// Each push replaces the previous inventory of the device.
+type SoftwareInventory struct {
+	DeviceID    string            `json:"device_id"`
+	OS          *OSInfo           `json:"os,omitempty"`
+	Packages    []SoftwarePackage `json:"packages"`
+	CollectedAt *time.Time        `json:"collected_at,omitempty"` // When the inventory was last pushed, nil if never
+}
+
+// PackageInstall is a package installed on a device, as returned by fleet-wide package queries
+type PackageInstall struct {
+	DeviceID   string `json:"device_id"`
+	DeviceName string `json:"device_name"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+}
+
+// PackageFilter holds criteria for querying installed packages across devices
+type PackageFilter struct {
+	Name    string // Package name, matched case-insensitively
+	Version string // Version constraint, e.g. "<3.0.8" or ">=3.0.0,<3.0.8"
+}
@@ -0,0 +1,90 @@
+package model
+
+// Topology node types
+const (
+	TopologyNodeDatacenter = "datacenter"
+	TopologyNodeNetwork    = "network"
+	TopologyNodeDevice     = "device"
+)
+
+// Structural topology edge types (relationship edges use the relationship type)
+const (
+	TopologyEdgeLocatedIn = "located_in"  // device or network -> datacenter
+	TopologyEdgeAttached  = "attached_to" // device -> network
+)
+
+// TopologyNode is a single vertex in the topology graph
+type TopologyNode struct {
+	ID         string            `json:"id"` // "<type>:<entity id>", unique across node types
+	Type       string            `json:"type"`
+	EntityID   string            `json:"entity_id"`
+	Label      string            `json:"label"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// TopologyEdge is a directed edge between two topology nodes
+type TopologyEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// Topology is a graph of datacenters, networks, devices and their relationships
+type Topology struct {
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"links"` // "links" matches the d3-force convention
+}
+
+// TopologyFilter holds filter criteria for building a topology
+type TopologyFilter struct {
+	DatacenterID      string   // Only include this datacenter and what it contains
+	Tags              []string // Only include devices carrying all of these tags
+	RelationshipTypes []string // Only include relationship edges of these types
+}
+
+// Topology layout grouping modes
+const (
+	TopologyGroupByDatacenter = "datacenter"
+	TopologyGroupByNetwork    = "network"
+)
+
+// TopologyLayout is a pre-positioned device graph for rendering in the UI.
+// Devices are clustered into groups and parallel relationships between the
+// same pair of devices (or groups) are collapsed into a single counted edge.
+type TopologyLayout struct {
+	GroupBy    string               `json:"group_by"`
+	Width      float64              `json:"width"`
+	Height     float64              `json:"height"`
+	Groups     []TopologyGroup      `json:"groups"`
+	Nodes      []TopologyLayoutNode `json:"nodes"`
+	Edges      []TopologyLayoutEdge `json:"edges"`
+	GroupEdges []TopologyLayoutEdge `json:"group_edges"`
+}
+
+// TopologyGroup is a circular cluster of devices sharing a datacenter or network
+type TopologyGroup struct {
+	ID        string  `json:"id"`
+	Label     string  `json:"label"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Radius    float64 `json:"radius"`
+	NodeCount int     `json:"node_count"`
+}
+
+// TopologyLayoutNode is a positioned device
+type TopologyLayoutNode struct {
+	ID     string  `json:"id"` // Device ID
+	Label  string  `json:"label"`
+	Group  string  `json:"group"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Degree int     `json:"degree"`
+}
+
+// TopologyLayoutEdge is an aggregated, undirected edge between two devices or groups
+type TopologyLayoutEdge struct {
+	Source string         `json:"source"`
+	Target string         `json:"target"`
+	Count  int            `json:"count"`
+	Types  map[string]int `json:"types,omitempty"`
+}
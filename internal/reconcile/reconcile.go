@@ -0,0 +1,451 @@
+// Package reconcile compares the hosts found by discovery with the devices recorded
+// in inventory and reports where they drifted apart.
+//
+// Both sides of the comparison come from the observations of completed scans, not
+// from the discovered devices, which keep every host ever seen. A host online in
+// the latest completed scan of its network is live. A live host whose IP is
+// recorded on a device is matched to that device, and a different MAC address or
+// hostname is reported. A live host on an unrecorded IP is tied to a device by MAC
+// address, by an earlier promotion or by a unique hostname match and reported as a
+// moved address, or otherwise as unrecorded. Active devices that were not online
+// in any of the last completed scans of their network are reported as not seen;
+// networks with fewer scans than that are skipped, so a single failed scan does
+// not flag a whole subnet.
+package reconcile
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// DefaultMissedScans is the number of consecutive scans an active device must be
+// missing from before it is reported as not seen
+const DefaultMissedScans = 3
+
+// statusOnline is the status of a host that answered the scan
+const statusOnline = "online"
+
+// Store is the storage reconciliation reads inventory and discovery results from
+type Store interface {
+	ListDevices(filter *model.DeviceFilter) ([]model.Device, error)
+	ListNetworks(filter *model.NetworkFilter) ([]model.Network, error)
+	ListDiscoveredDevices(filter *model.DiscoveredDeviceFilter) ([]model.DiscoveredDevice, error)
+	ListDiscoveryScans(networkID string) ([]model.DiscoveryScan, error)
+	ListDiscoveryObservations(scanID string) ([]model.DiscoveryObservation, error)
+}
+
+// recorded is a device address
+type recorded struct {
+	device  *model.Device
+	address model.Address
+}
+
+// networkIP is an IP within a network; networks may use the same private ranges
+type networkIP struct {
+	networkID string
+	ip        string
+}
+
+// inventory indexes the devices by the IPs and MAC addresses recorded on them
+type inventory struct {
+	devices  []model.Device
+	networks []model.Network
+	byIP     map[networkIP]recorded
+	byMAC    map[string][]*model.Device
+	byID     map[string]*model.Device
+}
+
+// Run reconciles the discovered hosts of one network, or all networks when
+// opts.NetworkID is empty, with the inventory
+func Run(store Store, opts model.ReconcileOptions) (*model.ReconcileReport, error) {
+	missed := opts.MissedScans
+	if missed <= 0 {
+		missed = DefaultMissedScans
+	}
+
+	networks, err := store.ListNetworks(nil)
+	if err != nil {
+		return nil, fmt.Errorf("listing networks: %w", err)
+	}
+	devices, err := store.ListDevices(nil)
+	if err != nil {
+		return nil, fmt.Errorf("listing devices: %w", err)
+	}
+	inv := index(devices, networks)
+
+	report := &model.ReconcileReport{
+		GeneratedAt: time.Now().UTC(),
+		NetworkID:   opts.NetworkID,
+		MissedScans: missed,
+		Summary:     make(map[string]int, len(model.DriftTypes)),
+		Findings:    []model.ReconcileFinding{},
+	}
+
+	for _, network := range networks {
+		if opts.NetworkID != "" && network.ID != opts.NetworkID {
+			continue
+		}
+		scans, err := observedScans(store, network.ID, missed)
+		if err != nil {
+			return nil, err
+		}
+		if len(scans) == 0 {
+			continue
+		}
+
+		hosts, err := liveHosts(store, network.ID, scans[0])
+		if err != nil {
+			return nil, err
+		}
+		for i := range hosts {
+			report.Findings = append(report.Findings, inv.checkHost(&hosts[i], report)...)
+		}
+		if len(scans) == missed {
+			report.Findings = append(report.Findings, inv.notSeen(network, scans, missed)...)
+		}
+	}
+
+	sortFindings(report.Findings)
+	for _, t := range model.DriftTypes {
+		report.Summary[t] = 0
+	}
+	for _, f := range report.Findings {
+		report.Summary[f.Type]++
+	}
+	return report, nil
+}
+
+// index builds the lookups of the inventory. An address without a network is
+// placed in the network whose subnet contains it.
+func index(devices []model.Device, networks []model.Network) *inventory {
+	inv := &inventory{
+		devices:  devices,
+		networks: networks,
+		byIP:     make(map[networkIP]recorded),
+		byMAC:    make(map[string][]*model.Device),
+		byID:     make(map[string]*model.Device, len(devices)),
+	}
+	for i := range devices {
+		d := &devices[i]
+		inv.byID[d.ID] = d
+		for _, addr := range d.Addresses {
+			key := networkIP{inv.addressNetwork(addr), addr.IP}
+			if _, ok := inv.byIP[key]; !ok {
+				inv.byIP[key] = recorded{device: d, address: addr}
+			}
+			if mac := normalizeMAC(addr.MACAddress); mac != "" && !contains(inv.byMAC[mac], d) {
+				inv.byMAC[mac] = append(inv.byMAC[mac], d)
+			}
+		}
+	}
+	return inv
+}
+
+// addressNetwork returns the network of an address, or of the subnet containing it
+func (inv *inventory) addressNetwork(addr model.Address) string {
+	if addr.NetworkID != "" {
+		return addr.NetworkID
+	}
+	return containingNetwork(addr.IP, inv.networks)
+}
+
+// checkHost returns the drift of a live discovered host
+func (inv *inventory) checkHost(host *model.DiscoveredDevice, report *model.ReconcileReport) []model.ReconcileFinding {
+	finding := model.ReconcileFinding{
+		IP:           host.IP,
+		NetworkID:    host.NetworkID,
+		DiscoveredID: host.ID,
+	}
+
+	if rec, ok := inv.byIP[networkIP{host.NetworkID, host.IP}]; ok {
+		report.Matched++
+		finding.DeviceID = rec.device.ID
+		finding.DeviceName = rec.device.Name
+
+		var findings []model.ReconcileFinding
+		expected, observed := normalizeMAC(rec.address.MACAddress), normalizeMAC(host.MACAddress)
+		if expected != "" && observed != "" && expected != observed {
+			f := finding
+			f.Type = model.DriftMACMismatch
+			f.Expected = rec.address.MACAddress
+			f.Observed = host.MACAddress
+			f.Message = fmt.Sprintf("%s of %s answered with MAC %s, recorded %s", host.IP, rec.device.Name, host.MACAddress, rec.address.MACAddress)
+			findings = append(findings, f)
+		}
+		if host.Hostname != "" && !matchesHostname(rec.device, host.Hostname) {
+			f := finding
+			f.Type = model.DriftHostnameMismatch
+			f.Expected = rec.device.Name
+			f.Observed = host.Hostname
+			f.Message = fmt.Sprintf("%s of %s resolves to %s", host.IP, rec.device.Name, host.Hostname)
+			findings = append(findings, f)
+		}
+		return findings
+	}
+
+	if device, matchedBy := inv.identify(host); device != nil {
+		finding.Type = model.DriftAddressMoved
+		finding.DeviceID = device.ID
+		finding.DeviceName = device.Name
+		finding.MatchedBy = matchedBy
+		finding.Expected = strings.Join(addressIPs(device), ", ")
+		finding.Observed = host.IP
+		finding.Message = fmt.Sprintf("%s was seen on unrecorded IP %s (matched by %s)", device.Name, host.IP, matchedBy)
+		return []model.ReconcileFinding{finding}
+	}
+
+	finding.Type = model.DriftUnrecorded
+	finding.Observed = host.Hostname
+	finding.Message = fmt.Sprintf("%s is live but not recorded on any device", host.IP)
+	if host.Hostname != "" {
+		finding.Message = fmt.Sprintf("%s (%s) is live but not recorded on any device", host.IP, host.Hostname)
+	}
+	return []model.ReconcileFinding{finding}
+}
+
+// identify ties a host on an unrecorded IP to a device by MAC address, promotion
+// or hostname, in that order. Ambiguous matches identify nothing.
+func (inv *inventory) identify(host *model.DiscoveredDevice) (*model.Device, string) {
+	if mac := normalizeMAC(host.MACAddress); mac != "" {
+		if devices := inv.byMAC[mac]; len(devices) == 1 {
+			return devices[0], "mac_address"
+		}
+	}
+	if host.PromotedToDeviceID != "" {
+		if device, ok := inv.byID[host.PromotedToDeviceID]; ok {
+			return device, "promotion"
+		}
+	}
+	if host.Hostname != "" {
+		var match *model.Device
+		for i := range inv.devices {
+			if !matchesHostname(&inv.devices[i], host.Hostname) {
+				continue
+			}
+			if match != nil {
+				return nil, ""
+			}
+			match = &inv.devices[i]
+		}
+		if match != nil {
+			return match, "hostname"
+		}
+	}
+	return nil, ""
+}
+
+// observedScans returns the observations of up to limit of the latest completed
+// scans of a network, newest first
+func observedScans(store Store, networkID string, limit int) ([][]model.DiscoveryObservation, error) {
+	scans, err := store.ListDiscoveryScans(networkID)
+	if err != nil {
+		return nil, fmt.Errorf("listing scans of network %s: %w", networkID, err)
+	}
+	sort.Slice(scans, func(i, j int) bool { return scans[i].CreatedAt.After(scans[j].CreatedAt) })
+
+	// Scans by scanners that do not record observations say nothing about presence
+	var observed [][]model.DiscoveryObservation
+	for _, scan := range scans {
+		if len(observed) == limit {
+			break
+		}
+		if scan.Status != "completed" {
+			continue
+		}
+		observations, err := store.ListDiscoveryObservations(scan.ID)
+		if err != nil {
+			return nil, fmt.Errorf("listing observations of scan %s: %w", scan.ID, err)
+		}
+		if len(observations) > 0 {
+			observed = append(observed, observations)
+		}
+	}
+	return observed, nil
+}
+
+// liveHosts returns the hosts online in the observations of a scan of a
+// network. A host carries what the scan saw, along with the ID and promotion of
+// the discovered device of its IP.
+func liveHosts(store Store, networkID string, observations []model.DiscoveryObservation) ([]model.DiscoveredDevice, error) {
+	discovered, err := store.ListDiscoveredDevices(&model.DiscoveredDeviceFilter{NetworkID: networkID})
+	if err != nil {
+		return nil, fmt.Errorf("listing discovered devices: %w", err)
+	}
+	byIP := make(map[string]model.DiscoveredDevice, len(discovered))
+	for _, d := range discovered {
+		byIP[d.IP] = d
+	}
+
+	var hosts []model.DiscoveredDevice
+	for _, obs := range observations {
+		if obs.Status != statusOnline {
+			continue
+		}
+		host := byIP[obs.IP]
+		host.IP = obs.IP
+		host.NetworkID = networkID
+		host.Status = obs.Status
+		host.MACAddress = obs.MACAddress
+		host.Hostname = obs.Hostname
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// notSeen reports the active devices of network that were not online in any of
+// the observed scans
+func (inv *inventory) notSeen(network model.Network, scans [][]model.DiscoveryObservation, missed int) []model.ReconcileFinding {
+	seen := make(map[string]bool)
+	for _, observations := range scans {
+		for _, obs := range observations {
+			if obs.Status == statusOnline {
+				seen[obs.IP] = true
+			}
+		}
+	}
+
+	var findings []model.ReconcileFinding
+	for i := range inv.devices {
+		d := &inv.devices[i]
+		if d.Status != model.DeviceStatusActive && d.Status != "" {
+			continue
+		}
+		var ips []string
+		online := false
+		for _, addr := range d.Addresses {
+			if inv.addressNetwork(addr) != network.ID {
+				continue
+			}
+			ips = append(ips, addr.IP)
+			online = online || seen[addr.IP]
+		}
+		if len(ips) == 0 || online {
+			continue
+		}
+		findings = append(findings, model.ReconcileFinding{
+			Type:       model.DriftNotSeen,
+			IP:         ips[0],
+			NetworkID:  network.ID,
+			DeviceID:   d.ID,
+			DeviceName: d.Name,
+			Expected:   strings.Join(ips, ", "),
+			Message:    fmt.Sprintf("%s was not seen in the last %d scans of %s", d.Name, missed, network.Name),
+		})
+	}
+	return findings
+}
+
+// matchesHostname reports whether hostname names device: its name, one of its
+// domains, or its name within one of its domains. Comparison ignores case and a
+// trailing dot, and a bare name matches the first label of a qualified one.
+func matchesHostname(device *model.Device, hostname string) bool {
+	host := normalizeName(hostname)
+	if host == "" {
+		return false
+	}
+	name := normalizeName(device.Name)
+	candidates := []string{name}
+	for _, domain := range device.Domains {
+		domain = normalizeName(domain)
+		candidates = append(candidates, domain)
+		if name != "" && domain != "" {
+			candidates = append(candidates, name+"."+domain)
+		}
+	}
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		if c == host || firstLabel(c) == host || c == firstLabel(host) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+func firstLabel(name string) string {
+	label, _, _ := strings.Cut(name, ".")
+	return label
+}
+
+// normalizeMAC returns mac in canonical form, or lower-cased as is when it does
+// not parse, so differently formatted addresses of one interface compare equal
+func normalizeMAC(mac string) string {
+	mac = strings.TrimSpace(mac)
+	if mac == "" {
+		return ""
+	}
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+	return strings.ToLower(mac)
+}
+
+// containingNetwork returns the network whose subnet contains ip, or "" if none
+func containingNetwork(ip string, networks []model.Network) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	for _, n := range networks {
+		prefix, err := netip.ParsePrefix(n.Subnet)
+		if err == nil && prefix.Contains(addr) {
+			return n.ID
+		}
+	}
+	return ""
+}
+
+func addressIPs(device *model.Device) []string {
+	ips := make([]string, 0, len(device.Addresses))
+	for _, addr := range device.Addresses {
+		ips = append(ips, addr.IP)
+	}
+	return ips
+}
+
+func contains(devices []*model.Device, device *model.Device) bool {
+	for _, d := range devices {
+		if d == device {
+			return true
+		}
+	}
+	return false
+}
+
+// sortFindings orders findings by drift type, then address, then device
+func sortFindings(findings []model.ReconcileFinding) {
+	rank := make(map[string]int, len(model.DriftTypes))
+	for i, t := range model.DriftTypes {
+		rank[t] = i
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Type != b.Type {
+			return rank[a.Type] < rank[b.Type]
+		}
+		if a.IP != b.IP {
+			return lessIP(a.IP, b.IP)
+		}
+		return a.DeviceName < b.DeviceName
+	})
+}
+
+func lessIP(a, b string) bool {
+	x, errA := netip.ParseAddr(a)
+	y, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return x.Less(y)
+}
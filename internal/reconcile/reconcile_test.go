@@ -0,0 +1,234 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// memStore holds inventory and discovery results in memory
+type memStore struct {
+	devices      []model.Device
+	networks     []model.Network
+	discovered   []model.DiscoveredDevice
+	scans        []model.DiscoveryScan
+	observations map[string][]model.DiscoveryObservation
+}
+
+func (s *memStore) ListDevices(filter *model.DeviceFilter) ([]model.Device, error) {
+	return append([]model.Device(nil), s.devices...), nil
+}
+
+func (s *memStore) ListNetworks(filter *model.NetworkFilter) ([]model.Network, error) {
+	return s.networks, nil
+}
+
+func (s *memStore) ListDiscoveredDevices(filter *model.DiscoveredDeviceFilter) ([]model.DiscoveredDevice, error) {
+	var devices []model.DiscoveredDevice
+	for _, d := range s.discovered {
+		if filter.NetworkID != "" && d.NetworkID != filter.NetworkID {
+			continue
+		}
+		if filter.Status != "" && d.Status != filter.Status {
+			continue
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+func (s *memStore) ListDiscoveryScans(networkID string) ([]model.DiscoveryScan, error) {
+	var scans []model.DiscoveryScan
+	for _, scan := range s.scans {
+		if scan.NetworkID == networkID {
+			scans = append(scans, scan)
+		}
+	}
+	return scans, nil
+}
+
+func (s *memStore) ListDiscoveryObservations(scanID string) ([]model.DiscoveryObservation, error) {
+	return s.observations[scanID], nil
+}
+
+func newStore() *memStore {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &memStore{
+		networks: []model.Network{{ID: "net-1", Name: "lan", Subnet: "10.0.0.0/24"}},
+		devices: []model.Device{
+			{ID: "dev-db1", Name: "db1", Status: model.DeviceStatusActive, Domains: []string{"example.com"},
+				Addresses: []model.Address{{IP: "10.0.0.5", MACAddress: "aa:bb:cc:dd:ee:01"}}},
+			{ID: "dev-web1", Name: "web1", Status: model.DeviceStatusActive,
+				Addresses: []model.Address{{IP: "10.0.0.6", NetworkID: "net-1"}}},
+			{ID: "dev-app1", Name: "app1", Status: model.DeviceStatusActive,
+				Addresses: []model.Address{{IP: "10.0.0.7", MACAddress: "AA-BB-CC-DD-EE-07"}}},
+			{ID: "dev-old", Name: "old", Status: model.DeviceStatusDecommissioned,
+				Addresses: []model.Address{{IP: "10.0.0.8"}}},
+			{ID: "dev-cache", Name: "cache", Status: model.DeviceStatusActive,
+				Addresses: []model.Address{{IP: "10.0.0.9"}}},
+			{ID: "dev-nas", Name: "nas", Status: model.DeviceStatusActive,
+				Addresses: []model.Address{{IP: "10.0.0.10"}}},
+		},
+		// The discovered devices hold the latest state of every IP ever seen;
+		// 10.0.0.30 was online once but not in the latest scan
+		discovered: []model.DiscoveredDevice{
+			{ID: "d-5", IP: "10.0.0.5", NetworkID: "net-1", Status: "online", Hostname: "db1"},
+			{ID: "d-6", IP: "10.0.0.6", NetworkID: "net-1", Status: "online"},
+			{ID: "d-20", IP: "10.0.0.20", NetworkID: "net-1", Status: "online"},
+			{ID: "d-21", IP: "10.0.0.21", NetworkID: "net-1", Status: "online"},
+			{ID: "d-22", IP: "10.0.0.22", NetworkID: "net-1", Status: "online"},
+			{ID: "d-23", IP: "10.0.0.23", NetworkID: "net-1", Status: "offline"},
+			{ID: "d-30", IP: "10.0.0.30", NetworkID: "net-1", Status: "online", Hostname: "laptop"},
+		},
+		scans: []model.DiscoveryScan{
+			{ID: "s1", NetworkID: "net-1", Status: "completed", CreatedAt: base},
+			{ID: "s2", NetworkID: "net-1", Status: "completed", CreatedAt: base.Add(time.Hour)},
+			{ID: "s3", NetworkID: "net-1", Status: "failed", CreatedAt: base.Add(2 * time.Hour)},
+			{ID: "s4", NetworkID: "net-1", Status: "completed", CreatedAt: base.Add(3 * time.Hour)},
+		},
+		observations: map[string][]model.DiscoveryObservation{
+			"s1": {{IP: "10.0.0.5", Status: "online"}, {IP: "10.0.0.6", Status: "online"}, {IP: "10.0.0.10", Status: "online"}, {IP: "10.0.0.30", Status: "online"}},
+			"s2": {{IP: "10.0.0.5", Status: "online"}, {IP: "10.0.0.9", Status: "offline"}},
+			"s4": {
+				{IP: "10.0.0.5", Status: "online", MACAddress: "aa:bb:cc:dd:ee:99", Hostname: "db1.example.com"},
+				{IP: "10.0.0.6", Status: "online", Hostname: "mail"},
+				{IP: "10.0.0.20", Status: "online", MACAddress: "aa:bb:cc:dd:ee:07"},
+				{IP: "10.0.0.21", Status: "online", Hostname: "CACHE.lan."},
+				{IP: "10.0.0.22", Status: "online", Hostname: "printer"},
+				{IP: "10.0.0.23", Status: "offline"},
+			},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	report, err := Run(newStore(), model.ReconcileOptions{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if report.MissedScans != DefaultMissedScans {
+		t.Errorf("MissedScans = %d, want %d", report.MissedScans, DefaultMissedScans)
+	}
+	if report.Matched != 2 {
+		t.Errorf("Matched = %d, want 2", report.Matched)
+	}
+
+	type key struct{ typ, ip, device, matchedBy string }
+	var got []key
+	for _, f := range report.Findings {
+		got = append(got, key{f.Type, f.IP, f.DeviceID, f.MatchedBy})
+	}
+	want := []key{
+		{model.DriftMACMismatch, "10.0.0.5", "dev-db1", ""},
+		{model.DriftHostnameMismatch, "10.0.0.6", "dev-web1", ""},
+		{model.DriftAddressMoved, "10.0.0.20", "dev-app1", "mac_address"},
+		{model.DriftAddressMoved, "10.0.0.21", "dev-cache", "hostname"},
+		{model.DriftUnrecorded, "10.0.0.22", "", ""},
+		{model.DriftNotSeen, "10.0.0.7", "dev-app1", ""},
+		{model.DriftNotSeen, "10.0.0.9", "dev-cache", ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findings = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if unrecorded := report.Findings[4]; unrecorded.DiscoveredID != "d-22" || unrecorded.Observed != "printer" {
+		t.Errorf("expected the unrecorded host to carry its discovered device, got %+v", unrecorded)
+	}
+
+	if report.Summary[model.DriftAddressMoved] != 2 || report.Summary[model.DriftNotSeen] != 2 {
+		t.Errorf("unexpected summary %v", report.Summary)
+	}
+	if n, ok := report.Summary[model.DriftMACMismatch]; !ok || n != 1 {
+		t.Errorf("expected every drift type in the summary, got %v", report.Summary)
+	}
+}
+
+func TestRunNeedsEnoughScans(t *testing.T) {
+	store := newStore()
+	report, err := Run(store, model.ReconcileOptions{NetworkID: "net-1", MissedScans: 4})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Summary[model.DriftNotSeen] != 0 {
+		t.Errorf("expected no not_seen findings with fewer scans than required, got %v", report.Findings)
+	}
+
+	// Only the latest scan counts: db1 and web1 were seen in it, nas was not
+	report, err = Run(store, model.ReconcileOptions{NetworkID: "net-1", MissedScans: 1})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	notSeen := map[string]bool{}
+	for _, f := range report.Findings {
+		if f.Type == model.DriftNotSeen {
+			notSeen[f.DeviceID] = true
+		}
+	}
+	if !notSeen["dev-nas"] || notSeen["dev-db1"] || notSeen["dev-web1"] || notSeen["dev-old"] {
+		t.Errorf("unexpected not_seen devices %v", notSeen)
+	}
+}
+
+func TestRunSameIPInNetworks(t *testing.T) {
+	store := newStore()
+	// A second network reuses the range of the first, and records the IP of
+	// the printer live on the first
+	store.networks = append(store.networks, model.Network{ID: "net-2", Name: "lab", Subnet: "10.0.0.0/24"})
+	store.devices = append(store.devices, model.Device{ID: "dev-lab", Name: "lab1", Status: model.DeviceStatusActive,
+		Addresses: []model.Address{{IP: "10.0.0.22", NetworkID: "net-2"}}})
+
+	report, err := Run(store, model.ReconcileOptions{NetworkID: "net-1"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Matched != 2 {
+		t.Errorf("Matched = %d, want 2", report.Matched)
+	}
+	found := false
+	for _, f := range report.Findings {
+		if f.IP != "10.0.0.22" {
+			continue
+		}
+		found = true
+		if f.Type != model.DriftUnrecorded || f.DeviceID != "" {
+			t.Errorf("expected 10.0.0.22 on lan to be unrecorded, got %+v", f)
+		}
+	}
+	if !found {
+		t.Errorf("expected a finding for 10.0.0.22, got %+v", report.Findings)
+	}
+}
+
+func TestMatchesHostname(t *testing.T) {
+	device := &model.Device{Name: "db1", Domains: []string{"db.example.com"}}
+	for host, want := range map[string]bool{
+		"db1":                true,
+		"DB1.corp.local":     true,
+		"db1.db.example.com": true,
+		"db.example.com.":    true,
+		"db2":                false,
+		"":                   false,
+	} {
+		if got := matchesHostname(device, host); got != want {
+			t.Errorf("matchesHostname(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestRunWithoutScans(t *testing.T) {
+	store := newStore()
+	store.scans = nil
+	report, err := Run(store, model.ReconcileOptions{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Findings) != 0 || report.Matched != 0 {
+		t.Errorf("expected no findings from discovered devices alone, got %+v", report.Findings)
+	}
+}
@@ -0,0 +1,183 @@
+// Package scandiff compares the hosts seen by consecutive discovery scans of a network.
+//
+// Scans are only compared with earlier scans of the same type, since a quick scan
+// probes no ports and a deep one probes more than a full one.
+//
+// A host is present in a scan when it was observed online. Hosts present now but
+// not in the previous scan are new, hosts present before but not now have
+// disappeared. For hosts present in both, opened and closed ports are reported, as
+// are a changed MAC address, hostname or OS guess. An attribute only counts as
+// changed when both scans saw a value, so a failed reverse lookup does not look
+// like a renamed host.
+package scandiff
+
+import (
+	"net/netip"
+	"sort"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// statusOnline is the status of a host that answered the scan
+const statusOnline = "online"
+
+// Store is the storage the comparison reads scans and their observations from
+type Store interface {
+	ListDiscoveryScans(networkID string) ([]model.DiscoveryScan, error)
+	ListDiscoveryObservations(scanID string) ([]model.DiscoveryObservation, error)
+}
+
+// ForScan compares scan with the previous completed scan of its network and type.
+// The first scan of a network and type is its baseline and has no changes.
+func ForScan(store Store, scan *model.DiscoveryScan) (*model.DiscoveryScanDiff, error) {
+	previous, err := Previous(store, scan)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return Compare(scan, nil, nil, nil), nil
+	}
+
+	current, err := store.ListDiscoveryObservations(scan.ID)
+	if err != nil {
+		return nil, err
+	}
+	before, err := store.ListDiscoveryObservations(previous.ID)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(scan, previous, current, before), nil
+}
+
+// Previous returns the latest completed scan of the network and type of scan
+// that was created before it, or nil if there is none
+func Previous(store Store, scan *model.DiscoveryScan) (*model.DiscoveryScan, error) {
+	scans, err := store.ListDiscoveryScans(scan.NetworkID)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *model.DiscoveryScan
+	for i := range scans {
+		s := &scans[i]
+		if s.ID == scan.ID || s.Status != "completed" || s.ScanType != scan.ScanType || !s.CreatedAt.Before(scan.CreatedAt) {
+			continue
+		}
+		if previous == nil || s.CreatedAt.After(previous.CreatedAt) {
+			previous = s
+		}
+	}
+	return previous, nil
+}
+
+// Compare returns the changes from the observations before of the previous scan
+// to the observations current of scan. Without a previous scan nothing changed.
+func Compare(scan, previous *model.DiscoveryScan, current, before []model.DiscoveryObservation) *model.DiscoveryScanDiff {
+	diff := &model.DiscoveryScanDiff{
+		ScanID:           scan.ID,
+		NetworkID:        scan.NetworkID,
+		NewHosts:         []model.DiscoveryObservation{},
+		DisappearedHosts: []model.DiscoveryObservation{},
+		ChangedHosts:     []model.DiscoveryHostChange{},
+	}
+	if previous == nil {
+		return diff
+	}
+	diff.Summary.PreviousScanID = previous.ID
+
+	now := online(current)
+	then := online(before)
+
+	for _, ip := range sortedIPs(now) {
+		obs := now[ip]
+		old, ok := then[ip]
+		if !ok {
+			diff.NewHosts = append(diff.NewHosts, obs)
+			continue
+		}
+		if change, changed := compareHost(old, obs); changed {
+			diff.ChangedHosts = append(diff.ChangedHosts, change)
+			diff.Summary.OpenedPorts += len(change.OpenedPorts)
+			diff.Summary.ClosedPorts += len(change.ClosedPorts)
+		}
+	}
+	for _, ip := range sortedIPs(then) {
+		if _, ok := now[ip]; !ok {
+			diff.DisappearedHosts = append(diff.DisappearedHosts, then[ip])
+		}
+	}
+
+	diff.Summary.NewHosts = len(diff.NewHosts)
+	diff.Summary.DisappearedHosts = len(diff.DisappearedHosts)
+	diff.Summary.ChangedHosts = len(diff.ChangedHosts)
+	return diff
+}
+
+// compareHost returns how a host changed between two observations
+func compareHost(old, obs model.DiscoveryObservation) (model.DiscoveryHostChange, bool) {
+	change := model.DiscoveryHostChange{IP: obs.IP, Hostname: obs.Hostname}
+	change.OpenedPorts = missing(obs.OpenPorts, old.OpenPorts)
+	change.ClosedPorts = missing(old.OpenPorts, obs.OpenPorts)
+
+	fields := []struct {
+		name     string
+		old, obs string
+	}{
+		{"mac_address", old.MACAddress, obs.MACAddress},
+		{"hostname", old.Hostname, obs.Hostname},
+		{"os_guess", old.OSGuess, obs.OSGuess},
+	}
+	for _, f := range fields {
+		if f.old != "" && f.obs != "" && f.old != f.obs {
+			change.Fields = append(change.Fields, model.DiscoveryFieldChange{Field: f.name, Previous: f.old, Current: f.obs})
+		}
+	}
+
+	changed := len(change.OpenedPorts) > 0 || len(change.ClosedPorts) > 0 || len(change.Fields) > 0
+	return change, changed
+}
+
+// online returns the observations of online hosts by IP
+func online(observations []model.DiscoveryObservation) map[string]model.DiscoveryObservation {
+	hosts := make(map[string]model.DiscoveryObservation)
+	for _, obs := range observations {
+		if obs.Status == statusOnline {
+			hosts[obs.IP] = obs
+		}
+	}
+	return hosts
+}
+
+// missing returns the ports of a that are not in b, in ascending order
+func missing(a, b []int) []int {
+	in := make(map[int]bool, len(b))
+	for _, port := range b {
+		in[port] = true
+	}
+	var ports []int
+	for _, port := range a {
+		if !in[port] {
+			ports = append(ports, port)
+			in[port] = true
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// sortedIPs returns the IPs of hosts in address order, so diffs are stable
+func sortedIPs(hosts map[string]model.DiscoveryObservation) []string {
+	ips := make([]string, 0, len(hosts))
+	for ip := range hosts {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		a, errA := netip.ParseAddr(ips[i])
+		b, errB := netip.ParseAddr(ips[j])
+		if errA != nil || errB != nil {
+			return ips[i] < ips[j]
+		}
+		return a.Less(b)
+	})
+	return ips
+}
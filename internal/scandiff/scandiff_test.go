@@ -0,0 +1,171 @@
+package scandiff
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// memStore holds scans and their observations in memory
+type memStore struct {
+	scans        []model.DiscoveryScan
+	observations map[string][]model.DiscoveryObservation
+}
+
+func (s *memStore) ListDiscoveryScans(networkID string) ([]model.DiscoveryScan, error) {
+	var scans []model.DiscoveryScan
+	for _, scan := range s.scans {
+		if networkID == "" || scan.NetworkID == networkID {
+			scans = append(scans, scan)
+		}
+	}
+	return scans, nil
+}
+
+func (s *memStore) ListDiscoveryObservations(scanID string) ([]model.DiscoveryObservation, error) {
+	return s.observations[scanID], nil
+}
+
+func TestCompare(t *testing.T) {
+	previous := &model.DiscoveryScan{ID: "scan-1", NetworkID: "net-1"}
+	scan := &model.DiscoveryScan{ID: "scan-2", NetworkID: "net-1"}
+	before := []model.DiscoveryObservation{
+		{IP: "10.0.0.2", Status: "online", MACAddress: "aa:aa", Hostname: "db1", OpenPorts: []int{22, 5432}},
+		{IP: "10.0.0.3", Status: "online", Hostname: "web1", OpenPorts: []int{80}},
+		{IP: "10.0.0.4", Status: "online", OpenPorts: []int{22}},
+		{IP: "10.0.0.5", Status: "offline", Hostname: "printer"},
+		{IP: "10.0.0.6", Status: "online", Hostname: "nas", OSGuess: "Linux"},
+	}
+	current := []model.DiscoveryObservation{
+		{IP: "10.0.0.2", Status: "online", MACAddress: "bb:bb", Hostname: "db1", OpenPorts: []int{22, 3306}},
+		{IP: "10.0.0.3", Status: "online", OpenPorts: []int{80}},
+		{IP: "10.0.0.4", Status: "offline"},
+		{IP: "10.0.0.5", Status: "online", Hostname: "printer", OpenPorts: []int{631}},
+		{IP: "10.0.0.10", Status: "online", OpenPorts: []int{23}},
+		{IP: "10.0.0.6", Status: "online", Hostname: "nas", OSGuess: "Linux"},
+	}
+
+	diff := Compare(scan, previous, current, before)
+
+	want := model.DiscoveryChangeSummary{
+		PreviousScanID:   "scan-1",
+		NewHosts:         2,
+		DisappearedHosts: 1,
+		ChangedHosts:     1,
+		OpenedPorts:      1,
+		ClosedPorts:      1,
+	}
+	if diff.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", diff.Summary, want)
+	}
+	if diff.ScanID != "scan-2" || diff.NetworkID != "net-1" {
+		t.Errorf("unexpected scan %s of network %s", diff.ScanID, diff.NetworkID)
+	}
+
+	// An offline host that comes online is new, hosts are in address order
+	if len(diff.NewHosts) != 2 || diff.NewHosts[0].IP != "10.0.0.5" || diff.NewHosts[1].IP != "10.0.0.10" {
+		t.Errorf("NewHosts = %+v", diff.NewHosts)
+	}
+	if len(diff.DisappearedHosts) != 1 || diff.DisappearedHosts[0].IP != "10.0.0.4" {
+		t.Errorf("DisappearedHosts = %+v", diff.DisappearedHosts)
+	}
+
+	// A hostname that was not resolved this time is not a change
+	wantChange := model.DiscoveryHostChange{
+		IP:          "10.0.0.2",
+		Hostname:    "db1",
+		OpenedPorts: []int{3306},
+		ClosedPorts: []int{5432},
+		Fields:      []model.DiscoveryFieldChange{{Field: "mac_address", Previous: "aa:aa", Current: "bb:bb"}},
+	}
+	if len(diff.ChangedHosts) != 1 || !reflect.DeepEqual(diff.ChangedHosts[0], wantChange) {
+		t.Errorf("ChangedHosts = %+v, want %+v", diff.ChangedHosts, wantChange)
+	}
+}
+
+func TestCompareWithoutPrevious(t *testing.T) {
+	scan := &model.DiscoveryScan{ID: "scan-1", NetworkID: "net-1"}
+	current := []model.DiscoveryObservation{{IP: "10.0.0.2", Status: "online"}}
+
+	diff := Compare(scan, nil, current, nil)
+	if diff.Summary != (model.DiscoveryChangeSummary{}) {
+		t.Errorf("expected no changes for the first scan, got %+v", diff.Summary)
+	}
+	if diff.NewHosts == nil || len(diff.NewHosts) != 0 || diff.DisappearedHosts == nil || diff.ChangedHosts == nil {
+		t.Errorf("expected empty lists, got %+v", diff)
+	}
+}
+
+func TestForScan(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &memStore{
+		scans: []model.DiscoveryScan{
+			{ID: "old", NetworkID: "net-1", Status: "completed", CreatedAt: base},
+			{ID: "last", NetworkID: "net-1", Status: "completed", CreatedAt: base.Add(time.Hour)},
+			{ID: "cancelled", NetworkID: "net-1", Status: "cancelled", CreatedAt: base.Add(2 * time.Hour)},
+			{ID: "other", NetworkID: "net-2", Status: "completed", CreatedAt: base.Add(2 * time.Hour)},
+			{ID: "current", NetworkID: "net-1", Status: "completed", CreatedAt: base.Add(3 * time.Hour)},
+			{ID: "later", NetworkID: "net-1", Status: "completed", CreatedAt: base.Add(4 * time.Hour)},
+		},
+		observations: map[string][]model.DiscoveryObservation{
+			"old":     {{IP: "10.0.0.9", Status: "online"}},
+			"last":    {{IP: "10.0.0.2", Status: "online"}},
+			"current": {{IP: "10.0.0.2", Status: "online"}, {IP: "10.0.0.3", Status: "online"}},
+		},
+	}
+
+	diff, err := ForScan(store, &store.scans[4])
+	if err != nil {
+		t.Fatalf("ForScan failed: %v", err)
+	}
+	if diff.Summary.PreviousScanID != "last" {
+		t.Errorf("expected the last completed scan before it as baseline, got %q", diff.Summary.PreviousScanID)
+	}
+	if len(diff.NewHosts) != 1 || diff.NewHosts[0].IP != "10.0.0.3" || len(diff.DisappearedHosts) != 0 {
+		t.Errorf("unexpected diff %+v", diff)
+	}
+
+	first, err := ForScan(store, &store.scans[0])
+	if err != nil {
+		t.Fatalf("ForScan failed: %v", err)
+	}
+	if first.Summary.PreviousScanID != "" || len(first.NewHosts) != 0 {
+		t.Errorf("expected the first scan to be the baseline, got %+v", first)
+	}
+}
+
+func TestForScanMixedTypes(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &memStore{
+		scans: []model.DiscoveryScan{
+			{ID: "full", NetworkID: "net-1", Status: "completed", ScanType: "full", CreatedAt: base},
+			{ID: "quick", NetworkID: "net-1", Status: "completed", ScanType: "quick", CreatedAt: base.Add(time.Hour)},
+			{ID: "full-again", NetworkID: "net-1", Status: "completed", ScanType: "full", CreatedAt: base.Add(2 * time.Hour)},
+		},
+		observations: map[string][]model.DiscoveryObservation{
+			"full":       {{IP: "10.0.0.2", Status: "online", OpenPorts: []int{22, 443}}},
+			"quick":      {{IP: "10.0.0.2", Status: "online"}},
+			"full-again": {{IP: "10.0.0.2", Status: "online", OpenPorts: []int{22, 443}}},
+		},
+	}
+
+	// A quick scan probes no ports, so it is not compared with the full scan
+	quick, err := ForScan(store, &store.scans[1])
+	if err != nil {
+		t.Fatalf("ForScan failed: %v", err)
+	}
+	if quick.Summary.PreviousScanID != "" || quick.Summary.ClosedPorts != 0 {
+		t.Errorf("expected the first quick scan to be a baseline, got %+v", quick.Summary)
+	}
+
+	// The next full scan skips the quick scan in between
+	full, err := ForScan(store, &store.scans[2])
+	if err != nil {
+		t.Fatalf("ForScan failed: %v", err)
+	}
+	if full.Summary.PreviousScanID != "full" || full.Summary.OpenedPorts != 0 || len(full.ChangedHosts) != 0 {
+		t.Errorf("expected no changes from the previous full scan, got %+v", full.Summary)
+	}
+}
@@ -0,0 +1,37 @@
+package scanner
+
+import (
+	"context"
+
+	"github.com/martinsuchenak/rackd/pkg/discovery"
+)
+
+// probes returns the built-in probes of the basic scanner
+func (ds *DiscoveryScanner) probes() []discovery.Probe {
+	return []discovery.Probe{
+		discovery.NewProbe("hostname", nil, ds.probeHostname),
+		discovery.NewProbe("ports", nil, ds.probePorts),
+	}
+}
+
+// probeHostname looks up the host name by reverse DNS
+func (ds *DiscoveryScanner) probeHostname(ctx context.Context, host *discovery.Host) error {
+	hostname, err := ds.getHostname(host.IP)
+	if err != nil {
+		return err
+	}
+	host.Device.Hostname = hostname
+	return nil
+}
+
+// probePorts checks liveness using TCP connection attempts to common ports
+// This works without special privileges and gives us basic online/offline status
+func (ds *DiscoveryScanner) probePorts(ctx context.Context, host *discovery.Host) error {
+	openPorts := ds.checkTCPPorts(ctx, host.IP)
+	if len(openPorts) > 0 {
+		host.Alive = true
+		host.Device.Status = "online"
+		host.Device.OpenPorts = openPorts
+	}
+	return nil
+}
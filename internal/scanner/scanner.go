@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
@@ -19,8 +20,9 @@ var commonPorts = []int{80, 443, 22, 3389, 23, 21, 25, 53, 110, 143, 993, 995}
 // maxTCPPortTimeout is the maximum time to wait for a TCP connection attempt
 const maxTCPPortTimeout = 2 * time.Second
 
-// Compile-time interface check to ensure DiscoveryScanner implements discovery.Scanner
-var _ discovery.Scanner = (*DiscoveryScanner)(nil)
+// Compile-time interface checks to ensure DiscoveryScanner implements discovery.ResumableScanner and discovery.ProbeScanner
+var _ discovery.ResumableScanner = (*DiscoveryScanner)(nil)
+var _ discovery.ProbeScanner = (*DiscoveryScanner)(nil)
 
 // DiscoveryStorage interface for storage operations
 type DiscoveryStorage interface {
@@ -37,18 +39,31 @@ type Network interface {
 // DiscoveryScanner performs basic network discovery (OSS version)
 // Premium features (ping, port scanning, ARP, service detection) are available in rackd-enterprise
 type DiscoveryScanner struct {
-	storage DiscoveryStorage
+	storage  DiscoveryStorage
+	pipeline *discovery.Pipeline
 }
 
 // NewDiscoveryScanner creates a new basic scanner
 func NewDiscoveryScanner(storage DiscoveryStorage) *DiscoveryScanner {
-	return &DiscoveryScanner{
+	ds := &DiscoveryScanner{
 		storage: storage,
 	}
+	ds.pipeline = discovery.NewScannerPipeline(ds.probes()...)
+	return ds
+}
+
+// ProbeNames returns the names of the probes the scanner runs on each host
+func (ds *DiscoveryScanner) ProbeNames() []string {
+	return ds.pipeline.Names()
 }
 
 // ScanNetwork scans a network based on discovery rules (basic discovery only)
 func (ds *DiscoveryScanner) ScanNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, updateFunc func(*model.DiscoveryScan)) error {
+	return ds.ResumeNetwork(ctx, networkID, rule, nil, updateFunc)
+}
+
+// ResumeNetwork scans a network, skipping the hosts an earlier run of the scan finished
+func (ds *DiscoveryScanner) ResumeNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, progress discovery.Progress, updateFunc func(*model.DiscoveryScan)) error {
 	// Create scan record
 	scan := &model.DiscoveryScan{
 		ID:        generateID("scan"),
@@ -118,11 +133,24 @@ func (ds *DiscoveryScanner) ScanNetwork(ctx context.Context, networkID string, r
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
+			// Stop starting hosts once the scan is cancelled
+			if ctx.Err() != nil {
+				return
+			}
+
 			// Skip excluded IPs
 			if ds.isExcluded(ip, rule.ExcludeIPs) {
 				return
 			}
 
+			// Skip hosts finished before the scan was interrupted
+			if progress != nil && progress.Done(ip) {
+				mu.Lock()
+				scan.ScannedHosts++
+				mu.Unlock()
+				return
+			}
+
 			// Log every 50th host to show progress
 			mu.Lock()
 			scannedCount++
@@ -132,11 +160,15 @@ func (ds *DiscoveryScanner) ScanNetwork(ctx context.Context, networkID string, r
 			mu.Unlock()
 
 			// Scan the host (basic discovery only)
-			device, err := ds.scanHost(ctx, ip, networkID, scan.ID)
+			device, err := ds.scanHost(ctx, ip, networkID, rule, scan.ID)
 			if err != nil {
 				log.Debug("Host discovery failed", "ip", ip, "error", err)
 				return
 			}
+			// A host cut short by cancellation is scanned again on resume
+			if ctx.Err() != nil {
+				return
+			}
 
 			if device != nil {
 				mu.Lock()
@@ -155,6 +187,10 @@ func (ds *DiscoveryScanner) ScanNetwork(ctx context.Context, networkID string, r
 				}
 			}
 
+			if progress != nil {
+				progress.MarkDone(ip, device)
+			}
+
 			// Update progress (throttled to every 50 hosts to reduce DB load)
 			mu.Lock()
 			scan.ScannedHosts++
@@ -172,6 +208,11 @@ func (ds *DiscoveryScanner) ScanNetwork(ctx context.Context, networkID string, r
 
 	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		log.Info("Network discovery stopped", "network_id", networkID, "scanned", scan.ScannedHosts, "total", len(ips))
+		return err
+	}
+
 	// Complete scan
 	now = time.Now()
 	scan.Status = "completed"
@@ -186,10 +227,10 @@ func (ds *DiscoveryScanner) ScanNetwork(ctx context.Context, networkID string, r
 	return nil
 }
 
-// scanHost performs basic discovery on a single host
+// scanHost performs basic discovery on a single host by running the probe pipeline
 // OSS version: TCP-based liveness checking + hostname lookup
 // Premium features (ICMP ping, ARP, advanced port scanning, service detection, OS detection) are available in rackd-enterprise
-func (ds *DiscoveryScanner) scanHost(ctx context.Context, ip, networkID, scanID string) (*model.DiscoveredDevice, error) {
+func (ds *DiscoveryScanner) scanHost(ctx context.Context, ip, networkID string, rule *model.DiscoveryRule, scanID string) (*model.DiscoveredDevice, error) {
 	log.Debug("Discovering host", "ip", ip)
 
 	// Create device record with basic information
@@ -202,17 +243,12 @@ func (ds *DiscoveryScanner) scanHost(ctx context.Context, ip, networkID, scanID
 		LastSeen:  time.Now(),
 	}
 
-	// Attempt hostname lookup (basic DNS reverse lookup)
-	if hostname, err := ds.getHostname(ip); err == nil {
-		device.Hostname = hostname
-	}
-
-	// Check liveness using TCP connection attempts to common ports
-	// This works without special privileges and gives us basic online/offline status
-	openPorts := ds.checkTCPPorts(ctx, ip)
-	if len(openPorts) > 0 {
-		device.Status = "online"
-		device.OpenPorts = openPorts
+	host := &discovery.Host{IP: ip, Rule: rule, Device: device}
+	if err := ds.pipeline.Run(ctx, host); err != nil {
+		if errors.Is(err, discovery.ErrSkipHost) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
 	// Calculate confidence score based on what we found
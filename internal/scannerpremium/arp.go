@@ -2,25 +2,36 @@ package scannerpremium
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net"
 	"sync"
 	"time"
 )
 
+// errRawARPNotSupported is returned where raw ARP requests cannot be sent
+var errRawARPNotSupported = errors.New("raw ARP not supported on this interface or platform")
+
+// neighborPollInterval is how often the neighbor table is read while waiting
+// for the kernel to resolve an address
+const neighborPollInterval = 50 * time.Millisecond
+
 // ARPScanner performs ARP scanning for MAC address discovery
 type ARPScanner struct {
-	timeout time.Duration
+	timeout    time.Duration
+	privileged bool // Send raw ARP requests
 }
 
 // NewARPScanner creates a new ARP scanner
-func NewARPScanner(timeout time.Duration) *ARPScanner {
+func NewARPScanner(timeout time.Duration, privileged bool) *ARPScanner {
 	return &ARPScanner{
-		timeout: timeout,
+		timeout:    timeout,
+		privileged: privileged,
 	}
 }
 
-// GetMAC performs an ARP request to get the MAC address of an IP
+// GetMAC resolves the MAC address of an IP on a local segment
 // Returns (macAddress, vendor)
 func (a *ARPScanner) GetMAC(ctx context.Context, ip string) (string, string) {
 	// Parse IP to ensure it's valid
@@ -29,11 +40,7 @@ func (a *ARPScanner) GetMAC(ctx context.Context, ip string) (string, string) {
 		return "", ""
 	}
 
-	// For local networks, try ARP scan
-	// This requires raw socket access which may not be available
-	// We'll use a simple approach that works on most systems
-
-	mac, err := a.arping(parsedIP)
+	mac, err := a.arping(ctx, parsedIP)
 	if err != nil {
 		return "", ""
 	}
@@ -42,41 +49,109 @@ func (a *ARPScanner) GetMAC(ctx context.Context, ip string) (string, string) {
 	return mac, vendor
 }
 
-// arping performs a simple ARP request
-func (a *ARPScanner) arping(ip net.IP) (string, error) {
-	// This is a simplified implementation
-	// Full ARP scanning requires more complex networking code
+// arping resolves a MAC address from the kernel neighbor table. When the IP is
+// not in the table yet but on a local segment, a datagram is sent to it so the
+// kernel resolves it, and the table is read again until the timeout; when
+// privileged, a raw ARP request is the last resort.
+func (a *ARPScanner) arping(ctx context.Context, ip net.IP) (string, error) {
+	if mac := lookupNeighbor(ip.String()); mac != "" {
+		return mac, nil
+	}
+
+	// Only hosts on a local segment have a neighbor entry of their own
+	iface, src, ok := localInterface(ip)
+	if !ok {
+		return "", fmt.Errorf("%s is not on a local segment", ip)
+	}
 
-	// For now, return empty - this can be enhanced with proper ARP packet handling
-	// The enterprise version could use external tools or libraries
-	return "", fmt.Errorf("ARP not implemented")
+	probeHost(ip)
+	deadline := time.Now().Add(a.timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(neighborPollInterval):
+		}
+		if mac := lookupNeighbor(ip.String()); mac != "" {
+			return mac, nil
+		}
+	}
+
+	if a.privileged {
+		macs, err := arpSweep(ctx, iface, src, []net.IP{ip}, a.timeout)
+		if err == nil && macs[ip.String()] != "" {
+			return macs[ip.String()], nil
+		}
+	}
+	return "", fmt.Errorf("no MAC address for %s", ip)
 }
 
-// getVendor attempts to identify the hardware vendor from MAC address OUI
-func (a *ARPScanner) getVendor(mac string) string {
-	if mac == "" || len(mac) < 8 {
-		return ""
+// Sweep broadcasts raw ARP requests for every IP on a local segment and
+// returns the MAC addresses that answered. It needs privileges; without them,
+// or when no IP is on a local segment, it returns nil and GetMAC resolves
+// hosts one by one.
+func (a *ARPScanner) Sweep(ctx context.Context, ips []string) map[string]string {
+	if !a.privileged {
+		return nil
+	}
+
+	type segment struct {
+		iface   *net.Interface
+		src     net.IP
+		targets []net.IP
+	}
+	segments := make(map[int]*segment)
+	for _, ip := range ips {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			continue
+		}
+		iface, src, ok := localInterface(parsed)
+		if !ok {
+			continue
+		}
+		seg, ok := segments[iface.Index]
+		if !ok {
+			seg = &segment{iface: iface, src: src}
+			segments[iface.Index] = seg
+		}
+		seg.targets = append(seg.targets, parsed)
 	}
 
-	// Extract OUI (first 3 bytes / 6 hex chars)
-	oui := mac[:8]
-
-	// Common vendor OUIs
-	vendors := map[string]string{
-		"00:50:56": "VMware",
-		"00:0C:29": "VMware",
-		"08:00:27": "VirtualBox",
-		"00:15:5D": "Hyper-V",
-		"00:1B:21": "Intel",
-		"00:E0:4C": "Realtek",
-		"BC:5F:F4": "Intel",
-		"F4:8E:38": "Intel",
+	var results map[string]string
+	for _, seg := range segments {
+		macs, err := arpSweep(ctx, seg.iface, seg.src, seg.targets, a.timeout)
+		if err != nil {
+			log.Printf("ARP sweep unavailable, resolving hosts individually: interface=%s error=%v", seg.iface.Name, err)
+			continue
+		}
+		if results == nil {
+			results = make(map[string]string)
+		}
+		for ip, mac := range macs {
+			results[ip] = mac
+		}
 	}
+	return results
+}
 
-	if vendor, ok := vendors[oui]; ok {
-		return vendor
+// probeHost sends a datagram to the discard port of ip, so the kernel
+// resolves its link-layer address whether or not the host answers
+func probeHost(ip net.IP) {
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: ip, Port: 9})
+	if err != nil {
+		return
 	}
+	defer conn.Close()
+	conn.Write([]byte{0})
+}
 
+// getVendor identifies the hardware vendor from the IEEE registry of the MAC
+// address block
+func (a *ARPScanner) getVendor(mac string) string {
+	if vendor := lookupVendor(mac); vendor != "" {
+		return vendor
+	}
 	return "Unknown"
 }
 
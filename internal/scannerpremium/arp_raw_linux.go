@@ -0,0 +1,125 @@
+//go:build linux
+
+package scannerpremium
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	arpPacketLen = 28
+	arpRequest   = 1
+	arpReply     = 2
+)
+
+// arpSweep broadcasts an ARP request for each target on the interface and
+// collects the replies until timeout after the last request. It needs a raw
+// packet socket, so it fails without CAP_NET_RAW.
+func arpSweep(ctx context.Context, iface *net.Interface, src net.IP, targets []net.IP, timeout time.Duration) (map[string]string, error) {
+	if len(iface.HardwareAddr) != 6 {
+		return nil, errRawARPNotSupported
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_DGRAM, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	addr := &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: iface.Index}
+	if err := unix.Bind(fd, addr); err != nil {
+		return nil, err
+	}
+	// Short reads, so the receiver notices the deadline and cancellation
+	tv := unix.NsecToTimeval((50 * time.Millisecond).Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(targets))
+	for _, ip := range targets {
+		wanted[ip.To4().String()] = true
+	}
+
+	sent := make(chan struct{})
+	results := make(chan map[string]string, 1)
+	go func() {
+		results <- receiveARPReplies(ctx, fd, wanted, sent, timeout)
+	}()
+
+	broadcast := &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: iface.Index, Halen: 6}
+	copy(broadcast.Addr[:], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	for _, ip := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		// A failed send only loses that target
+		unix.Sendto(fd, arpRequestPacket(iface.HardwareAddr, src, ip), 0, broadcast)
+	}
+	close(sent)
+
+	return <-results, nil
+}
+
+// receiveARPReplies reads ARP replies from the wanted addresses until timeout
+// after sent is closed, or until ctx is done
+func receiveARPReplies(ctx context.Context, fd int, wanted map[string]bool, sent <-chan struct{}, timeout time.Duration) map[string]string {
+	macs := make(map[string]string)
+	buf := make([]byte, 1500)
+	var deadline time.Time
+	for {
+		if ctx.Err() != nil {
+			return macs
+		}
+		if deadline.IsZero() {
+			select {
+			case <-sent:
+				deadline = time.Now().Add(timeout)
+			default:
+			}
+		} else if time.Now().After(deadline) || len(macs) == len(wanted) {
+			return macs
+		}
+
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil || n < arpPacketLen {
+			continue
+		}
+		packet := buf[:n]
+		if binary.BigEndian.Uint16(packet[6:8]) != arpReply {
+			continue
+		}
+		ip := net.IP(packet[14:18]).String()
+		if !wanted[ip] {
+			continue
+		}
+		if mac := hardwareAddr(net.HardwareAddr(packet[8:14])); mac != "" {
+			macs[ip] = mac
+		}
+	}
+}
+
+// arpRequestPacket builds an Ethernet/IPv4 ARP request asking for target
+func arpRequestPacket(hw net.HardwareAddr, src, target net.IP) []byte {
+	packet := make([]byte, arpPacketLen)
+	binary.BigEndian.PutUint16(packet[0:2], 1)      // Hardware type: Ethernet
+	binary.BigEndian.PutUint16(packet[2:4], 0x0800) // Protocol type: IPv4
+	packet[4] = 6                                   // Hardware address length
+	packet[5] = 4                                   // Protocol address length
+	binary.BigEndian.PutUint16(packet[6:8], arpRequest)
+	copy(packet[8:14], hw)
+	copy(packet[14:18], src.To4())
+	// Target hardware address stays zero
+	copy(packet[24:28], target.To4())
+	return packet
+}
+
+// htons converts a 16 bit value to network byte order
+func htons(v uint16) uint16 {
+	return binary.NativeEndian.Uint16(binary.BigEndian.AppendUint16(nil, v))
+}
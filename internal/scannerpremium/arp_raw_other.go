@@ -0,0 +1,14 @@
+//go:build !linux
+
+package scannerpremium
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// arpSweep needs Linux packet sockets
+func arpSweep(ctx context.Context, iface *net.Interface, src net.IP, targets []net.IP, timeout time.Duration) (map[string]string, error) {
+	return nil, errRawARPNotSupported
+}
@@ -0,0 +1,68 @@
+package scannerpremium
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProcNetARP(t *testing.T) {
+	table := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         AA:BB:CC:DD:EE:01     *        eth0
+192.168.1.7      0x1         0x0         00:00:00:00:00:00     *        eth0
+192.168.1.9      0x1         0x6         aa:bb:cc:dd:ee:09     *        eth0
+192.168.1.12     0x1         0x2         00:00:00:00:00:00     *        eth0
+`
+	neighbors, err := parseProcNetARP(strings.NewReader(table))
+	if err != nil {
+		t.Fatalf("parseProcNetARP failed: %v", err)
+	}
+	want := map[string]string{
+		"192.168.1.1": "aa:bb:cc:dd:ee:01",
+		"192.168.1.9": "aa:bb:cc:dd:ee:09",
+	}
+	if len(neighbors) != len(want) {
+		t.Fatalf("neighbors = %v, want %v", neighbors, want)
+	}
+	for ip, mac := range want {
+		if neighbors[ip] != mac {
+			t.Errorf("neighbors[%s] = %q, want %q", ip, neighbors[ip], mac)
+		}
+	}
+}
+
+func TestGetVendor(t *testing.T) {
+	a := NewARPScanner(0, false)
+	for mac, want := range map[string]string{
+		"00:50:56:aa:bb:cc": "VMware, Inc.",
+		"00-0C-29-01-02-03": "VMware, Inc.",
+		"b827.eb12.3456":    "Raspberry Pi Foundation",
+		"BC:5F:F4:11:22:33": "ASRock Incorporation",
+		"f4:8e:38:11:22:33": "Dell Inc.",
+		"02:00:00:00:00:01": "Unknown",
+		"":                  "Unknown",
+	} {
+		if got := a.getVendor(mac); got != want {
+			t.Errorf("getVendor(%q) = %q, want %q", mac, got, want)
+		}
+	}
+}
+
+func TestLookupVendorPrefersLongestBlock(t *testing.T) {
+	db := loadOUI()
+	db["0050569"] = "Carved MA-M block"
+	defer delete(db, "0050569")
+
+	if got := lookupVendor("00:50:56:9a:00:01"); got != "Carved MA-M block" {
+		t.Errorf("expected the MA-M block, got %q", got)
+	}
+	if got := lookupVendor("00:50:56:1a:00:01"); got != "VMware, Inc." {
+		t.Errorf("expected the MA-L block, got %q", got)
+	}
+}
+
+func TestOUIRegistryComplete(t *testing.T) {
+	// The IEEE has assigned tens of thousands of blocks across the registries
+	if n := len(loadOUI()); n < 30000 {
+		t.Errorf("oui.csv has %d blocks, expected the full IEEE registry; run go generate in internal/scannerpremium", n)
+	}
+}
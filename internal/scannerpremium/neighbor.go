@@ -0,0 +1,124 @@
+package scannerpremium
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// procNetARP is the kernel's IPv4 neighbor table on Linux
+const procNetARP = "/proc/net/arp"
+
+// atfComplete is the /proc/net/arp flag of a resolved entry
+const atfComplete = 0x2
+
+// errNeighborsNotSupported is returned where the neighbor table cannot be read
+var errNeighborsNotSupported = errors.New("neighbor table not supported on this platform")
+
+// lookupNeighbor returns the MAC address the kernel resolved for an IP, read
+// over netlink or, when that is not available, from /proc/net/arp
+func lookupNeighbor(ip string) string {
+	neighbors, err := netlinkNeighbors()
+	if err != nil {
+		neighbors, err = readProcNetARP(procNetARP)
+		if err != nil {
+			return ""
+		}
+	}
+	return neighbors[ip]
+}
+
+// readProcNetARP reads the resolved entries of a /proc/net/arp file
+func readProcNetARP(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseProcNetARP(f)
+}
+
+// parseProcNetARP parses the /proc/net/arp format, keeping complete entries:
+//
+//	IP address       HW type     Flags       HW address            Mask     Device
+//	192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:ff     *        eth0
+func parseProcNetARP(r io.Reader) (map[string]string, error) {
+	neighbors := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[2], 0, 32)
+		if err != nil || flags&atfComplete == 0 {
+			continue
+		}
+		if mac := normalizeMAC(fields[3]); mac != "" {
+			neighbors[fields[0]] = mac
+		}
+	}
+	return neighbors, scanner.Err()
+}
+
+// normalizeMAC returns a MAC address in lower case colon form, or "" for an
+// invalid or all-zero address
+func normalizeMAC(mac string) string {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return ""
+	}
+	return hardwareAddr(hw)
+}
+
+// hardwareAddr formats a 6 byte hardware address, or returns "" for anything
+// else or the all-zero address of an unresolved entry
+func hardwareAddr(hw net.HardwareAddr) string {
+	if len(hw) != 6 {
+		return ""
+	}
+	for _, b := range hw {
+		if b != 0 {
+			return hw.String()
+		}
+	}
+	return ""
+}
+
+// localInterface returns the up, non-loopback interface whose IPv4 subnet
+// contains ip, with the interface's own address on that subnet
+func localInterface(ip net.IP) (*net.Interface, net.IP, bool) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, nil, false
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, false
+	}
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil {
+				continue
+			}
+			if ipNet.Contains(ip4) {
+				return iface, ipNet.IP.To4(), true
+			}
+		}
+	}
+	return nil, nil, false
+}
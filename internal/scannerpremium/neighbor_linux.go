@@ -0,0 +1,71 @@
+//go:build linux
+
+package scannerpremium
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// usableNeighborStates are the neighbor states whose link-layer address is valid
+const usableNeighborStates = unix.NUD_REACHABLE | unix.NUD_STALE | unix.NUD_DELAY | unix.NUD_PROBE | unix.NUD_PERMANENT
+
+// netlinkNeighbors dumps the kernel neighbor table (IPv4 and IPv6) over
+// rtnetlink, keeping entries with a usable link-layer address
+func netlinkNeighbors() (map[string]string, error) {
+	tab, err := syscall.NetlinkRIB(unix.RTM_GETNEIGH, unix.AF_UNSPEC)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(tab)
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors := make(map[string]string)
+	for _, m := range msgs {
+		if m.Header.Type != unix.RTM_NEWNEIGH || len(m.Data) < unix.SizeofNdMsg {
+			continue
+		}
+		// struct ndmsg: family, pad, pad, ifindex, state, flags, type
+		state := binary.NativeEndian.Uint16(m.Data[8:10])
+		if state&usableNeighborStates == 0 {
+			continue
+		}
+
+		var ip net.IP
+		var mac string
+		attrs := m.Data[nlmsgAlign(unix.SizeofNdMsg):]
+		for len(attrs) >= unix.SizeofRtAttr {
+			length := int(binary.NativeEndian.Uint16(attrs[0:2]))
+			kind := binary.NativeEndian.Uint16(attrs[2:4])
+			if length < unix.SizeofRtAttr || length > len(attrs) {
+				break
+			}
+			value := attrs[unix.SizeofRtAttr:length]
+			switch kind {
+			case unix.NDA_DST:
+				ip = net.IP(value)
+			case unix.NDA_LLADDR:
+				mac = hardwareAddr(net.HardwareAddr(value))
+			}
+			if aligned := nlmsgAlign(length); aligned < len(attrs) {
+				attrs = attrs[aligned:]
+			} else {
+				break
+			}
+		}
+		if ip != nil && mac != "" {
+			neighbors[ip.String()] = mac
+		}
+	}
+	return neighbors, nil
+}
+
+// nlmsgAlign rounds a netlink length up to the 4 byte alignment
+func nlmsgAlign(n int) int {
+	return (n + unix.NLMSG_ALIGNTO - 1) &^ (unix.NLMSG_ALIGNTO - 1)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package scannerpremium
+
+// netlinkNeighbors is only available on Linux
+func netlinkNeighbors() (map[string]string, error) {
+	return nil, errNeighborsNotSupported
+}
@@ -0,0 +1,74 @@
+package scannerpremium
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"strings"
+	"sync"
+)
+
+//go:generate go run ./ouigen -o oui.csv
+
+// ouiCSV is oui.csv as written by ouigen from the IEEE listings of MAC address
+// blocks (MA-L, MA-M, MA-S and IAB), one "Registry,Assignment,Organization Name"
+// record per block. The file is checked in; go generate refreshes it from the
+// IEEE, and TestOUIRegistryComplete fails if it is partial.
+//
+//go:embed oui.csv
+var ouiCSV string
+
+// ouiPrefixLengths are the assignment lengths in hex digits, longest first so
+// a MA-S block wins over the MA-L block it is carved from
+var ouiPrefixLengths = []int{9, 7, 6}
+
+var (
+	ouiOnce sync.Once
+	ouiDB   map[string]string
+)
+
+// loadOUI parses the embedded registry into a map of assignment to organization
+func loadOUI() map[string]string {
+	ouiOnce.Do(func() {
+		ouiDB = make(map[string]string)
+		r := csv.NewReader(strings.NewReader(ouiCSV))
+		r.FieldsPerRecord = -1
+		records, err := r.ReadAll()
+		if err != nil {
+			return
+		}
+		for _, record := range records {
+			if len(record) < 3 || record[0] == "Registry" {
+				continue
+			}
+			ouiDB[strings.ToUpper(record[1])] = strings.TrimSpace(record[2])
+		}
+	})
+	return ouiDB
+}
+
+// lookupVendor returns the organization the MAC address block is assigned to,
+// or "" when the address is not in the registry
+func lookupVendor(mac string) string {
+	digits := macDigits(mac)
+	if len(digits) != 12 {
+		return ""
+	}
+	db := loadOUI()
+	for _, n := range ouiPrefixLengths {
+		if vendor, ok := db[digits[:n]]; ok {
+			return vendor
+		}
+	}
+	return ""
+}
+
+// macDigits returns the upper case hex digits of a MAC address, without separators
+func macDigits(mac string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(mac) {
+		if (c >= '0' && c <= '9') || (c >= 'A' && c <= 'F') {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+// Command ouigen downloads the IEEE registries of MAC address blocks and writes
+// them as the vendor database embedded in the premium scanner.
+//
+// Usage (from internal/scannerpremium):
+//
+//	go generate
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// registries are the IEEE public listings; CID blocks are not MAC addresses
+var registries = []string{
+	"https://standards-oui.ieee.org/oui/oui.csv",
+	"https://standards-oui.ieee.org/oui28/mam.csv",
+	"https://standards-oui.ieee.org/oui36/oui36.csv",
+	"https://standards-oui.ieee.org/iab/iab.csv",
+}
+
+type block struct {
+	registry, assignment, organization string
+}
+
+func main() {
+	output := flag.String("o", "oui.csv", "file to write")
+	flag.Parse()
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	seen := make(map[string]bool)
+	var blocks []block
+	for _, url := range registries {
+		fetched, err := fetch(client, url)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ouigen: %s: %v\n", url, err)
+			os.Exit(1)
+		}
+		for _, b := range fetched {
+			if !seen[b.assignment] {
+				seen[b.assignment] = true
+				blocks = append(blocks, b)
+			}
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].assignment < blocks[j].assignment })
+
+	if err := write(*output, blocks); err != nil {
+		fmt.Fprintf(os.Stderr, "ouigen: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("ouigen: wrote %d blocks to %s\n", len(blocks), *output)
+}
+
+// fetch downloads a registry listing, keeping registry, assignment and organization
+func fetch(client *http.Client, url string) ([]block, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	r := csv.NewReader(resp.Body)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	var blocks []block
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 || record[0] == "Registry" {
+			continue
+		}
+		organization := strings.Join(strings.Fields(record[2]), " ")
+		if organization == "" {
+			continue
+		}
+		blocks = append(blocks, block{record[0], strings.ToUpper(record[1]), organization})
+	}
+	return blocks, nil
+}
+
+func write(path string, blocks []block) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"Registry", "Assignment", "Organization Name"})
+	for _, b := range blocks {
+		w.Write([]string{b.registry, b.assignment, b.organization})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -2,6 +2,7 @@ package scannerpremium
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -14,8 +15,9 @@ import (
 	"github.com/martinsuchenak/rackd/internal/model"
 )
 
-// Compile-time interface check to ensure PremiumScanner implements discovery.Scanner
-var _ discovery.Scanner = (*PremiumScanner)(nil)
+// Compile-time interface checks to ensure PremiumScanner implements discovery.ResumableScanner and discovery.ProbeScanner
+var _ discovery.ResumableScanner = (*PremiumScanner)(nil)
+var _ discovery.ProbeScanner = (*PremiumScanner)(nil)
 
 // PremiumScanOptions configures the premium scanner behavior
 type PremiumScanOptions struct {
@@ -44,6 +46,7 @@ type PremiumScanner struct {
 	portScanner    *PortScanner
 	arpScanner     *ARPScanner
 	serviceScanner *ServiceScanner
+	pipeline       *discovery.Pipeline
 
 	// Options
 	options *PremiumScanOptions
@@ -73,14 +76,25 @@ func NewPremiumScanner(store storage.DiscoveryStorage, options *PremiumScanOptio
 	// Initialize scanners
 	ps.pingScanner = NewPingScanner(options.PingTimeout, options.Privileged)
 	ps.portScanner = NewPortScanner(options.PortTimeout, options.PortScanType)
-	ps.arpScanner = NewARPScanner(options.ARPTimeout)
+	ps.arpScanner = NewARPScanner(options.ARPTimeout, options.Privileged)
 	ps.serviceScanner = NewServiceScanner()
+	ps.pipeline = discovery.NewScannerPipeline(ps.probes()...)
 
 	return ps
 }
 
+// ProbeNames returns the names of the probes the scanner runs on each host
+func (ps *PremiumScanner) ProbeNames() []string {
+	return ps.pipeline.Names()
+}
+
 // ScanNetwork scans a network based on discovery rules (premium version with all features)
 func (ps *PremiumScanner) ScanNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, updateFunc func(*model.DiscoveryScan)) error {
+	return ps.ResumeNetwork(ctx, networkID, rule, nil, updateFunc)
+}
+
+// ResumeNetwork scans a network, skipping the hosts an earlier run of the scan finished
+func (ps *PremiumScanner) ResumeNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, progress discovery.Progress, updateFunc func(*model.DiscoveryScan)) error {
 	// Create scan record
 	scan := &model.DiscoveryScan{
 		ID:        generateID("scan"),
@@ -130,6 +144,12 @@ func (ps *PremiumScanner) ScanNetwork(ctx context.Context, networkID string, rul
 
 	log.Printf("Starting premium network discovery: network_id=%s hosts=%d scan_type=%s", networkID, len(ips), rule.ScanType)
 
+	// Resolve the local segment in one ARP sweep instead of host by host
+	var sweptMACs map[string]string
+	if ps.options.ARPScan && ps.arpScanner != nil {
+		sweptMACs = ps.arpScanner.Sweep(ctx, ips)
+	}
+
 	// Limit concurrent scans
 	maxConcurrent := ps.options.MaxConcurrency
 	if maxConcurrent <= 0 {
@@ -165,6 +185,14 @@ func (ps *PremiumScanner) ScanNetwork(ctx context.Context, networkID string, rul
 				return
 			}
 
+			// Skip hosts finished before the scan was interrupted
+			if progress != nil && progress.Done(ip) {
+				mu.Lock()
+				scan.ScannedHosts++
+				mu.Unlock()
+				return
+			}
+
 			// Log every 50th host to show progress
 			mu.Lock()
 			scannedCount++
@@ -174,11 +202,15 @@ func (ps *PremiumScanner) ScanNetwork(ctx context.Context, networkID string, rul
 			mu.Unlock()
 
 			// Scan the host with all premium features
-			device, err := ps.scanHost(ctx, ip, networkID, rule, scan.ID)
+			device, err := ps.scanHost(ctx, ip, networkID, rule, scan.ID, sweptMACs[ip])
 			if err != nil {
 				// Continue with other hosts
 				return
 			}
+			// A host cut short by cancellation is scanned again on resume
+			if ctx.Err() != nil {
+				return
+			}
 
 			if device != nil {
 				mu.Lock()
@@ -195,6 +227,10 @@ func (ps *PremiumScanner) ScanNetwork(ctx context.Context, networkID string, rul
 				}
 			}
 
+			if progress != nil {
+				progress.MarkDone(ip, device)
+			}
+
 			// Update progress (throttled)
 			mu.Lock()
 			scan.ScannedHosts++
@@ -212,6 +248,11 @@ func (ps *PremiumScanner) ScanNetwork(ctx context.Context, networkID string, rul
 
 	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		log.Printf("Premium network discovery stopped: network_id=%s scanned=%d total=%d", networkID, scan.ScannedHosts, len(ips))
+		return err
+	}
+
 	// Complete scan
 	now = time.Now()
 	scan.Status = "completed"
@@ -226,8 +267,10 @@ func (ps *PremiumScanner) ScanNetwork(ctx context.Context, networkID string, rul
 	return nil
 }
 
-// scanHost performs multi-stage scanning on a single host (premium version)
-func (ps *PremiumScanner) scanHost(ctx context.Context, ip, networkID string, rule *model.DiscoveryRule, scanID string) (*model.DiscoveredDevice, error) {
+// scanHost performs multi-stage scanning on a single host (premium version) by
+// running the probe pipeline
+// sweptMAC is the MAC address the host answered the ARP sweep with, if any
+func (ps *PremiumScanner) scanHost(ctx context.Context, ip, networkID string, rule *model.DiscoveryRule, scanID, sweptMAC string) (*model.DiscoveredDevice, error) {
 	log.Printf("Scanning host (premium): %s", ip)
 
 	device := &model.DiscoveredDevice{
@@ -239,58 +282,24 @@ func (ps *PremiumScanner) scanHost(ctx context.Context, ip, networkID string, ru
 		LastSeen:  time.Now(),
 	}
 
-	// Stage 1: Ping check (ICMP)
-	var alive bool
-	if ps.pingScanner != nil {
-		alive, _ = ps.pingScanner.Ping(ctx, ip)
-		if alive {
-			device.Status = "online"
-		}
-	}
-
-	// For quick scans, only report hosts that respond to ping
-	if rule.ScanType == "quick" && !alive {
-		return nil, nil // Host is down or unreachable
+	host := &discovery.Host{IP: ip, Rule: rule, Device: device}
+	// A host that answered the ARP sweep is alive even when it drops ICMP
+	if sweptMAC != "" {
+		host.Alive = true
+		device.Status = "online"
+		device.MACAddress = sweptMAC
 	}
 
-	// Stage 2: MAC address and hostname (if alive or doing full/deep scan)
-	if alive || rule.ScanType != "quick" {
-		// ARP scan for MAC address
-		if ps.options.ARPScan && ps.arpScanner != nil {
-			mac, _ := ps.arpScanner.GetMAC(ctx, ip)
-			if mac != "" {
-				device.MACAddress = mac
-			}
-		}
-
-		// Hostname lookup
-		if hostname, err := ps.getHostname(ip); err == nil {
-			device.Hostname = hostname
+	if err := ps.pipeline.Run(ctx, host); err != nil {
+		if errors.Is(err, discovery.ErrSkipHost) {
+			return nil, nil
 		}
+		return nil, err
 	}
 
-	// Stage 3: Port scanning (for full/deep scans)
-	if rule.ScanPorts && rule.ScanType != "quick" && ps.portScanner != nil {
-		ports, err := ps.portScanner.ScanPorts(ctx, ip, rule)
-		if err == nil && len(ports) > 0 {
-			device.OpenPorts = ports
-			if device.Status == "unknown" {
-				device.Status = "online"
-			}
-		}
-	}
-
-	// Stage 4: Service fingerprinting (if enabled and ports found)
-	if ps.options.ServiceDetection && rule.ServiceDetection && len(device.OpenPorts) > 0 && ps.serviceScanner != nil {
-		services := ps.serviceScanner.DetectServices(ctx, ip, device.OpenPorts)
-		device.Services = services
-	}
-
-	// Stage 5: OS fingerprinting (if enabled)
-	if ps.options.OSDetection && rule.OSDetection {
-		osGuess := ps.guessOS(device)
-		device.OSGuess = osGuess.OS
-		device.OSFamily = osGuess.Family
+	// For quick scans, only report hosts that answered
+	if rule.ScanType == "quick" && !host.Alive {
+		return nil, nil
 	}
 
 	// Calculate confidence score
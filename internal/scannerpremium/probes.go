@@ -0,0 +1,104 @@
+package scannerpremium
+
+import (
+	"context"
+
+	"github.com/martinsuchenak/rackd/pkg/discovery"
+)
+
+// probes returns the built-in probes of the premium scanner, in the order
+// ping → ARP → hostname → ports → services → OS guess
+func (ps *PremiumScanner) probes() []discovery.Probe {
+	return []discovery.Probe{
+		discovery.NewProbe("ping", nil, ps.probePing),
+		discovery.NewProbe("arp", []string{"ping"}, ps.probeARP),
+		discovery.NewProbe("hostname", []string{"ping"}, ps.probeHostname),
+		discovery.NewProbe("ports", []string{"ping"}, ps.probePorts),
+		discovery.NewProbe("services", []string{"ports"}, ps.probeServices),
+		discovery.NewProbe("os", []string{"ports", "services"}, ps.probeOS),
+	}
+}
+
+// reachable reports whether a host is worth probing further: quick scans only
+// look at hosts that answered
+func reachable(host *discovery.Host) bool {
+	return host.Alive || host.Rule.ScanType != "quick"
+}
+
+// probePing checks the host with ICMP; for quick scans, only hosts that
+// respond are reported
+func (ps *PremiumScanner) probePing(ctx context.Context, host *discovery.Host) error {
+	if ps.pingScanner != nil && !host.Alive {
+		if alive, _ := ps.pingScanner.Ping(ctx, host.IP); alive {
+			host.Alive = true
+			host.Device.Status = "online"
+		}
+	}
+	if !reachable(host) {
+		return discovery.ErrSkipHost // Host is down or unreachable
+	}
+	return nil
+}
+
+// probeARP resolves the MAC address, unless the ARP sweep already did
+func (ps *PremiumScanner) probeARP(ctx context.Context, host *discovery.Host) error {
+	if !ps.options.ARPScan || ps.arpScanner == nil || host.Device.MACAddress != "" || !reachable(host) {
+		return nil
+	}
+	if mac, _ := ps.arpScanner.GetMAC(ctx, host.IP); mac != "" {
+		host.Device.MACAddress = mac
+	}
+	return nil
+}
+
+// probeHostname looks up the host name by reverse DNS
+func (ps *PremiumScanner) probeHostname(ctx context.Context, host *discovery.Host) error {
+	if !reachable(host) {
+		return nil
+	}
+	hostname, err := ps.getHostname(host.IP)
+	if err != nil {
+		return err
+	}
+	host.Device.Hostname = hostname
+	return nil
+}
+
+// probePorts scans the ports of the rule (for full/deep scans)
+func (ps *PremiumScanner) probePorts(ctx context.Context, host *discovery.Host) error {
+	if !host.Rule.ScanPorts || host.Rule.ScanType == "quick" || ps.portScanner == nil {
+		return nil
+	}
+	ports, err := ps.portScanner.ScanPorts(ctx, host.IP, host.Rule)
+	if err != nil {
+		return err
+	}
+	if len(ports) > 0 {
+		host.Device.OpenPorts = ports
+		host.Alive = true
+		if host.Device.Status == "unknown" {
+			host.Device.Status = "online"
+		}
+	}
+	return nil
+}
+
+// probeServices fingerprints the services on the open ports
+func (ps *PremiumScanner) probeServices(ctx context.Context, host *discovery.Host) error {
+	if !ps.options.ServiceDetection || !host.Rule.ServiceDetection || len(host.Device.OpenPorts) == 0 || ps.serviceScanner == nil {
+		return nil
+	}
+	host.Device.Services = ps.serviceScanner.DetectServices(ctx, host.IP, host.Device.OpenPorts)
+	return nil
+}
+
+// probeOS guesses the OS from the open ports and services
+func (ps *PremiumScanner) probeOS(ctx context.Context, host *discovery.Host) error {
+	if !ps.options.OSDetection || !host.Rule.OSDetection {
+		return nil
+	}
+	osGuess := ps.guessOS(host.Device)
+	host.Device.OSGuess = osGuess.OS
+	host.Device.OSFamily = osGuess.Family
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package schedule works out when discovery rules are due: on a fixed interval
+// or a cron expression in a time zone, outside blackout windows, with jitter.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	// Time zones must resolve on hosts without a zoneinfo database
+	_ "time/tzdata"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// maxBlackoutSkips bounds the runs skipped for blackouts when looking for the
+// next one, so overlapping windows covering every run cannot loop forever
+const maxBlackoutSkips = 1000
+
+// Plan is the schedule of a discovery rule
+type Plan struct {
+	interval  time.Duration
+	cron      *Cron
+	loc       *time.Location
+	jitter    time.Duration
+	blackouts []blackout
+}
+
+// blackout is a window in which a rule does not run: recurring, starting at
+// every match of cron for duration, or one-off, from start to end
+type blackout struct {
+	cron       *Cron
+	duration   time.Duration
+	start, end time.Time
+}
+
+// ForRule returns the plan of a rule, validating its schedule, time zone,
+// jitter and blackouts
+func ForRule(rule *model.DiscoveryRule) (*Plan, error) {
+	p := &Plan{
+		interval: time.Duration(rule.ScanIntervalHours) * time.Hour,
+		loc:      time.Local,
+		jitter:   time.Duration(rule.JitterMinutes) * time.Minute,
+	}
+
+	if rule.Timezone != "" {
+		loc, err := time.LoadLocation(rule.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", rule.Timezone)
+		}
+		p.loc = loc
+	}
+
+	if rule.Schedule != "" {
+		c, err := ParseCron(rule.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		if c.Next(time.Now().In(p.loc)).IsZero() {
+			return nil, fmt.Errorf("schedule %q never runs", rule.Schedule)
+		}
+		p.cron = c
+	} else if rule.ScanIntervalHours < 0 {
+		return nil, errors.New("scan_interval_hours must not be negative")
+	}
+
+	if rule.JitterMinutes < 0 {
+		return nil, errors.New("jitter_minutes must not be negative")
+	}
+
+	for i, b := range rule.Blackouts {
+		var w blackout
+		switch {
+		case b.Schedule != "":
+			if b.Start != nil || b.End != nil {
+				return nil, fmt.Errorf("blackout %d: set either schedule and duration_minutes, or start and end", i+1)
+			}
+			c, err := ParseCron(b.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("blackout %d: %w", i+1, err)
+			}
+			if b.DurationMinutes <= 0 {
+				return nil, fmt.Errorf("blackout %d: duration_minutes must be positive", i+1)
+			}
+			w.cron, w.duration = c, time.Duration(b.DurationMinutes)*time.Minute
+		case b.Start != nil && b.End != nil:
+			if !b.End.After(*b.Start) {
+				return nil, fmt.Errorf("blackout %d: end must be after start", i+1)
+			}
+			w.start, w.end = *b.Start, *b.End
+		default:
+			return nil, fmt.Errorf("blackout %d: set either schedule and duration_minutes, or start and end", i+1)
+		}
+		p.blackouts = append(p.blackouts, w)
+	}
+
+	return p, nil
+}
+
+// Next returns when the rule is next due after a run at t, or the zero time
+// if it never is
+func (p *Plan) Next(t time.Time) time.Time {
+	if p.cron != nil {
+		return p.Allowed(p.cron.Next(t.In(p.loc)))
+	}
+	return p.Allowed(t.Add(p.interval))
+}
+
+// Allowed returns t delayed by the jitter, or when that falls in a blackout
+// the first run after it: the next match of the schedule, or the end of the
+// blackout for a rule on an interval
+func (p *Plan) Allowed(t time.Time) time.Time {
+	for i := 0; i < maxBlackoutSkips && !t.IsZero(); i++ {
+		at := t
+		if p.jitter > 0 {
+			at = at.Add(rand.N(p.jitter))
+		}
+
+		end, blocked := p.Blackout(at)
+		if !blocked {
+			return at
+		}
+		if p.cron != nil {
+			t = p.cron.Next(end.Add(-time.Second).In(p.loc))
+		} else {
+			t = end
+		}
+	}
+	return time.Time{}
+}
+
+// Blackout reports whether t falls in a blackout window, and when the last
+// window covering it ends
+func (p *Plan) Blackout(t time.Time) (time.Time, bool) {
+	var end time.Time
+	for _, b := range p.blackouts {
+		if b.cron == nil {
+			if !t.Before(b.start) && t.Before(b.end) && b.end.After(end) {
+				end = b.end
+			}
+			continue
+		}
+
+		// Windows starting after t - duration and no later than t cover t
+		for start := b.cron.Next(t.Add(-b.duration).In(p.loc)); !start.IsZero() && !start.After(t); start = b.cron.Next(start) {
+			if e := start.Add(b.duration); e.After(end) {
+				end = e
+			}
+		}
+	}
+	return end, !end.IsZero()
+}
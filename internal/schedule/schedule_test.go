@@ -0,0 +1,174 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+func mustTime(t *testing.T, loc *time.Location, value string) time.Time {
+	t.Helper()
+	v, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@often",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q): expected an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	utc := time.UTC
+	tests := []struct {
+		expr, from, want string
+	}{
+		{"* * * * *", "2025-01-01 10:00", "2025-01-01 10:01"},
+		{"*/15 * * * *", "2025-01-01 10:07", "2025-01-01 10:15"},
+		{"0 2 * * *", "2025-01-01 02:00", "2025-01-02 02:00"},
+		{"30 22-23,0-5/2 * * *", "2025-01-01 23:45", "2025-01-02 00:30"},
+		{"0 1 * * mon-fri", "2025-01-03 12:00", "2025-01-06 01:00"}, // Friday to Monday
+		{"0 0 * * 7", "2025-01-01 00:00", "2025-01-05 00:00"},       // Sunday as 7
+		{"0 0 1 jan *", "2025-02-01 00:00", "2026-01-01 00:00"},
+		{"0 0 31 * *", "2025-02-01 00:00", "2025-03-31 00:00"},
+		{"0 0 29 2 *", "2025-01-01 00:00", "2028-02-29 00:00"},
+		// Both day fields restricted: either matches
+		{"0 0 15 * sat", "2025-01-05 00:00", "2025-01-11 00:00"},
+		{"@daily", "2025-01-01 00:00", "2025-01-02 00:00"},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", tt.expr, err)
+		}
+		got := c.Next(mustTime(t, utc, tt.from))
+		if want := mustTime(t, utc, tt.want); !got.Equal(want) {
+			t.Errorf("%q from %s: got %v, want %v", tt.expr, tt.from, got, want)
+		}
+	}
+
+	c, _ := ParseCron("0 0 30 2 *")
+	if next := c.Next(time.Now()); !next.IsZero() {
+		t.Errorf("expected no match for February 30th, got %v", next)
+	}
+}
+
+func TestCronNextTimezone(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := ParseCron("0 2 * * *")
+
+	// 2am in New York is 7am UTC in winter, 6am in summer
+	if got := c.Next(mustTime(t, time.UTC, "2025-01-10 12:00").In(ny)); !got.Equal(mustTime(t, time.UTC, "2025-01-11 07:00")) {
+		t.Errorf("winter: got %v", got.UTC())
+	}
+	if got := c.Next(mustTime(t, time.UTC, "2025-07-10 12:00").In(ny)); !got.Equal(mustTime(t, time.UTC, "2025-07-11 06:00")) {
+		t.Errorf("summer: got %v", got.UTC())
+	}
+
+	// 2:30am does not exist the day clocks go forward, so there is no run that day
+	c, _ = ParseCron("30 2 * * *")
+	if got := c.Next(mustTime(t, ny, "2025-03-09 00:00")); !got.Equal(mustTime(t, ny, "2025-03-10 02:30")) {
+		t.Errorf("spring forward: got %v", got)
+	}
+}
+
+func TestForRuleErrors(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	tests := []struct {
+		rule model.DiscoveryRule
+		want string
+	}{
+		{model.DiscoveryRule{Schedule: "not cron"}, "invalid cron expression"},
+		{model.DiscoveryRule{Schedule: "0 0 30 2 *"}, "never runs"},
+		{model.DiscoveryRule{Timezone: "Mars/Olympus"}, "unknown timezone"},
+		{model.DiscoveryRule{JitterMinutes: -1}, "jitter_minutes"},
+		{model.DiscoveryRule{ScanIntervalHours: -1}, "scan_interval_hours"},
+		{model.DiscoveryRule{Blackouts: []model.DiscoveryBlackout{{}}}, "blackout 1"},
+		{model.DiscoveryRule{Blackouts: []model.DiscoveryBlackout{{Schedule: "0 0 * * *"}}}, "duration_minutes"},
+		{model.DiscoveryRule{Blackouts: []model.DiscoveryBlackout{{Schedule: "0 0 * * *", DurationMinutes: 10, Start: &start}}}, "either"},
+		{model.DiscoveryRule{Blackouts: []model.DiscoveryBlackout{{Start: &end, End: &start}}}, "end must be after start"},
+	}
+	for _, tt := range tests {
+		if _, err := ForRule(&tt.rule); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ForRule(%+v): expected error containing %q, got %v", tt.rule, tt.want, err)
+		}
+	}
+}
+
+func TestPlanNext(t *testing.T) {
+	// Nightly at 1am, never on weekends
+	plan, err := ForRule(&model.DiscoveryRule{
+		Schedule:  "0 1 * * *",
+		Timezone:  "Europe/Berlin",
+		Blackouts: []model.DiscoveryBlackout{{Schedule: "0 0 * * sat,sun", DurationMinutes: 24 * 60}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	friday := mustTime(t, berlin, "2025-01-10 12:00")
+	if got, want := plan.Next(friday), mustTime(t, berlin, "2025-01-13 01:00"); !got.Equal(want) {
+		t.Errorf("expected the weekend to be skipped, got %v, want %v", got, want)
+	}
+
+	// On an interval, a run that falls in a change freeze waits for its end
+	freezeStart := mustTime(t, time.UTC, "2025-01-01 06:00")
+	freezeEnd := mustTime(t, time.UTC, "2025-01-03 00:00")
+	plan, err = ForRule(&model.DiscoveryRule{
+		ScanIntervalHours: 12,
+		Blackouts:         []model.DiscoveryBlackout{{Reason: "freeze", Start: &freezeStart, End: &freezeEnd}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.Next(mustTime(t, time.UTC, "2025-01-01 00:00")); !got.Equal(freezeEnd) {
+		t.Errorf("expected the run at the end of the freeze, got %v", got)
+	}
+	if got, want := plan.Next(freezeEnd), freezeEnd.Add(12*time.Hour); !got.Equal(want) {
+		t.Errorf("expected the next run after the freeze, got %v, want %v", got, want)
+	}
+	if end, blocked := plan.Blackout(freezeStart); !blocked || !end.Equal(freezeEnd) {
+		t.Errorf("expected the freeze to cover its start, got %v, %v", end, blocked)
+	}
+	if _, blocked := plan.Blackout(freezeEnd); blocked {
+		t.Error("expected the freeze to end at its end time")
+	}
+}
+
+func TestPlanJitter(t *testing.T) {
+	plan, err := ForRule(&model.DiscoveryRule{Schedule: "0 3 * * *", Timezone: "UTC", JitterMinutes: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := mustTime(t, time.UTC, "2025-01-01 12:00")
+	base := mustTime(t, time.UTC, "2025-01-02 03:00")
+	for i := 0; i < 100; i++ {
+		got := plan.Next(from)
+		if got.Before(base) || !got.Before(base.Add(30*time.Minute)) {
+			t.Fatalf("expected a run within 30 minutes of %v, got %v", base, got)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Package software compares package versions and matches them against version constraints.
+//
+// Versions are compared segment by segment in the way rpm and dpkg do: an optional
+// numeric epoch ("1:2.0") comes first, runs of digits compare numerically, runs of
+// letters compare lexically, a digit run is newer than a letter run, and a '~' sorts
+// before everything including the end of the version (so "1.0~rc1" < "1.0").
+// Other characters only separate segments.
+//
+// A constraint is a comma-separated list of comparisons that must all match:
+//
+//	<3.0.8            older than 3.0.8
+//	>=3.0.0,<3.0.8    in the 3.0.x series before 3.0.8
+//	!=1.1.1k          any version except 1.1.1k
+//	3.0.8             exactly 3.0.8 (also =3.0.8 or ==3.0.8)
+package software
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidConstraint is returned when a version constraint cannot be parsed
+var ErrInvalidConstraint = errors.New("invalid version constraint")
+
+// Compare returns -1, 0 or 1 when version a is older than, equal to or newer than b
+func Compare(a, b string) int {
+	epochA, a := splitEpoch(a)
+	epochB, b := splitEpoch(b)
+	if c := compareNumeric(epochA, epochB); c != 0 {
+		return c
+	}
+
+	for {
+		a = strings.TrimLeftFunc(a, isSeparator)
+		b = strings.TrimLeftFunc(b, isSeparator)
+
+		// A tilde sorts before anything, even the end of the version
+		tildeA, tildeB := strings.HasPrefix(a, "~"), strings.HasPrefix(b, "~")
+		if tildeA || tildeB {
+			if !tildeA {
+				return 1
+			}
+			if !tildeB {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if a == "" || b == "" {
+			break
+		}
+
+		segA, restA := nextSegment(a)
+		segB, restB := nextSegment(b)
+		numA, numB := isDigit(rune(segA[0])), isDigit(rune(segB[0]))
+		if numA != numB {
+			if numA {
+				return 1
+			}
+			return -1
+		}
+
+		var c int
+		if numA {
+			c = compareNumeric(segA, segB)
+		} else {
+			c = strings.Compare(segA, segB)
+		}
+		if c != 0 {
+			return c
+		}
+		a, b = restA, restB
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// splitEpoch splits a leading "N:" epoch from a version, defaulting the epoch to 0
+func splitEpoch(v string) (string, string) {
+	v = strings.TrimSpace(v)
+	if i := strings.IndexByte(v, ':'); i > 0 && strings.IndexFunc(v[:i], func(r rune) bool { return !isDigit(r) }) < 0 {
+		return v[:i], v[i+1:]
+	}
+	return "0", v
+}
+
+// nextSegment returns the leading run of digits or letters and the remainder
+func nextSegment(v string) (string, string) {
+	digits := isDigit(rune(v[0]))
+	end := strings.IndexFunc(v, func(r rune) bool {
+		if digits {
+			return !isDigit(r)
+		}
+		return !isLetter(r)
+	})
+	if end < 0 {
+		return v, ""
+	}
+	return v[:end], v[end:]
+}
+
+// compareNumeric compares two digit strings of any length
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(r rune) bool  { return r >= '0' && r <= '9' }
+func isLetter(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') }
+
+func isSeparator(r rune) bool {
+	return !isDigit(r) && !isLetter(r) && r != '~'
+}
+
+// Operator is the comparison used by a constraint term
+type Operator string
+
+const (
+	Equal          Operator = "="
+	NotEqual       Operator = "!="
+	Less           Operator = "<"
+	LessOrEqual    Operator = "<="
+	Greater        Operator = ">"
+	GreaterOrEqual Operator = ">="
+)
+
+// Term is a single comparison of a constraint
+type Term struct {
+	Operator Operator
+	Version  string
+}
+
+// Constraint is a set of terms that must all match (AND logic)
+type Constraint []Term
+
+// ParseConstraint parses a comma-separated version constraint. An empty string matches every version.
+func ParseConstraint(s string) (Constraint, error) {
+	var constraint Constraint
+	if strings.TrimSpace(s) == "" {
+		return constraint, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		op := Equal
+		// Two-character operators must be checked before their one-character prefixes
+		for _, candidate := range []Operator{"==", LessOrEqual, GreaterOrEqual, NotEqual, Less, Greater, Equal} {
+			if strings.HasPrefix(part, string(candidate)) {
+				op = candidate
+				part = strings.TrimSpace(part[len(candidate):])
+				break
+			}
+		}
+		if op == "==" {
+			op = Equal
+		}
+		if part == "" || strings.ContainsAny(part, " <>=!") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidConstraint, s)
+		}
+		constraint = append(constraint, Term{Operator: op, Version: part})
+	}
+	return constraint, nil
+}
+
+// Matches reports whether a version satisfies every term of the constraint
+func (c Constraint) Matches(version string) bool {
+	for _, term := range c {
+		if !term.Matches(version) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether a version satisfies the term
+func (t Term) Matches(version string) bool {
+	c := Compare(version, t.Version)
+	switch t.Operator {
+	case Equal:
+		return c == 0
+	case NotEqual:
+		return c != 0
+	case Less:
+		return c < 0
+	case LessOrEqual:
+		return c <= 0
+	case Greater:
+		return c > 0
+	case GreaterOrEqual:
+		return c >= 0
+	}
+	return false
+}
+
+// String formats the constraint in its parseable form
+func (c Constraint) String() string {
+	terms := make([]string, len(c))
+	for i, term := range c {
+		terms[i] = string(term.Operator) + term.Version
+	}
+	return strings.Join(terms, ",")
+}
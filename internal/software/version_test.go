@@ -0,0 +1,97 @@
+package software
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"3.0.8", "3.0.8", 0},
+		{"3.0.7", "3.0.8", -1},
+		{"3.0.10", "3.0.8", 1},
+		{"3.0", "3.0.1", -1},
+		{"3.0.8-1ubuntu1", "3.0.8", 1},
+		{"1.1.1k", "1.1.1", 1},
+		{"1.1.1k", "1.1.1w", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1:1.0", "2.0", 1},
+		{"0:2.0", "2.0", 0},
+		{"2.0.0", "2.0.00", 0},
+		{"1.0a", "1.0.1", -1},
+		{"20240101", "9", 1},
+	}
+
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"<3.0.8", "<3.0.8"},
+		{"< 3.0.8", "<3.0.8"},
+		{"3.0.8", "=3.0.8"},
+		{"==3.0.8", "=3.0.8"},
+		{">=3.0.0, <3.0.8", ">=3.0.0,<3.0.8"},
+		{"!=1.1.1k", "!=1.1.1k"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseConstraint(tt.in)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseConstraint(%q) = %q, want %q", tt.in, got.String(), tt.want)
+		}
+	}
+
+	for _, in := range []string{"<", "<3.0,", "=>3.0", "<3.0 >2.0", "<<3"} {
+		if _, err := ParseConstraint(in); !errors.Is(err, ErrInvalidConstraint) {
+			t.Errorf("ParseConstraint(%q) error = %v, want ErrInvalidConstraint", in, err)
+		}
+	}
+}
+
+func TestConstraintMatches(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"<3.0.8", "3.0.7", true},
+		{"<3.0.8", "3.0.8", false},
+		{"<3.0.8", "1.1.1w", true},
+		{"<3.0.8", "3.0.8-1ubuntu1", false},
+		{">=3.0.0,<3.0.8", "1.1.1w", false},
+		{">=3.0.0,<3.0.8", "3.0.2", true},
+		{"!=3.0.2", "3.0.2", false},
+		{"3.0.2", "3.0.2", true},
+		{"", "anything", true},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q) returned error: %v", tt.constraint, err)
+		}
+		if got := c.Matches(tt.version); got != tt.want {
+			t.Errorf("%q.Matches(%q) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+var (
+	// ErrInvalidAsset is returned when hardware asset data fails validation
+	ErrInvalidAsset = errors.New("invalid asset")
+	// ErrDuplicateAsset is returned when a serial number or asset tag is already used by another device
+	ErrDuplicateAsset = errors.New("duplicate asset")
+)
+
+// AssetStorage defines the interface for hardware asset reports.
+// Asset data itself is stored with its device through CreateDevice and UpdateDevice.
+type AssetStorage interface {
+	// ListAssets returns the assets of all devices with asset data, ordered by warranty expiry
+	ListAssets(filter *model.AssetFilter) ([]model.DeviceAsset, error)
+}
+
+// normalizeAsset trims the asset fields, validates them and rewrites dates as YYYY-MM-DD
+func normalizeAsset(asset *model.HardwareAsset) error {
+	asset.SerialNumber = strings.TrimSpace(asset.SerialNumber)
+	asset.AssetTag = strings.TrimSpace(asset.AssetTag)
+	asset.Vendor = strings.TrimSpace(asset.Vendor)
+	asset.PONumber = strings.TrimSpace(asset.PONumber)
+	asset.SupportContract = strings.TrimSpace(asset.SupportContract)
+
+	if asset.Cost < 0 {
+		return fmt.Errorf("%w: cost must not be negative", ErrInvalidAsset)
+	}
+
+	var err error
+	if asset.PurchaseDate, err = normalizeAssetDate("purchase_date", asset.PurchaseDate); err != nil {
+		return err
+	}
+	if asset.WarrantyExpires, err = normalizeAssetDate("warranty_expires", asset.WarrantyExpires); err != nil {
+		return err
+	}
+	return nil
+}
+
+func normalizeAssetDate(field, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s must be a date (YYYY-MM-DD)", ErrInvalidAsset, field)
+		}
+	}
+	return t.Format("2006-01-02"), nil
+}
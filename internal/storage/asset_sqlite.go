@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// ListAssets returns the hardware assets of all devices that have asset data
+func (ss *sqlStorage) ListAssets(filter *model.AssetFilter) ([]model.DeviceAsset, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	query := `
+		SELECT d.id, d.name, d.datacenter_id, d.status, a.serial_number, a.asset_tag, a.vendor, a.purchase_date,
+		       a.po_number, a.cost, a.warranty_expires, a.support_contract
+		FROM device_assets a
+		INNER JOIN devices d ON d.id = a.device_id
+	`
+	var conditions []string
+	var args []interface{}
+	if filter != nil {
+		if filter.WarrantyExpiresAfter != "" {
+			conditions = append(conditions, "a.warranty_expires >= ?")
+			args = append(args, filter.WarrantyExpiresAfter)
+		}
+		if filter.WarrantyExpiresBefore != "" {
+			conditions = append(conditions, "a.warranty_expires <= ?")
+			args = append(args, filter.WarrantyExpiresBefore)
+		}
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY a.warranty_expires IS NULL, a.warranty_expires, d.name"
+
+	rows, err := ss.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying assets: %w", err)
+	}
+	defer rows.Close()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	assets := []model.DeviceAsset{}
+	for rows.Next() {
+		var da model.DeviceAsset
+		var datacenterID sql.NullString
+		var asset assetRow
+		if err := rows.Scan(&da.DeviceID, &da.DeviceName, &datacenterID, &da.Status, &asset.serial, &asset.tag,
+			&asset.vendor, &asset.purchaseDate, &asset.poNumber, &asset.cost, &asset.warrantyExpires, &asset.supportContract); err != nil {
+			return nil, fmt.Errorf("scanning asset: %w", err)
+		}
+		da.DatacenterID = datacenterID.String
+		da.HardwareAsset = asset.toModel()
+		if expires, err := time.Parse("2006-01-02", da.WarrantyExpires); err == nil {
+			days := int(expires.Sub(today).Hours() / 24)
+			da.WarrantyDaysLeft = &days
+		}
+		assets = append(assets, da)
+	}
+
+	return assets, rows.Err()
+}
+
+// assetRow holds the nullable columns of a device_assets row
+type assetRow struct {
+	serial, tag, vendor, purchaseDate, poNumber sql.NullString
+	warrantyExpires, supportContract            sql.NullString
+	cost                                        sql.NullFloat64
+}
+
+func (r assetRow) toModel() model.HardwareAsset {
+	return model.HardwareAsset{
+		SerialNumber:    r.serial.String,
+		AssetTag:        r.tag.String,
+		Vendor:          r.vendor.String,
+		PurchaseDate:    r.purchaseDate.String,
+		PONumber:        r.poNumber.String,
+		Cost:            r.cost.Float64,
+		WarrantyExpires: r.warrantyExpires.String,
+		SupportContract: r.supportContract.String,
+	}
+}
+
+// saveDeviceAsset validates and stores the asset data of a device, replacing any previous data.
+// An empty asset removes the asset data.
+func saveDeviceAsset(tx sqlTx, deviceID string, asset *model.HardwareAsset) error {
+	if err := normalizeAsset(asset); err != nil {
+		return err
+	}
+
+	if asset.IsZero() {
+		if _, err := tx.Exec("DELETE FROM device_assets WHERE device_id = ?", deviceID); err != nil {
+			return fmt.Errorf("deleting asset: %w", err)
+		}
+		return nil
+	}
+
+	for _, unique := range []struct{ column, field, value string }{
+		{"serial_number", "serial number", asset.SerialNumber},
+		{"asset_tag", "asset tag", asset.AssetTag},
+	} {
+		if unique.value == "" {
+			continue
+		}
+		var owner string
+		err := tx.QueryRow(`SELECT d.name FROM device_assets a INNER JOIN devices d ON d.id = a.device_id
+			WHERE LOWER(a.`+unique.column+`) = LOWER(?) AND a.device_id != ?`, unique.value, deviceID).Scan(&owner)
+		if err == nil {
+			return fmt.Errorf("%w: %s %q is already used by device %s", ErrDuplicateAsset, unique.field, unique.value, owner)
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("checking %s: %w", unique.field, err)
+		}
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO device_assets (device_id, serial_number, asset_tag, vendor, purchase_date, po_number, cost, warranty_expires, support_contract)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (device_id) DO UPDATE SET
+		    serial_number = excluded.serial_number, asset_tag = excluded.asset_tag, vendor = excluded.vendor,
+		    purchase_date = excluded.purchase_date, po_number = excluded.po_number, cost = excluded.cost,
+		    warranty_expires = excluded.warranty_expires, support_contract = excluded.support_contract
+	`, deviceID, nullString(asset.SerialNumber), nullString(asset.AssetTag), nullString(asset.Vendor),
+		nullString(asset.PurchaseDate), nullString(asset.PONumber), asset.Cost,
+		nullString(asset.WarrantyExpires), nullString(asset.SupportContract))
+	if err != nil {
+		return fmt.Errorf("saving asset: %w", err)
+	}
+	return nil
+}
+
+// loadDeviceAssets returns the asset data of the given devices, keyed by device ID
+func loadDeviceAssets(q queryer, ids []string) (map[string]*model.HardwareAsset, error) {
+	result := make(map[string]*model.HardwareAsset)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.Repeat("?,", len(ids)-1) + "?"
+
+	rows, err := q.Query(`SELECT device_id, serial_number, asset_tag, vendor, purchase_date, po_number, cost, warranty_expires, support_contract
+		FROM device_assets WHERE device_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying assets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var asset assetRow
+		if err := rows.Scan(&id, &asset.serial, &asset.tag, &asset.vendor, &asset.purchaseDate, &asset.poNumber,
+			&asset.cost, &asset.warrantyExpires, &asset.supportContract); err != nil {
+			return nil, fmt.Errorf("scanning asset: %w", err)
+		}
+		a := asset.toModel()
+		result[id] = &a
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,8 @@
+package storage
+
+// BackupStorage defines the interface for online database backups
+type BackupStorage interface {
+	// Backup writes a consistent copy of the database to path, which must not exist.
+	// The server keeps running while the backup is taken.
+	Backup(path string) error
+}
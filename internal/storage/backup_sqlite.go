@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+)
+
+// Backup writes a consistent, compacted copy of the database using VACUUM INTO,
+// which is safe while the database is in use and in WAL mode
+func (ss *SQLiteStorage) Backup(path string) error {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	log.Debug("Backing up database", "path", path)
+
+	if _, err := ss.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("backing up database: %w", err)
+	}
+
+	log.Info("Database backed up", "path", path)
+	return nil
+}
+
+// VerifyDatabase checks that the file at path is an intact rackd database
+func VerifyDatabase(path string) error {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return fmt.Errorf("checking integrity: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return fmt.Errorf("checking integrity: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("checking integrity: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+
+	var devices int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM devices`).Scan(&devices); err != nil {
+		return fmt.Errorf("not a rackd database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package storage_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/martinsuchenak/rackd/internal/storage/storagetest"
+	"github.com/martinsuchenak/rackd/pkg/registry"
+)
+
+// postgresDSNEnv names the database used to run the conformance tests against PostgreSQL.
+// Every test works in a schema of its own, which is dropped afterwards.
+const postgresDSNEnv = "RACKD_TEST_POSTGRES_DSN"
+
+var postgresSchemaSeq atomic.Int64
+
+func TestSQLiteConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := storage.NewSQLiteStorage(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewSQLiteStorage failed: %v", err)
+		}
+		return s
+	})
+}
+
+func TestPostgresConformance(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresDSNEnv)
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := openPostgres(dsn, t.Cleanup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+// TestRegisteredProvidersConformance runs the suite against every storage provider
+// in the registry, so plugged in backends are held to the same contracts. The
+// built-in backends are registered as providers too, so the suite also covers the
+// way a provider is opened from the registry.
+func TestRegisteredProvidersConformance(t *testing.T) {
+	reg := registry.GetRegistry()
+	reg.RegisterStorageProvider(storage.TypeSQLite, func(config map[string]interface{}) (interface{}, error) {
+		dataDir, _ := config["data_dir"].(string)
+		return storage.NewSQLiteStorage(dataDir)
+	})
+	if dsn := os.Getenv(postgresDSNEnv); dsn != "" {
+		reg.RegisterStorageProvider(storage.TypePostgres, func(config map[string]interface{}) (interface{}, error) {
+			return openPostgres(dsn, t.Cleanup)
+		})
+	}
+
+	names := reg.ListStorageProviders()
+	if len(names) == 0 {
+		t.Fatal("no storage providers registered")
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			storagetest.Run(t, storagetest.Provider(name))
+		})
+	}
+}
+
+// openPostgres opens a store working in a new schema of the database at dsn.
+// Closing the connection and dropping the schema are passed to cleanup.
+func openPostgres(dsn string, cleanup func(func())) (*storage.PostgresStorage, error) {
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres: %w", err)
+	}
+	cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("rackd_test_%d_%d", os.Getpid(), postgresSchemaSeq.Add(1))
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+	cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	s, err := storage.NewPostgresStorage(withSearchPath(dsn, schema))
+	if err != nil {
+		return nil, fmt.Errorf("NewPostgresStorage failed: %w", err)
+	}
+	return s, nil
+}
+
+// withSearchPath sets the schema a connection string works in
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
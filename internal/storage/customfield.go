@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+var (
+	// ErrCustomFieldNotFound is returned when a custom field definition is not found
+	ErrCustomFieldNotFound = errors.New("custom field not found")
+	// ErrInvalidCustomField is returned when a definition or a value fails validation
+	ErrInvalidCustomField = errors.New("invalid custom field")
+)
+
+// CustomFieldStorage defines the interface for custom field definitions.
+// Values are stored with their entity through the regular Create/Update methods.
+type CustomFieldStorage interface {
+	ListCustomFields(filter *model.CustomFieldFilter) ([]model.CustomFieldDefinition, error)
+	GetCustomField(id string) (*model.CustomFieldDefinition, error)
+	CreateCustomField(def *model.CustomFieldDefinition) error
+	UpdateCustomField(def *model.CustomFieldDefinition) error
+	DeleteCustomField(id string) error
+}
+
+var customFieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validateCustomFieldDefinition checks a definition before it is stored
+func validateCustomFieldDefinition(def *model.CustomFieldDefinition) error {
+	switch def.EntityType {
+	case model.CustomFieldEntityDevice, model.CustomFieldEntityNetwork, model.CustomFieldEntityDatacenter:
+	default:
+		return fmt.Errorf("%w: entity_type must be device, network or datacenter", ErrInvalidCustomField)
+	}
+
+	if !customFieldNamePattern.MatchString(def.Name) {
+		return fmt.Errorf("%w: name must start with a letter and contain only lowercase letters, digits and underscores", ErrInvalidCustomField)
+	}
+
+	switch def.Type {
+	case model.CustomFieldTypeString, model.CustomFieldTypeInt, model.CustomFieldTypeBool,
+		model.CustomFieldTypeDate, model.CustomFieldTypeURL:
+		if len(def.Options) > 0 {
+			return fmt.Errorf("%w: options are only allowed for enum fields", ErrInvalidCustomField)
+		}
+	case model.CustomFieldTypeEnum:
+		if len(def.Options) == 0 {
+			return fmt.Errorf("%w: enum fields require options", ErrInvalidCustomField)
+		}
+	default:
+		return fmt.Errorf("%w: type must be string, int, bool, date, enum or url", ErrInvalidCustomField)
+	}
+
+	if def.Pattern != "" {
+		if _, err := regexp.Compile(def.Pattern); err != nil {
+			return fmt.Errorf("%w: invalid pattern: %v", ErrInvalidCustomField, err)
+		}
+	}
+
+	return nil
+}
+
+// normalizeCustomFieldValue validates a value against its definition and
+// returns it in canonical form, so that filtering can use exact matches
+func normalizeCustomFieldValue(def *model.CustomFieldDefinition, value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch def.Type {
+	case model.CustomFieldTypeInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s must be an integer", ErrInvalidCustomField, def.Name)
+		}
+		value = strconv.FormatInt(n, 10)
+	case model.CustomFieldTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s must be true or false", ErrInvalidCustomField, def.Name)
+		}
+		value = strconv.FormatBool(b)
+	case model.CustomFieldTypeDate:
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, value)
+			if err != nil {
+				return "", fmt.Errorf("%w: %s must be a date (YYYY-MM-DD)", ErrInvalidCustomField, def.Name)
+			}
+		}
+		value = t.Format("2006-01-02")
+	case model.CustomFieldTypeEnum:
+		found := false
+		for _, option := range def.Options {
+			if option == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("%w: %s must be one of %s", ErrInvalidCustomField, def.Name, strings.Join(def.Options, ", "))
+		}
+	case model.CustomFieldTypeURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "", fmt.Errorf("%w: %s must be an absolute URL", ErrInvalidCustomField, def.Name)
+		}
+	}
+
+	if def.Pattern != "" {
+		re, err := regexp.Compile(def.Pattern)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid pattern for %s: %v", ErrInvalidCustomField, def.Name, err)
+		}
+		if !re.MatchString(value) {
+			return "", fmt.Errorf("%w: %s does not match pattern %s", ErrInvalidCustomField, def.Name, def.Pattern)
+		}
+	}
+
+	return value, nil
+}
@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// queryer is satisfied by both sqlConn and sqlTx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// execer is satisfied by both sqlConn and sqlTx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+const customFieldColumns = `id, entity_type, name, label, type, required, pattern, options, description, created_at, updated_at`
+
+// ListCustomFields returns custom field definitions, optionally filtered by entity type
+func (ss *sqlStorage) ListCustomFields(filter *model.CustomFieldFilter) ([]model.CustomFieldDefinition, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	entityType := ""
+	if filter != nil {
+		entityType = filter.EntityType
+	}
+	return ss.listCustomFields(ss.db, entityType)
+}
+
+// GetCustomField retrieves a custom field definition by ID
+func (ss *sqlStorage) GetCustomField(id string) (*model.CustomFieldDefinition, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	rows, err := ss.db.Query(`SELECT `+customFieldColumns+` FROM custom_field_definitions WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("querying custom field: %w", err)
+	}
+	defer rows.Close()
+
+	defs, err := scanCustomFields(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(defs) == 0 {
+		return nil, ErrCustomFieldNotFound
+	}
+	return &defs[0], nil
+}
+
+// CreateCustomField adds a new custom field definition
+func (ss *sqlStorage) CreateCustomField(def *model.CustomFieldDefinition) error {
+	if err := validateCustomFieldDefinition(def); err != nil {
+		return err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if def.ID == "" {
+		def.ID = generateUUID()
+	}
+	now := time.Now()
+	def.CreatedAt = now
+	def.UpdatedAt = now
+
+	if err := insertCustomField(ss.db, def); err != nil {
+		return err
+	}
+
+	log.Info("Custom field created in storage", "id", def.ID, "entity_type", def.EntityType, "name", def.Name)
+	return nil
+}
+
+// UpdateCustomField updates an existing custom field definition.
+// The entity type of a field cannot be changed; existing values are kept when the field is renamed.
+func (ss *sqlStorage) UpdateCustomField(def *model.CustomFieldDefinition) error {
+	if err := validateCustomFieldDefinition(def); err != nil {
+		return err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	var entityType string
+	err := ss.db.QueryRow(`SELECT entity_type FROM custom_field_definitions WHERE id = ?`, def.ID).Scan(&entityType)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCustomFieldNotFound
+		}
+		return fmt.Errorf("querying custom field: %w", err)
+	}
+	if entityType != def.EntityType {
+		return fmt.Errorf("%w: entity_type cannot be changed", ErrInvalidCustomField)
+	}
+
+	def.UpdatedAt = time.Now()
+
+	_, err = ss.db.Exec(`
+		UPDATE custom_field_definitions
+		SET name = ?, label = ?, type = ?, required = ?, pattern = ?, options = ?, description = ?, updated_at = ?
+		WHERE id = ?
+	`, def.Name, nullString(def.Label), def.Type, def.Required, nullString(def.Pattern),
+		jsonBytes(def.Options), nullString(def.Description), def.UpdatedAt, def.ID)
+	if err != nil {
+		return fmt.Errorf("updating custom field: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCustomField removes a custom field definition and all of its values
+func (ss *sqlStorage) DeleteCustomField(id string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	result, err := ss.db.Exec(`DELETE FROM custom_field_definitions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("deleting custom field: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrCustomFieldNotFound
+	}
+
+	return nil
+}
+
+// Helper functions
+
+func insertCustomField(ex execer, def *model.CustomFieldDefinition) error {
+	_, err := ex.Exec(`
+		INSERT INTO custom_field_definitions
+		    (id, entity_type, name, label, type, required, pattern, options, description, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, def.ID, def.EntityType, def.Name, nullString(def.Label), def.Type, def.Required,
+		nullString(def.Pattern), jsonBytes(def.Options), nullString(def.Description), def.CreatedAt, def.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting custom field: %w", err)
+	}
+	return nil
+}
+
+func (ss *sqlStorage) listCustomFields(q queryer, entityType string) ([]model.CustomFieldDefinition, error) {
+	query := `SELECT ` + customFieldColumns + ` FROM custom_field_definitions`
+	var args []interface{}
+	if entityType != "" {
+		query += " WHERE entity_type = ?"
+		args = append(args, entityType)
+	}
+	query += " ORDER BY entity_type, name"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	return scanCustomFields(rows)
+}
+
+func scanCustomFields(rows *sql.Rows) ([]model.CustomFieldDefinition, error) {
+	defs := []model.CustomFieldDefinition{}
+	for rows.Next() {
+		var def model.CustomFieldDefinition
+		var label, pattern, options, description sql.NullString
+		err := rows.Scan(&def.ID, &def.EntityType, &def.Name, &label, &def.Type, &def.Required,
+			&pattern, &options, &description, &def.CreatedAt, &def.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scanning custom field: %w", err)
+		}
+		if label.Valid {
+			def.Label = label.String
+		}
+		if pattern.Valid {
+			def.Pattern = pattern.String
+		}
+		if options.Valid {
+			json.Unmarshal([]byte(options.String), &def.Options)
+		}
+		if description.Valid {
+			def.Description = description.String
+		}
+		defs = append(defs, def)
+	}
+	return defs, rows.Err()
+}
+
+// saveCustomFieldValues validates and stores the custom field values of an entity,
+// replacing any previous values. A nil map leaves the stored values untouched unless
+// the entity is being created, in which case required fields are still enforced.
+// The normalized values are returned.
+func (ss *sqlStorage) saveCustomFieldValues(tx sqlTx, entityType, entityID string, values map[string]string, creating bool) (map[string]string, error) {
+	if values == nil && !creating {
+		return nil, nil
+	}
+
+	defs, err := ss.listCustomFields(tx, entityType)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*model.CustomFieldDefinition, len(defs))
+	for i := range defs {
+		byName[defs[i].Name] = &defs[i]
+	}
+
+	normalized := make(map[string]string, len(values))
+	for name, value := range values {
+		def, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown %s field %q", ErrInvalidCustomField, entityType, name)
+		}
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		v, err := normalizeCustomFieldValue(def, value)
+		if err != nil {
+			return nil, err
+		}
+		normalized[name] = v
+	}
+
+	for _, def := range defs {
+		if _, ok := normalized[def.Name]; def.Required && !ok {
+			return nil, fmt.Errorf("%w: %s is required", ErrInvalidCustomField, def.Name)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM custom_field_values WHERE entity_type = ? AND entity_id = ?`, entityType, entityID); err != nil {
+		return nil, fmt.Errorf("deleting old custom field values: %w", err)
+	}
+	for name, value := range normalized {
+		_, err := tx.Exec(`
+			INSERT INTO custom_field_values (entity_type, entity_id, field_id, value)
+			VALUES (?, ?, ?, ?)
+		`, entityType, entityID, byName[name].ID, value)
+		if err != nil {
+			return nil, fmt.Errorf("inserting custom field value: %w", err)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// loadCustomFieldValues returns the custom field values of the given entities, keyed by entity ID
+func (ss *sqlStorage) loadCustomFieldValues(entityType string, ids []string) (map[string]map[string]string, error) {
+	result := make(map[string]map[string]string)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, entityType)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.Repeat("?,", len(ids)-1) + "?"
+
+	rows, err := ss.db.Query(fmt.Sprintf(`
+		SELECT v.entity_id, f.name, v.value
+		FROM custom_field_values v
+		INNER JOIN custom_field_definitions f ON f.id = v.field_id
+		WHERE v.entity_type = ? AND v.entity_id IN (%s)
+	`, placeholders), args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying custom field values: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entityID, name, value string
+		if err := rows.Scan(&entityID, &name, &value); err != nil {
+			return nil, fmt.Errorf("scanning custom field value: %w", err)
+		}
+		if result[entityID] == nil {
+			result[entityID] = make(map[string]string)
+		}
+		result[entityID][name] = value
+	}
+
+	return result, rows.Err()
+}
+
+// customFieldConditions builds SQL conditions matching entities whose custom fields
+// have the given values (case-insensitive, all must match)
+func customFieldConditions(entityType, idColumn string, fields map[string]string) ([]string, []interface{}) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var conditions []string
+	var args []interface{}
+	for _, name := range names {
+		conditions = append(conditions, idColumn+` IN (
+			SELECT v.entity_id FROM custom_field_values v
+			INNER JOIN custom_field_definitions f ON f.id = v.field_id
+			WHERE v.entity_type = ? AND f.name = ? AND LOWER(v.value) = LOWER(?))`)
+		args = append(args, entityType, name, fields[name])
+	}
+	return conditions, args
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"errors"
+	"time"
 
 	"github.com/martinsuchenak/rackd/internal/model"
 )
@@ -13,6 +14,10 @@ var (
 	ErrDiscoveryScanNotFound = errors.New("discovery scan not found")
 	// ErrDiscoveryRuleNotFound is returned when a rule is not found
 	ErrDiscoveryRuleNotFound = errors.New("discovery rule not found")
+	// ErrPromotionPolicyNotFound is returned when a promotion policy is not found
+	ErrPromotionPolicyNotFound = errors.New("promotion policy not found")
+	// ErrDuplicatePromotionPolicy is returned when a promotion policy name is already used
+	ErrDuplicatePromotionPolicy = errors.New("promotion policy name already in use")
 )
 
 // DiscoveryStorage defines the interface for discovery-related storage operations
@@ -37,6 +42,15 @@ type DiscoveryStorage interface {
 	UpdateDiscoveryScan(scan *model.DiscoveryScan) error
 	DeleteDiscoveryScan(id string) error
 
+	// Scan progress: the hosts a scan has finished, mapped to whether a device was found
+	GetDiscoveryScanHosts(scanID string) (map[string]bool, error)
+	MarkDiscoveryScanHost(scanID, ip string, found bool) error
+	DeleteDiscoveryScanHosts(scanID string) error
+
+	// Scan observations: what each scan saw on the hosts it found, kept for comparing scans
+	RecordDiscoveryObservation(obs *model.DiscoveryObservation) error
+	ListDiscoveryObservations(scanID string) ([]model.DiscoveryObservation, error)
+
 	// Discovery Rules
 	ListDiscoveryRules(networkID string) ([]model.DiscoveryRule, error)
 	GetDiscoveryRule(id string) (*model.DiscoveryRule, error)
@@ -44,4 +58,12 @@ type DiscoveryStorage interface {
 	CreateDiscoveryRule(rule *model.DiscoveryRule) error
 	UpdateDiscoveryRule(rule *model.DiscoveryRule) error
 	DeleteDiscoveryRule(id string) error
+	SetDiscoveryRuleSchedule(id string, lastRunAt, nextRunAt *time.Time) error
+
+	// Auto-promotion policies, listed in the order they run
+	ListPromotionPolicies() ([]model.PromotionPolicy, error)
+	GetPromotionPolicy(id string) (*model.PromotionPolicy, error)
+	CreatePromotionPolicy(policy *model.PromotionPolicy) error
+	UpdatePromotionPolicy(policy *model.PromotionPolicy) error
+	DeletePromotionPolicy(id string) error
 }
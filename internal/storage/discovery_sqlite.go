@@ -13,10 +13,14 @@ import (
 )
 
 // ListDiscoveredDevices returns discovered devices, optionally filtered
-func (ss *SQLiteStorage) ListDiscoveredDevices(filter *model.DiscoveredDeviceFilter) ([]model.DiscoveredDevice, error) {
+func (ss *sqlStorage) ListDiscoveredDevices(filter *model.DiscoveredDeviceFilter) ([]model.DiscoveredDevice, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
+	return ss.listDiscoveredDevicesLocked(filter)
+}
 
+// listDiscoveredDevicesLocked returns discovered devices matching filter; caller must hold ss.mu
+func (ss *sqlStorage) listDiscoveredDevicesLocked(filter *model.DiscoveredDeviceFilter) ([]model.DiscoveredDevice, error) {
 	query := `
 		SELECT id, ip, mac_address, hostname, network_id, status, confidence,
 		       os_guess, os_family, open_ports, services, first_seen, last_seen,
@@ -115,7 +119,7 @@ func (ss *SQLiteStorage) ListDiscoveredDevices(filter *model.DiscoveredDeviceFil
 }
 
 // GetDiscoveredDevice retrieves a discovered device by ID
-func (ss *SQLiteStorage) GetDiscoveredDevice(id string) (*model.DiscoveredDevice, error) {
+func (ss *sqlStorage) GetDiscoveredDevice(id string) (*model.DiscoveredDevice, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
@@ -185,7 +189,7 @@ func (ss *SQLiteStorage) GetDiscoveredDevice(id string) (*model.DiscoveredDevice
 }
 
 // GetDiscoveredDeviceByIP retrieves a discovered device by IP
-func (ss *SQLiteStorage) GetDiscoveredDeviceByIP(ip string) (*model.DiscoveredDevice, error) {
+func (ss *sqlStorage) GetDiscoveredDeviceByIP(ip string) (*model.DiscoveredDevice, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
@@ -204,7 +208,7 @@ func (ss *SQLiteStorage) GetDiscoveredDeviceByIP(ip string) (*model.DiscoveredDe
 }
 
 // CreateOrUpdateDiscoveredDevice creates or updates a discovered device
-func (ss *SQLiteStorage) CreateOrUpdateDiscoveredDevice(device *model.DiscoveredDevice) error {
+func (ss *sqlStorage) CreateOrUpdateDiscoveredDevice(device *model.DiscoveredDevice) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -295,7 +299,7 @@ func (ss *SQLiteStorage) CreateOrUpdateDiscoveredDevice(device *model.Discovered
 }
 
 // PromoteDevice promotes a discovered device to a documented device
-func (ss *SQLiteStorage) PromoteDevice(id string, req *model.PromoteDeviceRequest) (*model.Device, error) {
+func (ss *sqlStorage) PromoteDevice(id string, req *model.PromoteDeviceRequest) (*model.Device, error) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -341,11 +345,12 @@ func (ss *SQLiteStorage) PromoteDevice(id string, req *model.PromoteDeviceReques
 	// Create address from discovered IP
 	device.Addresses = []model.Address{
 		{
-			IP:        discovered.IP,
-			Port:      0,
-			Type:      "ipv4",
-			Label:     "discovered",
-			NetworkID: discovered.NetworkID,
+			IP:         discovered.IP,
+			Port:       0,
+			Type:       "ipv4",
+			Label:      "discovered",
+			NetworkID:  discovered.NetworkID,
+			MACAddress: discovered.MACAddress,
 		},
 	}
 
@@ -385,7 +390,7 @@ func (ss *SQLiteStorage) PromoteDevice(id string, req *model.PromoteDeviceReques
 }
 
 // BulkPromoteDevices promotes multiple discovered devices
-func (ss *SQLiteStorage) BulkPromoteDevices(ids []string, promoteReqs []model.PromoteDeviceRequest) ([]model.Device, []error) {
+func (ss *sqlStorage) BulkPromoteDevices(ids []string, promoteReqs []model.PromoteDeviceRequest) ([]model.Device, []error) {
 	var devices []model.Device
 	var errs []error
 
@@ -411,7 +416,7 @@ func (ss *SQLiteStorage) BulkPromoteDevices(ids []string, promoteReqs []model.Pr
 }
 
 // DeleteDiscoveredDevice deletes a discovered device
-func (ss *SQLiteStorage) DeleteDiscoveredDevice(id string) error {
+func (ss *sqlStorage) DeleteDiscoveredDevice(id string) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -429,7 +434,7 @@ func (ss *SQLiteStorage) DeleteDiscoveredDevice(id string) error {
 }
 
 // CleanupOldDevices removes old discovered devices that haven't been seen
-func (ss *SQLiteStorage) CleanupOldDevices(olderThanDays int) (int, error) {
+func (ss *sqlStorage) CleanupOldDevices(olderThanDays int) (int, error) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -449,14 +454,14 @@ func (ss *SQLiteStorage) CleanupOldDevices(olderThanDays int) (int, error) {
 }
 
 // ListDiscoveryScans returns discovery scans for a network
-func (ss *SQLiteStorage) ListDiscoveryScans(networkID string) ([]model.DiscoveryScan, error) {
+func (ss *sqlStorage) ListDiscoveryScans(networkID string) ([]model.DiscoveryScan, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	query := `
-		SELECT id, network_id, status, scan_type, scan_depth,
+		SELECT id, network_id, rule_id, status, scan_type, scan_depth,
 		       total_hosts, scanned_hosts, found_hosts,
-		       started_at, completed_at, duration_seconds, error_message,
+		       started_at, completed_at, duration_seconds, error_message, change_summary,
 		       created_at, updated_at
 		FROM discovery_scans
 		WHERE 1=1
@@ -480,18 +485,19 @@ func (ss *SQLiteStorage) ListDiscoveryScans(networkID string) ([]model.Discovery
 	for rows.Next() {
 		var s model.DiscoveryScan
 		var startedAt, completedAt sql.NullTime
-		var errorMessage sql.NullString
+		var ruleID, errorMessage, changes sql.NullString
 
 		err := rows.Scan(
-			&s.ID, &s.NetworkID, &s.Status, &s.ScanType, &s.ScanDepth,
+			&s.ID, &s.NetworkID, &ruleID, &s.Status, &s.ScanType, &s.ScanDepth,
 			&s.TotalHosts, &s.ScannedHosts, &s.FoundHosts,
-			&startedAt, &completedAt, &s.DurationSeconds, &errorMessage,
+			&startedAt, &completedAt, &s.DurationSeconds, &errorMessage, &changes,
 			&s.CreatedAt, &s.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scanning discovery scan: %w", err)
 		}
 
+		s.RuleID = ruleID.String
 		if startedAt.Valid {
 			s.StartedAt = &startedAt.Time
 		}
@@ -501,6 +507,9 @@ func (ss *SQLiteStorage) ListDiscoveryScans(networkID string) ([]model.Discovery
 		if errorMessage.Valid {
 			s.ErrorMessage = errorMessage.String
 		}
+		if changes.Valid {
+			json.Unmarshal([]byte(changes.String), &s.Changes)
+		}
 
 		// Calculate progress percentage
 		if s.TotalHosts > 0 {
@@ -514,14 +523,14 @@ func (ss *SQLiteStorage) ListDiscoveryScans(networkID string) ([]model.Discovery
 }
 
 // GetDiscoveryScan retrieves a discovery scan by ID
-func (ss *SQLiteStorage) GetDiscoveryScan(id string) (*model.DiscoveryScan, error) {
+func (ss *sqlStorage) GetDiscoveryScan(id string) (*model.DiscoveryScan, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	query := `
-		SELECT id, network_id, status, scan_type, scan_depth,
+		SELECT id, network_id, rule_id, status, scan_type, scan_depth,
 		       total_hosts, scanned_hosts, found_hosts,
-		       started_at, completed_at, duration_seconds, error_message,
+		       started_at, completed_at, duration_seconds, error_message, change_summary,
 		       created_at, updated_at
 		FROM discovery_scans
 		WHERE id = ?
@@ -529,12 +538,12 @@ func (ss *SQLiteStorage) GetDiscoveryScan(id string) (*model.DiscoveryScan, erro
 
 	var s model.DiscoveryScan
 	var startedAt, completedAt sql.NullTime
-	var errorMessage sql.NullString
+	var ruleID, errorMessage, changes sql.NullString
 
 	err := ss.db.QueryRow(query, id).Scan(
-		&s.ID, &s.NetworkID, &s.Status, &s.ScanType, &s.ScanDepth,
+		&s.ID, &s.NetworkID, &ruleID, &s.Status, &s.ScanType, &s.ScanDepth,
 		&s.TotalHosts, &s.ScannedHosts, &s.FoundHosts,
-		&startedAt, &completedAt, &s.DurationSeconds, &errorMessage,
+		&startedAt, &completedAt, &s.DurationSeconds, &errorMessage, &changes,
 		&s.CreatedAt, &s.UpdatedAt,
 	)
 
@@ -545,6 +554,7 @@ func (ss *SQLiteStorage) GetDiscoveryScan(id string) (*model.DiscoveryScan, erro
 		return nil, err
 	}
 
+	s.RuleID = ruleID.String
 	if startedAt.Valid {
 		s.StartedAt = &startedAt.Time
 	}
@@ -554,6 +564,9 @@ func (ss *SQLiteStorage) GetDiscoveryScan(id string) (*model.DiscoveryScan, erro
 	if errorMessage.Valid {
 		s.ErrorMessage = errorMessage.String
 	}
+	if changes.Valid {
+		json.Unmarshal([]byte(changes.String), &s.Changes)
+	}
 
 	if s.TotalHosts > 0 {
 		s.ProgressPercent = float64(s.ScannedHosts) / float64(s.TotalHosts) * 100
@@ -563,7 +576,7 @@ func (ss *SQLiteStorage) GetDiscoveryScan(id string) (*model.DiscoveryScan, erro
 }
 
 // CreateDiscoveryScan creates a new discovery scan
-func (ss *SQLiteStorage) CreateDiscoveryScan(scan *model.DiscoveryScan) error {
+func (ss *sqlStorage) CreateDiscoveryScan(scan *model.DiscoveryScan) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -573,16 +586,16 @@ func (ss *SQLiteStorage) CreateDiscoveryScan(scan *model.DiscoveryScan) error {
 
 	_, err := ss.db.Exec(`
 		INSERT INTO discovery_scans
-		    (id, network_id, status, scan_type, scan_depth,
+		    (id, network_id, rule_id, status, scan_type, scan_depth,
 		     total_hosts, scanned_hosts, found_hosts,
-		     started_at, completed_at, duration_seconds, error_message,
+		     started_at, completed_at, duration_seconds, error_message, change_summary,
 		     created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
-		scan.ID, scan.NetworkID, scan.Status, scan.ScanType, scan.ScanDepth,
+		scan.ID, scan.NetworkID, nullString(scan.RuleID), scan.Status, scan.ScanType, scan.ScanDepth,
 		scan.TotalHosts, scan.ScannedHosts, scan.FoundHosts,
 		timePtr(scan.StartedAt), timePtr(scan.CompletedAt),
-		scan.DurationSeconds, nullString(scan.ErrorMessage),
+		scan.DurationSeconds, nullString(scan.ErrorMessage), changeSummary(scan.Changes),
 		scan.CreatedAt, scan.UpdatedAt,
 	)
 
@@ -594,19 +607,19 @@ func (ss *SQLiteStorage) CreateDiscoveryScan(scan *model.DiscoveryScan) error {
 }
 
 // UpdateDiscoveryScan updates a discovery scan
-func (ss *SQLiteStorage) UpdateDiscoveryScan(scan *model.DiscoveryScan) error {
+func (ss *sqlStorage) UpdateDiscoveryScan(scan *model.DiscoveryScan) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
 	result, err := ss.db.Exec(`
 		UPDATE discovery_scans
 		SET status = ?, total_hosts = ?, scanned_hosts = ?, found_hosts = ?,
-		    started_at = ?, completed_at = ?, duration_seconds = ?, error_message = ?, updated_at = ?
+		    started_at = ?, completed_at = ?, duration_seconds = ?, error_message = ?, change_summary = ?, updated_at = ?
 		WHERE id = ?
 	`,
 		scan.Status, scan.TotalHosts, scan.ScannedHosts, scan.FoundHosts,
 		timePtr(scan.StartedAt), timePtr(scan.CompletedAt),
-		scan.DurationSeconds, nullString(scan.ErrorMessage),
+		scan.DurationSeconds, nullString(scan.ErrorMessage), changeSummary(scan.Changes),
 		scan.UpdatedAt, scan.ID,
 	)
 
@@ -623,7 +636,7 @@ func (ss *SQLiteStorage) UpdateDiscoveryScan(scan *model.DiscoveryScan) error {
 }
 
 // DeleteDiscoveryScan deletes a discovery scan
-func (ss *SQLiteStorage) DeleteDiscoveryScan(id string) error {
+func (ss *sqlStorage) DeleteDiscoveryScan(id string) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -640,17 +653,131 @@ func (ss *SQLiteStorage) DeleteDiscoveryScan(id string) error {
 	return nil
 }
 
+// GetDiscoveryScanHosts returns the hosts finished by a scan, mapped to whether a device was found
+func (ss *sqlStorage) GetDiscoveryScanHosts(scanID string) (map[string]bool, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	rows, err := ss.db.Query(`SELECT ip, found FROM discovery_scan_hosts WHERE scan_id = ?`, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("querying discovery scan hosts: %w", err)
+	}
+	defer rows.Close()
+
+	hosts := make(map[string]bool)
+	for rows.Next() {
+		var ip string
+		var found bool
+		if err := rows.Scan(&ip, &found); err != nil {
+			return nil, fmt.Errorf("scanning discovery scan host: %w", err)
+		}
+		hosts[ip] = found
+	}
+	return hosts, rows.Err()
+}
+
+// MarkDiscoveryScanHost records that a scan has finished a host
+func (ss *sqlStorage) MarkDiscoveryScanHost(scanID, ip string, found bool) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	_, err := ss.db.Exec(`
+		INSERT INTO discovery_scan_hosts (scan_id, ip, found) VALUES (?, ?, ?)
+		ON CONFLICT (scan_id, ip) DO UPDATE SET found = excluded.found
+	`, scanID, ip, found)
+	if err != nil {
+		return fmt.Errorf("marking discovery scan host: %w", err)
+	}
+	return nil
+}
+
+// DeleteDiscoveryScanHosts removes the progress of a scan that no longer needs to resume
+func (ss *sqlStorage) DeleteDiscoveryScanHosts(scanID string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if _, err := ss.db.Exec(`DELETE FROM discovery_scan_hosts WHERE scan_id = ?`, scanID); err != nil {
+		return fmt.Errorf("deleting discovery scan hosts: %w", err)
+	}
+	return nil
+}
+
+// RecordDiscoveryObservation stores what a scan saw on a host, replacing an
+// earlier observation of the host by the same scan
+func (ss *sqlStorage) RecordDiscoveryObservation(obs *model.DiscoveryObservation) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if obs.ObservedAt.IsZero() {
+		obs.ObservedAt = time.Now()
+	}
+
+	_, err := ss.db.Exec(`
+		INSERT INTO discovery_observations
+		    (scan_id, ip, status, mac_address, hostname, os_guess, open_ports, observed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (scan_id, ip) DO UPDATE SET
+		    status = excluded.status, mac_address = excluded.mac_address,
+		    hostname = excluded.hostname, os_guess = excluded.os_guess,
+		    open_ports = excluded.open_ports, observed_at = excluded.observed_at
+	`,
+		obs.ScanID, obs.IP, obs.Status,
+		nullString(obs.MACAddress), nullString(obs.Hostname), nullString(obs.OSGuess),
+		jsonBytes(obs.OpenPorts), obs.ObservedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("recording discovery observation: %w", err)
+	}
+	return nil
+}
+
+// ListDiscoveryObservations returns the hosts seen by a scan, ordered by IP
+func (ss *sqlStorage) ListDiscoveryObservations(scanID string) ([]model.DiscoveryObservation, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	rows, err := ss.db.Query(`
+		SELECT scan_id, ip, status, mac_address, hostname, os_guess, open_ports, observed_at
+		FROM discovery_observations
+		WHERE scan_id = ?
+		ORDER BY ip
+	`, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("querying discovery observations: %w", err)
+	}
+	defer rows.Close()
+
+	var observations []model.DiscoveryObservation
+	for rows.Next() {
+		var o model.DiscoveryObservation
+		var mac, hostname, osGuess, openPorts sql.NullString
+		if err := rows.Scan(&o.ScanID, &o.IP, &o.Status, &mac, &hostname, &osGuess, &openPorts, &o.ObservedAt); err != nil {
+			return nil, fmt.Errorf("scanning discovery observation: %w", err)
+		}
+		o.MACAddress, o.Hostname, o.OSGuess = mac.String, hostname.String, osGuess.String
+		if openPorts.Valid {
+			json.Unmarshal([]byte(openPorts.String), &o.OpenPorts)
+		}
+		observations = append(observations, o)
+	}
+	return observations, rows.Err()
+}
+
 // ListDiscoveryRules returns discovery rules
-func (ss *SQLiteStorage) ListDiscoveryRules(networkID string) ([]model.DiscoveryRule, error) {
+func (ss *sqlStorage) ListDiscoveryRules(networkID string) ([]model.DiscoveryRule, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
+	return ss.listDiscoveryRulesLocked(networkID)
+}
 
+// listDiscoveryRulesLocked returns the rules of a network, or all; caller must hold ss.mu
+func (ss *sqlStorage) listDiscoveryRulesLocked(networkID string) ([]model.DiscoveryRule, error) {
 	query := `
-		SELECT id, network_id, enabled, scan_interval_hours, scan_type,
+		SELECT id, network_id, enabled, scan_interval_hours, schedule, timezone, jitter_minutes, blackouts, probes, scan_type,
 		       max_concurrent_scans, timeout_seconds,
 		       scan_ports, port_scan_type, custom_ports,
 		       service_detection, os_detection,
-		       exclude_ips, exclude_hosts, created_at, updated_at
+		       exclude_ips, exclude_hosts, last_run_at, next_run_at, created_at, updated_at
 		FROM discovery_rules
 		WHERE 1=1
 	`
@@ -670,14 +797,15 @@ func (ss *SQLiteStorage) ListDiscoveryRules(networkID string) ([]model.Discovery
 	var rules []model.DiscoveryRule
 	for rows.Next() {
 		var r model.DiscoveryRule
-		var customPorts, excludeIPs, excludeHosts sql.NullString
+		var customPorts, excludeIPs, excludeHosts, schedule, timezone, blackouts, probes sql.NullString
+		var lastRunAt, nextRunAt sql.NullTime
 
 		err := rows.Scan(
-			&r.ID, &r.NetworkID, &r.Enabled, &r.ScanIntervalHours, &r.ScanType,
+			&r.ID, &r.NetworkID, &r.Enabled, &r.ScanIntervalHours, &schedule, &timezone, &r.JitterMinutes, &blackouts, &probes, &r.ScanType,
 			&r.MaxConcurrentScans, &r.TimeoutSeconds,
 			&r.ScanPorts, &r.PortScanType, &customPorts,
 			&r.ServiceDetection, &r.OSDetection,
-			&excludeIPs, &excludeHosts, &r.CreatedAt, &r.UpdatedAt,
+			&excludeIPs, &excludeHosts, &lastRunAt, &nextRunAt, &r.CreatedAt, &r.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scanning discovery rule: %w", err)
@@ -693,6 +821,19 @@ func (ss *SQLiteStorage) ListDiscoveryRules(networkID string) ([]model.Discovery
 		if excludeHosts.Valid {
 			json.Unmarshal([]byte(excludeHosts.String), &r.ExcludeHosts)
 		}
+		if blackouts.Valid {
+			json.Unmarshal([]byte(blackouts.String), &r.Blackouts)
+		}
+		if probes.Valid {
+			json.Unmarshal([]byte(probes.String), &r.Probes)
+		}
+		r.Schedule, r.Timezone = schedule.String, timezone.String
+		if lastRunAt.Valid {
+			r.LastRunAt = &lastRunAt.Time
+		}
+		if nextRunAt.Valid {
+			r.NextRunAt = &nextRunAt.Time
+		}
 
 		rules = append(rules, r)
 	}
@@ -701,29 +842,30 @@ func (ss *SQLiteStorage) ListDiscoveryRules(networkID string) ([]model.Discovery
 }
 
 // GetDiscoveryRule retrieves a discovery rule by ID
-func (ss *SQLiteStorage) GetDiscoveryRule(id string) (*model.DiscoveryRule, error) {
+func (ss *sqlStorage) GetDiscoveryRule(id string) (*model.DiscoveryRule, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	query := `
-		SELECT id, network_id, enabled, scan_interval_hours, scan_type,
+		SELECT id, network_id, enabled, scan_interval_hours, schedule, timezone, jitter_minutes, blackouts, probes, scan_type,
 		       max_concurrent_scans, timeout_seconds,
 		       scan_ports, port_scan_type, custom_ports,
 		       service_detection, os_detection,
-		       exclude_ips, exclude_hosts, created_at, updated_at
+		       exclude_ips, exclude_hosts, last_run_at, next_run_at, created_at, updated_at
 		FROM discovery_rules
 		WHERE id = ?
 	`
 
 	var r model.DiscoveryRule
-	var customPorts, excludeIPs, excludeHosts sql.NullString
+	var customPorts, excludeIPs, excludeHosts, schedule, timezone, blackouts, probes sql.NullString
+	var lastRunAt, nextRunAt sql.NullTime
 
 	err := ss.db.QueryRow(query, id).Scan(
-		&r.ID, &r.NetworkID, &r.Enabled, &r.ScanIntervalHours, &r.ScanType,
+		&r.ID, &r.NetworkID, &r.Enabled, &r.ScanIntervalHours, &schedule, &timezone, &r.JitterMinutes, &blackouts, &probes, &r.ScanType,
 		&r.MaxConcurrentScans, &r.TimeoutSeconds,
 		&r.ScanPorts, &r.PortScanType, &customPorts,
 		&r.ServiceDetection, &r.OSDetection,
-		&excludeIPs, &excludeHosts, &r.CreatedAt, &r.UpdatedAt,
+		&excludeIPs, &excludeHosts, &lastRunAt, &nextRunAt, &r.CreatedAt, &r.UpdatedAt,
 	)
 
 	if err != nil {
@@ -743,34 +885,48 @@ func (ss *SQLiteStorage) GetDiscoveryRule(id string) (*model.DiscoveryRule, erro
 	if excludeHosts.Valid {
 		json.Unmarshal([]byte(excludeHosts.String), &r.ExcludeHosts)
 	}
+	if blackouts.Valid {
+		json.Unmarshal([]byte(blackouts.String), &r.Blackouts)
+	}
+	if probes.Valid {
+		json.Unmarshal([]byte(probes.String), &r.Probes)
+	}
+	r.Schedule, r.Timezone = schedule.String, timezone.String
+	if lastRunAt.Valid {
+		r.LastRunAt = &lastRunAt.Time
+	}
+	if nextRunAt.Valid {
+		r.NextRunAt = &nextRunAt.Time
+	}
 
 	return &r, nil
 }
 
 // GetDiscoveryRuleByNetwork retrieves a discovery rule by network ID
-func (ss *SQLiteStorage) GetDiscoveryRuleByNetwork(networkID string) (*model.DiscoveryRule, error) {
+func (ss *sqlStorage) GetDiscoveryRuleByNetwork(networkID string) (*model.DiscoveryRule, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	query := `
-		SELECT id, network_id, enabled, scan_interval_hours, scan_type,
+		SELECT id, network_id, enabled, scan_interval_hours, schedule, timezone, jitter_minutes, blackouts, probes, scan_type,
 		       max_concurrent_scans, timeout_seconds,
 		       scan_ports, port_scan_type, custom_ports,
 		       service_detection, os_detection,
-		       exclude_ips, exclude_hosts, created_at, updated_at
+		       exclude_ips, exclude_hosts, last_run_at, next_run_at, created_at, updated_at
 		FROM discovery_rules
 		WHERE network_id = ?
 	`
 
 	var r model.DiscoveryRule
-	var customPorts, excludeIPs, excludeHosts sql.NullString
+	var customPorts, excludeIPs, excludeHosts, schedule, timezone, blackouts, probes sql.NullString
+	var lastRunAt, nextRunAt sql.NullTime
 
 	err := ss.db.QueryRow(query, networkID).Scan(
-		&r.ID, &r.NetworkID, &r.Enabled, &r.ScanIntervalHours, &r.ScanType,
+		&r.ID, &r.NetworkID, &r.Enabled, &r.ScanIntervalHours, &schedule, &timezone, &r.JitterMinutes, &blackouts, &probes, &r.ScanType,
 		&r.MaxConcurrentScans, &r.TimeoutSeconds,
 		&r.ScanPorts, &r.PortScanType, &customPorts,
 		&r.ServiceDetection, &r.OSDetection,
-		&excludeIPs, &excludeHosts, &r.CreatedAt, &r.UpdatedAt,
+		&excludeIPs, &excludeHosts, &lastRunAt, &nextRunAt, &r.CreatedAt, &r.UpdatedAt,
 	)
 
 	if err != nil {
@@ -790,12 +946,25 @@ func (ss *SQLiteStorage) GetDiscoveryRuleByNetwork(networkID string) (*model.Dis
 	if excludeHosts.Valid {
 		json.Unmarshal([]byte(excludeHosts.String), &r.ExcludeHosts)
 	}
+	if blackouts.Valid {
+		json.Unmarshal([]byte(blackouts.String), &r.Blackouts)
+	}
+	if probes.Valid {
+		json.Unmarshal([]byte(probes.String), &r.Probes)
+	}
+	r.Schedule, r.Timezone = schedule.String, timezone.String
+	if lastRunAt.Valid {
+		r.LastRunAt = &lastRunAt.Time
+	}
+	if nextRunAt.Valid {
+		r.NextRunAt = &nextRunAt.Time
+	}
 
 	return &r, nil
 }
 
 // CreateDiscoveryRule creates a new discovery rule
-func (ss *SQLiteStorage) CreateDiscoveryRule(rule *model.DiscoveryRule) error {
+func (ss *sqlStorage) CreateDiscoveryRule(rule *model.DiscoveryRule) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -803,20 +972,26 @@ func (ss *SQLiteStorage) CreateDiscoveryRule(rule *model.DiscoveryRule) error {
 		rule.ID = generateUUID()
 	}
 
-	_, err := ss.db.Exec(`
+	return insertDiscoveryRule(ss.db, rule)
+}
+
+func insertDiscoveryRule(ex execer, rule *model.DiscoveryRule) error {
+	_, err := ex.Exec(`
 		INSERT INTO discovery_rules
-		    (id, network_id, enabled, scan_interval_hours, scan_type,
+		    (id, network_id, enabled, scan_interval_hours, schedule, timezone, jitter_minutes, blackouts, probes, scan_type,
 		     max_concurrent_scans, timeout_seconds,
 		     scan_ports, port_scan_type, custom_ports,
 		     service_detection, os_detection,
-		     exclude_ips, exclude_hosts, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		     exclude_ips, exclude_hosts, last_run_at, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
-		rule.ID, rule.NetworkID, rule.Enabled, rule.ScanIntervalHours, rule.ScanType,
+		rule.ID, rule.NetworkID, rule.Enabled, rule.ScanIntervalHours,
+		rule.Schedule, rule.Timezone, rule.JitterMinutes, jsonBytes(rule.Blackouts), jsonBytes(rule.Probes), rule.ScanType,
 		rule.MaxConcurrentScans, rule.TimeoutSeconds,
 		rule.ScanPorts, rule.PortScanType, jsonBytes(rule.CustomPorts),
 		rule.ServiceDetection, rule.OSDetection,
 		jsonBytes(rule.ExcludeIPs), jsonBytes(rule.ExcludeHosts),
+		timePtr(rule.LastRunAt), timePtr(rule.NextRunAt),
 		rule.CreatedAt, rule.UpdatedAt,
 	)
 
@@ -828,20 +1003,21 @@ func (ss *SQLiteStorage) CreateDiscoveryRule(rule *model.DiscoveryRule) error {
 }
 
 // UpdateDiscoveryRule updates a discovery rule
-func (ss *SQLiteStorage) UpdateDiscoveryRule(rule *model.DiscoveryRule) error {
+func (ss *sqlStorage) UpdateDiscoveryRule(rule *model.DiscoveryRule) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
 	result, err := ss.db.Exec(`
 		UPDATE discovery_rules
-		SET enabled = ?, scan_interval_hours = ?, scan_type = ?,
+		SET enabled = ?, scan_interval_hours = ?, schedule = ?, timezone = ?, jitter_minutes = ?, blackouts = ?, probes = ?, scan_type = ?,
 		    max_concurrent_scans = ?, timeout_seconds = ?,
 		    scan_ports = ?, port_scan_type = ?, custom_ports = ?,
 		    service_detection = ?, os_detection = ?,
 		    exclude_ips = ?, exclude_hosts = ?, updated_at = ?
 		WHERE id = ?
 	`,
-		rule.Enabled, rule.ScanIntervalHours, rule.ScanType,
+		rule.Enabled, rule.ScanIntervalHours,
+		rule.Schedule, rule.Timezone, rule.JitterMinutes, jsonBytes(rule.Blackouts), jsonBytes(rule.Probes), rule.ScanType,
 		rule.MaxConcurrentScans, rule.TimeoutSeconds,
 		rule.ScanPorts, rule.PortScanType, jsonBytes(rule.CustomPorts),
 		rule.ServiceDetection, rule.OSDetection,
@@ -861,8 +1037,27 @@ func (ss *SQLiteStorage) UpdateDiscoveryRule(rule *model.DiscoveryRule) error {
 	return nil
 }
 
+// SetDiscoveryRuleSchedule records when a rule last ran and is next due
+func (ss *sqlStorage) SetDiscoveryRuleSchedule(id string, lastRunAt, nextRunAt *time.Time) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	result, err := ss.db.Exec(`UPDATE discovery_rules SET last_run_at = ?, next_run_at = ? WHERE id = ?`,
+		timePtr(lastRunAt), timePtr(nextRunAt), id)
+	if err != nil {
+		return fmt.Errorf("updating discovery rule schedule: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrDiscoveryRuleNotFound
+	}
+
+	return nil
+}
+
 // DeleteDiscoveryRule deletes a discovery rule
-func (ss *SQLiteStorage) DeleteDiscoveryRule(id string) error {
+func (ss *sqlStorage) DeleteDiscoveryRule(id string) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -881,7 +1076,7 @@ func (ss *SQLiteStorage) DeleteDiscoveryRule(id string) error {
 
 // Helper functions
 
-func (ss *SQLiteStorage) getDiscoveredDeviceLocked(id string) (*model.DiscoveredDevice, error) {
+func (ss *sqlStorage) getDiscoveredDeviceLocked(id string) (*model.DiscoveredDevice, error) {
 	query := `
 		SELECT id, ip, mac_address, hostname, network_id, status, confidence,
 		       os_guess, os_family, open_ports, services, first_seen, last_seen,
@@ -947,23 +1142,35 @@ func (ss *SQLiteStorage) getDiscoveredDeviceLocked(id string) (*model.Discovered
 	return &d, nil
 }
 
-func (ss *SQLiteStorage) insertDeviceTx(tx *sql.Tx, device *model.Device) error {
+func (ss *sqlStorage) insertDeviceTx(tx sqlTx, device *model.Device) error {
+	if device.Status == "" {
+		device.Status = model.DeviceStatusActive
+	}
+	if !device.Status.Valid() {
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, device.Status)
+	}
+	device.StatusChangedAt = &device.CreatedAt
+	initVersion(&device.Version)
+
 	_, err := tx.Exec(`
-		INSERT INTO devices (id, name, description, make_model, os, datacenter_id, username, location, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO devices (id, name, description, make_model, os, datacenter_id, username, location, status, status_changed_at, created_at, updated_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, device.ID, device.Name, device.Description, device.MakeModel, device.OS,
-		device.DatacenterID, device.Username, device.Location, device.CreatedAt, device.UpdatedAt)
+		device.DatacenterID, device.Username, device.Location, device.Status, device.StatusChangedAt, device.CreatedAt, device.UpdatedAt, device.Version)
 
 	if err != nil {
 		return fmt.Errorf("inserting device: %w", err)
 	}
+	if _, err := recordStatusChangeTx(tx, device.ID, "", device.Status, "", device.CreatedAt); err != nil {
+		return err
+	}
 
 	// Insert addresses
 	for _, addr := range device.Addresses {
 		_, err := tx.Exec(`
-			INSERT INTO addresses (device_id, ip, port, type, label, network_id, switch_port)
-			VALUES (?, ?, ?, ?, ?, ?, ?)
-		`, device.ID, addr.IP, addr.Port, addr.Type, addr.Label, addr.NetworkID, addr.SwitchPort)
+			INSERT INTO addresses (device_id, ip, port, type, label, network_id, switch_port, mac_address)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, device.ID, addr.IP, addr.Port, addr.Type, addr.Label, addr.NetworkID, addr.SwitchPort, nullString(addr.MACAddress))
 		if err != nil {
 			return fmt.Errorf("inserting address: %w", err)
 		}
@@ -1007,6 +1214,14 @@ func jsonBytes(v interface{}) interface{} {
 	return string(b)
 }
 
+// changeSummary returns the JSON of a scan's change summary, or NULL while it has none
+func changeSummary(c *model.DiscoveryChangeSummary) interface{} {
+	if c == nil {
+		return nil
+	}
+	return jsonBytes(c)
+}
+
 func timePtr(t *time.Time) interface{} {
 	if t == nil {
 		return nil
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+var (
+	// ErrInvalidExport is returned when an export cannot be restored
+	ErrInvalidExport = errors.New("invalid export")
+	// ErrInventoryNotEmpty is returned when restoring over existing data without replacing it
+	ErrInventoryNotEmpty = errors.New("inventory is not empty")
+)
+
+// ExportStorage defines the interface for full inventory exports and restores
+type ExportStorage interface {
+	// Export returns a consistent snapshot of the whole inventory
+	Export() (*model.Export, error)
+	// Restore loads an export in a single transaction, keeping IDs and timestamps.
+	// Unless replace is set, the inventory must be empty apart from the built-in
+	// default datacenter; with replace, all existing data is removed first.
+	Restore(export *model.Export, replace bool) (*model.RestoreResult, error)
+}
+
+// maxExportProblems limits how many reference problems are reported at once
+const maxExportProblems = 20
+
+// validateExport checks the export version, duplicate IDs and that every
+// reference points at an entity within the export
+func validateExport(export *model.Export) error {
+	if export.Version < 1 || export.Version > model.ExportVersion {
+		return fmt.Errorf("%w: unsupported version %d (this release reads versions 1 to %d)", ErrInvalidExport, export.Version, model.ExportVersion)
+	}
+
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	index := func(kind string, ids []string) map[string]bool {
+		set := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			if id == "" {
+				problem("%s without id", kind)
+			} else if set[id] {
+				problem("duplicate %s id %q", kind, id)
+			}
+			set[id] = true
+		}
+		return set
+	}
+
+	ids := make([]string, len(export.CustomFields))
+	for i, def := range export.CustomFields {
+		ids[i] = def.ID
+		if err := validateCustomFieldDefinition(&def); err != nil {
+			problem("custom field %q: %v", def.Name, err)
+		}
+	}
+	index("custom field", ids)
+
+	ids = make([]string, len(export.Datacenters))
+	for i, dc := range export.Datacenters {
+		ids[i] = dc.ID
+	}
+	datacenters := index("datacenter", ids)
+
+	ids = make([]string, len(export.Networks))
+	for i, n := range export.Networks {
+		ids[i] = n.ID
+		if n.DatacenterID != "" && !datacenters[n.DatacenterID] {
+			problem("network %q refers to unknown datacenter %q", n.ID, n.DatacenterID)
+		}
+	}
+	networks := index("network", ids)
+
+	ids = make([]string, len(export.Pools))
+	for i, p := range export.Pools {
+		ids[i] = p.ID
+		if !networks[p.NetworkID] {
+			problem("pool %q refers to unknown network %q", p.ID, p.NetworkID)
+		}
+	}
+	pools := index("pool", ids)
+
+	ids = make([]string, len(export.Devices))
+	for i, d := range export.Devices {
+		ids[i] = d.ID
+		if d.DatacenterID != "" && !datacenters[d.DatacenterID] {
+			problem("device %q refers to unknown datacenter %q", d.ID, d.DatacenterID)
+		}
+		for _, addr := range d.Addresses {
+			if addr.NetworkID != "" && !networks[addr.NetworkID] {
+				problem("device %q address %s refers to unknown network %q", d.ID, addr.IP, addr.NetworkID)
+			}
+			if addr.PoolID != "" && !pools[addr.PoolID] {
+				problem("device %q address %s refers to unknown pool %q", d.ID, addr.IP, addr.PoolID)
+			}
+		}
+	}
+	devices := index("device", ids)
+
+	for _, r := range export.Relationships {
+		if !devices[r.ParentID] || !devices[r.ChildID] {
+			problem("relationship %s -> %s refers to an unknown device", r.ParentID, r.ChildID)
+		}
+	}
+	for _, inv := range export.Software {
+		if !devices[inv.DeviceID] {
+			problem("software inventory refers to unknown device %q", inv.DeviceID)
+		}
+	}
+
+	ids = make([]string, len(export.DiscoveryRules))
+	for i, rule := range export.DiscoveryRules {
+		ids[i] = rule.ID
+		if !networks[rule.NetworkID] {
+			problem("discovery rule %q refers to unknown network %q", rule.ID, rule.NetworkID)
+		}
+	}
+	index("discovery rule", ids)
+
+	ids = make([]string, len(export.DiscoveredDevices))
+	for i, d := range export.DiscoveredDevices {
+		ids[i] = d.ID
+		if !networks[d.NetworkID] {
+			problem("discovered device %q refers to unknown network %q", d.ID, d.NetworkID)
+		}
+		if d.PromotedToDeviceID != "" && !devices[d.PromotedToDeviceID] {
+			problem("discovered device %q refers to unknown device %q", d.ID, d.PromotedToDeviceID)
+		}
+	}
+	index("discovered device", ids)
+
+	if len(problems) == 0 {
+		return nil
+	}
+	if len(problems) > maxExportProblems {
+		problems = append(problems[:maxExportProblems], fmt.Sprintf("and %d more", len(problems)-maxExportProblems))
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidExport, strings.Join(problems, "; "))
+}
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// restoreTables lists the tables cleared before a restore, children before parents
+var restoreTables = []string{
+	"device_packages",
+	"device_software",
+	"device_assets",
+	"device_status_history",
+	"device_labels",
+	"network_labels",
+	"pool_labels",
+	"custom_field_values",
+	"custom_field_definitions",
+	"relationships",
+	"addresses",
+	"tags",
+	"domains",
+	"pool_tags",
+	"discovered_devices",
+	"discovery_scan_hosts",
+	"discovery_observations",
+	"discovery_scans",
+	"discovery_rules",
+	"promotion_policies",
+	"devices",
+	"network_pools",
+	"networks",
+	"datacenters",
+}
+
+// Export returns a consistent snapshot of the whole inventory
+func (ss *sqlStorage) Export() (*model.Export, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	log.Debug("Exporting inventory")
+
+	export := &model.Export{Version: model.ExportVersion, ExportedAt: time.Now().UTC()}
+	var err error
+	if export.CustomFields, err = ss.listCustomFields(ss.db, ""); err != nil {
+		return nil, err
+	}
+	if export.Datacenters, err = ss.listDatacentersLocked(nil); err != nil {
+		return nil, err
+	}
+	if export.Networks, err = ss.listNetworksLocked(nil); err != nil {
+		return nil, err
+	}
+	if export.Pools, err = ss.listNetworkPoolsLocked(nil); err != nil {
+		return nil, err
+	}
+	if export.Devices, err = ss.listDevicesLocked(nil); err != nil {
+		return nil, err
+	}
+	if export.Relationships, err = ss.listRelationshipsLocked(""); err != nil {
+		return nil, err
+	}
+	if export.Software, err = listSoftwareInventories(ss.db); err != nil {
+		return nil, err
+	}
+	if export.DiscoveryRules, err = ss.listDiscoveryRulesLocked(""); err != nil {
+		return nil, err
+	}
+	if export.PromotionPolicies, err = listPromotionPolicies(ss.db); err != nil {
+		return nil, err
+	}
+	if export.DiscoveredDevices, err = ss.listDiscoveredDevicesLocked(nil); err != nil {
+		return nil, err
+	}
+
+	// Empty sections are written as [] rather than null
+	if export.Pools == nil {
+		export.Pools = []model.NetworkPool{}
+	}
+	if export.Relationships == nil {
+		export.Relationships = []model.DeviceRelationship{}
+	}
+	if export.DiscoveryRules == nil {
+		export.DiscoveryRules = []model.DiscoveryRule{}
+	}
+	if export.DiscoveredDevices == nil {
+		export.DiscoveredDevices = []model.DiscoveredDevice{}
+	}
+
+	log.Info("Inventory exported", "datacenters", len(export.Datacenters), "networks", len(export.Networks), "devices", len(export.Devices))
+	return export, nil
+}
+
+// Restore replaces the inventory with the contents of an export
+func (ss *sqlStorage) Restore(export *model.Export, replace bool) (*model.RestoreResult, error) {
+	if err := validateExport(export); err != nil {
+		return nil, err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	log.Debug("Restoring inventory", "version", export.Version, "replace", replace)
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if !replace {
+		var count int
+		err := tx.QueryRow(`
+			SELECT (SELECT COUNT(*) FROM datacenters WHERE id != 'default')
+			     + (SELECT COUNT(*) FROM networks)
+			     + (SELECT COUNT(*) FROM devices)
+			     + (SELECT COUNT(*) FROM discovered_devices)
+			     + (SELECT COUNT(*) FROM discovery_rules)
+			     + (SELECT COUNT(*) FROM promotion_policies)
+			     + (SELECT COUNT(*) FROM custom_field_definitions)
+		`).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("checking existing data: %w", err)
+		}
+		if count > 0 {
+			return nil, ErrInventoryNotEmpty
+		}
+	}
+
+	for _, table := range restoreTables {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return nil, fmt.Errorf("clearing %s: %w", table, err)
+		}
+	}
+
+	result := &model.RestoreResult{Version: export.Version}
+
+	// Definitions are inserted as optional and made required once all values are in,
+	// since entities created before a field became required have no value for it
+	for _, def := range export.CustomFields {
+		def.Required = false
+		if err := insertCustomField(tx, &def); err != nil {
+			return nil, err
+		}
+		result.CustomFields++
+	}
+
+	for _, dc := range export.Datacenters {
+		if err := ss.createDatacenterTx(tx, &dc); err != nil {
+			return nil, fmt.Errorf("restoring datacenter %q: %w", dc.ID, err)
+		}
+		result.Datacenters++
+	}
+
+	for _, network := range export.Networks {
+		if err := ss.createNetworkTx(tx, &network); err != nil {
+			return nil, fmt.Errorf("restoring network %q: %w", network.ID, err)
+		}
+		result.Networks++
+	}
+
+	for _, pool := range export.Pools {
+		if err := createNetworkPoolTx(tx, &pool); err != nil {
+			return nil, fmt.Errorf("restoring pool %q: %w", pool.ID, err)
+		}
+		result.Pools++
+	}
+
+	for _, device := range export.Devices {
+		if err := ss.createDeviceTx(tx, &device); err != nil {
+			return nil, fmt.Errorf("restoring device %q: %w", device.ID, err)
+		}
+		result.Devices++
+	}
+
+	for _, r := range export.Relationships {
+		if _, err := tx.Exec(`INSERT INTO relationships (parent_id, child_id, type, created_at) VALUES (?, ?, ?, ?)`,
+			r.ParentID, r.ChildID, r.Type, r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("restoring relationship %s -> %s: %w", r.ParentID, r.ChildID, err)
+		}
+		result.Relationships++
+	}
+
+	for _, inventory := range export.Software {
+		if inventory.CollectedAt == nil {
+			now := time.Now()
+			inventory.CollectedAt = &now
+		}
+		if err := saveDeviceSoftwareTx(tx, &inventory); err != nil {
+			return nil, fmt.Errorf("restoring software of device %q: %w", inventory.DeviceID, err)
+		}
+		result.Software++
+	}
+
+	for _, rule := range export.DiscoveryRules {
+		if err := insertDiscoveryRule(tx, &rule); err != nil {
+			return nil, err
+		}
+		result.DiscoveryRules++
+	}
+
+	for _, policy := range export.PromotionPolicies {
+		if err := insertPromotionPolicy(tx, &policy); err != nil {
+			return nil, err
+		}
+		result.PromotionPolicies++
+	}
+
+	for _, d := range export.DiscoveredDevices {
+		if err := insertDiscoveredDevice(tx, &d); err != nil {
+			return nil, err
+		}
+		result.DiscoveredDevices++
+	}
+
+	if err := restoreRequiredCustomFields(tx, export.CustomFields); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing restore: %w", err)
+	}
+
+	log.Info("Inventory restored", "version", export.Version, "datacenters", result.Datacenters, "networks", result.Networks, "devices", result.Devices)
+	return result, nil
+}
+
+// restoreRequiredCustomFields marks custom fields required again after all values were
+// restored. The update trigger refreshes their update time.
+func restoreRequiredCustomFields(tx sqlTx, defs []model.CustomFieldDefinition) error {
+	for _, def := range defs {
+		if !def.Required {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE custom_field_definitions SET required = ? WHERE id = ?`, true, def.ID); err != nil {
+			return fmt.Errorf("restoring custom field %q: %w", def.Name, err)
+		}
+	}
+	return nil
+}
+
+func insertDiscoveredDevice(ex execer, d *model.DiscoveredDevice) error {
+	_, err := ex.Exec(`
+		INSERT INTO discovered_devices
+		    (id, ip, mac_address, hostname, network_id, status, confidence,
+		     os_guess, os_family, open_ports, services, first_seen, last_seen,
+		     last_scan_id, promoted_to_device_id, promoted_at, raw_scan_data, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		d.ID, d.IP, nullString(d.MACAddress), nullString(d.Hostname), d.NetworkID, d.Status, d.Confidence,
+		nullString(d.OSGuess), nullString(d.OSFamily), jsonBytes(d.OpenPorts), jsonBytes(d.Services),
+		d.FirstSeen, d.LastSeen, nullString(d.LastScanID), nullString(d.PromotedToDeviceID), timePtr(d.PromotedAt),
+		nullString(d.RawScanData), d.CreatedAt, d.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("restoring discovered device %q: %w", d.ID, err)
+	}
+	return nil
+}
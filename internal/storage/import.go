@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// ErrInvalidImport is returned for import rows that cannot be applied
+var ErrInvalidImport = errors.New("invalid import")
+
+// ImportStorage defines the interface for bulk imports
+type ImportStorage interface {
+	// Import upserts the rows of a batch in a single transaction. Rows are matched to
+	// existing entities by ID, then by name, and may refer to other entities by name.
+	// Changes are rolled back when dryRun is set or any row fails; the result reports
+	// the outcome of every row either way.
+	Import(batch *model.ImportBatch, dryRun bool) (*model.ImportResult, error)
+}
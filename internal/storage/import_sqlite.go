@@ -0,0 +1,786 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// importNames maps lower-cased entity names to the IDs using them
+type importNames map[string][]string
+
+func (n importNames) add(name, id string) {
+	key := strings.ToLower(name)
+	for _, existing := range n[key] {
+		if existing == id {
+			return
+		}
+	}
+	n[key] = append(n[key], id)
+}
+
+func (n importNames) remove(name, id string) {
+	key := strings.ToLower(name)
+	ids := n[key]
+	for i, existing := range ids {
+		if existing == id {
+			n[key] = append(ids[:i:i], ids[i+1:]...)
+			return
+		}
+	}
+}
+
+// sqliteImport holds the state of a running import. The indexes start with the
+// existing entities and track rows as they are written, so later rows can refer
+// to entities created earlier in the same batch.
+type sqliteImport struct {
+	ss     *sqlStorage
+	tx     sqlTx
+	result *model.ImportResult
+
+	datacenters     map[string]*model.Datacenter
+	datacenterNames importNames
+	networks        map[string]*model.Network
+	networkNames    importNames
+	pools           map[string]*model.NetworkPool
+	poolNames       importNames
+	devices         map[string]*model.Device
+	deviceNames     importNames
+}
+
+// Import upserts a batch of entities in a single transaction
+func (ss *sqlStorage) Import(batch *model.ImportBatch, dryRun bool) (*model.ImportResult, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	log.Debug("Importing batch", "rows", batch.Len(), "dry_run", dryRun)
+
+	im, err := ss.newImport()
+	if err != nil {
+		return nil, err
+	}
+	im.result.DryRun = dryRun
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+	im.tx = tx
+
+	sections := []struct {
+		entity string
+		rows   []model.ImportRecord
+		apply  func(model.ImportRecord, *model.ImportRowResult) error
+	}{
+		{model.ImportEntityDatacenter, batch.Datacenters, im.importDatacenter},
+		{model.ImportEntityNetwork, batch.Networks, im.importNetwork},
+		{model.ImportEntityPool, batch.Pools, im.importPool},
+		{model.ImportEntityDevice, batch.Devices, im.importDevice},
+		{model.ImportEntityRelationship, batch.Relationships, im.importRelationship},
+	}
+	for _, section := range sections {
+		if err := im.run(section.entity, section.rows, section.apply); err != nil {
+			return nil, err
+		}
+	}
+
+	result := im.result
+	if dryRun || result.Failed > 0 {
+		log.Info("Import rolled back", "dry_run", dryRun, "created", result.Created, "updated", result.Updated, "failed", result.Failed)
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing import: %w", err)
+	}
+	result.Committed = true
+
+	log.Info("Import committed", "created", result.Created, "updated", result.Updated, "unchanged", result.Unchanged)
+	return result, nil
+}
+
+// newImport loads the existing entities into the import indexes; caller must hold ss.mu
+func (ss *sqlStorage) newImport() (*sqliteImport, error) {
+	im := &sqliteImport{
+		ss:              ss,
+		result:          &model.ImportResult{Rows: []model.ImportRowResult{}},
+		datacenters:     make(map[string]*model.Datacenter),
+		datacenterNames: make(importNames),
+		networks:        make(map[string]*model.Network),
+		networkNames:    make(importNames),
+		pools:           make(map[string]*model.NetworkPool),
+		poolNames:       make(importNames),
+		devices:         make(map[string]*model.Device),
+		deviceNames:     make(importNames),
+	}
+
+	datacenters, err := ss.listDatacentersLocked(nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range datacenters {
+		im.datacenters[datacenters[i].ID] = &datacenters[i]
+		im.datacenterNames.add(datacenters[i].Name, datacenters[i].ID)
+	}
+
+	networks, err := ss.listNetworksLocked(nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range networks {
+		im.networks[networks[i].ID] = &networks[i]
+		im.networkNames.add(networks[i].Name, networks[i].ID)
+	}
+
+	pools, err := ss.listNetworkPoolsLocked(nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pools {
+		im.pools[pools[i].ID] = &pools[i]
+		im.poolNames.add(pools[i].Name, pools[i].ID)
+	}
+
+	devices, err := ss.listDevicesLocked(nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range devices {
+		im.devices[devices[i].ID] = &devices[i]
+		im.deviceNames.add(devices[i].Name, devices[i].ID)
+	}
+
+	return im, nil
+}
+
+// run applies the rows of one section, each within its own savepoint so a failed
+// row leaves no partial writes behind
+func (im *sqliteImport) run(entity string, rows []model.ImportRecord, apply func(model.ImportRecord, *model.ImportRowResult) error) error {
+	for i, rec := range rows {
+		row := model.ImportRowResult{Entity: entity, Row: i + 1}
+
+		if _, err := im.tx.Exec(`SAVEPOINT import_row`); err != nil {
+			return fmt.Errorf("creating savepoint: %w", err)
+		}
+
+		// Work on a copy so the caller's batch is left untouched
+		copied := make(model.ImportRecord, len(rec))
+		for k, v := range rec {
+			copied[k] = v
+		}
+
+		if err := apply(copied, &row); err != nil {
+			if _, rbErr := im.tx.Exec(`ROLLBACK TO import_row`); rbErr != nil {
+				return fmt.Errorf("rolling back row: %w", rbErr)
+			}
+			row.Action = model.ImportActionError
+			row.Error = err.Error()
+		}
+
+		if _, err := im.tx.Exec(`RELEASE import_row`); err != nil {
+			return fmt.Errorf("releasing savepoint: %w", err)
+		}
+		im.result.Add(row)
+	}
+	return nil
+}
+
+func (im *sqliteImport) importDatacenter(rec model.ImportRecord, row *model.ImportRowResult) error {
+	id, name := importString(rec["id"]), importString(rec["name"])
+	row.Name = name
+
+	targetID, err := matchImportTarget(model.ImportEntityDatacenter, id, name, im.datacenterNames, func(id string) bool {
+		return im.datacenters[id] != nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var dc model.Datacenter
+	var existing *model.Datacenter
+	if targetID != "" {
+		existing = im.datacenters[targetID]
+		dc = *existing
+	}
+	dropImportKeys(rec, "created_at", "updated_at", "version")
+	if err := decodeImportRecord(rec, &dc); err != nil {
+		return err
+	}
+	row.Name = dc.Name
+
+	if dc.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidImport)
+	}
+
+	if existing != nil {
+		row.ID = dc.ID
+		if importUnchanged(existing, &dc) {
+			row.Action = model.ImportActionUnchanged
+			return nil
+		}
+		if err := im.ss.updateDatacenterTx(im.tx, &dc); err != nil {
+			return err
+		}
+		im.datacenterNames.remove(existing.Name, dc.ID)
+		row.Action = model.ImportActionUpdate
+	} else {
+		if dc.ID == "" {
+			dc.ID = generateUUID()
+		}
+		row.ID = dc.ID
+		if err := im.ss.createDatacenterTx(im.tx, &dc); err != nil {
+			return err
+		}
+		row.Action = model.ImportActionCreate
+	}
+
+	im.datacenters[dc.ID] = &dc
+	im.datacenterNames.add(dc.Name, dc.ID)
+	return nil
+}
+
+func (im *sqliteImport) importNetwork(rec model.ImportRecord, row *model.ImportRowResult) error {
+	id, name := importString(rec["id"]), importString(rec["name"])
+	row.Name = name
+
+	targetID, err := matchImportTarget(model.ImportEntityNetwork, id, name, im.networkNames, func(id string) bool {
+		return im.networks[id] != nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var network model.Network
+	var existing *model.Network
+	if targetID != "" {
+		existing = im.networks[targetID]
+		network = *existing
+	}
+
+	dcRef, hasDC := takeImportRef(rec, "datacenter", "datacenter_id")
+	dropImportKeys(rec, "created_at", "updated_at", "version")
+	if err := decodeImportRecord(rec, &network); err != nil {
+		return err
+	}
+	row.Name = network.Name
+
+	if hasDC {
+		if network.DatacenterID, err = im.resolveDatacenter(dcRef); err != nil {
+			return err
+		}
+	}
+	if network.DatacenterID == "" {
+		if network.DatacenterID = im.defaultDatacenter(); network.DatacenterID == "" {
+			return fmt.Errorf("%w: datacenter is required", ErrInvalidImport)
+		}
+	}
+
+	if network.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidImport)
+	}
+	if network.Subnet == "" {
+		return fmt.Errorf("%w: subnet is required", ErrInvalidImport)
+	}
+	if _, _, err := net.ParseCIDR(network.Subnet); err != nil {
+		return fmt.Errorf("%w: invalid subnet CIDR: %s", ErrInvalidImport, network.Subnet)
+	}
+
+	if existing != nil {
+		row.ID = network.ID
+		if importUnchanged(existing, &network) {
+			row.Action = model.ImportActionUnchanged
+			return nil
+		}
+		if err := im.ss.updateNetworkTx(im.tx, &network); err != nil {
+			return err
+		}
+		im.networkNames.remove(existing.Name, network.ID)
+		row.Action = model.ImportActionUpdate
+	} else {
+		if network.ID == "" {
+			network.ID = generateUUID()
+		}
+		row.ID = network.ID
+		if err := im.ss.createNetworkTx(im.tx, &network); err != nil {
+			return err
+		}
+		row.Action = model.ImportActionCreate
+	}
+
+	im.networks[network.ID] = &network
+	im.networkNames.add(network.Name, network.ID)
+	return nil
+}
+
+func (im *sqliteImport) importPool(rec model.ImportRecord, row *model.ImportRowResult) error {
+	id, name := importString(rec["id"]), importString(rec["name"])
+	row.Name = name
+
+	networkRef, hasNetwork := takeImportRef(rec, "network", "network_id")
+	networkID := ""
+	if hasNetwork {
+		var err error
+		if networkID, err = im.resolveNetwork(networkRef); err != nil {
+			return err
+		}
+	}
+
+	// Pool names are only unique within their network
+	names := im.poolNames
+	if networkID != "" {
+		names = make(importNames)
+		for _, poolID := range im.poolNames[strings.ToLower(name)] {
+			if im.pools[poolID].NetworkID == networkID {
+				names.add(name, poolID)
+			}
+		}
+	}
+	targetID, err := matchImportTarget(model.ImportEntityPool, id, name, names, func(id string) bool {
+		return im.pools[id] != nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var pool model.NetworkPool
+	var existing *model.NetworkPool
+	if targetID != "" {
+		existing = im.pools[targetID]
+		pool = *existing
+	} else if networkID == "" {
+		return fmt.Errorf("%w: network is required", ErrInvalidImport)
+	}
+
+	dropImportKeys(rec, "created_at", "updated_at", "version")
+	if err := decodeImportRecord(rec, &pool); err != nil {
+		return err
+	}
+	row.Name = pool.Name
+	if networkID != "" {
+		pool.NetworkID = networkID
+	}
+
+	if pool.Name == "" {
+		return fmt.Errorf("%w: pool name is required", ErrInvalidImport)
+	}
+	if pool.StartIP == "" || pool.EndIP == "" {
+		return fmt.Errorf("%w: start_ip and end_ip are required", ErrInvalidImport)
+	}
+	if net.ParseIP(pool.StartIP) == nil || net.ParseIP(pool.EndIP) == nil {
+		return fmt.Errorf("%w: invalid IP address format", ErrInvalidImport)
+	}
+
+	if existing != nil {
+		row.ID = pool.ID
+		if importUnchanged(existing, &pool) {
+			row.Action = model.ImportActionUnchanged
+			return nil
+		}
+		if err := updateNetworkPoolTx(im.tx, &pool); err != nil {
+			return err
+		}
+		im.poolNames.remove(existing.Name, pool.ID)
+		row.Action = model.ImportActionUpdate
+	} else {
+		if pool.ID == "" {
+			pool.ID = generateUUID()
+		}
+		row.ID = pool.ID
+		if err := createNetworkPoolTx(im.tx, &pool); err != nil {
+			return err
+		}
+		row.Action = model.ImportActionCreate
+	}
+
+	im.pools[pool.ID] = &pool
+	im.poolNames.add(pool.Name, pool.ID)
+	return nil
+}
+
+func (im *sqliteImport) importDevice(rec model.ImportRecord, row *model.ImportRowResult) error {
+	id, name := importString(rec["id"]), importString(rec["name"])
+	row.Name = name
+
+	targetID, err := matchImportTarget(model.ImportEntityDevice, id, name, im.deviceNames, func(id string) bool {
+		return im.devices[id] != nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var device model.Device
+	var existing *model.Device
+	if targetID != "" {
+		existing = im.devices[targetID]
+		device = *existing
+	}
+
+	dcRef, hasDC := takeImportRef(rec, "datacenter", "datacenter_id")
+	if err := im.resolveAddressRefs(rec); err != nil {
+		return err
+	}
+	dropImportKeys(rec, "created_at", "updated_at", "version", "status_changed_at", "os_info")
+	if err := decodeImportRecord(rec, &device); err != nil {
+		return err
+	}
+	row.Name = device.Name
+
+	if hasDC {
+		if device.DatacenterID, err = im.resolveDatacenter(dcRef); err != nil {
+			return err
+		}
+	}
+
+	if device.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidImport)
+	}
+	for _, addr := range device.Addresses {
+		ip := net.ParseIP(addr.IP)
+		if ip == nil {
+			return fmt.Errorf("%w: invalid IP address: %s", ErrInvalidImport, addr.IP)
+		}
+		if addr.PoolID != "" {
+			pool := im.pools[addr.PoolID]
+			if pool == nil {
+				return fmt.Errorf("%w: pool %q not found", ErrInvalidImport, addr.PoolID)
+			}
+			start, end := net.ParseIP(pool.StartIP), net.ParseIP(pool.EndIP)
+			if start == nil || end == nil || ipCompare(ip, start) < 0 || ipCompare(ip, end) > 0 {
+				return fmt.Errorf("%w: IP %s is not valid for pool %s", ErrInvalidImport, addr.IP, pool.Name)
+			}
+		}
+	}
+
+	if existing != nil {
+		row.ID = device.ID
+		if importUnchanged(existing, &device) {
+			row.Action = model.ImportActionUnchanged
+			return nil
+		}
+		if err := im.ss.updateDeviceTx(im.tx, &device); err != nil {
+			return err
+		}
+		im.deviceNames.remove(existing.Name, device.ID)
+		row.Action = model.ImportActionUpdate
+	} else {
+		if device.ID == "" {
+			device.ID = generateUUID()
+		}
+		if device.DatacenterID == "" {
+			device.DatacenterID = im.defaultDatacenter()
+		}
+		row.ID = device.ID
+		if err := im.ss.createDeviceTx(im.tx, &device); err != nil {
+			return err
+		}
+		row.Action = model.ImportActionCreate
+	}
+
+	im.devices[device.ID] = &device
+	im.deviceNames.add(device.Name, device.ID)
+	return nil
+}
+
+func (im *sqliteImport) importRelationship(rec model.ImportRecord, row *model.ImportRowResult) error {
+	parentRef, _ := takeImportRef(rec, "parent", "parent_id")
+	childRef, _ := takeImportRef(rec, "child", "child_id")
+	relType, _ := takeImportRef(rec, "type", "relationship_type")
+	row.Name = parentRef + " -> " + childRef
+	if len(rec) > 0 {
+		unknown := make([]string, 0, len(rec))
+		for key := range rec {
+			unknown = append(unknown, key)
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: unknown field %q", ErrInvalidImport, unknown[0])
+	}
+
+	if parentRef == "" || childRef == "" {
+		return fmt.Errorf("%w: parent and child are required", ErrInvalidImport)
+	}
+	if relType == "" {
+		relType = "related"
+	}
+
+	parentID, err := im.resolveDevice(parentRef)
+	if err != nil {
+		return err
+	}
+	childID, err := im.resolveDevice(childRef)
+	if err != nil {
+		return err
+	}
+	row.Name = im.devices[parentID].Name + " -> " + im.devices[childID].Name
+
+	created, err := addRelationshipTx(im.tx, parentID, childID, relType)
+	if err != nil {
+		return err
+	}
+	if created {
+		row.Action = model.ImportActionCreate
+	} else {
+		row.Action = model.ImportActionUnchanged
+	}
+	return nil
+}
+
+// resolveAddressRefs rewrites network and pool references of the addresses in a
+// device row into IDs. A pool name is looked up within the address network if one
+// is given, and the pool's network is filled in otherwise.
+func (im *sqliteImport) resolveAddressRefs(rec model.ImportRecord) error {
+	addresses, ok := rec["addresses"].([]interface{})
+	if !ok {
+		return nil
+	}
+	resolvedAddresses := make([]interface{}, len(addresses))
+	for i, item := range addresses {
+		addr, ok := item.(map[string]interface{})
+		if !ok {
+			resolvedAddresses[i] = item
+			continue
+		}
+		resolved := make(map[string]interface{}, len(addr))
+		for k, v := range addr {
+			resolved[k] = v
+		}
+
+		networkRef, hasNetwork := takeImportRef(resolved, "network", "network_id")
+		networkID := ""
+		if hasNetwork && networkRef != "" {
+			var err error
+			if networkID, err = im.resolveNetwork(networkRef); err != nil {
+				return fmt.Errorf("address %d: %w", i+1, err)
+			}
+			resolved["network_id"] = networkID
+		}
+
+		poolRef, hasPool := takeImportRef(resolved, "pool", "pool_id")
+		if hasPool && poolRef != "" {
+			poolID, err := im.resolvePool(poolRef, networkID)
+			if err != nil {
+				return fmt.Errorf("address %d: %w", i+1, err)
+			}
+			resolved["pool_id"] = poolID
+			if networkID == "" {
+				resolved["network_id"] = im.pools[poolID].NetworkID
+			}
+		}
+		resolvedAddresses[i] = resolved
+	}
+	rec["addresses"] = resolvedAddresses
+	return nil
+}
+
+func (im *sqliteImport) resolveDatacenter(ref string) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+	return resolveImportRef(model.ImportEntityDatacenter, ref, im.datacenterNames, func(id string) bool {
+		return im.datacenters[id] != nil
+	})
+}
+
+func (im *sqliteImport) resolveNetwork(ref string) (string, error) {
+	return resolveImportRef(model.ImportEntityNetwork, ref, im.networkNames, func(id string) bool {
+		return im.networks[id] != nil
+	})
+}
+
+func (im *sqliteImport) resolvePool(ref, networkID string) (string, error) {
+	names := im.poolNames
+	if networkID != "" {
+		names = make(importNames)
+		for _, poolID := range im.poolNames[strings.ToLower(ref)] {
+			if im.pools[poolID].NetworkID == networkID {
+				names.add(ref, poolID)
+			}
+		}
+	}
+	return resolveImportRef(model.ImportEntityPool, ref, names, func(id string) bool {
+		return im.pools[id] != nil
+	})
+}
+
+func (im *sqliteImport) resolveDevice(ref string) (string, error) {
+	return resolveImportRef(model.ImportEntityDevice, ref, im.deviceNames, func(id string) bool {
+		return im.devices[id] != nil
+	})
+}
+
+// defaultDatacenter returns the only datacenter, if there is exactly one
+func (im *sqliteImport) defaultDatacenter() string {
+	if len(im.datacenters) != 1 {
+		return ""
+	}
+	for id := range im.datacenters {
+		return id
+	}
+	return ""
+}
+
+// resolveImportRef resolves a reference given as an ID or a unique name
+func resolveImportRef(entity, ref string, names importNames, exists func(string) bool) (string, error) {
+	if exists(ref) {
+		return ref, nil
+	}
+	switch ids := names[strings.ToLower(ref)]; len(ids) {
+	case 0:
+		return "", fmt.Errorf("%w: %s %q not found", ErrInvalidImport, entity, ref)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s name %q is ambiguous", ErrInvalidImport, entity, ref)
+	}
+}
+
+// matchImportTarget returns the ID of the existing entity a row updates, or an
+// empty string if the row creates a new one. Rows match by ID first, then by name.
+func matchImportTarget(entity, id, name string, names importNames, exists func(string) bool) (string, error) {
+	var byName []string
+	if name != "" {
+		byName = names[strings.ToLower(name)]
+	}
+	if id != "" {
+		if exists(id) {
+			return id, nil
+		}
+		if len(byName) > 0 {
+			return "", fmt.Errorf("%w: %s name %q is already used by %s", ErrInvalidImport, entity, name, byName[0])
+		}
+		return "", nil
+	}
+	switch len(byName) {
+	case 0:
+		return "", nil
+	case 1:
+		return byName[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s name %q is ambiguous", ErrInvalidImport, entity, name)
+	}
+}
+
+// decodeImportRecord applies the fields present in a row onto dst. Fields present
+// in the row replace the current value as a whole; unknown fields are rejected.
+func decodeImportRecord(rec model.ImportRecord, dst interface{}) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	// Reset present fields first, otherwise decoding merges into existing maps and
+	// reuses the backing arrays of slices shared with the indexed entity
+	v := reflect.ValueOf(dst).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if _, ok := rec[name]; ok {
+			v.Field(i).Set(reflect.Zero(field.Type))
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// importUnchanged reports whether an update would leave an entity as it is.
+// Empty values are ignored so that missing, null and empty fields compare equal.
+func importUnchanged(before, after interface{}) bool {
+	return reflect.DeepEqual(importSnapshot(before), importSnapshot(after))
+}
+
+func importSnapshot(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil
+	}
+	return compactImportValue(generic)
+}
+
+func compactImportValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			val = compactImportValue(val)
+			if isEmptyImportValue(val) {
+				delete(t, k)
+			} else {
+				t[k] = val
+			}
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = compactImportValue(t[i])
+		}
+	}
+	return v
+}
+
+func isEmptyImportValue(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case bool:
+		return !t
+	case float64:
+		return t == 0
+	case map[string]interface{}:
+		return len(t) == 0
+	case []interface{}:
+		return len(t) == 0
+	}
+	return false
+}
+
+// takeImportRef removes reference keys from a row and returns the first one present
+func takeImportRef(rec map[string]interface{}, keys ...string) (string, bool) {
+	var ref string
+	found := false
+	for _, key := range keys {
+		v, ok := rec[key]
+		if !ok {
+			continue
+		}
+		delete(rec, key)
+		if !found {
+			ref, found = importString(v), true
+		}
+	}
+	return ref, found
+}
+
+// dropImportKeys removes read-only fields, so rows exported from rackd can be re-imported
+func dropImportKeys(rec model.ImportRecord, keys ...string) {
+	for _, key := range keys {
+		delete(rec, key)
+	}
+}
+
+// importString converts a scalar row value to a trimmed string
+func importString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(t)
+	default:
+		return strings.TrimSpace(fmt.Sprint(t))
+	}
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// ErrJobNotFound is returned when a job does not exist
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStorage defines the interface for background job history
+type JobStorage interface {
+	// CreateJob stores a new job, generating its ID if empty
+	CreateJob(job *model.Job) error
+	GetJob(id string) (*model.Job, error)
+	ListJobs(filter *model.JobFilter) ([]model.Job, error)
+	// UpdateJob replaces the status, attempts, result, error, logs and times of a job
+	UpdateJob(job *model.Job) error
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+const jobColumns = `id, type, status, params, result, error, attempts, max_attempts, logs,
+		       run_at, started_at, finished_at, created_at, updated_at`
+
+// CreateJob stores a new job
+func (ss *sqlStorage) CreateJob(job *model.Job) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if job.ID == "" {
+		job.ID = generateUUID()
+	}
+	now := time.Now()
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = now
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = job.CreatedAt
+	}
+	job.UpdatedAt = now
+
+	_, err := ss.db.Exec(`
+		INSERT INTO jobs (`+jobColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		job.ID, job.Type, job.Status, rawJSON(job.Params), rawJSON(job.Result), nullString(job.Error),
+		job.Attempts, job.MaxAttempts, jobLogs(job.Logs),
+		job.RunAt, timePtr(job.StartedAt), timePtr(job.FinishedAt), job.CreatedAt, job.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("creating job: %w", err)
+	}
+	return nil
+}
+
+// GetJob retrieves a job by ID
+func (ss *sqlStorage) GetJob(id string) (*model.Job, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	job, err := scanJob(ss.db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying job: %w", err)
+	}
+	return job, nil
+}
+
+// ListJobs returns the jobs matching filter, newest first
+func (ss *sqlStorage) ListJobs(filter *model.JobFilter) ([]model.Job, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE 1=1`
+	var args []interface{}
+	if filter != nil {
+		if filter.Type != "" {
+			query += " AND type = ?"
+			args = append(args, filter.Type)
+		}
+		if filter.Status != "" {
+			query += " AND status = ?"
+			args = append(args, filter.Status)
+		}
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if filter != nil && filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := ss.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []model.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// UpdateJob saves the progress of a job
+func (ss *sqlStorage) UpdateJob(job *model.Job) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	job.UpdatedAt = time.Now()
+	result, err := ss.db.Exec(`
+		UPDATE jobs
+		SET status = ?, result = ?, error = ?, attempts = ?, max_attempts = ?, logs = ?,
+		    run_at = ?, started_at = ?, finished_at = ?, updated_at = ?
+		WHERE id = ?
+	`,
+		job.Status, rawJSON(job.Result), nullString(job.Error), job.Attempts, job.MaxAttempts, jobLogs(job.Logs),
+		job.RunAt, timePtr(job.StartedAt), timePtr(job.FinishedAt), job.UpdatedAt, job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("updating job: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// rowScanner is implemented by both sql.Row and sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanJob reads a job selected with jobColumns
+func scanJob(row rowScanner) (*model.Job, error) {
+	var job model.Job
+	var params, result, jobError, logs sql.NullString
+	var startedAt, finishedAt sql.NullTime
+	if err := row.Scan(
+		&job.ID, &job.Type, &job.Status, &params, &result, &jobError, &job.Attempts, &job.MaxAttempts, &logs,
+		&job.RunAt, &startedAt, &finishedAt, &job.CreatedAt, &job.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if params.Valid {
+		job.Params = json.RawMessage(params.String)
+	}
+	if result.Valid {
+		job.Result = json.RawMessage(result.String)
+	}
+	job.Error = jobError.String
+	if logs.Valid {
+		json.Unmarshal([]byte(logs.String), &job.Logs)
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// rawJSON stores an already encoded JSON value, or NULL when empty
+func rawJSON(v json.RawMessage) interface{} {
+	if len(v) == 0 {
+		return nil
+	}
+	return string(v)
+}
+
+func jobLogs(logs []model.JobLogEntry) interface{} {
+	if len(logs) == 0 {
+		return nil
+	}
+	return jsonBytes(logs)
+}
@@ -0,0 +1,13 @@
+package storage
+
+import (
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// LabelStorage defines the interface for label maintenance.
+// Labels themselves are stored with their entity through the regular Create/Update methods.
+type LabelStorage interface {
+	// MigrateTagsToLabels converts key=value tags of devices and pools into labels.
+	// With dryRun set nothing is changed and the planned changes are returned.
+	MigrateTagsToLabels(dryRun bool) (*model.LabelMigration, error)
+}
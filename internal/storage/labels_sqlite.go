@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/labels"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// labelTable names the table and owner column holding the labels of one entity type
+type labelTable struct {
+	name     string
+	idColumn string
+}
+
+var (
+	deviceLabelTable  = labelTable{name: "device_labels", idColumn: "device_id"}
+	networkLabelTable = labelTable{name: "network_labels", idColumn: "network_id"}
+	poolLabelTable    = labelTable{name: "pool_labels", idColumn: "pool_id"}
+)
+
+// MigrateTagsToLabels converts key=value tags of devices and pools into labels.
+// Tags whose key is already set to a different value, or that repeat a key,
+// are kept as tags and reported as skipped.
+func (ss *sqlStorage) MigrateTagsToLabels(dryRun bool) (*model.LabelMigration, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &model.LabelMigration{DryRun: dryRun}
+
+	result.Devices, err = migrateTagsToLabels(tx, dryRun, deviceLabelTable, `
+		SELECT d.id, d.name, t.tag FROM tags t
+		INNER JOIN devices d ON d.id = t.device_id
+		ORDER BY d.name, d.id, t.tag
+	`, `DELETE FROM tags WHERE device_id = ? AND tag = ?`)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Pools, err = migrateTagsToLabels(tx, dryRun, poolLabelTable, `
+		SELECT p.id, p.name, t.tag FROM pool_tags t
+		INNER JOIN network_pools p ON p.id = t.pool_id
+		ORDER BY p.name, p.id, t.tag
+	`, `DELETE FROM pool_tags WHERE pool_id = ? AND tag = ?`)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing label migration: %w", err)
+	}
+
+	log.Info("Migrated tags to labels", "devices", len(result.Devices), "pools", len(result.Pools))
+	return result, nil
+}
+
+func migrateTagsToLabels(tx sqlTx, dryRun bool, table labelTable, tagQuery, deleteTag string) ([]model.LabelMigrationChange, error) {
+	type entity struct {
+		id, name string
+		tags     []string
+	}
+
+	rows, err := tx.Query(tagQuery)
+	if err != nil {
+		return nil, fmt.Errorf("querying tags: %w", err)
+	}
+	var entities []*entity
+	for rows.Next() {
+		var id, name, tag string
+		if err := rows.Scan(&id, &name, &tag); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning tag: %w", err)
+		}
+		if n := len(entities); n == 0 || entities[n-1].id != id {
+			entities = append(entities, &entity{id: id, name: name})
+		}
+		e := entities[len(entities)-1]
+		e.tags = append(e.tags, tag)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	changes := []model.LabelMigrationChange{}
+	for _, e := range entities {
+		existing, err := table.load(tx, []string{e.id})
+		if err != nil {
+			return nil, err
+		}
+		current := existing[e.id]
+
+		change := model.LabelMigrationChange{ID: e.id, Name: e.name, Labels: map[string]string{}}
+		var migrated []string
+		for _, tag := range e.tags {
+			key, value, ok := labels.ParseTag(tag)
+			if !ok {
+				continue
+			}
+			if v, taken := change.Labels[key]; taken && v != value {
+				change.Skipped = append(change.Skipped, tag)
+				continue
+			}
+			if v, set := current[key]; set && v != value {
+				change.Skipped = append(change.Skipped, tag)
+				continue
+			}
+			change.Labels[key] = value
+			migrated = append(migrated, tag)
+		}
+		if len(migrated) == 0 && len(change.Skipped) == 0 {
+			continue
+		}
+		changes = append(changes, change)
+
+		if dryRun {
+			continue
+		}
+		for key, value := range change.Labels {
+			_, err := tx.Exec(`INSERT INTO `+table.name+` (`+table.idColumn+`, key, value) VALUES (?, ?, ?)
+				ON CONFLICT (`+table.idColumn+`, key) DO UPDATE SET value = excluded.value`, e.id, key, value)
+			if err != nil {
+				return nil, fmt.Errorf("inserting label: %w", err)
+			}
+		}
+		for _, tag := range migrated {
+			if _, err := tx.Exec(deleteTag, e.id, tag); err != nil {
+				return nil, fmt.Errorf("deleting migrated tag: %w", err)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// save validates and stores the labels of an entity, replacing any previous labels
+func (t labelTable) save(tx sqlTx, id string, values map[string]string) error {
+	if err := labels.Validate(values); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM `+t.name+` WHERE `+t.idColumn+` = ?`, id); err != nil {
+		return fmt.Errorf("deleting old labels: %w", err)
+	}
+	for key, value := range values {
+		_, err := tx.Exec(`INSERT INTO `+t.name+` (`+t.idColumn+`, key, value) VALUES (?, ?, ?)`, id, key, value)
+		if err != nil {
+			return fmt.Errorf("inserting label: %w", err)
+		}
+	}
+	return nil
+}
+
+// load returns the labels of the given entities, keyed by entity ID
+func (t labelTable) load(q queryer, ids []string) (map[string]map[string]string, error) {
+	result := make(map[string]map[string]string)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.Repeat("?,", len(ids)-1) + "?"
+
+	rows, err := q.Query(fmt.Sprintf(`SELECT %s, key, value FROM %s WHERE %s IN (%s)`,
+		t.idColumn, t.name, t.idColumn, placeholders), args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying labels: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, key, value string
+		if err := rows.Scan(&id, &key, &value); err != nil {
+			return nil, fmt.Errorf("scanning label: %w", err)
+		}
+		if result[id] == nil {
+			result[id] = make(map[string]string)
+		}
+		result[id][key] = value
+	}
+
+	return result, rows.Err()
+}
+
+// conditions builds SQL conditions matching entities selected by a label selector
+func (t labelTable) conditions(entityColumn, selector string) ([]string, []interface{}, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, req := range sel {
+		subquery := `SELECT ` + t.idColumn + ` FROM ` + t.name + ` WHERE key = ?`
+		args = append(args, req.Key)
+		if len(req.Values) > 0 {
+			subquery += ` AND value IN (` + strings.Repeat("?,", len(req.Values)-1) + `?)`
+			for _, v := range req.Values {
+				args = append(args, v)
+			}
+		}
+
+		switch req.Operator {
+		case labels.Equals, labels.In, labels.Exists:
+			conditions = append(conditions, entityColumn+` IN (`+subquery+`)`)
+		default:
+			conditions = append(conditions, entityColumn+` NOT IN (`+subquery+`)`)
+		}
+	}
+	return conditions, args, nil
+}
@@ -0,0 +1,14 @@
+package storage
+
+import "time"
+
+// LeaseStorage defines the interface for leases, which rackd instances sharing a
+// database take on work before starting it so only one of them runs it
+type LeaseStorage interface {
+	// AcquireLease takes lease name for owner, or renews it when owner already
+	// holds it, until ttl from now. It returns false while another owner holds
+	// a lease that has not expired.
+	AcquireLease(name, owner string, ttl time.Duration) (bool, error)
+	// ReleaseLease gives up lease name if owner holds it
+	ReleaseLease(name, owner string) error
+}
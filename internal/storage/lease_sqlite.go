@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// AcquireLease takes or renews a lease. The insert only replaces a lease that
+// has expired or belongs to owner, so of the instances racing for a lease the
+// database lets exactly one have it.
+func (ss *sqlStorage) AcquireLease(name, owner string, ttl time.Duration) (bool, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	now := time.Now()
+	result, err := ss.db.Exec(`
+		INSERT INTO leases (name, owner, expires_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE leases.owner = excluded.owner OR leases.expires_at <= ?
+	`, name, owner, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("acquiring lease: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ReleaseLease deletes a lease held by owner
+func (ss *sqlStorage) ReleaseLease(name, owner string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if _, err := ss.db.Exec(`DELETE FROM leases WHERE name = ? AND owner = ?`, name, owner); err != nil {
+		return fmt.Errorf("releasing lease: %w", err)
+	}
+	return nil
+}
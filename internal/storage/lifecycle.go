@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+var (
+	// ErrInvalidStatus is returned when a device status is not a known lifecycle state
+	ErrInvalidStatus = errors.New("invalid device status")
+	// ErrInvalidTransition is returned when a device may not move to the requested status
+	ErrInvalidTransition = errors.New("invalid status transition")
+)
+
+// LifecycleStorage defines the interface for device lifecycle transitions.
+// The status of a device can also be changed through UpdateDevice, which applies the same rules.
+type LifecycleStorage interface {
+	// TransitionDevice moves a device to a new status. Decommissioning a device
+	// releases its pool addresses, which are returned in the change.
+	TransitionDevice(id string, status model.DeviceStatus, note string) (*model.DeviceStatusChange, error)
+	// GetDeviceStatusHistory returns the transitions of a device, oldest first
+	GetDeviceStatusHistory(id string) ([]model.DeviceStatusChange, error)
+}
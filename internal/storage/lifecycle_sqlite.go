@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// TransitionDevice moves a device to a new lifecycle status
+func (ss *sqlStorage) TransitionDevice(id string, status model.DeviceStatus, note string) (*model.DeviceStatusChange, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deviceID, current, err := lookupDeviceStatus(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := transitionDeviceTx(tx, deviceID, current, status, note)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transition: %w", err)
+	}
+
+	log.Info("Device status changed", "id", deviceID, "from", current, "to", status, "released_addresses", len(change.ReleasedAddresses))
+	return change, nil
+}
+
+// GetDeviceStatusHistory returns the lifecycle transitions of a device, oldest first
+func (ss *sqlStorage) GetDeviceStatusHistory(id string) ([]model.DeviceStatusChange, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	deviceID, _, err := lookupDeviceStatus(ss.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := ss.db.Query(`
+		SELECT id, device_id, from_status, to_status, note, changed_at
+		FROM device_status_history
+		WHERE device_id = ?
+		ORDER BY changed_at, id
+	`, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("querying status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []model.DeviceStatusChange{}
+	for rows.Next() {
+		var c model.DeviceStatusChange
+		var from, note sql.NullString
+		if err := rows.Scan(&c.ID, &c.DeviceID, &from, &c.ToStatus, &note, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("scanning status change: %w", err)
+		}
+		c.FromStatus = model.DeviceStatus(from.String)
+		c.Note = note.String
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
+
+// rowQueryer is implemented by both sqlConn and sqlTx
+type rowQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// lookupDeviceStatus resolves a device by ID or name and returns its ID and current status
+func lookupDeviceStatus(q rowQueryer, id string) (string, model.DeviceStatus, error) {
+	var deviceID string
+	var status model.DeviceStatus
+	err := q.QueryRow(`SELECT id, status FROM devices WHERE id = ?`, id).Scan(&deviceID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = q.QueryRow(`SELECT id, status FROM devices WHERE LOWER(name) = LOWER(?) LIMIT 1`, id).Scan(&deviceID, &status)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", ErrDeviceNotFound
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("querying device status: %w", err)
+	}
+	return deviceID, status, nil
+}
+
+// transitionDeviceTx checks and applies a status change, records it and releases
+// pool addresses when the device is decommissioned
+func transitionDeviceTx(tx sqlTx, deviceID string, from, to model.DeviceStatus, note string) (*model.DeviceStatusChange, error) {
+	if !to.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidStatus, to)
+	}
+	if !from.CanTransitionTo(to) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+
+	now := time.Now()
+	if _, err := tx.Exec(`UPDATE devices SET status = ?, status_changed_at = ?, updated_at = ?, version = version + 1 WHERE id = ?`,
+		to, now, now, deviceID); err != nil {
+		return nil, fmt.Errorf("updating device status: %w", err)
+	}
+
+	change, err := recordStatusChangeTx(tx, deviceID, from, to, note, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == model.DeviceStatusDecommissioned {
+		change.ReleasedAddresses, err = releasePoolAddressesTx(tx, deviceID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return change, nil
+}
+
+// recordStatusChangeTx stores a status change in the device history
+func recordStatusChangeTx(tx sqlTx, deviceID string, from, to model.DeviceStatus, note string, at time.Time) (*model.DeviceStatusChange, error) {
+	change := &model.DeviceStatusChange{
+		ID:         generateUUID(),
+		DeviceID:   deviceID,
+		FromStatus: from,
+		ToStatus:   to,
+		Note:       note,
+		ChangedAt:  at,
+	}
+	_, err := tx.Exec(`
+		INSERT INTO device_status_history (id, device_id, from_status, to_status, note, changed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, change.ID, change.DeviceID, nullString(string(from)), change.ToStatus, nullString(note), change.ChangedAt)
+	if err != nil {
+		return nil, fmt.Errorf("inserting status change: %w", err)
+	}
+	return change, nil
+}
+
+// releasePoolAddressesTx removes the pool-assigned addresses of a device so their IPs
+// become available in the pool again
+func releasePoolAddressesTx(tx sqlTx, deviceID string) ([]model.Address, error) {
+	rows, err := tx.Query(`
+		SELECT ip, port, type, label, network_id, pool_id, switch_port, mac_address
+		FROM addresses WHERE device_id = ? AND pool_id IS NOT NULL ORDER BY ip
+	`, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("querying pool addresses: %w", err)
+	}
+	var released []model.Address
+	for rows.Next() {
+		var a model.Address
+		var networkID, poolID, switchPort, macAddress sql.NullString
+		if err := rows.Scan(&a.IP, &a.Port, &a.Type, &a.Label, &networkID, &poolID, &switchPort, &macAddress); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning pool address: %w", err)
+		}
+		a.NetworkID = networkID.String
+		a.PoolID = poolID.String
+		a.SwitchPort = switchPort.String
+		a.MACAddress = macAddress.String
+		released = append(released, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM addresses WHERE device_id = ? AND pool_id IS NOT NULL`, deviceID); err != nil {
+		return nil, fmt.Errorf("releasing pool addresses: %w", err)
+	}
+	return released, nil
+}
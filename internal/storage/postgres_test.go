@@ -0,0 +1,19 @@
+package storage
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := []struct{ query, want string }{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM t WHERE a = ? AND b IN (?, ?)", "SELECT * FROM t WHERE a = $1 AND b IN ($2, $3)"},
+		{"SELECT '?' || ? FROM \"odd?\"", "SELECT '?' || $1 FROM \"odd?\""},
+		{"SELECT 'it''s?', ?", "SELECT 'it''s?', $1"},
+		{"-- why?\nSELECT ?", "-- why?\nSELECT $1"},
+		{"SELECT 'unterminated ?", "SELECT 'unterminated ?"},
+	}
+	for _, tt := range tests {
+		if got := rebind(tt.query); got != tt.want {
+			t.Errorf("rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
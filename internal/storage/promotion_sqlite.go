@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+const promotionPolicyColumns = `id, name, description, enabled, priority, match_conditions, template, created_at, updated_at`
+
+// ListPromotionPolicies returns all promotion policies in the order they run
+func (ss *sqlStorage) ListPromotionPolicies() ([]model.PromotionPolicy, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return listPromotionPolicies(ss.db)
+}
+
+// GetPromotionPolicy retrieves a promotion policy by ID
+func (ss *sqlStorage) GetPromotionPolicy(id string) (*model.PromotionPolicy, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	rows, err := ss.db.Query(`SELECT `+promotionPolicyColumns+` FROM promotion_policies WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("querying promotion policy: %w", err)
+	}
+	defer rows.Close()
+
+	policies, err := scanPromotionPolicies(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, ErrPromotionPolicyNotFound
+	}
+	return &policies[0], nil
+}
+
+// CreatePromotionPolicy adds a new promotion policy
+func (ss *sqlStorage) CreatePromotionPolicy(policy *model.PromotionPolicy) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if policy.ID == "" {
+		policy.ID = generateUUID()
+	}
+	if err := ss.checkPromotionPolicyName(policy); err != nil {
+		return err
+	}
+	now := time.Now()
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+
+	if err := insertPromotionPolicy(ss.db, policy); err != nil {
+		return err
+	}
+
+	log.Info("Promotion policy created in storage", "id", policy.ID, "name", policy.Name)
+	return nil
+}
+
+// UpdatePromotionPolicy updates an existing promotion policy
+func (ss *sqlStorage) UpdatePromotionPolicy(policy *model.PromotionPolicy) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if err := ss.checkPromotionPolicyName(policy); err != nil {
+		return err
+	}
+	policy.UpdatedAt = time.Now()
+
+	result, err := ss.db.Exec(`
+		UPDATE promotion_policies
+		SET name = ?, description = ?, enabled = ?, priority = ?, match_conditions = ?, template = ?, updated_at = ?
+		WHERE id = ?
+	`, policy.Name, nullString(policy.Description), policy.Enabled, policy.Priority,
+		jsonBytes(policy.Match), jsonBytes(policy.Template), policy.UpdatedAt, policy.ID)
+	if err != nil {
+		return fmt.Errorf("updating promotion policy: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrPromotionPolicyNotFound
+	}
+
+	// created_at is not part of the update, load it for the caller
+	if err := ss.db.QueryRow(`SELECT created_at FROM promotion_policies WHERE id = ?`, policy.ID).Scan(&policy.CreatedAt); err != nil {
+		return fmt.Errorf("querying promotion policy: %w", err)
+	}
+	return nil
+}
+
+// DeletePromotionPolicy deletes a promotion policy
+func (ss *sqlStorage) DeletePromotionPolicy(id string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	result, err := ss.db.Exec(`DELETE FROM promotion_policies WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("deleting promotion policy: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrPromotionPolicyNotFound
+	}
+
+	return nil
+}
+
+// Helper functions
+
+// checkPromotionPolicyName returns ErrDuplicatePromotionPolicy when another
+// policy has the name of policy; caller must hold ss.mu
+func (ss *sqlStorage) checkPromotionPolicyName(policy *model.PromotionPolicy) error {
+	var owner string
+	err := ss.db.QueryRow(`SELECT id FROM promotion_policies WHERE name = ? AND id != ?`, policy.Name, policy.ID).Scan(&owner)
+	if err == nil {
+		return fmt.Errorf("%w: %q", ErrDuplicatePromotionPolicy, policy.Name)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("querying promotion policy: %w", err)
+	}
+	return nil
+}
+
+func insertPromotionPolicy(ex execer, policy *model.PromotionPolicy) error {
+	_, err := ex.Exec(`
+		INSERT INTO promotion_policies
+		    (id, name, description, enabled, priority, match_conditions, template, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, policy.ID, policy.Name, nullString(policy.Description), policy.Enabled, policy.Priority,
+		jsonBytes(policy.Match), jsonBytes(policy.Template), policy.CreatedAt, policy.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting promotion policy: %w", err)
+	}
+	return nil
+}
+
+func listPromotionPolicies(q queryer) ([]model.PromotionPolicy, error) {
+	rows, err := q.Query(`SELECT ` + promotionPolicyColumns + ` FROM promotion_policies ORDER BY priority, name`)
+	if err != nil {
+		return nil, fmt.Errorf("querying promotion policies: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPromotionPolicies(rows)
+}
+
+func scanPromotionPolicies(rows *sql.Rows) ([]model.PromotionPolicy, error) {
+	policies := []model.PromotionPolicy{}
+	for rows.Next() {
+		var p model.PromotionPolicy
+		var description, match, template sql.NullString
+		err := rows.Scan(&p.ID, &p.Name, &description, &p.Enabled, &p.Priority, &match, &template, &p.CreatedAt, &p.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scanning promotion policy: %w", err)
+		}
+		p.Description = description.String
+		if match.Valid {
+			json.Unmarshal([]byte(match.String), &p.Match)
+		}
+		if template.Valid {
+			json.Unmarshal([]byte(template.String), &p.Template)
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
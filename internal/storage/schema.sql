@@ -1,5 +1,6 @@
 -- Initial Schema for Rackd
--- This file contains the complete database schema for a fresh installation
+-- This file contains the complete database schema. It is applied on every
+-- start, so all statements must be idempotent (IF NOT EXISTS).
 
 -- Datacenters table
 CREATE TABLE IF NOT EXISTS datacenters (
@@ -8,7 +9,8 @@ CREATE TABLE IF NOT EXISTS datacenters (
 	location TEXT,
 	description TEXT,
 	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1
 );
 
 -- Trigger to update datacenters timestamp
@@ -29,6 +31,7 @@ CREATE TABLE IF NOT EXISTS networks (
 	vlan INTEGER,
 	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
 	FOREIGN KEY (datacenter_id) REFERENCES datacenters(id) ON DELETE SET NULL,
 	UNIQUE(datacenter_id, name)
 );
@@ -54,13 +57,17 @@ CREATE TABLE IF NOT EXISTS devices (
 	os TEXT,
 	username TEXT,
 	location TEXT,
+	status TEXT NOT NULL DEFAULT 'active',
+	status_changed_at TIMESTAMP,
 	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
 	FOREIGN KEY (datacenter_id) REFERENCES datacenters(id) ON DELETE SET NULL
 );
 
--- Index for devices
+-- Indexes for devices
 CREATE INDEX IF NOT EXISTS idx_devices_datacenter ON devices(datacenter_id);
+CREATE INDEX IF NOT EXISTS idx_devices_status ON devices(status);
 
 -- Trigger to update devices timestamp
 CREATE TRIGGER IF NOT EXISTS update_devices_timestamp
@@ -80,6 +87,7 @@ CREATE TABLE IF NOT EXISTS addresses (
 	network_id TEXT,
 	pool_id TEXT,
 	switch_port TEXT,
+	mac_address TEXT,
 	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE,
 	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE SET NULL,
 	FOREIGN KEY (pool_id) REFERENCES network_pools(id) ON DELETE SET NULL
@@ -138,6 +146,7 @@ CREATE TABLE IF NOT EXISTS network_pools (
 	description TEXT,
 	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
 	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE,
 	UNIQUE(network_id, name)
 );
@@ -199,6 +208,7 @@ CREATE INDEX IF NOT EXISTS idx_discovered_devices_last_seen ON discovered_device
 CREATE TABLE IF NOT EXISTS discovery_scans (
 	id TEXT PRIMARY KEY,
 	network_id TEXT NOT NULL,
+	rule_id TEXT,
 	status TEXT NOT NULL DEFAULT 'pending',
 	scan_type TEXT NOT NULL,
 	scan_depth INTEGER DEFAULT 1,
@@ -210,6 +220,7 @@ CREATE TABLE IF NOT EXISTS discovery_scans (
 	completed_at TIMESTAMP,
 	duration_seconds INTEGER DEFAULT 0,
 	error_message TEXT,
+	change_summary TEXT,
 	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE
@@ -228,12 +239,40 @@ BEGIN
 	UPDATE discovery_scans SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
 END;
 
+-- Discovery: Hosts finished by a scan, so an interrupted scan can resume where it stopped
+CREATE TABLE IF NOT EXISTS discovery_scan_hosts (
+	scan_id TEXT NOT NULL,
+	ip TEXT NOT NULL,
+	found BOOLEAN NOT NULL DEFAULT 0,
+	PRIMARY KEY (scan_id, ip),
+	FOREIGN KEY (scan_id) REFERENCES discovery_scans(id) ON DELETE CASCADE
+);
+
+-- Discovery: What each scan saw on the hosts it found, for comparing consecutive scans
+CREATE TABLE IF NOT EXISTS discovery_observations (
+	scan_id TEXT NOT NULL,
+	ip TEXT NOT NULL,
+	status TEXT NOT NULL,
+	mac_address TEXT,
+	hostname TEXT,
+	os_guess TEXT,
+	open_ports TEXT,
+	observed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (scan_id, ip),
+	FOREIGN KEY (scan_id) REFERENCES discovery_scans(id) ON DELETE CASCADE
+);
+
 -- Discovery: Discovery rules table
 CREATE TABLE IF NOT EXISTS discovery_rules (
 	id TEXT PRIMARY KEY,
 	network_id TEXT NOT NULL UNIQUE,
 	enabled BOOLEAN DEFAULT 1,
 	scan_interval_hours INTEGER DEFAULT 24,
+	schedule TEXT,
+	timezone TEXT,
+	jitter_minutes INTEGER NOT NULL DEFAULT 0,
+	blackouts TEXT,
+	probes TEXT,
 	scan_type TEXT DEFAULT 'full',
 	max_concurrent_scans INTEGER DEFAULT 10,
 	timeout_seconds INTEGER DEFAULT 5,
@@ -262,6 +301,19 @@ BEGIN
 	UPDATE discovery_rules SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
 END;
 
+-- Discovery: Auto-promotion policies table
+CREATE TABLE IF NOT EXISTS promotion_policies (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	description TEXT,
+	enabled BOOLEAN DEFAULT 1,
+	priority INTEGER NOT NULL DEFAULT 0,
+	match_conditions TEXT,
+	template TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Trigger to update discovered_devices timestamp
 CREATE TRIGGER IF NOT EXISTS update_discovered_devices_timestamp
 AFTER UPDATE ON discovered_devices
@@ -270,6 +322,183 @@ BEGIN
 	UPDATE discovered_devices SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
 END;
 
--- Insert default datacenter
-INSERT INTO datacenters (id, name, location, description)
-VALUES ('default', 'Default', 'Default location', 'Default datacenter for devices not assigned to a specific datacenter');
+-- Custom field definitions table
+CREATE TABLE IF NOT EXISTS custom_field_definitions (
+	id TEXT PRIMARY KEY,
+	entity_type TEXT NOT NULL,
+	name TEXT NOT NULL,
+	label TEXT,
+	type TEXT NOT NULL,
+	required BOOLEAN DEFAULT 0,
+	pattern TEXT,
+	options TEXT,
+	description TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(entity_type, name)
+);
+
+-- Trigger to update custom_field_definitions timestamp
+CREATE TRIGGER IF NOT EXISTS update_custom_field_definitions_timestamp
+AFTER UPDATE ON custom_field_definitions
+FOR EACH ROW
+BEGIN
+	UPDATE custom_field_definitions SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+-- Custom field values table (entity_id refers to a device, network or datacenter)
+CREATE TABLE IF NOT EXISTS custom_field_values (
+	entity_type TEXT NOT NULL,
+	entity_id TEXT NOT NULL,
+	field_id TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (entity_type, entity_id, field_id),
+	FOREIGN KEY (field_id) REFERENCES custom_field_definitions(id) ON DELETE CASCADE
+);
+
+-- Indexes for custom field values
+CREATE INDEX IF NOT EXISTS idx_custom_field_values_field ON custom_field_values(field_id, value);
+
+-- Triggers to remove custom field values of deleted entities
+CREATE TRIGGER IF NOT EXISTS delete_device_custom_field_values
+AFTER DELETE ON devices
+FOR EACH ROW
+BEGIN
+	DELETE FROM custom_field_values WHERE entity_type = 'device' AND entity_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS delete_network_custom_field_values
+AFTER DELETE ON networks
+FOR EACH ROW
+BEGIN
+	DELETE FROM custom_field_values WHERE entity_type = 'network' AND entity_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS delete_datacenter_custom_field_values
+AFTER DELETE ON datacenters
+FOR EACH ROW
+BEGIN
+	DELETE FROM custom_field_values WHERE entity_type = 'datacenter' AND entity_id = OLD.id;
+END;
+
+-- Device labels table
+CREATE TABLE IF NOT EXISTS device_labels (
+	device_id TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (device_id, key),
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Index for device labels
+CREATE INDEX IF NOT EXISTS idx_device_labels_key ON device_labels(key, value);
+
+-- Network labels table
+CREATE TABLE IF NOT EXISTS network_labels (
+	network_id TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (network_id, key),
+	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE
+);
+
+-- Index for network labels
+CREATE INDEX IF NOT EXISTS idx_network_labels_key ON network_labels(key, value);
+
+-- Pool labels table
+CREATE TABLE IF NOT EXISTS pool_labels (
+	pool_id TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (pool_id, key),
+	FOREIGN KEY (pool_id) REFERENCES network_pools(id) ON DELETE CASCADE
+);
+
+-- Index for pool labels
+CREATE INDEX IF NOT EXISTS idx_pool_labels_key ON pool_labels(key, value);
+
+-- Device lifecycle transitions
+CREATE TABLE IF NOT EXISTS device_status_history (
+	id TEXT PRIMARY KEY,
+	device_id TEXT NOT NULL,
+	from_status TEXT,
+	to_status TEXT NOT NULL,
+	note TEXT,
+	changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Index for device lifecycle transitions
+CREATE INDEX IF NOT EXISTS idx_device_status_history_device ON device_status_history(device_id, changed_at);
+
+-- Hardware asset data of devices (serial numbers and asset tags are unique when set)
+CREATE TABLE IF NOT EXISTS device_assets (
+	device_id TEXT PRIMARY KEY,
+	serial_number TEXT,
+	asset_tag TEXT,
+	vendor TEXT,
+	purchase_date TEXT,
+	po_number TEXT,
+	cost REAL,
+	warranty_expires TEXT,
+	support_contract TEXT,
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Indexes for device assets
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_assets_serial ON device_assets(serial_number COLLATE NOCASE) WHERE serial_number IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_assets_tag ON device_assets(asset_tag COLLATE NOCASE) WHERE asset_tag IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_device_assets_warranty ON device_assets(warranty_expires);
+
+-- Software inventory of devices, replaced on every push
+CREATE TABLE IF NOT EXISTS device_software (
+	device_id TEXT PRIMARY KEY,
+	os_family TEXT,
+	os_distribution TEXT,
+	os_version TEXT,
+	os_kernel TEXT,
+	collected_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS device_packages (
+	device_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	version TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (device_id, name, version),
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Indexes for software inventory
+DROP INDEX IF EXISTS idx_device_packages_name;
+CREATE INDEX IF NOT EXISTS idx_device_packages_lower_name ON device_packages(LOWER(name));
+
+-- Background jobs and their history
+CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	params TEXT,
+	result TEXT,
+	error TEXT,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL DEFAULT 1,
+	logs TEXT,
+	run_at TIMESTAMP NOT NULL,
+	started_at TIMESTAMP,
+	finished_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status, run_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_created ON jobs(created_at);
+
+-- Leases on work claimed by a rackd instance, so instances sharing the
+-- database don't run the same job or scan twice
+CREATE TABLE IF NOT EXISTS leases (
+	name TEXT PRIMARY KEY,
+	owner TEXT NOT NULL,
+	expires_at INTEGER NOT NULL -- unix milliseconds
+);
@@ -0,0 +1,487 @@
+-- PostgreSQL Schema for Rackd
+-- This file mirrors schema.sql for the PostgreSQL backend and must be kept in
+-- sync with it. It is applied on every start, so all statements must be
+-- idempotent. Requires PostgreSQL 14 or newer (CREATE OR REPLACE TRIGGER).
+
+-- Keeps updated_at current, like the update triggers of schema.sql
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+	NEW.updated_at = CURRENT_TIMESTAMP;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Removes the custom field values of a deleted entity; the entity type is the trigger argument
+CREATE OR REPLACE FUNCTION delete_custom_field_values() RETURNS TRIGGER AS $$
+BEGIN
+	DELETE FROM custom_field_values WHERE entity_type = TG_ARGV[0] AND entity_id = OLD.id;
+	RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Datacenters table
+CREATE TABLE IF NOT EXISTS datacenters (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	location TEXT,
+	description TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1
+);
+
+-- Trigger to update datacenters timestamp
+CREATE OR REPLACE TRIGGER update_datacenters_timestamp
+BEFORE UPDATE ON datacenters
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Networks table
+CREATE TABLE IF NOT EXISTS networks (
+	id TEXT PRIMARY KEY,
+	datacenter_id TEXT,
+	name TEXT NOT NULL,
+	subnet TEXT NOT NULL,
+	description TEXT,
+	vlan INTEGER,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
+	FOREIGN KEY (datacenter_id) REFERENCES datacenters(id) ON DELETE SET NULL,
+	UNIQUE(datacenter_id, name)
+);
+
+-- Index for networks
+CREATE INDEX IF NOT EXISTS idx_networks_datacenter ON networks(datacenter_id);
+
+-- Trigger to update networks timestamp
+CREATE OR REPLACE TRIGGER update_networks_timestamp
+BEFORE UPDATE ON networks
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Devices table
+CREATE TABLE IF NOT EXISTS devices (
+	id TEXT PRIMARY KEY,
+	datacenter_id TEXT,
+	name TEXT NOT NULL,
+	description TEXT,
+	make_model TEXT,
+	os TEXT,
+	username TEXT,
+	location TEXT,
+	status TEXT NOT NULL DEFAULT 'active',
+	status_changed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
+	FOREIGN KEY (datacenter_id) REFERENCES datacenters(id) ON DELETE SET NULL
+);
+
+-- Indexes for devices
+CREATE INDEX IF NOT EXISTS idx_devices_datacenter ON devices(datacenter_id);
+CREATE INDEX IF NOT EXISTS idx_devices_status ON devices(status);
+
+-- Trigger to update devices timestamp
+CREATE OR REPLACE TRIGGER update_devices_timestamp
+BEFORE UPDATE ON devices
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Network pools table
+CREATE TABLE IF NOT EXISTS network_pools (
+	id TEXT PRIMARY KEY,
+	network_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	start_ip TEXT NOT NULL,
+	end_ip TEXT NOT NULL,
+	description TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
+	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE,
+	UNIQUE(network_id, name)
+);
+
+-- Indexes for network pools
+CREATE INDEX IF NOT EXISTS idx_network_pools_network_id ON network_pools(network_id);
+
+-- Trigger to update network_pools timestamp
+CREATE OR REPLACE TRIGGER update_network_pools_timestamp
+BEFORE UPDATE ON network_pools
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Device addresses table (with pool_id support)
+CREATE TABLE IF NOT EXISTS addresses (
+	device_id TEXT NOT NULL,
+	ip TEXT NOT NULL,
+	port INTEGER,
+	type TEXT,
+	label TEXT,
+	network_id TEXT,
+	pool_id TEXT,
+	switch_port TEXT,
+	mac_address TEXT,
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE,
+	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE SET NULL,
+	FOREIGN KEY (pool_id) REFERENCES network_pools(id) ON DELETE SET NULL
+);
+
+-- Indexes for addresses
+CREATE INDEX IF NOT EXISTS idx_addresses_device ON addresses(device_id);
+CREATE INDEX IF NOT EXISTS idx_addresses_network ON addresses(network_id);
+CREATE INDEX IF NOT EXISTS idx_addresses_pool ON addresses(pool_id);
+CREATE INDEX IF NOT EXISTS idx_addresses_ip ON addresses(ip);
+
+-- Device tags table
+CREATE TABLE IF NOT EXISTS tags (
+	device_id TEXT NOT NULL,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (device_id, tag),
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Index for tags
+CREATE INDEX IF NOT EXISTS idx_tags_device ON tags(device_id);
+
+-- Device domains table
+CREATE TABLE IF NOT EXISTS domains (
+	device_id TEXT NOT NULL,
+	domain TEXT NOT NULL,
+	PRIMARY KEY (device_id, domain),
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Index for domains
+CREATE INDEX IF NOT EXISTS idx_domains_device ON domains(device_id);
+
+-- Device relationships table
+CREATE TABLE IF NOT EXISTS relationships (
+	parent_id TEXT NOT NULL,
+	child_id TEXT NOT NULL,
+	type TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (parent_id, child_id, type),
+	FOREIGN KEY (parent_id) REFERENCES devices(id) ON DELETE CASCADE,
+	FOREIGN KEY (child_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Index for relationships
+CREATE INDEX IF NOT EXISTS idx_relationships_parent ON relationships(parent_id);
+CREATE INDEX IF NOT EXISTS idx_relationships_child ON relationships(child_id);
+
+-- Pool tags table
+CREATE TABLE IF NOT EXISTS pool_tags (
+	pool_id TEXT NOT NULL,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (pool_id, tag),
+	FOREIGN KEY (pool_id) REFERENCES network_pools(id) ON DELETE CASCADE
+);
+
+-- Index for pool tags
+CREATE INDEX IF NOT EXISTS idx_pool_tags_pool ON pool_tags(pool_id);
+
+-- Discovery: Discovered devices table
+CREATE TABLE IF NOT EXISTS discovered_devices (
+	id TEXT PRIMARY KEY,
+	ip TEXT NOT NULL UNIQUE,
+	mac_address TEXT,
+	hostname TEXT,
+	network_id TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'unknown',
+	confidence INTEGER DEFAULT 50,
+	os_guess TEXT,
+	os_family TEXT,
+	open_ports TEXT,
+	services TEXT,
+	first_seen TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_seen TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_scan_id TEXT,
+	promoted_to_device_id TEXT,
+	promoted_at TIMESTAMPTZ,
+	raw_scan_data TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE,
+	FOREIGN KEY (promoted_to_device_id) REFERENCES devices(id) ON DELETE SET NULL
+);
+
+-- Indexes for discovered_devices
+CREATE INDEX IF NOT EXISTS idx_discovered_devices_network ON discovered_devices(network_id);
+CREATE INDEX IF NOT EXISTS idx_discovered_devices_status ON discovered_devices(status);
+CREATE INDEX IF NOT EXISTS idx_discovered_devices_promoted ON discovered_devices(promoted_to_device_id);
+CREATE INDEX IF NOT EXISTS idx_discovered_devices_last_seen ON discovered_devices(last_seen);
+
+-- Discovery: Discovery scans table
+CREATE TABLE IF NOT EXISTS discovery_scans (
+	id TEXT PRIMARY KEY,
+	network_id TEXT NOT NULL,
+	rule_id TEXT,
+	status TEXT NOT NULL DEFAULT 'pending',
+	scan_type TEXT NOT NULL,
+	scan_depth INTEGER DEFAULT 1,
+	total_hosts INTEGER DEFAULT 0,
+	scanned_hosts INTEGER DEFAULT 0,
+	found_hosts INTEGER DEFAULT 0,
+	progress_percent DOUBLE PRECISION DEFAULT 0,
+	started_at TIMESTAMPTZ,
+	completed_at TIMESTAMPTZ,
+	duration_seconds INTEGER DEFAULT 0,
+	error_message TEXT,
+	change_summary TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE
+);
+
+-- Indexes for discovery_scans
+CREATE INDEX IF NOT EXISTS idx_discovery_scans_network ON discovery_scans(network_id);
+CREATE INDEX IF NOT EXISTS idx_discovery_scans_status ON discovery_scans(status);
+CREATE INDEX IF NOT EXISTS idx_discovery_scans_created ON discovery_scans(created_at);
+
+-- Trigger to update discovery_scans timestamp
+CREATE OR REPLACE TRIGGER update_discovery_scans_timestamp
+BEFORE UPDATE ON discovery_scans
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Discovery: Hosts finished by a scan, so an interrupted scan can resume where it stopped
+CREATE TABLE IF NOT EXISTS discovery_scan_hosts (
+	scan_id TEXT NOT NULL,
+	ip TEXT NOT NULL,
+	found BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (scan_id, ip),
+	FOREIGN KEY (scan_id) REFERENCES discovery_scans(id) ON DELETE CASCADE
+);
+
+-- Discovery: What each scan saw on the hosts it found, for comparing consecutive scans
+CREATE TABLE IF NOT EXISTS discovery_observations (
+	scan_id TEXT NOT NULL,
+	ip TEXT NOT NULL,
+	status TEXT NOT NULL,
+	mac_address TEXT,
+	hostname TEXT,
+	os_guess TEXT,
+	open_ports TEXT,
+	observed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (scan_id, ip),
+	FOREIGN KEY (scan_id) REFERENCES discovery_scans(id) ON DELETE CASCADE
+);
+
+-- Discovery: Discovery rules table
+CREATE TABLE IF NOT EXISTS discovery_rules (
+	id TEXT PRIMARY KEY,
+	network_id TEXT NOT NULL UNIQUE,
+	enabled BOOLEAN DEFAULT TRUE,
+	scan_interval_hours INTEGER DEFAULT 24,
+	schedule TEXT,
+	timezone TEXT,
+	jitter_minutes INTEGER NOT NULL DEFAULT 0,
+	blackouts TEXT,
+	probes TEXT,
+	scan_type TEXT DEFAULT 'full',
+	max_concurrent_scans INTEGER DEFAULT 10,
+	timeout_seconds INTEGER DEFAULT 5,
+	scan_ports BOOLEAN DEFAULT TRUE,
+	port_scan_type TEXT DEFAULT 'common',
+	custom_ports TEXT,
+	service_detection BOOLEAN DEFAULT TRUE,
+	os_detection BOOLEAN DEFAULT TRUE,
+	exclude_ips TEXT,
+	exclude_hosts TEXT,
+	last_run_at TIMESTAMPTZ,
+	next_run_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE
+);
+
+-- Index for discovery_rules
+CREATE INDEX IF NOT EXISTS idx_discovery_rules_network ON discovery_rules(network_id);
+
+-- Trigger to update discovery_rules timestamp
+CREATE OR REPLACE TRIGGER update_discovery_rules_timestamp
+BEFORE UPDATE ON discovery_rules
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Discovery: Auto-promotion policies table
+CREATE TABLE IF NOT EXISTS promotion_policies (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	description TEXT,
+	enabled BOOLEAN DEFAULT TRUE,
+	priority INTEGER NOT NULL DEFAULT 0,
+	match_conditions TEXT,
+	template TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Trigger to update discovered_devices timestamp
+CREATE OR REPLACE TRIGGER update_discovered_devices_timestamp
+BEFORE UPDATE ON discovered_devices
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Custom field definitions table
+CREATE TABLE IF NOT EXISTS custom_field_definitions (
+	id TEXT PRIMARY KEY,
+	entity_type TEXT NOT NULL,
+	name TEXT NOT NULL,
+	label TEXT,
+	type TEXT NOT NULL,
+	required BOOLEAN DEFAULT FALSE,
+	pattern TEXT,
+	options TEXT,
+	description TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(entity_type, name)
+);
+
+-- Trigger to update custom_field_definitions timestamp
+CREATE OR REPLACE TRIGGER update_custom_field_definitions_timestamp
+BEFORE UPDATE ON custom_field_definitions
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Custom field values table (entity_id refers to a device, network or datacenter)
+CREATE TABLE IF NOT EXISTS custom_field_values (
+	entity_type TEXT NOT NULL,
+	entity_id TEXT NOT NULL,
+	field_id TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (entity_type, entity_id, field_id),
+	FOREIGN KEY (field_id) REFERENCES custom_field_definitions(id) ON DELETE CASCADE
+);
+
+-- Indexes for custom field values
+CREATE INDEX IF NOT EXISTS idx_custom_field_values_field ON custom_field_values(field_id, value);
+
+-- Triggers to remove custom field values of deleted entities
+CREATE OR REPLACE TRIGGER delete_device_custom_field_values
+AFTER DELETE ON devices
+FOR EACH ROW EXECUTE FUNCTION delete_custom_field_values('device');
+
+CREATE OR REPLACE TRIGGER delete_network_custom_field_values
+AFTER DELETE ON networks
+FOR EACH ROW EXECUTE FUNCTION delete_custom_field_values('network');
+
+CREATE OR REPLACE TRIGGER delete_datacenter_custom_field_values
+AFTER DELETE ON datacenters
+FOR EACH ROW EXECUTE FUNCTION delete_custom_field_values('datacenter');
+
+-- Device labels table
+CREATE TABLE IF NOT EXISTS device_labels (
+	device_id TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (device_id, key),
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Index for device labels
+CREATE INDEX IF NOT EXISTS idx_device_labels_key ON device_labels(key, value);
+
+-- Network labels table
+CREATE TABLE IF NOT EXISTS network_labels (
+	network_id TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (network_id, key),
+	FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE
+);
+
+-- Index for network labels
+CREATE INDEX IF NOT EXISTS idx_network_labels_key ON network_labels(key, value);
+
+-- Pool labels table
+CREATE TABLE IF NOT EXISTS pool_labels (
+	pool_id TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (pool_id, key),
+	FOREIGN KEY (pool_id) REFERENCES network_pools(id) ON DELETE CASCADE
+);
+
+-- Index for pool labels
+CREATE INDEX IF NOT EXISTS idx_pool_labels_key ON pool_labels(key, value);
+
+-- Device lifecycle transitions
+CREATE TABLE IF NOT EXISTS device_status_history (
+	id TEXT PRIMARY KEY,
+	device_id TEXT NOT NULL,
+	from_status TEXT,
+	to_status TEXT NOT NULL,
+	note TEXT,
+	changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Index for device lifecycle transitions
+CREATE INDEX IF NOT EXISTS idx_device_status_history_device ON device_status_history(device_id, changed_at);
+
+-- Hardware asset data of devices (serial numbers and asset tags are unique when set)
+CREATE TABLE IF NOT EXISTS device_assets (
+	device_id TEXT PRIMARY KEY,
+	serial_number TEXT,
+	asset_tag TEXT,
+	vendor TEXT,
+	purchase_date TEXT,
+	po_number TEXT,
+	cost DOUBLE PRECISION,
+	warranty_expires TEXT,
+	support_contract TEXT,
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Indexes for device assets
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_assets_serial ON device_assets(LOWER(serial_number)) WHERE serial_number IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_assets_tag ON device_assets(LOWER(asset_tag)) WHERE asset_tag IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_device_assets_warranty ON device_assets(warranty_expires);
+
+-- Software inventory of devices, replaced on every push
+CREATE TABLE IF NOT EXISTS device_software (
+	device_id TEXT PRIMARY KEY,
+	os_family TEXT,
+	os_distribution TEXT,
+	os_version TEXT,
+	os_kernel TEXT,
+	collected_at TIMESTAMPTZ NOT NULL,
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS device_packages (
+	device_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	version TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (device_id, name, version),
+	FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+);
+
+-- Indexes for software inventory
+CREATE INDEX IF NOT EXISTS idx_device_packages_lower_name ON device_packages(LOWER(name));
+
+-- Background jobs and their history
+CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	params TEXT,
+	result TEXT,
+	error TEXT,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL DEFAULT 1,
+	logs TEXT,
+	run_at TIMESTAMPTZ NOT NULL,
+	started_at TIMESTAMPTZ,
+	finished_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status, run_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_created ON jobs(created_at);
+
+-- Leases on work claimed by a rackd instance, so instances sharing the
+-- database don't run the same job or scan twice
+CREATE TABLE IF NOT EXISTS leases (
+	name TEXT PRIMARY KEY,
+	owner TEXT NOT NULL,
+	expires_at BIGINT NOT NULL -- unix milliseconds
+);
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// ErrInvalidSoftware is returned when a software inventory fails validation
+var ErrInvalidSoftware = errors.New("invalid software inventory")
+
+// SoftwareStorage defines the interface for device OS and software package inventory
+type SoftwareStorage interface {
+	// SetDeviceSoftware replaces the software inventory of a device (by ID or name)
+	SetDeviceSoftware(id string, inventory *model.SoftwareInventory) error
+	// GetDeviceSoftware returns the software inventory of a device (by ID or name)
+	GetDeviceSoftware(id string) (*model.SoftwareInventory, error)
+	// FindPackageInstalls returns the devices with a package installed in a version
+	// matching the filter. An invalid version constraint returns software.ErrInvalidConstraint.
+	FindPackageInstalls(filter *model.PackageFilter) ([]model.PackageInstall, error)
+}
+
+// normalizeSoftware trims the inventory, validates package names and removes duplicate packages
+func normalizeSoftware(inventory *model.SoftwareInventory) error {
+	if os := inventory.OS; os != nil {
+		os.Family = strings.TrimSpace(os.Family)
+		os.Distribution = strings.TrimSpace(os.Distribution)
+		os.Version = strings.TrimSpace(os.Version)
+		os.Kernel = strings.TrimSpace(os.Kernel)
+		if *os == (model.OSInfo{}) {
+			inventory.OS = nil
+		}
+	}
+
+	seen := make(map[model.SoftwarePackage]bool, len(inventory.Packages))
+	packages := make([]model.SoftwarePackage, 0, len(inventory.Packages))
+	for _, pkg := range inventory.Packages {
+		pkg.Name = strings.TrimSpace(pkg.Name)
+		pkg.Version = strings.TrimSpace(pkg.Version)
+		if pkg.Name == "" {
+			return fmt.Errorf("%w: package name is required", ErrInvalidSoftware)
+		}
+		if seen[pkg] {
+			continue
+		}
+		seen[pkg] = true
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Name != packages[j].Name {
+			return packages[i].Name < packages[j].Name
+		}
+		return packages[i].Version < packages[j].Version
+	})
+	inventory.Packages = packages
+	return nil
+}
+
+// osDescription formats structured OS data as the free-text OS of a device, e.g. "ubuntu 22.04"
+func osDescription(os *model.OSInfo) string {
+	name := os.Distribution
+	if name == "" {
+		name = os.Family
+	}
+	return strings.TrimSpace(name + " " + os.Version)
+}
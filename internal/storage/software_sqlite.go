@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/software"
+)
+
+// SetDeviceSoftware replaces the OS and package inventory of a device.
+// The free-text OS of the device is filled from the structured OS if it is empty.
+func (ss *sqlStorage) SetDeviceSoftware(id string, inventory *model.SoftwareInventory) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if err := normalizeSoftware(inventory); err != nil {
+		return err
+	}
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deviceID, _, err := lookupDeviceStatus(tx, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	inventory.DeviceID = deviceID
+	inventory.CollectedAt = &now
+
+	if err := saveDeviceSoftwareTx(tx, inventory); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// saveDeviceSoftwareTx replaces the inventory of inventory.DeviceID within a transaction
+func saveDeviceSoftwareTx(tx sqlTx, inventory *model.SoftwareInventory) error {
+	deviceID := inventory.DeviceID
+	os := inventory.OS
+	if os == nil {
+		os = &model.OSInfo{}
+	}
+	_, err := tx.Exec(`
+		INSERT INTO device_software (device_id, os_family, os_distribution, os_version, os_kernel, collected_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (device_id) DO UPDATE SET
+		    os_family = excluded.os_family, os_distribution = excluded.os_distribution,
+		    os_version = excluded.os_version, os_kernel = excluded.os_kernel, collected_at = excluded.collected_at
+	`, deviceID, nullString(os.Family), nullString(os.Distribution), nullString(os.Version), nullString(os.Kernel), inventory.CollectedAt)
+	if err != nil {
+		return fmt.Errorf("saving software inventory: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM device_packages WHERE device_id = ?", deviceID); err != nil {
+		return fmt.Errorf("deleting old packages: %w", err)
+	}
+	for _, pkg := range inventory.Packages {
+		if _, err := tx.Exec(`INSERT INTO device_packages (device_id, name, version) VALUES (?, ?, ?)`,
+			deviceID, pkg.Name, pkg.Version); err != nil {
+			return fmt.Errorf("inserting package: %w", err)
+		}
+	}
+
+	if inventory.OS != nil {
+		if description := osDescription(inventory.OS); description != "" {
+			if _, err := tx.Exec(`UPDATE devices SET os = ?, version = version + 1 WHERE id = ? AND (os IS NULL OR os = '')`, description, deviceID); err != nil {
+				return fmt.Errorf("updating device OS: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetDeviceSoftware returns the OS and package inventory of a device
+func (ss *sqlStorage) GetDeviceSoftware(id string) (*model.SoftwareInventory, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	deviceID, _, err := lookupDeviceStatus(ss.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := &model.SoftwareInventory{DeviceID: deviceID, Packages: []model.SoftwarePackage{}}
+
+	var collectedAt sql.NullTime
+	var osRow osInfoRow
+	err = ss.db.QueryRow(`SELECT os_family, os_distribution, os_version, os_kernel, collected_at FROM device_software WHERE device_id = ?`,
+		deviceID).Scan(&osRow.family, &osRow.distribution, &osRow.version, &osRow.kernel, &collectedAt)
+	if err == sql.ErrNoRows {
+		return inventory, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying software inventory: %w", err)
+	}
+	inventory.OS = osRow.toModel()
+	if collectedAt.Valid {
+		inventory.CollectedAt = &collectedAt.Time
+	}
+
+	rows, err := ss.db.Query(`SELECT name, version FROM device_packages WHERE device_id = ? ORDER BY name, version`, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("querying packages: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pkg model.SoftwarePackage
+		if err := rows.Scan(&pkg.Name, &pkg.Version); err != nil {
+			return nil, fmt.Errorf("scanning package: %w", err)
+		}
+		inventory.Packages = append(inventory.Packages, pkg)
+	}
+
+	return inventory, rows.Err()
+}
+
+// FindPackageInstalls returns the devices that have a package installed in a matching version
+func (ss *sqlStorage) FindPackageInstalls(filter *model.PackageFilter) ([]model.PackageInstall, error) {
+	constraint, err := software.ParseConstraint(filter.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	rows, err := ss.db.Query(`
+		SELECT d.id, d.name, p.name, p.version
+		FROM device_packages p
+		INNER JOIN devices d ON d.id = p.device_id
+		WHERE LOWER(p.name) = LOWER(?)
+		ORDER BY d.name, p.version
+	`, strings.TrimSpace(filter.Name))
+	if err != nil {
+		return nil, fmt.Errorf("querying packages: %w", err)
+	}
+	defer rows.Close()
+
+	installs := []model.PackageInstall{}
+	for rows.Next() {
+		var install model.PackageInstall
+		if err := rows.Scan(&install.DeviceID, &install.DeviceName, &install.Name, &install.Version); err != nil {
+			return nil, fmt.Errorf("scanning package: %w", err)
+		}
+		if constraint.Matches(install.Version) {
+			installs = append(installs, install)
+		}
+	}
+
+	return installs, rows.Err()
+}
+
+// osInfoRow holds the nullable OS columns of a device_software row
+type osInfoRow struct {
+	family, distribution, version, kernel sql.NullString
+}
+
+// toModel returns the OS info, or nil if no OS data was reported
+func (r osInfoRow) toModel() *model.OSInfo {
+	os := &model.OSInfo{
+		Family:       r.family.String,
+		Distribution: r.distribution.String,
+		Version:      r.version.String,
+		Kernel:       r.kernel.String,
+	}
+	if *os == (model.OSInfo{}) {
+		return nil
+	}
+	return os
+}
+
+// loadDeviceOSInfo returns the structured OS of the given devices, keyed by device ID
+func loadDeviceOSInfo(q queryer, ids []string) (map[string]*model.OSInfo, error) {
+	result := make(map[string]*model.OSInfo)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.Repeat("?,", len(ids)-1) + "?"
+
+	rows, err := q.Query(`SELECT device_id, os_family, os_distribution, os_version, os_kernel
+		FROM device_software WHERE device_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying OS info: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var osRow osInfoRow
+		if err := rows.Scan(&id, &osRow.family, &osRow.distribution, &osRow.version, &osRow.kernel); err != nil {
+			return nil, fmt.Errorf("scanning OS info: %w", err)
+		}
+		if os := osRow.toModel(); os != nil {
+			result[id] = os
+		}
+	}
+
+	return result, rows.Err()
+}
+
+// listSoftwareInventories returns the inventories of all devices that reported one
+func listSoftwareInventories(q queryer) ([]model.SoftwareInventory, error) {
+	rows, err := q.Query(`SELECT device_id, os_family, os_distribution, os_version, os_kernel, collected_at
+		FROM device_software ORDER BY device_id`)
+	if err != nil {
+		return nil, fmt.Errorf("querying software inventories: %w", err)
+	}
+	defer rows.Close()
+
+	inventories := []model.SoftwareInventory{}
+	byDevice := make(map[string]int)
+	for rows.Next() {
+		var inv model.SoftwareInventory
+		var osRow osInfoRow
+		var collectedAt sql.NullTime
+		if err := rows.Scan(&inv.DeviceID, &osRow.family, &osRow.distribution, &osRow.version, &osRow.kernel, &collectedAt); err != nil {
+			return nil, fmt.Errorf("scanning software inventory: %w", err)
+		}
+		inv.OS = osRow.toModel()
+		if collectedAt.Valid {
+			inv.CollectedAt = &collectedAt.Time
+		}
+		inv.Packages = []model.SoftwarePackage{}
+		byDevice[inv.DeviceID] = len(inventories)
+		inventories = append(inventories, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	pkgRows, err := q.Query(`SELECT device_id, name, version FROM device_packages ORDER BY device_id, name, version`)
+	if err != nil {
+		return nil, fmt.Errorf("querying packages: %w", err)
+	}
+	defer pkgRows.Close()
+	for pkgRows.Next() {
+		var deviceID string
+		var pkg model.SoftwarePackage
+		if err := pkgRows.Scan(&deviceID, &pkg.Name, &pkg.Version); err != nil {
+			return nil, fmt.Errorf("scanning package: %w", err)
+		}
+		if i, ok := byDevice[deviceID]; ok {
+			inventories[i].Packages = append(inventories[i].Packages, pkg)
+		}
+	}
+
+	return inventories, pkgRows.Err()
+}
@@ -23,13 +23,41 @@ import (
 //go:embed schema.sql
 var schemaFS embed.FS
 
+// sqlStorage implements the storage interfaces on a database/sql handle. Its
+// queries are written in the SQL dialect shared by SQLite and PostgreSQL, with ?
+// placeholders, and are used by both SQLiteStorage and PostgresStorage.
+type sqlStorage struct {
+	mu rwLocker
+	db sqlConn
+}
+
+// rwLocker is the lock storage methods hold while they query. SQLite allows one
+// writer at a time, so its methods take a mutex; PostgreSQL serializes writers
+// itself, with its transactions and row locks, so its methods take none.
+type rwLocker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+// noLock is the rwLocker of stores that leave concurrency to the database
+type noLock struct{}
+
+func (noLock) Lock()    {}
+func (noLock) Unlock()  {}
+func (noLock) RLock()   {}
+func (noLock) RUnlock() {}
+
 // SQLiteStorage implements Storage with SQLite backend
 type SQLiteStorage struct {
-	mu   sync.RWMutex
-	db   *sql.DB
+	sqlStorage
 	path string
 }
 
+// DatabaseFile is the name of the SQLite database within the data directory
+const DatabaseFile = "devices.db"
+
 // NewSQLiteStorage creates a new SQLite-based storage
 func NewSQLiteStorage(dataDir string) (*SQLiteStorage, error) {
 	// Ensure data directory exists
@@ -37,7 +65,7 @@ func NewSQLiteStorage(dataDir string) (*SQLiteStorage, error) {
 		return nil, err
 	}
 
-	dbPath := filepath.Join(dataDir, "devices.db")
+	dbPath := filepath.Join(dataDir, DatabaseFile)
 
 	// Open database with SQLite settings
 	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)", dbPath)
@@ -57,8 +85,8 @@ func NewSQLiteStorage(dataDir string) (*SQLiteStorage, error) {
 	db.SetMaxIdleConns(1)
 
 	ss := &SQLiteStorage{
-		db:   db,
-		path: dbPath,
+		sqlStorage: sqlStorage{mu: new(sync.RWMutex), db: dbConn{db}},
+		path:       dbPath,
 	}
 
 	// Initialize schema
@@ -71,47 +99,160 @@ func NewSQLiteStorage(dataDir string) (*SQLiteStorage, error) {
 }
 
 // initSchema creates the database schema from schema.sql
+//
+// schema.sql only contains idempotent statements, so it is applied on every
+// start. This lets tables added in later releases appear in existing databases.
 func (ss *SQLiteStorage) initSchema() error {
 	// Check if database is already initialized by checking for datacenters table
 	var tableName string
 	err := ss.db.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name='datacenters'").Scan(&tableName)
-	if err == sql.ErrNoRows {
-		// Fresh database - run schema.sql
-		schema, err := schemaFS.ReadFile("schema.sql")
+	fresh := err == sql.ErrNoRows
+	if err != nil && !fresh {
+		return fmt.Errorf("checking schema: %w", err)
+	}
+
+	if !fresh {
+		if err := ss.addMissingColumns(); err != nil {
+			return err
+		}
+	}
+
+	schema, err := schemaFS.ReadFile("schema.sql")
+	if err != nil {
+		return fmt.Errorf("reading schema: %w", err)
+	}
+
+	if _, err := ss.db.Exec(string(schema)); err != nil {
+		return fmt.Errorf("executing schema.sql: %w", err)
+	}
+
+	if fresh {
+		// Insert default datacenter
+		_, err := ss.db.Exec(`
+			INSERT INTO datacenters (id, name, location, description)
+			VALUES ('default', 'Default', 'Default location', 'Default datacenter for devices not assigned to a specific datacenter')
+		`)
 		if err != nil {
-			return fmt.Errorf("reading schema: %w", err)
+			return fmt.Errorf("creating default datacenter: %w", err)
 		}
+		log.Info("Database initialized from schema.sql")
+	}
+	return nil
+}
+
+// schemaColumns lists columns added to existing tables after their initial release.
+// CREATE TABLE IF NOT EXISTS does not alter existing tables, so these are added
+// with ALTER TABLE before schema.sql runs (its indexes may reference them).
+var schemaColumns = []struct {
+	table, column, definition string
+}{
+	{"devices", "location", "TEXT"},
+	{"devices", "status", "TEXT NOT NULL DEFAULT 'active'"},
+	{"devices", "status_changed_at", "TIMESTAMP"},
+	{"addresses", "pool_id", "TEXT REFERENCES network_pools(id) ON DELETE SET NULL"},
+	{"addresses", "switch_port", "TEXT"},
+	{"addresses", "mac_address", "TEXT"},
+	{"datacenters", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"networks", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"devices", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"network_pools", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"discovery_rules", "schedule", "TEXT"},
+	{"discovery_rules", "timezone", "TEXT"},
+	{"discovery_rules", "jitter_minutes", "INTEGER NOT NULL DEFAULT 0"},
+	{"discovery_rules", "blackouts", "TEXT"},
+	{"discovery_rules", "probes", "TEXT"},
+	{"discovery_scans", "change_summary", "TEXT"},
+	{"discovery_scans", "rule_id", "TEXT"},
+}
 
-		_, err = ss.db.Exec(string(schema))
+// addMissingColumns adds columns from schemaColumns that an existing database lacks
+func (ss *SQLiteStorage) addMissingColumns() error {
+	for _, c := range schemaColumns {
+		rows, err := ss.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", c.table))
 		if err != nil {
-			return fmt.Errorf("executing schema.sql: %w", err)
+			return fmt.Errorf("reading columns of %s: %w", c.table, err)
+		}
+		tableExists, found := false, false
+		for rows.Next() {
+			var cid, notNull, pk int
+			var name, colType string
+			var defaultValue sql.NullString
+			if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+				rows.Close()
+				return fmt.Errorf("scanning columns of %s: %w", c.table, err)
+			}
+			tableExists = true
+			if strings.EqualFold(name, c.column) {
+				found = true
+			}
 		}
+		rows.Close()
 
-		log.Info("Database initialized from schema.sql")
+		// Tables that don't exist yet are created by schema.sql
+		if !tableExists || found {
+			continue
+		}
+		if _, err := ss.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)); err != nil {
+			return fmt.Errorf("adding column %s.%s: %w", c.table, c.column, err)
+		}
+		log.Info("Added missing database column", "table", c.table, "column", c.column)
 	}
 	return nil
 }
 
 // Close closes the database connection
-func (ss *SQLiteStorage) Close() error {
-	return ss.db.Close()
+func (ss *sqlStorage) Close() error {
+	// The store of a unit of work shares the connections of its backend
+	if conn, ok := ss.db.(dbConn); ok {
+		return conn.Close()
+	}
+	return nil
 }
 
 // ListDevices returns all devices, optionally filtered
-func (ss *SQLiteStorage) ListDevices(filter *model.DeviceFilter) ([]model.Device, error) {
+func (ss *sqlStorage) ListDevices(filter *model.DeviceFilter) ([]model.Device, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
+	return ss.listDevicesLocked(filter)
+}
 
+func (ss *sqlStorage) listDevicesLocked(filter *model.DeviceFilter) ([]model.Device, error) {
 	log.Debug("Listing devices from storage", "filter_tags", filter != nil && len(filter.Tags) > 0)
 
 	query := `
 		SELECT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
-		ORDER BY d.name
 	`
 
-	rows, err := ss.db.Query(query)
+	var conditions []string
+	var args []interface{}
+	if filter != nil && len(filter.Statuses) > 0 {
+		conditions = append(conditions, "d.status IN ("+strings.Repeat("?,", len(filter.Statuses)-1)+"?)")
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if filter != nil && len(filter.CustomFields) > 0 {
+		cfConditions, cfArgs := customFieldConditions(model.CustomFieldEntityDevice, "d.id", filter.CustomFields)
+		conditions = append(conditions, cfConditions...)
+		args = append(args, cfArgs...)
+	}
+	if filter != nil && filter.LabelSelector != "" {
+		labelConditions, labelArgs, err := deviceLabelTable.conditions("d.id", filter.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, labelConditions...)
+		args = append(args, labelArgs...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY d.name"
+
+	rows, err := ss.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying devices: %w", err)
 	}
@@ -139,18 +280,18 @@ func (ss *SQLiteStorage) ListDevices(filter *model.DeviceFilter) ([]model.Device
 }
 
 // GetDevice retrieves a device by ID or name
-func (ss *SQLiteStorage) GetDevice(id string) (*model.Device, error) {
+func (ss *sqlStorage) GetDevice(id string) (*model.Device, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 	return ss.getDeviceLocked(id)
 }
 
-func (ss *SQLiteStorage) getDeviceLocked(id string) (*model.Device, error) {
+func (ss *sqlStorage) getDeviceLocked(id string) (*model.Device, error) {
 
 	// Try ID lookup first
 	query := `
 		SELECT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
 		WHERE d.id = ?
 		LIMIT 1
@@ -167,7 +308,7 @@ func (ss *SQLiteStorage) getDeviceLocked(id string) (*model.Device, error) {
 	// Try name lookup
 	query = `
 		SELECT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
 		WHERE LOWER(d.name) = LOWER(?)
 		LIMIT 1
@@ -189,15 +330,14 @@ func (ss *SQLiteStorage) getDeviceLocked(id string) (*model.Device, error) {
 }
 
 // CreateDevice adds a new device
-func (ss *SQLiteStorage) CreateDevice(device *model.Device) error {
+func (ss *sqlStorage) CreateDevice(device *model.Device) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
 	log.Debug("Creating device in storage", "id", device.ID, "name", device.Name)
 
-	now := time.Now()
-	device.CreatedAt = now
-	device.UpdatedAt = now
+	// New devices are always stamped with the current time
+	device.CreatedAt, device.UpdatedAt, device.StatusChangedAt, device.Version = time.Time{}, time.Time{}, nil, 0
 
 	tx, err := ss.db.Begin()
 	if err != nil {
@@ -205,6 +345,19 @@ func (ss *SQLiteStorage) CreateDevice(device *model.Device) error {
 	}
 	defer tx.Rollback()
 
+	if err := ss.createDeviceTx(tx, device); err != nil {
+		return err
+	}
+
+	log.Info("Device created in storage", "id", device.ID, "name", device.Name, "addresses_count", len(device.Addresses))
+	return tx.Commit()
+}
+
+// createDeviceTx inserts a device with all its relations within a transaction
+func (ss *sqlStorage) createDeviceTx(tx sqlTx, device *model.Device) error {
+	now := initTimestamps(&device.CreatedAt, &device.UpdatedAt)
+	initVersion(&device.Version)
+
 	// Insert device (convert empty string to nil for NULL in SQL)
 	var datacenterIDValue interface{}
 	if device.DatacenterID == "" {
@@ -227,14 +380,27 @@ func (ss *SQLiteStorage) CreateDevice(device *model.Device) error {
 		locationValue = device.Location
 	}
 
-	_, err = tx.Exec(`
-		INSERT INTO devices (id, name, description, make_model, os, datacenter_id, username, location, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	if device.Status == "" {
+		device.Status = model.DeviceStatusActive
+	}
+	if !device.Status.Valid() {
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, device.Status)
+	}
+	if device.StatusChangedAt == nil {
+		device.StatusChangedAt = &now
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO devices (id, name, description, make_model, os, datacenter_id, username, location, status, status_changed_at, created_at, updated_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, device.ID, device.Name, device.Description, device.MakeModel, device.OS, datacenterIDValue, usernameValue, locationValue,
-		device.CreatedAt, device.UpdatedAt)
+		device.Status, device.StatusChangedAt, device.CreatedAt, device.UpdatedAt, device.Version)
 	if err != nil {
 		return fmt.Errorf("inserting device: %w", err)
 	}
+	if _, err := recordStatusChangeTx(tx, device.ID, "", device.Status, "", *device.StatusChangedAt); err != nil {
+		return err
+	}
 
 	// Insert addresses
 	if err := ss.insertDeviceAddresses(tx, device.ID, device.Addresses); err != nil {
@@ -251,23 +417,56 @@ func (ss *SQLiteStorage) CreateDevice(device *model.Device) error {
 		return err
 	}
 
-	log.Info("Device created in storage", "id", device.ID, "name", device.Name, "addresses_count", len(device.Addresses))
-	return tx.Commit()
+	// Insert labels
+	if err := deviceLabelTable.save(tx, device.ID, device.Labels); err != nil {
+		return err
+	}
+
+	// The structured OS is only set by software inventory ingest
+	device.OSInfo = nil
+
+	// Insert asset data
+	if device.Asset != nil {
+		if err := saveDeviceAsset(tx, device.ID, device.Asset); err != nil {
+			return err
+		}
+		if device.Asset.IsZero() {
+			device.Asset = nil
+		}
+	}
+
+	// Insert custom field values
+	customFields, err := ss.saveCustomFieldValues(tx, model.CustomFieldEntityDevice, device.ID, device.CustomFields, true)
+	if err != nil {
+		return err
+	}
+	device.CustomFields = customFields
+
+	return nil
 }
 
 // UpdateDevice updates an existing device
-func (ss *SQLiteStorage) UpdateDevice(device *model.Device) error {
+func (ss *sqlStorage) UpdateDevice(device *model.Device) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	device.UpdatedAt = time.Now()
-
 	tx, err := ss.db.Begin()
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if err := ss.updateDeviceTx(tx, device); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateDeviceTx updates a device with all its relations within a transaction
+func (ss *sqlStorage) updateDeviceTx(tx sqlTx, device *model.Device) error {
+	device.UpdatedAt = time.Now()
+
 	// Update device (convert empty string to nil for NULL in SQL)
 	var datacenterIDValue interface{}
 	if device.DatacenterID == "" {
@@ -292,17 +491,18 @@ func (ss *SQLiteStorage) UpdateDevice(device *model.Device) error {
 
 	result, err := tx.Exec(`
 		UPDATE devices
-		SET name = ?, description = ?, make_model = ?, os = ?, datacenter_id = ?, username = ?, location = ?, updated_at = ?
-		WHERE id = ?
-	`, device.Name, device.Description, device.MakeModel, device.OS, datacenterIDValue, usernameValue, locationValue,
-		device.UpdatedAt, device.ID)
+		SET name = ?, description = ?, make_model = ?, os = ?, datacenter_id = ?, username = ?, location = ?, updated_at = ?,
+		    version = version + 1
+		WHERE id = ? AND `+versionCondition,
+		device.Name, device.Description, device.MakeModel, device.OS, datacenterIDValue, usernameValue, locationValue,
+		device.UpdatedAt, device.ID, device.Version, device.Version)
 	if err != nil {
 		return fmt.Errorf("updating device: %w", err)
 	}
 
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return ErrDeviceNotFound
+		return updateMissed(tx, "devices", device.ID, device.Version, ErrDeviceNotFound)
 	}
 
 	// Delete and reinsert addresses
@@ -329,11 +529,85 @@ func (ss *SQLiteStorage) UpdateDevice(device *model.Device) error {
 		return err
 	}
 
-	return tx.Commit()
+	// Replace labels if provided
+	if device.Labels != nil {
+		if err := deviceLabelTable.save(tx, device.ID, device.Labels); err != nil {
+			return err
+		}
+	}
+
+	// Replace asset data if provided, otherwise keep the current data
+	if device.Asset != nil {
+		if err := saveDeviceAsset(tx, device.ID, device.Asset); err != nil {
+			return err
+		}
+		if device.Asset.IsZero() {
+			device.Asset = nil
+		}
+	} else {
+		assets, err := loadDeviceAssets(tx, []string{device.ID})
+		if err != nil {
+			return err
+		}
+		device.Asset = assets[device.ID]
+	}
+
+	// The structured OS is only set by software inventory ingest
+	deviceOS, err := loadDeviceOSInfo(tx, []string{device.ID})
+	if err != nil {
+		return err
+	}
+	device.OSInfo = deviceOS[device.ID]
+
+	// Replace custom field values if provided
+	if device.CustomFields != nil {
+		customFields, err := ss.saveCustomFieldValues(tx, model.CustomFieldEntityDevice, device.ID, device.CustomFields, false)
+		if err != nil {
+			return err
+		}
+		device.CustomFields = customFields
+	}
+
+	// Apply a status change if requested, otherwise keep the current status
+	_, current, err := lookupDeviceStatus(tx, device.ID)
+	if err != nil {
+		return err
+	}
+	if device.Status != "" && device.Status != current {
+		change, err := transitionDeviceTx(tx, device.ID, current, device.Status, "")
+		if err != nil {
+			return err
+		}
+		device.StatusChangedAt = &change.ChangedAt
+		if len(change.ReleasedAddresses) > 0 {
+			var kept []model.Address
+			for _, addr := range device.Addresses {
+				if addr.PoolID == "" {
+					kept = append(kept, addr)
+				}
+			}
+			device.Addresses = kept
+		}
+	} else {
+		device.Status = current
+		var changedAt sql.NullTime
+		if err := tx.QueryRow("SELECT status_changed_at FROM devices WHERE id = ?", device.ID).Scan(&changedAt); err != nil {
+			return fmt.Errorf("querying status change time: %w", err)
+		}
+		if changedAt.Valid {
+			device.StatusChangedAt = &changedAt.Time
+		}
+	}
+
+	// Read last, as a status change bumps the version again
+	if device.Version, err = rowVersion(tx, "devices", device.ID); err != nil {
+		return err
+	}
+	return nil
 }
 
 // DeleteDevice removes a device
-func (ss *SQLiteStorage) DeleteDevice(id string) error {
+func (ss *sqlStorage) DeleteDevice(id string) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -356,12 +630,12 @@ func (ss *SQLiteStorage) DeleteDevice(id string) error {
 }
 
 // SearchDevices searches for devices matching the query
-func (ss *SQLiteStorage) SearchDevices(query string) ([]model.Device, error) {
+func (ss *sqlStorage) SearchDevices(query string) ([]model.Device, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	if query == "" {
-		return ss.ListDevices(nil)
+		return ss.listDevicesLocked(nil)
 	}
 
 	searchPattern := "%" + strings.ToLower(query) + "%"
@@ -369,7 +643,7 @@ func (ss *SQLiteStorage) SearchDevices(query string) ([]model.Device, error) {
 	// Search in device fields
 	sqlQuery := `
 		SELECT DISTINCT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
 		WHERE LOWER(d.name) LIKE ? OR LOWER(d.description) LIKE ?
 		   OR LOWER(d.make_model) LIKE ? OR LOWER(d.os) LIKE ? OR LOWER(d.location) LIKE ?
@@ -391,7 +665,7 @@ func (ss *SQLiteStorage) SearchDevices(query string) ([]model.Device, error) {
 	// Search in tags
 	tagRows, err := ss.db.Query(`
 		SELECT DISTINCT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
 		INNER JOIN tags t ON d.id = t.device_id
 		WHERE LOWER(t.tag) LIKE ?
@@ -408,7 +682,7 @@ func (ss *SQLiteStorage) SearchDevices(query string) ([]model.Device, error) {
 	// Search in domains
 	domainRows, err := ss.db.Query(`
 		SELECT DISTINCT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
 		INNER JOIN domains dm ON d.id = dm.device_id
 		WHERE LOWER(dm.domain) LIKE ?
@@ -425,7 +699,7 @@ func (ss *SQLiteStorage) SearchDevices(query string) ([]model.Device, error) {
 	// Search in addresses
 	addrRows, err := ss.db.Query(`
 		SELECT DISTINCT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
 		INNER JOIN addresses a ON d.id = a.device_id
 		WHERE a.ip LIKE ?
@@ -439,6 +713,57 @@ func (ss *SQLiteStorage) SearchDevices(query string) ([]model.Device, error) {
 		}
 	}
 
+	// Search in labels (matches "key=value" as well as key or value alone)
+	labelRows, err := ss.db.Query(`
+		SELECT DISTINCT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
+		FROM devices d
+		INNER JOIN device_labels l ON d.id = l.device_id
+		WHERE LOWER(l.key || '=' || l.value) LIKE ?
+		ORDER BY d.name
+	`, searchPattern)
+	if err == nil {
+		labelDevices, err := ss.scanDevices(labelRows)
+		labelRows.Close()
+		if err == nil {
+			devices = ss.mergeDevices(devices, labelDevices)
+		}
+	}
+
+	// Search in serial numbers and asset tags
+	assetRows, err := ss.db.Query(`
+		SELECT DISTINCT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
+		FROM devices d
+		INNER JOIN device_assets a ON d.id = a.device_id
+		WHERE LOWER(a.serial_number) LIKE ? OR LOWER(a.asset_tag) LIKE ?
+		ORDER BY d.name
+	`, searchPattern, searchPattern)
+	if err == nil {
+		assetDevices, err := ss.scanDevices(assetRows)
+		assetRows.Close()
+		if err == nil {
+			devices = ss.mergeDevices(devices, assetDevices)
+		}
+	}
+
+	// Search in custom field values
+	cfRows, err := ss.db.Query(`
+		SELECT DISTINCT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
+		FROM devices d
+		INNER JOIN custom_field_values v ON v.entity_type = 'device' AND v.entity_id = d.id
+		WHERE LOWER(v.value) LIKE ?
+		ORDER BY d.name
+	`, searchPattern)
+	if err == nil {
+		cfDevices, err := ss.scanDevices(cfRows)
+		cfRows.Close()
+		if err == nil {
+			devices = ss.mergeDevices(devices, cfDevices)
+		}
+	}
+
 	// Load all relations for the result devices
 	if len(devices) > 0 {
 		if err := ss.loadBatchRelations(devices); err != nil {
@@ -450,7 +775,7 @@ func (ss *SQLiteStorage) SearchDevices(query string) ([]model.Device, error) {
 }
 
 // AddRelationship adds a relationship between two devices
-func (ss *SQLiteStorage) AddRelationship(parentID, childID, relationshipType string) error {
+func (ss *sqlStorage) AddRelationship(parentID, childID, relationshipType string) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -462,23 +787,32 @@ func (ss *SQLiteStorage) AddRelationship(parentID, childID, relationshipType str
 		return fmt.Errorf("child device not found: %w", err)
 	}
 
-	_, err := ss.db.Exec(`
-		INSERT INTO device_relationships (parent_id, child_id, relationship_type, created_at)
+	_, err := addRelationshipTx(ss.db, parentID, childID, relationshipType)
+	return err
+}
+
+// addRelationshipTx inserts a relationship between two existing devices, reporting whether it was new
+func addRelationshipTx(ex execer, parentID, childID, relationshipType string) (bool, error) {
+	result, err := ex.Exec(`
+		INSERT INTO relationships (parent_id, child_id, type, created_at)
 		VALUES (?, ?, ?, ?)
-		ON CONFLICT (parent_id, child_id, relationship_type) DO NOTHING
+		ON CONFLICT (parent_id, child_id, type) DO NOTHING
 	`, parentID, childID, relationshipType, time.Now())
-
-	return err
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
 }
 
 // RemoveRelationship removes a relationship between two devices
-func (ss *SQLiteStorage) RemoveRelationship(parentID, childID, relationshipType string) error {
+func (ss *sqlStorage) RemoveRelationship(parentID, childID, relationshipType string) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
 	result, err := ss.db.Exec(`
-		DELETE FROM device_relationships
-		WHERE parent_id = ? AND child_id = ? AND relationship_type = ?
+		DELETE FROM relationships
+		WHERE parent_id = ? AND child_id = ? AND type = ?
 	`, parentID, childID, relationshipType)
 	if err != nil {
 		return fmt.Errorf("deleting relationship: %w", err)
@@ -493,15 +827,15 @@ func (ss *SQLiteStorage) RemoveRelationship(parentID, childID, relationshipType
 }
 
 // GetRelationships gets all relationships for a device
-func (ss *SQLiteStorage) GetRelationships(deviceID string) ([]model.DeviceRelationship, error) {
+func (ss *sqlStorage) GetRelationships(deviceID string) ([]model.DeviceRelationship, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	rows, err := ss.db.Query(`
-		SELECT parent_id, child_id, relationship_type, created_at
-		FROM device_relationships
+		SELECT parent_id, child_id, type, created_at
+		FROM relationships
 		WHERE parent_id = ? OR child_id = ?
-		ORDER BY relationship_type, created_at
+		ORDER BY type, created_at
 	`, deviceID, deviceID)
 	if err != nil {
 		return nil, fmt.Errorf("querying relationships: %w", err)
@@ -520,22 +854,57 @@ func (ss *SQLiteStorage) GetRelationships(deviceID string) ([]model.DeviceRelati
 	return relationships, rows.Err()
 }
 
+// ListRelationships returns all relationships, optionally limited to a single type
+func (ss *sqlStorage) ListRelationships(relationshipType string) ([]model.DeviceRelationship, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.listRelationshipsLocked(relationshipType)
+}
+
+// listRelationshipsLocked returns relationships of a type, or all; caller must hold ss.mu
+func (ss *sqlStorage) listRelationshipsLocked(relationshipType string) ([]model.DeviceRelationship, error) {
+	query := `SELECT parent_id, child_id, type, created_at FROM relationships`
+	var args []interface{}
+	if relationshipType != "" {
+		query += " WHERE type = ?"
+		args = append(args, relationshipType)
+	}
+	query += " ORDER BY type, parent_id, child_id"
+
+	rows, err := ss.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying relationships: %w", err)
+	}
+	defer rows.Close()
+
+	var relationships []model.DeviceRelationship
+	for rows.Next() {
+		var r model.DeviceRelationship
+		if err := rows.Scan(&r.ParentID, &r.ChildID, &r.Type, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning relationship: %w", err)
+		}
+		relationships = append(relationships, r)
+	}
+
+	return relationships, rows.Err()
+}
+
 // GetRelatedDevices gets all devices related to the given device
-func (ss *SQLiteStorage) GetRelatedDevices(deviceID string, relationshipType string) ([]model.Device, error) {
+func (ss *sqlStorage) GetRelatedDevices(deviceID string, relationshipType string) ([]model.Device, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	query := `
 		SELECT DISTINCT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
-		INNER JOIN device_relationships dr ON (d.id = dr.parent_id OR d.id = dr.child_id)
+		INNER JOIN relationships dr ON (d.id = dr.parent_id OR d.id = dr.child_id)
 		WHERE (dr.parent_id = ? OR dr.child_id = ?) AND d.id != ?
 	`
 	args := []interface{}{deviceID, deviceID, deviceID}
 
 	if relationshipType != "" {
-		query += " AND dr.relationship_type = ?"
+		query += " AND dr.type = ?"
 		args = append(args, relationshipType)
 	}
 
@@ -563,7 +932,7 @@ func (ss *SQLiteStorage) GetRelatedDevices(deviceID string, relationshipType str
 
 // Helper functions
 
-func (ss *SQLiteStorage) queryDevice(query string, args ...interface{}) (*model.Device, error) {
+func (ss *sqlStorage) queryDevice(query string, args ...interface{}) (*model.Device, error) {
 	rows, err := ss.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying device: %w", err)
@@ -586,7 +955,7 @@ func (ss *SQLiteStorage) queryDevice(query string, args ...interface{}) (*model.
 	return &devices[0], nil
 }
 
-func (ss *SQLiteStorage) scanDevices(rows *sql.Rows) ([]model.Device, error) {
+func (ss *sqlStorage) scanDevices(rows *sql.Rows) ([]model.Device, error) {
 	devices := []model.Device{}
 
 	for rows.Next() {
@@ -594,8 +963,9 @@ func (ss *SQLiteStorage) scanDevices(rows *sql.Rows) ([]model.Device, error) {
 		var datacenterID sql.NullString
 		var username sql.NullString
 		var location sql.NullString
+		var statusChangedAt sql.NullTime
 		err := rows.Scan(&d.ID, &d.Name, &d.Description, &d.MakeModel, &d.OS, &datacenterID, &username, &location,
-			&d.CreatedAt, &d.UpdatedAt)
+			&d.Status, &statusChangedAt, &d.CreatedAt, &d.UpdatedAt, &d.Version)
 		if err != nil {
 			return nil, fmt.Errorf("scanning device: %w", err)
 		}
@@ -614,13 +984,16 @@ func (ss *SQLiteStorage) scanDevices(rows *sql.Rows) ([]model.Device, error) {
 		} else {
 			d.Location = ""
 		}
+		if statusChangedAt.Valid {
+			d.StatusChangedAt = &statusChangedAt.Time
+		}
 		devices = append(devices, d)
 	}
 
 	return devices, rows.Err()
 }
 
-func (ss *SQLiteStorage) loadBatchRelations(devices []model.Device) error {
+func (ss *sqlStorage) loadBatchRelations(devices []model.Device) error {
 	if len(devices) == 0 {
 		return nil
 	}
@@ -655,7 +1028,7 @@ func (ss *SQLiteStorage) loadBatchRelations(devices []model.Device) error {
 	}
 
 	// Load Addresses
-	addrQuery := fmt.Sprintf("SELECT device_id, ip, port, type, label, network_id, pool_id, switch_port FROM addresses WHERE device_id IN (%s) ORDER BY ip", placeholders)
+	addrQuery := fmt.Sprintf("SELECT device_id, ip, port, type, label, network_id, pool_id, switch_port, mac_address FROM addresses WHERE device_id IN (%s) ORDER BY ip", placeholders)
 	rows, err = ss.db.Query(addrQuery, ids...)
 	if err != nil {
 		return fmt.Errorf("querying batch addresses: %w", err)
@@ -667,8 +1040,8 @@ func (ss *SQLiteStorage) loadBatchRelations(devices []model.Device) error {
 		var a model.Address
 		var networkID sql.NullString
 		var poolID sql.NullString
-		var switchPort sql.NullString
-		if err := rows.Scan(&deviceID, &a.IP, &a.Port, &a.Type, &a.Label, &networkID, &poolID, &switchPort); err != nil {
+		var switchPort, macAddress sql.NullString
+		if err := rows.Scan(&deviceID, &a.IP, &a.Port, &a.Type, &a.Label, &networkID, &poolID, &switchPort, &macAddress); err != nil {
 			return err
 		}
 		if networkID.Valid {
@@ -680,6 +1053,7 @@ func (ss *SQLiteStorage) loadBatchRelations(devices []model.Device) error {
 		if switchPort.Valid {
 			a.SwitchPort = switchPort.String
 		}
+		a.MACAddress = macAddress.String
 		if d, ok := deviceMap[deviceID]; ok {
 			d.Addresses = append(d.Addresses, a)
 		}
@@ -703,10 +1077,58 @@ func (ss *SQLiteStorage) loadBatchRelations(devices []model.Device) error {
 		}
 	}
 
+	// Load labels
+	deviceIDs := make([]string, len(devices))
+	for i := range devices {
+		deviceIDs[i] = devices[i].ID
+	}
+	deviceLabels, err := deviceLabelTable.load(ss.db, deviceIDs)
+	if err != nil {
+		return err
+	}
+	for id, l := range deviceLabels {
+		if d, ok := deviceMap[id]; ok {
+			d.Labels = l
+		}
+	}
+
+	// Load asset data
+	deviceAssets, err := loadDeviceAssets(ss.db, deviceIDs)
+	if err != nil {
+		return err
+	}
+	for id, a := range deviceAssets {
+		if d, ok := deviceMap[id]; ok {
+			d.Asset = a
+		}
+	}
+
+	// Load structured OS
+	deviceOS, err := loadDeviceOSInfo(ss.db, deviceIDs)
+	if err != nil {
+		return err
+	}
+	for id, os := range deviceOS {
+		if d, ok := deviceMap[id]; ok {
+			d.OSInfo = os
+		}
+	}
+
+	// Load custom field values
+	values, err := ss.loadCustomFieldValues(model.CustomFieldEntityDevice, deviceIDs)
+	if err != nil {
+		return err
+	}
+	for id, fields := range values {
+		if d, ok := deviceMap[id]; ok {
+			d.CustomFields = fields
+		}
+	}
+
 	return nil
 }
 
-func (ss *SQLiteStorage) loadDeviceRelations(device *model.Device) error {
+func (ss *sqlStorage) loadDeviceRelations(device *model.Device) error {
 	if err := ss.loadDeviceTags(device); err != nil {
 		return err
 	}
@@ -716,10 +1138,30 @@ func (ss *SQLiteStorage) loadDeviceRelations(device *model.Device) error {
 	if err := ss.loadDeviceDomains(device); err != nil {
 		return err
 	}
+	deviceLabels, err := deviceLabelTable.load(ss.db, []string{device.ID})
+	if err != nil {
+		return err
+	}
+	device.Labels = deviceLabels[device.ID]
+	deviceAssets, err := loadDeviceAssets(ss.db, []string{device.ID})
+	if err != nil {
+		return err
+	}
+	device.Asset = deviceAssets[device.ID]
+	deviceOS, err := loadDeviceOSInfo(ss.db, []string{device.ID})
+	if err != nil {
+		return err
+	}
+	device.OSInfo = deviceOS[device.ID]
+	values, err := ss.loadCustomFieldValues(model.CustomFieldEntityDevice, []string{device.ID})
+	if err != nil {
+		return err
+	}
+	device.CustomFields = values[device.ID]
 	return nil
 }
 
-func (ss *SQLiteStorage) loadDeviceTags(device *model.Device) error {
+func (ss *sqlStorage) loadDeviceTags(device *model.Device) error {
 	rows, err := ss.db.Query("SELECT tag FROM tags WHERE device_id = ? ORDER BY tag", device.ID)
 	if err != nil {
 		return fmt.Errorf("querying tags: %w", err)
@@ -739,8 +1181,8 @@ func (ss *SQLiteStorage) loadDeviceTags(device *model.Device) error {
 	return rows.Err()
 }
 
-func (ss *SQLiteStorage) loadDeviceAddresses(device *model.Device) error {
-	rows, err := ss.db.Query("SELECT ip, port, type, label, network_id, pool_id, switch_port FROM addresses WHERE device_id = ? ORDER BY ip", device.ID)
+func (ss *sqlStorage) loadDeviceAddresses(device *model.Device) error {
+	rows, err := ss.db.Query("SELECT ip, port, type, label, network_id, pool_id, switch_port, mac_address FROM addresses WHERE device_id = ? ORDER BY ip", device.ID)
 	if err != nil {
 		return fmt.Errorf("querying addresses: %w", err)
 	}
@@ -749,8 +1191,8 @@ func (ss *SQLiteStorage) loadDeviceAddresses(device *model.Device) error {
 	var addresses []model.Address
 	for rows.Next() {
 		var a model.Address
-		var networkID, poolID, switchPort sql.NullString
-		if err := rows.Scan(&a.IP, &a.Port, &a.Type, &a.Label, &networkID, &poolID, &switchPort); err != nil {
+		var networkID, poolID, switchPort, macAddress sql.NullString
+		if err := rows.Scan(&a.IP, &a.Port, &a.Type, &a.Label, &networkID, &poolID, &switchPort, &macAddress); err != nil {
 			return err
 		}
 		if networkID.Valid {
@@ -762,13 +1204,14 @@ func (ss *SQLiteStorage) loadDeviceAddresses(device *model.Device) error {
 		if switchPort.Valid {
 			a.SwitchPort = switchPort.String
 		}
+		a.MACAddress = macAddress.String
 		addresses = append(addresses, a)
 	}
 
 	device.Addresses = addresses
 	return rows.Err()
 }
-func (ss *SQLiteStorage) loadDeviceDomains(device *model.Device) error {
+func (ss *sqlStorage) loadDeviceDomains(device *model.Device) error {
 	rows, err := ss.db.Query("SELECT domain FROM domains WHERE device_id = ? ORDER BY domain", device.ID)
 	if err != nil {
 		return fmt.Errorf("querying domains: %w", err)
@@ -788,11 +1231,11 @@ func (ss *SQLiteStorage) loadDeviceDomains(device *model.Device) error {
 	return rows.Err()
 }
 
-func (ss *SQLiteStorage) insertDeviceAddresses(tx *sql.Tx, deviceID string, addresses []model.Address) error {
+func (ss *sqlStorage) insertDeviceAddresses(tx sqlTx, deviceID string, addresses []model.Address) error {
 	for _, addr := range addresses {
 		query := `
-			INSERT INTO addresses (device_id, ip, port, type, label, network_id, pool_id, switch_port)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO addresses (device_id, ip, port, type, label, network_id, pool_id, switch_port, mac_address)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`
 		var err error
 		// Convert empty string to nil for NULL in SQL
@@ -816,9 +1259,9 @@ func (ss *SQLiteStorage) insertDeviceAddresses(tx *sql.Tx, deviceID string, addr
 		}
 
 		if tx != nil {
-			_, err = tx.Exec(query, deviceID, addr.IP, addr.Port, addr.Type, addr.Label, networkIDValue, poolIDValue, switchPortValue)
+			_, err = tx.Exec(query, deviceID, addr.IP, addr.Port, addr.Type, addr.Label, networkIDValue, poolIDValue, switchPortValue, nullString(addr.MACAddress))
 		} else {
-			_, err = ss.db.Exec(query, deviceID, addr.IP, addr.Port, addr.Type, addr.Label, networkIDValue, poolIDValue, switchPortValue)
+			_, err = ss.db.Exec(query, deviceID, addr.IP, addr.Port, addr.Type, addr.Label, networkIDValue, poolIDValue, switchPortValue, nullString(addr.MACAddress))
 		}
 		if err != nil {
 			return fmt.Errorf("inserting address: %w", err)
@@ -827,7 +1270,7 @@ func (ss *SQLiteStorage) insertDeviceAddresses(tx *sql.Tx, deviceID string, addr
 	return nil
 }
 
-func (ss *SQLiteStorage) insertDeviceTags(tx *sql.Tx, deviceID string, tags []string) error {
+func (ss *sqlStorage) insertDeviceTags(tx sqlTx, deviceID string, tags []string) error {
 	for _, tag := range tags {
 		query := `INSERT INTO tags (device_id, tag) VALUES (?, ?)`
 		var err error
@@ -843,7 +1286,7 @@ func (ss *SQLiteStorage) insertDeviceTags(tx *sql.Tx, deviceID string, tags []st
 	return nil
 }
 
-func (ss *SQLiteStorage) insertDeviceDomains(tx *sql.Tx, deviceID string, domains []string) error {
+func (ss *sqlStorage) insertDeviceDomains(tx sqlTx, deviceID string, domains []string) error {
 	for _, domain := range domains {
 		query := `INSERT INTO domains (device_id, domain) VALUES (?, ?)`
 		var err error
@@ -859,7 +1302,7 @@ func (ss *SQLiteStorage) insertDeviceDomains(tx *sql.Tx, deviceID string, domain
 	return nil
 }
 
-func (ss *SQLiteStorage) filterByTags(devices []model.Device, tags []string) []model.Device {
+func (ss *sqlStorage) filterByTags(devices []model.Device, tags []string) []model.Device {
 	var filtered []model.Device
 
 	for _, device := range devices {
@@ -876,7 +1319,7 @@ func (ss *SQLiteStorage) filterByTags(devices []model.Device, tags []string) []m
 	return filtered
 }
 
-func (ss *SQLiteStorage) mergeDevices(devices1, devices2 []model.Device) []model.Device {
+func (ss *sqlStorage) mergeDevices(devices1, devices2 []model.Device) []model.Device {
 	seen := make(map[string]bool)
 	var result []model.Device
 
@@ -897,19 +1340,83 @@ func (ss *SQLiteStorage) mergeDevices(devices1, devices2 []model.Device) []model
 	return result
 }
 
-// ExportToFile exports all devices to a JSON file
-func (ss *SQLiteStorage) ExportToFile(filePath string) error {
-	devices, err := ss.ListDevices(nil)
+// ExportToFile writes a full inventory export to a JSON file
+func (ss *sqlStorage) ExportToFile(filePath string) error {
+	export, err := ss.Export()
 	if err != nil {
 		return err
 	}
 
-	data, err := json.MarshalIndent(devices, "", "  ")
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling export: %w", err)
+	}
+
+	return os.WriteFile(filePath, data, 0600)
+}
+
+// RestoreFromFile restores the inventory from a JSON file written by ExportToFile
+func (ss *sqlStorage) RestoreFromFile(filePath string, replace bool) (*model.RestoreResult, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading export: %w", err)
+	}
+
+	var export model.Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+
+	return ss.Restore(&export, replace)
+}
+
+// initTimestamps fills in unset creation and update times, so restored
+// entities keep their original ones, and returns the current time
+func initTimestamps(created, updated *time.Time) time.Time {
+	now := time.Now()
+	if created.IsZero() {
+		*created = now
+	}
+	if updated.IsZero() {
+		*updated = *created
+	}
+	return now
+}
+
+// initVersion starts new entities at version 1, so restored ones keep theirs
+func initVersion(version *int) {
+	if *version < 1 {
+		*version = 1
+	}
+}
+
+// versionCondition restricts an UPDATE to the expected version of a row; 0 matches any version
+const versionCondition = `(? = 0 OR version = ?)`
+
+// updateMissed returns the error for an update of table that matched no row: a
+// version conflict when the row exists, otherwise notFound
+func updateMissed(tx sqlTx, table, id string, expected int, notFound error) error {
+	if expected == 0 {
+		return notFound
+	}
+	var exists int
+	err := tx.QueryRow(`SELECT 1 FROM `+table+` WHERE id = ?`, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
 	if err != nil {
-		return fmt.Errorf("marshaling devices: %w", err)
+		return fmt.Errorf("checking %s version: %w", table, err)
 	}
+	return ErrVersionConflict
+}
 
-	return os.WriteFile(filePath, data, 0644)
+// rowVersion returns the current version of a row in table
+func rowVersion(tx sqlTx, table, id string) (int, error) {
+	var version int
+	if err := tx.QueryRow(`SELECT version FROM `+table+` WHERE id = ?`, id).Scan(&version); err != nil {
+		return 0, fmt.Errorf("reading %s version: %w", table, err)
+	}
+	return version, nil
 }
 
 // GetDatabasePath returns the database file path
@@ -920,16 +1427,31 @@ func (ss *SQLiteStorage) GetDatabasePath() string {
 // Datacenter CRUD operations
 
 // ListDatacenters returns all datacenters
-func (ss *SQLiteStorage) ListDatacenters(filter *model.DatacenterFilter) ([]model.Datacenter, error) {
+func (ss *sqlStorage) ListDatacenters(filter *model.DatacenterFilter) ([]model.Datacenter, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
+	return ss.listDatacentersLocked(filter)
+}
 
-	query := `SELECT id, name, location, description, created_at, updated_at FROM datacenters`
+// listDatacentersLocked returns datacenters matching filter; caller must hold ss.mu
+func (ss *sqlStorage) listDatacentersLocked(filter *model.DatacenterFilter) ([]model.Datacenter, error) {
+	query := `SELECT id, name, location, description, created_at, updated_at, version FROM datacenters`
 
 	var args []interface{}
-	if filter != nil && filter.Name != "" {
-		query += " WHERE LOWER(name) LIKE ?"
-		args = append(args, "%"+strings.ToLower(filter.Name)+"%")
+	if filter != nil {
+		conditions := []string{}
+		if filter.Name != "" {
+			conditions = append(conditions, "LOWER(name) LIKE ?")
+			args = append(args, "%"+strings.ToLower(filter.Name)+"%")
+		}
+		if len(filter.CustomFields) > 0 {
+			cfConditions, cfArgs := customFieldConditions(model.CustomFieldEntityDatacenter, "id", filter.CustomFields)
+			conditions = append(conditions, cfConditions...)
+			args = append(args, cfArgs...)
+		}
+		if len(conditions) > 0 {
+			query += " WHERE " + strings.Join(conditions, " AND ")
+		}
 	}
 
 	query += " ORDER BY name"
@@ -943,24 +1465,40 @@ func (ss *SQLiteStorage) ListDatacenters(filter *model.DatacenterFilter) ([]mode
 	datacenters := []model.Datacenter{}
 	for rows.Next() {
 		var dc model.Datacenter
-		err := rows.Scan(&dc.ID, &dc.Name, &dc.Location, &dc.Description, &dc.CreatedAt, &dc.UpdatedAt)
+		err := rows.Scan(&dc.ID, &dc.Name, &dc.Location, &dc.Description, &dc.CreatedAt, &dc.UpdatedAt, &dc.Version)
 		if err != nil {
 			return nil, fmt.Errorf("scanning datacenter: %w", err)
 		}
 		datacenters = append(datacenters, dc)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Load custom field values
+	ids := make([]string, len(datacenters))
+	for i := range datacenters {
+		ids[i] = datacenters[i].ID
+	}
+	values, err := ss.loadCustomFieldValues(model.CustomFieldEntityDatacenter, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range datacenters {
+		datacenters[i].CustomFields = values[datacenters[i].ID]
+	}
 
-	return datacenters, rows.Err()
+	return datacenters, nil
 }
 
 // GetDatacenter retrieves a datacenter by ID or name
-func (ss *SQLiteStorage) GetDatacenter(id string) (*model.Datacenter, error) {
+func (ss *sqlStorage) GetDatacenter(id string) (*model.Datacenter, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	// Try ID lookup first
 	query := `
-		SELECT id, name, location, description, created_at, updated_at
+		SELECT id, name, location, description, created_at, updated_at, version
 		FROM datacenters
 		WHERE id = ?
 		LIMIT 1
@@ -973,7 +1511,7 @@ func (ss *SQLiteStorage) GetDatacenter(id string) (*model.Datacenter, error) {
 
 	// Try name lookup
 	query = `
-		SELECT id, name, location, description, created_at, updated_at
+		SELECT id, name, location, description, created_at, updated_at, version
 		FROM datacenters
 		WHERE LOWER(name) = LOWER(?)
 		LIMIT 1
@@ -991,51 +1529,100 @@ func (ss *SQLiteStorage) GetDatacenter(id string) (*model.Datacenter, error) {
 }
 
 // CreateDatacenter adds a new datacenter
-func (ss *SQLiteStorage) CreateDatacenter(dc *model.Datacenter) error {
+func (ss *sqlStorage) CreateDatacenter(dc *model.Datacenter) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	now := time.Now()
-	dc.CreatedAt = now
-	dc.UpdatedAt = now
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	dc.CreatedAt, dc.UpdatedAt, dc.Version = time.Time{}, time.Time{}, 0
+	if err := ss.createDatacenterTx(tx, dc); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// createDatacenterTx inserts a datacenter within a transaction
+func (ss *sqlStorage) createDatacenterTx(tx sqlTx, dc *model.Datacenter) error {
+	initTimestamps(&dc.CreatedAt, &dc.UpdatedAt)
+	initVersion(&dc.Version)
 
-	_, err := ss.db.Exec(`
-		INSERT INTO datacenters (id, name, location, description, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, dc.ID, dc.Name, dc.Location, dc.Description, dc.CreatedAt, dc.UpdatedAt)
+	_, err := tx.Exec(`
+		INSERT INTO datacenters (id, name, location, description, created_at, updated_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, dc.ID, dc.Name, dc.Location, dc.Description, dc.CreatedAt, dc.UpdatedAt, dc.Version)
 	if err != nil {
 		return fmt.Errorf("inserting datacenter: %w", err)
 	}
 
+	// Insert custom field values
+	customFields, err := ss.saveCustomFieldValues(tx, model.CustomFieldEntityDatacenter, dc.ID, dc.CustomFields, true)
+	if err != nil {
+		return err
+	}
+	dc.CustomFields = customFields
+
 	return nil
 }
 
 // UpdateDatacenter updates an existing datacenter
-func (ss *SQLiteStorage) UpdateDatacenter(dc *model.Datacenter) error {
+func (ss *sqlStorage) UpdateDatacenter(dc *model.Datacenter) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := ss.updateDatacenterTx(tx, dc); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateDatacenterTx updates a datacenter within a transaction
+func (ss *sqlStorage) updateDatacenterTx(tx sqlTx, dc *model.Datacenter) error {
 	dc.UpdatedAt = time.Now()
 
-	result, err := ss.db.Exec(`
+	result, err := tx.Exec(`
 		UPDATE datacenters
-		SET name = ?, location = ?, description = ?, updated_at = ?
-		WHERE id = ?
-	`, dc.Name, dc.Location, dc.Description, dc.UpdatedAt, dc.ID)
+		SET name = ?, location = ?, description = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND `+versionCondition,
+		dc.Name, dc.Location, dc.Description, dc.UpdatedAt, dc.ID, dc.Version, dc.Version)
 	if err != nil {
 		return fmt.Errorf("updating datacenter: %w", err)
 	}
 
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return ErrDatacenterNotFound
+		return updateMissed(tx, "datacenters", dc.ID, dc.Version, ErrDatacenterNotFound)
+	}
+	if dc.Version, err = rowVersion(tx, "datacenters", dc.ID); err != nil {
+		return err
+	}
+
+	// Replace custom field values if provided
+	if dc.CustomFields != nil {
+		customFields, err := ss.saveCustomFieldValues(tx, model.CustomFieldEntityDatacenter, dc.ID, dc.CustomFields, false)
+		if err != nil {
+			return err
+		}
+		dc.CustomFields = customFields
 	}
 
 	return nil
 }
 
 // DeleteDatacenter removes a datacenter and sets device references to NULL
-func (ss *SQLiteStorage) DeleteDatacenter(id string) error {
+func (ss *sqlStorage) DeleteDatacenter(id string) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -1060,13 +1647,13 @@ func (ss *SQLiteStorage) DeleteDatacenter(id string) error {
 }
 
 // GetDatacenterDevices returns all devices in a datacenter
-func (ss *SQLiteStorage) GetDatacenterDevices(datacenterID string) ([]model.Device, error) {
+func (ss *sqlStorage) GetDatacenterDevices(datacenterID string) ([]model.Device, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	query := `
 		SELECT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
 		WHERE d.datacenter_id = ?
 		ORDER BY d.name
@@ -1095,7 +1682,7 @@ func (ss *SQLiteStorage) GetDatacenterDevices(datacenterID string) ([]model.Devi
 
 // Helper functions for datacenter queries
 
-func (ss *SQLiteStorage) queryDatacenter(query string, args ...interface{}) (*model.Datacenter, error) {
+func (ss *sqlStorage) queryDatacenter(query string, args ...interface{}) (*model.Datacenter, error) {
 	rows, err := ss.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying datacenter: %w", err)
@@ -1107,10 +1694,17 @@ func (ss *SQLiteStorage) queryDatacenter(query string, args ...interface{}) (*mo
 	}
 
 	var dc model.Datacenter
-	err = rows.Scan(&dc.ID, &dc.Name, &dc.Location, &dc.Description, &dc.CreatedAt, &dc.UpdatedAt)
+	err = rows.Scan(&dc.ID, &dc.Name, &dc.Location, &dc.Description, &dc.CreatedAt, &dc.UpdatedAt, &dc.Version)
 	if err != nil {
 		return nil, fmt.Errorf("scanning datacenter: %w", err)
 	}
+	rows.Close()
+
+	values, err := ss.loadCustomFieldValues(model.CustomFieldEntityDatacenter, []string{dc.ID})
+	if err != nil {
+		return nil, err
+	}
+	dc.CustomFields = values[dc.ID]
 
 	return &dc, nil
 }
@@ -1118,11 +1712,15 @@ func (ss *SQLiteStorage) queryDatacenter(query string, args ...interface{}) (*mo
 // Network CRUD operations
 
 // ListNetworks returns all networks
-func (ss *SQLiteStorage) ListNetworks(filter *model.NetworkFilter) ([]model.Network, error) {
+func (ss *sqlStorage) ListNetworks(filter *model.NetworkFilter) ([]model.Network, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
+	return ss.listNetworksLocked(filter)
+}
 
-	query := `SELECT id, name, subnet, datacenter_id, description, created_at, updated_at FROM networks`
+// listNetworksLocked returns networks matching filter; caller must hold ss.mu
+func (ss *sqlStorage) listNetworksLocked(filter *model.NetworkFilter) ([]model.Network, error) {
+	query := `SELECT id, name, subnet, datacenter_id, description, created_at, updated_at, version FROM networks`
 
 	var args []interface{}
 	if filter != nil {
@@ -1135,6 +1733,19 @@ func (ss *SQLiteStorage) ListNetworks(filter *model.NetworkFilter) ([]model.Netw
 			conditions = append(conditions, "datacenter_id = ?")
 			args = append(args, filter.DatacenterID)
 		}
+		if len(filter.CustomFields) > 0 {
+			cfConditions, cfArgs := customFieldConditions(model.CustomFieldEntityNetwork, "id", filter.CustomFields)
+			conditions = append(conditions, cfConditions...)
+			args = append(args, cfArgs...)
+		}
+		if filter.LabelSelector != "" {
+			labelConditions, labelArgs, err := networkLabelTable.conditions("id", filter.LabelSelector)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, labelConditions...)
+			args = append(args, labelArgs...)
+		}
 		if len(conditions) > 0 {
 			query += " WHERE " + strings.Join(conditions, " AND ")
 		}
@@ -1151,24 +1762,45 @@ func (ss *SQLiteStorage) ListNetworks(filter *model.NetworkFilter) ([]model.Netw
 	networks := []model.Network{}
 	for rows.Next() {
 		var n model.Network
-		err := rows.Scan(&n.ID, &n.Name, &n.Subnet, &n.DatacenterID, &n.Description, &n.CreatedAt, &n.UpdatedAt)
+		err := rows.Scan(&n.ID, &n.Name, &n.Subnet, &n.DatacenterID, &n.Description, &n.CreatedAt, &n.UpdatedAt, &n.Version)
 		if err != nil {
 			return nil, fmt.Errorf("scanning network: %w", err)
 		}
 		networks = append(networks, n)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Load labels and custom field values
+	ids := make([]string, len(networks))
+	for i := range networks {
+		ids[i] = networks[i].ID
+	}
+	networkLabels, err := networkLabelTable.load(ss.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	values, err := ss.loadCustomFieldValues(model.CustomFieldEntityNetwork, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range networks {
+		networks[i].Labels = networkLabels[networks[i].ID]
+		networks[i].CustomFields = values[networks[i].ID]
+	}
 
-	return networks, rows.Err()
+	return networks, nil
 }
 
 // GetNetwork retrieves a network by ID or name
-func (ss *SQLiteStorage) GetNetwork(id string) (*model.Network, error) {
+func (ss *sqlStorage) GetNetwork(id string) (*model.Network, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	// Try ID lookup first
 	query := `
-		SELECT id, name, subnet, datacenter_id, description, created_at, updated_at
+		SELECT id, name, subnet, datacenter_id, description, created_at, updated_at, version
 		FROM networks
 		WHERE id = ?
 		LIMIT 1
@@ -1181,7 +1813,7 @@ func (ss *SQLiteStorage) GetNetwork(id string) (*model.Network, error) {
 
 	// Try name lookup
 	query = `
-		SELECT id, name, subnet, datacenter_id, description, created_at, updated_at
+		SELECT id, name, subnet, datacenter_id, description, created_at, updated_at, version
 		FROM networks
 		WHERE LOWER(name) = LOWER(?)
 		LIMIT 1
@@ -1199,57 +1831,119 @@ func (ss *SQLiteStorage) GetNetwork(id string) (*model.Network, error) {
 }
 
 // CreateNetwork adds a new network
-func (ss *SQLiteStorage) CreateNetwork(network *model.Network) error {
+func (ss *sqlStorage) CreateNetwork(network *model.Network) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	now := time.Now()
-	network.CreatedAt = now
-	network.UpdatedAt = now
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	_, err := ss.db.Exec(`
-		INSERT INTO networks (id, name, subnet, datacenter_id, description, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+	network.CreatedAt, network.UpdatedAt, network.Version = time.Time{}, time.Time{}, 0
+	if err := ss.createNetworkTx(tx, network); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// createNetworkTx inserts a network within a transaction
+func (ss *sqlStorage) createNetworkTx(tx sqlTx, network *model.Network) error {
+	initTimestamps(&network.CreatedAt, &network.UpdatedAt)
+	initVersion(&network.Version)
+
+	_, err := tx.Exec(`
+		INSERT INTO networks (id, name, subnet, datacenter_id, description, created_at, updated_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`, network.ID, network.Name, network.Subnet, network.DatacenterID, network.Description,
-		network.CreatedAt, network.UpdatedAt)
+		network.CreatedAt, network.UpdatedAt, network.Version)
 	if err != nil {
 		return fmt.Errorf("inserting network: %w", err)
 	}
 
+	// Insert labels
+	if err := networkLabelTable.save(tx, network.ID, network.Labels); err != nil {
+		return err
+	}
+
+	// Insert custom field values
+	customFields, err := ss.saveCustomFieldValues(tx, model.CustomFieldEntityNetwork, network.ID, network.CustomFields, true)
+	if err != nil {
+		return err
+	}
+	network.CustomFields = customFields
+
 	return nil
 }
 
 // UpdateNetwork updates an existing network
-func (ss *SQLiteStorage) UpdateNetwork(network *model.Network) error {
+func (ss *sqlStorage) UpdateNetwork(network *model.Network) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := ss.updateNetworkTx(tx, network); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateNetworkTx updates a network within a transaction
+func (ss *sqlStorage) updateNetworkTx(tx sqlTx, network *model.Network) error {
 	network.UpdatedAt = time.Now()
 
-	result, err := ss.db.Exec(`
+	result, err := tx.Exec(`
 		UPDATE networks
-		SET name = ?, subnet = ?, datacenter_id = ?, description = ?, updated_at = ?
-		WHERE id = ?
-	`, network.Name, network.Subnet, network.DatacenterID, network.Description, network.UpdatedAt, network.ID)
+		SET name = ?, subnet = ?, datacenter_id = ?, description = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND `+versionCondition,
+		network.Name, network.Subnet, network.DatacenterID, network.Description, network.UpdatedAt, network.ID,
+		network.Version, network.Version)
 	if err != nil {
 		return fmt.Errorf("updating network: %w", err)
 	}
 
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return ErrNetworkNotFound
+		return updateMissed(tx, "networks", network.ID, network.Version, ErrNetworkNotFound)
+	}
+	if network.Version, err = rowVersion(tx, "networks", network.ID); err != nil {
+		return err
+	}
+
+	// Replace labels if provided
+	if network.Labels != nil {
+		if err := networkLabelTable.save(tx, network.ID, network.Labels); err != nil {
+			return err
+		}
+	}
+
+	// Replace custom field values if provided
+	if network.CustomFields != nil {
+		customFields, err := ss.saveCustomFieldValues(tx, model.CustomFieldEntityNetwork, network.ID, network.CustomFields, false)
+		if err != nil {
+			return err
+		}
+		network.CustomFields = customFields
 	}
 
 	return nil
 }
 
 // DeleteNetwork removes a network and sets device references to NULL
-func (ss *SQLiteStorage) DeleteNetwork(id string) error {
+func (ss *sqlStorage) DeleteNetwork(id string) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	// First, update all devices in this network to set network_id to NULL
-	_, err := ss.db.Exec(`UPDATE devices SET network_id = NULL WHERE network_id = ?`, id)
+	// First, detach all addresses in this network
+	_, err := ss.db.Exec(`UPDATE addresses SET network_id = NULL WHERE network_id = ?`, id)
 	if err != nil {
 		return fmt.Errorf("clearing device network references: %w", err)
 	}
@@ -1269,15 +1963,16 @@ func (ss *SQLiteStorage) DeleteNetwork(id string) error {
 }
 
 // GetNetworkDevices returns all devices in a network
-func (ss *SQLiteStorage) GetNetworkDevices(networkID string) ([]model.Device, error) {
+func (ss *sqlStorage) GetNetworkDevices(networkID string) ([]model.Device, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	query := `
-		SELECT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
-		       d.created_at, d.updated_at
+		SELECT DISTINCT d.id, d.name, d.description, d.make_model, d.os, d.datacenter_id, d.username, d.location,
+		       d.status, d.status_changed_at, d.created_at, d.updated_at, d.version
 		FROM devices d
-		WHERE d.network_id = ?
+		INNER JOIN addresses a ON a.device_id = d.id
+		WHERE a.network_id = ?
 		ORDER BY d.name
 	`
 
@@ -1304,7 +1999,7 @@ func (ss *SQLiteStorage) GetNetworkDevices(networkID string) ([]model.Device, er
 
 // Helper functions for network queries
 
-func (ss *SQLiteStorage) queryNetwork(query string, args ...interface{}) (*model.Network, error) {
+func (ss *sqlStorage) queryNetwork(query string, args ...interface{}) (*model.Network, error) {
 	rows, err := ss.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying network: %w", err)
@@ -1316,10 +2011,23 @@ func (ss *SQLiteStorage) queryNetwork(query string, args ...interface{}) (*model
 	}
 
 	var n model.Network
-	err = rows.Scan(&n.ID, &n.Name, &n.Subnet, &n.DatacenterID, &n.Description, &n.CreatedAt, &n.UpdatedAt)
+	err = rows.Scan(&n.ID, &n.Name, &n.Subnet, &n.DatacenterID, &n.Description, &n.CreatedAt, &n.UpdatedAt, &n.Version)
 	if err != nil {
 		return nil, fmt.Errorf("scanning network: %w", err)
 	}
+	rows.Close()
+
+	networkLabels, err := networkLabelTable.load(ss.db, []string{n.ID})
+	if err != nil {
+		return nil, err
+	}
+	n.Labels = networkLabels[n.ID]
+
+	values, err := ss.loadCustomFieldValues(model.CustomFieldEntityNetwork, []string{n.ID})
+	if err != nil {
+		return nil, err
+	}
+	n.CustomFields = values[n.ID]
 
 	return &n, nil
 }
@@ -1327,12 +2035,16 @@ func (ss *SQLiteStorage) queryNetwork(query string, args ...interface{}) (*model
 // Network Pool Methods
 
 // ListNetworkPools returns pools matching filter
-func (ss *SQLiteStorage) ListNetworkPools(filter *model.NetworkPoolFilter) ([]model.NetworkPool, error) {
+func (ss *sqlStorage) ListNetworkPools(filter *model.NetworkPoolFilter) ([]model.NetworkPool, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
+	return ss.listNetworkPoolsLocked(filter)
+}
 
+// listNetworkPoolsLocked returns pools matching filter; caller must hold ss.mu
+func (ss *sqlStorage) listNetworkPoolsLocked(filter *model.NetworkPoolFilter) ([]model.NetworkPool, error) {
 	query := `
-		SELECT id, network_id, name, start_ip, end_ip, description, created_at, updated_at
+		SELECT id, network_id, name, start_ip, end_ip, description, created_at, updated_at, version
 		FROM network_pools
 		WHERE 1=1
 	`
@@ -1343,6 +2055,16 @@ func (ss *SQLiteStorage) ListNetworkPools(filter *model.NetworkPoolFilter) ([]mo
 			query += " AND network_id = ?"
 			args = append(args, filter.NetworkID)
 		}
+		if filter.LabelSelector != "" {
+			labelConditions, labelArgs, err := poolLabelTable.conditions("id", filter.LabelSelector)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range labelConditions {
+				query += " AND " + c
+			}
+			args = append(args, labelArgs...)
+		}
 	}
 
 	query += " ORDER BY name"
@@ -1356,38 +2078,47 @@ func (ss *SQLiteStorage) ListNetworkPools(filter *model.NetworkPoolFilter) ([]mo
 	var pools []model.NetworkPool
 	for rows.Next() {
 		var p model.NetworkPool
-		if err := rows.Scan(&p.ID, &p.NetworkID, &p.Name, &p.StartIP, &p.EndIP, &p.Description, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.NetworkID, &p.Name, &p.StartIP, &p.EndIP, &p.Description, &p.CreatedAt, &p.UpdatedAt, &p.Version); err != nil {
 			return nil, fmt.Errorf("scanning network pool: %w", err)
 		}
 		pools = append(pools, p)
 	}
 
-	// Load tags for pools
+	// Load tags and labels for pools
+	ids := make([]string, len(pools))
 	for i := range pools {
 		if err := ss.loadPoolTags(&pools[i]); err != nil {
 			return nil, err
 		}
+		ids[i] = pools[i].ID
+	}
+	poolLabels, err := poolLabelTable.load(ss.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pools {
+		pools[i].Labels = poolLabels[pools[i].ID]
 	}
 
 	return pools, nil
 }
 
 // GetNetworkPool returns a single pool
-func (ss *SQLiteStorage) GetNetworkPool(id string) (*model.NetworkPool, error) {
+func (ss *sqlStorage) GetNetworkPool(id string) (*model.NetworkPool, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	query := `
-		SELECT id, network_id, name, start_ip, end_ip, description, created_at, updated_at
+		SELECT id, network_id, name, start_ip, end_ip, description, created_at, updated_at, version
 		FROM network_pools
 		WHERE id = ?
 	`
 	row := ss.db.QueryRow(query, id)
 
 	var p model.NetworkPool
-	if err := row.Scan(&p.ID, &p.NetworkID, &p.Name, &p.StartIP, &p.EndIP, &p.Description, &p.CreatedAt, &p.UpdatedAt); err != nil {
+	if err := row.Scan(&p.ID, &p.NetworkID, &p.Name, &p.StartIP, &p.EndIP, &p.Description, &p.CreatedAt, &p.UpdatedAt, &p.Version); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("network pool not found")
+			return nil, ErrPoolNotFound
 		}
 		return nil, fmt.Errorf("scanning network pool: %w", err)
 	}
@@ -1396,28 +2127,43 @@ func (ss *SQLiteStorage) GetNetworkPool(id string) (*model.NetworkPool, error) {
 		return nil, err
 	}
 
+	poolLabels, err := poolLabelTable.load(ss.db, []string{p.ID})
+	if err != nil {
+		return nil, err
+	}
+	p.Labels = poolLabels[p.ID]
+
 	return &p, nil
 }
 
 // CreateNetworkPool creates a pool
-func (ss *SQLiteStorage) CreateNetworkPool(pool *model.NetworkPool) error {
+func (ss *sqlStorage) CreateNetworkPool(pool *model.NetworkPool) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	now := time.Now()
-	pool.CreatedAt = now
-	pool.UpdatedAt = now
-
 	tx, err := ss.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`
-		INSERT INTO network_pools (id, network_id, name, start_ip, end_ip, description, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`, pool.ID, pool.NetworkID, pool.Name, pool.StartIP, pool.EndIP, pool.Description, pool.CreatedAt, pool.UpdatedAt)
+	pool.CreatedAt, pool.UpdatedAt, pool.Version = time.Time{}, time.Time{}, 0
+	if err := createNetworkPoolTx(tx, pool); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// createNetworkPoolTx inserts a pool within a transaction
+func createNetworkPoolTx(tx sqlTx, pool *model.NetworkPool) error {
+	initTimestamps(&pool.CreatedAt, &pool.UpdatedAt)
+	initVersion(&pool.Version)
+
+	_, err := tx.Exec(`
+		INSERT INTO network_pools (id, network_id, name, start_ip, end_ip, description, created_at, updated_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, pool.ID, pool.NetworkID, pool.Name, pool.StartIP, pool.EndIP, pool.Description, pool.CreatedAt, pool.UpdatedAt, pool.Version)
 	if err != nil {
 		return fmt.Errorf("inserting network pool: %w", err)
 	}
@@ -1430,33 +2176,50 @@ func (ss *SQLiteStorage) CreateNetworkPool(pool *model.NetworkPool) error {
 		}
 	}
 
-	return tx.Commit()
+	// Insert labels
+	if err := poolLabelTable.save(tx, pool.ID, pool.Labels); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // UpdateNetworkPool updates a pool
-func (ss *SQLiteStorage) UpdateNetworkPool(pool *model.NetworkPool) error {
+func (ss *sqlStorage) UpdateNetworkPool(pool *model.NetworkPool) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	pool.UpdatedAt = time.Now()
-
 	tx, err := ss.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := updateNetworkPoolTx(tx, pool); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateNetworkPoolTx updates a pool within a transaction
+func updateNetworkPoolTx(tx sqlTx, pool *model.NetworkPool) error {
+	pool.UpdatedAt = time.Now()
+
 	result, err := tx.Exec(`
 		UPDATE network_pools
-		SET name = ?, start_ip = ?, end_ip = ?, description = ?, updated_at = ?
-		WHERE id = ?
-	`, pool.Name, pool.StartIP, pool.EndIP, pool.Description, pool.UpdatedAt, pool.ID)
+		SET name = ?, start_ip = ?, end_ip = ?, description = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND `+versionCondition,
+		pool.Name, pool.StartIP, pool.EndIP, pool.Description, pool.UpdatedAt, pool.ID, pool.Version, pool.Version)
 	if err != nil {
 		return fmt.Errorf("updating network pool: %w", err)
 	}
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return fmt.Errorf("network pool not found")
+		return updateMissed(tx, "network_pools", pool.ID, pool.Version, ErrPoolNotFound)
+	}
+	if pool.Version, err = rowVersion(tx, "network_pools", pool.ID); err != nil {
+		return err
 	}
 
 	// Update tags
@@ -1471,11 +2234,18 @@ func (ss *SQLiteStorage) UpdateNetworkPool(pool *model.NetworkPool) error {
 		}
 	}
 
-	return tx.Commit()
+	// Replace labels if provided
+	if pool.Labels != nil {
+		if err := poolLabelTable.save(tx, pool.ID, pool.Labels); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // DeleteNetworkPool deletes a pool
-func (ss *SQLiteStorage) DeleteNetworkPool(id string) error {
+func (ss *sqlStorage) DeleteNetworkPool(id string) error {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -1497,20 +2267,23 @@ func (ss *SQLiteStorage) DeleteNetworkPool(id string) error {
 	}
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return fmt.Errorf("network pool not found")
+		return ErrPoolNotFound
 	}
 
 	return tx.Commit()
 }
 
 // GetNextAvailableIP calculates next available IP in a pool
-func (ss *SQLiteStorage) GetNextAvailableIP(poolID string) (string, error) {
+func (ss *sqlStorage) GetNextAvailableIP(poolID string) (string, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	// Get pool details including network_id
 	var startIPStr, endIPStr, networkID string
 	err := ss.db.QueryRow("SELECT start_ip, end_ip, network_id FROM network_pools WHERE id = ?", poolID).Scan(&startIPStr, &endIPStr, &networkID)
+	if err == sql.ErrNoRows {
+		return "", ErrPoolNotFound
+	}
 	if err != nil {
 		return "", fmt.Errorf("getting pool: %w", err)
 	}
@@ -1550,16 +2323,19 @@ func (ss *SQLiteStorage) GetNextAvailableIP(poolID string) (string, error) {
 		incIP(curr)
 	}
 
-	return "", fmt.Errorf("no available IPs in pool")
+	return "", ErrPoolExhausted
 }
 
 // ValidateIPInPool checks if an IP is valid for the given pool
-func (ss *SQLiteStorage) ValidateIPInPool(poolID, ipStr string) (bool, error) {
+func (ss *sqlStorage) ValidateIPInPool(poolID, ipStr string) (bool, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	var startIPStr, endIPStr string
 	err := ss.db.QueryRow("SELECT start_ip, end_ip FROM network_pools WHERE id = ?", poolID).Scan(&startIPStr, &endIPStr)
+	if err == sql.ErrNoRows {
+		return false, ErrPoolNotFound
+	}
 	if err != nil {
 		return false, fmt.Errorf("getting pool: %w", err)
 	}
@@ -1581,7 +2357,7 @@ func (ss *SQLiteStorage) ValidateIPInPool(poolID, ipStr string) (bool, error) {
 	return false, nil
 }
 
-func (ss *SQLiteStorage) loadPoolTags(pool *model.NetworkPool) error {
+func (ss *sqlStorage) loadPoolTags(pool *model.NetworkPool) error {
 	rows, err := ss.db.Query("SELECT tag FROM pool_tags WHERE pool_id = ?", pool.ID)
 	if err != nil {
 		return err
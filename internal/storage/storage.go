@@ -2,8 +2,10 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/pkg/registry"
 )
 
 var (
@@ -12,21 +14,90 @@ var (
 	ErrDatacenterNotFound = errors.New("datacenter not found")
 	ErrNetworkNotFound    = errors.New("network not found")
 	ErrPoolNotFound       = errors.New("network pool not found")
+	ErrPoolExhausted      = errors.New("no available IPs in pool")
+	ErrVersionConflict    = errors.New("version conflict")
 )
 
-// NewStorage creates a SQLite storage backend
-func NewStorage(dataDir, storageType, format string) (Storage, error) {
-	return NewSQLiteStorage(dataDir)
+// Storage backend types accepted by NewStorage
+const (
+	TypeSQLite   = "sqlite"
+	TypePostgres = "postgres"
+)
+
+// NewStorage creates the storage backend named by storageType. SQLite keeps its
+// database in dataDir; PostgreSQL connects to dsn. An empty type selects SQLite,
+// other types are looked up in the storage providers of the registry.
+func NewStorage(dataDir, storageType, dsn string) (Storage, error) {
+	s, err := openStorage(dataDir, storageType, dsn)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := s.(Storage)
+	if !ok {
+		return nil, fmt.Errorf("storage type %q does not implement Storage", storageType)
+	}
+	return store, nil
 }
 
 // NewExtendedStorage creates an extended storage with relationship support
-func NewExtendedStorage(dataDir, storageType, format string) (ExtendedStorage, error) {
-	return NewSQLiteStorage(dataDir)
+func NewExtendedStorage(dataDir, storageType, dsn string) (ExtendedStorage, error) {
+	s, err := openStorage(dataDir, storageType, dsn)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := s.(ExtendedStorage)
+	if !ok {
+		return nil, fmt.Errorf("storage type %q does not implement ExtendedStorage", storageType)
+	}
+	return store, nil
 }
 
 // NewDiscoveryStorage creates a storage backend with discovery support
-func NewDiscoveryStorage(dataDir, storageType, format string) (DiscoveryStorage, error) {
-	return NewSQLiteStorage(dataDir)
+func NewDiscoveryStorage(dataDir, storageType, dsn string) (DiscoveryStorage, error) {
+	s, err := openStorage(dataDir, storageType, dsn)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := s.(DiscoveryStorage)
+	if !ok {
+		return nil, fmt.Errorf("storage type %q does not implement DiscoveryStorage", storageType)
+	}
+	return store, nil
+}
+
+// openStorage creates a built-in backend or one from a registered storage provider,
+// which is passed the data directory and DSN as "data_dir" and "dsn"
+func openStorage(dataDir, storageType, dsn string) (interface{}, error) {
+	switch storageType {
+	case "", TypeSQLite:
+		s, err := NewSQLiteStorage(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case TypePostgres:
+		if dsn == "" {
+			return nil, errors.New("postgres storage requires a DSN")
+		}
+		s, err := NewPostgresStorage(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	factory, ok := registry.GetRegistry().GetStorageProvider(storageType)
+	if !ok {
+		return nil, fmt.Errorf("unknown storage type %q", storageType)
+	}
+	s, err := factory(map[string]interface{}{"data_dir": dataDir, "dsn": dsn})
+	if err != nil {
+		return nil, fmt.Errorf("creating %s storage: %w", storageType, err)
+	}
+	if s == nil {
+		return nil, fmt.Errorf("storage provider %q returned no storage", storageType)
+	}
+	return s, nil
 }
 
 // DatacenterStorage defines the interface for datacenter storage
@@ -66,6 +137,7 @@ type RelationshipStorage interface {
 	RemoveRelationship(parentID, childID, relationshipType string) error
 	GetRelationships(deviceID string) ([]model.DeviceRelationship, error)
 	GetRelatedDevices(deviceID, relationshipType string) ([]model.Device, error)
+	ListRelationships(relationshipType string) ([]model.DeviceRelationship, error)
 }
 
 // Storage defines the interface for device storage
@@ -0,0 +1,1159 @@
+// Package storagetest checks that a storage backend honors the contracts of the
+// storage interfaces. Every implementation runs the same suite from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage {
+//			s, err := NewMyStorage(t.TempDir())
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return s
+//		})
+//	}
+//
+// Tests for optional interfaces (datacenters, networks, relationships, pools,
+// discovery, jobs, leases and transactions) are skipped when the store does not implement them.
+package storagetest
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/martinsuchenak/rackd/pkg/registry"
+)
+
+// Factory returns a new, empty store. Stores implementing io.Closer are closed
+// when the test ends.
+type Factory func(t *testing.T) storage.Storage
+
+// Run runs the conformance suite, opening a fresh store for every test
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s storage.Storage)
+	}{
+		{"DeviceCRUD", testDeviceCRUD},
+		{"DeviceNotFound", testDeviceNotFound},
+		{"SearchDevices", testSearchDevices},
+		{"DatacenterCRUD", testDatacenterCRUD},
+		{"NetworkCRUD", testNetworkCRUD},
+		{"Relationships", testRelationships},
+		{"RelationshipErrors", testRelationshipErrors},
+		{"NetworkPools", testNetworkPools},
+		{"NetworkPoolErrors", testNetworkPoolErrors},
+		{"Discovery", testDiscovery},
+		{"DiscoveryErrors", testDiscoveryErrors},
+		{"PromotionPolicies", testPromotionPolicies},
+		{"Jobs", testJobs},
+		{"Leases", testLeases},
+		{"Versions", testVersions},
+		{"Transactions", testTransactions},
+		{"NestedTransactions", testNestedTransactions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			if c, ok := s.(io.Closer); ok {
+				t.Cleanup(func() { c.Close() })
+			}
+			tt.run(t, s)
+		})
+	}
+}
+
+// Provider returns a factory for the storage provider registered under name, giving
+// every store a data directory of its own
+func Provider(name string) Factory {
+	return func(t *testing.T) storage.Storage {
+		factory, ok := registry.GetRegistry().GetStorageProvider(name)
+		if !ok {
+			t.Fatalf("storage provider %q is not registered", name)
+		}
+		s, err := factory(map[string]interface{}{"data_dir": t.TempDir()})
+		if err != nil {
+			t.Fatalf("creating %s storage: %v", name, err)
+		}
+		store, ok := s.(storage.Storage)
+		if !ok {
+			t.Fatalf("storage provider %q returned %T, which does not implement storage.Storage", name, s)
+		}
+		return store
+	}
+}
+
+// need returns s as T, skipping the test when the store does not implement it
+func need[T any](t *testing.T, s storage.Storage) T {
+	t.Helper()
+	v, ok := s.(T)
+	if !ok {
+		var zero T
+		t.Skipf("%T does not implement %T", s, &zero)
+	}
+	return v
+}
+
+func newID() string {
+	return uuid.NewString()
+}
+
+func testDeviceCRUD(t *testing.T, s storage.Storage) {
+	device := &model.Device{
+		ID:        newID(),
+		Name:      "db-1",
+		MakeModel: "Dell R640",
+		Tags:      []string{"db", "prod"},
+		Domains:   []string{"db-1.example.com"},
+		Addresses: []model.Address{{IP: "192.168.1.10", Port: 22, Type: "ipv4", Label: "mgmt", MACAddress: "aa:bb:cc:dd:ee:ff"}},
+		Labels:    map[string]string{"env": "prod"},
+	}
+	if err := s.CreateDevice(device); err != nil {
+		t.Fatalf("CreateDevice failed: %v", err)
+	}
+
+	got, err := s.GetDevice(device.ID)
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if got.Name != "db-1" || got.MakeModel != "Dell R640" || len(got.Tags) != 2 || len(got.Domains) != 1 || len(got.Addresses) != 1 {
+		t.Errorf("unexpected device %+v", got)
+	}
+	if got.Addresses[0].Port != 22 || got.Addresses[0].Label != "mgmt" || got.Addresses[0].MACAddress != "aa:bb:cc:dd:ee:ff" {
+		t.Errorf("unexpected address %+v", got.Addresses[0])
+	}
+	if got.Labels["env"] != "prod" {
+		t.Errorf("expected env label, got %v", got.Labels)
+	}
+	if got.Status != model.DeviceStatusActive {
+		t.Errorf("expected active status, got %q", got.Status)
+	}
+	if time.Since(got.CreatedAt) > time.Minute {
+		t.Errorf("unexpected creation time %v", got.CreatedAt)
+	}
+
+	got.Tags = []string{"db"}
+	got.Labels = map[string]string{"env": "staging"}
+	got.Addresses = nil
+	if err := s.UpdateDevice(got); err != nil {
+		t.Fatalf("UpdateDevice failed: %v", err)
+	}
+
+	// Updates replace the tags, labels and addresses of a device
+	updated, err := s.GetDevice(device.ID)
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if len(updated.Tags) != 1 || len(updated.Addresses) != 0 || updated.Labels["env"] != "staging" {
+		t.Errorf("expected update to replace collections, got %+v", updated)
+	}
+
+	byLabel, err := s.ListDevices(&model.DeviceFilter{LabelSelector: "env=staging"})
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(byLabel) != 1 || byLabel[0].ID != device.ID {
+		t.Errorf("expected updated device by label, got %+v", byLabel)
+	}
+
+	byTag, err := s.ListDevices(&model.DeviceFilter{Tags: []string{"prod"}})
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(byTag) != 0 {
+		t.Errorf("expected removed tag to no longer match, got %d devices", len(byTag))
+	}
+
+	all, err := s.ListDevices(nil)
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(all) != 1 {
+		t.Errorf("expected 1 device, got %d", len(all))
+	}
+
+	if err := s.DeleteDevice(device.ID); err != nil {
+		t.Fatalf("DeleteDevice failed: %v", err)
+	}
+	if _, err := s.GetDevice(device.ID); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound after delete, got %v", err)
+	}
+}
+
+func testDeviceNotFound(t *testing.T, s storage.Storage) {
+	if _, err := s.GetDevice("missing"); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("GetDevice: expected ErrDeviceNotFound, got %v", err)
+	}
+	if err := s.UpdateDevice(&model.Device{ID: "missing", Name: "x"}); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("UpdateDevice: expected ErrDeviceNotFound, got %v", err)
+	}
+	if err := s.DeleteDevice("missing"); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("DeleteDevice: expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func testSearchDevices(t *testing.T, s storage.Storage) {
+	for _, d := range []*model.Device{
+		{ID: newID(), Name: "web-1", Description: "Frontend", Tags: []string{"nginx"}},
+		{ID: newID(), Name: "web-2", Domains: []string{"shop.example.com"}},
+		{ID: newID(), Name: "db-1", Addresses: []model.Address{{IP: "10.9.8.7", Type: "ipv4"}}},
+	} {
+		if err := s.CreateDevice(d); err != nil {
+			t.Fatalf("CreateDevice failed: %v", err)
+		}
+	}
+
+	for query, want := range map[string]int{
+		"WEB":      2,
+		"frontend": 1,
+		"nginx":    1,
+		"shop":     1,
+		"10.9.8":   1,
+		"nothing":  0,
+	} {
+		devices, err := s.SearchDevices(query)
+		if err != nil {
+			t.Fatalf("SearchDevices(%q) failed: %v", query, err)
+		}
+		if len(devices) != want {
+			t.Errorf("SearchDevices(%q) returned %d devices, want %d", query, len(devices), want)
+		}
+	}
+}
+
+func testDatacenterCRUD(t *testing.T, s storage.Storage) {
+	dcs := need[storage.DatacenterStorage](t, s)
+
+	dc := &model.Datacenter{ID: newID(), Name: "DC1", Location: "Berlin"}
+	if err := dcs.CreateDatacenter(dc); err != nil {
+		t.Fatalf("CreateDatacenter failed: %v", err)
+	}
+
+	got, err := dcs.GetDatacenter(dc.ID)
+	if err != nil {
+		t.Fatalf("GetDatacenter failed: %v", err)
+	}
+	if got.Name != "DC1" || got.Location != "Berlin" {
+		t.Errorf("unexpected datacenter %+v", got)
+	}
+
+	got.Location = "Paris"
+	if err := dcs.UpdateDatacenter(got); err != nil {
+		t.Fatalf("UpdateDatacenter failed: %v", err)
+	}
+	if got, _ = dcs.GetDatacenter(dc.ID); got == nil || got.Location != "Paris" {
+		t.Errorf("expected updated location, got %+v", got)
+	}
+
+	// The default datacenter exists in a new store
+	all, err := dcs.ListDatacenters(nil)
+	if err != nil {
+		t.Fatalf("ListDatacenters failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("expected 2 datacenters, got %d", len(all))
+	}
+
+	device := &model.Device{ID: newID(), Name: "server-1", DatacenterID: dc.ID}
+	if err := s.CreateDevice(device); err != nil {
+		t.Fatalf("CreateDevice failed: %v", err)
+	}
+	devices, err := dcs.GetDatacenterDevices(dc.ID)
+	if err != nil {
+		t.Fatalf("GetDatacenterDevices failed: %v", err)
+	}
+	if len(devices) != 1 || devices[0].ID != device.ID {
+		t.Errorf("expected server-1 in datacenter, got %+v", devices)
+	}
+
+	if err := dcs.DeleteDatacenter(dc.ID); err != nil {
+		t.Fatalf("DeleteDatacenter failed: %v", err)
+	}
+	if _, err := dcs.GetDatacenter(dc.ID); !errors.Is(err, storage.ErrDatacenterNotFound) {
+		t.Errorf("GetDatacenter: expected ErrDatacenterNotFound, got %v", err)
+	}
+	if err := dcs.DeleteDatacenter(dc.ID); !errors.Is(err, storage.ErrDatacenterNotFound) {
+		t.Errorf("DeleteDatacenter: expected ErrDatacenterNotFound deleting twice, got %v", err)
+	}
+	if err := dcs.UpdateDatacenter(&model.Datacenter{ID: "missing", Name: "x"}); !errors.Is(err, storage.ErrDatacenterNotFound) {
+		t.Errorf("UpdateDatacenter: expected ErrDatacenterNotFound, got %v", err)
+	}
+
+	// Devices outlive their datacenter
+	got2, err := s.GetDevice(device.ID)
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if got2.DatacenterID != "" {
+		t.Errorf("expected datacenter reference to be cleared, got %q", got2.DatacenterID)
+	}
+}
+
+func testNetworkCRUD(t *testing.T, s storage.Storage) {
+	ns := need[storage.NetworkStorage](t, s)
+
+	network := &model.Network{ID: newID(), Name: "mgmt", Subnet: "10.0.0.0/24", DatacenterID: "default"}
+	if err := ns.CreateNetwork(network); err != nil {
+		t.Fatalf("CreateNetwork failed: %v", err)
+	}
+
+	network.Description = "Management"
+	if err := ns.UpdateNetwork(network); err != nil {
+		t.Fatalf("UpdateNetwork failed: %v", err)
+	}
+	got, err := ns.GetNetwork(network.ID)
+	if err != nil {
+		t.Fatalf("GetNetwork failed: %v", err)
+	}
+	if got.Description != "Management" || got.Subnet != "10.0.0.0/24" {
+		t.Errorf("unexpected network %+v", got)
+	}
+
+	device := &model.Device{ID: newID(), Name: "switch-1", Addresses: []model.Address{{IP: "10.0.0.5", Type: "ipv4", NetworkID: network.ID}}}
+	if err := s.CreateDevice(device); err != nil {
+		t.Fatalf("CreateDevice failed: %v", err)
+	}
+	devices, err := ns.GetNetworkDevices(network.ID)
+	if err != nil {
+		t.Fatalf("GetNetworkDevices failed: %v", err)
+	}
+	if len(devices) != 1 || devices[0].ID != device.ID {
+		t.Errorf("expected switch-1 in network, got %+v", devices)
+	}
+
+	filtered, err := ns.ListNetworks(&model.NetworkFilter{DatacenterID: "default"})
+	if err != nil {
+		t.Fatalf("ListNetworks failed: %v", err)
+	}
+	if len(filtered) != 1 {
+		t.Errorf("expected 1 network, got %d", len(filtered))
+	}
+
+	if err := ns.DeleteNetwork(network.ID); err != nil {
+		t.Fatalf("DeleteNetwork failed: %v", err)
+	}
+	if _, err := ns.GetNetwork(network.ID); !errors.Is(err, storage.ErrNetworkNotFound) {
+		t.Errorf("GetNetwork: expected ErrNetworkNotFound, got %v", err)
+	}
+	if err := ns.DeleteNetwork(network.ID); !errors.Is(err, storage.ErrNetworkNotFound) {
+		t.Errorf("DeleteNetwork: expected ErrNetworkNotFound deleting twice, got %v", err)
+	}
+	if err := ns.UpdateNetwork(&model.Network{ID: "missing", Name: "x", Subnet: "10.0.0.0/24"}); !errors.Is(err, storage.ErrNetworkNotFound) {
+		t.Errorf("UpdateNetwork: expected ErrNetworkNotFound, got %v", err)
+	}
+
+	// Addresses stay on the device without the network
+	got2, err := s.GetDevice(device.ID)
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if len(got2.Addresses) != 1 || got2.Addresses[0].NetworkID != "" {
+		t.Errorf("expected address to remain without network, got %+v", got2.Addresses)
+	}
+}
+
+func testRelationships(t *testing.T, s storage.Storage) {
+	rs := need[storage.RelationshipStorage](t, s)
+
+	parent := &model.Device{ID: newID(), Name: "rack-1"}
+	child := &model.Device{ID: newID(), Name: "server-1"}
+	for _, d := range []*model.Device{parent, child} {
+		if err := s.CreateDevice(d); err != nil {
+			t.Fatalf("CreateDevice failed: %v", err)
+		}
+	}
+
+	if err := rs.AddRelationship(parent.ID, child.ID, "contains"); err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+	// Adding the same relationship again is a no-op
+	if err := rs.AddRelationship(parent.ID, child.ID, "contains"); err != nil {
+		t.Fatalf("AddRelationship twice failed: %v", err)
+	}
+
+	// Relationships are listed from either end
+	for _, id := range []string{parent.ID, child.ID} {
+		rels, err := rs.GetRelationships(id)
+		if err != nil {
+			t.Fatalf("GetRelationships failed: %v", err)
+		}
+		if len(rels) != 1 || rels[0].ParentID != parent.ID || rels[0].ChildID != child.ID || rels[0].Type != "contains" {
+			t.Errorf("unexpected relationships of %s: %+v", id, rels)
+		}
+	}
+
+	related, err := rs.GetRelatedDevices(parent.ID, "contains")
+	if err != nil {
+		t.Fatalf("GetRelatedDevices failed: %v", err)
+	}
+	if len(related) != 1 || related[0].ID != child.ID {
+		t.Errorf("unexpected related devices %+v", related)
+	}
+
+	byType, err := rs.ListRelationships("depends_on")
+	if err != nil {
+		t.Fatalf("ListRelationships failed: %v", err)
+	}
+	if len(byType) != 0 {
+		t.Errorf("expected no depends_on relationships, got %+v", byType)
+	}
+
+	if err := rs.RemoveRelationship(parent.ID, child.ID, "contains"); err != nil {
+		t.Fatalf("RemoveRelationship failed: %v", err)
+	}
+	if err := rs.AddRelationship(parent.ID, child.ID, "contains"); err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+
+	// Relationships go with their devices
+	if err := s.DeleteDevice(child.ID); err != nil {
+		t.Fatalf("DeleteDevice failed: %v", err)
+	}
+	all, err := rs.ListRelationships("")
+	if err != nil {
+		t.Fatalf("ListRelationships failed: %v", err)
+	}
+	if len(all) != 0 {
+		t.Errorf("expected relationships of deleted device to be removed, got %+v", all)
+	}
+}
+
+func testRelationshipErrors(t *testing.T, s storage.Storage) {
+	rs := need[storage.RelationshipStorage](t, s)
+
+	device := &model.Device{ID: newID(), Name: "rack-1"}
+	if err := s.CreateDevice(device); err != nil {
+		t.Fatalf("CreateDevice failed: %v", err)
+	}
+
+	if err := rs.AddRelationship(device.ID, "missing", "contains"); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("AddRelationship with missing child: expected ErrDeviceNotFound, got %v", err)
+	}
+	if err := rs.AddRelationship("missing", device.ID, "contains"); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("AddRelationship with missing parent: expected ErrDeviceNotFound, got %v", err)
+	}
+	if err := rs.RemoveRelationship(device.ID, "missing", "contains"); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("RemoveRelationship: expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func testNetworkPools(t *testing.T, s storage.Storage) {
+	ns := need[storage.NetworkStorage](t, s)
+	ps := need[storage.NetworkPoolStorage](t, s)
+
+	network := &model.Network{ID: newID(), Name: "servers", Subnet: "10.1.0.0/24", DatacenterID: "default"}
+	if err := ns.CreateNetwork(network); err != nil {
+		t.Fatalf("CreateNetwork failed: %v", err)
+	}
+	pool := &model.NetworkPool{ID: newID(), NetworkID: network.ID, Name: "dhcp", StartIP: "10.1.0.10", EndIP: "10.1.0.11", Tags: []string{"dynamic"}}
+	if err := ps.CreateNetworkPool(pool); err != nil {
+		t.Fatalf("CreateNetworkPool failed: %v", err)
+	}
+
+	pool.Description = "Dynamic range"
+	if err := ps.UpdateNetworkPool(pool); err != nil {
+		t.Fatalf("UpdateNetworkPool failed: %v", err)
+	}
+	got, err := ps.GetNetworkPool(pool.ID)
+	if err != nil {
+		t.Fatalf("GetNetworkPool failed: %v", err)
+	}
+	if got.Description != "Dynamic range" || got.NetworkID != network.ID || len(got.Tags) != 1 {
+		t.Errorf("unexpected pool %+v", got)
+	}
+	pools, err := ps.ListNetworkPools(&model.NetworkPoolFilter{NetworkID: network.ID})
+	if err != nil {
+		t.Fatalf("ListNetworkPools failed: %v", err)
+	}
+	if len(pools) != 1 {
+		t.Errorf("expected 1 pool, got %d", len(pools))
+	}
+
+	// Addresses are handed out in order, skipping those in use
+	ip, err := ps.GetNextAvailableIP(pool.ID)
+	if err != nil {
+		t.Fatalf("GetNextAvailableIP failed: %v", err)
+	}
+	if ip != "10.1.0.10" {
+		t.Errorf("expected 10.1.0.10, got %s", ip)
+	}
+	first := &model.Device{ID: newID(), Name: "host-1", Addresses: []model.Address{{IP: ip, Type: "ipv4", NetworkID: network.ID, PoolID: pool.ID}}}
+	if err := s.CreateDevice(first); err != nil {
+		t.Fatalf("CreateDevice failed: %v", err)
+	}
+	if ip, err = ps.GetNextAvailableIP(pool.ID); err != nil || ip != "10.1.0.11" {
+		t.Errorf("expected 10.1.0.11 after allocation, got %q, %v", ip, err)
+	}
+	second := &model.Device{ID: newID(), Name: "host-2", Addresses: []model.Address{{IP: ip, Type: "ipv4", NetworkID: network.ID, PoolID: pool.ID}}}
+	if err := s.CreateDevice(second); err != nil {
+		t.Fatalf("CreateDevice failed: %v", err)
+	}
+	if _, err := ps.GetNextAvailableIP(pool.ID); !errors.Is(err, storage.ErrPoolExhausted) {
+		t.Errorf("expected ErrPoolExhausted, got %v", err)
+	}
+
+	if ok, err := ps.ValidateIPInPool(pool.ID, "10.1.0.11"); err != nil || !ok {
+		t.Errorf("expected 10.1.0.11 in pool, got %v, %v", ok, err)
+	}
+	if ok, _ := ps.ValidateIPInPool(pool.ID, "10.1.0.12"); ok {
+		t.Error("expected 10.1.0.12 outside pool")
+	}
+
+	// Deleting a pool keeps the addresses allocated from it
+	if err := ps.DeleteNetworkPool(pool.ID); err != nil {
+		t.Fatalf("DeleteNetworkPool failed: %v", err)
+	}
+	if _, err := ps.GetNetworkPool(pool.ID); !errors.Is(err, storage.ErrPoolNotFound) {
+		t.Errorf("expected ErrPoolNotFound after delete, got %v", err)
+	}
+	got2, err := s.GetDevice(first.ID)
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if len(got2.Addresses) != 1 || got2.Addresses[0].PoolID != "" || got2.Addresses[0].NetworkID != network.ID {
+		t.Errorf("expected address to remain without pool, got %+v", got2.Addresses)
+	}
+
+	// Pools are removed with their network
+	other := &model.NetworkPool{ID: newID(), NetworkID: network.ID, Name: "static", StartIP: "10.1.0.100", EndIP: "10.1.0.200"}
+	if err := ps.CreateNetworkPool(other); err != nil {
+		t.Fatalf("CreateNetworkPool failed: %v", err)
+	}
+	if err := ns.DeleteNetwork(network.ID); err != nil {
+		t.Fatalf("DeleteNetwork failed: %v", err)
+	}
+	if _, err := ps.GetNetworkPool(other.ID); !errors.Is(err, storage.ErrPoolNotFound) {
+		t.Errorf("expected pool to be removed with its network, got %v", err)
+	}
+}
+
+func testNetworkPoolErrors(t *testing.T, s storage.Storage) {
+	ps := need[storage.NetworkPoolStorage](t, s)
+
+	if _, err := ps.GetNetworkPool("missing"); !errors.Is(err, storage.ErrPoolNotFound) {
+		t.Errorf("GetNetworkPool: expected ErrPoolNotFound, got %v", err)
+	}
+	if err := ps.UpdateNetworkPool(&model.NetworkPool{ID: "missing", Name: "x", StartIP: "10.0.0.1", EndIP: "10.0.0.2"}); !errors.Is(err, storage.ErrPoolNotFound) {
+		t.Errorf("UpdateNetworkPool: expected ErrPoolNotFound, got %v", err)
+	}
+	if err := ps.DeleteNetworkPool("missing"); !errors.Is(err, storage.ErrPoolNotFound) {
+		t.Errorf("DeleteNetworkPool: expected ErrPoolNotFound, got %v", err)
+	}
+	if _, err := ps.GetNextAvailableIP("missing"); !errors.Is(err, storage.ErrPoolNotFound) {
+		t.Errorf("GetNextAvailableIP: expected ErrPoolNotFound, got %v", err)
+	}
+	if _, err := ps.ValidateIPInPool("missing", "10.0.0.1"); !errors.Is(err, storage.ErrPoolNotFound) {
+		t.Errorf("ValidateIPInPool: expected ErrPoolNotFound, got %v", err)
+	}
+}
+
+func testDiscovery(t *testing.T, s storage.Storage) {
+	ds := need[storage.DiscoveryStorage](t, s)
+	ns := need[storage.NetworkStorage](t, s)
+
+	network := &model.Network{ID: newID(), Name: "lan", Subnet: "172.16.0.0/24", DatacenterID: "default"}
+	if err := ns.CreateNetwork(network); err != nil {
+		t.Fatalf("CreateNetwork failed: %v", err)
+	}
+
+	rule := &model.DiscoveryRule{ID: newID(), NetworkID: network.ID, Enabled: true, ScanIntervalHours: 12, ScanType: "quick", ScanPorts: true, CustomPorts: []int{22, 443}}
+	if err := ds.CreateDiscoveryRule(rule); err != nil {
+		t.Fatalf("CreateDiscoveryRule failed: %v", err)
+	}
+	gotRule, err := ds.GetDiscoveryRuleByNetwork(network.ID)
+	if err != nil {
+		t.Fatalf("GetDiscoveryRuleByNetwork failed: %v", err)
+	}
+	if gotRule.ID != rule.ID || !gotRule.Enabled || gotRule.OSDetection || len(gotRule.CustomPorts) != 2 {
+		t.Errorf("unexpected rule %+v", gotRule)
+	}
+	gotRule.ScanIntervalHours = 6
+	gotRule.Schedule, gotRule.Timezone, gotRule.JitterMinutes = "0 2 * * *", "Europe/London", 15
+	gotRule.Blackouts = []model.DiscoveryBlackout{{Reason: "freeze", Schedule: "0 0 * 12 *", DurationMinutes: 60}}
+	gotRule.Probes = map[string]bool{"os": false, "snmp": true}
+	if err := ds.UpdateDiscoveryRule(gotRule); err != nil {
+		t.Fatalf("UpdateDiscoveryRule failed: %v", err)
+	}
+	if gotRule, err = ds.GetDiscoveryRule(rule.ID); err != nil || gotRule.ScanIntervalHours != 6 {
+		t.Errorf("expected updated interval, got %+v, %v", gotRule, err)
+	}
+	if gotRule.Schedule != "0 2 * * *" || gotRule.Timezone != "Europe/London" || gotRule.JitterMinutes != 15 ||
+		len(gotRule.Blackouts) != 1 || gotRule.Blackouts[0].Reason != "freeze" || gotRule.Blackouts[0].DurationMinutes != 60 {
+		t.Errorf("expected updated schedule, got %+v", gotRule)
+	}
+	if len(gotRule.Probes) != 2 || gotRule.Probes["os"] || !gotRule.Probes["snmp"] {
+		t.Errorf("expected updated probes, got %v", gotRule.Probes)
+	}
+	lastRun := time.Now().Add(-time.Hour).Truncate(time.Second)
+	nextRun := lastRun.Add(6 * time.Hour)
+	if err := ds.SetDiscoveryRuleSchedule(rule.ID, &lastRun, &nextRun); err != nil {
+		t.Fatalf("SetDiscoveryRuleSchedule failed: %v", err)
+	}
+	if gotRule, err = ds.GetDiscoveryRule(rule.ID); err != nil || gotRule.LastRunAt == nil || !gotRule.LastRunAt.Equal(lastRun) ||
+		gotRule.NextRunAt == nil || !gotRule.NextRunAt.Equal(nextRun) {
+		t.Errorf("expected schedule to be recorded, got %+v, %v", gotRule, err)
+	}
+	if err := ds.UpdateDiscoveryRule(gotRule); err != nil {
+		t.Fatalf("UpdateDiscoveryRule failed: %v", err)
+	}
+	if gotRule, err = ds.GetDiscoveryRule(rule.ID); err != nil || gotRule.NextRunAt == nil {
+		t.Errorf("expected update to keep the schedule, got %+v, %v", gotRule, err)
+	}
+	if err := ds.SetDiscoveryRuleSchedule("missing", nil, nil); !errors.Is(err, storage.ErrDiscoveryRuleNotFound) {
+		t.Errorf("SetDiscoveryRuleSchedule: expected ErrDiscoveryRuleNotFound, got %v", err)
+	}
+
+	started := time.Now()
+	scan := &model.DiscoveryScan{ID: newID(), NetworkID: network.ID, RuleID: rule.ID, Status: "running", ScanType: "quick", TotalHosts: 254, StartedAt: &started}
+	if err := ds.CreateDiscoveryScan(scan); err != nil {
+		t.Fatalf("CreateDiscoveryScan failed: %v", err)
+	}
+	scan.Status = "completed"
+	scan.ScannedHosts = 254
+	if err := ds.UpdateDiscoveryScan(scan); err != nil {
+		t.Fatalf("UpdateDiscoveryScan failed: %v", err)
+	}
+	if got, err := ds.GetDiscoveryScan(scan.ID); err != nil || got.Status != "completed" || got.ScannedHosts != 254 || got.RuleID != rule.ID {
+		t.Errorf("unexpected scan %+v, %v", got, err)
+	}
+	if scans, err := ds.ListDiscoveryScans(network.ID); err != nil || len(scans) != 1 {
+		t.Errorf("expected 1 scan, got %d, %v", len(scans), err)
+	}
+	for ip, found := range map[string]bool{"172.16.0.1": false, "172.16.0.2": true} {
+		if err := ds.MarkDiscoveryScanHost(scan.ID, ip, found); err != nil {
+			t.Fatalf("MarkDiscoveryScanHost failed: %v", err)
+		}
+	}
+	if err := ds.MarkDiscoveryScanHost(scan.ID, "172.16.0.1", true); err != nil {
+		t.Fatalf("MarkDiscoveryScanHost twice failed: %v", err)
+	}
+	if hosts, err := ds.GetDiscoveryScanHosts(scan.ID); err != nil || len(hosts) != 2 || !hosts["172.16.0.1"] || !hosts["172.16.0.2"] {
+		t.Errorf("unexpected scan hosts %v, %v", hosts, err)
+	}
+	if err := ds.DeleteDiscoveryScanHosts(scan.ID); err != nil {
+		t.Fatalf("DeleteDiscoveryScanHosts failed: %v", err)
+	}
+	if hosts, err := ds.GetDiscoveryScanHosts(scan.ID); err != nil || len(hosts) != 0 {
+		t.Errorf("expected no scan hosts after delete, got %v, %v", hosts, err)
+	}
+
+	// Observations are kept per scan; a host observed again replaces its observation
+	for _, obs := range []model.DiscoveryObservation{
+		{ScanID: scan.ID, IP: "172.16.0.2", Status: "online", Hostname: "nas", OpenPorts: []int{22}},
+		{ScanID: scan.ID, IP: "172.16.0.1", Status: "offline"},
+		{ScanID: scan.ID, IP: "172.16.0.2", Status: "online", Hostname: "nas", MACAddress: "00:11:22:33:44:55", OpenPorts: []int{22, 445}},
+	} {
+		if err := ds.RecordDiscoveryObservation(&obs); err != nil {
+			t.Fatalf("RecordDiscoveryObservation failed: %v", err)
+		}
+	}
+	observations, err := ds.ListDiscoveryObservations(scan.ID)
+	if err != nil {
+		t.Fatalf("ListDiscoveryObservations failed: %v", err)
+	}
+	if len(observations) != 2 || observations[0].IP != "172.16.0.1" || observations[1].MACAddress != "00:11:22:33:44:55" ||
+		len(observations[1].OpenPorts) != 2 || observations[1].ObservedAt.IsZero() {
+		t.Errorf("unexpected observations %+v", observations)
+	}
+	scan.Changes = &model.DiscoveryChangeSummary{PreviousScanID: "earlier", NewHosts: 2, OpenedPorts: 1}
+	if err := ds.UpdateDiscoveryScan(scan); err != nil {
+		t.Fatalf("UpdateDiscoveryScan failed: %v", err)
+	}
+	if got, err := ds.GetDiscoveryScan(scan.ID); err != nil || got.Changes == nil || *got.Changes != *scan.Changes {
+		t.Errorf("expected scan changes to be stored, got %+v, %v", got, err)
+	}
+
+	found := &model.DiscoveredDevice{IP: "172.16.0.20", NetworkID: network.ID, Status: "online", Confidence: 60, OpenPorts: []int{22}, LastSeen: time.Now()}
+	if err := ds.CreateOrUpdateDiscoveredDevice(found); err != nil {
+		t.Fatalf("CreateOrUpdateDiscoveredDevice failed: %v", err)
+	}
+	// A later sighting of the same IP updates the device and keeps the higher confidence
+	again := &model.DiscoveredDevice{IP: "172.16.0.20", NetworkID: network.ID, Status: "online", Confidence: 40, Hostname: "printer", LastSeen: time.Now()}
+	if err := ds.CreateOrUpdateDiscoveredDevice(again); err != nil {
+		t.Fatalf("CreateOrUpdateDiscoveredDevice failed: %v", err)
+	}
+	got, err := ds.GetDiscoveredDeviceByIP("172.16.0.20")
+	if err != nil {
+		t.Fatalf("GetDiscoveredDeviceByIP failed: %v", err)
+	}
+	if got.ID != found.ID || got.Confidence != 60 || got.Hostname != "printer" {
+		t.Errorf("unexpected discovered device %+v", got)
+	}
+
+	device, err := ds.PromoteDevice(got.ID, &model.PromoteDeviceRequest{Name: "printer-1", Tags: []string{"office"}})
+	if err != nil {
+		t.Fatalf("PromoteDevice failed: %v", err)
+	}
+	if len(device.Addresses) != 1 || device.Addresses[0].IP != "172.16.0.20" || device.Addresses[0].NetworkID != network.ID {
+		t.Errorf("expected promoted device to take the discovered address, got %+v", device.Addresses)
+	}
+	if stored, err := s.GetDevice(device.ID); err != nil || stored.Name != "printer-1" {
+		t.Errorf("expected promoted device to be stored, got %+v, %v", stored, err)
+	}
+	if got, _ = ds.GetDiscoveredDevice(got.ID); got == nil || got.PromotedToDeviceID != device.ID {
+		t.Errorf("expected discovered device to reference %s, got %+v", device.ID, got)
+	}
+
+	// Bulk promotion reports failures without stopping at them
+	other := &model.DiscoveredDevice{IP: "172.16.0.21", NetworkID: network.ID, Status: "online", LastSeen: time.Now()}
+	if err := ds.CreateOrUpdateDiscoveredDevice(other); err != nil {
+		t.Fatalf("CreateOrUpdateDiscoveredDevice failed: %v", err)
+	}
+	promoted, errs := ds.BulkPromoteDevices([]string{"missing", other.ID}, []model.PromoteDeviceRequest{{Name: "ghost"}, {Name: "scanner-1"}})
+	if len(promoted) != 1 || promoted[0].Name != "scanner-1" {
+		t.Errorf("expected scanner-1 to be promoted, got %+v", promoted)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], storage.ErrDiscoveredDeviceNotFound) {
+		t.Errorf("expected one ErrDiscoveredDeviceNotFound, got %v", errs)
+	}
+
+	// Recently seen devices survive a cleanup
+	if removed, err := ds.CleanupOldDevices(30); err != nil || removed != 0 {
+		t.Errorf("expected no devices to be cleaned up, got %d, %v", removed, err)
+	}
+
+	// Discovery data goes with the network, promoted devices stay
+	if err := ns.DeleteNetwork(network.ID); err != nil {
+		t.Fatalf("DeleteNetwork failed: %v", err)
+	}
+	devices, err := ds.ListDiscoveredDevices(nil)
+	if err != nil {
+		t.Fatalf("ListDiscoveredDevices failed: %v", err)
+	}
+	rules, err := ds.ListDiscoveryRules("")
+	if err != nil {
+		t.Fatalf("ListDiscoveryRules failed: %v", err)
+	}
+	if len(devices) != 0 || len(rules) != 0 {
+		t.Errorf("expected discovery data to be removed, got %d devices and %d rules", len(devices), len(rules))
+	}
+	if _, err := ds.GetDiscoveryScan(scan.ID); !errors.Is(err, storage.ErrDiscoveryScanNotFound) {
+		t.Errorf("expected scan to be removed with its network, got %v", err)
+	}
+	if observations, err := ds.ListDiscoveryObservations(scan.ID); err != nil || len(observations) != 0 {
+		t.Errorf("expected observations to be removed with their scan, got %d, %v", len(observations), err)
+	}
+	if _, err := s.GetDevice(device.ID); err != nil {
+		t.Errorf("expected promoted device to outlive its network, got %v", err)
+	}
+}
+
+func testDiscoveryErrors(t *testing.T, s storage.Storage) {
+	ds := need[storage.DiscoveryStorage](t, s)
+
+	if _, err := ds.GetDiscoveredDevice("missing"); !errors.Is(err, storage.ErrDiscoveredDeviceNotFound) {
+		t.Errorf("GetDiscoveredDevice: expected ErrDiscoveredDeviceNotFound, got %v", err)
+	}
+	if _, err := ds.GetDiscoveredDeviceByIP("192.0.2.1"); !errors.Is(err, storage.ErrDiscoveredDeviceNotFound) {
+		t.Errorf("GetDiscoveredDeviceByIP: expected ErrDiscoveredDeviceNotFound, got %v", err)
+	}
+	if err := ds.DeleteDiscoveredDevice("missing"); !errors.Is(err, storage.ErrDiscoveredDeviceNotFound) {
+		t.Errorf("DeleteDiscoveredDevice: expected ErrDiscoveredDeviceNotFound, got %v", err)
+	}
+	if _, err := ds.PromoteDevice("missing", &model.PromoteDeviceRequest{Name: "x"}); !errors.Is(err, storage.ErrDiscoveredDeviceNotFound) {
+		t.Errorf("PromoteDevice: expected ErrDiscoveredDeviceNotFound, got %v", err)
+	}
+	if _, err := ds.GetDiscoveryScan("missing"); !errors.Is(err, storage.ErrDiscoveryScanNotFound) {
+		t.Errorf("GetDiscoveryScan: expected ErrDiscoveryScanNotFound, got %v", err)
+	}
+	if err := ds.DeleteDiscoveryScan("missing"); !errors.Is(err, storage.ErrDiscoveryScanNotFound) {
+		t.Errorf("DeleteDiscoveryScan: expected ErrDiscoveryScanNotFound, got %v", err)
+	}
+	if _, err := ds.GetDiscoveryRule("missing"); !errors.Is(err, storage.ErrDiscoveryRuleNotFound) {
+		t.Errorf("GetDiscoveryRule: expected ErrDiscoveryRuleNotFound, got %v", err)
+	}
+	if _, err := ds.GetDiscoveryRuleByNetwork("missing"); !errors.Is(err, storage.ErrDiscoveryRuleNotFound) {
+		t.Errorf("GetDiscoveryRuleByNetwork: expected ErrDiscoveryRuleNotFound, got %v", err)
+	}
+	if err := ds.DeleteDiscoveryRule("missing"); !errors.Is(err, storage.ErrDiscoveryRuleNotFound) {
+		t.Errorf("DeleteDiscoveryRule: expected ErrDiscoveryRuleNotFound, got %v", err)
+	}
+}
+
+func testPromotionPolicies(t *testing.T, s storage.Storage) {
+	ds := need[storage.DiscoveryStorage](t, s)
+
+	policy := &model.PromotionPolicy{
+		Name:     "web servers",
+		Enabled:  true,
+		Priority: 10,
+		Match:    model.PromotionMatch{MinConfidence: 80, OpenPorts: []int{80, 443}, HostnamePattern: "^web"},
+		Template: model.PromotionTemplate{
+			NamePattern:   "{short_hostname}",
+			Tags:          []string{"web"},
+			Relationships: []model.PromotionRelationship{{DeviceID: "lb-1", Type: "depends_on", AsParent: true}},
+		},
+	}
+	if err := ds.CreatePromotionPolicy(policy); err != nil {
+		t.Fatalf("CreatePromotionPolicy failed: %v", err)
+	}
+	if policy.ID == "" {
+		t.Fatal("expected an ID to be assigned")
+	}
+
+	got, err := ds.GetPromotionPolicy(policy.ID)
+	if err != nil {
+		t.Fatalf("GetPromotionPolicy failed: %v", err)
+	}
+	if got.Name != "web servers" || !got.Enabled || got.Priority != 10 || got.Match.HostnamePattern != "^web" ||
+		len(got.Match.OpenPorts) != 2 || len(got.Template.Relationships) != 1 || !got.Template.Relationships[0].AsParent {
+		t.Errorf("unexpected policy %+v", got)
+	}
+
+	first := &model.PromotionPolicy{Name: "printers", Priority: 1, Match: model.PromotionMatch{OpenPorts: []int{631}}}
+	if err := ds.CreatePromotionPolicy(first); err != nil {
+		t.Fatalf("CreatePromotionPolicy failed: %v", err)
+	}
+	if err := ds.CreatePromotionPolicy(&model.PromotionPolicy{Name: "printers"}); !errors.Is(err, storage.ErrDuplicatePromotionPolicy) {
+		t.Errorf("expected ErrDuplicatePromotionPolicy, got %v", err)
+	}
+
+	policies, err := ds.ListPromotionPolicies()
+	if err != nil {
+		t.Fatalf("ListPromotionPolicies failed: %v", err)
+	}
+	if len(policies) != 2 || policies[0].ID != first.ID {
+		t.Errorf("expected policies in priority order, got %+v", policies)
+	}
+
+	got.Enabled = false
+	got.Match.OSFamily = "Linux"
+	if err := ds.UpdatePromotionPolicy(got); err != nil {
+		t.Fatalf("UpdatePromotionPolicy failed: %v", err)
+	}
+	updated, _ := ds.GetPromotionPolicy(policy.ID)
+	if updated.Enabled || updated.Match.OSFamily != "Linux" || updated.CreatedAt.IsZero() {
+		t.Errorf("unexpected updated policy %+v", updated)
+	}
+	got.Name = "printers"
+	if err := ds.UpdatePromotionPolicy(got); !errors.Is(err, storage.ErrDuplicatePromotionPolicy) {
+		t.Errorf("expected ErrDuplicatePromotionPolicy on rename, got %v", err)
+	}
+
+	if err := ds.DeletePromotionPolicy(policy.ID); err != nil {
+		t.Fatalf("DeletePromotionPolicy failed: %v", err)
+	}
+	if _, err := ds.GetPromotionPolicy(policy.ID); !errors.Is(err, storage.ErrPromotionPolicyNotFound) {
+		t.Errorf("GetPromotionPolicy: expected ErrPromotionPolicyNotFound, got %v", err)
+	}
+	if err := ds.DeletePromotionPolicy(policy.ID); !errors.Is(err, storage.ErrPromotionPolicyNotFound) {
+		t.Errorf("DeletePromotionPolicy: expected ErrPromotionPolicyNotFound, got %v", err)
+	}
+	if err := ds.UpdatePromotionPolicy(&model.PromotionPolicy{ID: "missing", Name: "missing"}); !errors.Is(err, storage.ErrPromotionPolicyNotFound) {
+		t.Errorf("UpdatePromotionPolicy: expected ErrPromotionPolicyNotFound, got %v", err)
+	}
+}
+
+func testJobs(t *testing.T, s storage.Storage) {
+	js := need[storage.JobStorage](t, s)
+
+	if _, err := js.GetJob("missing"); !errors.Is(err, storage.ErrJobNotFound) {
+		t.Errorf("GetJob: expected ErrJobNotFound, got %v", err)
+	}
+	if err := js.UpdateJob(&model.Job{ID: "missing"}); !errors.Is(err, storage.ErrJobNotFound) {
+		t.Errorf("UpdateJob: expected ErrJobNotFound, got %v", err)
+	}
+
+	older := &model.Job{Type: model.JobTypeBackup, Status: model.JobStatusPending, MaxAttempts: 3,
+		CreatedAt: time.Now().Add(-time.Minute)}
+	if err := js.CreateJob(older); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if older.ID == "" || !older.RunAt.Equal(older.CreatedAt) {
+		t.Errorf("expected an ID and a run time defaulting to creation, got %+v", older)
+	}
+	job := &model.Job{Type: model.JobTypeCleanup, Status: model.JobStatusPending, MaxAttempts: 1,
+		Params: []byte(`{"older_than_days":7}`)}
+	if err := js.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	started := time.Now().Truncate(time.Second)
+	job.Status = model.JobStatusCompleted
+	job.Attempts = 1
+	job.StartedAt = &started
+	job.FinishedAt = &started
+	job.Result = []byte(`{"removed":2}`)
+	job.Logs = []model.JobLogEntry{{Time: started, Message: "Removed 2 devices"}}
+	if err := js.UpdateJob(job); err != nil {
+		t.Fatalf("UpdateJob failed: %v", err)
+	}
+
+	got, err := js.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetJob failed: %v", err)
+	}
+	if got.Status != model.JobStatusCompleted || got.Attempts != 1 || string(got.Params) != `{"older_than_days":7}` ||
+		string(got.Result) != `{"removed":2}` || got.StartedAt == nil || !got.StartedAt.Equal(started) {
+		t.Errorf("unexpected job after update: %+v", got)
+	}
+	if len(got.Logs) != 1 || got.Logs[0].Message != "Removed 2 devices" {
+		t.Errorf("expected the log to be stored, got %+v", got.Logs)
+	}
+
+	all, err := js.ListJobs(nil)
+	if err != nil {
+		t.Fatalf("ListJobs failed: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != job.ID || all[1].ID != older.ID {
+		t.Errorf("expected jobs newest first, got %+v", all)
+	}
+	for _, tt := range []struct {
+		filter model.JobFilter
+		want   string
+	}{
+		{model.JobFilter{Type: model.JobTypeBackup}, older.ID},
+		{model.JobFilter{Status: model.JobStatusCompleted}, job.ID},
+		{model.JobFilter{Limit: 1}, job.ID},
+	} {
+		jobs, err := js.ListJobs(&tt.filter)
+		if err != nil {
+			t.Fatalf("ListJobs(%+v) failed: %v", tt.filter, err)
+		}
+		if len(jobs) != 1 || jobs[0].ID != tt.want {
+			t.Errorf("ListJobs(%+v): expected %s, got %+v", tt.filter, tt.want, jobs)
+		}
+	}
+}
+
+func testLeases(t *testing.T, s storage.Storage) {
+	ls := need[storage.LeaseStorage](t, s)
+
+	acquire := func(owner string, ttl time.Duration, want bool) {
+		t.Helper()
+		ok, err := ls.AcquireLease("job:1", owner, ttl)
+		if err != nil {
+			t.Fatalf("AcquireLease(%s) failed: %v", owner, err)
+		}
+		if ok != want {
+			t.Errorf("AcquireLease(%s): expected %v, got %v", owner, want, ok)
+		}
+	}
+
+	acquire("a", time.Minute, true)
+	acquire("b", time.Minute, false)
+	// The holder renews its lease, here with one that expires straight away
+	acquire("a", -time.Second, true)
+	acquire("b", time.Minute, true)
+	acquire("a", time.Minute, false)
+
+	// Only the holder releases a lease
+	if err := ls.ReleaseLease("job:1", "a"); err != nil {
+		t.Fatalf("ReleaseLease failed: %v", err)
+	}
+	acquire("a", time.Minute, false)
+	if err := ls.ReleaseLease("job:1", "b"); err != nil {
+		t.Fatalf("ReleaseLease failed: %v", err)
+	}
+	acquire("a", time.Minute, true)
+
+	if ok, err := ls.AcquireLease("job:2", "b", time.Minute); err != nil || !ok {
+		t.Errorf("expected leases to be independent, got %v, %v", ok, err)
+	}
+}
+
+func testVersions(t *testing.T, s storage.Storage) {
+	device := &model.Device{ID: newID(), Name: "web-1"}
+	if err := s.CreateDevice(device); err != nil {
+		t.Fatalf("CreateDevice failed: %v", err)
+	}
+	if device.Version != 1 {
+		t.Errorf("expected new device at version 1, got %d", device.Version)
+	}
+
+	// Every update advances the version
+	stale, err := s.GetDevice(device.ID)
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	fresh := *stale
+	fresh.Description = "first"
+	if err := s.UpdateDevice(&fresh); err != nil {
+		t.Fatalf("UpdateDevice failed: %v", err)
+	}
+	if fresh.Version != 2 {
+		t.Errorf("expected version 2 after update, got %d", fresh.Version)
+	}
+
+	// An update based on an older version is rejected and changes nothing
+	stale.Description = "second"
+	if err := s.UpdateDevice(stale); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict for stale update, got %v", err)
+	}
+	if got, _ := s.GetDevice(device.ID); got == nil || got.Description != "first" || got.Version != 2 {
+		t.Errorf("expected stale update to change nothing, got %+v", got)
+	}
+
+	// Without a version the update always applies
+	unversioned := &model.Device{ID: device.ID, Name: "web-1", Description: "third"}
+	if err := s.UpdateDevice(unversioned); err != nil {
+		t.Fatalf("UpdateDevice without version failed: %v", err)
+	}
+	if unversioned.Version != 3 {
+		t.Errorf("expected version 3, got %d", unversioned.Version)
+	}
+	if err := s.UpdateDevice(&model.Device{ID: "missing", Name: "x", Version: 1}); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound for missing device with version, got %v", err)
+	}
+
+	if dcs, ok := s.(storage.DatacenterStorage); ok {
+		dc := &model.Datacenter{ID: newID(), Name: "DC1"}
+		if err := dcs.CreateDatacenter(dc); err != nil {
+			t.Fatalf("CreateDatacenter failed: %v", err)
+		}
+		dc.Location = "Berlin"
+		if err := dcs.UpdateDatacenter(dc); err != nil || dc.Version != 2 {
+			t.Errorf("expected datacenter version 2, got %d, %v", dc.Version, err)
+		}
+		dc.Version = 1
+		if err := dcs.UpdateDatacenter(dc); !errors.Is(err, storage.ErrVersionConflict) {
+			t.Errorf("expected ErrVersionConflict for datacenter, got %v", err)
+		}
+	}
+
+	ns, ok := s.(storage.NetworkStorage)
+	if !ok {
+		return
+	}
+	network := &model.Network{ID: newID(), Name: "lan", Subnet: "10.2.0.0/24", DatacenterID: "default"}
+	if err := ns.CreateNetwork(network); err != nil {
+		t.Fatalf("CreateNetwork failed: %v", err)
+	}
+	network.Description = "LAN"
+	if err := ns.UpdateNetwork(network); err != nil || network.Version != 2 {
+		t.Errorf("expected network version 2, got %d, %v", network.Version, err)
+	}
+	network.Version = 1
+	if err := ns.UpdateNetwork(network); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict for network, got %v", err)
+	}
+
+	if ps, ok := s.(storage.NetworkPoolStorage); ok {
+		pool := &model.NetworkPool{ID: newID(), NetworkID: network.ID, Name: "dhcp", StartIP: "10.2.0.10", EndIP: "10.2.0.20"}
+		if err := ps.CreateNetworkPool(pool); err != nil {
+			t.Fatalf("CreateNetworkPool failed: %v", err)
+		}
+		if got, err := ps.GetNetworkPool(pool.ID); err != nil || got.Version != 1 {
+			t.Errorf("expected pool version 1, got %+v, %v", got, err)
+		}
+		pool.EndIP = "10.2.0.30"
+		if err := ps.UpdateNetworkPool(pool); err != nil || pool.Version != 2 {
+			t.Errorf("expected pool version 2, got %d, %v", pool.Version, err)
+		}
+		pool.Version = 1
+		if err := ps.UpdateNetworkPool(pool); !errors.Is(err, storage.ErrVersionConflict) {
+			t.Errorf("expected ErrVersionConflict for pool, got %v", err)
+		}
+	}
+}
+
+func testTransactions(t *testing.T, s storage.Storage) {
+	ts := need[storage.TxStorage](t, s)
+	need[storage.RelationshipStorage](t, s)
+
+	rack := &model.Device{ID: newID(), Name: "rack-1"}
+	server := &model.Device{ID: newID(), Name: "server-1"}
+
+	// Changes are visible inside the transaction and committed together
+	err := ts.WithTx(func(tx storage.Storage) error {
+		for _, d := range []*model.Device{rack, server} {
+			if err := tx.CreateDevice(d); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.GetDevice(rack.ID); err != nil {
+			return err
+		}
+		return tx.(storage.RelationshipStorage).AddRelationship(rack.ID, server.ID, "contains")
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	rels, err := s.(storage.RelationshipStorage).GetRelationships(server.ID)
+	if err != nil || len(rels) != 1 {
+		t.Errorf("expected committed relationship, got %+v, %v", rels, err)
+	}
+
+	// An error rolls back every change, including those of earlier calls
+	failed := errors.New("abort")
+	orphan := &model.Device{ID: newID(), Name: "orphan"}
+	err = ts.WithTx(func(tx storage.Storage) error {
+		if err := tx.CreateDevice(orphan); err != nil {
+			return err
+		}
+		if err := tx.DeleteDevice(rack.ID); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected WithTx to return the error of fn, got %v", err)
+	}
+	if _, err := s.GetDevice(orphan.ID); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("expected created device to be rolled back, got %v", err)
+	}
+	if _, err := s.GetDevice(rack.ID); err != nil {
+		t.Errorf("expected deleted device to be restored, got %v", err)
+	}
+
+	// A panic rolls back as well
+	func() {
+		defer func() { recover() }()
+		ts.WithTx(func(tx storage.Storage) error {
+			if err := tx.CreateDevice(orphan); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+	if _, err := s.GetDevice(orphan.ID); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("expected device to be rolled back after panic, got %v", err)
+	}
+
+	// The store is usable once a transaction ended
+	if err := s.CreateDevice(orphan); err != nil {
+		t.Errorf("CreateDevice after transactions failed: %v", err)
+	}
+}
+
+func testNestedTransactions(t *testing.T, s storage.Storage) {
+	ts := need[storage.TxStorage](t, s)
+
+	kept := &model.Device{ID: newID(), Name: "kept"}
+	dropped := &model.Device{ID: newID(), Name: "dropped"}
+	failed := errors.New("abort")
+
+	err := ts.WithTx(func(tx storage.Storage) error {
+		if err := tx.CreateDevice(kept); err != nil {
+			return err
+		}
+		// A failing nested unit of work only undoes its own changes
+		nested := tx.(storage.TxStorage).WithTx(func(tx storage.Storage) error {
+			if err := tx.CreateDevice(dropped); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(nested, failed) {
+			return nested
+		}
+		if _, err := tx.GetDevice(dropped.ID); !errors.Is(err, storage.ErrDeviceNotFound) {
+			t.Errorf("expected nested changes to be rolled back, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if _, err := s.GetDevice(kept.ID); err != nil {
+		t.Errorf("expected outer changes to be committed, got %v", err)
+	}
+	if _, err := s.GetDevice(dropped.ID); !errors.Is(err, storage.ErrDeviceNotFound) {
+		t.Errorf("expected nested changes to stay rolled back, got %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+package storage
+
+// TxStorage is implemented by backends that can run several operations as one unit of work
+type TxStorage interface {
+	// WithTx runs fn in a transaction. The store passed to fn implements the same
+	// storage interfaces as the backend and sees the changes made so far; they are
+	// committed when fn returns nil and rolled back when it returns an error or panics.
+	// Other callers wait until the transaction finishes, so fn must only use tx.
+	WithTx(fn func(tx Storage) error) error
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// sqlConn is the handle queries run on: the connection pool, or the transaction
+// of a unit of work
+type sqlConn interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Begin() (sqlTx, error)
+}
+
+// sqlTx is satisfied by *sql.Tx and by the savepoints nested in a unit of work
+type sqlTx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Commit() error
+	Rollback() error
+}
+
+// dbConn runs queries on the connection pool
+type dbConn struct {
+	*sql.DB
+}
+
+func (c dbConn) Begin() (sqlTx, error) {
+	return c.DB.Begin()
+}
+
+// txConn runs queries in the transaction of a unit of work. Storage methods that
+// begin a transaction of their own get a savepoint instead.
+type txConn struct {
+	tx         *sql.Tx
+	savepoints int
+}
+
+func (c *txConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.tx.Exec(query, args...)
+}
+
+func (c *txConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.tx.Query(query, args...)
+}
+
+func (c *txConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.tx.QueryRow(query, args...)
+}
+
+func (c *txConn) Begin() (sqlTx, error) {
+	c.savepoints++
+	name := fmt.Sprintf("sp_%d", c.savepoints)
+	if _, err := c.tx.Exec("SAVEPOINT " + name); err != nil {
+		return nil, fmt.Errorf("creating savepoint: %w", err)
+	}
+	return &savepoint{txConn: c, name: name}, nil
+}
+
+// savepoint is a transaction nested in a unit of work
+type savepoint struct {
+	*txConn
+	name string
+	done bool
+}
+
+func (sp *savepoint) Commit() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	_, err := sp.tx.Exec("RELEASE SAVEPOINT " + sp.name)
+	return err
+}
+
+func (sp *savepoint) Rollback() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	if _, err := sp.tx.Exec("ROLLBACK TO SAVEPOINT " + sp.name); err != nil {
+		return err
+	}
+	_, err := sp.tx.Exec("RELEASE SAVEPOINT " + sp.name)
+	return err
+}
+
+// WithTx runs fn in a transaction, see TxStorage. Calling WithTx on the store
+// passed to fn nests a savepoint in the outer transaction.
+func (ss *sqlStorage) WithTx(fn func(tx Storage) error) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var conn sqlConn
+	if sqlTx, ok := tx.(*sql.Tx); ok {
+		conn = &txConn{tx: sqlTx}
+	} else {
+		// Already inside a unit of work, tx is a savepoint of it
+		conn = tx.(*savepoint).txConn
+	}
+
+	// The store of the unit of work runs under the lock taken here
+	if err := fn(&sqlStorage{mu: noLock{}, db: conn}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
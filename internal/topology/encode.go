@@ -0,0 +1,185 @@
+package topology
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// Supported export formats
+const (
+	FormatDOT     = "dot"
+	FormatGraphML = "graphml"
+	FormatJSON    = "json"
+)
+
+// ErrUnsupportedFormat is returned when an unknown export format is requested
+var ErrUnsupportedFormat = errors.New("unsupported topology format")
+
+// ContentType returns the HTTP content type for an export format
+func ContentType(format string) string {
+	switch format {
+	case FormatDOT:
+		return "text/vnd.graphviz; charset=utf-8"
+	case FormatGraphML:
+		return "application/graphml+xml; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// Encode writes the graph to w in the requested format
+func Encode(w io.Writer, g *model.Topology, format string) error {
+	switch format {
+	case FormatDOT:
+		return EncodeDOT(w, g)
+	case FormatGraphML:
+		return EncodeGraphML(w, g)
+	case FormatJSON, "":
+		return EncodeJSON(w, g)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
+
+// EncodeJSON writes the graph as D3-style {"nodes": [...], "links": [...]} JSON
+func EncodeJSON(w io.Writer, g *model.Topology) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// dotShapes maps node types to Graphviz shapes
+var dotShapes = map[string]string{
+	model.TopologyNodeDatacenter: "box3d",
+	model.TopologyNodeNetwork:    "ellipse",
+	model.TopologyNodeDevice:     "box",
+}
+
+// EncodeDOT writes the graph in Graphviz DOT format
+func EncodeDOT(w io.Writer, g *model.Topology) error {
+	var sb strings.Builder
+	sb.WriteString("digraph rackd {\n")
+	sb.WriteString("  rankdir=LR;\n")
+
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&sb, "  %s [label=%s, shape=%s, type=%s];\n",
+			dotQuote(n.ID), dotQuote(n.Label), dotShapes[n.Type], dotQuote(n.Type))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  %s -> %s [label=%s];\n", dotQuote(e.Source), dotQuote(e.Target), dotQuote(e.Type))
+	}
+
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// dotQuote returns s as a quoted DOT identifier
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// GraphML document structure
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// EncodeGraphML writes the graph as a GraphML document
+func EncodeGraphML(w io.Writer, g *model.Topology) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "entity_id", For: "node", AttrName: "entity_id", AttrType: "string"},
+			{ID: "edge_type", For: "edge", AttrName: "type", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "rackd", EdgeDefault: "directed"},
+	}
+
+	attrKeys := attributeKeys(g)
+	for _, k := range attrKeys {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: "attr_" + k, For: "node", AttrName: k, AttrType: "string"})
+	}
+
+	for _, n := range g.Nodes {
+		node := graphMLNode{
+			ID: n.ID,
+			Data: []graphMLData{
+				{Key: "type", Value: n.Type},
+				{Key: "label", Value: n.Label},
+				{Key: "entity_id", Value: n.EntityID},
+			},
+		}
+		keys := make([]string, 0, len(n.Attributes))
+		for k := range n.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			node.Data = append(node.Data, graphMLData{Key: "attr_" + k, Value: n.Attributes[k]})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.Source,
+			Target: e.Target,
+			Data:   []graphMLData{{Key: "edge_type", Value: e.Type}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,211 @@
+package topology
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// UngroupedID is the group used for devices without a datacenter or network
+const UngroupedID = "ungrouped"
+
+// Layout spacing, in layout units (roughly pixels at 1:1 zoom)
+const (
+	nodeSpacing  = 18.0
+	groupPadding = 30.0
+	groupGap     = 60.0
+)
+
+// goldenAngle spreads nodes evenly on a sunflower spiral
+var goldenAngle = math.Pi * (3 - math.Sqrt(5))
+
+// Layout positions the devices of a topology graph in per-datacenter or
+// per-network clusters. It runs in linear time so large inventories can be
+// rendered without a client-side force simulation.
+func Layout(g *model.Topology, groupBy string) (*model.TopologyLayout, error) {
+	var groupEdgeType, groupNodeType string
+	switch groupBy {
+	case model.TopologyGroupByDatacenter, "":
+		groupBy = model.TopologyGroupByDatacenter
+		groupEdgeType, groupNodeType = model.TopologyEdgeLocatedIn, model.TopologyNodeDatacenter
+	case model.TopologyGroupByNetwork:
+		groupEdgeType, groupNodeType = model.TopologyEdgeAttached, model.TopologyNodeNetwork
+	default:
+		return nil, fmt.Errorf("unsupported group_by: %s", groupBy)
+	}
+
+	nodesByID := make(map[string]model.TopologyNode, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodesByID[n.ID] = n
+	}
+
+	// Assign each device to the first group it is linked to
+	deviceGroup := make(map[string]string)
+	for _, e := range g.Edges {
+		if e.Type != groupEdgeType {
+			continue
+		}
+		src, dst := nodesByID[e.Source], nodesByID[e.Target]
+		if src.Type != model.TopologyNodeDevice || dst.Type != groupNodeType {
+			continue
+		}
+		if _, ok := deviceGroup[src.EntityID]; !ok {
+			deviceGroup[src.EntityID] = dst.EntityID
+		}
+	}
+
+	layout := &model.TopologyLayout{
+		GroupBy:    groupBy,
+		Groups:     []model.TopologyGroup{},
+		Nodes:      []model.TopologyLayoutNode{},
+		Edges:      []model.TopologyLayoutEdge{},
+		GroupEdges: []model.TopologyLayoutEdge{},
+	}
+
+	members := make(map[string][]model.TopologyNode)
+	var groupOrder []string
+	for _, n := range g.Nodes {
+		if n.Type != model.TopologyNodeDevice {
+			continue
+		}
+		groupID, ok := deviceGroup[n.EntityID]
+		if !ok {
+			groupID = UngroupedID
+			deviceGroup[n.EntityID] = groupID
+		}
+		if _, seen := members[groupID]; !seen {
+			groupOrder = append(groupOrder, groupID)
+		}
+		members[groupID] = append(members[groupID], n)
+	}
+
+	// Largest groups first so the packing stays compact
+	sort.SliceStable(groupOrder, func(i, j int) bool {
+		return len(members[groupOrder[i]]) > len(members[groupOrder[j]])
+	})
+
+	for _, id := range groupOrder {
+		label := "Ungrouped"
+		if n, ok := nodesByID[NodeID(groupNodeType, id)]; ok {
+			label = n.Label
+		}
+		layout.Groups = append(layout.Groups, model.TopologyGroup{
+			ID:        id,
+			Label:     label,
+			Radius:    groupRadius(len(members[id])),
+			NodeCount: len(members[id]),
+		})
+	}
+	layout.Width, layout.Height = packGroups(layout.Groups)
+
+	// Aggregate relationship edges per device pair and per group pair
+	degree := make(map[string]int)
+	deviceEdges := make(map[[2]string]*model.TopologyLayoutEdge)
+	groupEdges := make(map[[2]string]*model.TopologyLayoutEdge)
+	var deviceEdgeOrder, groupEdgeOrder [][2]string
+	for _, e := range g.Edges {
+		src, dst := nodesByID[e.Source], nodesByID[e.Target]
+		if src.Type != model.TopologyNodeDevice || dst.Type != model.TopologyNodeDevice {
+			continue
+		}
+		degree[src.EntityID]++
+		degree[dst.EntityID]++
+
+		key := pairKey(src.EntityID, dst.EntityID)
+		edge, ok := deviceEdges[key]
+		if !ok {
+			edge = &model.TopologyLayoutEdge{Source: key[0], Target: key[1], Types: make(map[string]int)}
+			deviceEdges[key] = edge
+			deviceEdgeOrder = append(deviceEdgeOrder, key)
+		}
+		edge.Count++
+		edge.Types[e.Type]++
+
+		srcGroup, dstGroup := deviceGroup[src.EntityID], deviceGroup[dst.EntityID]
+		if srcGroup == dstGroup {
+			continue
+		}
+		key = pairKey(srcGroup, dstGroup)
+		gEdge, ok := groupEdges[key]
+		if !ok {
+			gEdge = &model.TopologyLayoutEdge{Source: key[0], Target: key[1]}
+			groupEdges[key] = gEdge
+			groupEdgeOrder = append(groupEdgeOrder, key)
+		}
+		gEdge.Count++
+	}
+	for _, key := range deviceEdgeOrder {
+		layout.Edges = append(layout.Edges, *deviceEdges[key])
+	}
+	for _, key := range groupEdgeOrder {
+		layout.GroupEdges = append(layout.GroupEdges, *groupEdges[key])
+	}
+
+	for _, group := range layout.Groups {
+		for i, n := range members[group.ID] {
+			r := nodeSpacing * math.Sqrt(float64(i)+0.5)
+			theta := float64(i) * goldenAngle
+			layout.Nodes = append(layout.Nodes, model.TopologyLayoutNode{
+				ID:     n.EntityID,
+				Label:  n.Label,
+				Group:  group.ID,
+				X:      round(group.X + r*math.Cos(theta)),
+				Y:      round(group.Y + r*math.Sin(theta)),
+				Degree: degree[n.EntityID],
+			})
+		}
+	}
+
+	return layout, nil
+}
+
+// groupRadius returns the radius of a cluster holding n nodes
+func groupRadius(n int) float64 {
+	return round(nodeSpacing*math.Sqrt(float64(n)) + groupPadding)
+}
+
+// packGroups places groups left to right in rows of roughly square overall
+// shape and returns the resulting width and height
+func packGroups(groups []model.TopologyGroup) (float64, float64) {
+	if len(groups) == 0 {
+		return 0, 0
+	}
+
+	var area, widest float64
+	for _, g := range groups {
+		d := 2*g.Radius + groupGap
+		area += d * d
+		widest = math.Max(widest, d)
+	}
+	rowWidth := math.Max(math.Sqrt(area), widest)
+
+	var x, y, rowHeight, width float64
+	for i := range groups {
+		d := 2*groups[i].Radius + groupGap
+		if x > 0 && x+d > rowWidth {
+			y += rowHeight
+			x, rowHeight = 0, 0
+		}
+		groups[i].X = round(x + d/2)
+		groups[i].Y = round(y + d/2)
+		x += d
+		rowHeight = math.Max(rowHeight, d)
+		width = math.Max(width, x)
+	}
+
+	return round(width), round(y + rowHeight)
+}
+
+// pairKey returns an order-independent key for an undirected edge
+func pairKey(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
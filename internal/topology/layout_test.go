@@ -0,0 +1,121 @@
+package topology
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+func TestLayout(t *testing.T) {
+	s := setupTestStorage(t)
+	if err := s.AddRelationship("db", "web", "connected_to"); err != nil {
+		t.Fatalf("Failed to add relationship: %v", err)
+	}
+
+	g, err := Build(s, nil)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	t.Run("Datacenter", func(t *testing.T) {
+		layout, err := Layout(g, model.TopologyGroupByDatacenter)
+		if err != nil {
+			t.Fatalf("Layout failed: %v", err)
+		}
+
+		if len(layout.Nodes) != 3 {
+			t.Fatalf("Expected 3 device nodes, got %d", len(layout.Nodes))
+		}
+		groups := make(map[string]string)
+		for _, n := range layout.Nodes {
+			groups[n.ID] = n.Group
+		}
+		if groups["web"] != "dc-a" || groups["db"] != "dc-a" || groups["lab"] != "dc-b" {
+			t.Errorf("Unexpected grouping: %v", groups)
+		}
+
+		// web->db depends_on and db->web connected_to collapse into one edge
+		var webDB *model.TopologyLayoutEdge
+		for i, e := range layout.Edges {
+			if pairKey(e.Source, e.Target) == pairKey("web", "db") {
+				webDB = &layout.Edges[i]
+			}
+		}
+		if webDB == nil || webDB.Count != 2 || webDB.Types["depends_on"] != 1 || webDB.Types["connected_to"] != 1 {
+			t.Errorf("Expected aggregated web/db edge, got %+v", webDB)
+		}
+
+		if len(layout.GroupEdges) != 1 || layout.GroupEdges[0].Count != 1 {
+			t.Errorf("Expected a single dc-a/dc-b group edge, got %+v", layout.GroupEdges)
+		}
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		layout, err := Layout(g, model.TopologyGroupByNetwork)
+		if err != nil {
+			t.Fatalf("Layout failed: %v", err)
+		}
+		for _, n := range layout.Nodes {
+			want := UngroupedID
+			if n.ID == "web" {
+				want = "net-a"
+			}
+			if n.Group != want {
+				t.Errorf("Expected %s in group %s, got %s", n.ID, want, n.Group)
+			}
+		}
+	})
+
+	t.Run("InvalidGroupBy", func(t *testing.T) {
+		if _, err := Layout(g, "rack"); err == nil {
+			t.Errorf("Expected error for unsupported group_by")
+		}
+	})
+}
+
+func TestLayout_Large(t *testing.T) {
+	g := &model.Topology{}
+	for dc := 0; dc < 10; dc++ {
+		g.Nodes = append(g.Nodes, model.TopologyNode{ID: NodeID(model.TopologyNodeDatacenter, fmt.Sprint(dc)), Type: model.TopologyNodeDatacenter, EntityID: fmt.Sprint(dc)})
+	}
+	for i := 0; i < 5000; i++ {
+		id := fmt.Sprintf("d%d", i)
+		g.Nodes = append(g.Nodes, model.TopologyNode{ID: NodeID(model.TopologyNodeDevice, id), Type: model.TopologyNodeDevice, EntityID: id})
+		g.Edges = append(g.Edges, model.TopologyEdge{Source: NodeID(model.TopologyNodeDevice, id), Target: NodeID(model.TopologyNodeDatacenter, fmt.Sprint(i%10)), Type: model.TopologyEdgeLocatedIn})
+		if i > 0 {
+			g.Edges = append(g.Edges, model.TopologyEdge{Source: NodeID(model.TopologyNodeDevice, id), Target: NodeID(model.TopologyNodeDevice, fmt.Sprintf("d%d", i-1)), Type: "connected_to"})
+		}
+	}
+
+	layout, err := Layout(g, model.TopologyGroupByDatacenter)
+	if err != nil {
+		t.Fatalf("Layout failed: %v", err)
+	}
+	if len(layout.Nodes) != 5000 || len(layout.Groups) != 10 {
+		t.Fatalf("Expected 5000 nodes in 10 groups, got %d in %d", len(layout.Nodes), len(layout.Groups))
+	}
+
+	// Groups must not overlap
+	for i := range layout.Groups {
+		for j := i + 1; j < len(layout.Groups); j++ {
+			a, b := layout.Groups[i], layout.Groups[j]
+			if math.Hypot(a.X-b.X, a.Y-b.Y) < a.Radius+b.Radius {
+				t.Errorf("Groups %s and %s overlap", a.ID, b.ID)
+			}
+		}
+	}
+
+	// Every device sits inside its group circle
+	groups := make(map[string]model.TopologyGroup)
+	for _, grp := range layout.Groups {
+		groups[grp.ID] = grp
+	}
+	for _, n := range layout.Nodes {
+		grp := groups[n.Group]
+		if math.Hypot(n.X-grp.X, n.Y-grp.Y) > grp.Radius {
+			t.Fatalf("Node %s lies outside group %s", n.ID, grp.ID)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package topology
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// NodeID returns the graph node ID for an entity of the given node type
+func NodeID(nodeType, entityID string) string {
+	return nodeType + ":" + entityID
+}
+
+// Build assembles a topology graph from the storage backend.
+// Datacenters, networks and relationships are only included when the
+// backend supports them.
+func Build(s storage.Storage, filter *model.TopologyFilter) (*model.Topology, error) {
+	if filter == nil {
+		filter = &model.TopologyFilter{}
+	}
+
+	b := &builder{
+		graph: &model.Topology{Nodes: []model.TopologyNode{}, Edges: []model.TopologyEdge{}},
+		nodes: make(map[string]bool),
+	}
+
+	dcStorage, hasDatacenters := s.(storage.DatacenterStorage)
+	netStorage, hasNetworks := s.(storage.NetworkStorage)
+	relStorage, hasRelationships := s.(storage.RelationshipStorage)
+
+	// Resolve the datacenter filter first so names can be used as well as IDs
+	datacenterID := filter.DatacenterID
+	if datacenterID != "" && hasDatacenters {
+		dc, err := dcStorage.GetDatacenter(datacenterID)
+		if err != nil {
+			return nil, err
+		}
+		datacenterID = dc.ID
+	}
+
+	devices, err := s.ListDevices(&model.DeviceFilter{Tags: filter.Tags})
+	if err != nil {
+		return nil, err
+	}
+	if datacenterID != "" {
+		filtered := devices[:0]
+		for _, d := range devices {
+			if d.DatacenterID == datacenterID {
+				filtered = append(filtered, d)
+			}
+		}
+		devices = filtered
+	}
+
+	// With a tag filter only the datacenters and networks the matching
+	// devices actually use are part of the graph
+	restrictToDevices := len(filter.Tags) > 0
+	usedDatacenters := make(map[string]bool)
+	usedNetworks := make(map[string]bool)
+	for _, d := range devices {
+		if d.DatacenterID != "" {
+			usedDatacenters[d.DatacenterID] = true
+		}
+		for _, addr := range d.Addresses {
+			if addr.NetworkID != "" {
+				usedNetworks[addr.NetworkID] = true
+			}
+		}
+	}
+
+	if hasDatacenters {
+		datacenters, err := dcStorage.ListDatacenters(nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, dc := range datacenters {
+			if datacenterID != "" && dc.ID != datacenterID {
+				continue
+			}
+			if restrictToDevices && !usedDatacenters[dc.ID] {
+				continue
+			}
+			b.addNode(model.TopologyNodeDatacenter, dc.ID, dc.Name, map[string]string{
+				"location": dc.Location,
+			})
+		}
+	}
+
+	if hasNetworks {
+		networks, err := netStorage.ListNetworks(&model.NetworkFilter{DatacenterID: datacenterID})
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range networks {
+			if restrictToDevices && !usedNetworks[n.ID] {
+				continue
+			}
+			b.addNode(model.TopologyNodeNetwork, n.ID, n.Name, map[string]string{
+				"subnet": n.Subnet,
+			})
+			b.addEdge(NodeID(model.TopologyNodeNetwork, n.ID), NodeID(model.TopologyNodeDatacenter, n.DatacenterID), model.TopologyEdgeLocatedIn)
+		}
+	}
+
+	for _, d := range devices {
+		var ips []string
+		for _, addr := range d.Addresses {
+			ips = append(ips, addr.IP)
+		}
+		b.addNode(model.TopologyNodeDevice, d.ID, d.Name, map[string]string{
+			"make_model": d.MakeModel,
+			"os":         d.OS,
+			"location":   d.Location,
+			"tags":       strings.Join(d.Tags, ","),
+			"addresses":  strings.Join(ips, ","),
+		})
+
+		deviceNode := NodeID(model.TopologyNodeDevice, d.ID)
+		b.addEdge(deviceNode, NodeID(model.TopologyNodeDatacenter, d.DatacenterID), model.TopologyEdgeLocatedIn)
+
+		seen := make(map[string]bool)
+		for _, addr := range d.Addresses {
+			if addr.NetworkID == "" || seen[addr.NetworkID] {
+				continue
+			}
+			seen[addr.NetworkID] = true
+			b.addEdge(deviceNode, NodeID(model.TopologyNodeNetwork, addr.NetworkID), model.TopologyEdgeAttached)
+		}
+	}
+
+	if hasRelationships {
+		relationships, err := relStorage.ListRelationships("")
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range relationships {
+			if len(filter.RelationshipTypes) > 0 && !contains(filter.RelationshipTypes, rel.Type) {
+				continue
+			}
+			b.addEdge(NodeID(model.TopologyNodeDevice, rel.ParentID), NodeID(model.TopologyNodeDevice, rel.ChildID), rel.Type)
+		}
+	}
+
+	return b.graph, nil
+}
+
+// builder accumulates nodes and edges, dropping edges to nodes outside the graph
+type builder struct {
+	graph *model.Topology
+	nodes map[string]bool
+}
+
+func (b *builder) addNode(nodeType, entityID, label string, attrs map[string]string) {
+	id := NodeID(nodeType, entityID)
+	if b.nodes[id] {
+		return
+	}
+	b.nodes[id] = true
+
+	// Drop empty attributes to keep the exported formats compact
+	for k, v := range attrs {
+		if v == "" {
+			delete(attrs, k)
+		}
+	}
+	if len(attrs) == 0 {
+		attrs = nil
+	}
+
+	b.graph.Nodes = append(b.graph.Nodes, model.TopologyNode{
+		ID:         id,
+		Type:       nodeType,
+		EntityID:   entityID,
+		Label:      label,
+		Attributes: attrs,
+	})
+}
+
+// addEdge must be called after both endpoints have been added
+func (b *builder) addEdge(source, target, edgeType string) {
+	if !b.nodes[source] || !b.nodes[target] {
+		return
+	}
+	b.graph.Edges = append(b.graph.Edges, model.TopologyEdge{
+		Source: source,
+		Target: target,
+		Type:   edgeType,
+	})
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeKeys returns the sorted set of attribute keys used by the graph
+func attributeKeys(g *model.Topology) []string {
+	set := make(map[string]bool)
+	for _, n := range g.Nodes {
+		for k := range n.Attributes {
+			set[k] = true
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,181 @@
+package topology
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+func setupTestStorage(t *testing.T) *storage.SQLiteStorage {
+	t.Helper()
+	s, err := storage.NewSQLiteStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	dcs := []*model.Datacenter{
+		{ID: "dc-a", Name: "DC A"},
+		{ID: "dc-b", Name: "DC B"},
+	}
+	for _, dc := range dcs {
+		if err := s.CreateDatacenter(dc); err != nil {
+			t.Fatalf("Failed to create datacenter: %v", err)
+		}
+	}
+
+	if err := s.CreateNetwork(&model.Network{ID: "net-a", Name: "Net A", Subnet: "10.0.0.0/24", DatacenterID: "dc-a"}); err != nil {
+		t.Fatalf("Failed to create network: %v", err)
+	}
+
+	devices := []*model.Device{
+		{ID: "web", Name: "web", DatacenterID: "dc-a", Tags: []string{"prod"},
+			Addresses: []model.Address{{IP: "10.0.0.10", NetworkID: "net-a"}}},
+		{ID: "db", Name: "db", DatacenterID: "dc-a", Tags: []string{"prod"}},
+		{ID: "lab", Name: "lab", DatacenterID: "dc-b", Tags: []string{"lab"}},
+	}
+	for _, d := range devices {
+		if err := s.CreateDevice(d); err != nil {
+			t.Fatalf("Failed to create device: %v", err)
+		}
+	}
+
+	if err := s.AddRelationship("web", "db", "depends_on"); err != nil {
+		t.Fatalf("Failed to add relationship: %v", err)
+	}
+	if err := s.AddRelationship("web", "lab", "connected_to"); err != nil {
+		t.Fatalf("Failed to add relationship: %v", err)
+	}
+
+	return s
+}
+
+func edgeSet(g *model.Topology) map[string]bool {
+	set := make(map[string]bool)
+	for _, e := range g.Edges {
+		set[e.Source+" "+e.Type+" "+e.Target] = true
+	}
+	return set
+}
+
+func TestBuild(t *testing.T) {
+	s := setupTestStorage(t)
+
+	t.Run("All", func(t *testing.T) {
+		g, err := Build(s, nil)
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+
+		// default datacenter from schema.sql plus two created ones, one network, three devices
+		if len(g.Nodes) != 7 {
+			t.Errorf("Expected 7 nodes, got %d", len(g.Nodes))
+		}
+
+		edges := edgeSet(g)
+		for _, want := range []string{
+			"network:net-a located_in datacenter:dc-a",
+			"device:web located_in datacenter:dc-a",
+			"device:web attached_to network:net-a",
+			"device:web depends_on device:db",
+			"device:web connected_to device:lab",
+		} {
+			if !edges[want] {
+				t.Errorf("Missing edge %q", want)
+			}
+		}
+	})
+
+	t.Run("Datacenter", func(t *testing.T) {
+		g, err := Build(s, &model.TopologyFilter{DatacenterID: "DC A"})
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		for _, n := range g.Nodes {
+			if n.ID == "device:lab" || n.ID == "datacenter:dc-b" {
+				t.Errorf("Unexpected node %s outside datacenter filter", n.ID)
+			}
+		}
+		if edgeSet(g)["device:web connected_to device:lab"] {
+			t.Errorf("Expected edge to filtered-out device to be dropped")
+		}
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		g, err := Build(s, &model.TopologyFilter{Tags: []string{"lab"}})
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if len(g.Nodes) != 2 {
+			t.Errorf("Expected lab device and its datacenter, got %d nodes", len(g.Nodes))
+		}
+	})
+
+	t.Run("RelationshipTypes", func(t *testing.T) {
+		g, err := Build(s, &model.TopologyFilter{RelationshipTypes: []string{"depends_on"}})
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		edges := edgeSet(g)
+		if !edges["device:web depends_on device:db"] || edges["device:web connected_to device:lab"] {
+			t.Errorf("Relationship type filter not applied: %v", edges)
+		}
+	})
+
+	t.Run("UnknownDatacenter", func(t *testing.T) {
+		if _, err := Build(s, &model.TopologyFilter{DatacenterID: "missing"}); err == nil {
+			t.Errorf("Expected error for unknown datacenter")
+		}
+	})
+}
+
+func TestEncode(t *testing.T) {
+	g := &model.Topology{
+		Nodes: []model.TopologyNode{
+			{ID: "device:a", Type: model.TopologyNodeDevice, EntityID: "a", Label: `say "hi"`, Attributes: map[string]string{"os": "linux"}},
+			{ID: "device:b", Type: model.TopologyNodeDevice, EntityID: "b", Label: "b"},
+		},
+		Edges: []model.TopologyEdge{{Source: "device:a", Target: "device:b", Type: "depends_on"}},
+	}
+
+	t.Run("DOT", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Encode(&buf, g, FormatDOT); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		out := buf.String()
+		if !strings.Contains(out, `label="say \"hi\""`) {
+			t.Errorf("Expected escaped label, got %s", out)
+		}
+		if !strings.Contains(out, `"device:a" -> "device:b" [label="depends_on"];`) {
+			t.Errorf("Expected edge line, got %s", out)
+		}
+	})
+
+	t.Run("GraphML", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Encode(&buf, g, FormatGraphML); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		var doc graphML
+		if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("Invalid GraphML: %v", err)
+		}
+		if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 1 {
+			t.Errorf("Expected 2 nodes and 1 edge, got %d and %d", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+		}
+		if !strings.Contains(buf.String(), `<key id="attr_os" for="node" attr.name="os" attr.type="string">`) {
+			t.Errorf("Expected attribute key declaration, got %s", buf.String())
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		if err := Encode(&bytes.Buffer{}, g, "png"); err == nil {
+			t.Errorf("Expected error for unsupported format")
+		}
+	})
+}
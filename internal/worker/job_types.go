@@ -0,0 +1,281 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/martinsuchenak/rackd/internal/backup"
+	"github.com/martinsuchenak/rackd/internal/importer"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+// Reports generated by report jobs
+const (
+	ReportAssets             = "assets"
+	ReportExpiringWarranties = "expiring_warranties"
+)
+
+// defaultReportWarrantyDays is the look-ahead window of the expiring warranty report
+const defaultReportWarrantyDays = 90
+
+// CleanupStorage interface for the storage operations of cleanup jobs
+type CleanupStorage interface {
+	CleanupOldDevices(olderThanDays int) (int, error)
+}
+
+// CleanupResult is the result of a cleanup job
+type CleanupResult struct {
+	OlderThanDays int `json:"older_than_days"`
+	Removed       int `json:"removed"`
+}
+
+// ReportResult is the result of a report job
+type ReportResult struct {
+	Report      string              `json:"report"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Count       int                 `json:"count"`
+	Assets      []model.DeviceAsset `json:"assets"`
+}
+
+// CleanupJob returns the handler of cleanup jobs, which remove the discovered
+// devices that have not been seen for older_than_days, defaulting to days
+func CleanupJob(s CleanupStorage, days int) JobHandler {
+	return func(ctx context.Context, run *JobRun) (interface{}, error) {
+		params := struct {
+			OlderThanDays int `json:"older_than_days"`
+		}{OlderThanDays: days}
+		if err := run.Params(&params); err != nil {
+			return nil, err
+		}
+		if params.OlderThanDays <= 0 {
+			return nil, Permanent(errors.New("older_than_days must be positive"))
+		}
+
+		removed, err := s.CleanupOldDevices(params.OlderThanDays)
+		if err != nil {
+			return nil, err
+		}
+		run.Logf("Removed %d discovered devices not seen for %d days", removed, params.OlderThanDays)
+		return &CleanupResult{OlderThanDays: params.OlderThanDays, Removed: removed}, nil
+	}
+}
+
+// BackupJob returns the handler of backup jobs, which take a database backup
+func BackupJob(m *backup.Manager) JobHandler {
+	return func(ctx context.Context, run *JobRun) (interface{}, error) {
+		b, err := m.Create()
+		if err != nil {
+			return nil, err
+		}
+		run.Logf("Created backup %s", b.Name)
+		return b, nil
+	}
+}
+
+// DiscoveryJob returns the handler of discovery jobs, which run a scan of
+// network_id with scan_type and wait for it to end. The scan is checkpointed,
+// so an attempt interrupted by a restart follows the resumed scan instead of
+// starting another.
+func DiscoveryJob(scans *ScanManager) JobHandler {
+	return func(ctx context.Context, run *JobRun) (interface{}, error) {
+		var params struct {
+			NetworkID string `json:"network_id"`
+			ScanType  string `json:"scan_type"`
+		}
+		if err := run.Params(&params); err != nil {
+			return nil, err
+		}
+		if params.NetworkID == "" {
+			return nil, Permanent(errors.New("network_id is required"))
+		}
+		if params.ScanType == "" {
+			params.ScanType = "full"
+		}
+
+		var scan *model.DiscoveryScan
+		var previous model.DiscoveryScan
+		if len(run.Job.Result) > 0 && json.Unmarshal(run.Job.Result, &previous) == nil && previous.ID != "" {
+			if s, err := scans.storage.GetDiscoveryScan(previous.ID); err == nil && !scanEnded(s) {
+				scan = s
+				run.Logf("Following discovery scan %s", scan.ID)
+			}
+		}
+		if scan == nil {
+			scan = &model.DiscoveryScan{
+				ID:        uuid.NewString(),
+				NetworkID: params.NetworkID,
+				ScanType:  params.ScanType,
+			}
+		}
+
+		// Subscribe before submitting or reading the scan, so its end is not missed
+		events, unsubscribe := scans.Subscribe(scan.ID)
+		defer unsubscribe()
+
+		if scan.Status == "" {
+			if err := scans.Submit(scan, nil); err != nil {
+				return nil, err
+			}
+			if err := run.Checkpoint(scan); err != nil {
+				return nil, err
+			}
+			run.Logf("Started %s discovery scan %s of network %s", scan.ScanType, scan.ID, scan.NetworkID)
+		}
+
+		for {
+			current, err := scans.storage.GetDiscoveryScan(scan.ID)
+			if err != nil {
+				return nil, err
+			}
+			if scanEnded(current) {
+				return scanOutcome(run, current)
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case _, ok := <-events:
+				if !ok {
+					// The scan manager stopped; the scan resumes with it
+					if current, err = scans.storage.GetDiscoveryScan(scan.ID); err == nil && scanEnded(current) {
+						return scanOutcome(run, current)
+					}
+					return nil, fmt.Errorf("discovery scan %s stopped before it ended", scan.ID)
+				}
+			}
+		}
+	}
+}
+
+// scanEnded reports whether a scan reached a final status
+func scanEnded(scan *model.DiscoveryScan) bool {
+	switch scan.Status {
+	case "completed", "failed", "cancelled", "interrupted":
+		return true
+	}
+	return false
+}
+
+// scanOutcome returns the result of a discovery job from the final state of its scan
+func scanOutcome(run *JobRun, scan *model.DiscoveryScan) (interface{}, error) {
+	switch scan.Status {
+	case "completed":
+		run.Logf("Discovery scan %s completed, %d hosts found", scan.ID, scan.FoundHosts)
+		return scan, nil
+	case "cancelled":
+		return scan, Permanent(fmt.Errorf("discovery scan %s was cancelled", scan.ID))
+	}
+
+	// Keep the scan out of the checkpoint, so the next attempt scans again
+	run.Job.Result = nil
+	if scan.ErrorMessage != "" {
+		return nil, fmt.Errorf("discovery scan %s %s: %s", scan.ID, scan.Status, scan.ErrorMessage)
+	}
+	return nil, fmt.Errorf("discovery scan %s %s", scan.ID, scan.Status)
+}
+
+// ReportJob returns the handler of report jobs, which list the hardware assets
+// of all devices, or those whose warranty expires within days
+func ReportJob(s storage.AssetStorage) JobHandler {
+	return func(ctx context.Context, run *JobRun) (interface{}, error) {
+		params := struct {
+			Report         string `json:"report"`
+			Days           int    `json:"days"`
+			IncludeExpired bool   `json:"include_expired"`
+		}{Report: ReportAssets, Days: defaultReportWarrantyDays}
+		if err := run.Params(&params); err != nil {
+			return nil, err
+		}
+
+		filter := &model.AssetFilter{}
+		switch params.Report {
+		case ReportAssets:
+		case ReportExpiringWarranties:
+			if params.Days < 0 {
+				return nil, Permanent(errors.New("days must not be negative"))
+			}
+			today := time.Now().UTC()
+			filter.WarrantyExpiresBefore = today.AddDate(0, 0, params.Days).Format("2006-01-02")
+			if !params.IncludeExpired {
+				filter.WarrantyExpiresAfter = today.Format("2006-01-02")
+			}
+		default:
+			return nil, Permanent(fmt.Errorf("unknown report %q, expected %s or %s", params.Report, ReportAssets, ReportExpiringWarranties))
+		}
+
+		assets, err := s.ListAssets(filter)
+		if err != nil {
+			return nil, err
+		}
+		run.Logf("Generated %s report with %d assets", params.Report, len(assets))
+		return &ReportResult{Report: params.Report, GeneratedAt: time.Now(), Count: len(assets), Assets: assets}, nil
+	}
+}
+
+// ImportJob returns the handler of import jobs, which import data given as
+// text in the job parameters, as POST /api/import does. Imports are not
+// retried: a batch that fails to import fails the same way again.
+func ImportJob(s storage.ImportStorage) JobHandler {
+	return func(ctx context.Context, run *JobRun) (interface{}, error) {
+		var params struct {
+			Format  string   `json:"format"`
+			Entity  string   `json:"entity"`
+			Mapping []string `json:"mapping"`
+			Data    string   `json:"data"`
+			DryRun  bool     `json:"dry_run"`
+		}
+		if err := run.Params(&params); err != nil {
+			return nil, err
+		}
+		if params.Data == "" {
+			return nil, Permanent(errors.New("data is required"))
+		}
+
+		format := importer.FormatJSON
+		if params.Format != "" {
+			var err error
+			if format, err = importer.ParseFormat(params.Format); err != nil {
+				return nil, Permanent(err)
+			}
+		}
+		mapping, err := importer.ParseMapping(params.Mapping)
+		if err != nil {
+			return nil, Permanent(err)
+		}
+
+		batch, err := importer.Parse(strings.NewReader(params.Data), importer.Options{
+			Format:  format,
+			Entity:  params.Entity,
+			Mapping: mapping,
+		})
+		if err != nil {
+			return nil, Permanent(err)
+		}
+
+		result, err := s.Import(batch, params.DryRun)
+		if err != nil {
+			if errors.Is(err, storage.ErrInvalidImport) {
+				return nil, Permanent(err)
+			}
+			return nil, err
+		}
+
+		run.Logf("Imported %d rows: %d created, %d updated, %d unchanged, %d failed",
+			batch.Len(), result.Created, result.Updated, result.Unchanged, result.Failed)
+		if result.Failed > 0 {
+			// Keep the row errors with the failed job
+			if err := run.Checkpoint(result); err != nil {
+				return nil, err
+			}
+			return nil, Permanent(fmt.Errorf("%d of %d rows failed to import", result.Failed, batch.Len()))
+		}
+		return result, nil
+	}
+}
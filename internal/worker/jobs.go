@@ -0,0 +1,580 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/storage"
+	"github.com/martinsuchenak/rackd/pkg/discovery"
+)
+
+var (
+	// ErrUnknownJobType is returned when enqueuing a job of a type without a handler
+	ErrUnknownJobType = errors.New("unknown job type")
+	// ErrJobNotRetryable is returned when retrying a job that has not failed
+	ErrJobNotRetryable = errors.New("only failed jobs can be retried")
+)
+
+const (
+	// DefaultJobBackoff is the delay before the first retry of a failed job; it
+	// doubles with every further attempt
+	DefaultJobBackoff = 30 * time.Second
+
+	// maxJobBackoff caps the delay between attempts
+	maxJobBackoff = time.Hour
+	// jobPollInterval is how often pending jobs are looked for when nothing
+	// else wakes the dispatcher, picking up jobs stored by other means
+	jobPollInterval = time.Minute
+	// maxJobLogEntries bounds the log kept with a job, dropping the oldest lines
+	maxJobLogEntries = 200
+)
+
+// JobHandler runs a job. The value it returns is stored as the result of the
+// job. Errors are retried with backoff until the job runs out of attempts,
+// unless they are wrapped with Permanent.
+type JobHandler func(ctx context.Context, run *JobRun) (interface{}, error)
+
+// jobType is a registered job handler
+type jobType struct {
+	handler     JobHandler
+	maxAttempts int
+}
+
+// JobManager runs background jobs on a WorkerPool. Jobs are stored with their
+// status, attempts, logs and results, so they survive restarts and stay
+// available as history once finished. Each job is claimed with a lease before
+// it runs, so instances sharing the database never run a job at once.
+type JobManager struct {
+	mu      sync.Mutex
+	types   map[string]jobType
+	active  map[string]bool // jobs handed to the pool and not finished
+	running bool
+	wake    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	pool    *WorkerPool
+
+	workers int
+	backoff time.Duration
+
+	// Dependencies
+	storage storage.JobStorage
+}
+
+// NewJobManager creates a job manager running at most workers jobs at once.
+// A failed job is retried after backoff, doubling with every attempt.
+func NewJobManager(storage storage.JobStorage, workers int, backoff time.Duration) *JobManager {
+	if workers <= 0 {
+		workers = 1
+	}
+	if backoff <= 0 {
+		backoff = DefaultJobBackoff
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobManager{
+		types:   make(map[string]jobType),
+		active:  make(map[string]bool),
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		workers: workers,
+		backoff: backoff,
+		storage: storage,
+	}
+}
+
+// Register sets the handler of a job type, and how many times a job of the
+// type is attempted before it fails
+func (m *JobManager) Register(name string, handler JobHandler, maxAttempts int) {
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.types[name] = jobType{handler: handler, maxAttempts: maxAttempts}
+}
+
+// Start starts running jobs. Jobs a previous run of the server left running
+// are queued again once no instance holds their lease.
+func (m *JobManager) Start() {
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = true
+	m.pool = NewWorkerPool(m.workers)
+	m.pool.Start()
+	m.mu.Unlock()
+
+	m.recoverJobs()
+
+	m.wg.Add(1)
+	go m.dispatch()
+}
+
+// recoverJobs queues again the running jobs that no instance holds the lease
+// of, left behind by a server that stopped without finishing them
+func (m *JobManager) recoverJobs() {
+	jobs, err := m.storage.ListJobs(&model.JobFilter{Status: model.JobStatusRunning})
+	if err != nil {
+		log.Error("Failed to load unfinished jobs", "error", err)
+		return
+	}
+	for i := range jobs {
+		m.mu.Lock()
+		active := m.active[jobs[i].ID]
+		m.mu.Unlock()
+		if active {
+			continue
+		}
+
+		release := claim(m.storage, "job:"+jobs[i].ID)
+		if release == nil {
+			continue
+		}
+		// The job may have finished since it was listed
+		job, err := m.storage.GetJob(jobs[i].ID)
+		if err == nil && job.Status == model.JobStatusRunning {
+			log.Warn("Job interrupted by restart, queuing it again", "job_id", job.ID, "type", job.Type)
+			m.requeue(job, "Interrupted by a server restart")
+		}
+		release()
+	}
+}
+
+// Stop stops dispatching jobs, cancels the running ones and waits for them to
+// return. Cancelled jobs are left pending, so the next Start runs them again.
+func (m *JobManager) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	m.mu.Unlock()
+
+	m.cancel()
+	m.wg.Wait()
+	m.pool.Stop()
+}
+
+// Enqueue stores a pending job of the given type, with params encoded as its
+// parameters, and wakes the dispatcher
+func (m *JobManager) Enqueue(name string, params interface{}) (*model.Job, error) {
+	m.mu.Lock()
+	jt, ok := m.types[name]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownJobType, name)
+	}
+
+	job := &model.Job{
+		Type:        name,
+		Status:      model.JobStatusPending,
+		MaxAttempts: jt.maxAttempts,
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("encoding job parameters: %w", err)
+		}
+		if string(data) != "null" {
+			job.Params = data
+		}
+	}
+	if err := m.storage.CreateJob(job); err != nil {
+		return nil, err
+	}
+
+	log.Info("Job queued", "job_id", job.ID, "type", job.Type)
+	m.notify()
+	return job, nil
+}
+
+// Retry queues a failed job again with a fresh set of attempts
+func (m *JobManager) Retry(id string) (*model.Job, error) {
+	job, err := m.storage.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != model.JobStatusFailed {
+		return nil, fmt.Errorf("%w: job is %s", ErrJobNotRetryable, job.Status)
+	}
+
+	job.Attempts = 0
+	job.FinishedAt = nil
+	if err := m.requeue(job, "Retry requested"); err != nil {
+		return nil, err
+	}
+
+	log.Info("Job retry requested", "job_id", job.ID, "type", job.Type)
+	m.notify()
+	return job, nil
+}
+
+// Task returns a recurring scheduler task that enqueues a job of the given
+// type every interval. A run is skipped while a job of the type is still
+// pending or running, so a backlog does not build up behind a failing job,
+// and instances sharing the database queue one job between them.
+func (m *JobManager) Task(id, name, jobType string, interval time.Duration, params interface{}) *discovery.Task {
+	return &discovery.Task{
+		ID:       id,
+		Name:     name,
+		Type:     "recurring",
+		Interval: int(interval.Seconds()),
+		Status:   "pending",
+		Handler: func(ctx context.Context, taskID string) error {
+			// Instances sharing the database schedule the task alike; the
+			// one holding its lease checks for and queues the job
+			release := claim(m.storage, "task:"+taskID)
+			if release == nil {
+				return nil
+			}
+			defer release()
+
+			for _, status := range []string{model.JobStatusPending, model.JobStatusRunning} {
+				jobs, err := m.storage.ListJobs(&model.JobFilter{Type: jobType, Status: status, Limit: 1})
+				if err != nil {
+					return err
+				}
+				if len(jobs) > 0 {
+					log.Info("Skipping scheduled job, one is already queued", "task_id", taskID, "job_id", jobs[0].ID)
+					return nil
+				}
+			}
+			_, err := m.Enqueue(jobType, params)
+			return err
+		},
+	}
+}
+
+// notify wakes the dispatcher
+func (m *JobManager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch hands due jobs to the pool until the manager stops. It wakes when
+// a job is queued or finishes, when the next job backing off is due, and at
+// least every jobPollInterval, when it also recovers the jobs of instances
+// that stopped.
+func (m *JobManager) dispatch() {
+	defer m.wg.Done()
+
+	recovered := time.Now()
+	for {
+		if time.Since(recovered) >= jobPollInterval {
+			m.recoverJobs()
+			recovered = time.Now()
+		}
+
+		wait := jobPollInterval
+		if next := m.dispatchDue(); !next.IsZero() {
+			if d := time.Until(next); d < wait {
+				wait = d
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-m.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// dispatchDue submits the due pending jobs to the pool, oldest first and no
+// more than there are free workers, and returns when the next job backing off
+// is due, or the zero time if none is
+func (m *JobManager) dispatchDue() time.Time {
+	jobs, err := m.storage.ListJobs(&model.JobFilter{Status: model.JobStatusPending})
+	if err != nil {
+		log.Error("Failed to load pending jobs", "error", err)
+		return time.Time{}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].RunAt.Equal(jobs[j].RunAt) {
+			return jobs[i].RunAt.Before(jobs[j].RunAt)
+		}
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	now := time.Now()
+	var next time.Time
+	for i := range jobs {
+		job := &jobs[i]
+		if job.RunAt.After(now) {
+			if next.IsZero() || job.RunAt.Before(next) {
+				next = job.RunAt
+			}
+			continue
+		}
+
+		m.mu.Lock()
+		if m.active[job.ID] {
+			m.mu.Unlock()
+			continue
+		}
+		if len(m.active) >= m.workers {
+			// A finishing job wakes the dispatcher again
+			m.mu.Unlock()
+			break
+		}
+		m.active[job.ID] = true
+		m.mu.Unlock()
+
+		claimed, release := m.claimJob(job.ID, now)
+		if claimed == nil {
+			m.mu.Lock()
+			delete(m.active, job.ID)
+			m.mu.Unlock()
+			continue
+		}
+
+		err := m.pool.Submit(Job{
+			ID: job.ID,
+			Handler: func(ctx context.Context) error {
+				m.run(ctx, claimed, release)
+				return nil
+			},
+		})
+		if err != nil {
+			release()
+			m.mu.Lock()
+			delete(m.active, job.ID)
+			m.mu.Unlock()
+			return next
+		}
+	}
+	return next
+}
+
+// claimJob takes the lease of a due pending job and returns the job as stored
+// with the function releasing the lease, or nil when another instance holds
+// the job or has run it since it was listed
+func (m *JobManager) claimJob(id string, now time.Time) (*model.Job, func()) {
+	release := claim(m.storage, "job:"+id)
+	if release == nil {
+		return nil, nil
+	}
+	job, err := m.storage.GetJob(id)
+	if err != nil {
+		log.Error("Failed to load job", "job_id", id, "error", err)
+	}
+	if err != nil || job.Status != model.JobStatusPending || job.RunAt.After(now) {
+		release()
+		return nil, nil
+	}
+	return job, release
+}
+
+// run runs a job and records its outcome: completed, failed, or pending again
+// for another attempt after the backoff. The lease of the job is released once
+// the outcome is stored.
+func (m *JobManager) run(ctx context.Context, job *model.Job, release func()) {
+	defer func() {
+		release()
+		m.mu.Lock()
+		delete(m.active, job.ID)
+		m.mu.Unlock()
+		m.notify()
+	}()
+
+	m.mu.Lock()
+	jt, ok := m.types[job.Type]
+	m.mu.Unlock()
+
+	now := time.Now()
+	job.Status = model.JobStatusRunning
+	job.Attempts++
+	job.StartedAt = &now
+	job.FinishedAt = nil
+	job.Error = ""
+
+	run := &JobRun{Job: job, manager: m}
+	if !ok {
+		m.finish(run, nil, Permanent(fmt.Errorf("%w %q", ErrUnknownJobType, job.Type)))
+		return
+	}
+
+	log.Info("Running job", "job_id", job.ID, "type", job.Type, "attempt", job.Attempts)
+	run.Logf("Attempt %d of %d started", job.Attempts, job.MaxAttempts)
+
+	result, err := callJob(ctx, jt.handler, run)
+
+	// A job cut short by a shutdown did not fail; it runs again on the next start
+	if err != nil && ctx.Err() != nil {
+		job.Attempts--
+		if err := m.requeue(job, "Interrupted by shutdown"); err != nil {
+			log.Error("Failed to save interrupted job", "job_id", job.ID, "error", err)
+		}
+		return
+	}
+	m.finish(run, result, err)
+}
+
+// callJob calls handler, turning a panic into an error
+func callJob(ctx context.Context, handler JobHandler, run *JobRun) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, run)
+}
+
+// finish records the outcome of an attempt
+func (m *JobManager) finish(run *JobRun, result interface{}, err error) {
+	job := run.Job
+	now := time.Now()
+
+	switch {
+	case err == nil:
+		if result != nil {
+			data, merr := json.Marshal(result)
+			if merr != nil {
+				log.Error("Failed to encode job result", "job_id", job.ID, "error", merr)
+			} else {
+				job.Result = data
+			}
+		}
+		job.Status = model.JobStatusCompleted
+		job.FinishedAt = &now
+		run.addLog("Completed")
+		log.Info("Job completed", "job_id", job.ID, "type", job.Type)
+
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		job.Status = model.JobStatusFailed
+		job.Error = err.Error()
+		job.FinishedAt = &now
+		run.addLog("Failed: " + err.Error())
+		log.Error("Job failed", "job_id", job.ID, "type", job.Type, "attempts", job.Attempts, "error", err)
+
+	default:
+		delay := m.backoffFor(job.Attempts)
+		job.Status = model.JobStatusPending
+		job.Error = err.Error()
+		job.RunAt = now.Add(delay)
+		run.addLog(fmt.Sprintf("Attempt failed: %v; retrying in %s", err, delay))
+		log.Warn("Job attempt failed, retrying", "job_id", job.ID, "type", job.Type, "attempt", job.Attempts, "retry_in", delay, "error", err)
+	}
+
+	if err := m.storage.UpdateJob(job); err != nil {
+		log.Error("Failed to save job", "job_id", job.ID, "error", err)
+	}
+}
+
+// requeue stores job as pending and due now, with a line in its log
+func (m *JobManager) requeue(job *model.Job, message string) error {
+	job.Status = model.JobStatusPending
+	job.RunAt = time.Now()
+	job.Error = ""
+	(&JobRun{Job: job}).addLog(message)
+	if err := m.storage.UpdateJob(job); err != nil {
+		log.Error("Failed to queue job", "job_id", job.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// backoffFor returns the delay after the given failed attempt
+func (m *JobManager) backoffFor(attempt int) time.Duration {
+	delay := m.backoff
+	for i := 1; i < attempt && delay < maxJobBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxJobBackoff {
+		delay = maxJobBackoff
+	}
+	return delay
+}
+
+// JobRun is a job being run, handed to its handler
+type JobRun struct {
+	Job *model.Job
+
+	manager *JobManager
+}
+
+// Params decodes the parameters of the job into v. Invalid parameters fail
+// the job without further attempts.
+func (r *JobRun) Params(v interface{}) error {
+	if len(r.Job.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Job.Params, v); err != nil {
+		return Permanent(fmt.Errorf("invalid job parameters: %w", err))
+	}
+	return nil
+}
+
+// Logf adds a line to the log of the job and saves it
+func (r *JobRun) Logf(format string, args ...interface{}) {
+	r.addLog(fmt.Sprintf(format, args...))
+	r.save()
+}
+
+// Checkpoint saves v as the partial result of the job. An attempt that does
+// not complete leaves it in Job.Result for the next one.
+func (r *JobRun) Checkpoint(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding job checkpoint: %w", err)
+	}
+	r.Job.Result = data
+	r.save()
+	return nil
+}
+
+// addLog adds a line to the log of the job without saving it
+func (r *JobRun) addLog(message string) {
+	r.Job.Logs = append(r.Job.Logs, model.JobLogEntry{Time: time.Now(), Message: message})
+	if n := len(r.Job.Logs) - maxJobLogEntries; n > 0 {
+		r.Job.Logs = r.Job.Logs[n:]
+	}
+}
+
+// save stores the progress of the job
+func (r *JobRun) save() {
+	if err := r.manager.storage.UpdateJob(r.Job); err != nil {
+		log.Error("Failed to save job", "job_id", r.Job.ID, "error", err)
+	}
+}
+
+// permanentError is an error that retrying does not fix
+type permanentError struct {
+	err error
+}
+
+// Permanent marks err as one that fails a job straight away, without the
+// remaining attempts
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// isPermanent reports whether err was marked with Permanent
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
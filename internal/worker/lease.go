@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/storage"
+)
+
+const (
+	// leaseTTL is how long a lease lasts without being renewed. Work held by an
+	// instance that stopped without releasing it is taken over once it expires.
+	leaseTTL = 2 * time.Minute
+	// leaseRenewInterval is how often the lease of running work is renewed
+	leaseRenewInterval = 30 * time.Second
+)
+
+// instanceID owns the leases taken by this process
+var instanceID = uuid.NewString()
+
+// claim takes lease name on store before work starts, so that of the rackd
+// instances sharing the database only one runs it. It returns a function
+// releasing the lease once the work is done, renewing it until then, or nil
+// when another instance holds the lease. A store without leases runs all work
+// it is given.
+func claim(store interface{}, name string) func() {
+	leases, ok := store.(storage.LeaseStorage)
+	if !ok {
+		return func() {}
+	}
+
+	acquired, err := leases.AcquireLease(name, instanceID, leaseTTL)
+	if err != nil {
+		log.Error("Failed to acquire lease", "lease", name, "error", err)
+		return nil
+	}
+	if !acquired {
+		return nil
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(leaseRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if ok, err := leases.AcquireLease(name, instanceID, leaseTTL); err != nil || !ok {
+					log.Warn("Failed to renew lease", "lease", name, "error", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-stopped
+			if err := leases.ReleaseLease(name, instanceID); err != nil {
+				log.Warn("Failed to release lease", "lease", name, "error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,606 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/martinsuchenak/rackd/internal/events"
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/scandiff"
+	"github.com/martinsuchenak/rackd/pkg/discovery"
+)
+
+var (
+	// ErrScanNotActive is returned when cancelling a scan that is not pending or running
+	ErrScanNotActive = errors.New("scan is not pending or running")
+	// ErrScanManagerStopped is returned when submitting a scan to a stopped manager
+	ErrScanManagerStopped = errors.New("scan manager is not running")
+
+	// errShutdown is the cancellation cause of scans stopped by a server shutdown
+	errShutdown = errors.New("server shutting down")
+)
+
+// scanRecoverInterval is how often the scans of instances that stopped are
+// looked for
+const scanRecoverInterval = time.Minute
+
+// Event types published while a scan runs
+const (
+	EventProgress = "progress" // the scan with updated counts
+	EventFound    = "found"    // a discovered device seen for the first time
+	EventUpdated  = "updated"  // a discovered device seen before
+	EventComplete = "complete" // the scan in its final state
+)
+
+// ScanStorage interface for the storage operations of the scan manager
+type ScanStorage interface {
+	ListDiscoveryScans(networkID string) ([]model.DiscoveryScan, error)
+	GetDiscoveryScan(id string) (*model.DiscoveryScan, error)
+	CreateDiscoveryScan(scan *model.DiscoveryScan) error
+	UpdateDiscoveryScan(scan *model.DiscoveryScan) error
+	GetDiscoveryScanHosts(scanID string) (map[string]bool, error)
+	MarkDiscoveryScanHost(scanID, ip string, found bool) error
+	DeleteDiscoveryScanHosts(scanID string) error
+	GetDiscoveryRule(id string) (*model.DiscoveryRule, error)
+	RecordDiscoveryObservation(obs *model.DiscoveryObservation) error
+	ListDiscoveryObservations(scanID string) ([]model.DiscoveryObservation, error)
+	ListDiscoveredDevices(filter *model.DiscoveredDeviceFilter) ([]model.DiscoveredDevice, error)
+}
+
+// ScanManager runs discovery scans in the background. It owns the context of
+// every scan so scans can be cancelled, limits how many run at once, and picks
+// up the scans a previous run of the server left unfinished. Each scan is
+// claimed with a lease while it runs, so instances sharing the database never
+// run a scan at once.
+type ScanManager struct {
+	mu      sync.Mutex
+	jobs    map[string]*scanJob
+	running bool
+	stop    chan struct{}
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	events  *events.Bus
+
+	// recovering is held by Submit and recover, so a scan being submitted is
+	// not picked up as one left unfinished
+	recovering sync.Mutex
+
+	// completed runs with the network of every scan that completes
+	completed func(networkID string)
+
+	// Dependencies
+	storage ScanStorage
+	scanner discovery.Scanner
+}
+
+// scanJob is a pending or running scan
+type scanJob struct {
+	scan    *model.DiscoveryScan
+	rule    *model.DiscoveryRule // nil until the scan starts, when resumed or one-time
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	done    chan struct{}
+	release func() // releases the lease of the scan
+
+	// started is when this run of the scan started
+	started time.Time
+
+	// progress is the latest known state of the running scan
+	mu       sync.Mutex
+	progress model.DiscoveryScan
+}
+
+// NewScanManager creates a scan manager running at most maxConcurrent scans at once
+func NewScanManager(storage ScanStorage, scanner discovery.Scanner, maxConcurrent int) *ScanManager {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &ScanManager{
+		jobs:    make(map[string]*scanJob),
+		sem:     make(chan struct{}, maxConcurrent),
+		events:  events.NewBus(),
+		storage: storage,
+		scanner: scanner,
+	}
+}
+
+// Start starts accepting scans and picks up the scans left pending or running
+// by a previous run of the server, and every scanRecoverInterval those of other
+// instances that stopped.
+func (m *ScanManager) Start() {
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = true
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.mu.Unlock()
+
+	m.recover()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(scanRecoverInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				m.recover()
+			}
+		}
+	}()
+}
+
+// recover picks up the scans left pending or running that no instance holds
+// the lease of. They are resumed when the scanner supports it and marked
+// interrupted otherwise.
+func (m *ScanManager) recover() {
+	m.recovering.Lock()
+	defer m.recovering.Unlock()
+
+	scans, err := m.storage.ListDiscoveryScans("")
+	if err != nil {
+		log.Error("Failed to load unfinished discovery scans", "error", err)
+		return
+	}
+
+	_, resumable := m.scanner.(discovery.ResumableScanner)
+	for i := range scans {
+		if !scanUnfinished(&scans[i]) {
+			continue
+		}
+		m.mu.Lock()
+		_, active := m.jobs[scans[i].ID]
+		m.mu.Unlock()
+		if active {
+			continue
+		}
+
+		release := claim(m.storage, "scan:"+scans[i].ID)
+		if release == nil {
+			continue
+		}
+		// The scan may have ended since it was listed
+		scan, err := m.storage.GetDiscoveryScan(scans[i].ID)
+		if err != nil || !scanUnfinished(scan) {
+			release()
+			continue
+		}
+
+		if resumable {
+			log.Info("Resuming discovery scan", "scan_id", scan.ID, "network_id", scan.NetworkID)
+			if err := m.enqueue(scan, nil, release); err != nil {
+				log.Error("Failed to resume discovery scan", "scan_id", scan.ID, "error", err)
+				release()
+			}
+			continue
+		}
+
+		log.Warn("Discovery scan interrupted by restart", "scan_id", scan.ID, "network_id", scan.NetworkID)
+		m.finish(scan.ID, "interrupted", "interrupted by a server restart")
+		release()
+	}
+}
+
+// scanUnfinished reports whether a scan is pending or running
+func scanUnfinished(scan *model.DiscoveryScan) bool {
+	return scan.Status == "pending" || scan.Status == "running"
+}
+
+// Stop stops all scans and waits for them to return. Their status is left as
+// it is, so the next Start resumes them.
+func (m *ScanManager) Stop() {
+	m.mu.Lock()
+	if m.running {
+		close(m.stop)
+	}
+	m.running = false
+	for _, job := range m.jobs {
+		job.cancel(errShutdown)
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// Submit stores scan as pending and queues it. It starts once fewer than the
+// maximum number of scans are running. The scan runs with the settings of
+// rule, or those of its scan type when rule is nil.
+func (m *ScanManager) Submit(scan *model.DiscoveryScan, rule *model.DiscoveryRule) error {
+	m.mu.Lock()
+	running := m.running
+	m.mu.Unlock()
+	if !running {
+		return ErrScanManagerStopped
+	}
+
+	if rule != nil {
+		scan.RuleID = rule.ID
+		scan.NetworkID = rule.NetworkID
+		if scan.ScanType == "" {
+			scan.ScanType = rule.ScanType
+		}
+	}
+	if scan.ScanType == "" {
+		scan.ScanType = "full"
+	}
+	if scan.ID == "" {
+		scan.ID = uuid.NewString()
+	}
+
+	m.recovering.Lock()
+	defer m.recovering.Unlock()
+
+	release := claim(m.storage, "scan:"+scan.ID)
+	if release == nil {
+		return fmt.Errorf("discovery scan %s is claimed by another instance", scan.ID)
+	}
+	now := time.Now()
+	scan.Status = "pending"
+	scan.CreatedAt = now
+	scan.UpdatedAt = now
+	if err := m.storage.CreateDiscoveryScan(scan); err != nil {
+		release()
+		return err
+	}
+	if err := m.enqueue(scan, rule, release); err != nil {
+		release()
+		return err
+	}
+	return nil
+}
+
+// ProbeNames returns the probes of the scanner that discovery rules turn on or
+// off, and false when the scanner does not run probes
+func (m *ScanManager) ProbeNames() ([]string, bool) {
+	scanner, ok := m.scanner.(discovery.ProbeScanner)
+	if !ok {
+		return nil, false
+	}
+	return scanner.ProbeNames(), true
+}
+
+// OnCompleted sets a function run with the network of every scan that
+// completes, such as the auto-promotion policies
+func (m *ScanManager) OnCompleted(fn func(networkID string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.completed = fn
+}
+
+// Subscribe returns a channel receiving the events of scan id and a function
+// ending the subscription. The channel is closed when the scan ends or is
+// stopped for shutdown.
+func (m *ScanManager) Subscribe(id string) (<-chan events.Event, func()) {
+	return m.events.Subscribe(id)
+}
+
+// Cancel stops a pending or running scan and waits until it is marked cancelled
+func (m *ScanManager) Cancel(id string) error {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return ErrScanNotActive
+	}
+
+	log.Info("Cancelling discovery scan", "scan_id", id)
+	job.cancel(nil)
+	<-job.done
+	return nil
+}
+
+// enqueue starts a goroutine that runs the scan once a slot is free, releasing
+// the lease of the scan when it ends
+func (m *ScanManager) enqueue(scan *model.DiscoveryScan, rule *model.DiscoveryRule, release func()) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.running {
+		return ErrScanManagerStopped
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	job := &scanJob{scan: scan, rule: rule, ctx: ctx, cancel: cancel, done: make(chan struct{}), release: release}
+	m.jobs[scan.ID] = job
+
+	m.wg.Add(1)
+	go m.run(job)
+	return nil
+}
+
+// run waits for a free slot, runs the scan and records how it ended
+func (m *ScanManager) run(job *scanJob) {
+	defer m.wg.Done()
+	defer close(job.done)
+	defer func() {
+		job.release()
+		m.mu.Lock()
+		delete(m.jobs, job.scan.ID)
+		m.mu.Unlock()
+		job.cancel(nil)
+		m.events.Close(job.scan.ID)
+	}()
+
+	var err error
+	select {
+	case m.sem <- struct{}{}:
+		if job.ctx.Err() == nil {
+			err = m.scan(job)
+		}
+		<-m.sem
+	case <-job.ctx.Done():
+	}
+
+	switch {
+	case errors.Is(context.Cause(job.ctx), errShutdown):
+		log.Info("Discovery scan stopped for shutdown", "scan_id", job.scan.ID)
+	case job.ctx.Err() != nil:
+		log.Info("Discovery scan cancelled", "scan_id", job.scan.ID)
+		m.finish(job.scan.ID, "cancelled", "")
+	case err != nil:
+		log.Error("Discovery scan failed", "scan_id", job.scan.ID, "error", err)
+		m.finish(job.scan.ID, "failed", err.Error())
+	default:
+		// The scanner has reported the scan completed
+		if err := m.storage.DeleteDiscoveryScanHosts(job.scan.ID); err != nil {
+			log.Warn("Failed to delete discovery scan progress", "scan_id", job.scan.ID, "error", err)
+		}
+		if scan, err := m.storage.GetDiscoveryScan(job.scan.ID); err == nil {
+			m.events.Publish(scan.ID, events.Event{Type: EventComplete, Data: scan})
+		}
+		m.mu.Lock()
+		completed := m.completed
+		m.mu.Unlock()
+		if completed != nil {
+			completed(job.scan.NetworkID)
+		}
+	}
+}
+
+// scan runs the scanner, resuming from the hosts an earlier run finished
+func (m *ScanManager) scan(job *scanJob) error {
+	if m.scanner == nil {
+		return errors.New("no discovery scanner configured")
+	}
+
+	rule, err := m.rule(job)
+	if err != nil {
+		return err
+	}
+	job.started = time.Now()
+	log.Info("Discovery scan started", "scan_id", job.scan.ID, "network_id", job.scan.NetworkID, "scan_type", job.scan.ScanType)
+
+	resumable, ok := m.scanner.(discovery.ResumableScanner)
+	if !ok {
+		return m.scanner.ScanNetwork(job.ctx, job.scan.NetworkID, rule, m.updateFunc(job, 0))
+	}
+
+	done, err := m.storage.GetDiscoveryScanHosts(job.scan.ID)
+	if err != nil {
+		return err
+	}
+	found := 0
+	for _, f := range done {
+		if f {
+			found++
+		}
+	}
+	progress := &scanProgress{manager: m, job: job, done: done}
+	return resumable.ResumeNetwork(job.ctx, job.scan.NetworkID, rule, progress, m.updateFunc(job, found))
+}
+
+// rule returns the settings a scan runs with: the rule it was submitted with,
+// the stored rule of a resumed scheduled scan, or for a one-time scan those of
+// its scan type
+func (m *ScanManager) rule(job *scanJob) (*model.DiscoveryRule, error) {
+	if job.rule != nil {
+		return job.rule, nil
+	}
+	if job.scan.RuleID != "" {
+		rule, err := m.storage.GetDiscoveryRule(job.scan.RuleID)
+		if err != nil {
+			return nil, fmt.Errorf("loading discovery rule %s: %w", job.scan.RuleID, err)
+		}
+		return rule, nil
+	}
+
+	scanType := job.scan.ScanType
+	return &model.DiscoveryRule{
+		NetworkID:        job.scan.NetworkID,
+		ScanType:         scanType,
+		TimeoutSeconds:   5,
+		ScanPorts:        scanType != "quick",
+		ServiceDetection: scanType != "quick",
+		OSDetection:      scanType == "deep",
+	}, nil
+}
+
+// updateFunc returns the scanner callback storing and publishing the progress
+// of a scan, adding the devices found by earlier runs of the scan
+func (m *ScanManager) updateFunc(job *scanJob, found int) func(*model.DiscoveryScan) {
+	return func(update *model.DiscoveryScan) {
+		scan := *update
+		scan.ID = job.scan.ID
+		scan.FoundHosts += found
+		if scan.Status == "completed" {
+			// All hosts are observed, so the scan is compared before it is stored as completed
+			m.observeScanned(job)
+			scan.Changes = m.changes(job.scan)
+		}
+		if err := m.storage.UpdateDiscoveryScan(&scan); err != nil {
+			log.Warn("Failed to update discovery scan", "scan_id", scan.ID, "error", err)
+		}
+
+		// The scanner stores progress in batches; the snapshot is also advanced per host
+		job.mu.Lock()
+		job.progress = scan
+		job.mu.Unlock()
+		if scan.Status == "running" {
+			m.events.Publish(scan.ID, events.Event{Type: EventProgress, Data: &scan})
+		}
+	}
+}
+
+// observeScanned records an observation of each device the scan saw that it
+// has none of yet. Resumable scanners report each host as they finish it, other
+// scanners only store the devices; either way a completed scan is observed in
+// full.
+func (m *ScanManager) observeScanned(job *scanJob) {
+	id := job.scan.ID
+	observed, err := m.storage.ListDiscoveryObservations(id)
+	if err != nil {
+		log.Warn("Failed to load discovery observations", "scan_id", id, "error", err)
+		return
+	}
+	seen := make(map[string]bool, len(observed))
+	for _, obs := range observed {
+		seen[obs.IP] = true
+	}
+
+	devices, err := m.storage.ListDiscoveredDevices(&model.DiscoveredDeviceFilter{NetworkID: job.scan.NetworkID})
+	if err != nil {
+		log.Warn("Failed to load discovered devices", "scan_id", id, "error", err)
+		return
+	}
+	// Stored times may have lost their sub-second part
+	since := job.started.Truncate(time.Second)
+	for i := range devices {
+		device := &devices[i]
+		if seen[device.IP] || device.LastSeen.Before(since) {
+			continue
+		}
+		if err := m.storage.RecordDiscoveryObservation(observation(id, device)); err != nil {
+			log.Warn("Failed to record discovery observation", "scan_id", id, "ip", device.IP, "error", err)
+		}
+	}
+}
+
+// observation returns what scan scanID saw of device
+func observation(scanID string, device *model.DiscoveredDevice) *model.DiscoveryObservation {
+	return &model.DiscoveryObservation{
+		ScanID:     scanID,
+		IP:         device.IP,
+		Status:     device.Status,
+		MACAddress: device.MACAddress,
+		Hostname:   device.Hostname,
+		OSGuess:    device.OSGuess,
+		OpenPorts:  device.OpenPorts,
+	}
+}
+
+// changes compares a scan that completed with the previous completed scan of
+// its network, or returns nil when they cannot be compared
+func (m *ScanManager) changes(scan *model.DiscoveryScan) *model.DiscoveryChangeSummary {
+	// The submitted scan may lack its creation time, the stored one has it
+	stored, err := m.storage.GetDiscoveryScan(scan.ID)
+	if err != nil {
+		log.Warn("Failed to load discovery scan for comparison", "scan_id", scan.ID, "error", err)
+		return nil
+	}
+	diff, err := scandiff.ForScan(m.storage, stored)
+	if err != nil {
+		log.Warn("Failed to compare discovery scan", "scan_id", scan.ID, "error", err)
+		return nil
+	}
+
+	summary := diff.Summary
+	if summary.PreviousScanID != "" {
+		log.Info("Discovery scan changes", "scan_id", scan.ID, "previous_scan_id", summary.PreviousScanID,
+			"new_hosts", summary.NewHosts, "disappeared_hosts", summary.DisappearedHosts, "changed_hosts", summary.ChangedHosts,
+			"opened_ports", summary.OpenedPorts, "closed_ports", summary.ClosedPorts)
+	}
+	return &summary
+}
+
+// finish marks a scan as ended with status, drops its host progress and
+// publishes the final state
+func (m *ScanManager) finish(id, status, message string) {
+	scan, err := m.storage.GetDiscoveryScan(id)
+	if err != nil {
+		log.Error("Failed to load discovery scan", "scan_id", id, "error", err)
+		return
+	}
+
+	now := time.Now()
+	scan.Status = status
+	scan.ErrorMessage = message
+	scan.CompletedAt = &now
+	if scan.StartedAt != nil {
+		scan.DurationSeconds = int(now.Sub(*scan.StartedAt).Seconds())
+	}
+	if err := m.storage.UpdateDiscoveryScan(scan); err != nil {
+		log.Error("Failed to update discovery scan", "scan_id", id, "error", err)
+	}
+	if err := m.storage.DeleteDiscoveryScanHosts(id); err != nil {
+		log.Warn("Failed to delete discovery scan progress", "scan_id", id, "error", err)
+	}
+	m.events.Publish(id, events.Event{Type: EventComplete, Data: scan})
+}
+
+// scanProgress persists the hosts a scan has finished and publishes them
+type scanProgress struct {
+	manager *ScanManager
+	job     *scanJob
+	done    map[string]bool
+}
+
+// Done reports whether an earlier run of the scan finished the host
+func (p *scanProgress) Done(ip string) bool {
+	_, ok := p.done[ip]
+	return ok
+}
+
+// MarkDone records that the scan finished the host and publishes the device
+// found on it along with the updated progress
+func (p *scanProgress) MarkDone(ip string, device *model.DiscoveredDevice) {
+	id := p.job.scan.ID
+	if device != nil {
+		// Observed before the host is marked done, so a resumed scan has it
+		obs := observation(id, device)
+		obs.IP = ip
+		if err := p.manager.storage.RecordDiscoveryObservation(obs); err != nil {
+			log.Warn("Failed to record discovery observation", "scan_id", id, "ip", ip, "error", err)
+		}
+	}
+	if err := p.manager.storage.MarkDiscoveryScanHost(id, ip, device != nil); err != nil {
+		log.Warn("Failed to record discovery scan progress", "scan_id", id, "ip", ip, "error", err)
+	}
+
+	if device != nil {
+		// A stored device that was new has the same first seen and update time
+		eventType := EventUpdated
+		if device.FirstSeen.Equal(device.UpdatedAt) {
+			eventType = EventFound
+		}
+		p.manager.events.Publish(id, events.Event{Type: eventType, Data: device})
+	}
+
+	p.job.mu.Lock()
+	scan := p.job.progress
+	if scan.ID == "" {
+		p.job.mu.Unlock()
+		return
+	}
+	scan.ScannedHosts++
+	if device != nil {
+		scan.FoundHosts++
+	}
+	if scan.TotalHosts > 0 {
+		scan.ProgressPercent = float64(scan.ScannedHosts) / float64(scan.TotalHosts) * 100
+	}
+	p.job.progress = scan
+	p.job.mu.Unlock()
+	p.manager.events.Publish(id, events.Event{Type: EventProgress, Data: &scan})
+}
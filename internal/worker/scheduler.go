@@ -2,24 +2,38 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/martinsuchenak/rackd/pkg/discovery"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/internal/schedule"
+	"github.com/martinsuchenak/rackd/internal/storage"
 )
 
 // Compile-time interface check to ensure Scheduler implements discovery.Scheduler
 var _ discovery.Scheduler = (*Scheduler)(nil)
 
+// discoveryTaskPrefix prefixes the IDs of the tasks scanning for discovery rules
+const discoveryTaskPrefix = "discovery-"
+
 // DiscoveryStorage interface for storage operations
 type DiscoveryStorage interface {
 	ListDiscoveryRules(networkID string) ([]model.DiscoveryRule, error)
 	GetDiscoveryRule(id string) (*model.DiscoveryRule, error)
+	SetDiscoveryRuleSchedule(id string, lastRunAt, nextRunAt *time.Time) error
 }
 
-// Scheduler manages background tasks
+// Scheduler manages background tasks. Instances sharing the database all
+// schedule the discovery rules, and the first to claim a due run scans it.
 // Implements discovery.Scheduler interface
 type Scheduler struct {
 	mu      sync.RWMutex
@@ -31,7 +45,7 @@ type Scheduler struct {
 
 	// Dependencies
 	storage DiscoveryStorage
-	scanner discovery.Scanner
+	scans   *ScanManager
 }
 
 // Task represents a scheduled or running task
@@ -40,17 +54,37 @@ type Task struct {
 	Name        string
 	Type        string // "recurring", "oneshot"
 	Interval    time.Duration
+	Schedule    string // cron expression, instead of the interval
 	NextRun     time.Time
 	LastRun     *time.Time
 	Status      string // "pending", "running", "completed", "failed"
 	Handler     TaskHandler
+
+	// Discovery rule tasks: when the rule is due, the settings it was planned
+	// from and when the task was first scheduled
+	plan    *schedule.Plan
+	planned string
+	since   time.Time
+}
+
+// TaskInfo describes a task, as listed by Tasks
+type TaskInfo struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	IntervalSeconds int64      `json:"interval_seconds"`
+	Schedule        string     `json:"schedule,omitempty"`
+	NextRun         time.Time  `json:"next_run"`
+	LastRun         *time.Time `json:"last_run,omitempty"`
+	Status          string     `json:"status"`
 }
 
 // TaskHandler is the function executed by a task
 type TaskHandler func(ctx context.Context, taskID string) error
 
-// NewScheduler creates a new scheduler
-func NewScheduler(storage DiscoveryStorage, scanner discovery.Scanner) *Scheduler {
+// NewScheduler creates a new scheduler. Discovery rules that fall due are
+// submitted to scans.
+func NewScheduler(storage DiscoveryStorage, scans *ScanManager) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		tasks:   make(map[string]*Task),
@@ -58,7 +92,7 @@ func NewScheduler(storage DiscoveryStorage, scanner discovery.Scanner) *Schedule
 		ctx:     ctx,
 		cancel:  cancel,
 		storage: storage,
-		scanner: scanner,
+		scans:   scans,
 	}
 }
 
@@ -74,12 +108,14 @@ func (s *Scheduler) Start() {
 	s.running = true
 	log.Info("Starting background scheduler")
 
+	// Schedule the discovery rules before the first tick, and run those that
+	// fell due while the server was down
+	s.syncRules()
+	s.runDueTasks()
+
 	// Start scheduler goroutine
 	s.wg.Add(1)
 	go s.run()
-
-	// Load and register recurring tasks from database
-	s.loadRecurringTasks()
 }
 
 // Stop gracefully stops the scheduler
@@ -94,7 +130,11 @@ func (s *Scheduler) Stop() {
 	log.Info("Stopping background scheduler")
 	s.cancel()
 	s.running = false
+	s.mu.Unlock()
+
+	// Running tasks take the lock when they finish
 	s.wg.Wait()
+	s.mu.Lock()
 }
 
 // run is the main scheduler loop
@@ -109,6 +149,7 @@ func (s *Scheduler) run() {
 		case <-s.ctx.Done():
 			return
 		case <-ticker.C:
+			s.Reload()
 			s.checkAndRunTasks()
 		}
 	}
@@ -151,6 +192,44 @@ func (s *Scheduler) RegisterTask(task *discovery.Task) error {
 	return nil
 }
 
+// Tasks returns the registered tasks, ordered by their next run
+func (s *Scheduler) Tasks() []TaskInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := make([]TaskInfo, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, TaskInfo{
+			ID:              task.ID,
+			Name:            task.Name,
+			Type:            task.Type,
+			IntervalSeconds: int64(task.Interval / time.Second),
+			Schedule:        task.Schedule,
+			NextRun:         task.NextRun,
+			LastRun:         task.LastRun,
+			Status:          task.Status,
+		})
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].NextRun.Equal(tasks[j].NextRun) {
+			return tasks[i].NextRun.Before(tasks[j].NextRun)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks
+}
+
+// Reload brings the discovery tasks in line with the discovery rules in
+// storage. The discovery API calls it after every change to a rule, and it
+// runs on every tick to pick up changes made otherwise, such as by another
+// instance sharing the database.
+func (s *Scheduler) Reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.syncRules()
+}
+
 // registerTaskInternal registers an internal task (for backward compatibility)
 func (s *Scheduler) registerTaskInternal(task *Task) error {
 	s.mu.Lock()
@@ -166,28 +245,83 @@ func (s *Scheduler) checkAndRunTasks() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.runDueTasks()
+}
+
+// runDueTasks starts the tasks that are due. Caller must hold s.mu.
+func (s *Scheduler) runDueTasks() {
 	now := time.Now()
 
 	for _, task := range s.tasks {
-		if task.Status == "running" {
+		// A zero next run is a schedule that never comes round
+		if task.Status == "running" || task.NextRun.IsZero() {
 			continue
 		}
 
 		if now.After(task.NextRun) || now.Equal(task.NextRun) {
-			s.runTask(task)
+			// A run due during a blackout waits for the next allowed time
+			if task.plan != nil {
+				if _, blocked := task.plan.Blackout(now); blocked {
+					task.NextRun = task.plan.Allowed(now)
+					log.Info("Task deferred by blackout", "task_id", task.ID, "next_run", task.NextRun)
+					s.saveSchedule(task)
+					continue
+				}
+			}
+			release := s.claimRun(task, now)
+			if release == nil {
+				continue
+			}
+			s.runTask(task, release)
 		}
 	}
 }
 
-// runTask executes a task in a goroutine
-func (s *Scheduler) runTask(task *Task) {
+// claimRun takes the lease of a due discovery task, so that of the instances
+// sharing the database one scans the rule. Once claimed the rule is read
+// again: when another instance has run it meanwhile, the task takes the
+// schedule stored with the rule instead. It returns the function releasing the
+// lease, or nil when the task does not run now. Caller must hold s.mu.
+func (s *Scheduler) claimRun(task *Task, now time.Time) func() {
+	if s.storage == nil || !strings.HasPrefix(task.ID, discoveryTaskPrefix) {
+		return func() {}
+	}
+
+	release := claim(s.storage, "task:"+task.ID)
+	if release == nil {
+		return nil
+	}
+	rule, err := s.storage.GetDiscoveryRule(strings.TrimPrefix(task.ID, discoveryTaskPrefix))
+	if err != nil {
+		log.Error("Failed to load discovery rule", "task_id", task.ID, "error", err)
+		release()
+		return nil
+	}
+	if rule.NextRunAt != nil && rule.NextRunAt.After(now) {
+		task.NextRun = *rule.NextRunAt
+		task.LastRun = rule.LastRunAt
+		log.Info("Discovery task ran on another instance", "task_id", task.ID, "next_run", task.NextRun)
+		release()
+		return nil
+	}
+	return release
+}
+
+// runTask executes a task in a goroutine, calling release once the run has
+// ended and the next one is scheduled
+func (s *Scheduler) runTask(task *Task, release func()) {
 	task.Status = "running"
 	now := time.Now()
 	task.LastRun = &now
 
+	// The due time is kept until the run ends, so a run cut short by a
+	// restart is repeated rather than skipped
+	s.saveSchedule(task)
+
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
+		defer release()
 
 		log.Info("Running task", "task_id", task.ID, "name", task.Name)
 
@@ -206,41 +340,174 @@ func (s *Scheduler) runTask(task *Task) {
 
 		// Schedule next run for recurring tasks
 		if task.Type == "recurring" {
-			task.NextRun = time.Now().Add(task.Interval)
+			if task.plan != nil {
+				task.NextRun = task.plan.Next(time.Now())
+			} else {
+				task.NextRun = time.Now().Add(task.Interval)
+			}
+			s.saveSchedule(task)
 		}
 	}()
 }
 
-// loadRecurringTasks loads discovery rules and creates tasks
-func (s *Scheduler) loadRecurringTasks() {
+// syncRules schedules a task for each enabled discovery rule, follows changes
+// to their schedules and drops the tasks of rules that were disabled or
+// deleted. A task resumes from the run times stored with its rule, so a
+// restart does not push the next scan back by a full interval. Caller must
+// hold s.mu; a scheduler without storage only runs tasks registered with
+// RegisterTask.
+func (s *Scheduler) syncRules() {
+	if s.storage == nil {
+		return
+	}
+
 	rules, err := s.storage.ListDiscoveryRules("")
 	if err != nil {
 		log.Error("Failed to load discovery rules", "error", err)
 		return
 	}
 
+	now := time.Now()
+	scheduled := make(map[string]bool)
 	for _, rule := range rules {
-		if !rule.Enabled {
+		if !rule.Enabled || (rule.Schedule == "" && rule.ScanIntervalHours <= 0) {
+			continue
+		}
+
+		id := discoveryTaskPrefix + rule.ID
+		plan, err := schedule.ForRule(&rule)
+		if err != nil {
+			log.Error("Invalid discovery rule schedule", "rule_id", rule.ID, "error", err)
+			continue
+		}
+		scheduled[id] = true
+		planned := plannedSettings(&rule)
+
+		task, ok := s.tasks[id]
+		if !ok {
+			task = &Task{
+				ID:      id,
+				Name:    "Discovery scan for network " + rule.NetworkID,
+				Type:    "recurring",
+				LastRun: rule.LastRunAt,
+				Status:  "pending",
+				since:   now,
+			}
+			s.tasks[id] = task
+		} else if task.planned == planned {
+			// The next run uses the current settings of the rule
+			task.Handler = s.createDiscoveryHandler(rule)
+			continue
+		}
+
+		task.Interval = time.Duration(rule.ScanIntervalHours) * time.Hour
+		task.Schedule = rule.Schedule
+		task.Handler = s.createDiscoveryHandler(rule)
+		task.plan = plan
+
+		switch {
+		case task.planned == "" && rule.NextRunAt != nil:
+			// Resume where the schedule was before a restart
+			task.NextRun = *rule.NextRunAt
+		case task.LastRun != nil:
+			task.NextRun = plan.Next(*task.LastRun)
+		default:
+			task.NextRun = plan.Next(task.since)
+		}
+
+		if task.planned == "" {
+			log.Info("Scheduled discovery task", "task_id", id, "network_id", rule.NetworkID, "next_run", task.NextRun)
+		} else {
+			log.Info("Rescheduled discovery task", "task_id", id, "network_id", rule.NetworkID, "next_run", task.NextRun)
+		}
+		task.planned = planned
+
+		if task.Status != "running" && (rule.NextRunAt == nil || !rule.NextRunAt.Equal(task.NextRun)) {
+			s.saveSchedule(task)
+		}
+	}
+
+	for id, task := range s.tasks {
+		if !strings.HasPrefix(id, discoveryTaskPrefix) || scheduled[id] {
 			continue
 		}
+		delete(s.tasks, id)
+		log.Info("Unscheduled discovery task", "task_id", id)
 
-		task := &Task{
-			ID:       "discovery-" + rule.ID,
-			Name:     "Discovery scan for network " + rule.NetworkID,
-			Type:     "recurring",
-			Interval: time.Duration(rule.ScanIntervalHours) * time.Hour,
-			NextRun:  time.Now().Add(time.Duration(rule.ScanIntervalHours) * time.Hour),
-			Handler:  s.createDiscoveryHandler(rule),
+		// A rule that is enabled again is due one interval after its last run
+		ruleID := strings.TrimPrefix(id, discoveryTaskPrefix)
+		if err := s.storage.SetDiscoveryRuleSchedule(ruleID, task.LastRun, nil); err != nil && !errors.Is(err, storage.ErrDiscoveryRuleNotFound) {
+			log.Error("Failed to clear discovery rule schedule", "rule_id", ruleID, "error", err)
 		}
+	}
+}
+
+// plannedSettings returns the settings of a rule its schedule depends on, to
+// tell when a task has to be planned again
+func plannedSettings(rule *model.DiscoveryRule) string {
+	b, _ := json.Marshal([]interface{}{rule.ScanIntervalHours, rule.Schedule, rule.Timezone, rule.JitterMinutes, rule.Blackouts})
+	return string(b)
+}
+
+// saveSchedule stores the run times of a discovery task with its rule. Tasks
+// that are not for a rule, or no longer scheduled, are left alone. Caller must
+// hold s.mu.
+func (s *Scheduler) saveSchedule(task *Task) {
+	if s.storage == nil || !strings.HasPrefix(task.ID, discoveryTaskPrefix) || s.tasks[task.ID] != task {
+		return
+	}
 
-		s.tasks[task.ID] = task
-		log.Info("Loaded discovery task", "task_id", task.ID, "network_id", rule.NetworkID)
+	ruleID := strings.TrimPrefix(task.ID, discoveryTaskPrefix)
+	nextRun := task.NextRun
+	if err := s.storage.SetDiscoveryRuleSchedule(ruleID, task.LastRun, &nextRun); err != nil {
+		log.Error("Failed to save discovery rule schedule", "rule_id", ruleID, "error", err)
 	}
 }
 
-// createDiscoveryHandler creates a handler for discovery scans
+// createDiscoveryHandler creates a handler for discovery scans. The scan is
+// submitted to the scan manager, like one started through the API, and the
+// task runs until the scan ends so runs of a rule never overlap.
 func (s *Scheduler) createDiscoveryHandler(rule model.DiscoveryRule) TaskHandler {
 	return func(ctx context.Context, taskID string) error {
-		return s.scanner.ScanNetwork(ctx, rule.NetworkID, &rule, nil)
+		if s.scans == nil {
+			return errors.New("no discovery scan manager configured")
+		}
+
+		scan := &model.DiscoveryScan{ID: uuid.NewString()}
+
+		// Subscribe before submitting, so the end of the scan is not missed
+		events, unsubscribe := s.scans.Subscribe(scan.ID)
+		defer unsubscribe()
+
+		if err := s.scans.Submit(scan, &rule); err != nil {
+			return err
+		}
+		log.Info("Scheduled discovery scan queued", "task_id", taskID, "scan_id", scan.ID, "network_id", rule.NetworkID)
+
+		for {
+			select {
+			case <-ctx.Done():
+				// The scan keeps running in the scan manager
+				return ctx.Err()
+			case _, ok := <-events:
+				if ok {
+					continue
+				}
+				current, err := s.scans.storage.GetDiscoveryScan(scan.ID)
+				if err != nil {
+					return err
+				}
+				switch {
+				case current.Status == "completed":
+					return nil
+				case current.ErrorMessage != "":
+					return fmt.Errorf("discovery scan %s %s: %s", scan.ID, current.Status, current.ErrorMessage)
+				case scanEnded(current):
+					return fmt.Errorf("discovery scan %s %s", scan.ID, current.Status)
+				}
+				// The scan manager stopped; the scan resumes with it
+				return fmt.Errorf("discovery scan %s stopped before it ended", scan.ID)
+			}
+		}
 	}
 }
@@ -4,11 +4,20 @@ import (
 	"context"
 	"os"
 
+	"github.com/martinsuchenak/rackd/cmd/asset"
+	"github.com/martinsuchenak/rackd/cmd/backup"
+	"github.com/martinsuchenak/rackd/cmd/customfield"
 	"github.com/martinsuchenak/rackd/cmd/datacenter"
 	"github.com/martinsuchenak/rackd/cmd/device"
 	"github.com/martinsuchenak/rackd/cmd/discovery"
+	"github.com/martinsuchenak/rackd/cmd/export"
+	"github.com/martinsuchenak/rackd/cmd/importer"
+	"github.com/martinsuchenak/rackd/cmd/jobs"
+	"github.com/martinsuchenak/rackd/cmd/label"
 	"github.com/martinsuchenak/rackd/cmd/network"
 	"github.com/martinsuchenak/rackd/cmd/server"
+	"github.com/martinsuchenak/rackd/cmd/software"
+	"github.com/martinsuchenak/rackd/cmd/topology"
 	"github.com/martinsuchenak/rackd/internal/log"
 	"github.com/paularlott/cli"
 	"github.com/paularlott/cli/env"
@@ -80,6 +89,51 @@ func main() {
 				Description: "Device discovery and testing commands",
 				Commands:    discovery.Commands(),
 			},
+			{
+				Name:        "customfield",
+				Usage:       "Custom field commands",
+				Description: "Manage custom field definitions for devices, networks and datacenters",
+				Commands:    customfield.Commands(),
+			},
+			{
+				Name:        "label",
+				Usage:       "Label commands",
+				Description: "Manage key/value labels on devices, networks and pools",
+				Commands:    label.Commands(),
+			},
+			{
+				Name:        "asset",
+				Usage:       "Hardware asset commands",
+				Description: "Report on serial numbers, warranties and procurement data",
+				Commands:    asset.Commands(),
+			},
+			{
+				Name:        "software",
+				Usage:       "Software inventory commands",
+				Description: "Push and query the OS and installed packages of devices",
+				Commands:    software.Commands(),
+			},
+			{
+				Name:        "topology",
+				Usage:       "Topology commands",
+				Description: "Export the inventory as a graph",
+				Commands:    topology.Commands(),
+			},
+			{
+				Name:        "backup",
+				Usage:       "Database backup commands",
+				Description: "Take, list, download and restore online database backups",
+				Commands:    backup.Commands(),
+			},
+			{
+				Name:        "jobs",
+				Usage:       "Background job commands",
+				Description: "Inspect and retry background jobs such as discovery, cleanup, backups, reports and imports",
+				Commands:    jobs.Commands(),
+			},
+			importer.Command(),
+			export.Command(),
+			export.RestoreCommand(),
 		},
 	}
 
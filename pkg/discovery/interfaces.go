@@ -13,6 +13,36 @@ type Scanner interface {
 	ScanNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, updateFunc func(*model.DiscoveryScan)) error
 }
 
+// Progress tracks the hosts a scan has finished, so an interrupted scan can be
+// resumed without scanning them again
+type Progress interface {
+	// Done reports whether an earlier run of the scan finished the host
+	Done(ip string) bool
+
+	// MarkDone records that the host was scanned, with the stored device
+	// found on it or nil
+	MarkDone(ip string, device *model.DiscoveredDevice)
+}
+
+// ResumableScanner is a Scanner that can resume an interrupted scan
+type ResumableScanner interface {
+	Scanner
+
+	// ResumeNetwork scans a network like ScanNetwork, skipping the hosts that
+	// progress reports as done and recording each host it finishes.
+	// It returns the context's error, without completing the scan, when cancelled.
+	ResumeNetwork(ctx context.Context, networkID string, rule *model.DiscoveryRule, progress Progress, updateFunc func(*model.DiscoveryScan)) error
+}
+
+// ProbeScanner is a Scanner that examines hosts with a pipeline of probes
+type ProbeScanner interface {
+	Scanner
+
+	// ProbeNames returns the names of the probes in the pipeline, which
+	// discovery rules turn on or off
+	ProbeNames() []string
+}
+
 // Scheduler manages and executes scheduled discovery tasks
 type Scheduler interface {
 	// Start begins the scheduler's task execution loop
@@ -0,0 +1,221 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/martinsuchenak/rackd/internal/log"
+	"github.com/martinsuchenak/rackd/internal/model"
+	"github.com/martinsuchenak/rackd/pkg/registry"
+)
+
+// ErrSkipHost is returned by a probe that decided the host is not reported,
+// such as a liveness check of a quick scan that got no answer
+var ErrSkipHost = errors.New("host not reported")
+
+// Probe is one step of scanning a host, such as a liveness check, a port scan
+// or an OS guess. Probes run in dependency order and merge what they find into
+// the host's DiscoveredDevice.
+type Probe interface {
+	// Name identifies the probe in dependencies and in DiscoveryRule.Probes
+	Name() string
+
+	// DependsOn names the probes that run before this one. A dependency that
+	// is missing or disabled does not stop the probe; one that failed does.
+	DependsOn() []string
+
+	// Probe examines the host and updates host.Device
+	Probe(ctx context.Context, host *Host) error
+}
+
+// Host is a host being scanned, shared by the probes of a pipeline
+type Host struct {
+	IP     string
+	Rule   *model.DiscoveryRule
+	Device *model.DiscoveredDevice
+	Alive  bool // Set by a probe that saw the host answer
+}
+
+// NewProbe returns a Probe that runs fn
+func NewProbe(name string, dependsOn []string, fn func(ctx context.Context, host *Host) error) Probe {
+	return &funcProbe{name: name, dependsOn: dependsOn, fn: fn}
+}
+
+type funcProbe struct {
+	name      string
+	dependsOn []string
+	fn        func(ctx context.Context, host *Host) error
+}
+
+func (p *funcProbe) Name() string        { return p.name }
+func (p *funcProbe) DependsOn() []string { return p.dependsOn }
+func (p *funcProbe) Probe(ctx context.Context, host *Host) error {
+	return p.fn(ctx, host)
+}
+
+// ProbeEnabled reports whether a rule runs a probe; probes the rule does not
+// mention run
+func ProbeEnabled(rule *model.DiscoveryRule, name string) bool {
+	if rule == nil {
+		return true
+	}
+	enabled, ok := rule.Probes[name]
+	return !ok || enabled
+}
+
+// RegisterProbe adds a probe to the pipelines of the scanners created after it,
+// replacing a built-in probe of the same name. A scanner builds its pipeline
+// when it is created, so probes must be registered before then, typically from
+// an init function; scanners that already exist never run a later registration.
+func RegisterProbe(probe Probe) {
+	registry.GetRegistry().RegisterProbe(probe.Name(), probe)
+}
+
+// WithRegisteredProbes returns the built-in probes of a scanner with the
+// probes of the registry: a registered probe takes the place of the built-in
+// probe it is named after, and the others follow in name order
+func WithRegisteredProbes(builtin ...Probe) []Probe {
+	reg := registry.GetRegistry()
+	probes := make([]Probe, 0, len(builtin))
+	seen := make(map[string]bool, len(builtin))
+	for _, p := range builtin {
+		if registered, ok := registeredProbe(reg, p.Name()); ok {
+			p = registered
+		}
+		probes = append(probes, p)
+		seen[p.Name()] = true
+	}
+	for _, name := range reg.ListProbes() {
+		if seen[name] {
+			continue
+		}
+		if p, ok := registeredProbe(reg, name); ok {
+			probes = append(probes, p)
+		}
+	}
+	return probes
+}
+
+func registeredProbe(reg *registry.Registry, name string) (Probe, bool) {
+	value, ok := reg.GetProbe(name)
+	if !ok {
+		return nil, false
+	}
+	p, ok := value.(Probe)
+	return p, ok
+}
+
+// Pipeline runs probes in dependency order
+type Pipeline struct {
+	probes []Probe
+}
+
+// NewPipeline orders probes so each runs after its dependencies, keeping the
+// given order otherwise. It fails for duplicate names and dependency cycles.
+func NewPipeline(probes ...Probe) (*Pipeline, error) {
+	byName := make(map[string]Probe, len(probes))
+	for _, p := range probes {
+		if _, ok := byName[p.Name()]; ok {
+			return nil, fmt.Errorf("duplicate probe %q", p.Name())
+		}
+		byName[p.Name()] = p
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(probes))
+	ordered := make([]Probe, 0, len(probes))
+	var visit func(p Probe) error
+	visit = func(p Probe) error {
+		switch state[p.Name()] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle through probe %q", p.Name())
+		}
+		state[p.Name()] = visiting
+		for _, dep := range p.DependsOn() {
+			if d, ok := byName[dep]; ok {
+				if err := visit(d); err != nil {
+					return err
+				}
+			}
+		}
+		state[p.Name()] = visited
+		ordered = append(ordered, p)
+		return nil
+	}
+	for _, p := range probes {
+		if err := visit(p); err != nil {
+			return nil, err
+		}
+	}
+	return &Pipeline{probes: ordered}, nil
+}
+
+// NewScannerPipeline builds the pipeline of a scanner from its built-in probes
+// and the registered ones. When the registered probes cannot be ordered they
+// are ignored, so a broken extension does not stop discovery.
+func NewScannerPipeline(builtin ...Probe) *Pipeline {
+	pl, err := NewPipeline(WithRegisteredProbes(builtin...)...)
+	if err == nil {
+		return pl
+	}
+	log.Error("Ignoring registered probes", "error", err)
+	pl, err = NewPipeline(builtin...)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in probes: %v", err))
+	}
+	return pl
+}
+
+// Names returns the probe names in the order they run
+func (pl *Pipeline) Names() []string {
+	names := make([]string, len(pl.probes))
+	for i, p := range pl.probes {
+		names[i] = p.Name()
+	}
+	return names
+}
+
+// Run runs the probes the host's rule enables. A failing probe is logged and
+// the probes depending on it are skipped. Run returns ErrSkipHost when a probe
+// decided the host is not reported, or the context's error when cancelled.
+func (pl *Pipeline) Run(ctx context.Context, host *Host) error {
+	failed := make(map[string]bool)
+	for _, p := range pl.probes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !ProbeEnabled(host.Rule, p.Name()) {
+			continue
+		}
+		// What depends on a skipped probe is skipped as well
+		if dependencyFailed(p, failed) {
+			failed[p.Name()] = true
+			continue
+		}
+
+		err := p.Probe(ctx, host)
+		if errors.Is(err, ErrSkipHost) {
+			return err
+		}
+		if err != nil {
+			log.Debug("Probe failed", "probe", p.Name(), "ip", host.IP, "error", err)
+			failed[p.Name()] = true
+		}
+	}
+	return ctx.Err()
+}
+
+func dependencyFailed(p Probe, failed map[string]bool) bool {
+	for _, dep := range p.DependsOn() {
+		if failed[dep] {
+			return true
+		}
+	}
+	return false
+}
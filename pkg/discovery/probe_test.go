@@ -0,0 +1,111 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/martinsuchenak/rackd/internal/model"
+)
+
+// recorder returns a probe that records its name when it runs and returns err
+func recorder(ran *[]string, name string, deps []string, err error) Probe {
+	return NewProbe(name, deps, func(ctx context.Context, host *Host) error {
+		*ran = append(*ran, name)
+		return err
+	})
+}
+
+func TestPipelineOrder(t *testing.T) {
+	var ran []string
+	pl, err := NewPipeline(
+		recorder(&ran, "os", []string{"ports", "services"}, nil),
+		recorder(&ran, "ping", nil, nil),
+		recorder(&ran, "services", []string{"ports"}, nil),
+		recorder(&ran, "ports", []string{"ping", "missing"}, nil),
+	)
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+
+	want := []string{"ping", "ports", "services", "os"}
+	if got := pl.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+	if err := pl.Run(context.Background(), &Host{IP: "10.0.0.1", Device: &model.DiscoveredDevice{}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestPipelineInvalid(t *testing.T) {
+	var ran []string
+	if _, err := NewPipeline(recorder(&ran, "a", []string{"b"}, nil), recorder(&ran, "b", []string{"a"}, nil)); err == nil || err.Error() != `dependency cycle through probe "a"` {
+		t.Errorf("expected a dependency cycle to fail, got %v", err)
+	}
+	if _, err := NewPipeline(recorder(&ran, "a", nil, nil), recorder(&ran, "a", nil, nil)); err == nil {
+		t.Error("expected duplicate probes to fail")
+	}
+}
+
+func TestPipelineRun(t *testing.T) {
+	var ran []string
+	pl, err := NewPipeline(
+		recorder(&ran, "ping", nil, nil),
+		recorder(&ran, "ports", nil, errors.New("filtered")),
+		recorder(&ran, "services", []string{"ports"}, nil),
+		recorder(&ran, "os", []string{"services"}, nil),
+		recorder(&ran, "arp", []string{"ping"}, nil),
+		recorder(&ran, "hostname", nil, nil),
+	)
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+
+	// Disabling ping does not stop arp; a failed probe skips what depends on it
+	rule := &model.DiscoveryRule{Probes: map[string]bool{"ping": false, "hostname": true}}
+	if err := pl.Run(context.Background(), &Host{Rule: rule, Device: &model.DiscoveredDevice{}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []string{"ports", "arp", "hostname"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestPipelineSkipHost(t *testing.T) {
+	var ran []string
+	pl, err := NewPipeline(recorder(&ran, "ping", nil, ErrSkipHost), recorder(&ran, "ports", nil, nil))
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+	if err := pl.Run(context.Background(), &Host{Device: &model.DiscoveredDevice{}}); !errors.Is(err, ErrSkipHost) {
+		t.Errorf("expected ErrSkipHost, got %v", err)
+	}
+	if len(ran) != 1 {
+		t.Errorf("expected the pipeline to stop, ran %v", ran)
+	}
+}
+
+func TestWithRegisteredProbes(t *testing.T) {
+	var ran []string
+	RegisterProbe(recorder(&ran, "test-hostname", nil, nil))
+	RegisterProbe(recorder(&ran, "test-snmp", []string{"test-ping"}, nil))
+
+	probes := WithRegisteredProbes(
+		recorder(&ran, "test-ping", nil, nil),
+		NewProbe("test-hostname", nil, func(ctx context.Context, host *Host) error {
+			t.Error("expected the built-in probe to be replaced")
+			return nil
+		}),
+	)
+	pl := NewScannerPipeline(probes...)
+	if err := pl.Run(context.Background(), &Host{Device: &model.DiscoveredDevice{}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []string{"test-ping", "test-hostname", "test-snmp"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Backup describes a database backup file
+type Backup struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+	Compressed bool      `json:"compressed"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "encoding/json"
+
+// BatchOperation is one API request of a batch. Path and body may refer to a
+// field of an earlier result as ${N.field}, where N is the 0-based index of the
+// operation, e.g. "/api/devices/${0.id}/relationships".
+type BatchOperation struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchRequest is a list of API requests executed in one transaction
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult is the response to one operation of a batch
+type BatchResult struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResponse holds the results of the operations that ran. When an operation
+// fails the batch is rolled back, Error describes the failure and FailedIndex
+// points at the operation.
+type BatchResponse struct {
+	Results     []BatchResult `json:"results"`
+	Error       string        `json:"error,omitempty"`
+	FailedIndex *int          `json:"failed_index,omitempty"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// Entity types that custom fields can be attached to
+const (
+	CustomFieldEntityDevice     = "device"
+	CustomFieldEntityNetwork    = "network"
+	CustomFieldEntityDatacenter = "datacenter"
+)
+
+// Custom field value types
+const (
+	CustomFieldTypeString = "string"
+	CustomFieldTypeInt    = "int"
+	CustomFieldTypeBool   = "bool"
+	CustomFieldTypeDate   = "date" // YYYY-MM-DD
+	CustomFieldTypeEnum   = "enum"
+	CustomFieldTypeURL    = "url"
+)
+
+// CustomFieldDefinition describes an admin-defined attribute for devices, networks or datacenters
+type CustomFieldDefinition struct {
+	ID          string    `json:"id"`
+	EntityType  string    `json:"entity_type"` // "device", "network", "datacenter"
+	Name        string    `json:"name"`        // Key used in custom_fields maps, e.g. "serial_number"
+	Label       string    `json:"label,omitempty"`
+	Type        string    `json:"type"` // "string", "int", "bool", "date", "enum", "url"
+	Required    bool      `json:"required"`
+	Pattern     string    `json:"pattern,omitempty"` // Optional regular expression values must match
+	Options     []string  `json:"options,omitempty"` // Allowed values for enum fields
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CustomFieldFilter holds filter criteria for listing custom field definitions
+type CustomFieldFilter struct {
+	EntityType string
+}
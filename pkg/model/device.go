@@ -6,19 +6,26 @@ import (
 
 // Device represents a tracked device with all its properties
 type Device struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description"`
-	MakeModel    string    `json:"make_model"`
-	OS           string    `json:"os"`
-	DatacenterID string    `json:"datacenter_id,omitempty"`
-	Username     string    `json:"username,omitempty"`
-	Location     string    `json:"location,omitempty"`
-	Tags         []string  `json:"tags"`
-	Addresses    []Address `json:"addresses"`
-	Domains      []string  `json:"domains"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Description     string            `json:"description"`
+	MakeModel       string            `json:"make_model"`
+	OS              string            `json:"os"`
+	OSInfo          *OSInfo           `json:"os_info,omitempty"` // Structured OS reported by software inventory ingest
+	DatacenterID    string            `json:"datacenter_id,omitempty"`
+	Username        string            `json:"username,omitempty"`
+	Location        string            `json:"location,omitempty"`
+	Tags            []string          `json:"tags"`
+	Addresses       []Address         `json:"addresses"`
+	Domains         []string          `json:"domains"`
+	Status          DeviceStatus      `json:"status"`
+	StatusChangedAt *time.Time        `json:"status_changed_at,omitempty"` // When the device entered its current status
+	Asset           *HardwareAsset    `json:"asset,omitempty"`             // Procurement and support data, nil if not recorded
+	Labels          map[string]string `json:"labels,omitempty"`
+	CustomFields    map[string]string `json:"custom_fields,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	Version         int               `json:"version"` // Revision, incremented on every change; updates with a non-zero version only apply to that revision
 }
 
 // Address represents a network address for a device
@@ -29,12 +36,16 @@ type Address struct {
 	Label      string `json:"label"`                 // e.g., "management", "data"
 	NetworkID  string `json:"network_id,omitempty"`  // Network this IP belongs to
 	SwitchPort string `json:"switch_port,omitempty"` // Switch port (e.g., "eth0", "Gi1/0/1")
+	MACAddress string `json:"mac_address,omitempty"` // MAC address of the interface, compared with discovery scans
 	PoolID     string `json:"pool_id,omitempty"`     // Pool this IP belongs to
 }
 
 // DeviceFilter holds filter criteria for listing devices
 type DeviceFilter struct {
-	Tags []string // Filter by tags (OR logic)
+	Tags          []string          // Filter by tags (OR logic)
+	Statuses      []DeviceStatus    // Filter by lifecycle status (OR logic)
+	LabelSelector string            // Filter by label selector, e.g. "env=prod,role in (db,cache)"
+	CustomFields  map[string]string // Filter by custom field values (AND logic, exact match)
 }
 
 // SearchQuery holds search criteria
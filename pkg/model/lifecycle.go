@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+// DeviceStatus is the lifecycle state of a device
+type DeviceStatus string
+
+// Device lifecycle states, in lifecycle order
+const (
+	DeviceStatusPlanned        DeviceStatus = "planned"
+	DeviceStatusOrdered        DeviceStatus = "ordered"
+	DeviceStatusReceived       DeviceStatus = "received"
+	DeviceStatusStaged         DeviceStatus = "staged"
+	DeviceStatusActive         DeviceStatus = "active"
+	DeviceStatusMaintenance    DeviceStatus = "maintenance"
+	DeviceStatusDecommissioned DeviceStatus = "decommissioned"
+	DeviceStatusDisposed       DeviceStatus = "disposed"
+)
+
+// DeviceStatuses lists all lifecycle states in lifecycle order
+var DeviceStatuses = []DeviceStatus{
+	DeviceStatusPlanned,
+	DeviceStatusOrdered,
+	DeviceStatusReceived,
+	DeviceStatusStaged,
+	DeviceStatusActive,
+	DeviceStatusMaintenance,
+	DeviceStatusDecommissioned,
+	DeviceStatusDisposed,
+}
+
+// DeviceStatusTransitions lists the states each state may move to
+var DeviceStatusTransitions = map[DeviceStatus][]DeviceStatus{
+	DeviceStatusPlanned:        {DeviceStatusOrdered, DeviceStatusDisposed},
+	DeviceStatusOrdered:        {DeviceStatusReceived, DeviceStatusPlanned, DeviceStatusDisposed},
+	DeviceStatusReceived:       {DeviceStatusStaged, DeviceStatusDecommissioned},
+	DeviceStatusStaged:         {DeviceStatusActive, DeviceStatusDecommissioned},
+	DeviceStatusActive:         {DeviceStatusMaintenance, DeviceStatusDecommissioned},
+	DeviceStatusMaintenance:    {DeviceStatusActive, DeviceStatusDecommissioned},
+	DeviceStatusDecommissioned: {DeviceStatusStaged, DeviceStatusDisposed},
+	DeviceStatusDisposed:       {},
+}
+
+// Valid reports whether s is a known lifecycle state
+func (s DeviceStatus) Valid() bool {
+	_, ok := DeviceStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a device may move from s to next
+func (s DeviceStatus) CanTransitionTo(next DeviceStatus) bool {
+	for _, allowed := range DeviceStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// DeviceStatusChange records a lifecycle transition of a device
+type DeviceStatusChange struct {
+	ID                string       `json:"id"`
+	DeviceID          string       `json:"device_id"`
+	FromStatus        DeviceStatus `json:"from_status,omitempty"` // Empty for the initial state of a new device
+	ToStatus          DeviceStatus `json:"to_status"`
+	Note              string       `json:"note,omitempty"`
+	ChangedAt         time.Time    `json:"changed_at"`
+	ReleasedAddresses []Address    `json:"released_addresses,omitempty"` // Pool addresses released by decommissioning
+}
@@ -4,35 +4,43 @@ import "time"
 
 // Network represents a network subnet in a data center
 type Network struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	Subnet       string    `json:"subnet"` // CIDR notation, e.g., "192.168.1.0/24"
-	DatacenterID string    `json:"datacenter_id"`
-	Description  string    `json:"description,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Subnet       string            `json:"subnet"` // CIDR notation, e.g., "192.168.1.0/24"
+	DatacenterID string            `json:"datacenter_id"`
+	Description  string            `json:"description,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	Version      int               `json:"version"` // Revision, see Device.Version
 }
 
 // NetworkFilter holds filter criteria for listing networks
 type NetworkFilter struct {
-	Name         string // Filter by name (partial match)
-	DatacenterID string // Filter by datacenter
+	Name          string            // Filter by name (partial match)
+	DatacenterID  string            // Filter by datacenter
+	LabelSelector string            // Filter by label selector
+	CustomFields  map[string]string // Filter by custom field values (AND logic, exact match)
 }
 
 // NetworkPool represents a range of IPs within a network
 type NetworkPool struct {
-	ID          string    `json:"id"`
-	NetworkID   string    `json:"network_id"`
-	Name        string    `json:"name"`
-	StartIP     string    `json:"start_ip"`
-	EndIP       string    `json:"end_ip"`
-	Tags        []string  `json:"tags"`
-	Description string    `json:"description,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string            `json:"id"`
+	NetworkID   string            `json:"network_id"`
+	Name        string            `json:"name"`
+	StartIP     string            `json:"start_ip"`
+	EndIP       string            `json:"end_ip"`
+	Tags        []string          `json:"tags"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Description string            `json:"description,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Version     int               `json:"version"` // Revision, see Device.Version
 }
 
 // NetworkPoolFilter holds filter criteria for listing network pools
 type NetworkPoolFilter struct {
-	NetworkID string
+	NetworkID     string
+	LabelSelector string // Filter by label selector
 }
@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"sort"
 	"sync"
 )
 
@@ -17,11 +18,15 @@ type Registry struct {
 	apiHandlerFactories map[string]APIHandlerFactory
 	mcpToolsFactories   map[string]MCPToolsFactory
 
+	// Scan probes by name, see discovery.Probe
+	probes map[string]interface{}
+
 	// Feature implementations - stores actual feature objects, not just flags
 	features map[string]interface{}
 }
 
-// StorageProviderFactory creates storage provider instances
+// StorageProviderFactory creates storage provider instances. The config holds the
+// "data_dir" and "dsn" settings; the returned value implements the storage interfaces.
 type StorageProviderFactory func(config map[string]interface{}) (interface{}, error)
 
 // ScannerProviderFactory creates scanner provider instances
@@ -52,6 +57,7 @@ func GetRegistry() *Registry {
 			workerProviders:    make(map[string]WorkerProviderFactory),
 			apiHandlerFactories: make(map[string]APIHandlerFactory),
 			mcpToolsFactories:   make(map[string]MCPToolsFactory),
+			probes:             make(map[string]interface{}),
 			features:           make(map[string]interface{}),
 		}
 	})
@@ -102,6 +108,18 @@ func (r *Registry) GetStorageProvider(name string) (StorageProviderFactory, bool
 	return factory, exists
 }
 
+// ListStorageProviders returns all registered storage provider names
+func (r *Registry) ListStorageProviders() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.storageProviders))
+	for name := range r.storageProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // GetScannerProvider returns a scanner provider factory by name
 func (r *Registry) GetScannerProvider(name string) (ScannerProviderFactory, bool) {
 	r.mu.RLock()
@@ -185,3 +203,31 @@ func (r *Registry) ListMCPTools() []string {
 	}
 	return names
 }
+
+// RegisterProbe registers a scan probe
+// The value should implement discovery.Probe
+func (r *Registry) RegisterProbe(name string, probe interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.probes[name] = probe
+}
+
+// GetProbe returns a scan probe by name
+func (r *Registry) GetProbe(name string) (interface{}, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	probe, exists := r.probes[name]
+	return probe, exists
+}
+
+// ListProbes returns all registered probe names
+func (r *Registry) ListProbes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.probes))
+	for name := range r.probes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
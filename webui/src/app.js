@@ -6,13 +6,14 @@ import './datacenter.js';
 import './network.js';
 import './device.js';
 import './discovery.js';
+import './topology.js';
 
 Alpine.plugin(focus);
 
 // Simple hash-based router
 Alpine.store('router', {
     currentView: 'devices',
-    routes: ['devices', 'networks', 'datacenters', 'discovery'],
+    routes: ['devices', 'networks', 'datacenters', 'topology', 'discovery'],
 
     init() {
         // Read initial hash from URL
@@ -39,6 +39,8 @@ Alpine.data('deviceManager', () => ({
             Alpine.store('appData').loadNetworks(true).then(() => this.loadDevices());
         });
         window.addEventListener('refresh-devices', () => this.loadDevices());
+        // Opened from other views, e.g. clicking a node in the topology graph
+        window.addEventListener('view-device', (e) => this.viewDevice(e.detail.id));
     },
 
     // Check if there's only one datacenter
@@ -2,12 +2,36 @@ import Alpine from 'alpinejs';
 import { api } from './api.js';
 import { modalConfig, viewModalConfig } from './modal.js';
 
+// emptyRuleForm returns the form of a new discovery rule
+function emptyRuleForm() {
+    return {
+        id: '',
+        network_id: '',
+        enabled: true,
+        schedule_mode: 'interval',
+        scan_interval_hours: 24,
+        schedule: '',
+        timezone: '',
+        jitter_minutes: 0,
+        blackouts: [],
+        exclude_ips: ''
+    };
+}
+
+// toLocalInput converts an ISO timestamp to the value of a datetime-local input
+function toLocalInput(value) {
+    if (!value) return '';
+    const date = new Date(value);
+    return new Date(date.getTime() - date.getTimezoneOffset() * 60000).toISOString().slice(0, 16);
+}
+
 Alpine.data('discoveryManager', () => ({
     // Reusable modal configurations
     viewModal: viewModalConfig('2xl'),
     promoteModal: modalConfig('2xl'),
     bulkPromoteModal: modalConfig('2xl'),
     scanModal: modalConfig('md'),
+    ruleModal: modalConfig('2xl'),
 
     discoveredDevices: [],
     scans: [],
@@ -26,6 +50,8 @@ Alpine.data('discoveryManager', () => ({
     set showViewModal(value) { this.viewModal.show = value; },
     get showScanModal() { return this.scanModal.show; },
     set showScanModal(value) { this.scanModal.show = value; },
+    get showRuleModal() { return this.ruleModal.show; },
+    set showRuleModal(value) { this.ruleModal.show = value; },
     selectedDevices: new Set(),
     filters: {
         network_id: '',
@@ -58,13 +84,7 @@ Alpine.data('discoveryManager', () => ({
     scanForm: {
         network_id: ''
     },
-    ruleForm: {
-        id: '',
-        network_id: '',
-        enabled: true,
-        scan_interval_hours: 24,
-        exclude_ips: ''
-    },
+    ruleForm: emptyRuleForm(),
 
     init() {
         this.loadDiscoveredDevices();
@@ -357,43 +377,59 @@ Alpine.data('discoveryManager', () => ({
     openRuleModal(rule = null) {
         if (rule) {
             this.ruleForm = {
+                ...emptyRuleForm(),
                 ...rule,
-                exclude_ips: rule.exclude_ips ? rule.exclude_ips.join(', ') : ''
+                schedule_mode: rule.schedule ? 'cron' : 'interval',
+                schedule: rule.schedule || '',
+                timezone: rule.timezone || '',
+                jitter_minutes: rule.jitter_minutes || 0,
+                exclude_ips: rule.exclude_ips ? rule.exclude_ips.join(', ') : '',
+                blackouts: (rule.blackouts || []).map(b => ({
+                    type: b.schedule ? 'recurring' : 'once',
+                    reason: b.reason || '',
+                    schedule: b.schedule || '',
+                    duration_minutes: b.duration_minutes || 60,
+                    start: toLocalInput(b.start),
+                    end: toLocalInput(b.end)
+                }))
             };
         } else {
-            this.ruleForm = {
-                id: '',
-                network_id: '',
-                enabled: true,
-                scan_interval_hours: 24,
-                exclude_ips: ''
-            };
+            this.ruleForm = emptyRuleForm();
         }
-        this.showRuleModal = true;
+        this.ruleModal.open();
     },
 
     closeRuleModal() {
-        this.showRuleModal = false;
+        this.ruleModal.close();
         this.resetRuleForm();
     },
 
     resetRuleForm() {
-        this.ruleForm = {
-            id: '',
-            network_id: '',
-            enabled: true,
-            scan_interval_hours: 24,
-            exclude_ips: ''
-        };
+        this.ruleForm = emptyRuleForm();
+    },
+
+    addBlackout() {
+        this.ruleForm.blackouts.push({ type: 'recurring', reason: '', schedule: '', duration_minutes: 60, start: '', end: '' });
+    },
+
+    removeBlackout(index) {
+        this.ruleForm.blackouts.splice(index, 1);
     },
 
     async saveRule() {
         this.saving = true;
         try {
+            const cron = this.ruleForm.schedule_mode === 'cron';
             const payload = {
                 network_id: this.ruleForm.network_id,
                 enabled: this.ruleForm.enabled,
-                scan_interval_hours: this.ruleForm.scan_interval_hours,
+                scan_interval_hours: Number(this.ruleForm.scan_interval_hours) || 0,
+                schedule: cron ? this.ruleForm.schedule.trim() : '',
+                timezone: this.ruleForm.timezone.trim(),
+                jitter_minutes: Number(this.ruleForm.jitter_minutes) || 0,
+                blackouts: this.ruleForm.blackouts.map(b => b.type === 'recurring'
+                    ? { reason: b.reason, schedule: b.schedule.trim(), duration_minutes: Number(b.duration_minutes) || 0 }
+                    : { reason: b.reason, start: b.start ? new Date(b.start).toISOString() : null, end: b.end ? new Date(b.end).toISOString() : null }),
                 scan_type: 'basic',
                 exclude_ips: this.ruleForm.exclude_ips.split(',').map(t => t.trim()).filter(t => t)
             };
@@ -415,6 +451,18 @@ Alpine.data('discoveryManager', () => ({
         }
     },
 
+    ruleNetworkName(rule) {
+        return this.networks.find(n => n.id === rule.network_id)?.name || rule.network_id;
+    },
+
+    ruleScheduleText(rule) {
+        let text = rule.schedule
+            ? `${rule.schedule} (${rule.timezone || 'server time'})`
+            : `Every ${rule.scan_interval_hours} hour(s)`;
+        if (rule.jitter_minutes) text += ` ±${rule.jitter_minutes}m`;
+        return text;
+    },
+
     async deleteRule(id) {
         if (!confirm('Are you sure you want to delete this rule?')) return;
         try {
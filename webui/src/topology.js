@@ -0,0 +1,280 @@
+import Alpine from 'alpinejs';
+import { api } from './api.js';
+
+// Layout data is kept outside Alpine's reactive state: wrapping thousands of
+// nodes in proxies makes every redraw noticeably slower.
+let layout = null;
+let nodesById = new Map();
+let groupsById = new Map();
+let grid = new Map();
+
+const GRID_SIZE = 40;
+const NODE_RADIUS = 5;
+// Below this zoom level only groups and aggregated group edges are drawn
+const DETAIL_SCALE = 0.35;
+// Above this zoom level device labels are drawn
+const LABEL_SCALE = 1.5;
+
+function gridKey(x, y) {
+    return `${Math.floor(x / GRID_SIZE)}:${Math.floor(y / GRID_SIZE)}`;
+}
+
+// Index nodes into a coarse grid so click hit-testing stays cheap
+function buildIndex() {
+    nodesById = new Map(layout.nodes.map(n => [n.id, n]));
+    groupsById = new Map(layout.groups.map(g => [g.id, g]));
+    grid = new Map();
+    for (const node of layout.nodes) {
+        const key = gridKey(node.x, node.y);
+        if (!grid.has(key)) grid.set(key, []);
+        grid.get(key).push(node);
+    }
+}
+
+Alpine.data('topologyManager', () => ({
+    loading: false,
+    groupBy: 'datacenter',
+    datacenterId: '',
+    tagsInput: '',
+    stats: { groups: 0, nodes: 0, edges: 0 },
+    hoveredLabel: '',
+    get datacenters() { return Alpine.store('appData').datacenters; },
+
+    // Viewport transform (world -> screen)
+    view: { x: 0, y: 0, scale: 1 },
+    dragging: null,
+
+    init() {
+        Alpine.store('appData').loadDatacenters();
+
+        this.$watch('$store.router.currentView', (view) => {
+            if (view === 'topology') this.$nextTick(() => this.loadTopology());
+        });
+        if (Alpine.store('router').currentView === 'topology') {
+            this.$nextTick(() => this.loadTopology());
+        }
+
+        window.addEventListener('resize', () => {
+            if (Alpine.store('router').currentView === 'topology') this.draw();
+        });
+        window.addEventListener('refresh-devices', () => {
+            if (Alpine.store('router').currentView === 'topology') this.loadTopology();
+        });
+    },
+
+    async loadTopology() {
+        this.loading = true;
+        try {
+            const params = new URLSearchParams({ group_by: this.groupBy });
+            if (this.datacenterId) params.set('datacenter_id', this.datacenterId);
+            this.tagsInput.split(',').map(t => t.trim()).filter(t => t).forEach(t => params.append('tag', t));
+
+            layout = await api.get(`/api/topology/layout?${params}`);
+            buildIndex();
+            this.stats = { groups: layout.groups.length, nodes: layout.nodes.length, edges: layout.edges.length };
+            this.fitToView();
+        } catch (error) {
+            Alpine.store('toast').notify('Failed to load topology', 'error');
+            layout = null;
+            this.stats = { groups: 0, nodes: 0, edges: 0 };
+            this.draw();
+        } finally {
+            this.loading = false;
+        }
+    },
+
+    get canvas() {
+        return this.$refs.canvas;
+    },
+
+    // Resize the canvas backing store to its CSS size
+    resizeCanvas() {
+        const canvas = this.canvas;
+        const ratio = window.devicePixelRatio || 1;
+        const width = canvas.clientWidth;
+        const height = canvas.clientHeight;
+        if (canvas.width !== width * ratio || canvas.height !== height * ratio) {
+            canvas.width = width * ratio;
+            canvas.height = height * ratio;
+        }
+        return { width, height, ratio };
+    },
+
+    fitToView() {
+        const { width, height } = this.resizeCanvas();
+        if (!layout || !layout.width || !layout.height || !width || !height) {
+            this.view = { x: 0, y: 0, scale: 1 };
+        } else {
+            const scale = Math.min(width / layout.width, height / layout.height, 2);
+            this.view = {
+                scale,
+                x: (width - layout.width * scale) / 2,
+                y: (height - layout.height * scale) / 2
+            };
+        }
+        this.draw();
+    },
+
+    draw() {
+        const canvas = this.canvas;
+        if (!canvas) return;
+        const { width, height, ratio } = this.resizeCanvas();
+        const ctx = canvas.getContext('2d');
+        const dark = document.documentElement.classList.contains('dark');
+
+        ctx.setTransform(ratio, 0, 0, ratio, 0, 0);
+        ctx.clearRect(0, 0, width, height);
+        if (!layout) return;
+
+        const { x, y, scale } = this.view;
+        ctx.setTransform(ratio * scale, 0, 0, ratio * scale, ratio * x, ratio * y);
+
+        // Group clusters
+        ctx.lineWidth = 1 / scale;
+        for (const group of layout.groups) {
+            ctx.beginPath();
+            ctx.arc(group.x, group.y, group.radius, 0, Math.PI * 2);
+            ctx.fillStyle = dark ? 'rgba(59,130,246,0.08)' : 'rgba(59,130,246,0.06)';
+            ctx.fill();
+            ctx.strokeStyle = dark ? '#374151' : '#bfdbfe';
+            ctx.stroke();
+
+            ctx.fillStyle = dark ? '#d1d5db' : '#374151';
+            ctx.font = `${14 / scale}px sans-serif`;
+            ctx.textAlign = 'center';
+            ctx.fillText(`${group.label} (${group.node_count})`, group.x, group.y - group.radius - 6 / scale);
+        }
+
+        if (scale < DETAIL_SCALE) {
+            // Overview: one edge per group pair, width scaled by relationship count
+            ctx.strokeStyle = dark ? 'rgba(156,163,175,0.6)' : 'rgba(107,114,128,0.5)';
+            for (const edge of layout.group_edges) {
+                const a = groupsById.get(edge.source);
+                const b = groupsById.get(edge.target);
+                if (!a || !b) continue;
+                ctx.lineWidth = (1 + Math.log2(edge.count)) / scale;
+                ctx.beginPath();
+                ctx.moveTo(a.x, a.y);
+                ctx.lineTo(b.x, b.y);
+                ctx.stroke();
+            }
+            return;
+        }
+
+        // Device relationships, batched into a single path per width
+        ctx.strokeStyle = dark ? 'rgba(156,163,175,0.5)' : 'rgba(107,114,128,0.4)';
+        const byWidth = new Map();
+        for (const edge of layout.edges) {
+            const w = 1 + Math.log2(edge.count);
+            if (!byWidth.has(w)) byWidth.set(w, []);
+            byWidth.get(w).push(edge);
+        }
+        for (const [w, edges] of byWidth) {
+            ctx.lineWidth = w / scale;
+            ctx.beginPath();
+            for (const edge of edges) {
+                const a = nodesById.get(edge.source);
+                const b = nodesById.get(edge.target);
+                if (!a || !b) continue;
+                ctx.moveTo(a.x, a.y);
+                ctx.lineTo(b.x, b.y);
+            }
+            ctx.stroke();
+        }
+
+        // Devices
+        ctx.fillStyle = dark ? '#60a5fa' : '#2563eb';
+        ctx.beginPath();
+        for (const node of layout.nodes) {
+            ctx.moveTo(node.x + NODE_RADIUS, node.y);
+            ctx.arc(node.x, node.y, NODE_RADIUS, 0, Math.PI * 2);
+        }
+        ctx.fill();
+
+        if (scale >= LABEL_SCALE) {
+            ctx.fillStyle = dark ? '#e5e7eb' : '#111827';
+            ctx.font = `${11 / scale}px sans-serif`;
+            ctx.textAlign = 'left';
+            for (const node of layout.nodes) {
+                ctx.fillText(node.label, node.x + NODE_RADIUS + 2, node.y + 3 / scale);
+            }
+        }
+    },
+
+    // Convert a mouse event to layout coordinates
+    toWorld(event) {
+        const rect = this.canvas.getBoundingClientRect();
+        return {
+            x: (event.clientX - rect.left - this.view.x) / this.view.scale,
+            y: (event.clientY - rect.top - this.view.y) / this.view.scale
+        };
+    },
+
+    nodeAt(event) {
+        if (!layout || this.view.scale < DETAIL_SCALE) return null;
+        const p = this.toWorld(event);
+        const cx = Math.floor(p.x / GRID_SIZE);
+        const cy = Math.floor(p.y / GRID_SIZE);
+        const maxDist = NODE_RADIUS + 3 / this.view.scale;
+        let best = null;
+        let bestDist = maxDist;
+        for (let dx = -1; dx <= 1; dx++) {
+            for (let dy = -1; dy <= 1; dy++) {
+                for (const node of grid.get(`${cx + dx}:${cy + dy}`) || []) {
+                    const d = Math.hypot(node.x - p.x, node.y - p.y);
+                    if (d <= bestDist) {
+                        best = node;
+                        bestDist = d;
+                    }
+                }
+            }
+        }
+        return best;
+    },
+
+    onWheel(event) {
+        const rect = this.canvas.getBoundingClientRect();
+        const mx = event.clientX - rect.left;
+        const my = event.clientY - rect.top;
+        const factor = event.deltaY < 0 ? 1.15 : 1 / 1.15;
+        const scale = Math.min(Math.max(this.view.scale * factor, 0.02), 8);
+        // Zoom around the cursor position
+        this.view = {
+            scale,
+            x: mx - (mx - this.view.x) * (scale / this.view.scale),
+            y: my - (my - this.view.y) * (scale / this.view.scale)
+        };
+        this.draw();
+    },
+
+    onMouseDown(event) {
+        this.dragging = { x: event.clientX, y: event.clientY, moved: false };
+    },
+
+    onMouseMove(event) {
+        if (this.dragging) {
+            const dx = event.clientX - this.dragging.x;
+            const dy = event.clientY - this.dragging.y;
+            if (Math.abs(dx) + Math.abs(dy) > 2) this.dragging.moved = true;
+            this.dragging.x = event.clientX;
+            this.dragging.y = event.clientY;
+            this.view = { ...this.view, x: this.view.x + dx, y: this.view.y + dy };
+            this.draw();
+            return;
+        }
+        const node = this.nodeAt(event);
+        this.hoveredLabel = node ? node.label : '';
+        this.canvas.style.cursor = node ? 'pointer' : 'grab';
+    },
+
+    onMouseUp(event) {
+        const wasDrag = this.dragging?.moved;
+        this.dragging = null;
+        if (wasDrag) return;
+
+        const node = this.nodeAt(event);
+        if (node) {
+            window.dispatchEvent(new CustomEvent('view-device', { detail: { id: node.id } }));
+        }
+    }
+}));